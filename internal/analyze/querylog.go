@@ -0,0 +1,441 @@
+// Package analyze suggests schema changes from how the database is used.
+package analyze
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/lib/pq"
+)
+
+// maxIndexColumns caps the width of a suggested index; later columns rarely pay
+// for the extra writes
+const maxIndexColumns = 3
+
+// Statement is a normalized statement recorded by pg_stat_statements
+type Statement struct {
+	Query     string
+	Calls     int64
+	TotalTime float64 // Milliseconds spent executing the statement
+	Rows      int64
+}
+
+// Suggestion is a candidate index for the filters and sorts of one or more statements
+type Suggestion struct {
+	Table     string
+	Struct    string   // Model that declares the table
+	Columns   []string // Equality filters first, then a range filter or the sort
+	Calls     int64    // Calls of the statements that would use the index
+	TotalTime float64  // Milliseconds spent in those statements
+	Queries   []string // The statements, heaviest first
+}
+
+// IndexName names the index the way the schema generator names declared indexes
+func (s Suggestion) IndexName() string {
+	return generator.TruncateIdentifier(fmt.Sprintf("idx_%s_%s", s.Table, strings.Join(s.Columns, "_")))
+}
+
+// Tag is the table-level tag attribute that declares the index on the model
+func (s Suggestion) Tag() string {
+	return fmt.Sprintf("index:%s,%s", s.IndexName(), strings.Join(s.Columns, ","))
+}
+
+// SQL creates the index without blocking writes to the table
+func (s Suggestion) SQL() string {
+	columns := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		columns[i] = pq.QuoteIdentifier(column)
+	}
+	return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s);",
+		pq.QuoteIdentifier(s.IndexName()), pq.QuoteIdentifier(s.Table), strings.Join(columns, ", "))
+}
+
+// DropSQL drops the index created by SQL
+func (s Suggestion) DropSQL() string {
+	return fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS %s;", pq.QuoteIdentifier(s.IndexName()))
+}
+
+// LoadStatements reads the limit statements with the highest total execution
+// time from pg_stat_statements, which must be installed in the database
+func LoadStatements(ctx context.Context, db *sql.DB, limit int) ([]Statement, error) {
+	// PostgreSQL 13 split total_time into planning and execution time
+	timeColumn := "total_exec_time"
+	var hasExecTime bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (
+		SELECT 1 FROM pg_attribute
+		WHERE attrelid = to_regclass('pg_stat_statements') AND attname = 'total_exec_time'
+	)`).Scan(&hasExecTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect pg_stat_statements: %w", err)
+	}
+	if !hasExecTime {
+		timeColumn = "total_time"
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT query, SUM(calls), SUM(%[1]s), SUM(rows)
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		GROUP BY query
+		ORDER BY SUM(%[1]s) DESC
+		LIMIT $1`, timeColumn), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pg_stat_statements (is the extension installed?): %w", err)
+	}
+	defer rows.Close()
+
+	var statements []Statement
+	for rows.Next() {
+		var s Statement
+		if err := rows.Scan(&s.Query, &s.Calls, &s.TotalTime, &s.Rows); err != nil {
+			return nil, fmt.Errorf("failed to scan pg_stat_statements: %w", err)
+		}
+		statements = append(statements, s)
+	}
+	return statements, rows.Err()
+}
+
+// knownTable is a model table with the column lists its indexes already serve
+type knownTable struct {
+	structName string
+	columns    map[string]bool
+	indexed    [][]string
+}
+
+// Suggest matches the statements against the tables of the models and returns
+// the indexes their filters and sorts are missing, heaviest first. Statements
+// on tables without a model, and filters an existing index or key already
+// leads with, are skipped.
+func Suggest(statements []Statement, tables []parser.TableDefinition) ([]Suggestion, error) {
+	known, err := loadTables(tables)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*Suggestion)
+	var order []string
+	for _, statement := range statements {
+		usages := indexColumns(statement.Query, known)
+		tableNames := make([]string, 0, len(usages))
+		for table := range usages {
+			tableNames = append(tableNames, table)
+		}
+		sort.Strings(tableNames)
+
+		for _, table := range tableNames {
+			columns := usages[table]
+			if covered(known[table].indexed, columns) {
+				continue
+			}
+
+			key := table + "(" + strings.Join(columns, ",") + ")"
+			suggestion, ok := byKey[key]
+			if !ok {
+				suggestion = &Suggestion{Table: table, Struct: known[table].structName, Columns: columns}
+				byKey[key] = suggestion
+				order = append(order, key)
+			}
+			suggestion.Calls += statement.Calls
+			suggestion.TotalTime += statement.TotalTime
+			suggestion.Queries = append(suggestion.Queries, statement.Query)
+		}
+	}
+
+	suggestions := make([]Suggestion, 0, len(order))
+	for _, key := range order {
+		suggestions = append(suggestions, *byKey[key])
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].TotalTime > suggestions[j].TotalTime
+	})
+
+	return suggestions, nil
+}
+
+func loadTables(tables []parser.TableDefinition) (map[string]*knownTable, error) {
+	schemaGenerator := generator.NewSchemaGenerator()
+	known := make(map[string]*knownTable, len(tables))
+
+	for _, tableDef := range tables {
+		table, err := schemaGenerator.GenerateTable(tableDef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read model %s: %w", tableDef.StructName, err)
+		}
+
+		entry := &knownTable{structName: tableDef.StructName, columns: make(map[string]bool)}
+		for _, column := range table.Columns {
+			entry.columns[column.Name] = true
+			if column.IsUnique {
+				entry.indexed = append(entry.indexed, []string{column.Name})
+			}
+		}
+		for _, index := range table.Indexes {
+			if index.Where == "" {
+				entry.indexed = append(entry.indexed, index.Columns)
+			}
+		}
+		for _, constraint := range table.Constraints {
+			if constraint.Type == "PRIMARY KEY" || constraint.Type == "UNIQUE" {
+				entry.indexed = append(entry.indexed, constraint.Columns)
+			}
+		}
+		known[table.Name] = entry
+	}
+
+	return known, nil
+}
+
+// covered reports whether an existing index starts with the same columns, in
+// which case it already serves the filter
+func covered(indexed [][]string, columns []string) bool {
+	for _, index := range indexed {
+		if len(index) < len(columns) {
+			continue
+		}
+		match := true
+		for i, column := range columns {
+			match = match && index[i] == column
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// usage is how a statement uses the columns of one table
+type usage struct {
+	equal  []string
+	ranged []string
+	sorted []string
+}
+
+func (u *usage) add(list *[]string, column string) {
+	for _, existing := range *list {
+		if existing == column {
+			return
+		}
+	}
+	*list = append(*list, column)
+}
+
+// columns orders the columns for a b-tree: equality filters, then one range
+// filter, or the sort when nothing is filtered by range
+func (u *usage) columns() []string {
+	columns := append([]string(nil), u.equal...)
+	tail := u.sorted
+	if len(u.ranged) > 0 {
+		tail = u.ranged[:1]
+	}
+	for _, column := range tail {
+		found := false
+		for _, existing := range columns {
+			found = found || existing == column
+		}
+		if !found {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) > maxIndexColumns {
+		columns = columns[:maxIndexColumns]
+	}
+	return columns
+}
+
+var stopWords = map[string]bool{
+	"select": true, "from": true, "where": true, "join": true, "inner": true, "left": true,
+	"right": true, "full": true, "outer": true, "cross": true, "natural": true, "lateral": true,
+	"on": true, "using": true, "group": true, "order": true, "by": true, "having": true,
+	"limit": true, "offset": true, "fetch": true, "for": true, "union": true, "except": true,
+	"intersect": true, "returning": true, "set": true, "values": true, "as": true, "and": true,
+	"or": true, "not": true, "in": true, "is": true, "null": true, "like": true, "ilike": true,
+	"between": true, "asc": true, "desc": true, "nulls": true, "first": true, "last": true,
+	"distinct": true, "case": true, "when": true, "then": true, "else": true, "end": true,
+	"true": true, "false": true, "exists": true, "any": true, "all": true, "window": true,
+	"update": true, "delete": true, "insert": true, "into": true, "with": true,
+}
+
+// clauses are the keywords that change which part of a statement is being read
+var clauses = map[string]bool{
+	"select": true, "from": true, "where": true, "join": true, "on": true, "group": true,
+	"order": true, "having": true, "limit": true, "offset": true, "returning": true,
+	"set": true, "update": true, "values": true, "using": true, "window": true,
+}
+
+var equalityOperators = map[string]bool{"=": true, "in": true, "is": true}
+
+var rangeOperators = map[string]bool{
+	"<": true, ">": true, "<=": true, ">=": true, "between": true, "like": true, "ilike": true,
+}
+
+// indexColumns returns, per model table, the columns an index for the statement
+// would cover. Only SELECT, UPDATE and DELETE statements are considered.
+func indexColumns(query string, known map[string]*knownTable) map[string][]string {
+	tokens := tokenize(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+	switch tokens[0].text {
+	case "select", "update", "delete", "with":
+	default:
+		return nil
+	}
+
+	aliases := tableAliases(tokens, known)
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	usages := make(map[string]*usage)
+	resolve := func(name string) (string, string, bool) {
+		alias, column, qualified := strings.Cut(name, ".")
+		if qualified {
+			table, ok := aliases[alias]
+			return table, column, ok && known[table].columns[column]
+		}
+		match := ""
+		for _, table := range aliases {
+			if known[table].columns[name] {
+				if match != "" && match != table {
+					return "", "", false
+				}
+				match = table
+			}
+		}
+		return match, name, match != ""
+	}
+	record := func(name string, list func(*usage) *[]string) {
+		table, column, ok := resolve(name)
+		if !ok {
+			return
+		}
+		if usages[table] == nil {
+			usages[table] = &usage{}
+		}
+		u := usages[table]
+		u.add(list(u), column)
+	}
+	equal := func(u *usage) *[]string { return &u.equal }
+	ranged := func(u *usage) *[]string { return &u.ranged }
+	sorted := func(u *usage) *[]string { return &u.sorted }
+
+	clause := ""
+	var stack []string
+	for i, tok := range tokens {
+		switch tok.kind {
+		case tokenOpen:
+			stack = append(stack, clause)
+			continue
+		case tokenClose:
+			if len(stack) > 0 {
+				clause = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			continue
+		case tokenWord:
+			if clauses[tok.text] {
+				clause = tok.text
+				continue
+			}
+		}
+
+		if !isColumn(tokens, i) {
+			continue
+		}
+
+		switch clause {
+		case "where", "on", "having":
+			if operator := comparison(tokens, i+1); equalityOperators[operator] {
+				record(tok.text, equal)
+			} else if rangeOperators[operator] {
+				record(tok.text, ranged)
+			} else if i > 0 && tokens[i-1].text == "=" && isColumn(tokens, i-2) {
+				record(tok.text, equal)
+			}
+		case "order":
+			record(tok.text, sorted)
+		}
+	}
+
+	result := make(map[string][]string, len(usages))
+	for table, u := range usages {
+		if columns := u.columns(); len(columns) > 0 {
+			result[table] = columns
+		}
+	}
+	return result
+}
+
+// isColumn reports whether the token at i names a column rather than a
+// keyword or a function
+func isColumn(tokens []token, i int) bool {
+	if i < 0 || i >= len(tokens) || tokens[i].kind != tokenWord || stopWords[tokens[i].text] {
+		return false
+	}
+	return i+1 >= len(tokens) || tokens[i+1].kind != tokenOpen
+}
+
+// comparison returns the operator that compares the column before i, with
+// NOT IN, NOT LIKE and the like reduced to their positive form
+func comparison(tokens []token, i int) string {
+	if i >= len(tokens) {
+		return ""
+	}
+	if tokens[i].text == "not" && i+1 < len(tokens) {
+		i++
+	}
+	switch tokens[i].text {
+	case "!=", "<>":
+		return ""
+	}
+	return tokens[i].text
+}
+
+// tableAliases maps the names the statement uses for model tables, both their
+// aliases and their own names, to the table
+func tableAliases(tokens []token, known map[string]*knownTable) map[string]string {
+	aliases := make(map[string]string)
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "from", "join", "update":
+		default:
+			continue
+		}
+
+		for {
+			i++
+			if i >= len(tokens) || tokens[i].kind != tokenWord || stopWords[tokens[i].text] {
+				break
+			}
+
+			table := tokens[i].text
+			if _, name, qualified := strings.Cut(table, "."); qualified {
+				table = name
+			}
+			if i+1 < len(tokens) && tokens[i+1].text == "as" {
+				i++
+			}
+			alias := table
+			if i+1 < len(tokens) && tokens[i+1].kind == tokenWord && !stopWords[tokens[i+1].text] {
+				i++
+				alias = tokens[i].text
+			}
+			if _, ok := known[table]; ok {
+				aliases[table] = table
+				aliases[alias] = table
+			}
+
+			if i+1 >= len(tokens) || tokens[i+1].kind != tokenComma {
+				break
+			}
+			i++
+		}
+	}
+
+	return aliases
+}
@@ -0,0 +1,128 @@
+package analyze
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testModels = "package models\n\n" +
+	"type User struct {\n" +
+	"\t_ struct{} `storm:\"table:users;index:idx_users_status,status\"`\n" +
+	"\tID        string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+	"\tEmail     string `db:\"email\" storm:\"type:text;unique\"`\n" +
+	"\tStatus    string `db:\"status\" storm:\"type:text\"`\n" +
+	"\tTeamID    string `db:\"team_id\" storm:\"type:uuid\"`\n" +
+	"\tCreatedAt string `db:\"created_at\" storm:\"type:timestamptz\"`\n" +
+	"}\n\n" +
+	"type Post struct {\n" +
+	"\t_ struct{} `storm:\"table:posts\"`\n" +
+	"\tID       string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+	"\tAuthorID string `db:\"author_id\" storm:\"type:uuid\"`\n" +
+	"\tStatus   string `db:\"status\" storm:\"type:text\"`\n" +
+	"}\n"
+
+func loadModels(t *testing.T) []parser.TableDefinition {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(testModels), 0644))
+	tables, err := parser.NewStructParser().ParseDirectory(dir)
+	require.NoError(t, err)
+	return tables
+}
+
+func TestIndexColumns(t *testing.T) {
+	known, err := loadTables(loadModels(t))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		query string
+		want  map[string][]string
+	}{
+		{
+			name:  "equality then sort",
+			query: "SELECT id, email FROM users WHERE (team_id = $1 AND status IN ($2, $3)) ORDER BY created_at DESC LIMIT $4",
+			want:  map[string][]string{"users": {"team_id", "status", "created_at"}},
+		},
+		{
+			name:  "range filter replaces the sort",
+			query: "SELECT * FROM users u WHERE u.team_id = $1 AND u.created_at >= $2 ORDER BY u.email",
+			want:  map[string][]string{"users": {"team_id", "created_at"}},
+		},
+		{
+			name:  "join columns",
+			query: `SELECT p.* FROM posts AS p INNER JOIN users ON users.id = p.author_id WHERE users.status = $1`,
+			want:  map[string][]string{"posts": {"author_id"}, "users": {"id", "status"}},
+		},
+		{
+			name:  "ambiguous columns are skipped",
+			query: "SELECT * FROM posts, users WHERE status = $1 AND team_id = $2",
+			want:  map[string][]string{"users": {"team_id"}},
+		},
+		{
+			name:  "update assignments are not filters",
+			query: "UPDATE users SET status = $1 WHERE email = $2",
+			want:  map[string][]string{"users": {"email"}},
+		},
+		{
+			name:  "functions, literals and unknown tables",
+			query: "SELECT * FROM users WHERE lower(email) = $1 AND 'x' = $2; SELECT * FROM audit WHERE actor = $3",
+			want:  map[string][]string{},
+		},
+		{
+			name:  "inserts are ignored",
+			query: "INSERT INTO users (id, email) VALUES ($1, $2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, indexColumns(tt.query, known))
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	statements := []Statement{
+		{Query: "SELECT * FROM posts WHERE author_id = $1 AND status = $2", Calls: 10, TotalTime: 50},
+		{Query: "SELECT * FROM users WHERE team_id = $1 ORDER BY created_at DESC", Calls: 500, TotalTime: 900},
+		{Query: "SELECT * FROM users WHERE email = $1", Calls: 9000, TotalTime: 4000},
+		{Query: "SELECT * FROM users WHERE status = $1", Calls: 100, TotalTime: 300},
+		{Query: "DELETE FROM posts WHERE author_id = $1 AND status = $2", Calls: 5, TotalTime: 70},
+	}
+
+	suggestions, err := Suggest(statements, loadModels(t))
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2, "email and status are already indexed")
+
+	users := suggestions[0]
+	assert.Equal(t, "users", users.Table)
+	assert.Equal(t, "User", users.Struct)
+	assert.Equal(t, []string{"team_id", "created_at"}, users.Columns)
+	assert.Equal(t, "index:idx_users_team_id_created_at,team_id,created_at", users.Tag())
+	assert.Equal(t, `CREATE INDEX CONCURRENTLY IF NOT EXISTS "idx_users_team_id_created_at" ON "users" ("team_id", "created_at");`, users.SQL())
+	assert.Equal(t, `DROP INDEX CONCURRENTLY IF EXISTS "idx_users_team_id_created_at";`, users.DropSQL())
+
+	posts := suggestions[1]
+	assert.Equal(t, []string{"author_id", "status"}, posts.Columns)
+	assert.Equal(t, int64(15), posts.Calls)
+	assert.Equal(t, float64(120), posts.TotalTime)
+	assert.Len(t, posts.Queries, 2)
+}
+
+func TestTokenize(t *testing.T) {
+	tokens := tokenize(`SELECT "Users"."Email", u.id FROM users u WHERE name = 'it''s' -- trailing`)
+
+	var words []string
+	for _, tok := range tokens {
+		if tok.kind == tokenWord {
+			words = append(words, tok.text)
+		}
+	}
+	assert.Equal(t, []string{"select", "Users.Email", "u.id", "from", "users", "u", "where", "name"}, words)
+	assert.Equal(t, tokenValue, tokens[len(tokens)-1].kind)
+}
@@ -0,0 +1,123 @@
+package analyze
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenWord  tokenKind = iota // Keyword or identifier, possibly qualified
+	tokenValue                  // Literal or $n parameter
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string // Words are lower-cased unless they were quoted
+}
+
+// tokenize splits a normalized statement into the tokens the analyzer needs.
+// Qualified names such as u.email and "Users"."Email" become a single word.
+func tokenize(query string) []token {
+	var tokens []token
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\'':
+			i = skipQuoted(runes, i, '\'')
+			tokens = append(tokens, token{kind: tokenValue})
+		case r == '$' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]):
+			i++
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenValue})
+		case unicode.IsDigit(r):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenValue})
+		case r == '"' || isIdentStart(r):
+			var parts []string
+			for {
+				part, next := readIdentifier(runes, i)
+				parts = append(parts, part)
+				i = next
+				if i+1 < len(runes) && runes[i] == '.' && (runes[i+1] == '"' || isIdentStart(runes[i+1])) {
+					i++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: strings.Join(parts, ".")})
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ","})
+			i++
+		default:
+			start := i
+			for i < len(runes) && strings.ContainsRune("=<>!~*+-/%|&:^@#", runes[i]) {
+				i++
+			}
+			if i == start {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: string(runes[start:i])})
+		}
+	}
+
+	return tokens
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// readIdentifier reads a bare or double-quoted identifier starting at i
+func readIdentifier(runes []rune, i int) (string, int) {
+	if runes[i] == '"' {
+		end := skipQuoted(runes, i, '"')
+		name := string(runes[i+1 : end-1])
+		return strings.ReplaceAll(name, `""`, `"`), end
+	}
+
+	start := i
+	for i < len(runes) && (runes[i] == '_' || runes[i] == '$' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+		i++
+	}
+	return strings.ToLower(string(runes[start:i])), i
+}
+
+// skipQuoted returns the index after the quoted section starting at i, where a
+// doubled quote is part of the content
+func skipQuoted(runes []rune, i int, quote rune) int {
+	for i++; i < len(runes); i++ {
+		if runes[i] != quote {
+			continue
+		}
+		if i+1 < len(runes) && runes[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(runes)
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/analyze"
+	"github.com/eleven-am/storm/internal/parser"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyzePackage   string
+	analyzeLimit     int
+	analyzeMigration bool
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Suggest schema changes from database usage",
+}
+
+var analyzeQueryLogCmd = &cobra.Command{
+	Use:   "query-log",
+	Short: "Suggest indexes from pg_stat_statements",
+	Long: `Read the heaviest statements from pg_stat_statements, match their filters and
+sorts against the models, and suggest the indexes they are missing.
+
+Statements are weighed by their total execution time. Filters already served by
+an index, primary key or unique constraint of the model are skipped.
+
+Each suggestion is printed as the table tag attribute that declares it on the
+model. With --migration, a draft migration creating the indexes CONCURRENTLY is
+written to the migrations directory instead.
+
+The pg_stat_statements extension must be installed in the database.`,
+	RunE: runAnalyzeQueryLog,
+}
+
+func init() {
+	analyzeQueryLogCmd.Flags().StringVar(&analyzePackage, "package", "", "Path to package containing models")
+	analyzeQueryLogCmd.Flags().IntVar(&analyzeLimit, "limit", 50, "Number of statements to analyze, heaviest first")
+	analyzeQueryLogCmd.Flags().BoolVar(&analyzeMigration, "migration", false, "Write the suggestions as a draft migration")
+	analyzeQueryLogCmd.Flags().StringVar(&outputDir, "output", "", "Output directory for the draft migration")
+	analyzeCmd.AddCommand(analyzeQueryLogCmd)
+}
+
+func runAnalyzeQueryLog(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+	if analyzePackage == "" && stormConfig != nil {
+		analyzePackage = stormConfig.Models.Package
+	}
+	if analyzePackage == "" {
+		analyzePackage = "./models"
+	}
+
+	tables, err := parser.NewStructParser().ParseDirectory(analyzePackage)
+	if err != nil {
+		return fmt.Errorf("failed to parse models: %w", err)
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	statements, err := analyze.LoadStatements(ctx, db, analyzeLimit)
+	if err != nil {
+		return err
+	}
+
+	suggestions, err := analyze.Suggest(statements, tables)
+	if err != nil {
+		return err
+	}
+
+	if len(suggestions) == 0 {
+		cmd.Printf("No missing indexes found in the %d heaviest statements\n", len(statements))
+		return nil
+	}
+
+	if analyzeMigration {
+		return writeIndexMigration(cmd, suggestions)
+	}
+
+	for _, suggestion := range suggestions {
+		cmd.Printf("%s (%s): %d calls, %.0fms total\n", suggestion.Table, suggestion.Struct, suggestion.Calls, suggestion.TotalTime)
+		cmd.Printf("  add to the table tag: %s\n", suggestion.Tag())
+		cmd.Printf("  e.g. %s\n\n", oneLine(suggestion.Queries[0]))
+	}
+	return nil
+}
+
+// writeIndexMigration writes the suggestions as up and down migration files
+func writeIndexMigration(cmd *cobra.Command, suggestions []analyze.Suggestion) error {
+	if outputDir == "" && stormConfig != nil {
+		outputDir = stormConfig.Migrations.Directory
+	}
+	if outputDir == "" {
+		outputDir = "./migrations"
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var up, down strings.Builder
+	header := fmt.Sprintf("-- Migration: suggested_indexes\n-- Created at: %s\n-- Draft from storm analyze query-log; review before applying.\n-- CONCURRENTLY cannot run inside a transaction.\n\n", time.Now().Format(time.RFC3339))
+	up.WriteString(header)
+	down.WriteString(header)
+	for _, suggestion := range suggestions {
+		fmt.Fprintf(&up, "-- %d calls, %.0fms total, e.g. %s\n%s\n\n", suggestion.Calls, suggestion.TotalTime, oneLine(suggestion.Queries[0]), suggestion.SQL())
+		fmt.Fprintf(&down, "%s\n", suggestion.DropSQL())
+	}
+
+	baseName := fmt.Sprintf("%s_suggested_indexes", time.Now().UTC().Format("20060102150405"))
+	upFile := filepath.Join(outputDir, baseName+".up.sql")
+	downFile := filepath.Join(outputDir, baseName+".down.sql")
+	if err := os.WriteFile(upFile, []byte(up.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write UP migration: %w", err)
+	}
+	if err := os.WriteFile(downFile, []byte(down.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write DOWN migration: %w", err)
+	}
+
+	cmd.Printf("Wrote %d suggested index(es):\n  UP:   %s\n  DOWN: %s\n", len(suggestions), upFile, downFile)
+	return nil
+}
+
+// oneLine collapses the whitespace of a statement so it fits a comment
+func oneLine(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	formatText   = "text"
+	formatGitHub = "github"
+)
+
+// annotation is a problem reported as a GitHub Actions workflow command. When it
+// has a file and line, GitHub shows it inline on the pull request diff.
+type annotation struct {
+	Level   string // error, warning or notice
+	Pos     token.Position
+	Title   string
+	Message string
+}
+
+func (a annotation) String() string {
+	var properties []string
+	if a.Pos.Filename != "" {
+		properties = append(properties, "file="+escapeProperty(annotationPath(a.Pos.Filename)))
+		if a.Pos.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", a.Pos.Line))
+		}
+		if a.Pos.Column > 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", a.Pos.Column))
+		}
+	}
+	if a.Title != "" {
+		properties = append(properties, "title="+escapeProperty(a.Title))
+	}
+
+	command := "::" + a.Level
+	if len(properties) > 0 {
+		command += " " + strings.Join(properties, ",")
+	}
+	return command + "::" + escapeData(a.Message)
+}
+
+// addFormatFlag adds the --format flag choosing between plain text and GitHub Actions
+// annotations
+func addFormatFlag(cmd *cobra.Command, format *string) {
+	cmd.Flags().StringVar(format, "format", formatText, "Output format: text, or github for GitHub Actions annotations")
+}
+
+func validateFormat(format string) error {
+	switch format {
+	case formatText, formatGitHub:
+		return nil
+	}
+	return fmt.Errorf("unsupported format %q: use text or github", format)
+}
+
+// annotationPath makes a path relative to the repository, as GitHub expects it
+func annotationPath(path string) string {
+	if filepath.IsAbs(path) {
+		root := os.Getenv("GITHUB_WORKSPACE")
+		if root == "" {
+			root, _ = os.Getwd()
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
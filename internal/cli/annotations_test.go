@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"go/token"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/lint"
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func TestAnnotation_String(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation annotation
+		want       string
+	}{
+		{
+			name: "with position",
+			annotation: annotation{
+				Level:   "error",
+				Pos:     token.Position{Filename: "models/user.go", Line: 12, Column: 2},
+				Title:   "storm lint",
+				Message: "User.Email: unknown attribute \"uniq\"",
+			},
+			want: `::error file=models/user.go,line=12,col=2,title=storm lint::User.Email: unknown attribute "uniq"`,
+		},
+		{
+			name:       "escaped",
+			annotation: annotation{Level: "warning", Title: "a, b: c", Message: "100%\nnext"},
+			want:       "::warning title=a%2C b%3A c::100%25%0Anext",
+		},
+		{
+			name:       "bare",
+			annotation: annotation{Level: "notice", Message: "done"},
+			want:       "::notice::done",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.annotation.String(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotationPath(t *testing.T) {
+	t.Setenv("GITHUB_WORKSPACE", "/home/runner/work/app")
+
+	if got := annotationPath("/home/runner/work/app/models/user.go"); got != "models/user.go" {
+		t.Errorf("got %s", got)
+	}
+	if got := annotationPath("./models/user.go"); got != "models/user.go" {
+		t.Errorf("got %s", got)
+	}
+	if got := annotationPath("/elsewhere/user.go"); got != "/elsewhere/user.go" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestLintAnnotation(t *testing.T) {
+	issue := lint.Issue{Pos: token.Position{Filename: "models/post.go", Line: 7}, Struct: "Post", Field: "AuthorID", Message: "foreign key references unknown table authors"}
+
+	want := "::error file=models/post.go,line=7,title=storm lint::Post.AuthorID: foreign key references unknown table authors"
+	if got := lintAnnotation(issue).String(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestDiffAnnotation(t *testing.T) {
+	positions := modelPositions([]parser.TableDefinition{{
+		TableName: "users",
+		Pos:       token.Position{Filename: "models/user.go", Line: 5},
+		Fields: []parser.FieldDefinition{
+			{Name: "Email", DBName: "email", Pos: token.Position{Filename: "models/user.go", Line: 8}},
+		},
+	}})
+
+	tests := []struct {
+		difference generator.SchemaDifference
+		want       string
+	}{
+		{
+			generator.SchemaDifference{Table: "users", Column: "email", Message: "type differs"},
+			"::error file=models/user.go,line=8,title=storm diff::users.email: type differs",
+		},
+		{
+			generator.SchemaDifference{Table: "users", Column: "nickname", Message: "column dropped"},
+			"::error file=models/user.go,line=5,title=storm diff::users.nickname: column dropped",
+		},
+		{
+			generator.SchemaDifference{Table: "audit_log", Message: "table dropped"},
+			"::error title=storm diff::audit_log: table dropped",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := diffAnnotation(tt.difference, positions).String(); got != tt.want {
+			t.Errorf("got  %s\nwant %s", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backfillTable     string
+	backfillKey       string
+	backfillWhere     string
+	backfillSQL       string
+	backfillSQLFile   string
+	backfillBatchSize int
+	backfillThrottle  time.Duration
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Run and monitor resumable data backfills",
+	Long: `Backfills rewrite the data of a table in batches of keys, the data counterpart of
+schema-only migrations. The last key of each batch is checkpointed in
+` + orm.BackfillsTable + `, so an interrupted backfill resumes where it stopped.`,
+}
+
+var backfillRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a SQL backfill to the end",
+	Long: `Walk --table in order of --key and run the statement for each batch of keys,
+with $1 and $2 bound to the first and last key of the batch:
+
+  storm backfill run lower-emails --table users \
+    --sql 'UPDATE users SET email = lower(email) WHERE id BETWEEN $1 AND $2' \
+    --where 'email <> lower(email)' --batch-size 5000 --throttle 200ms
+
+Each batch runs in its own transaction together with its checkpoint. Running
+the same name again resumes after the last checkpoint; a backfill that is done
+does nothing. Interrupting it with Ctrl-C rolls back the current batch only.
+
+Backfills written in Go use Storm.RunBackfill, which shares the checkpoints.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBackfillRun,
+}
+
+var backfillStatusCmd = &cobra.Command{
+	Use:   "status [name]",
+	Short: "Show the progress of backfills",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runBackfillStatus,
+}
+
+var backfillResetCmd = &cobra.Command{
+	Use:   "reset <name>",
+	Short: "Delete the checkpoint of a backfill, so it runs again from the start",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBackfillReset,
+}
+
+func init() {
+	backfillRunCmd.Flags().StringVar(&backfillTable, "table", "", "Table to walk")
+	backfillRunCmd.Flags().StringVar(&backfillKey, "key", "id", "Unique column to walk in order")
+	backfillRunCmd.Flags().StringVar(&backfillWhere, "where", "", "SQL condition limiting the rows walked")
+	backfillRunCmd.Flags().StringVar(&backfillSQL, "sql", "", "Statement to run for each batch, with $1 and $2 the first and last key")
+	backfillRunCmd.Flags().StringVar(&backfillSQLFile, "sql-file", "", "File holding the statement to run for each batch")
+	backfillRunCmd.Flags().IntVar(&backfillBatchSize, "batch-size", 1000, "Keys per batch")
+	backfillRunCmd.Flags().DurationVar(&backfillThrottle, "throttle", 0, "Pause between batches")
+
+	backfillCmd.AddCommand(backfillRunCmd)
+	backfillCmd.AddCommand(backfillStatusCmd)
+	backfillCmd.AddCommand(backfillResetCmd)
+}
+
+// openBackfillStorm connects to --url for the backfill commands
+func openBackfillStorm() (*orm.Storm, func(), error) {
+	if databaseURL == "" {
+		return nil, nil, fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	db, err := sqlx.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return orm.NewStorm(db), func() { db.Close() }, nil
+}
+
+func runBackfillRun(cmd *cobra.Command, args []string) error {
+	statement := backfillSQL
+	if backfillSQLFile != "" {
+		if statement != "" {
+			return fmt.Errorf("use either --sql or --sql-file")
+		}
+		content, err := os.ReadFile(backfillSQLFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", backfillSQLFile, err)
+		}
+		statement = string(content)
+	}
+	if statement == "" {
+		return fmt.Errorf("statement required: use --sql or --sql-file")
+	}
+	if backfillTable == "" {
+		return fmt.Errorf("table required: use --table")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	storm, closeDB, err := openBackfillStorm()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	status, err := storm.RunBackfill(ctx, orm.Backfill{
+		Name:      args[0],
+		Table:     backfillTable,
+		Key:       backfillKey,
+		Where:     backfillWhere,
+		BatchSize: backfillBatchSize,
+		Throttle:  backfillThrottle,
+		Batch: func(ctx context.Context, tx *sqlx.Tx, batch orm.BackfillBatch) error {
+			_, err := tx.ExecContext(ctx, statement, batch.From, batch.To)
+			return err
+		},
+		Progress: func(status orm.BackfillStatus) {
+			if status.Status == orm.BackfillRunning {
+				cmd.Printf("%s: %d rows, last key %s\n", status.Name, status.RowsDone, status.LastKey.String)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("Backfill %s is done: %d rows\n", status.Name, status.RowsDone)
+	return nil
+}
+
+func runBackfillStatus(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	storm, closeDB, err := openBackfillStorm()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	statuses, err := storm.Backfills(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "NAME\tTABLE\tSTATUS\tROWS\tLAST KEY\tUPDATED\tERROR")
+	found := false
+	for _, status := range statuses {
+		if len(args) > 0 && status.Name != args[0] {
+			continue
+		}
+		found = true
+		fmt.Fprintf(out, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", status.Name, status.Table, status.Status, status.RowsDone,
+			status.LastKey.String, status.UpdatedAt.Format(time.RFC3339), status.Error.String)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 && !found {
+		return fmt.Errorf("no backfill named %s", args[0])
+	}
+	return nil
+}
+
+func runBackfillReset(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	storm, closeDB, err := openBackfillStorm()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if err := storm.ResetBackfill(ctx, args[0]); err != nil {
+		return err
+	}
+
+	cmd.Printf("Reset backfill %s\n", args[0])
+	return nil
+}
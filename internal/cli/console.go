@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/eleven-am/storm/internal/console"
+	"github.com/eleven-am/storm/internal/logger"
+	"github.com/eleven-am/storm/internal/parser"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consoleSchema  string
+	consolePackage string
+)
+
+var consoleCmd = &cobra.Command{
+	Use:   "console",
+	Short: "Interactive SQL and query console",
+	Long: `Open an interactive console on the database configured in storm.yaml, or given
+with --url, for quick data inspection.
+
+The console reads the tables and columns of the database, and the models when
+the models package can be parsed, and completes their names with Tab. It runs:
+- SQL statements, ended with a semicolon
+- Query expressions written like the generated query builders, e.g.
+  Users.Query().Where(Users.Email.Like("%@example.com")).Limit(10)
+
+Type \? in the console for the list of commands. When input is piped, the
+console reads statements from it without prompting.`,
+	RunE: runConsole,
+}
+
+func init() {
+	consoleCmd.Flags().StringVarP(&consoleSchema, "schema", "s", "public", "Database schema to inspect")
+	consoleCmd.Flags().StringVar(&consolePackage, "package", "", "Path to package containing models")
+}
+
+func runConsole(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	catalog, err := console.LoadCatalog(ctx, db, consoleSchema)
+	if err != nil {
+		return err
+	}
+
+	if consolePackage == "" && stormConfig != nil {
+		consolePackage = stormConfig.Models.Package
+	}
+	if consolePackage == "" {
+		consolePackage = "./models"
+	}
+	if models, err := parser.NewStructParser().ParseDirectory(consolePackage); err == nil {
+		catalog.AddModels(models)
+	} else {
+		logger.CLI().Debug("Completing without model names, failed to parse %s: %v", consolePackage, err)
+	}
+
+	repl := console.New(db, catalog, os.Stdout)
+	if isTerminal(os.Stdin) {
+		return repl.RunTerminal(ctx, os.Stdin, os.Stdout)
+	}
+	return repl.Run(ctx, os.Stdin)
+}
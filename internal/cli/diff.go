@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"go/token"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffPackage string
+	diffFormat  string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <schema.sql|migrations-dir> [other.sql|other-dir]",
+	Short: "Compare a SQL schema with models or another SQL schema",
+	Long: `Parse a schema.sql file, or a directory of migrations replayed in file name order
+(*.down.sql files are skipped), and compare it with your Go models. When a second
+path is given, the two SQL schemas are compared with each other instead. No database
+connection is needed, which makes this suitable for CI.
+
+Differences are reported for:
+- Added and dropped tables and columns
+- Column type, nullability, default, primary key and foreign key changes
+- Unique constraints and indexes
+- Enum types and their values, flagging removed or reordered values as unsafe
+- Views and materialized views, by their normalized definition
+- Functions and triggers, by their normalized definition, when comparing two schemas
+- Sequence options (type, increment, bounds, start, cache, cycle) and the column
+  owning each sequence, when comparing two schemas
+
+Objects listed under migrations.ignore in storm.yaml are left out of the comparison.
+
+With --format=github, differences are printed as GitHub Actions annotations. When
+comparing with models, each points at the struct or field it concerns.
+
+Returns exit code 0 if the schemas match, 1 otherwise.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffPackage, "package", "", "Path to package containing models")
+	addFormatFlag(diffCmd, &diffFormat)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if err := validateFormat(diffFormat); err != nil {
+		return err
+	}
+
+	from, err := generator.NewSQLSchemaParser().ParsePath(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", args[0], err)
+	}
+
+	var to *generator.DatabaseSchema
+	var positions map[string]token.Position
+	target := ""
+	if len(args) == 2 {
+		target = args[1]
+		to, err = generator.NewSQLSchemaParser().ParsePath(target)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", target, err)
+		}
+	} else {
+		if diffPackage == "" && stormConfig != nil && stormConfig.Models.Package != "" {
+			diffPackage = stormConfig.Models.Package
+		}
+		if diffPackage == "" {
+			diffPackage = "./models"
+		}
+		target = diffPackage
+
+		tables, err := parser.NewStructParser().ParseDirectory(diffPackage)
+		if err != nil {
+			return fmt.Errorf("failed to parse models: %w", err)
+		}
+		positions = modelPositions(tables)
+		schemaGenerator := generator.NewSchemaGenerator()
+		if stormConfig != nil {
+			schemaGenerator.SetForeignKeyConventions(generator.ForeignKeyConventions{
+				OnDelete: stormConfig.Schema.ForeignKeys.OnDelete,
+				OnUpdate: stormConfig.Schema.ForeignKeys.OnUpdate,
+				Naming:   stormConfig.Schema.ForeignKeys.Naming,
+			})
+		}
+		to, err = schemaGenerator.GenerateSchema(tables)
+		if err != nil {
+			return fmt.Errorf("failed to generate schema from models: %w", err)
+		}
+		// Models declare no functions, triggers or sequences, so those of the schema are not compared
+		from.Functions, from.Triggers, from.Sequences = nil, nil, nil
+	}
+
+	differences := generator.CompareSchemas(from, to)
+	if stormConfig != nil {
+		differences = withoutIgnored(differences, stormConfig.Migrations.Ignore)
+	}
+	for _, difference := range differences {
+		if diffFormat == formatGitHub {
+			cmd.Println(diffAnnotation(difference, positions))
+		} else {
+			cmd.Println(difference.String())
+		}
+	}
+
+	if len(differences) > 0 {
+		return fmt.Errorf("%s differs from %s in %d place(s)", target, args[0], len(differences))
+	}
+
+	cmd.Printf("%s matches %s\n", target, args[0])
+	return nil
+}
+
+// modelPositions maps tables and table.column names to where their model and field
+// are declared
+func modelPositions(tables []parser.TableDefinition) map[string]token.Position {
+	positions := make(map[string]token.Position)
+	for _, table := range tables {
+		positions[table.TableName] = table.Pos
+		for _, field := range table.Fields {
+			if field.IsColumn() {
+				positions[table.TableName+"."+field.DBName] = field.Pos
+			}
+		}
+	}
+	return positions
+}
+
+// diffAnnotation reports a difference on the field or model it concerns. A table
+// missing from the models has no position and is reported without one.
+func diffAnnotation(difference generator.SchemaDifference, positions map[string]token.Position) annotation {
+	pos, ok := positions[difference.Table+"."+difference.Column]
+	if !ok || difference.Column == "" {
+		pos = positions[difference.Table]
+	}
+	return annotation{
+		Level:   "error",
+		Pos:     pos,
+		Title:   "storm diff",
+		Message: difference.String(),
+	}
+}
+
+// withoutIgnored drops the differences in objects the ignore rules leave to other tools
+func withoutIgnored(differences []generator.SchemaDifference, rules migrator.IgnoreRules) []generator.SchemaDifference {
+	kept := differences[:0]
+	for _, difference := range differences {
+		switch {
+		case difference.Table != "" && rules.IgnoresTable("", difference.Table),
+			difference.Column != "" && rules.IgnoresColumn(difference.Table, difference.Column),
+			difference.Index != "" && rules.IgnoresIndex(difference.Index):
+			continue
+		}
+		kept = append(kept, difference)
+	}
+	return kept
+}
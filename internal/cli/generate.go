@@ -35,7 +35,7 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to resolve package path: %w", err)
 	}
 
-	fmt.Printf("Parsing structs from: %s\n", absPath)
+	status("Parsing structs from: %s\n", absPath)
 
 	config := storm.NewConfig()
 	config.ModelsPackage = absPath
@@ -49,6 +49,10 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 
 	ctx := context.Background()
 
+	prog := newProgress()
+	defer prog.Close()
+	prog.Start("Generating schema")
+
 	schemaSQL, err := stormClient.Schema().ExportSQL(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to generate schema SQL: %w", err)
@@ -63,7 +67,8 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to write SQL file: %w", err)
 	}
+	prog.Done()
 
-	fmt.Printf("Schema written to: %s\n", outputPath)
+	status("Schema written to: %s\n", outputPath)
 	return nil
 }
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/eleven-am/storm/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintPackage string
+	lintFormat  string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Validate model tags",
+	Long: `Statically validate the dbdef, storm and orm tags of your Go models without
+touching a database. Intended for CI.
+
+This command checks for:
+- Unknown tag attributes
+- Foreign keys to nonexistent tables or columns
+- Duplicate index names
+- Relationship targets that don't exist
+- Models without a primary key
+
+With --format=github, issues are printed as GitHub Actions annotations so they
+show up on the lines of the pull request that caused them.
+
+Returns exit code 0 if no issues are found, 1 otherwise.`,
+	RunE: runLint,
+}
+
+func init() {
+	lintCmd.Flags().StringVar(&lintPackage, "package", "", "Path to package containing models")
+	addFormatFlag(lintCmd, &lintFormat)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	if err := validateFormat(lintFormat); err != nil {
+		return err
+	}
+	if lintPackage == "" && stormConfig != nil && stormConfig.Models.Package != "" {
+		lintPackage = stormConfig.Models.Package
+	}
+	if lintPackage == "" {
+		lintPackage = "./models"
+	}
+
+	issues, err := lint.NewLinter().LintDirectory(lintPackage)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		if lintFormat == formatGitHub {
+			cmd.Println(lintAnnotation(issue))
+		} else {
+			cmd.Println(issue.String())
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("lint found %d issue(s) in %s", len(issues), lintPackage)
+	}
+
+	cmd.Printf("No issues found in %s\n", lintPackage)
+	return nil
+}
+
+func lintAnnotation(issue lint.Issue) annotation {
+	location := issue.Struct
+	if issue.Field != "" {
+		location += "." + issue.Field
+	}
+	return annotation{
+		Level:   "error",
+		Pos:     issue.Pos,
+		Title:   "storm lint",
+		Message: location + ": " + issue.Message,
+	}
+}
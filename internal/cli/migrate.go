@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/eleven-am/storm/internal/generator"
 	"github.com/eleven-am/storm/internal/logger"
 	"github.com/eleven-am/storm/internal/migrator"
 	"github.com/eleven-am/storm/pkg/storm"
@@ -30,6 +31,12 @@ var (
 	createDBIfNotExists bool
 	allowDestructive    bool
 	pushToDB            bool
+	strictMode          bool
+	preserveData        string
+	retentionPeriod     string
+	concurrentIndexes   bool
+	safeConstraints     bool
+	analyzeTables       bool
 )
 
 var migrateCmd = &cobra.Command{
@@ -55,26 +62,33 @@ func init() {
 	migrateCmd.Flags().BoolVar(&createDBIfNotExists, "create-if-not-exists", false, "Create the database if it does not exist")
 	migrateCmd.Flags().BoolVar(&allowDestructive, "allow-destructive", false, "Allow potentially destructive operations")
 	migrateCmd.Flags().BoolVar(&pushToDB, "push", false, "Execute the generated SQL directly on the database")
+	migrateCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on unknown tag attributes and Go types instead of warning")
+	migrateCmd.Flags().StringVar(&preserveData, "preserve-data", "", "Keep the data of dropped tables and columns (rename, archive)")
+	migrateCmd.Flags().BoolVar(&concurrentIndexes, "concurrent-indexes", false, "Build and drop indexes of existing tables with CONCURRENTLY")
+	migrateCmd.Flags().BoolVar(&safeConstraints, "safe-constraints", false, "Add constraints to existing tables NOT VALID and validate them in a separate step")
+	migrateCmd.Flags().BoolVar(&analyzeTables, "analyze", false, "ANALYZE existing tables that get indexes, filled or retyped columns, or updated rows")
+	migrateCmd.Flags().StringVar(&retentionPeriod, "retention-period", "", "Drop preserved tables and columns once they are this old (e.g. 30d, 72h)")
 }
 
 func runMigrate(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	if stormConfig != nil {
-		if outputDir == "" && stormConfig.Migrations.Directory != "" {
-			outputDir = stormConfig.Migrations.Directory
-		}
-		if migratePackagePath == "" && stormConfig.Models.Package != "" {
-			migratePackagePath = stormConfig.Models.Package
-		}
+	applyMigrateDefaults(cmd)
+	opts, err := migrateOptions()
+	if err != nil {
+		return err
 	}
 
-	if outputDir == "" {
-		outputDir = "./migrations"
+	selectedDialect, err := databaseDialect()
+	if err != nil {
+		return err
 	}
-	if migratePackagePath == "" {
-		migratePackagePath = "./models"
+	if selectedDialect == "sqlite" {
+		if preserveData != "" {
+			return fmt.Errorf("data preservation is not supported for SQLite")
+		}
+		return runSQLiteMigrate(ctx, cmd)
 	}
 
 	var dsn string
@@ -124,17 +138,14 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 
 	logger.CLI().Info("Generating migration...")
 
-	opts := storm.MigrateOptions{
-		PackagePath:         migratePackagePath,
-		OutputDir:           outputDir,
-		DryRun:              dryRun,
-		CreateDBIfNotExists: createDBIfNotExists,
-	}
-
 	if pushToDB {
+		if err := checkForbidUnsafe(allowDestructive); err != nil {
+			return err
+		}
+
 		// Direct push - generate and apply migration directly to database
 		logger.CLI().Info("Generating and applying migration directly to database...")
-		return executePushMigration(ctx, config, createDBIfNotExists, allowDestructive, migratePackagePath)
+		return executePushMigration(ctx, config, opts, allowDestructive)
 	}
 
 	// Generate migration files only (no push)
@@ -152,6 +163,81 @@ func runMigrate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// applyMigrateDefaults fills the migration flags that were not given from storm.yaml
+func applyMigrateDefaults(cmd *cobra.Command) {
+	if stormConfig != nil {
+		if outputDir == "" && stormConfig.Migrations.Directory != "" {
+			outputDir = stormConfig.Migrations.Directory
+		}
+		if migratePackagePath == "" && stormConfig.Models.Package != "" {
+			migratePackagePath = stormConfig.Models.Package
+		}
+		if !cmd.Flags().Changed("strict") && stormConfig.Schema.StrictMode {
+			strictMode = stormConfig.Schema.StrictMode
+		}
+		if preserveData == "" && stormConfig.Migrations.DataPreservation != "" {
+			preserveData = stormConfig.Migrations.DataPreservation
+		}
+		if !cmd.Flags().Changed("concurrent-indexes") && stormConfig.Migrations.ConcurrentIndexes {
+			concurrentIndexes = true
+		}
+		if !cmd.Flags().Changed("safe-constraints") && stormConfig.Migrations.SafeConstraints {
+			safeConstraints = true
+		}
+		if !cmd.Flags().Changed("analyze") && stormConfig.Migrations.Analyze {
+			analyzeTables = true
+		}
+		if retentionPeriod == "" && stormConfig.Migrations.RetentionPeriod != "" {
+			retentionPeriod = stormConfig.Migrations.RetentionPeriod
+		}
+	}
+
+	if outputDir == "" {
+		outputDir = "./migrations"
+	}
+	if migratePackagePath == "" {
+		migratePackagePath = "./models"
+	}
+}
+
+// migrateOptions validates the migration flags and returns them with the
+// schema conventions and ignore rules of storm.yaml
+func migrateOptions() (storm.MigrateOptions, error) {
+	if _, err := migrator.ParseDataPreservation(preserveData); err != nil {
+		return storm.MigrateOptions{}, err
+	}
+	retention, err := migrator.ParseRetentionPeriod(retentionPeriod)
+	if err != nil {
+		return storm.MigrateOptions{}, err
+	}
+	if stormConfig != nil {
+		if err := stormConfig.Migrations.Ignore.Validate(); err != nil {
+			return storm.MigrateOptions{}, err
+		}
+	}
+
+	opts := storm.MigrateOptions{
+		PackagePath:         migratePackagePath,
+		OutputDir:           outputDir,
+		DryRun:              dryRun,
+		CreateDBIfNotExists: createDBIfNotExists,
+		Strict:              strictMode,
+		DataPreservation:    preserveData,
+		RetentionPeriod:     retention,
+		ConcurrentIndexes:   concurrentIndexes,
+		SafeConstraints:     safeConstraints,
+		Analyze:             analyzeTables,
+	}
+	if stormConfig != nil {
+		opts.ForeignKeyOnDelete = stormConfig.Schema.ForeignKeys.OnDelete
+		opts.ForeignKeyOnUpdate = stormConfig.Schema.ForeignKeys.OnUpdate
+		opts.ForeignKeyNaming = stormConfig.Schema.ForeignKeys.Naming
+		opts.Ignore = storm.IgnoreRules(stormConfig.Migrations.Ignore)
+		opts.UpdatedAtTriggers = stormConfig.Schema.UpdatedAtTriggers
+	}
+	return opts, nil
+}
+
 // ensureDatabaseExistsFromURL creates the database if it doesn't exist
 func ensureDatabaseExistsFromURL(ctx context.Context, databaseURL string) error {
 	dbName := extractDatabaseNameFromURL(databaseURL)
@@ -236,43 +322,168 @@ func quoteIdentifierCLI(name string) string {
 }
 
 // executePushMigration executes migration directly using Atlas migrator
-func executePushMigration(ctx context.Context, config *storm.Config, createDBIfNotExists bool, allowDestructive bool, packagePath string) error {
+func executePushMigration(ctx context.Context, config *storm.Config, migrateOpts storm.MigrateOptions, allowDestructive bool) error {
 	logger.CLI().Info("Executing push migration...")
 
-	// Create database connection
-	db, err := sql.Open("postgres", config.DatabaseURL)
+	confirm := func(result *migrator.MigrationResult) (bool, error) {
+		if !requiresTypedConfirmation(result) {
+			return true, nil
+		}
+		return typedConfirmation(config.DatabaseURL, result)
+	}
+
+	result, err := pushMigration(ctx, config.DatabaseURL, migrateOpts, allowDestructive, confirm)
 	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+		return err
 	}
-	defer db.Close()
 
-	if err := db.PingContext(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	if len(result.Changes) == 0 {
+		logger.CLI().Info("No schema changes detected! Database is up to date.")
 	}
 
+	return nil
+}
+
+// pushMigration diffs the models against the database and executes the changes
+// directly, showing them to confirm first when it is set
+func pushMigration(ctx context.Context, databaseURL string, migrateOpts storm.MigrateOptions, allowDestructive bool, confirm func(*migrator.MigrationResult) (bool, error)) (*migrator.MigrationResult, error) {
+	dbConfig, err := migrationDBConfig(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	lockRetry, err := migrationLockRetry()
+	if err != nil {
+		return nil, err
+	}
+	if stormConfig != nil {
+		if err := stormConfig.Migrations.AfterPush.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create database connection
+	db, err := dbConfig.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
 	// Create Atlas migrator
-	dbConfig := migrator.NewDBConfig(config.DatabaseURL)
 	atlasMigrator := migrator.NewAtlasMigrator(dbConfig)
 
-	// Set up migration options
-	opts := migrator.MigrationOptions{
-		PackagePath:         packagePath,
-		OutputDir:           "", // No file output for push
-		DryRun:              false,
-		AllowDestructive:    allowDestructive,
-		PushToDB:            true, // This is the key difference
-		CreateDBIfNotExists: createDBIfNotExists,
+	opts, err := atlasMigrationOptions(migrateOpts)
+	if err != nil {
+		return nil, err
 	}
+	opts.AllowDestructive = allowDestructive
+	opts.PushToDB = true
+	opts.LockRetry = lockRetry
+	opts.Confirm = confirm
 
 	// Execute migration
 	result, err := atlasMigrator.GenerateMigration(ctx, db, opts)
 	if err != nil {
-		return fmt.Errorf("failed to execute push migration: %w", err)
+		return nil, fmt.Errorf("failed to execute push migration: %w", err)
 	}
 
-	if len(result.Changes) == 0 {
-		logger.CLI().Info("No schema changes detected! Database is up to date.")
+	if result.Applied {
+		if err := runAfterPush(ctx, db, databaseURL, result.ModelsHash); err != nil {
+			return nil, err
+		}
 	}
 
+	return result, nil
+}
+
+// runAfterPush runs the migrations.after_push hooks of storm.yaml once the
+// schema of databaseURL has changed
+func runAfterPush(ctx context.Context, db *sql.DB, databaseURL, schemaHash string) error {
+	if stormConfig == nil || stormConfig.Migrations.AfterPush.IsZero() {
+		return nil
+	}
+	event := migrator.AfterPushEvent{Database: extractDatabaseNameFromURL(databaseURL), SchemaHash: schemaHash}
+	if err := stormConfig.Migrations.AfterPush.Run(ctx, db, event); err != nil {
+		return fmt.Errorf("migration applied, but after_push failed: %w", err)
+	}
 	return nil
 }
+
+// atlasMigrationOptions returns the options the migrator diffs the models
+// against the database with. The history of applied migrations is not one of
+// the models.
+func atlasMigrationOptions(migrateOpts storm.MigrateOptions) (migrator.MigrationOptions, error) {
+	preservation, err := migrator.ParseDataPreservation(migrateOpts.DataPreservation)
+	if err != nil {
+		return migrator.MigrationOptions{}, err
+	}
+
+	ignore := migrator.IgnoreRules(migrateOpts.Ignore)
+	ignore.Tables = append(append([]string{}, ignore.Tables...), migrationsTable())
+
+	return migrator.MigrationOptions{
+		PackagePath:         migrateOpts.PackagePath,
+		CreateDBIfNotExists: migrateOpts.CreateDBIfNotExists,
+		Strict:              migrateOpts.Strict,
+		DataPreservation:    preservation,
+		RetentionPeriod:     migrateOpts.RetentionPeriod,
+		ConcurrentIndexes:   migrateOpts.ConcurrentIndexes,
+		SafeConstraints:     migrateOpts.SafeConstraints,
+		Analyze:             migrateOpts.Analyze,
+		ForeignKeys: generator.ForeignKeyConventions{
+			OnDelete: migrateOpts.ForeignKeyOnDelete,
+			OnUpdate: migrateOpts.ForeignKeyOnUpdate,
+			Naming:   migrateOpts.ForeignKeyNaming,
+		},
+		Ignore:            ignore,
+		UpdatedAtTriggers: migrateOpts.UpdatedAtTriggers,
+		StormVersion:      storm.Version,
+	}, nil
+}
+
+// migrationDBConfig returns the connection settings of migrations on databaseURL,
+// with the lock_timeout, statement_timeout and application_name of storm.yaml
+func migrationDBConfig(databaseURL string) (*migrator.DBConfig, error) {
+	dbConfig := migrator.NewDBConfig(databaseURL)
+	if stormConfig == nil {
+		return dbConfig, nil
+	}
+
+	settings := stormConfig.Migrations
+	for _, timeout := range []struct {
+		key   string
+		value string
+		dest  *time.Duration
+	}{
+		{"lock_timeout", settings.LockTimeout, &dbConfig.LockTimeout},
+		{"statement_timeout", settings.StatementTimeout, &dbConfig.StatementTimeout},
+	} {
+		if timeout.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(timeout.value)
+		if err != nil || duration < 0 {
+			return nil, fmt.Errorf("invalid migrations.%s %q: use a duration such as 10s, or 0 to turn it off", timeout.key, timeout.value)
+		}
+		*timeout.dest = duration
+	}
+	if settings.ApplicationName != "" {
+		dbConfig.ApplicationName = settings.ApplicationName
+	}
+
+	return dbConfig, nil
+}
+
+// migrationLockRetry returns how long pushed statements that hit lock_timeout
+// are retried, from migrations.lock_retry of storm.yaml
+func migrationLockRetry() (migrator.LockRetry, error) {
+	if stormConfig == nil || stormConfig.Migrations.LockRetry == "" {
+		return migrator.LockRetry{}, nil
+	}
+
+	period, err := time.ParseDuration(stormConfig.Migrations.LockRetry)
+	if err != nil || period < 0 {
+		return migrator.LockRetry{}, fmt.Errorf("invalid migrations.lock_retry %q: use a duration such as 2m", stormConfig.Migrations.LockRetry)
+	}
+	return migrator.LockRetry{Period: period}, nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateApplyDir  string
+	migrateDownSteps int
+)
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migration files",
+	Long: `Apply the .up.sql files of the migrations directory that are not recorded in
+the migrations table yet, in version order. Each migration runs in its own
+transaction together with its record. A migration building or dropping indexes
+CONCURRENTLY runs each such statement on its own outside a transaction, and the
+statements around them in transactions of their own; if one fails, the ones
+before it stay applied. Each VALIDATE CONSTRAINT also runs in a transaction of
+its own, so the locks taken before it are released while it scans the table. The version of a migration is the name of its file without .up.sql.
+
+The migrations table (migrations.table, default schema_migrations) holds the
+version, checksum and time of every applied migration.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the last applied migrations",
+	Long: `Run the .down.sql files of the last --steps applied migrations, newest first,
+and remove their records. Nothing is rolled back if one of them has no down file.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateDown,
+}
+
+func init() {
+	migrateUpCmd.Flags().StringVar(&migrateApplyDir, "dir", "", "Directory holding the migration files (default migrations.directory)")
+	migrateDownCmd.Flags().StringVar(&migrateApplyDir, "dir", "", "Directory holding the migration files (default migrations.directory)")
+	migrateDownCmd.Flags().IntVar(&migrateDownSteps, "steps", 1, "Number of migrations to roll back")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+}
+
+// migrationsTable returns the table applied migrations are recorded in
+func migrationsTable() string {
+	if stormConfig != nil && stormConfig.Migrations.Table != "" {
+		return stormConfig.Migrations.Table
+	}
+	return storm.NewConfig().MigrationsTable
+}
+
+// migrationFilesDir returns the directory of the migration files: --dir, else
+// migrations.directory of storm.yaml, else ./migrations
+func migrationFilesDir() string {
+	if migrateApplyDir != "" {
+		return migrateApplyDir
+	}
+	if stormConfig != nil && stormConfig.Migrations.Directory != "" {
+		return stormConfig.Migrations.Directory
+	}
+	return storm.NewConfig().MigrationsDir
+}
+
+// openMigrationRunner connects to --url with the migrations directory, table
+// and session settings of storm.yaml
+func openMigrationRunner(url string) (*storm.Storm, error) {
+	if url == "" {
+		return nil, fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	config := storm.NewConfig()
+	config.DatabaseURL = url
+	config.MigrationsTable = migrationsTable()
+	config.Debug = debug
+	config.MigrationsDir = migrationFilesDir()
+
+	dbConfig, err := migrationDBConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	config.MigrationLockTimeout = dbConfig.LockTimeout
+	config.MigrationStatementTimeout = dbConfig.StatementTimeout
+	config.MigrationApplicationName = dbConfig.ApplicationName
+
+	lockRetry, err := migrationLockRetry()
+	if err != nil {
+		return nil, err
+	}
+	config.MigrationLockRetry = lockRetry.Period
+
+	stormClient, err := storm.NewWithConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Storm client: %w", err)
+	}
+	return stormClient, nil
+}
+
+func runMigrateUp(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stormClient, err := openMigrationRunner(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer stormClient.Close()
+
+	applied, err := stormClient.Migrator().Up(ctx)
+	for _, migration := range applied {
+		fmt.Fprintln(cmd.OutOrStdout(), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		cmd.Println("No pending migrations")
+		return nil
+	}
+	cmd.Printf("Applied %d migrations\n", len(applied))
+	return nil
+}
+
+func runMigrateDown(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stormClient, err := openMigrationRunner(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer stormClient.Close()
+
+	rolledBack, err := stormClient.Migrator().Down(ctx, migrateDownSteps)
+	for _, migration := range rolledBack {
+		fmt.Fprintln(cmd.OutOrStdout(), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(rolledBack) == 0 {
+		cmd.Println("No applied migrations")
+		return nil
+	}
+	cmd.Printf("Rolled back %d migrations\n", len(rolledBack))
+	return nil
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/spf13/cobra"
+)
+
+var migrateChangelogOutput string
+
+var migrateChangelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Render the migration files as a Markdown changelog",
+	Long: `List the migration files as a Markdown changelog for audits and release notes.
+
+Each migration lists the statement descriptions and warnings storm migrate wrote
+to the .meta.json file next to its .up.sql file; hand-written migrations list
+their number of statements.
+Migrations are grouped under the first git tag holding their file, newest release
+first, with the migrations no tag holds yet under Unreleased.
+
+With --url, or a database URL in storm.yaml, each migration is marked as applied,
+with its date from the migrations table, or pending.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateChangelog,
+}
+
+func init() {
+	migrateChangelogCmd.Flags().StringVar(&migrateApplyDir, "dir", "", "Directory holding the migration files (default migrations.directory)")
+	migrateChangelogCmd.Flags().StringVarP(&migrateChangelogOutput, "output", "o", "", "File to write the changelog to (default stdout)")
+
+	migrateCmd.AddCommand(migrateChangelogCmd)
+}
+
+func runMigrateChangelog(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	dir := migrationFilesDir()
+	entries, err := migrator.ReadChangelogEntries(dir)
+	if err != nil {
+		return err
+	}
+
+	releases, err := migrator.ReleaseTags(ctx, dir)
+	if err != nil {
+		cmd.PrintErrf("Warning: listing every migration as unreleased: %v\n", err)
+	}
+	for i := range entries {
+		entries[i].Release = releases[entries[i].Version]
+	}
+
+	withStatus := databaseURL != ""
+	if withStatus {
+		stormClient, err := openMigrationRunner(databaseURL)
+		if err != nil {
+			return err
+		}
+		defer stormClient.Close()
+
+		history, err := stormClient.Migrator().History(ctx)
+		if err != nil {
+			return err
+		}
+		for _, record := range history {
+			for i := range entries {
+				if entries[i].Version == record.Version {
+					appliedAt := record.AppliedAt
+					entries[i].AppliedAt = &appliedAt
+				}
+			}
+		}
+	}
+
+	if migrateChangelogOutput == "" {
+		return migrator.WriteChangelog(cmd.OutOrStdout(), entries, withStatus)
+	}
+
+	file, err := os.Create(migrateChangelogOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create changelog file: %w", err)
+	}
+	if err := migrator.WriteChangelog(file, entries, withStatus); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migratePlanOutput string
+	migratePlanFile   string
+)
+
+var migratePlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Write the changes a push would make to a plan file",
+	Long: `Diff the models against the database like storm migrate --push, and write the
+statements it would run to a JSON plan file instead of running them. The plan
+records a fingerprint of every table, view and enum of the database as its
+preconditions, and a SHA-256 hash of its own content. The hash has no key: it
+catches a corrupted or hand-edited plan, not a deliberate change.
+
+  storm migrate plan -o plan.json
+  storm migrate apply --plan plan.json
+
+storm migrate apply --plan runs the statements of the plan exactly as
+reviewed. It refuses a plan that was edited, or whose preconditions no longer
+hold because the schema of the database changed after the plan was made.`,
+	Args: cobra.NoArgs,
+	RunE: runMigratePlan,
+}
+
+func init() {
+	migratePlanCmd.Flags().StringVarP(&migratePlanOutput, "output", "o", "plan.json", "File to write the plan to")
+	migratePlanCmd.Flags().StringVar(&migratePackagePath, "package", "", "Path to package containing models")
+	migratePlanCmd.Flags().BoolVar(&allowDestructive, "allow-destructive", false, "Allow potentially destructive operations")
+	migratePlanCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on unknown tag attributes and Go types instead of warning")
+	migratePlanCmd.Flags().StringVar(&preserveData, "preserve-data", "", "Keep the data of dropped tables and columns (rename, archive)")
+	migratePlanCmd.Flags().BoolVar(&concurrentIndexes, "concurrent-indexes", false, "Build and drop indexes of existing tables with CONCURRENTLY")
+	migratePlanCmd.Flags().BoolVar(&safeConstraints, "safe-constraints", false, "Add constraints to existing tables NOT VALID and validate them in a separate step")
+	migratePlanCmd.Flags().BoolVar(&analyzeTables, "analyze", false, "ANALYZE existing tables that get indexes, filled or retyped columns, or updated rows")
+	migratePlanCmd.Flags().StringVar(&retentionPeriod, "retention-period", "", "Drop preserved tables and columns once they are this old (e.g. 30d, 72h)")
+
+	migrateApplyCmd.Flags().StringVar(&migratePlanFile, "plan", "", "Apply the statements of a plan file written by storm migrate plan")
+
+	migrateCmd.AddCommand(migratePlanCmd)
+}
+
+func runMigratePlan(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	applyMigrateDefaults(cmd)
+	migrateOpts, err := migrateOptions()
+	if err != nil {
+		return err
+	}
+	if selectedDialect, err := databaseDialect(); err != nil {
+		return err
+	} else if selectedDialect == "sqlite" {
+		return fmt.Errorf("plan files are not supported for SQLite")
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+	if err := checkForbidUnsafe(allowDestructive); err != nil {
+		return err
+	}
+
+	dbConfig, err := migrationDBConfig(databaseURL)
+	if err != nil {
+		return err
+	}
+	db, err := dbConfig.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts, err := atlasMigrationOptions(migrateOpts)
+	if err != nil {
+		return err
+	}
+	opts.DryRun = true
+	opts.AllowDestructive = allowDestructive
+
+	// Fingerprint the schema before diffing, so that a change made in between
+	// fails the preconditions rather than slipping into the plan
+	preconditions, err := migrator.InspectPreconditions(ctx, db, opts.Ignore, migrationsTable())
+	if err != nil {
+		return err
+	}
+
+	result, err := migrator.NewAtlasMigrator(dbConfig).GenerateMigration(ctx, db, opts)
+	if err != nil {
+		return err
+	}
+	if result.HasDestructive && !allowDestructive {
+		return fmt.Errorf("the plan has destructive changes: use --allow-destructive to write it")
+	}
+
+	plan, err := migrator.NewPlan(result, preconditions, storm.Version)
+	if err != nil {
+		return err
+	}
+	if err := migrator.WritePlan(migratePlanOutput, plan); err != nil {
+		return err
+	}
+
+	cmd.Printf("Wrote a plan of %d statements to %s (hash %s)\n", len(plan.Statements), migratePlanOutput, plan.Hash)
+	return nil
+}
+
+// runApplyPlan applies the plan file of --plan to --url
+func runApplyPlan(cmd *cobra.Command) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	plan, err := migrator.ReadPlan(migratePlanFile)
+	if err != nil {
+		return err
+	}
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+	if err := checkForbidUnsafe(len(plan.Destructive) > 0); err != nil {
+		return err
+	}
+	if stormConfig != nil {
+		if err := stormConfig.Migrations.Ignore.Validate(); err != nil {
+			return err
+		}
+		if err := stormConfig.Migrations.AfterPush.Validate(); err != nil {
+			return err
+		}
+	}
+
+	dbConfig, err := migrationDBConfig(databaseURL)
+	if err != nil {
+		return err
+	}
+	lockRetry, err := migrationLockRetry()
+	if err != nil {
+		return err
+	}
+	db, err := dbConfig.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts := migrator.ApplyPlanOptions{
+		MigrationsTable: migrationsTable(),
+		LockRetry:       lockRetry,
+		Confirm: func(plan *migrator.Plan) (bool, error) {
+			result := planResult(plan)
+			if !requiresTypedConfirmation(result) {
+				return true, nil
+			}
+			return typedConfirmation(databaseURL, result)
+		},
+	}
+	if stormConfig != nil {
+		opts.Ignore = migrator.IgnoreRules(stormConfig.Migrations.Ignore)
+	}
+
+	applied, err := migrator.ApplyPlan(ctx, db, plan, opts)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		cmd.Println("Plan not applied")
+		return nil
+	}
+	if err := runAfterPush(ctx, db, databaseURL, plan.ModelsHash); err != nil {
+		return err
+	}
+
+	cmd.Printf("Applied %d statements of plan %s\n", len(plan.Statements), plan.Hash)
+	return nil
+}
+
+// planResult describes the statements of plan for the destructive change
+// confirmation
+func planResult(plan *migrator.Plan) *migrator.MigrationResult {
+	var upSQL strings.Builder
+	for i, stmt := range plan.Statements {
+		fmt.Fprintf(&upSQL, "-- Statement %d: %s\n%s;\n\n", i+1, stmt.Description, strings.TrimSuffix(stmt.SQL, ";"))
+	}
+	return &migrator.MigrationResult{
+		UpSQL:          upSQL.String(),
+		HasDestructive: len(plan.Destructive) > 0,
+		DestructiveOps: plan.Destructive,
+		Statements:     plan.Statements,
+		ModelsHash:     plan.ModelsHash,
+	}
+}
@@ -0,0 +1,344 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/introspect"
+	"github.com/eleven-am/storm/internal/logger"
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/internal/parser"
+	"github.com/spf13/cobra"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name of modernc.org/sqlite
+const sqliteDriver = "sqlite"
+
+// databaseDialect returns --dialect, or sqlite when database.driver of storm.yaml
+// says so, or postgres
+func databaseDialect() (string, error) {
+	name := dialect
+	if name == "" && stormConfig != nil && isSQLite(stormConfig.Database.Driver) {
+		name = stormConfig.Database.Driver
+	}
+
+	switch {
+	case name == "", strings.EqualFold(name, "postgres"), strings.EqualFold(name, "postgresql"):
+		return "postgres", nil
+	case isSQLite(name):
+		return "sqlite", nil
+	default:
+		return "", fmt.Errorf("unsupported dialect %q: use postgres or sqlite", name)
+	}
+}
+
+func isSQLite(name string) bool {
+	return strings.EqualFold(name, "sqlite") || strings.EqualFold(name, "sqlite3")
+}
+
+// sqlitePath returns the database file of url: a path, a file: URI, or a path
+// after sqlite://
+func sqlitePath(url string) string {
+	for _, prefix := range []string{"sqlite://", "sqlite3://"} {
+		if strings.HasPrefix(url, prefix) {
+			return strings.TrimPrefix(url, prefix)
+		}
+	}
+	return url
+}
+
+// openSQLite opens the SQLite database at url. The file is created if it does
+// not exist.
+func openSQLite(ctx context.Context, url string) (*sql.DB, error) {
+	path := sqlitePath(url)
+	if path == "" {
+		return nil, fmt.Errorf("database connection required: use --url with the path of the SQLite database, or specify it in storm.yaml")
+	}
+
+	db, err := sql.Open(sqliteDriver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// PRAGMA settings are per connection, and SQLite has a single writer anyway
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// runSQLiteMigrate diffs the models against the SQLite database of --url and
+// writes, prints or pushes the statements changing it, like runMigrate does
+// for PostgreSQL
+func runSQLiteMigrate(ctx context.Context, cmd *cobra.Command) error {
+	db, err := openSQLite(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ignore := migrator.IgnoreRules{}
+	if stormConfig != nil {
+		ignore = stormConfig.Migrations.Ignore
+	}
+	// The history of applied migrations is not one of the models
+	ignore.Tables = append(append([]string{}, ignore.Tables...), migrationsTable())
+
+	logger.CLI().Info("Inspecting SQLite database...")
+	tables, err := introspect.NewInspector(db, "sqlite").GetTables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %w", err)
+	}
+	current := sqliteSchemaFromTables(tables, ignore)
+
+	desired, err := modelSchema(migratePackagePath, strictMode)
+	if err != nil {
+		return err
+	}
+	for name := range desired.Tables {
+		if ignore.IgnoresTable("", name) {
+			delete(desired.Tables, name)
+		}
+	}
+
+	sqliteGenerator := generator.NewSQLiteGenerator()
+	up := sqliteGenerator.GenerateMigration(current, desired)
+	if up.IsEmpty() {
+		logger.CLI().Info("No schema changes detected! Database is up to date.")
+		return nil
+	}
+	for _, table := range up.Rebuilt {
+		logger.CLI().Info("Table %s is rebuilt: SQLite cannot alter it in place", table)
+	}
+
+	if len(up.Destructive) > 0 && !allowDestructive {
+		cmd.Println("\nPOTENTIALLY DESTRUCTIVE OPERATIONS DETECTED:")
+		for _, op := range up.Destructive {
+			cmd.Printf("  - %s\n", op)
+		}
+		cmd.Println("\nUse --allow-destructive to proceed with these changes.")
+		cmd.Println("Review the changes carefully as they may cause data loss.")
+		return nil
+	}
+
+	if dryRun {
+		fmt.Fprintln(cmd.OutOrStdout(), up.SQL())
+		return nil
+	}
+
+	if pushToDB {
+		if err := checkForbidUnsafe(allowDestructive); err != nil {
+			return err
+		}
+		result := &migrator.MigrationResult{
+			UpSQL:          up.SQL(),
+			HasDestructive: len(up.Destructive) > 0,
+			DestructiveOps: up.Destructive,
+		}
+		if requiresTypedConfirmation(result) {
+			if !isTerminal(os.Stdin) {
+				return fmt.Errorf("refusing to apply destructive changes: the configuration requires typing the database name at a terminal (migrations.require_confirmation)")
+			}
+			confirmed, err := confirmDestructive(os.Stdin, os.Stdout, filepath.Base(sqlitePath(databaseURL)), result)
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				cmd.Println("Migration not applied.")
+				return nil
+			}
+		}
+
+		if err := applySQLiteMigration(ctx, db, up); err != nil {
+			return err
+		}
+		logger.CLI().Info("Applied %d statements", len(up.Statements))
+		return nil
+	}
+
+	down := sqliteGenerator.GenerateMigration(desired, current)
+	upFile, downFile, err := writeMigrationFiles(outputDir, migrationName, up.SQL(), down.SQL())
+	if err != nil {
+		return err
+	}
+	cmd.Printf("\nMigration files created:\n")
+	cmd.Printf("  UP:   %s\n", upFile)
+	cmd.Printf("  DOWN: %s\n", downFile)
+	return nil
+}
+
+// modelSchema generates the schema of the models in packagePath
+func modelSchema(packagePath string, strict bool) (*generator.DatabaseSchema, error) {
+	tables, err := parser.NewStructParser().ParseDirectory(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse models: %w", err)
+	}
+
+	schemaGenerator := generator.NewSchemaGenerator()
+	schemaGenerator.SetStrictMode(strict)
+	if stormConfig != nil {
+		schemaGenerator.SetForeignKeyConventions(generator.ForeignKeyConventions{
+			OnDelete: stormConfig.Schema.ForeignKeys.OnDelete,
+			OnUpdate: stormConfig.Schema.ForeignKeys.OnUpdate,
+			Naming:   stormConfig.Schema.ForeignKeys.Naming,
+		})
+	}
+
+	schema, err := schemaGenerator.GenerateSchema(tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema from models: %w", err)
+	}
+	return schema, nil
+}
+
+// sqliteSchemaFromTables describes inspected SQLite tables the way the schema
+// generator describes models. Foreign keys over several columns are left out.
+func sqliteSchemaFromTables(tables []*introspect.TableSchema, ignore migrator.IgnoreRules) *generator.DatabaseSchema {
+	schema := &generator.DatabaseSchema{
+		Tables:    make(map[string]generator.SchemaTable),
+		EnumTypes: make(map[string][]string),
+	}
+
+	for _, table := range tables {
+		if ignore.IgnoresTable("", table.Name) {
+			continue
+		}
+
+		primaryKey := make(map[string]bool)
+		if table.PrimaryKey != nil {
+			for _, col := range table.PrimaryKey.Columns {
+				primaryKey[col] = true
+			}
+		}
+		foreignKeys := make(map[string]*generator.ForeignKeyRef)
+		for _, fk := range table.ForeignKeys {
+			if len(fk.Columns) == 1 {
+				foreignKeys[fk.Columns[0]] = &generator.ForeignKeyRef{
+					ReferencedTable:  fk.ReferencedTable,
+					ReferencedColumn: fk.ReferencedColumns[0],
+					OnDelete:         fk.OnDelete,
+					OnUpdate:         fk.OnUpdate,
+				}
+			}
+		}
+
+		schemaTable := generator.SchemaTable{Name: table.Name}
+		for _, col := range table.Columns {
+			schemaTable.Columns = append(schemaTable.Columns, generator.SchemaColumn{
+				Name:            col.Name,
+				Type:            col.UDTName,
+				IsNullable:      col.IsNullable,
+				DefaultValue:    col.DefaultValue,
+				IsPrimaryKey:    primaryKey[col.Name],
+				IsAutoIncrement: col.IsIdentity,
+				ForeignKey:      foreignKeys[col.Name],
+			})
+		}
+		for _, constraint := range table.Constraints {
+			if constraint.Type == "UNIQUE" {
+				schemaTable.Constraints = append(schemaTable.Constraints, generator.SchemaConstraint{
+					Name:    constraint.Name,
+					Type:    constraint.Type,
+					Columns: constraint.Columns,
+				})
+			}
+		}
+		for _, idx := range table.Indexes {
+			// Indexes SQLite builds for the primary key and unique constraints are implied by them
+			if idx.IsPrimary || strings.HasPrefix(idx.Name, "sqlite_autoindex_") {
+				continue
+			}
+			columns := make([]string, len(idx.Columns))
+			for i, col := range idx.Columns {
+				columns[i] = col.Name
+			}
+			schemaTable.Indexes = append(schemaTable.Indexes, generator.SchemaIndex{
+				Name:     idx.Name,
+				Columns:  columns,
+				IsUnique: idx.IsUnique,
+				Where:    idx.Where,
+			})
+		}
+
+		schema.Tables[table.Name] = schemaTable
+	}
+
+	return schema
+}
+
+// applySQLiteMigration runs the statements of migration in a transaction. When
+// tables are rebuilt or dropped, foreign keys are turned off for the connection
+// meanwhile, and the transaction is rolled back if it leaves rows whose
+// references are broken.
+func applySQLiteMigration(ctx context.Context, db *sql.DB, migration *generator.SQLiteMigration) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	// foreign_keys cannot change inside a transaction
+	if migration.DisableForeignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to turn off foreign keys: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, stmt := range migration.Statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute statement %d: %s\nError: %w", i+1, stmt, err)
+		}
+	}
+
+	if migration.DisableForeignKeys {
+		var table string
+		err := tx.QueryRowContext(ctx, `SELECT "table" FROM pragma_foreign_key_check`).Scan(&table)
+		if err == nil {
+			return fmt.Errorf("migration not applied: rows of %s reference rows that no longer exist", table)
+		}
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to check foreign keys: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// writeMigrationFiles writes upSQL and downSQL to <timestamp>_<name>.up.sql and
+// .down.sql in dir, the names storm migrate up reads
+func writeMigrationFiles(dir, name, upSQL, downSQL string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if name == "" {
+		name = "schema_update"
+	}
+	baseName := fmt.Sprintf("%s_%s", time.Now().UTC().Format("20060102150405"), name)
+	upFile := filepath.Join(dir, baseName+".up.sql")
+	downFile := filepath.Join(dir, baseName+".down.sql")
+
+	if err := os.WriteFile(upFile, []byte(upSQL), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write UP migration: %w", err)
+	}
+	if err := os.WriteFile(downFile, []byte(downSQL), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write DOWN migration: %w", err)
+	}
+	return upFile, downFile, nil
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateSquashTo     string
+	migrateSquashDryRun bool
+)
+
+var migrateSquashCmd = &cobra.Command{
+	Use:   "squash",
+	Short: "Collapse the migration files up to a version into one baseline",
+	Long: `Replace the migration files up to and including --to, or all of them, with a
+single baseline migration creating the schema they add up to, so that new
+databases are set up in one step.
+
+The squashed migrations are applied to a temporary database on the server of
+--url, and the baseline is generated from the schema they leave. Statements on
+objects the schema comparison does not see, such as functions, triggers, views
+and inserted rows, are copied into the baseline as written. The baseline is then
+applied to a second temporary database, and nothing is written unless both
+schemas match.
+
+The baseline takes the version of the last squashed migration, so databases
+that applied it skip the baseline; databases that applied only some of the
+squashed migrations must be migrated past --to before the files are squashed.
+The down file of the baseline runs the down files of the squashed migrations,
+newest first.`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateSquash,
+}
+
+func init() {
+	migrateSquashCmd.Flags().StringVar(&migrateApplyDir, "dir", "", "Directory holding the migration files (default migrations.directory)")
+	migrateSquashCmd.Flags().StringVar(&migrateSquashTo, "to", "", "Version of the last migration to squash (default the newest)")
+	migrateSquashCmd.Flags().BoolVar(&migrateSquashDryRun, "dry-run", false, "Print the baseline without changing the migration files")
+
+	migrateCmd.AddCommand(migrateSquashCmd)
+}
+
+func runMigrateSquash(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+	dbConfig, err := migrationDBConfig(databaseURL)
+	if err != nil {
+		return err
+	}
+
+	opts := migrator.SquashOptions{
+		Dir:    migrationFilesDir(),
+		To:     migrateSquashTo,
+		DryRun: migrateSquashDryRun,
+	}
+	if stormConfig != nil {
+		opts.Ignore = stormConfig.Migrations.Ignore
+	}
+
+	result, err := migrator.Squash(ctx, dbConfig, opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		fmt.Fprintln(cmd.OutOrStdout(), "=== UP Migration ===")
+		fmt.Fprintln(cmd.OutOrStdout(), result.UpSQL)
+		fmt.Fprintln(cmd.OutOrStdout(), "=== DOWN Migration ===")
+		fmt.Fprintln(cmd.OutOrStdout(), result.DownSQL)
+		return nil
+	}
+
+	for _, version := range result.Squashed {
+		fmt.Fprintln(cmd.OutOrStdout(), version)
+	}
+	cmd.Printf("Squashed %d migrations into %s\n", len(result.Squashed), result.Version)
+	if result.Carried > 0 {
+		cmd.Printf("%d statements were carried over as written; review them in %s.up.sql\n", result.Carried, result.Version)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyToTarget_SkipsAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result := applyToTarget(ctx, MigrationTarget{Name: "eu", URL: "postgres://localhost/app"})
+	if !result.Skipped || result.Status() != "skipped" {
+		t.Errorf("Expected a target after cancellation to be skipped, got %+v", result)
+	}
+}
+
+func TestWriteTargetReport(t *testing.T) {
+	results := []targetResult{
+		{Name: "eu", Applied: []string{"20240101000000_create_users"}, Duration: 1500 * time.Millisecond},
+		{Name: "us", Err: errors.New("connection refused\nretry later")},
+		{Name: "ap"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTargetReport(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and one line per target, got:\n%s", buf.String())
+	}
+	for i, status := range []string{"migrated", "failed", "up to date"} {
+		if !strings.HasPrefix(lines[i+1], results[i].Name) || !strings.Contains(lines[i+1], status) {
+			t.Errorf("line %d = %q, want %s %s", i+1, lines[i+1], results[i].Name, status)
+		}
+	}
+	if !strings.Contains(lines[2], "connection refused retry later") {
+		t.Errorf("Expected the error on one line, got %q", lines[2])
+	}
+}
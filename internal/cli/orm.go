@@ -14,6 +14,10 @@ var (
 	ormIncludeHooks bool
 	ormIncludeTests bool
 	ormIncludeMocks bool
+	ormForce        bool
+	ormModels       []string
+	ormFeatures     []string
+	ormTemplatesDir string
 )
 
 var ormCmd = &cobra.Command{
@@ -26,7 +30,10 @@ This command analyzes your Go struct definitions and generates:
 - Query builders and constants
 - Lifecycle hooks (optional)
 - Test files (optional)
-- Mock implementations (optional)`,
+- Mock implementations (optional)
+
+Only the files of models that changed since the last run are rewritten; use
+--force to regenerate everything.`,
 	RunE: runORM,
 }
 
@@ -36,6 +43,10 @@ func init() {
 	ormCmd.Flags().BoolVar(&ormIncludeHooks, "hooks", false, "Generate lifecycle hooks")
 	ormCmd.Flags().BoolVar(&ormIncludeTests, "tests", false, "Generate test files")
 	ormCmd.Flags().BoolVar(&ormIncludeMocks, "mocks", false, "Generate mock implementations")
+	ormCmd.Flags().BoolVar(&ormForce, "force", false, "Regenerate files of unchanged models too")
+	ormCmd.Flags().StringSliceVar(&ormModels, "models", nil, "Only generate these models, e.g. User,Team (default: all)")
+	ormCmd.Flags().StringSliceVar(&ormFeatures, "features", nil, "Only generate these features: metadata, columns, repositories, extensions, storm, filters (default: all)")
+	ormCmd.Flags().StringVar(&ormTemplatesDir, "templates-dir", "", "Directory of <template>.tmpl files replacing the built-in templates")
 }
 
 func runORM(cmd *cobra.Command, args []string) error {
@@ -54,6 +65,15 @@ func runORM(cmd *cobra.Command, args []string) error {
 		if !cmd.Flags().Changed("mocks") && stormConfig.ORM.GenerateMocks {
 			ormIncludeMocks = stormConfig.ORM.GenerateMocks
 		}
+		if !cmd.Flags().Changed("models") && len(stormConfig.ORM.Models) > 0 {
+			ormModels = stormConfig.ORM.Models
+		}
+		if !cmd.Flags().Changed("features") && len(stormConfig.ORM.Features) > 0 {
+			ormFeatures = stormConfig.ORM.Features
+		}
+		if ormTemplatesDir == "" {
+			ormTemplatesDir = stormConfig.ORM.TemplatesDir
+		}
 	}
 
 	if ormPackage == "" {
@@ -82,7 +102,11 @@ func runORM(cmd *cobra.Command, args []string) error {
 	}
 	defer stormClient.Close()
 
-	fmt.Printf("Generating ORM code from models in %s\n", ormPackage)
+	status("Generating ORM code from models in %s\n", ormPackage)
+
+	prog := newProgress()
+	defer prog.Close()
+	prog.Start("Generating ORM code")
 
 	opts := storm.GenerateOptions{
 		PackagePath:  ormPackage,
@@ -90,12 +114,23 @@ func runORM(cmd *cobra.Command, args []string) error {
 		IncludeHooks: ormIncludeHooks,
 		IncludeTests: ormIncludeTests,
 		IncludeMocks: ormIncludeMocks,
+		Models:       ormModels,
+		Features:     ormFeatures,
+		Force:        ormForce,
+		Progress:     prog.Update,
+		TemplateDir:  ormTemplatesDir,
+	}
+	if stormConfig != nil {
+		opts.TemplateData = stormConfig.ORM.TemplateData
 	}
 
 	if err := stormClient.Generate(ctx, opts); err != nil {
 		return fmt.Errorf("failed to generate ORM code: %w", err)
 	}
 
-	fmt.Printf("ORM code generated successfully in %s\n", ormOutput)
+	prog.Done()
+	prog.Summary()
+
+	status("ORM code generated successfully in %s\n", ormOutput)
 	return nil
 }
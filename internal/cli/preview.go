@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewPackage string
+	previewSeeds   []string
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Manage throwaway databases created from the models",
+	Long: `Create and drop preview databases for PR preview environments and local
+integration tests. Preview databases live on the server of --url and are named
+` + migrator.PreviewPrefix + `<name>.`,
+}
+
+var previewCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a database with the full schema of the models",
+	Long: `Create a database next to the one in --url, apply the full schema of the
+models to it and run the seed files in order. A directory given to --seed runs
+the .sql files it contains, sorted by name.
+
+The name defaults to a timestamp. The connection URL of the new database is
+printed to stdout, so scripts can capture it:
+
+  DATABASE_URL=$(storm preview create pr-42 --seed ./seeds)`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPreviewCreate,
+}
+
+var previewDropCmd = &cobra.Command{
+	Use:   "drop <name>",
+	Short: "Drop a preview database",
+	Long: `Drop a preview database, disconnecting any clients first. Only databases
+named like previews are dropped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPreviewDrop,
+}
+
+func init() {
+	previewCreateCmd.Flags().StringVar(&previewPackage, "package", "", "Path to package containing models")
+	previewCreateCmd.Flags().StringArrayVar(&previewSeeds, "seed", nil, "SQL file or directory to run after the schema (repeatable)")
+	previewCreateCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on unknown tag attributes and Go types instead of warning")
+	previewCmd.AddCommand(previewCreateCmd)
+	previewCmd.AddCommand(previewDropCmd)
+}
+
+func runPreviewCreate(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	opts := migrator.PreviewOptions{
+		PackagePath:  previewPackage,
+		Seeds:        previewSeeds,
+		Strict:       strictMode,
+		StormVersion: storm.Version,
+	}
+	if len(args) > 0 {
+		opts.Name = args[0]
+	}
+	if stormConfig != nil {
+		if opts.PackagePath == "" {
+			opts.PackagePath = stormConfig.Models.Package
+		}
+		if !cmd.Flags().Changed("strict") && stormConfig.Schema.StrictMode {
+			opts.Strict = true
+		}
+		opts.ForeignKeys = generator.ForeignKeyConventions{
+			OnDelete: stormConfig.Schema.ForeignKeys.OnDelete,
+			OnUpdate: stormConfig.Schema.ForeignKeys.OnUpdate,
+			Naming:   stormConfig.Schema.ForeignKeys.Naming,
+		}
+		opts.UpdatedAtTriggers = stormConfig.Schema.UpdatedAtTriggers
+	}
+	if opts.PackagePath == "" {
+		opts.PackagePath = "./models"
+	}
+
+	preview, err := migrator.CreatePreview(ctx, migrator.NewDBConfig(databaseURL), opts)
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("Created preview database %s\n", preview.Name)
+	fmt.Fprintln(cmd.OutOrStdout(), preview.URL)
+	return nil
+}
+
+func runPreviewDrop(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	if err := migrator.DropPreview(ctx, migrator.NewDBConfig(databaseURL), args[0]); err != nil {
+		return err
+	}
+
+	cmd.Printf("Dropped preview database %s\n", args[0])
+	return nil
+}
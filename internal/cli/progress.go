@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progress reports the phases of a long running command on stderr. While a phase runs
+// a bar is drawn on the terminal, each finished phase prints the time it took, and
+// Summary prints the total. Nothing is printed with --quiet.
+type progress struct {
+	out      io.Writer
+	enabled  bool
+	terminal bool
+	phase    string
+	started  time.Time
+	phases   []phaseTiming
+}
+
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+}
+
+func newProgress() *progress {
+	return &progress{
+		out:      os.Stderr,
+		enabled:  !quiet,
+		terminal: isTerminal(os.Stderr),
+	}
+}
+
+// Start begins timing a phase
+func (p *progress) Start(phase string) {
+	p.phase = phase
+	p.started = time.Now()
+	if p.enabled && p.terminal {
+		fmt.Fprintf(p.out, "%s...", phase)
+	}
+}
+
+// Update redraws the bar of the current phase. Off a terminal, e.g. in CI logs, only
+// finished phases are printed.
+func (p *progress) Update(done, total int, item string) {
+	if !p.enabled || !p.terminal || total <= 0 {
+		return
+	}
+	fmt.Fprintf(p.out, "\r\033[K%s %s %d/%d %s", p.phase, progressBar(done, total, 24), done, total, item)
+}
+
+// Done records the time the current phase took
+func (p *progress) Done() {
+	name, elapsed := p.phase, time.Since(p.started)
+	p.phases = append(p.phases, phaseTiming{name: name, duration: elapsed})
+	p.phase = ""
+
+	if !p.enabled {
+		return
+	}
+	if p.terminal {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+	fmt.Fprintf(p.out, "  ✓ %s (%s)\n", name, formatElapsed(elapsed))
+}
+
+// Close ends the line of a phase left unfinished by an error, so the error is printed
+// on a line of its own
+func (p *progress) Close() {
+	if p.enabled && p.terminal && p.phase != "" {
+		fmt.Fprintln(p.out)
+	}
+}
+
+// Summary prints the time of every phase and the total
+func (p *progress) Summary() {
+	if !p.enabled || len(p.phases) == 0 {
+		return
+	}
+
+	var total time.Duration
+	parts := make([]string, len(p.phases))
+	for i, phase := range p.phases {
+		total += phase.duration
+		parts[i] = fmt.Sprintf("%s %s", strings.ToLower(phase.name), formatElapsed(phase.duration))
+	}
+	fmt.Fprintf(p.out, "Finished in %s (%s)\n", formatElapsed(total), strings.Join(parts, ", "))
+}
+
+func progressBar(done, total, width int) string {
+	if done > total {
+		done = total
+	}
+	filled := done * width / total
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func formatElapsed(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// status prints informational output, which --quiet suppresses
+func status(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
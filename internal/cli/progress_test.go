@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	var out bytes.Buffer
+	p := &progress{out: &out, enabled: true}
+
+	p.Start("Inspecting database")
+	p.Update(1, 2, "users")
+	p.Done()
+	p.Start("Generating models")
+	p.Done()
+	p.Summary()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a line per phase and the summary, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], "  ✓ Inspecting database (") {
+		t.Errorf("unexpected phase line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "Finished in ") || !strings.Contains(lines[2], "generating models ") {
+		t.Errorf("unexpected summary %q", lines[2])
+	}
+	if strings.Contains(out.String(), "users") {
+		t.Error("expected no bar off a terminal")
+	}
+}
+
+func TestProgress_Quiet(t *testing.T) {
+	var out bytes.Buffer
+	p := &progress{out: &out, terminal: true}
+
+	p.Start("Inspecting database")
+	p.Update(1, 2, "users")
+	p.Done()
+	p.Summary()
+	p.Close()
+
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+	if len(p.phases) != 1 {
+		t.Error("expected phases to be timed even when quiet")
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	if bar := progressBar(1, 4, 8); bar != "[==      ]" {
+		t.Errorf("got %q", bar)
+	}
+	if bar := progressBar(5, 4, 4); bar != "[====]" {
+		t.Errorf("got %q", bar)
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	if got := formatElapsed(1234567 * time.Nanosecond); got != "1ms" {
+		t.Errorf("got %q", got)
+	}
+	if got := formatElapsed(2345 * time.Millisecond); got != "2.35s" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/migrator"
+)
+
+// checkForbidUnsafe refuses --allow-destructive for configs that set
+// migrations.forbid_unsafe
+func checkForbidUnsafe(allowDestructive bool) error {
+	if allowDestructive && stormConfig != nil && stormConfig.Migrations.ForbidUnsafe {
+		return fmt.Errorf("destructive changes are forbidden by the configuration (migrations.forbid_unsafe); apply them with reviewed migration files instead")
+	}
+	return nil
+}
+
+// requiresTypedConfirmation reports whether the config protects the database
+// from the destructive changes of result
+func requiresTypedConfirmation(result *migrator.MigrationResult) bool {
+	return result.HasDestructive && stormConfig != nil && stormConfig.Migrations.RequireConfirmation
+}
+
+// typedConfirmation asks for the name of the database at the terminal before
+// destructive changes are applied to it. Flags such as --yes never stand in
+// for it.
+func typedConfirmation(databaseURL string, result *migrator.MigrationResult) (bool, error) {
+	database := extractDatabaseNameFromURL(databaseURL)
+	if database == "" {
+		return false, fmt.Errorf("cannot confirm destructive changes: no database name in the connection URL (migrations.require_confirmation)")
+	}
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("refusing to apply destructive changes to %s: the configuration requires typing the database name at a terminal (migrations.require_confirmation)", database)
+	}
+	return confirmDestructive(os.Stdin, os.Stdout, database, result)
+}
+
+// confirmDestructive shows the statements about to run and asks for the
+// database name to be typed back
+func confirmDestructive(in io.Reader, out io.Writer, database string, result *migrator.MigrationResult) (bool, error) {
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, result.UpSQL)
+	fmt.Fprintln(out, "Destructive changes:")
+	for _, op := range result.DestructiveOps {
+		fmt.Fprintf(out, "  - %s\n", op)
+	}
+
+	fmt.Fprintf(out, "%s is protected. Type its name to apply these changes: ", database)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	return strings.TrimSpace(answer) == database, nil
+}
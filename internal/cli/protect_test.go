@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentGating(t *testing.T) {
+	origStormConfig := stormConfig
+	t.Cleanup(func() { stormConfig = origStormConfig })
+
+	destructive := &migrator.MigrationResult{HasDestructive: true}
+	safe := &migrator.MigrationResult{}
+
+	stormConfig = nil
+	assert.NoError(t, checkForbidUnsafe(true))
+	assert.False(t, requiresTypedConfirmation(destructive))
+
+	stormConfig = &StormConfig{}
+	stormConfig.Migrations.ForbidUnsafe = true
+	stormConfig.Migrations.RequireConfirmation = true
+	assert.NoError(t, checkForbidUnsafe(false))
+	assert.ErrorContains(t, checkForbidUnsafe(true), "migrations.forbid_unsafe")
+	assert.True(t, requiresTypedConfirmation(destructive))
+	assert.False(t, requiresTypedConfirmation(safe))
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	result := &migrator.MigrationResult{
+		UpSQL:          "DROP TABLE sessions;",
+		HasDestructive: true,
+		DestructiveOps: []string{"Drop table sessions"},
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "app_production\n", want: true},
+		{input: "  app_production  \n", want: true},
+		{input: "y\n", want: false},
+		{input: "APP_PRODUCTION\n", want: false},
+		{input: "", want: false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		confirmed, err := confirmDestructive(strings.NewReader(tt.input), &out, "app_production", result)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, confirmed, "answer %q", tt.input)
+		assert.Contains(t, out.String(), "DROP TABLE sessions;")
+		assert.Contains(t, out.String(), "  - Drop table sessions")
+		assert.Contains(t, out.String(), "app_production is protected. Type its name to apply these changes:")
+	}
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	refreshPackage      string
+	refreshConcurrently bool
+	refreshLock         bool
+)
+
+var refreshCmd = &cobra.Command{
+	Use:   "refresh [view...]",
+	Short: "Refresh materialized views",
+	Long: `Refresh the named materialized views, or without arguments every model of
+--package declared with the materialized_view table-level attribute:
+
+  storm refresh daily_sales --concurrently --lock
+
+--concurrently keeps the views readable while they are refreshed, which needs a
+unique index on each view. --lock guards each refresh with an advisory lock on
+the view name, so a refresh started while another one runs, say from an
+overlapping cron job, is skipped instead of queuing behind it.
+
+Applications refresh from Go with the generated Refresh<Model> helpers, or with
+Storm.RefreshMaterializedView, which take the same lock.`,
+	RunE: runRefresh,
+}
+
+func init() {
+	refreshCmd.Flags().StringVar(&refreshPackage, "package", "", "Path to package containing models")
+	refreshCmd.Flags().BoolVar(&refreshConcurrently, "concurrently", false, "Refresh without locking out readers")
+	refreshCmd.Flags().BoolVar(&refreshLock, "lock", false, "Skip views another refresh is already running for")
+}
+
+func runRefresh(cmd *cobra.Command, args []string) error {
+	views := args
+	if len(views) == 0 {
+		found, err := materializedViewModels()
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("no materialized view models found in %s", refreshPackage)
+		}
+		views = found
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	storm, closeDB, err := openBackfillStorm()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	opts := orm.RefreshOptions{Concurrently: refreshConcurrently, Lock: refreshLock}
+	for _, view := range views {
+		err := storm.RefreshMaterializedView(ctx, view, opts)
+		switch {
+		case errors.Is(err, orm.ErrRefreshInProgress):
+			cmd.Printf("Skipped %s: another refresh is in progress\n", view)
+		case err != nil:
+			return err
+		default:
+			cmd.Printf("Refreshed %s\n", view)
+		}
+	}
+	return nil
+}
+
+// materializedViewModels returns the views of the materialized view models of
+// --package, in order
+func materializedViewModels() ([]string, error) {
+	if refreshPackage == "" && stormConfig != nil && stormConfig.Models.Package != "" {
+		refreshPackage = stormConfig.Models.Package
+	}
+	if refreshPackage == "" {
+		refreshPackage = "./models"
+	}
+
+	tables, err := parser.NewStructParser().ParseDirectory(refreshPackage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse models: %w", err)
+	}
+
+	var views []string
+	for _, table := range tables {
+		if table.IsMaterializedView() {
+			views = append(views, table.TableName)
+		}
+	}
+	sort.Strings(views)
+	return views, nil
+}
@@ -11,8 +11,10 @@ var (
 	configFile  string
 	stormConfig *StormConfig
 	databaseURL string
+	dialect     string
 	debug       bool
 	verbose     bool
+	quiet       bool
 )
 
 func NewRootCommand() *cobra.Command {
@@ -30,7 +32,9 @@ Storm provides powerful tools for:
 		Version: storm.Version,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			// Configure logging
-			if verbose {
+			if quiet {
+				logger.SetLevel(logger.ErrorLevel)
+			} else if verbose {
 				logger.SetLevel(logger.DebugLevel)
 			} else if debug {
 				logger.SetLevel(logger.InfoLevel)
@@ -61,8 +65,10 @@ Storm provides powerful tools for:
 
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default: storm.yaml)")
 	rootCmd.PersistentFlags().StringVar(&databaseURL, "url", "", "database connection URL")
+	rootCmd.PersistentFlags().StringVar(&dialect, "dialect", "", "database dialect: postgres or sqlite (default database.driver of storm.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "enable debug output")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "enable verbose output")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress progress and informational output, e.g. in CI")
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(migrateCmd)
@@ -72,6 +78,15 @@ Storm provides powerful tools for:
 	rootCmd.AddCommand(introspectCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(ormCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(consoleCmd)
+	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(previewCmd)
+	rootCmd.AddCommand(dataCmd)
+	rootCmd.AddCommand(backfillCmd)
+	rootCmd.AddCommand(refreshCmd)
 
 	return rootCmd
 }
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaApplyPackage   string
+	schemaApplyYes       bool
+	schemaVersionPackage string
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Work with the database schema directly",
+}
+
+var schemaApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply model changes to the database without migration files",
+	Long: `Diff the models against the database and apply the changes directly, without
+writing migration files. Meant for preview environments and prototyping, where
+the history of migration files is not needed.
+
+The changes are shown before they are applied and have to be confirmed, unless
+--yes is given. Input that is not a terminal is never taken as confirmation.
+Destructive changes are refused unless --allow-destructive is given. Configs
+with migrations.require_confirmation also ask for the database name to be typed
+before destructive changes, even with --yes, and configs with
+migrations.forbid_unsafe refuse --allow-destructive.
+
+Set migrations.forbid_schema_apply in the config of a production database to
+refuse this command against it.`,
+	RunE: runSchemaApply,
+}
+
+var schemaVersionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show which model revision the database reflects",
+	Long: `Read the latest stamp in storm_schema_info, written by storm migrate --push and
+storm schema apply after each successful migration, and compare its models hash
+with the hash of the current models.
+
+Exits with an error when the database does not reflect the models.`,
+	RunE: runSchemaVersion,
+}
+
+func init() {
+	schemaVersionCmd.Flags().StringVar(&schemaVersionPackage, "package", "", "Path to package containing models")
+	schemaCmd.AddCommand(schemaVersionCmd)
+
+	schemaApplyCmd.Flags().StringVar(&schemaApplyPackage, "package", "", "Path to package containing models")
+	schemaApplyCmd.Flags().BoolVarP(&schemaApplyYes, "yes", "y", false, "Apply without asking for confirmation")
+	schemaApplyCmd.Flags().BoolVar(&allowDestructive, "allow-destructive", false, "Allow potentially destructive operations")
+	schemaApplyCmd.Flags().BoolVar(&strictMode, "strict", false, "Fail on unknown tag attributes and Go types instead of warning")
+	schemaApplyCmd.Flags().BoolVar(&concurrentIndexes, "concurrent-indexes", false, "Build and drop indexes of existing tables with CONCURRENTLY")
+	schemaApplyCmd.Flags().BoolVar(&safeConstraints, "safe-constraints", false, "Add constraints to existing tables NOT VALID and validate them in a separate step")
+	schemaApplyCmd.Flags().BoolVar(&analyzeTables, "analyze", false, "ANALYZE existing tables that get indexes, filled or retyped columns, or updated rows")
+	schemaCmd.AddCommand(schemaApplyCmd)
+}
+
+func runSchemaApply(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	opts := storm.MigrateOptions{
+		PackagePath:       schemaApplyPackage,
+		Strict:            strictMode,
+		ConcurrentIndexes: concurrentIndexes,
+		SafeConstraints:   safeConstraints,
+		Analyze:           analyzeTables,
+	}
+	if stormConfig != nil {
+		if stormConfig.Migrations.ForbidSchemaApply {
+			return fmt.Errorf("schema apply is forbidden by the configuration (migrations.forbid_schema_apply); generate migration files with storm migrate instead")
+		}
+		if err := stormConfig.Migrations.Ignore.Validate(); err != nil {
+			return err
+		}
+		if opts.PackagePath == "" {
+			opts.PackagePath = stormConfig.Models.Package
+		}
+		if !cmd.Flags().Changed("strict") && stormConfig.Schema.StrictMode {
+			opts.Strict = true
+		}
+		if !cmd.Flags().Changed("concurrent-indexes") && stormConfig.Migrations.ConcurrentIndexes {
+			opts.ConcurrentIndexes = true
+		}
+		if !cmd.Flags().Changed("safe-constraints") && stormConfig.Migrations.SafeConstraints {
+			opts.SafeConstraints = true
+		}
+		if !cmd.Flags().Changed("analyze") && stormConfig.Migrations.Analyze {
+			opts.Analyze = true
+		}
+		opts.DataPreservation = stormConfig.Migrations.DataPreservation
+		opts.ForeignKeyOnDelete = stormConfig.Schema.ForeignKeys.OnDelete
+		opts.ForeignKeyOnUpdate = stormConfig.Schema.ForeignKeys.OnUpdate
+		opts.ForeignKeyNaming = stormConfig.Schema.ForeignKeys.Naming
+		opts.Ignore = storm.IgnoreRules(stormConfig.Migrations.Ignore)
+		opts.UpdatedAtTriggers = stormConfig.Schema.UpdatedAtTriggers
+	}
+	if opts.PackagePath == "" {
+		opts.PackagePath = "./models"
+	}
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	if err := checkForbidUnsafe(allowDestructive); err != nil {
+		return err
+	}
+
+	confirm := func(result *migrator.MigrationResult) (bool, error) {
+		if requiresTypedConfirmation(result) {
+			return typedConfirmation(databaseURL, result)
+		}
+		if schemaApplyYes {
+			return true, nil
+		}
+		if !isTerminal(os.Stdin) {
+			return false, fmt.Errorf("refusing to apply without confirmation: rerun with --yes")
+		}
+		return confirmApply(os.Stdin, cmd.OutOrStdout(), extractDatabaseNameFromURL(databaseURL), result)
+	}
+
+	result, err := pushMigration(ctx, databaseURL, opts, allowDestructive, confirm)
+	if err != nil {
+		return err
+	}
+
+	if result.HasDestructive && !allowDestructive {
+		return fmt.Errorf("refusing to apply %d destructive change(s) without --allow-destructive", len(result.DestructiveOps))
+	}
+	return nil
+}
+
+func runSchemaVersion(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if databaseURL == "" {
+		return fmt.Errorf("database connection required: use --url flag or specify in storm.yaml")
+	}
+
+	var foreignKeys generator.ForeignKeyConventions
+	if stormConfig != nil {
+		if schemaVersionPackage == "" {
+			schemaVersionPackage = stormConfig.Models.Package
+		}
+		foreignKeys = generator.ForeignKeyConventions{
+			OnDelete: stormConfig.Schema.ForeignKeys.OnDelete,
+			OnUpdate: stormConfig.Schema.ForeignKeys.OnUpdate,
+			Naming:   stormConfig.Schema.ForeignKeys.Naming,
+		}
+	}
+	if schemaVersionPackage == "" {
+		schemaVersionPackage = "./models"
+	}
+
+	modelsHash, err := migrator.ModelsHash(schemaVersionPackage, foreignKeys)
+	if err != nil {
+		return err
+	}
+
+	db, err := sqlx.Open("postgres", databaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	info, err := orm.NewStorm(db).SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if info == nil {
+		fmt.Fprintf(out, "Database: not stamped\nModels:   %s\n", modelsHash)
+		return fmt.Errorf("%s has no %s; apply the models with storm migrate --push or storm schema apply", extractDatabaseNameFromURL(databaseURL), orm.SchemaInfoTable)
+	}
+
+	fmt.Fprintf(out, "Database: %s (storm %s, %s)\nModels:   %s\n", info.ModelsHash, info.StormVersion, info.GeneratedAt.Format(time.RFC3339), modelsHash)
+	if info.ModelsHash != modelsHash {
+		return fmt.Errorf("the database does not reflect the models in %s", schemaVersionPackage)
+	}
+	fmt.Fprintln(out, "The database reflects the models.")
+	return nil
+}
+
+// confirmApply shows the statements about to run and asks for a yes
+func confirmApply(in io.Reader, out io.Writer, database string, result *migrator.MigrationResult) (bool, error) {
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, result.UpSQL)
+	if len(result.DestructiveOps) > 0 {
+		fmt.Fprintln(out, "Destructive changes:")
+		for _, op := range result.DestructiveOps {
+			fmt.Fprintf(out, "  - %s\n", op)
+		}
+	}
+
+	fmt.Fprintf(out, "Apply these changes to %s? [y/N] ", database)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
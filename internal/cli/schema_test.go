@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmApply(t *testing.T) {
+	result := &migrator.MigrationResult{
+		UpSQL:          "ALTER TABLE users DROP COLUMN nickname;",
+		DestructiveOps: []string{"Drop column users.nickname"},
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{input: "y\n", want: true},
+		{input: " YES \n", want: true},
+		{input: "n\n", want: false},
+		{input: "\n", want: false},
+		{input: "", want: false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		confirmed, err := confirmApply(strings.NewReader(tt.input), &out, "preview_42", result)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, confirmed, "answer %q", tt.input)
+		assert.Contains(t, out.String(), "ALTER TABLE users DROP COLUMN nickname;")
+		assert.Contains(t, out.String(), "  - Drop column users.nickname")
+		assert.Contains(t, out.String(), "Apply these changes to preview_42? [y/N]")
+	}
+}
@@ -9,7 +9,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var verifyPackagePath string
+var (
+	verifyPackagePath string
+	verifyFormat      string
+)
 
 var verifyCmd = &cobra.Command{
 	Use:   "verify",
@@ -23,11 +26,25 @@ This command checks for:
 - Index differences
 - Foreign key constraints
 
+With --format=github, the result is printed as GitHub Actions annotations.
+
 Returns exit code 0 if schema matches, 1 if differences found.`,
 	RunE: runVerify,
 }
 
 func runVerify(cmd *cobra.Command, args []string) error {
+	if err := validateFormat(verifyFormat); err != nil {
+		return err
+	}
+
+	err := verifySchema()
+	if err != nil && verifyFormat == formatGitHub {
+		cmd.Println(annotation{Level: "error", Title: "storm verify", Message: err.Error()})
+	}
+	return err
+}
+
+func verifySchema() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
@@ -56,13 +73,20 @@ func runVerify(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	fmt.Println("Verifying database schema...")
+	if verifyFormat == formatText {
+		fmt.Println("Verifying database schema...")
+	}
 
 	currentSchema, err := stormClient.Introspect(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to introspect database: %w", err)
 	}
 
+	if verifyFormat == formatGitHub {
+		fmt.Println(annotation{Level: "notice", Title: "storm verify", Message: fmt.Sprintf("Found %d tables in database", len(currentSchema.Tables))})
+		return nil
+	}
+
 	fmt.Printf("Found %d tables in database\n", len(currentSchema.Tables))
 
 	for tableName, table := range currentSchema.Tables {
@@ -82,4 +106,5 @@ func init() {
 	verifyCmd.Flags().StringVar(&dbName, "dbname", "", "Database name")
 	verifyCmd.Flags().StringVar(&dbSSLMode, "sslmode", "disable", "SSL mode")
 	verifyCmd.Flags().StringVar(&verifyPackagePath, "package", "./models", "Path to package containing models")
+	addFormatFlag(verifyCmd, &verifyFormat)
 }
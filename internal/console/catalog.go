@@ -0,0 +1,256 @@
+package console
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+// Catalog is the table and column metadata the console completes and resolves
+// expressions against
+type Catalog struct {
+	tables []*Table
+}
+
+// Table is a table or view of the connected database
+type Table struct {
+	Name    string
+	Model   string // Name of the generated column constants, e.g. Users
+	Columns []*Column
+}
+
+// Column is a column of a table
+type Column struct {
+	Name  string
+	Field string // Go field name
+	Type  string
+}
+
+// LoadCatalog reads the tables and views of the schema with their columns
+func LoadCatalog(ctx context.Context, db *sql.DB, schemaName string) (*Catalog, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type
+		FROM information_schema.columns c
+		JOIN information_schema.tables t
+			ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = $1 AND t.table_type IN ('BASE TABLE', 'VIEW')
+		ORDER BY c.table_name, c.ordinal_position`, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tables: %w", err)
+	}
+	defer rows.Close()
+
+	catalog := &Catalog{}
+	var current *Table
+	for rows.Next() {
+		var table string
+		column := &Column{}
+		if err := rows.Scan(&table, &column.Name, &column.Type); err != nil {
+			return nil, fmt.Errorf("failed to read tables: %w", err)
+		}
+		if current == nil || current.Name != table {
+			current = &Table{Name: table, Model: pascalCase(table)}
+			catalog.tables = append(catalog.tables, current)
+		}
+		column.Field = pascalCase(column.Name)
+		current.Columns = append(current.Columns, column)
+	}
+	return catalog, rows.Err()
+}
+
+// AddModels names tables and columns after the models mapped to them, the way the
+// generated code does: the columns of User are Users.Email and so on
+func (c *Catalog) AddModels(models []parser.TableDefinition) {
+	for _, model := range models {
+		table := c.table(model.TableName)
+		if table == nil || table.Name != model.TableName {
+			continue
+		}
+		table.Model = model.StructName + "s"
+		for _, field := range model.Fields {
+			if column := table.column(field.DBName); column != nil {
+				column.Field = field.Name
+			}
+		}
+	}
+}
+
+// Tables returns the tables ordered by name
+func (c *Catalog) Tables() []*Table {
+	return c.tables
+}
+
+// table finds a table by its name or model name, ignoring case
+func (c *Catalog) table(name string) *Table {
+	for _, t := range c.tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	for _, t := range c.tables {
+		if strings.EqualFold(t.Name, name) || strings.EqualFold(t.Model, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// exampleColumn names a column of the table for error messages, e.g. Users.Email
+func (t *Table) exampleColumn() string {
+	if len(t.Columns) == 0 {
+		return t.Model + ".Column"
+	}
+	return t.Model + "." + t.Columns[0].Field
+}
+
+// column finds a column by its name or field name, ignoring case and underscores
+func (t *Table) column(name string) *Column {
+	for _, col := range t.Columns {
+		if col.Name == name || col.Field == name {
+			return col
+		}
+	}
+	for _, col := range t.Columns {
+		if strings.EqualFold(col.Name, name) || strings.EqualFold(strings.ReplaceAll(col.Name, "_", ""), name) {
+			return col
+		}
+	}
+	return nil
+}
+
+var (
+	metaCommands   = []string{`\d`, `\q`, `\sql`, `\?`}
+	queryMethods   = []string{"Count", "Find", "First", "Limit", "Offset", "OrderBy", "Query", "Select", "Where"}
+	columnMethods  = []string{"Asc", "Between", "Contains", "Desc", "EndsWith", "Eq", "Gt", "Gte", "ILike", "In", "IsNotNull", "IsNull", "Like", "Lt", "Lte", "NotEq", "NotIn", "StartsWith"}
+	completionStop = " \t(),=<>!;'\"*+-/"
+)
+
+// Complete completes the word before pos with table names, column names or methods.
+// It returns the completed line and cursor position, and the candidates when the word
+// is ambiguous.
+func (c *Catalog) Complete(line string, pos int) (string, int, []string) {
+	start := strings.LastIndexAny(line[:pos], completionStop) + 1
+	word := line[start:pos]
+
+	// The segments before the partial name, e.g. [models Users] in models.Users.Em
+	var segments []string
+	partial := word
+	if i := strings.LastIndex(word, "."); i >= 0 {
+		segments, partial = strings.Split(word[:i], "."), word[i+1:]
+	}
+
+	// Names typed in the case of a candidate match it, otherwise case is ignored
+	var candidates, folded []string
+	for _, candidate := range c.candidates(line[:start], segments) {
+		if strings.HasPrefix(candidate, partial) {
+			candidates = append(candidates, candidate)
+		} else if len(candidate) >= len(partial) && strings.EqualFold(candidate[:len(partial)], partial) {
+			folded = append(folded, candidate)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = folded
+	}
+	if len(candidates) == 0 {
+		return line, pos, nil
+	}
+
+	completion := candidates[0]
+	for _, candidate := range candidates[1:] {
+		completion = commonPrefix(completion, candidate)
+	}
+	if len(completion) < len(partial) {
+		completion = partial
+	}
+
+	newLine := line[:pos-len(partial)] + completion + line[pos:]
+	newPos := pos - len(partial) + len(completion)
+	if len(candidates) == 1 {
+		return newLine, newPos, nil
+	}
+	return newLine, newPos, candidates
+}
+
+// candidates lists what can follow the dotted segments of the word being completed
+func (c *Catalog) candidates(before string, segments []string) []string {
+	var names []string
+	last := func(n int) string { return segments[len(segments)-n] }
+
+	switch {
+	case len(segments) == 0:
+		if before == "" {
+			names = append(names, metaCommands...)
+		}
+		for _, t := range c.tables {
+			names = append(names, t.Name)
+			if t.Model != t.Name {
+				names = append(names, t.Model)
+			}
+		}
+	case last(1) == "":
+		// After a call, e.g. Users.Query().
+		names = append(names, queryMethods...)
+	case c.table(last(1)) != nil:
+		t := c.table(last(1))
+		for _, col := range t.Columns {
+			if last(1) == t.Name {
+				names = append(names, col.Name)
+			} else {
+				names = append(names, col.Field)
+			}
+		}
+		if last(1) != t.Name {
+			names = append(names, "Query", "Where")
+		}
+	case len(segments) >= 2 && c.table(last(2)) != nil:
+		if c.table(last(2)).column(last(1)) != nil {
+			names = append(names, columnMethods...)
+		}
+	case len(segments) == 1:
+		// A package name, e.g. models.Users
+		for _, t := range c.tables {
+			names = append(names, t.Model)
+		}
+	}
+
+	sort.Strings(names)
+	return compact(names)
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && strings.EqualFold(a[n:n+1], b[n:n+1]) {
+		n++
+	}
+	return a[:n]
+}
+
+func compact(names []string) []string {
+	var unique []string
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+// pascalCase names a table or column the way generated code names it, e.g. user_id
+// becomes UserID
+func pascalCase(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		switch strings.ToLower(part) {
+		case "":
+		case "id", "url", "uuid", "api", "ip":
+			b.WriteString(strings.ToUpper(part))
+		default:
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
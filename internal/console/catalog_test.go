@@ -0,0 +1,110 @@
+package console
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func testCatalog() *Catalog {
+	return &Catalog{tables: []*Table{
+		{Name: "posts", Model: "Posts", Columns: []*Column{
+			{Name: "id", Field: "ID", Type: "integer"},
+			{Name: "title", Field: "Title", Type: "text"},
+			{Name: "user_id", Field: "UserID", Type: "integer"},
+		}},
+		{Name: "users", Model: "Users", Columns: []*Column{
+			{Name: "id", Field: "ID", Type: "integer"},
+			{Name: "email", Field: "Email", Type: "text"},
+			{Name: "email_verified", Field: "EmailVerified", Type: "boolean"},
+			{Name: "age", Field: "Age", Type: "integer"},
+			{Name: "created_at", Field: "CreatedAt", Type: "timestamp with time zone"},
+		}},
+	}}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.columns").WithArgs("public").WillReturnRows(
+		sqlmock.NewRows([]string{"table_name", "column_name", "data_type"}).
+			AddRow("user_profiles", "id", "integer").
+			AddRow("user_profiles", "avatar_url", "text").
+			AddRow("users", "id", "integer"))
+
+	catalog, err := LoadCatalog(context.Background(), db, "public")
+	if err != nil {
+		t.Fatalf("LoadCatalog() error = %v", err)
+	}
+
+	tables := catalog.Tables()
+	if len(tables) != 2 || tables[0].Model != "UserProfiles" || len(tables[0].Columns) != 2 {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	if tables[0].Columns[1].Field != "AvatarURL" {
+		t.Errorf("expected AvatarURL, got %s", tables[0].Columns[1].Field)
+	}
+}
+
+func TestCatalog_AddModels(t *testing.T) {
+	catalog := testCatalog()
+	catalog.AddModels([]parser.TableDefinition{{
+		StructName: "Member",
+		TableName:  "users",
+		Fields:     []parser.FieldDefinition{{Name: "Mail", DBName: "email"}},
+	}})
+
+	users := catalog.table("Members")
+	if users == nil || users.Name != "users" || users.column("Mail").Name != "email" {
+		t.Errorf("expected users to be named after its model, got %+v", users)
+	}
+}
+
+func TestCatalog_Complete(t *testing.T) {
+	catalog := testCatalog()
+
+	tests := []struct {
+		name       string
+		line       string
+		want       string
+		candidates []string
+	}{
+		{"table name", "SELECT * FROM us", "SELECT * FROM users", nil},
+		{"model name", "Po", "Posts", nil},
+		{"sql column", "SELECT users.cr", "SELECT users.created_at", nil},
+		{"field", "Users.Cr", "Users.CreatedAt", nil},
+		{"common prefix", "Users.Em", "Users.Email", []string{"Email", "EmailVerified"}},
+		{"query method", "Users.Query().Wh", "Users.Query().Where", nil},
+		{"column method", "Users.Where(Users.Age.Gt", "Users.Where(Users.Age.Gt", []string{"Gt", "Gte"}},
+		{"package prefix", "models.Us", "models.Users", nil},
+		{"meta command", `\s`, `\sql`, nil},
+		{"describe table", `\d po`, `\d posts`, nil},
+		{"no match", "Users.Zz", "Users.Zz", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, pos, candidates := catalog.Complete(tt.line, len(tt.line))
+			if line != tt.want || pos != len(tt.want) {
+				t.Errorf("Complete(%q) = %q at %d, want %q", tt.line, line, pos, tt.want)
+			}
+			if !reflect.DeepEqual(candidates, tt.candidates) {
+				t.Errorf("Complete(%q) candidates = %v, want %v", tt.line, candidates, tt.candidates)
+			}
+		})
+	}
+}
+
+func TestCatalog_CompleteMidLine(t *testing.T) {
+	line, pos, _ := testCatalog().Complete("SELECT us FROM users", 9)
+	if line != "SELECT users FROM users" || pos != 12 {
+		t.Errorf("got %q at %d", line, pos)
+	}
+}
@@ -0,0 +1,282 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	prompt             = "storm> "
+	continuationPrompt = "   ...> "
+)
+
+// Console runs SQL statements and query expressions against a database and prints
+// the results as tables
+type Console struct {
+	db      *sql.DB
+	catalog *Catalog
+	out     io.Writer
+	pending strings.Builder // SQL statement read so far, until its terminating ;
+}
+
+func New(db *sql.DB, catalog *Catalog, out io.Writer) *Console {
+	return &Console{db: db, catalog: catalog, out: out}
+}
+
+// Run reads inputs from in until EOF or \q, e.g. from a pipe
+func (c *Console) Run(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if quit := c.feed(ctx, scanner.Text()); quit {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// RunTerminal reads inputs from the terminal with line editing, history and tab
+// completion of table names, column names and query methods
+func (c *Console) RunTerminal(ctx context.Context, in, out *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, prompt)
+	if width, height, err := term.GetSize(int(out.Fd())); err == nil {
+		terminal.SetSize(width, height)
+	}
+	terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		newLine, newPos, candidates := c.catalog.Complete(line, pos)
+		if len(candidates) > 1 && newLine == line {
+			fmt.Fprintln(terminal, strings.Join(candidates, "  "))
+		}
+		return newLine, newPos, true
+	}
+	c.out = terminal
+
+	fmt.Fprintf(terminal, "Connected. %d tables. Type \\? for help, \\q to quit.\n", len(c.catalog.Tables()))
+	for {
+		line, err := terminal.ReadLine()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if quit := c.feed(ctx, line); quit {
+			return nil
+		}
+		if c.pending.Len() > 0 {
+			terminal.SetPrompt(continuationPrompt)
+		} else {
+			terminal.SetPrompt(prompt)
+		}
+	}
+}
+
+// feed handles a line of input. Meta commands and expressions are a line each, SQL
+// statements run once a line ends with a semicolon.
+func (c *Console) feed(ctx context.Context, line string) (quit bool) {
+	trimmed := strings.TrimSpace(line)
+	if c.pending.Len() == 0 {
+		switch {
+		case trimmed == "":
+			return false
+		case trimmed == `\q` || trimmed == "exit" || trimmed == "quit":
+			return true
+		case strings.HasPrefix(trimmed, `\`) || c.catalog.IsExpression(trimmed):
+			c.report(c.Execute(ctx, trimmed))
+			return false
+		}
+	}
+
+	c.pending.WriteString(line)
+	c.pending.WriteString("\n")
+	if strings.HasSuffix(trimmed, ";") {
+		input := c.pending.String()
+		c.pending.Reset()
+		c.report(c.Execute(ctx, input))
+	}
+	return false
+}
+
+// Execute runs a meta command, a query expression or a SQL statement
+func (c *Console) Execute(ctx context.Context, input string) error {
+	input = strings.TrimSpace(input)
+	switch {
+	case strings.HasPrefix(input, `\`):
+		return c.meta(ctx, input)
+	case c.catalog.IsExpression(input):
+		query, args, err := c.catalog.Compile(input)
+		if err != nil {
+			return err
+		}
+		return c.query(ctx, query, args...)
+	case returnsRows(input):
+		return c.query(ctx, strings.TrimSuffix(input, ";"))
+	default:
+		started := time.Now()
+		result, err := c.db.ExecContext(ctx, strings.TrimSuffix(input, ";"))
+		if err != nil {
+			return err
+		}
+		affected, _ := result.RowsAffected()
+		fmt.Fprintf(c.out, "OK, %d rows affected (%s)\n", affected, time.Since(started).Round(time.Millisecond))
+		return nil
+	}
+}
+
+func (c *Console) meta(ctx context.Context, input string) error {
+	command, argument, _ := strings.Cut(input, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch command {
+	case `\?`:
+		fmt.Fprint(c.out, help)
+	case `\d`:
+		w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		if argument == "" {
+			fmt.Fprintln(w, "TABLE\tMODEL\tCOLUMNS")
+			for _, t := range c.catalog.Tables() {
+				fmt.Fprintf(w, "%s\t%s\t%d\n", t.Name, t.Model, len(t.Columns))
+			}
+			return w.Flush()
+		}
+		t := c.catalog.table(argument)
+		if t == nil {
+			return fmt.Errorf("table %s not found", argument)
+		}
+		fmt.Fprintln(w, "COLUMN\tFIELD\tTYPE")
+		for _, col := range t.Columns {
+			fmt.Fprintf(w, "%s\t%s.%s\t%s\n", col.Name, t.Model, col.Field, col.Type)
+		}
+		return w.Flush()
+	case `\sql`:
+		query, args, err := c.catalog.Compile(argument)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, query)
+		if len(args) > 0 {
+			fmt.Fprintf(c.out, "-- args: %v\n", args)
+		}
+	default:
+		return fmt.Errorf("unknown command %s, type \\? for help", command)
+	}
+	return nil
+}
+
+func (c *Console) query(ctx context.Context, query string, args ...interface{}) error {
+	started := time.Now()
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+	count := 0
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		cells := make([]string, len(values))
+		for i, value := range values {
+			cells[i] = formatValue(value)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "(%d rows, %s)\n", count, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+func (c *Console) report(err error) {
+	if err != nil {
+		fmt.Fprintf(c.out, "ERROR: %v\n", err)
+	}
+}
+
+// returnsRows reports whether a SQL statement produces a result set
+func returnsRows(statement string) bool {
+	fields := strings.Fields(strings.ToUpper(statement))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "SELECT", "WITH", "SHOW", "VALUES", "TABLE", "EXPLAIN":
+		return true
+	}
+	for _, field := range fields {
+		if field == "RETURNING" {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+const help = `SQL statements run when a line ends with ;
+Query expressions use the generated column names and run as soon as they are entered:
+
+  Users.Query().Where(Users.Email.Like("%@example.com")).OrderBy(Users.CreatedAt.Desc()).Limit(10)
+  Users.Where(Users.Age.Gte(18).And(Users.IsActive.Eq(true))).Count()
+
+Commands:
+  \d          list tables
+  \d TABLE    describe a table
+  \sql EXPR   show the SQL of a query expression without running it
+  \?          show this help
+  \q          quit
+
+Press Tab to complete table names, column names and methods.
+`
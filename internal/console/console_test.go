@@ -0,0 +1,57 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConsole_Run(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT \* FROM users WHERE users.age > \$1 LIMIT 1`).WithArgs(int64(30)).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "email"}).AddRow(1, []byte("ada@example.com")))
+	mock.ExpectQuery(`SELECT count\(\*\)\s+FROM posts`).WillReturnRows(
+		sqlmock.NewRows([]string{"count"}).AddRow(nil))
+	mock.ExpectExec(`DELETE FROM posts WHERE id = 3`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	input := strings.Join([]string{
+		`Users.Where(Users.Age.Gt(30)).First()`,
+		`SELECT count(*)`,
+		`  FROM posts;`,
+		`Users.Query().Join()`,
+		`\d users`,
+		`DELETE FROM posts WHERE id = 3;`,
+		`\q`,
+		`SELECT 1;`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := New(db, testCatalog(), &out).Run(context.Background(), strings.NewReader(input)); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	output := out.String()
+	for _, want := range []string{
+		"ada@example.com",
+		"NULL",
+		"(1 rows, ",
+		"ERROR: unsupported method Join",
+		"email_verified  Users.EmailVerified  boolean",
+		"OK, 1 rows affected",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,320 @@
+package console
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+// call is one method call of an expression chain, e.g. Where(...) in Users.Query().Where(...)
+type call struct {
+	name string
+	args []ast.Expr
+}
+
+// IsExpression reports whether the input is a query expression rather than SQL. An
+// expression starts with a table or model name, optionally behind a package name,
+// followed by a dot: Users.Query()..., models.Users.Where(...).
+func (c *Catalog) IsExpression(input string) bool {
+	input = strings.TrimSpace(input)
+	end := strings.IndexAny(input, "(")
+	if end < 0 {
+		return false
+	}
+	segments := strings.Split(input[:end], ".")
+	if len(segments) < 2 {
+		return false
+	}
+	return c.table(segments[0]) != nil || len(segments) > 2 && c.table(segments[1]) != nil
+}
+
+// Compile turns a query expression written like the generated query builders into SQL:
+//
+//	Users.Query().Where(Users.Email.Like("%@example.com")).OrderBy(Users.CreatedAt.Desc()).Limit(10).Find()
+func (c *Catalog) Compile(input string) (string, []interface{}, error) {
+	expr, err := parser.ParseExpr(strings.TrimSuffix(strings.TrimSpace(input), ";"))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	table, calls, err := c.chain(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := squirrel.Select("*").From(table.Name).PlaceholderFormat(squirrel.Dollar)
+	for _, call := range calls {
+		switch call.name {
+		case "Query", "Find":
+		case "First":
+			query = query.Limit(1)
+		case "Count":
+			query = query.RemoveColumns().Column("COUNT(*)")
+		case "Select":
+			columns := make([]string, len(call.args))
+			for i, arg := range call.args {
+				if columns[i], err = c.columnRef(arg, table); err != nil {
+					return "", nil, err
+				}
+			}
+			query = query.RemoveColumns().Columns(columns...)
+		case "Where":
+			for _, arg := range call.args {
+				condition, err := c.condition(arg, table)
+				if err != nil {
+					return "", nil, err
+				}
+				query = query.Where(condition)
+			}
+		case "OrderBy":
+			for _, arg := range call.args {
+				order, err := c.order(arg, table)
+				if err != nil {
+					return "", nil, err
+				}
+				query = query.OrderBy(order)
+			}
+		case "Limit", "Offset":
+			if len(call.args) != 1 {
+				return "", nil, fmt.Errorf("%s takes one argument", call.name)
+			}
+			n, err := literal(call.args[0])
+			if err != nil {
+				return "", nil, err
+			}
+			count, ok := n.(int64)
+			if !ok || count < 0 {
+				return "", nil, fmt.Errorf("%s takes a positive integer", call.name)
+			}
+			if call.name == "Limit" {
+				query = query.Limit(uint64(count))
+			} else {
+				query = query.Offset(uint64(count))
+			}
+		default:
+			return "", nil, fmt.Errorf("unsupported method %s, expected one of %s", call.name, strings.Join(queryMethods, ", "))
+		}
+	}
+
+	return query.ToSql()
+}
+
+// chain unwinds a chain of method calls down to the table it starts from
+func (c *Catalog) chain(expr ast.Expr) (*Table, []call, error) {
+	switch e := expr.(type) {
+	case *ast.CallExpr:
+		selector, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil, nil, fmt.Errorf("expected a method call, got %s", render(e.Fun))
+		}
+		if table := c.tableRef(selector); table != nil {
+			return table, []call{{name: selector.Sel.Name, args: e.Args}}, nil
+		}
+		table, calls, err := c.chain(selector.X)
+		if err != nil {
+			return nil, nil, err
+		}
+		return table, append(calls, call{name: selector.Sel.Name, args: e.Args}), nil
+	default:
+		return nil, nil, fmt.Errorf("expected a query such as Users.Query(), got %s", render(expr))
+	}
+}
+
+// tableRef resolves the table a selector is called on: Users or models.Users
+func (c *Catalog) tableRef(selector *ast.SelectorExpr) *Table {
+	switch x := selector.X.(type) {
+	case *ast.Ident:
+		return c.table(x.Name)
+	case *ast.SelectorExpr:
+		if _, ok := x.X.(*ast.Ident); ok {
+			return c.table(x.Sel.Name)
+		}
+	}
+	return nil
+}
+
+// columnRef resolves a column of the queried table, e.g. Users.Email, to its SQL name
+func (c *Catalog) columnRef(expr ast.Expr, table *Table) (string, error) {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", fmt.Errorf("expected a column such as %s, got %s", table.exampleColumn(), render(expr))
+	}
+	if owner := c.tableRef(selector); owner != table {
+		return "", fmt.Errorf("%s is not a column of %s", render(expr), table.Name)
+	}
+	column := table.column(selector.Sel.Name)
+	if column == nil {
+		return "", fmt.Errorf("table %s has no column %s", table.Name, selector.Sel.Name)
+	}
+	return table.Name + "." + column.Name, nil
+}
+
+// order compiles an ORDER BY term: Users.Name, Users.Name.Asc() or Users.Name.Desc()
+func (c *Catalog) order(expr ast.Expr, table *Table) (string, error) {
+	if e, ok := expr.(*ast.CallExpr); ok {
+		if selector, ok := e.Fun.(*ast.SelectorExpr); ok && (selector.Sel.Name == "Asc" || selector.Sel.Name == "Desc") {
+			column, err := c.columnRef(selector.X, table)
+			if err != nil {
+				return "", err
+			}
+			return column + " " + strings.ToUpper(selector.Sel.Name), nil
+		}
+	}
+	return c.columnRef(expr, table)
+}
+
+// condition compiles a condition such as Users.Age.Gt(18), combined with And, Or and Not
+func (c *Catalog) condition(expr ast.Expr, table *Table) (squirrel.Sqlizer, error) {
+	e, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil, fmt.Errorf("expected a condition such as %s.Eq(...), got %s", table.exampleColumn(), render(expr))
+	}
+	selector, ok := e.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil, fmt.Errorf("expected a condition, got %s", render(expr))
+	}
+
+	switch selector.Sel.Name {
+	case "And", "Or", "Not":
+		conditions := []squirrel.Sqlizer{}
+		for _, operand := range append([]ast.Expr{selector.X}, e.Args...) {
+			condition, err := c.condition(operand, table)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+		switch selector.Sel.Name {
+		case "And":
+			return squirrel.And(conditions), nil
+		case "Or":
+			return squirrel.Or(conditions), nil
+		default:
+			return squirrel.Expr("NOT (?)", conditions[0]), nil
+		}
+	}
+
+	column, err := c.columnRef(selector.X, table)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(e.Args))
+	for i, arg := range e.Args {
+		if args[i], err = literal(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	arity := map[string]int{"IsNull": 0, "IsNotNull": 0, "Between": 2, "In": -1, "NotIn": -1}
+	want, ok := arity[selector.Sel.Name]
+	if !ok {
+		want = 1
+	}
+	if want >= 0 && len(args) != want {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", selector.Sel.Name, want, len(args))
+	}
+
+	switch selector.Sel.Name {
+	case "Eq":
+		return squirrel.Eq{column: args[0]}, nil
+	case "NotEq":
+		return squirrel.NotEq{column: args[0]}, nil
+	case "In":
+		return squirrel.Eq{column: args}, nil
+	case "NotIn":
+		return squirrel.NotEq{column: args}, nil
+	case "IsNull":
+		return squirrel.Eq{column: nil}, nil
+	case "IsNotNull":
+		return squirrel.NotEq{column: nil}, nil
+	case "Gt":
+		return squirrel.Gt{column: args[0]}, nil
+	case "Gte":
+		return squirrel.GtOrEq{column: args[0]}, nil
+	case "Lt":
+		return squirrel.Lt{column: args[0]}, nil
+	case "Lte":
+		return squirrel.LtOrEq{column: args[0]}, nil
+	case "Between":
+		return squirrel.And{squirrel.GtOrEq{column: args[0]}, squirrel.LtOrEq{column: args[1]}}, nil
+	case "Like":
+		return squirrel.Like{column: args[0]}, nil
+	case "ILike":
+		return squirrel.ILike{column: args[0]}, nil
+	case "StartsWith", "EndsWith", "Contains":
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s takes a string", selector.Sel.Name)
+		}
+		pattern = orm.EscapeLike(pattern)
+		switch selector.Sel.Name {
+		case "StartsWith":
+			pattern += "%"
+		case "EndsWith":
+			pattern = "%" + pattern
+		default:
+			pattern = "%" + pattern + "%"
+		}
+		return squirrel.Like{column: pattern}, nil
+	default:
+		return nil, fmt.Errorf("unsupported condition %s, expected one of %s", selector.Sel.Name, strings.Join(columnMethods, ", "))
+	}
+}
+
+// literal evaluates a literal argument: numbers, strings, true, false and nil
+func literal(expr ast.Expr) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT:
+			return strconv.ParseInt(e.Value, 0, 64)
+		case token.FLOAT:
+			return strconv.ParseFloat(e.Value, 64)
+		case token.STRING, token.CHAR:
+			return strconv.Unquote(e.Value)
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.SUB {
+			value, err := literal(e.X)
+			switch v := value.(type) {
+			case int64:
+				return -v, err
+			case float64:
+				return -v, err
+			}
+		}
+	case *ast.ParenExpr:
+		return literal(e.X)
+	}
+	return nil, fmt.Errorf("expected a literal value, got %s", render(expr))
+}
+
+func render(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return render(e.X) + "." + e.Sel.Name
+	case *ast.CallExpr:
+		return render(e.Fun) + "(...)"
+	case *ast.BasicLit:
+		return e.Value
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
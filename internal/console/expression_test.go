@@ -0,0 +1,111 @@
+package console
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalog_IsExpression(t *testing.T) {
+	catalog := testCatalog()
+
+	for input, want := range map[string]bool{
+		"Users.Query()":                          true,
+		"models.Users.Where(Users.Age.Gt(1))":    true,
+		"users.Query().Find()":                   true,
+		"SELECT users.id FROM users WHERE x(1);": false,
+		"Orders.Query()":                         false,
+		`\d users`:                               false,
+	} {
+		if got := catalog.IsExpression(input); got != want {
+			t.Errorf("IsExpression(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestCatalog_Compile(t *testing.T) {
+	catalog := testCatalog()
+
+	tests := []struct {
+		name  string
+		input string
+		sql   string
+		args  []interface{}
+	}{
+		{
+			name:  "query",
+			input: "Users.Query().Find()",
+			sql:   "SELECT * FROM users",
+		},
+		{
+			name:  "where order limit",
+			input: `models.Users.Query().Where(Users.Email.Like("%@example.com")).OrderBy(Users.CreatedAt.Desc(), Users.ID).Limit(10).Offset(20)`,
+			sql:   "SELECT * FROM users WHERE users.email LIKE $1 ORDER BY users.created_at DESC, users.id LIMIT 10 OFFSET 20",
+			args:  []interface{}{"%@example.com"},
+		},
+		{
+			name:  "combined conditions",
+			input: `Users.Where(Users.Age.Gte(18).And(Users.EmailVerified.Eq(true)), Users.ID.In(1, 2)).Count()`,
+			sql:   "SELECT COUNT(*) FROM users WHERE (users.age >= $1 AND users.email_verified = $2) AND users.id IN ($3,$4)",
+			args:  []interface{}{int64(18), true, int64(1), int64(2)},
+		},
+		{
+			name:  "select first",
+			input: `Posts.Query().Select(Posts.Title).Where(Posts.Title.StartsWith("Go"), Posts.UserID.IsNotNull()).First()`,
+			sql:   "SELECT posts.title FROM posts WHERE posts.title LIKE $1 AND posts.user_id IS NOT NULL LIMIT 1",
+			args:  []interface{}{"Go%"},
+		},
+		{
+			name:  "wildcards match themselves",
+			input: `Posts.Where(Posts.Title.Contains("100%_off"), Posts.Title.EndsWith("\\"))`,
+			sql:   "SELECT * FROM posts WHERE posts.title LIKE $1 AND posts.title LIKE $2",
+			args:  []interface{}{`%100\%\_off%`, `%\\`},
+		},
+		{
+			name:  "negation and between",
+			input: `Users.Where(Users.Age.Between(-1, 2.5).Not())`,
+			sql:   "SELECT * FROM users WHERE NOT ((users.age >= $1 AND users.age <= $2))",
+			args:  []interface{}{int64(-1), 2.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := catalog.Compile(tt.input)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if sql != tt.sql {
+				t.Errorf("Compile() sql =\n%s\nwant\n%s", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("Compile() args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCatalog_CompileErrors(t *testing.T) {
+	catalog := testCatalog()
+
+	tests := map[string]string{
+		"Users.Query().Join()":                    "unsupported method Join",
+		"Users.Where(Users.Nickname.Eq(1))":       "has no column Nickname",
+		"Users.Where(Posts.Title.Eq(1))":          "is not a column of users",
+		"Users.Where(Users.Age.Eq(someVariable))": "expected a literal value",
+		"Users.Where(Users.Age.Between(1))":       "Between takes 2 argument(s)",
+		"Users.Query().Limit(-1)":                 "Limit takes a positive integer",
+		"Users.Query(":                            "invalid expression",
+	}
+
+	for input, want := range tests {
+		if _, _, err := catalog.Compile(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%q) error = %v, want %q", input, err, want)
+		}
+	}
+
+	empty := &Catalog{tables: []*Table{{Name: "audit", Model: "Audit"}}}
+	if _, _, err := empty.Compile("Audit.Where(1)"); err == nil || !strings.Contains(err.Error(), "such as Audit.Column.Eq(...)") {
+		t.Errorf("Compile() of a table without columns error = %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnumChanges holds the statements turning the enum types of one schema into those of
+// another, and the changes PostgreSQL cannot make in place
+type EnumChanges struct {
+	Up     []string
+	Down   []string
+	Unsafe []string // Removed and reordered values, which need a hand-written migration
+}
+
+// GenerateEnumChanges compares the enum types of from and to. New types are created and
+// dropped types dropped; values appended or inserted into an existing type are added with
+// ALTER TYPE ... ADD VALUE, keeping their position. PostgreSQL can neither remove nor
+// reorder enum values, so such types are reported as unsafe and left alone.
+//
+// The down statements drop the new types and create the dropped ones again. Since added
+// values cannot be removed either, a type that gained values is recreated in full with
+// its old values and the columns of to using it are converted back, which fails if rows
+// still hold one of the added values.
+func (g *SQLGenerator) GenerateEnumChanges(from, to *DatabaseSchema) EnumChanges {
+	var changes EnumChanges
+	var created, dropped, recreated []string
+
+	names := make(map[string]bool)
+	for name := range from.EnumTypes {
+		names[name] = true
+	}
+	for name := range to.EnumTypes {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		fromValues, inFrom := from.EnumTypes[name]
+		toValues, inTo := to.EnumTypes[name]
+
+		switch {
+		case !inFrom:
+			changes.Up = append(changes.Up, g.generateEnumType(name, toValues))
+			created = append(created, name)
+		case !inTo:
+			changes.Up = append(changes.Up, fmt.Sprintf("DROP TYPE IF EXISTS %s;", name))
+			dropped = append(dropped, name)
+		default:
+			additions, unsafe := enumValueAdditions(name, fromValues, toValues)
+			if unsafe != "" {
+				changes.Unsafe = append(changes.Unsafe, unsafe)
+				continue
+			}
+			if len(additions) > 0 {
+				changes.Up = append(changes.Up, additions...)
+				recreated = append(recreated, name)
+			}
+		}
+	}
+
+	for _, name := range dropped {
+		changes.Down = append(changes.Down, g.generateEnumType(name, from.EnumTypes[name]))
+	}
+	for _, name := range recreated {
+		changes.Down = append(changes.Down, g.recreateEnumType(name, from, to)...)
+	}
+	for _, name := range created {
+		changes.Down = append(changes.Down, fmt.Sprintf("DROP TYPE IF EXISTS %s;", name))
+	}
+
+	return changes
+}
+
+// enumValueAdditions returns the ADD VALUE statements turning the values from of the
+// enum type name into to, or why that cannot be done in place
+func enumValueAdditions(name string, from, to []string) ([]string, string) {
+	position := make(map[string]int, len(to))
+	for i, value := range to {
+		position[value] = i
+	}
+
+	var removed []string
+	last := -1
+	reordered := false
+	for _, value := range from {
+		i, kept := position[value]
+		switch {
+		case !kept:
+			removed = append(removed, value)
+		case i < last:
+			reordered = true
+		default:
+			last = i
+		}
+	}
+	switch {
+	case len(removed) > 0:
+		return nil, fmt.Sprintf("enum type %s: removing values (%s) requires recreating the type",
+			name, strings.Join(removed, ", "))
+	case reordered:
+		return nil, fmt.Sprintf("enum type %s: reordering values from (%s) to (%s) requires recreating the type",
+			name, strings.Join(from, ", "), strings.Join(to, ", "))
+	}
+
+	existing := make(map[string]bool, len(from))
+	for _, value := range from {
+		existing[value] = true
+	}
+
+	var statements []string
+	remaining := len(from)
+	for i, value := range to {
+		if existing[value] {
+			remaining--
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", name, quoteEnumValue(value))
+		switch {
+		case remaining == 0:
+			// Appended after every existing value
+		case i == 0:
+			stmt += " BEFORE " + quoteEnumValue(from[0])
+		default:
+			stmt += " AFTER " + quoteEnumValue(to[i-1])
+		}
+		statements = append(statements, stmt+";")
+	}
+	return statements, ""
+}
+
+// recreateEnumType returns the statements replacing the enum type name with its
+// definition in from, converting the columns of to that use it
+func (g *SQLGenerator) recreateEnumType(name string, from, to *DatabaseSchema) []string {
+	old := name + "_old"
+	statements := []string{
+		fmt.Sprintf("ALTER TYPE %s RENAME TO %s;", name, old),
+		g.generateEnumType(name, from.EnumTypes[name]),
+	}
+
+	tableNames := make([]string, 0, len(to.Tables))
+	for tableName := range to.Tables {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	for _, tableName := range tableNames {
+		for _, col := range to.Tables[tableName].Columns {
+			if !strings.EqualFold(col.Type, name) {
+				continue
+			}
+			// The default still refers to the renamed type and cannot be cast along
+			defaultValue := col.DefaultValue
+			if fromTable, exists := from.Tables[tableName]; exists {
+				for _, fromCol := range fromTable.Columns {
+					if fromCol.Name == col.Name {
+						defaultValue = fromCol.DefaultValue
+					}
+				}
+			}
+			if col.DefaultValue != nil {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", tableName, col.Name))
+			}
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::text::%s;",
+				tableName, col.Name, name, col.Name, name))
+			if defaultValue != nil {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;",
+					tableName, col.Name, quoteEnumValue(*defaultValue)))
+			}
+		}
+	}
+
+	return append(statements, fmt.Sprintf("DROP TYPE %s;", old))
+}
+
+// quoteEnumValue returns value as a string literal, unless it already is one
+func quoteEnumValue(value string) string {
+	if strings.HasPrefix(value, "'") {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
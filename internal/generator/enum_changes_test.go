@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSQLGenerator_GenerateEnumChanges(t *testing.T) {
+	from := &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {Name: "users", Columns: []SchemaColumn{
+				{Name: "id", Type: "SERIAL", IsPrimaryKey: true},
+				{Name: "status", Type: "user_status", DefaultValue: strPtr("active")},
+			}},
+		},
+		EnumTypes: map[string][]string{
+			"legacy_kind": {"a", "b"},
+			"priority":    {"low", "high"},
+			"user_status": {"active", "inactive"},
+		},
+	}
+	to := &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {Name: "users", Columns: []SchemaColumn{
+				{Name: "id", Type: "SERIAL", IsPrimaryKey: true},
+				{Name: "status", Type: "user_status", DefaultValue: strPtr("pending")},
+			}},
+		},
+		EnumTypes: map[string][]string{
+			"colour":      {"red", "green"},
+			"priority":    {"high", "low"},
+			"user_status": {"new", "pending", "active", "inactive", "banned"},
+		},
+	}
+
+	changes := NewSQLGenerator().GenerateEnumChanges(from, to)
+
+	expectedUp := []string{
+		"CREATE TYPE colour AS ENUM ('red', 'green');",
+		"DROP TYPE IF EXISTS legacy_kind;",
+		"ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'new' BEFORE 'active';",
+		"ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'pending' AFTER 'new';",
+		"ALTER TYPE user_status ADD VALUE IF NOT EXISTS 'banned';",
+	}
+	if !reflect.DeepEqual(changes.Up, expectedUp) {
+		t.Errorf("unexpected up statements:\n got: %q\nwant: %q", changes.Up, expectedUp)
+	}
+
+	expectedDown := []string{
+		"CREATE TYPE legacy_kind AS ENUM ('a', 'b');",
+		"ALTER TYPE user_status RENAME TO user_status_old;",
+		"CREATE TYPE user_status AS ENUM ('active', 'inactive');",
+		"ALTER TABLE users ALTER COLUMN status DROP DEFAULT;",
+		"ALTER TABLE users ALTER COLUMN status TYPE user_status USING status::text::user_status;",
+		"ALTER TABLE users ALTER COLUMN status SET DEFAULT 'active';",
+		"DROP TYPE user_status_old;",
+		"DROP TYPE IF EXISTS colour;",
+	}
+	if !reflect.DeepEqual(changes.Down, expectedDown) {
+		t.Errorf("unexpected down statements:\n got: %q\nwant: %q", changes.Down, expectedDown)
+	}
+
+	expectedUnsafe := []string{"enum type priority: reordering values from (low, high) to (high, low) requires recreating the type"}
+	if !reflect.DeepEqual(changes.Unsafe, expectedUnsafe) {
+		t.Errorf("unsafe = %q, want %q", changes.Unsafe, expectedUnsafe)
+	}
+}
+
+func TestEnumValueAdditions_Removed(t *testing.T) {
+	statements, unsafe := enumValueAdditions("status", []string{"a", "b", "c"}, []string{"a", "d"})
+	if statements != nil || unsafe != "enum type status: removing values (b, c) requires recreating the type" {
+		t.Errorf("got %q, %q", statements, unsafe)
+	}
+}
+
+func TestCompareSchemas_UnsafeEnumChange(t *testing.T) {
+	from := &DatabaseSchema{EnumTypes: map[string][]string{"status": {"a", "b"}}}
+	to := &DatabaseSchema{EnumTypes: map[string][]string{"status": {"a"}}}
+
+	diffs := CompareSchemas(from, to)
+	if len(diffs) != 1 || diffs[0].String() != "enum type status values changed from (a, b) to (a) (unsafe: values removed or reordered)" {
+		t.Errorf("unexpected differences %v", diffs)
+	}
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	parser2 "github.com/eleven-am/storm/internal/parser"
+)
+
+// ForeignKeyConventions are the project-wide defaults applied to every foreign key
+type ForeignKeyConventions struct {
+	// OnDelete is used when neither the field nor its table sets on_delete
+	OnDelete string
+	// OnUpdate is used when neither the field nor its table sets on_update
+	OnUpdate string
+	// Naming is the constraint name pattern, e.g. "fk_{table}_{column}". It may use
+	// {table}, {column}, {ref_table} and {ref_column}; empty keeps PostgreSQL's
+	// <table>_<column>_fkey.
+	Naming string
+}
+
+// referentialActions are the ON DELETE / ON UPDATE actions PostgreSQL accepts
+var referentialActions = map[string]bool{
+	"NO ACTION": true, "RESTRICT": true, "CASCADE": true, "SET NULL": true, "SET DEFAULT": true,
+}
+
+var namingPlaceholders = strings.NewReplacer("{table}", "", "{column}", "", "{ref_table}", "", "{ref_column}", "")
+
+// Validate checks the actions are known and the naming pattern only uses known placeholders
+func (c ForeignKeyConventions) Validate() error {
+	for _, action := range []string{c.OnDelete, c.OnUpdate} {
+		if action != "" && !referentialActions[normalizeReferentialAction(action)] {
+			return fmt.Errorf("unknown foreign key action '%s' (expected cascade, restrict, set_null, set_default or no_action)", action)
+		}
+	}
+	if strings.ContainsAny(namingPlaceholders.Replace(c.Naming), "{}") {
+		return fmt.Errorf("invalid foreign key naming '%s' (use {table}, {column}, {ref_table} and {ref_column})", c.Naming)
+	}
+	return nil
+}
+
+// ConstraintName returns the name of the foreign key from table.column to fk, or "" when
+// no naming pattern is set and PostgreSQL picks the name
+func (c ForeignKeyConventions) ConstraintName(table, column string, fk *ForeignKeyRef) string {
+	if c.Naming == "" {
+		return ""
+	}
+
+	name := strings.NewReplacer(
+		"{table}", table,
+		"{column}", column,
+		"{ref_table}", fk.ReferencedTable,
+		"{ref_column}", fk.ReferencedColumn,
+	).Replace(c.Naming)
+	return TruncateIdentifier(name)
+}
+
+// applyForeignKeyConventions fills in the actions the field left unset, from the table's
+// on_delete/on_update attributes first and the project-wide conventions second, and
+// names the constraint
+func (g *SchemaGenerator) applyForeignKeyConventions(column *SchemaColumn, field parser2.FieldDefinition, tableName string, tableLevel map[string]string) {
+	fk := column.ForeignKey
+	if fk == nil {
+		return
+	}
+
+	if _, set := field.DBDef["on_delete"]; !set {
+		if action := firstNonEmpty(tableLevel["on_delete"], g.foreignKeys.OnDelete); action != "" {
+			fk.OnDelete = normalizeReferentialAction(action)
+		}
+	}
+	if _, set := field.DBDef["on_update"]; !set {
+		if action := firstNonEmpty(tableLevel["on_update"], g.foreignKeys.OnUpdate); action != "" {
+			fk.OnUpdate = normalizeReferentialAction(action)
+		}
+	}
+
+	fk.Name = g.foreignKeys.ConstraintName(tableName, column.Name, fk)
+}
+
+// foreignKeyName returns the constraint name of the foreign key declared by column
+func foreignKeyName(table, column string, fk *ForeignKeyRef) string {
+	if fk != nil && fk.Name != "" {
+		return fk.Name
+	}
+	return fmt.Sprintf("%s_%s_fkey", table, column)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
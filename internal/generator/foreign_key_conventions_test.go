@@ -0,0 +1,146 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+func conventionTables(orderFK map[string]string, tableLevel map[string]string) []parser.TableDefinition {
+	userID := map[string]string{"not_null": "", "foreign_key": "users.id"}
+	for k, v := range orderFK {
+		userID[k] = v
+	}
+
+	return []parser.TableDefinition{
+		{
+			TableName: "users",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+			},
+			TableLevel: map[string]string{},
+		},
+		{
+			TableName: "orders",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+				{Name: "UserID", Type: "int", DBName: "user_id", DBDef: userID},
+			},
+			TableLevel: tableLevel,
+		},
+	}
+}
+
+func orderForeignKey(t *testing.T, gen *SchemaGenerator, tables []parser.TableDefinition) *ForeignKeyRef {
+	t.Helper()
+
+	schema, err := gen.GenerateSchema(tables)
+	if err != nil {
+		t.Fatalf("GenerateSchema() error = %v", err)
+	}
+	fk := schema.Tables["orders"].Columns[1].ForeignKey
+	if fk == nil {
+		t.Fatal("expected orders.user_id to have a foreign key")
+	}
+	return fk
+}
+
+func TestForeignKeyConventions_Actions(t *testing.T) {
+	gen := NewSchemaGenerator()
+	gen.SetForeignKeyConventions(ForeignKeyConventions{OnDelete: "cascade", OnUpdate: "set_null"})
+
+	t.Run("project defaults apply when nothing is set", func(t *testing.T) {
+		fk := orderForeignKey(t, gen, conventionTables(nil, map[string]string{}))
+		if fk.OnDelete != "CASCADE" || fk.OnUpdate != "SET NULL" {
+			t.Errorf("expected CASCADE / SET NULL, got %s / %s", fk.OnDelete, fk.OnUpdate)
+		}
+	})
+
+	t.Run("table attributes override project defaults", func(t *testing.T) {
+		fk := orderForeignKey(t, gen, conventionTables(nil, map[string]string{"table": "orders", "on_delete": "restrict"}))
+		if fk.OnDelete != "RESTRICT" || fk.OnUpdate != "SET NULL" {
+			t.Errorf("expected RESTRICT / SET NULL, got %s / %s", fk.OnDelete, fk.OnUpdate)
+		}
+	})
+
+	t.Run("field attributes win", func(t *testing.T) {
+		fk := orderForeignKey(t, gen, conventionTables(map[string]string{"on_delete": "NO ACTION"}, map[string]string{"on_delete": "restrict"}))
+		if fk.OnDelete != "NO ACTION" {
+			t.Errorf("expected the field's NO ACTION to be kept, got %s", fk.OnDelete)
+		}
+	})
+
+	t.Run("unconfigured generator keeps NO ACTION", func(t *testing.T) {
+		fk := orderForeignKey(t, NewSchemaGenerator(), conventionTables(nil, map[string]string{}))
+		if fk.OnDelete != "NO ACTION" || fk.OnUpdate != "NO ACTION" || fk.Name != "" {
+			t.Errorf("unexpected foreign key %+v", fk)
+		}
+	})
+}
+
+func TestForeignKeyConventions_Naming(t *testing.T) {
+	gen := NewSchemaGenerator()
+	gen.SetForeignKeyConventions(ForeignKeyConventions{Naming: "fk_{table}_{column}_{ref_table}"})
+
+	schema, err := gen.GenerateSchema(conventionTables(nil, map[string]string{}))
+	if err != nil {
+		t.Fatalf("GenerateSchema() error = %v", err)
+	}
+
+	orders := schema.Tables["orders"]
+	if name := orders.Columns[1].ForeignKey.Name; name != "fk_orders_user_id_users" {
+		t.Errorf("expected fk_orders_user_id_users, got %s", name)
+	}
+
+	var constraint string
+	for _, c := range orders.Constraints {
+		if c.Type == "FOREIGN KEY" {
+			constraint = c.Name
+		}
+	}
+	if constraint != "fk_orders_user_id_users" {
+		t.Errorf("expected the implicit constraint to use the configured name, got %s", constraint)
+	}
+
+	ddl := NewSQLGenerator().GenerateCreateTable(orders)
+	if !strings.Contains(ddl, "user_id INTEGER NOT NULL CONSTRAINT fk_orders_user_id_users REFERENCES users(id)") {
+		t.Errorf("expected the inline reference to be named, got:\n%s", ddl)
+	}
+}
+
+func TestForeignKeyConventions_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		conventions ForeignKeyConventions
+		wantErr     string
+	}{
+		{name: "empty", conventions: ForeignKeyConventions{}},
+		{name: "valid", conventions: ForeignKeyConventions{OnDelete: "set_default", OnUpdate: "No Action", Naming: "fk_{table}_{ref_column}"}},
+		{name: "unknown action", conventions: ForeignKeyConventions{OnDelete: "delete"}, wantErr: "unknown foreign key action 'delete'"},
+		{name: "unknown placeholder", conventions: ForeignKeyConventions{Naming: "fk_{tbl}"}, wantErr: "invalid foreign key naming 'fk_{tbl}'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conventions.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestForeignKeyConventions_ConstraintNameTruncated(t *testing.T) {
+	conventions := ForeignKeyConventions{Naming: "fk_{table}_{column}"}
+	name := conventions.ConstraintName(strings.Repeat("t", 40), strings.Repeat("c", 40), &ForeignKeyRef{})
+	if len(name) != MaxIdentifierLength {
+		t.Errorf("expected the name to be truncated to %d characters, got %d", MaxIdentifierLength, len(name))
+	}
+}
@@ -0,0 +1,175 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	dollarTagRe       = regexp.MustCompile(`\$[A-Za-z_]*\$`)
+	argumentDefaultRe = regexp.MustCompile(`(?is)\s*(?:\bDEFAULT\b|=).*$`)
+	functionReturnsRe = regexp.MustCompile(`(?is)^RETURNS\s+(.*?)\s+(?:LANGUAGE|AS|IMMUTABLE|STABLE|VOLATILE|STRICT|CALLED|SECURITY|PARALLEL|COST|ROWS|SET|WINDOW|LEAKPROOF|NOT|TRANSFORM|SUPPORT|BEGIN)\b`)
+	executeFunctionRe = regexp.MustCompile(`(?is)\bEXECUTE\s+(?:FUNCTION|PROCEDURE)\s+((?:"[^"]+"|[\w.]+))`)
+)
+
+// GenerateCreateFunction returns the statement creating fn, or replacing it if it exists
+func (g *SQLGenerator) GenerateCreateFunction(fn SchemaFunction) string {
+	return fmt.Sprintf("CREATE OR REPLACE FUNCTION %s(%s) %s;\n", fn.Name, fn.Arguments, fn.Definition)
+}
+
+// GenerateDropFunction returns the statement dropping fn
+func (g *SQLGenerator) GenerateDropFunction(fn SchemaFunction) string {
+	return fmt.Sprintf("DROP FUNCTION IF EXISTS %s(%s);\n", fn.Name, functionArgumentTypes(fn.Arguments))
+}
+
+// GenerateCreateTrigger returns the statement creating trigger
+func (g *SQLGenerator) GenerateCreateTrigger(trigger SchemaTrigger) string {
+	return fmt.Sprintf("CREATE TRIGGER %s %s;\n", trigger.Name, trigger.Definition)
+}
+
+// GenerateDropTrigger returns the statement dropping trigger
+func (g *SQLGenerator) GenerateDropTrigger(trigger SchemaTrigger) string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\n", trigger.Name, trigger.Table)
+}
+
+// GenerateFunctionChanges returns the statements turning the functions and triggers of
+// from into those of to. Changed functions are replaced in place, unless their arguments
+// or return type change, which CREATE OR REPLACE cannot do: they are dropped and created
+// again, and so are the triggers executing them. Triggers have no CREATE OR REPLACE
+// before PostgreSQL 14, so changed triggers are dropped and created again too.
+// Definitions are compared after normalizing whitespace, case and dollar quote tags.
+func (g *SQLGenerator) GenerateFunctionChanges(from, to *DatabaseSchema) []string {
+	dropFunctions := make(map[string]bool)
+	createFunctions := make(map[string]bool)
+
+	for name, fromFn := range from.Functions {
+		toFn, exists := to.Functions[name]
+		switch {
+		case !exists:
+			dropFunctions[name] = true
+		case normalizeRoutineDefinition(fromFn.Arguments) != normalizeRoutineDefinition(toFn.Arguments),
+			normalizeRoutineDefinition(functionReturns(fromFn)) != normalizeRoutineDefinition(functionReturns(toFn)):
+			dropFunctions[name] = true
+			createFunctions[name] = true
+		case normalizeRoutineDefinition(fromFn.Definition) != normalizeRoutineDefinition(toFn.Definition):
+			createFunctions[name] = true
+		}
+	}
+	for name := range to.Functions {
+		if _, exists := from.Functions[name]; !exists {
+			createFunctions[name] = true
+		}
+	}
+
+	dropTriggers := make(map[string]bool)
+	createTriggers := make(map[string]bool)
+	for key, fromTrigger := range from.Triggers {
+		toTrigger, exists := to.Triggers[key]
+		switch {
+		case !exists:
+			dropTriggers[key] = true
+		case normalizeRoutineDefinition(fromTrigger.Definition) != normalizeRoutineDefinition(toTrigger.Definition),
+			dropFunctions[triggerFunction(fromTrigger)]:
+			// A dropped function takes the triggers executing it along
+			dropTriggers[key] = true
+			createTriggers[key] = true
+		}
+	}
+	for key := range to.Triggers {
+		if _, exists := from.Triggers[key]; !exists {
+			createTriggers[key] = true
+		}
+	}
+
+	// Triggers go before the functions they execute, and come back after them
+	var statements []string
+	for _, key := range sortedKeys(dropTriggers) {
+		statements = append(statements, strings.TrimSpace(g.GenerateDropTrigger(from.Triggers[key])))
+	}
+	for _, name := range sortedKeys(dropFunctions) {
+		statements = append(statements, strings.TrimSpace(g.GenerateDropFunction(from.Functions[name])))
+	}
+	for _, name := range sortedKeys(createFunctions) {
+		statements = append(statements, strings.TrimSpace(g.GenerateCreateFunction(to.Functions[name])))
+	}
+	for _, key := range sortedKeys(createTriggers) {
+		statements = append(statements, strings.TrimSpace(g.GenerateCreateTrigger(to.Triggers[key])))
+	}
+	return statements
+}
+
+// sortedFunctionNames returns the function names of schema in order
+func sortedFunctionNames(schema *DatabaseSchema) []string {
+	names := make([]string, 0, len(schema.Functions))
+	for name := range schema.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedTriggerKeys returns the trigger keys of schema in order
+func sortedTriggerKeys(schema *DatabaseSchema) []string {
+	keys := make([]string, 0, len(schema.Triggers))
+	for key := range schema.Triggers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// functionArgumentTypes returns arguments without their defaults, as DROP FUNCTION
+// accepts names and types only
+func functionArgumentTypes(arguments string) string {
+	var types []string
+	for _, arg := range splitArguments(arguments) {
+		if arg = strings.TrimSpace(argumentDefaultRe.ReplaceAllString(arg, "")); arg != "" {
+			types = append(types, arg)
+		}
+	}
+	return strings.Join(types, ", ")
+}
+
+// splitArguments splits an argument list on the commas outside parentheses
+func splitArguments(arguments string) []string {
+	var args []string
+	depth, start := 0, 0
+	for i, r := range arguments {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, arguments[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, arguments[start:])
+}
+
+// functionReturns returns the return type of fn, or "" when it has none
+func functionReturns(fn SchemaFunction) string {
+	if m := functionReturnsRe.FindStringSubmatch(fn.Definition); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// triggerFunction returns the name of the function trigger executes
+func triggerFunction(trigger SchemaTrigger) string {
+	if m := executeFunctionRe.FindStringSubmatch(trigger.Definition); m != nil {
+		return normalizeIdentifier(m[1])
+	}
+	return ""
+}
+
+// normalizeRoutineDefinition makes function and trigger definitions that differ only
+// in whitespace, keyword case or dollar quote tags compare equal
+func normalizeRoutineDefinition(definition string) string {
+	return normalizeViewDefinition(dollarTagRe.ReplaceAllString(definition, "$$$$"))
+}
@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const touchFunction = `CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+	NEW.updated_at = now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+
+func TestSQLSchemaParser_FunctionsAndTriggers(t *testing.T) {
+	schema, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE users (id SERIAL PRIMARY KEY, updated_at TIMESTAMPTZ);
+		` + touchFunction + `;
+		CREATE FUNCTION add(a integer, b integer DEFAULT 1) RETURNS integer LANGUAGE sql AS 'SELECT a + b';
+		CREATE FUNCTION old_helper() RETURNS void LANGUAGE sql AS $body$ SELECT 1; $body$;
+		CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+		DROP FUNCTION IF EXISTS old_helper();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(schema.Functions) != 2 {
+		t.Fatalf("expected 2 functions, got %+v", schema.Functions)
+	}
+	add := schema.Functions["add"]
+	if add.Arguments != "a integer, b integer DEFAULT 1" || add.Definition != "RETURNS integer LANGUAGE sql AS 'SELECT a + b'" {
+		t.Errorf("add = %+v", add)
+	}
+	if !strings.Contains(schema.Functions["touch_updated_at"].Definition, "NEW.updated_at = now();") {
+		t.Errorf("expected the body to be kept whole, got %+v", schema.Functions["touch_updated_at"])
+	}
+
+	expected := map[string]SchemaTrigger{
+		"users.users_touch": {Name: "users_touch", Table: "users", Definition: "BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at()"},
+	}
+	if !reflect.DeepEqual(schema.Triggers, expected) {
+		t.Errorf("triggers = %+v, want %+v", schema.Triggers, expected)
+	}
+
+	dropped, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE users (id SERIAL PRIMARY KEY);
+		CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+		DROP TABLE users;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dropped.Triggers) != 0 {
+		t.Errorf("expected the triggers to go with their table, got %+v", dropped.Triggers)
+	}
+}
+
+func TestSQLGenerator_GenerateFunctionChanges(t *testing.T) {
+	from := &DatabaseSchema{
+		Functions: map[string]SchemaFunction{
+			"add":     {Name: "add", Arguments: "a integer, b integer DEFAULT 1", Definition: "RETURNS integer LANGUAGE sql AS 'SELECT a + b'"},
+			"audit":   {Name: "audit", Arguments: "", Definition: "RETURNS trigger AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql"},
+			"legacy":  {Name: "legacy", Arguments: "", Definition: "RETURNS void LANGUAGE sql AS 'SELECT 1'"},
+			"touched": {Name: "touched", Arguments: "", Definition: "RETURNS trigger AS $$ BEGIN RETURN NEW; END; $$ LANGUAGE plpgsql"},
+		},
+		Triggers: map[string]SchemaTrigger{
+			"orders.orders_audit": {Name: "orders_audit", Table: "orders", Definition: "AFTER INSERT ON orders FOR EACH ROW EXECUTE FUNCTION audit()"},
+			"users.users_audit":   {Name: "users_audit", Table: "users", Definition: "AFTER INSERT ON users FOR EACH ROW EXECUTE FUNCTION touched()"},
+			"users.users_touch":   {Name: "users_touch", Table: "users", Definition: "BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touched()"},
+		},
+	}
+	to := &DatabaseSchema{
+		Functions: map[string]SchemaFunction{
+			"add":     {Name: "add", Arguments: "a integer, b integer DEFAULT 1", Definition: "RETURNS bigint LANGUAGE sql AS 'SELECT a + b'"},
+			"audit":   {Name: "audit", Arguments: "", Definition: "returns trigger as $fn$\n  BEGIN\n    RETURN NEW;\n  END;\n$fn$ language plpgsql"},
+			"touched": {Name: "touched", Arguments: "", Definition: "RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $$ LANGUAGE plpgsql"},
+		},
+		Triggers: map[string]SchemaTrigger{
+			"orders.orders_audit": {Name: "orders_audit", Table: "orders", Definition: "AFTER INSERT ON orders FOR EACH ROW EXECUTE FUNCTION audit()"},
+			"users.users_touch":   {Name: "users_touch", Table: "users", Definition: "BEFORE INSERT OR UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touched()"},
+		},
+	}
+
+	got := NewSQLGenerator().GenerateFunctionChanges(from, to)
+
+	expected := []string{
+		"DROP TRIGGER IF EXISTS users_audit ON users;",
+		"DROP TRIGGER IF EXISTS users_touch ON users;",
+		"DROP FUNCTION IF EXISTS add(a integer, b integer);",
+		"DROP FUNCTION IF EXISTS legacy();",
+		"CREATE OR REPLACE FUNCTION add(a integer, b integer DEFAULT 1) RETURNS bigint LANGUAGE sql AS 'SELECT a + b';",
+		"CREATE OR REPLACE FUNCTION touched() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $$ LANGUAGE plpgsql;",
+		"CREATE TRIGGER users_touch BEFORE INSERT OR UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touched();",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+
+	var diffs []string
+	for _, diff := range CompareSchemas(from, to) {
+		diffs = append(diffs, diff.String())
+	}
+	expectedDiffs := []string{
+		"function add definition changed",
+		"function legacy dropped",
+		"function touched definition changed",
+		"users: trigger users_audit dropped",
+		"users: trigger users_touch definition changed",
+	}
+	if !reflect.DeepEqual(diffs, expectedDiffs) {
+		t.Errorf("unexpected differences:\n got: %q\nwant: %q", diffs, expectedDiffs)
+	}
+}
+
+func TestSQLGenerator_GenerateSchema_FunctionsAndTriggers(t *testing.T) {
+	schema, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE users (id SERIAL PRIMARY KEY, updated_at TIMESTAMPTZ);
+		` + touchFunction + `;
+		CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := NewSQLSchemaParser().ParseSQL(NewSQLGenerator().GenerateSchema(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Functions, schema.Functions) || !reflect.DeepEqual(parsed.Triggers, schema.Triggers) {
+		t.Errorf("functions and triggers do not round trip: got %+v and %+v", parsed.Functions, parsed.Triggers)
+	}
+}
@@ -0,0 +1,504 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	parser2 "github.com/eleven-am/storm/internal/parser"
+)
+
+// SchemaDifference describes one way the target schema differs from the source schema
+type SchemaDifference struct {
+	Table   string
+	Column  string
+	Index   string // Set for index differences
+	Message string
+}
+
+func (d SchemaDifference) String() string {
+	switch {
+	case d.Table == "":
+		return d.Message
+	case d.Column == "":
+		return fmt.Sprintf("%s: %s", d.Table, d.Message)
+	default:
+		return fmt.Sprintf("%s.%s: %s", d.Table, d.Column, d.Message)
+	}
+}
+
+// CompareSchemas lists how to differs from from: tables, columns (type, nullability,
+// default, primary key, uniqueness, foreign key), unique constraints, indexes, enum
+// types, views, functions, triggers and sequences. Types, defaults and definitions are compared
+// after normalizing spelling, so "int4" matches "INTEGER" and "'active'::text" matches
+// "'active'". CHECK constraints are not compared.
+func CompareSchemas(from, to *DatabaseSchema) []SchemaDifference {
+	var diffs []SchemaDifference
+
+	for _, name := range sortedTableNames(from, to) {
+		fromTable, inFrom := from.Tables[name]
+		toTable, inTo := to.Tables[name]
+
+		switch {
+		case !inFrom:
+			diffs = append(diffs, SchemaDifference{Table: name, Message: "table added"})
+		case !inTo:
+			diffs = append(diffs, SchemaDifference{Table: name, Message: "table dropped"})
+		default:
+			diffs = append(diffs, compareTables(fromTable, toTable)...)
+		}
+	}
+
+	diffs = append(diffs, compareEnumTypes(from.EnumTypes, to.EnumTypes)...)
+	diffs = append(diffs, compareViews(from.Views, to.Views)...)
+	diffs = append(diffs, compareFunctions(from.Functions, to.Functions)...)
+	diffs = append(diffs, compareTriggers(from.Triggers, to.Triggers)...)
+	diffs = append(diffs, compareSequences(from, to)...)
+	return diffs
+}
+
+func compareTables(from, to SchemaTable) []SchemaDifference {
+	var diffs []SchemaDifference
+	report := func(column, format string, args ...interface{}) {
+		diffs = append(diffs, SchemaDifference{Table: to.Name, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+	reportIndex := func(index, format string, args ...interface{}) {
+		diffs = append(diffs, SchemaDifference{Table: to.Name, Index: index, Message: fmt.Sprintf(format, args...)})
+	}
+
+	fromColumns := make(map[string]SchemaColumn, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+	}
+	toColumns := make(map[string]bool, len(to.Columns))
+
+	for _, toCol := range to.Columns {
+		toColumns[toCol.Name] = true
+		fromCol, exists := fromColumns[toCol.Name]
+		if !exists {
+			report(toCol.Name, "column added")
+			continue
+		}
+
+		if normalizeColumnType(fromCol.Type) != normalizeColumnType(toCol.Type) {
+			report(toCol.Name, "type changed from %s to %s", fromCol.Type, toCol.Type)
+		}
+		if fromCol.IsNullable != toCol.IsNullable {
+			report(toCol.Name, "nullable changed from %t to %t", fromCol.IsNullable, toCol.IsNullable)
+		}
+		if fromDefault, toDefault := normalizeColumnDefault(fromCol), normalizeColumnDefault(toCol); fromDefault != toDefault {
+			report(toCol.Name, "default changed from %s to %s", describeDefault(fromCol), describeDefault(toCol))
+		}
+		if fromCol.IsPrimaryKey != toCol.IsPrimaryKey {
+			report(toCol.Name, "primary key changed from %t to %t", fromCol.IsPrimaryKey, toCol.IsPrimaryKey)
+		}
+		if fromRef, toRef := describeForeignKey(fromCol.ForeignKey), describeForeignKey(toCol.ForeignKey); fromRef != toRef {
+			report(toCol.Name, "foreign key changed from %s to %s", fromRef, toRef)
+		}
+		if fromCol.Storage != toCol.Storage {
+			report(toCol.Name, "storage changed from %s to %s", describeSetting(fromCol.Storage), describeSetting(toCol.Storage))
+		}
+		if fromCol.Compression != toCol.Compression {
+			report(toCol.Name, "compression changed from %s to %s", describeSetting(fromCol.Compression), describeSetting(toCol.Compression))
+		}
+	}
+
+	for _, fromCol := range from.Columns {
+		if !toColumns[fromCol.Name] {
+			report(fromCol.Name, "column dropped")
+		}
+	}
+
+	fromUnique, toUnique := uniqueColumnSets(from), uniqueColumnSets(to)
+	for _, set := range sortedKeys(toUnique) {
+		if !fromUnique[set] {
+			report("", "unique constraint on %s added", set)
+		}
+	}
+	for _, set := range sortedKeys(fromUnique) {
+		if !toUnique[set] {
+			report("", "unique constraint on %s dropped", set)
+		}
+	}
+
+	fromIndexes := make(map[string]SchemaIndex, len(from.Indexes))
+	for _, idx := range from.Indexes {
+		fromIndexes[idx.Name] = idx
+	}
+	toIndexes := make(map[string]bool, len(to.Indexes))
+	for _, idx := range to.Indexes {
+		toIndexes[idx.Name] = true
+		fromIdx, exists := fromIndexes[idx.Name]
+		switch {
+		case !exists:
+			reportIndex(idx.Name, "index %s added", idx.Name)
+		case describeIndex(fromIdx) != describeIndex(idx):
+			reportIndex(idx.Name, "index %s changed from %s to %s", idx.Name, describeIndex(fromIdx), describeIndex(idx))
+		}
+	}
+	for _, idx := range from.Indexes {
+		if !toIndexes[idx.Name] {
+			reportIndex(idx.Name, "index %s dropped", idx.Name)
+		}
+	}
+
+	return diffs
+}
+
+func compareEnumTypes(from, to map[string][]string) []SchemaDifference {
+	var diffs []SchemaDifference
+
+	names := make(map[string]bool)
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		fromValues, inFrom := from[name]
+		toValues, inTo := to[name]
+
+		switch {
+		case !inFrom:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("enum type %s added", name)})
+		case !inTo:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("enum type %s dropped", name)})
+		case strings.Join(fromValues, ",") != strings.Join(toValues, ","):
+			message := fmt.Sprintf("enum type %s values changed from (%s) to (%s)",
+				name, strings.Join(fromValues, ", "), strings.Join(toValues, ", "))
+			// Only added values can be applied in place
+			if _, unsafe := enumValueAdditions(name, fromValues, toValues); unsafe != "" {
+				message += " (unsafe: values removed or reordered)"
+			}
+			diffs = append(diffs, SchemaDifference{Message: message})
+		}
+	}
+
+	return diffs
+}
+
+func compareViews(from, to map[string]SchemaView) []SchemaDifference {
+	var diffs []SchemaDifference
+
+	names := make(map[string]bool)
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		fromView, inFrom := from[name]
+		toView, inTo := to[name]
+
+		switch {
+		case !inFrom:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("%s %s added", describeViewKind(toView), name)})
+		case !inTo:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("%s %s dropped", describeViewKind(fromView), name)})
+		case fromView.Materialized != toView.Materialized:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("view %s changed from %s to %s",
+				name, describeViewKind(fromView), describeViewKind(toView))})
+		case normalizeViewDefinition(fromView.Definition) != normalizeViewDefinition(toView.Definition):
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("%s %s definition changed", describeViewKind(toView), name)})
+		}
+	}
+
+	return diffs
+}
+
+func compareFunctions(from, to map[string]SchemaFunction) []SchemaDifference {
+	var diffs []SchemaDifference
+
+	names := make(map[string]bool)
+	for name := range from {
+		names[name] = true
+	}
+	for name := range to {
+		names[name] = true
+	}
+
+	for _, name := range sortedKeys(names) {
+		fromFn, inFrom := from[name]
+		toFn, inTo := to[name]
+
+		switch {
+		case !inFrom:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("function %s added", name)})
+		case !inTo:
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("function %s dropped", name)})
+		case normalizeRoutineDefinition(fromFn.Arguments) != normalizeRoutineDefinition(toFn.Arguments):
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("function %s arguments changed from (%s) to (%s)",
+				name, fromFn.Arguments, toFn.Arguments)})
+		case normalizeRoutineDefinition(fromFn.Definition) != normalizeRoutineDefinition(toFn.Definition):
+			diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf("function %s definition changed", name)})
+		}
+	}
+
+	return diffs
+}
+
+func compareTriggers(from, to map[string]SchemaTrigger) []SchemaDifference {
+	var diffs []SchemaDifference
+
+	keys := make(map[string]bool)
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	for _, key := range sortedKeys(keys) {
+		fromTrigger, inFrom := from[key]
+		toTrigger, inTo := to[key]
+
+		switch {
+		case !inFrom:
+			diffs = append(diffs, SchemaDifference{Table: toTrigger.Table, Message: fmt.Sprintf("trigger %s added", toTrigger.Name)})
+		case !inTo:
+			diffs = append(diffs, SchemaDifference{Table: fromTrigger.Table, Message: fmt.Sprintf("trigger %s dropped", fromTrigger.Name)})
+		case normalizeRoutineDefinition(fromTrigger.Definition) != normalizeRoutineDefinition(toTrigger.Definition):
+			diffs = append(diffs, SchemaDifference{Table: toTrigger.Table, Message: fmt.Sprintf("trigger %s definition changed", toTrigger.Name)})
+		}
+	}
+
+	return diffs
+}
+
+func compareSequences(from, to *DatabaseSchema) []SchemaDifference {
+	var diffs []SchemaDifference
+	report := func(format string, args ...interface{}) {
+		diffs = append(diffs, SchemaDifference{Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range sortedSequenceNames(from) {
+		if _, exists := to.Sequences[name]; !exists {
+			report("sequence %s dropped", name)
+		}
+	}
+	for _, name := range sortedSequenceNames(to) {
+		toSeq := to.Sequences[name]
+		fromSeq, exists := from.Sequences[name]
+		if !exists {
+			report("sequence %s added", name)
+			continue
+		}
+
+		for _, option := range []struct {
+			name     string
+			from, to interface{}
+		}{
+			{"type", fromSeq.DataType, toSeq.DataType},
+			{"increment", fromSeq.Increment, toSeq.Increment},
+			{"minvalue", fromSeq.MinValue, toSeq.MinValue},
+			{"maxvalue", fromSeq.MaxValue, toSeq.MaxValue},
+			{"start", fromSeq.Start, toSeq.Start},
+			{"cache", fromSeq.Cache, toSeq.Cache},
+			{"cycle", fromSeq.Cycle, toSeq.Cycle},
+		} {
+			if option.from != option.to {
+				report("sequence %s %s changed from %v to %v", name, option.name, option.from, option.to)
+			}
+		}
+		if fromSeq.OwnedBy != toSeq.OwnedBy {
+			report("sequence %s owner changed from %s to %s", name, describeSequenceOwner(fromSeq), describeSequenceOwner(toSeq))
+		}
+	}
+
+	return diffs
+}
+
+func describeSequenceOwner(seq SchemaSequence) string {
+	if seq.OwnedBy == "" {
+		return "none"
+	}
+	return seq.OwnedBy
+}
+
+func describeViewKind(view SchemaView) string {
+	if view.Materialized {
+		return "materialized view"
+	}
+	return "view"
+}
+
+// uniqueColumnSets describes the column lists covered by unique columns and unique
+// constraints, along with whether NULLs are distinct in them
+func uniqueColumnSets(table SchemaTable) map[string]bool {
+	sets := make(map[string]bool)
+	for _, col := range table.Columns {
+		if col.IsUnique && !col.IsPrimaryKey {
+			sets["("+col.Name+")"] = true
+		}
+	}
+	for _, constraint := range table.Constraints {
+		if constraint.Type == "UNIQUE" {
+			set := "(" + strings.Join(constraint.Columns, ", ") + ")"
+			if constraint.NullsNotDistinct {
+				set += " NULLS NOT DISTINCT"
+			}
+			sets[set] = true
+		}
+	}
+	return sets
+}
+
+func describeIndex(idx SchemaIndex) string {
+	desc := "(" + strings.Join(idx.Columns, ", ") + ")"
+	if idx.IsUnique {
+		desc = "UNIQUE " + desc
+		if idx.NullsNotDistinct {
+			desc += " NULLS NOT DISTINCT"
+		}
+	}
+	if idx.Type != "" && !strings.EqualFold(idx.Type, "btree") {
+		desc += " USING " + strings.ToLower(idx.Type)
+	}
+	if idx.Where != "" {
+		desc += " WHERE " + strings.Join(strings.Fields(strings.ToLower(idx.Where)), " ")
+	}
+	return desc
+}
+
+func describeForeignKey(fk *ForeignKeyRef) string {
+	if fk == nil {
+		return "none"
+	}
+
+	desc := fmt.Sprintf("%s(%s)", fk.ReferencedTable, fk.ReferencedColumn)
+	if action := normalizeReferentialAction(fk.OnDelete); action != "NO ACTION" {
+		desc += " ON DELETE " + action
+	}
+	if action := normalizeReferentialAction(fk.OnUpdate); action != "NO ACTION" {
+		desc += " ON UPDATE " + action
+	}
+	return desc
+}
+
+func normalizeReferentialAction(action string) string {
+	action = strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(action, "_", " ")), " "))
+	if action == "" {
+		return "NO ACTION"
+	}
+	return action
+}
+
+func describeDefault(col SchemaColumn) string {
+	if col.DefaultValue == nil {
+		return "none"
+	}
+	return *col.DefaultValue
+}
+
+// describeSetting names a column storage setting, where empty stands for the default
+func describeSetting(value string) string {
+	if value == "" {
+		return "default"
+	}
+	return value
+}
+
+// columnTypeAliases maps PostgreSQL's alternative type spellings to a single name
+var columnTypeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int8":        "bigint",
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int2":        "smallint",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"bool":        "boolean",
+	"float8":      "double precision",
+	"float4":      "real",
+	"float":       "double precision",
+	"decimal":     "numeric",
+	"varchar":     "character varying",
+	"char":        "character",
+	"bpchar":      "character",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"timetz":      "time with time zone",
+	"time":        "time without time zone",
+}
+
+var typeModifierRe = regexp.MustCompile(`^([a-z0-9_ ]+?)\s*(\([^)]*\))?((?:\[\])*)$`)
+
+func normalizeColumnType(columnType string) string {
+	t := strings.ToLower(strings.Join(strings.Fields(columnType), " "))
+	t = strings.ReplaceAll(t, `"`, "")
+	t = strings.TrimPrefix(t, "public.")
+
+	m := typeModifierRe.FindStringSubmatch(t)
+	if m == nil {
+		return t
+	}
+
+	base, modifier, array := m[1], strings.ReplaceAll(m[2], " ", ""), m[3]
+	if alias, ok := columnTypeAliases[base]; ok {
+		base = alias
+	}
+	return base + modifier + array
+}
+
+var defaultCastRe = regexp.MustCompile(`::[a-z_][a-z0-9_ ]*(\([0-9, ]*\))?(\[\])*`)
+
+// normalizeColumnDefault formats a default the way it is written to DDL, then drops casts,
+// case and enclosing parentheses so equivalent spellings compare equal
+func normalizeColumnDefault(col SchemaColumn) string {
+	if col.DefaultValue == nil {
+		return ""
+	}
+
+	value := NewSQLGenerator().formatDefaultValue(col.Type, *col.DefaultValue)
+
+	var out strings.Builder
+	inQuote := false
+	for _, r := range value {
+		if r == '\'' {
+			inQuote = !inQuote
+		}
+		if !inQuote {
+			r = unicode.ToLower(r)
+		}
+		out.WriteRune(r)
+	}
+	value = defaultCastRe.ReplaceAllString(out.String(), "")
+
+	for strings.HasPrefix(value, "(") && parser2.TrimEnclosingParens(value) != value {
+		value = parser2.TrimEnclosingParens(value)
+	}
+
+	switch value {
+	case "now()", "current_timestamp":
+		return "current_timestamp"
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func sortedTableNames(schemas ...*DatabaseSchema) []string {
+	names := make(map[string]bool)
+	for _, s := range schemas {
+		for name := range s.Tables {
+			names[name] = true
+		}
+	}
+	return sortedKeys(names)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func roundTripSchema() *DatabaseSchema {
+	gen := NewSchemaGenerator()
+
+	users := SchemaTable{
+		Name: "users",
+		Columns: []SchemaColumn{
+			{Name: "id", Type: "UUID", IsPrimaryKey: true, DefaultValue: strPtr("gen_random_uuid()")},
+			{Name: "email", Type: "VARCHAR(255)", IsUnique: true},
+			{Name: "status", Type: "TEXT", DefaultValue: strPtr("active")},
+			{Name: "role", Type: "users_role_enum", EnumValues: []string{"admin", "member"}},
+			{Name: "created_at", Type: "TIMESTAMPTZ", DefaultValue: strPtr("now()")},
+		},
+		Indexes: []SchemaIndex{
+			{Name: "idx_users_status", Columns: []string{"status"}, Where: "status <> 'deleted'"},
+			{Name: "idx_users_role_email", Columns: []string{"role", "email"}, IsUnique: true, NullsNotDistinct: true},
+		},
+	}
+	posts := SchemaTable{
+		Name: "posts",
+		Columns: []SchemaColumn{
+			{Name: "id", Type: "SERIAL", IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "author_id", Type: "UUID", ForeignKey: &ForeignKeyRef{ReferencedTable: "users", ReferencedColumn: "id", OnDelete: "CASCADE"}},
+			{Name: "slug", Type: "TEXT", Storage: "external", Compression: "lz4"},
+			{Name: "score", Type: "INT", DefaultValue: strPtr("0")},
+		},
+		Constraints: []SchemaConstraint{
+			{Name: "posts_author_slug_key", Type: "UNIQUE", Columns: []string{"author_id", "slug"}, NullsNotDistinct: true},
+		},
+	}
+
+	gen.addImplicitConstraints(&users)
+	gen.addImplicitConstraints(&posts)
+
+	return &DatabaseSchema{
+		Tables:    map[string]SchemaTable{"users": users, "posts": posts},
+		EnumTypes: map[string][]string{"users_role_enum": {"admin", "member"}},
+	}
+}
+
+func TestCompareSchemas_GeneratedSQLRoundTrips(t *testing.T) {
+	expected := roundTripSchema()
+	sql := NewSQLGenerator().GenerateSchema(expected)
+
+	parsed, err := NewSQLSchemaParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("ParseSQL() error = %v\n%s", err, sql)
+	}
+
+	if diffs := CompareSchemas(parsed, expected); len(diffs) != 0 {
+		t.Errorf("expected generated SQL to match its schema, got %v\n%s", diffs, sql)
+	}
+}
+
+func TestCompareSchemas_ReportsDifferences(t *testing.T) {
+	from := roundTripSchema()
+	to := roundTripSchema()
+
+	users := to.Tables["users"]
+	users.Columns[1].Type = "TEXT"
+	users.Columns[1].IsNullable = true
+	users.Columns[2].DefaultValue = strPtr("'inactive'")
+	users.Columns[2].Compression = "lz4"
+	users.Columns = append(users.Columns, SchemaColumn{Name: "nickname", Type: "TEXT", IsNullable: true})
+	users.Indexes[0].Columns = []string{"status", "email"}
+	users.Indexes[1].NullsNotDistinct = false
+	to.Tables["users"] = users
+
+	posts := to.Tables["posts"]
+	posts.Columns[1].ForeignKey = &ForeignKeyRef{ReferencedTable: "users", ReferencedColumn: "id"}
+	posts.Columns = posts.Columns[:3]
+	posts.Constraints = nil
+	to.Tables["posts"] = posts
+
+	to.Tables["tags"] = SchemaTable{Name: "tags"}
+	to.EnumTypes["users_role_enum"] = []string{"admin", "member", "guest"}
+
+	var got []string
+	var indexes []string
+	for _, diff := range CompareSchemas(from, to) {
+		got = append(got, diff.String())
+		if diff.Index != "" {
+			indexes = append(indexes, diff.Index)
+		}
+	}
+
+	expected := []string{
+		"posts.author_id: foreign key changed from users(id) ON DELETE CASCADE to users(id)",
+		"posts.score: column dropped",
+		"posts: unique constraint on (author_id, slug) NULLS NOT DISTINCT dropped",
+		"tags: table added",
+		"users.email: type changed from VARCHAR(255) to TEXT",
+		"users.email: nullable changed from false to true",
+		"users.status: default changed from active to 'inactive'",
+		"users.status: compression changed from default to lz4",
+		"users.nickname: column added",
+		"users: index idx_users_status changed from (status) WHERE status <> 'deleted' to (status, email) WHERE status <> 'deleted'",
+		"users: index idx_users_role_email changed from UNIQUE (role, email) NULLS NOT DISTINCT to UNIQUE (role, email)",
+		"enum type users_role_enum values changed from (admin, member) to (admin, member, guest)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected differences:\n got: %q\nwant: %q", got, expected)
+	}
+	if !reflect.DeepEqual(indexes, []string{"idx_users_status", "idx_users_role_email"}) {
+		t.Errorf("expected index differences to name their index, got %q", indexes)
+	}
+}
+
+func TestNormalizeColumnType(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"int4", "INTEGER"},
+		{"serial", "integer"},
+		{"varchar(255)", "CHARACTER VARYING(255)"},
+		{"numeric(10, 2)", "DECIMAL(10,2)"},
+		{"timestamptz", "timestamp with time zone"},
+		{"int8[]", "BIGINT[]"},
+		{`"public"."mood"`, "mood"},
+	}
+
+	for _, tt := range tests {
+		if normalizeColumnType(tt.a) != normalizeColumnType(tt.b) {
+			t.Errorf("expected %q and %q to normalize equally, got %q and %q", tt.a, tt.b, normalizeColumnType(tt.a), normalizeColumnType(tt.b))
+		}
+	}
+}
@@ -2,13 +2,26 @@ package generator
 
 import (
 	"fmt"
+	"go/token"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/eleven-am/storm/internal/logger"
 	parser2 "github.com/eleven-am/storm/internal/parser"
 )
 
+// MaxIdentifierLength is PostgreSQL's NAMEDATALEN - 1; longer names are silently truncated
+const MaxIdentifierLength = 63
+
+// TruncateIdentifier cuts name to MaxIdentifierLength, the name PostgreSQL stores for it
+func TruncateIdentifier(name string) string {
+	if len(name) > MaxIdentifierLength {
+		return name[:MaxIdentifierLength]
+	}
+	return name
+}
+
 // SchemaColumn represents a column in the target database schema
 type SchemaColumn struct {
 	Name            string
@@ -21,6 +34,9 @@ type SchemaColumn struct {
 	ForeignKey      *ForeignKeyRef
 	CheckConstraint *string
 	EnumValues      []string
+	Storage         string         // Storage strategy set with SET STORAGE, empty for the type's default
+	Compression     string         // Compression method set with SET COMPRESSION, empty for the server's default
+	Pos             token.Position // Source position of the field that declared the column
 }
 
 // ForeignKeyRef represents a foreign key reference
@@ -29,6 +45,7 @@ type ForeignKeyRef struct {
 	ReferencedColumn string
 	OnDelete         string
 	OnUpdate         string
+	Name             string // Constraint name, empty for PostgreSQL's <table>_<column>_fkey
 }
 
 // SchemaTable represents a table in the target database schema
@@ -47,6 +64,13 @@ type SchemaIndex struct {
 	IsPrimary bool
 	Type      string
 	Where     string
+	With      string // Storage parameters, e.g. "m = 16, ef_construction = 64"
+	// Concurrent builds the index with CREATE INDEX CONCURRENTLY when it is added to an
+	// existing table, so writes are not blocked while it is built
+	Concurrent bool
+	// NullsNotDistinct makes a unique index treat NULLs as equal, so at most one row may
+	// have NULL in its columns. Requires PostgreSQL 15.
+	NullsNotDistinct bool
 }
 
 // SchemaConstraint represents a table constraint
@@ -55,17 +79,61 @@ type SchemaConstraint struct {
 	Type       string
 	Definition string
 	Columns    []string
+	// NullsNotDistinct makes a UNIQUE constraint treat NULLs as equal. Requires PostgreSQL 15.
+	NullsNotDistinct bool
+}
+
+// SchemaView represents a view or materialized view
+type SchemaView struct {
+	Name         string
+	Definition   string // The query after AS, without a trailing semicolon
+	Materialized bool
+}
+
+// SchemaFunction represents a user-defined function. Functions are told apart by name
+// only, so overloads are not supported.
+type SchemaFunction struct {
+	Name       string
+	Arguments  string // The argument list, without the parentheses
+	Definition string // Everything after the argument list: RETURNS, LANGUAGE, the body
+}
+
+// SchemaTrigger represents a trigger on a table or view
+type SchemaTrigger struct {
+	Name       string
+	Table      string
+	Definition string // Everything after the trigger name, from the timing to EXECUTE FUNCTION
+}
+
+// SchemaSequence represents a sequence. Options its definition leaves out hold the
+// values PostgreSQL gives them, so sequences written differently compare equal.
+type SchemaSequence struct {
+	Name      string
+	DataType  string // smallint, integer or bigint
+	Start     int64
+	Increment int64
+	MinValue  int64
+	MaxValue  int64
+	Cache     int64
+	Cycle     bool
+	OwnedBy   string // table.column the sequence is dropped with, or "" for none
 }
 
 // DatabaseSchema represents the complete target database schema
 type DatabaseSchema struct {
 	Tables    map[string]SchemaTable
 	EnumTypes map[string][]string
+	Views     map[string]SchemaView
+	Functions map[string]SchemaFunction
+	Triggers  map[string]SchemaTrigger // Keyed by table.name, trigger names being per table
+	Sequences map[string]SchemaSequence
 }
 
 // SchemaGenerator converts parsed struct definitions to database schema
 type SchemaGenerator struct {
-	tagParser *parser2.TagParser
+	tagParser   *parser2.TagParser
+	strict      bool
+	foreignKeys ForeignKeyConventions
 }
 
 func NewSchemaGenerator() *SchemaGenerator {
@@ -74,13 +142,33 @@ func NewSchemaGenerator() *SchemaGenerator {
 	}
 }
 
+// SetStrictMode makes unknown table-level attributes and unknown Go types
+// errors instead of warnings with a TEXT fallback
+func (g *SchemaGenerator) SetStrictMode(enabled bool) {
+	g.strict = enabled
+}
+
+// SetForeignKeyConventions sets the default actions and naming of foreign keys
+func (g *SchemaGenerator) SetForeignKeyConventions(conventions ForeignKeyConventions) {
+	g.foreignKeys = conventions
+}
+
 func (g *SchemaGenerator) GenerateSchema(tables []parser2.TableDefinition) (*DatabaseSchema, error) {
+	if err := g.foreignKeys.Validate(); err != nil {
+		return nil, err
+	}
+
 	schema := &DatabaseSchema{
 		Tables:    make(map[string]SchemaTable),
 		EnumTypes: make(map[string][]string),
 	}
 
 	for _, tableDef := range tables {
+		// Materialized views are created by hand, from a query the model does not know
+		if tableDef.IsMaterializedView() {
+			continue
+		}
+
 		schemaTable, err := g.generateTable(tableDef)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate schema for table %s: %w", tableDef.TableName, err)
@@ -102,6 +190,12 @@ func (g *SchemaGenerator) GenerateSchema(tables []parser2.TableDefinition) (*Dat
 	return schema, nil
 }
 
+// GenerateTable returns the schema of a single table, with its implicit constraints
+// named the way PostgreSQL names them. Foreign keys are not checked against other tables.
+func (g *SchemaGenerator) GenerateTable(tableDef parser2.TableDefinition) (SchemaTable, error) {
+	return g.generateTable(tableDef)
+}
+
 func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (SchemaTable, error) {
 	table := SchemaTable{
 		Name:        tableDef.TableName,
@@ -110,17 +204,28 @@ func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (Schem
 		Constraints: make([]SchemaConstraint, 0),
 	}
 
+	softDelete := ""
 	for _, field := range tableDef.Fields {
+		if !field.IsColumn() {
+			continue
+		}
 		column, err := g.generateColumn(field, tableDef.TableName)
 		if err != nil {
-			return table, fmt.Errorf("failed to generate column %s: %w", field.Name, err)
+			return table, parser2.WithPosition(field.Pos, fmt.Errorf("failed to generate column %s: %w", field.Name, err))
+		}
+		if g.tagParser.HasFlag(field.DBDef, "soft_delete") {
+			if softDelete != "" {
+				return table, parser2.WithPosition(field.Pos, fmt.Errorf("columns %s and %s are both soft_delete columns", softDelete, column.Name))
+			}
+			softDelete = column.Name
 		}
+		g.applyForeignKeyConventions(&column, field, tableDef.TableName, tableDef.TableLevel)
 		table.Columns = append(table.Columns, column)
 	}
 
 	err := g.processTableLevel(tableDef.TableLevel, &table)
 	if err != nil {
-		return table, fmt.Errorf("failed to process table-level definitions: %w", err)
+		return table, parser2.WithPosition(tableDef.Pos, fmt.Errorf("failed to process table-level definitions: %w", err))
 	}
 
 	g.addImplicitConstraints(&table)
@@ -131,6 +236,15 @@ func (g *SchemaGenerator) generateTable(tableDef parser2.TableDefinition) (Schem
 func (g *SchemaGenerator) generateColumn(field parser2.FieldDefinition, tableName string) (SchemaColumn, error) {
 	column := SchemaColumn{
 		Name: field.DBName,
+		Pos:  field.Pos,
+	}
+
+	if g.strict {
+		for key := range field.DBDef {
+			if !parser2.IsKnownFieldAttribute(key) {
+				return column, fmt.Errorf("unknown attribute '%s'", key)
+			}
+		}
 	}
 
 	pgType, err := g.mapGoTypeToPostgreSQL(field.Type, field.DBDef)
@@ -181,9 +295,43 @@ func (g *SchemaGenerator) generateColumn(field parser2.FieldDefinition, tableNam
 	}
 
 	if checkExpr, exists := field.DBDef["check"]; exists {
+		checkExpr = parser2.TrimEnclosingParens(checkExpr)
 		column.CheckConstraint = &checkExpr
 	}
 
+	if storage, exists := field.DBDef["storage"]; exists {
+		if err := parser2.ValidateStorage(storage); err != nil {
+			return column, fmt.Errorf("invalid storage '%s': %w", storage, err)
+		}
+		column.Storage = strings.ToLower(storage)
+	}
+	if compression, exists := field.DBDef["compression"]; exists {
+		if err := parser2.ValidateCompression(compression); err != nil {
+			return column, fmt.Errorf("invalid compression '%s': %w", compression, err)
+		}
+		column.Compression = strings.ToLower(compression)
+	}
+
+	// NULL marks the rows that are not deleted, and the ORM sets the column to now()
+	if g.tagParser.HasFlag(field.DBDef, "soft_delete") {
+		if !column.IsNullable {
+			return column, fmt.Errorf("soft_delete column must be nullable")
+		}
+		if !strings.HasPrefix(strings.ToLower(column.Type), "timestamp") {
+			return column, fmt.Errorf("soft_delete column must be a timestamp, not %s", column.Type)
+		}
+	}
+
+	// The ORM writes these columns with the current time
+	for _, flag := range []string{"auto_create_time", "auto_update_time"} {
+		if g.tagParser.HasFlag(field.DBDef, flag) && !strings.HasPrefix(strings.ToLower(column.Type), "timestamp") {
+			return column, fmt.Errorf("%s column must be a timestamp, not %s", flag, column.Type)
+		}
+	}
+	if g.tagParser.HasFlag(field.DBDef, "auto_create_time") && g.tagParser.HasFlag(field.DBDef, "auto_update_time") {
+		return column, fmt.Errorf("auto_create_time and auto_update_time cannot both be set on a column")
+	}
+
 	if enumValues := g.tagParser.GetEnum(field.DBDef); enumValues != nil {
 		column.EnumValues = enumValues
 
@@ -208,6 +356,12 @@ func (g *SchemaGenerator) mapGoTypeToPostgreSQL(goType string, dbDef map[string]
 			return "CHAR(25)", nil
 		case "cuid2":
 			return "VARCHAR(32)", nil
+		case "ulid":
+			return "CHAR(26)", nil
+		case "nanoid":
+			return "VARCHAR(21)", nil
+		case "vector":
+			return vectorType(dbDef["dimensions"])
 		}
 		return pgType, nil
 	}
@@ -259,12 +413,29 @@ func (g *SchemaGenerator) mapGoTypeToPostgreSQL(goType string, dbDef map[string]
 		return "JSONB", nil
 	case "cuid.CUID", "CUID":
 		return "CHAR(25)", nil
+	case "storm.Vector", "orm.Vector":
+		return vectorType(dbDef["dimensions"])
 	default:
+		if g.strict {
+			return "", fmt.Errorf("unknown Go type '%s' (set a type attribute or disable strict mode)", goType)
+		}
 		logger.Schema().Warn("Unknown Go type '%s', defaulting to TEXT", goType)
 		return "TEXT", nil
 	}
 }
 
+// vectorType returns the pgvector column type, vector(N) when the dimensions are declared
+func vectorType(dimensions string) (string, error) {
+	if dimensions == "" {
+		return "vector", nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(dimensions))
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("invalid vector dimensions '%s': must be a positive integer", dimensions)
+	}
+	return fmt.Sprintf("vector(%d)", n), nil
+}
+
 func (g *SchemaGenerator) parseForeignKeyRef(fkRef string) (*ForeignKeyRef, error) {
 	parts := strings.Split(fkRef, ".")
 	if len(parts) != 2 {
@@ -282,7 +453,7 @@ func (g *SchemaGenerator) parseForeignKeyRef(fkRef string) (*ForeignKeyRef, erro
 func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, table *SchemaTable) error {
 	for key, value := range tableLevelDef {
 		switch key {
-		case "table":
+		case "table", "on_delete", "on_update", "materialized_view":
 			continue
 		case "index":
 			indexes, err := g.parseIndexDefinition(value, table.Name)
@@ -311,6 +482,7 @@ func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, tab
 					indexName := strings.TrimSpace(parts[0])
 					var columns []string
 					var whereClause string
+					var nullsNotDistinct bool
 
 					for i := 1; i < len(parts); i++ {
 						col := strings.TrimSpace(parts[i])
@@ -333,11 +505,22 @@ func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, tab
 						}
 					}
 
+					kept := columns[:0]
+					for _, col := range columns {
+						if strings.EqualFold(col, "nulls_not_distinct") {
+							nullsNotDistinct = true
+							continue
+						}
+						kept = append(kept, col)
+					}
+					columns = kept
+
 					index := SchemaIndex{
-						Name:     indexName,
-						Columns:  columns,
-						IsUnique: true,
-						Where:    whereClause,
+						Name:             indexName,
+						Columns:          columns,
+						IsUnique:         true,
+						Where:            whereClause,
+						NullsNotDistinct: nullsNotDistinct,
 					}
 					table.Indexes = append(table.Indexes, index)
 				} else {
@@ -351,8 +534,13 @@ func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, tab
 					if len(constraint.Columns) == 1 {
 						columnName := constraint.Columns[0]
 						skipConstraint := false
-						for _, col := range table.Columns {
+						for i, col := range table.Columns {
 							if col.Name == columnName && col.IsUnique {
+								// The column's plain UNIQUE gives way to the constraint treating NULLs as equal
+								if constraint.NullsNotDistinct {
+									table.Columns[i].IsUnique = false
+									break
+								}
 								logger.Schema().Debug("Skipping duplicate unique constraint %s for column %s (column already has UNIQUE)", constraint.Name, columnName)
 								skipConstraint = true
 								break
@@ -368,12 +556,26 @@ func (g *SchemaGenerator) processTableLevel(tableLevelDef map[string]string, tab
 				}
 			}
 		case "check":
-			constraint, err := g.parseCheckConstraint(value, table.Name)
-			if err != nil {
-				return fmt.Errorf("failed to parse check constraint: %w", err)
+			unnamed := 0
+			for _, checkDef := range parser2.SplitTopLevel(value, ';') {
+				checkDef = strings.TrimSpace(checkDef)
+				if checkDef == "" {
+					continue
+				}
+				constraint, err := g.parseCheckConstraint(checkDef, table.Name)
+				if err != nil {
+					return fmt.Errorf("failed to parse check constraint: %w", err)
+				}
+				if constraint.Name == "" {
+					constraint.Name = defaultCheckName(table.Name, unnamed)
+					unnamed++
+				}
+				table.Constraints = append(table.Constraints, constraint)
 			}
-			table.Constraints = append(table.Constraints, constraint)
 		default:
+			if g.strict {
+				return fmt.Errorf("unknown table-level attribute '%s'", key)
+			}
 			logger.Schema().Warn("Unknown table-level attribute '%s'", key)
 		}
 	}
@@ -398,6 +600,12 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 			def = def[:whereIdx]
 		}
 
+		var storage string
+		if withIdx := strings.Index(def, " with:"); withIdx != -1 {
+			storage = parser2.TrimEnclosingParens(strings.TrimSpace(def[withIdx+6:]))
+			def = def[:withIdx]
+		}
+
 		var indexType string
 		if usingIdx := strings.Index(def, " using:"); usingIdx != -1 {
 			indexType = def[usingIdx+7:]
@@ -415,12 +623,23 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 			IsUnique: false,
 		}
 
+		if index.Name == "" {
+			return nil, fmt.Errorf("index definition is missing a name: %s", def)
+		}
+
 		if whereClause != "" {
 			index.Where = whereClause
 		}
 		if indexType != "" {
 			index.Type = indexType
 		}
+		if storage != "" {
+			params := parser2.SplitTopLevel(storage, ',')
+			for i := range params {
+				params[i] = strings.TrimSpace(params[i])
+			}
+			index.With = strings.Join(params, ", ")
+		}
 
 		for i := 1; i < len(parts); i++ {
 			part := strings.TrimSpace(parts[i])
@@ -433,6 +652,14 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 				index.IsUnique = true
 				continue
 			}
+			if strings.ToLower(part) == "concurrent" {
+				index.Concurrent = true
+				continue
+			}
+			if strings.ToLower(part) == "nulls_not_distinct" {
+				index.NullsNotDistinct = true
+				continue
+			}
 
 			column := part
 			if strings.HasSuffix(strings.ToLower(part), " desc") {
@@ -447,6 +674,9 @@ func (g *SchemaGenerator) parseIndexDefinition(indexDef, tableName string) ([]Sc
 		if len(index.Columns) == 0 {
 			return nil, fmt.Errorf("index must have at least one column: %s", def)
 		}
+		if index.NullsNotDistinct && !index.IsUnique {
+			return nil, fmt.Errorf("nulls_not_distinct only applies to unique indexes: %s", def)
+		}
 
 		indexes = append(indexes, index)
 	}
@@ -473,6 +703,10 @@ func (g *SchemaGenerator) parseUniqueConstraint(uniqueDef, tableName string) (Sc
 			hasWhere = true
 			break
 		}
+		if strings.EqualFold(col, "nulls_not_distinct") {
+			constraint.NullsNotDistinct = true
+			continue
+		}
 		if col != "" {
 			constraint.Columns = append(constraint.Columns, col)
 		}
@@ -485,19 +719,45 @@ func (g *SchemaGenerator) parseUniqueConstraint(uniqueDef, tableName string) (Sc
 	return constraint, nil
 }
 
+// parseCheckConstraint parses a table-level check of the form "name,expression" or a bare
+// parenthesised "(expression)", which is returned without a name.
+// Commas inside parentheses or quotes belong to the expression.
 func (g *SchemaGenerator) parseCheckConstraint(checkDef, tableName string) (SchemaConstraint, error) {
-	parts := strings.SplitN(checkDef, ",", 2)
-	if len(parts) != 2 {
+	checkDef = strings.TrimSpace(checkDef)
+
+	if strings.HasPrefix(checkDef, "(") && parser2.TrimEnclosingParens(checkDef) != checkDef {
+		return SchemaConstraint{
+			Type:       "CHECK",
+			Definition: parser2.TrimEnclosingParens(checkDef),
+		}, nil
+	}
+
+	parts := parser2.SplitTopLevel(checkDef, ',')
+	if len(parts) < 2 {
+		return SchemaConstraint{}, fmt.Errorf("check constraint must have name and expression: %s", checkDef)
+	}
+
+	name := strings.TrimSpace(parts[0])
+	definition := parser2.TrimEnclosingParens(strings.Join(parts[1:], ","))
+	if name == "" || definition == "" {
 		return SchemaConstraint{}, fmt.Errorf("check constraint must have name and expression: %s", checkDef)
 	}
 
 	return SchemaConstraint{
-		Name:       strings.TrimSpace(parts[0]),
+		Name:       name,
 		Type:       "CHECK",
-		Definition: strings.TrimSpace(parts[1]),
+		Definition: definition,
 	}, nil
 }
 
+// defaultCheckName mirrors PostgreSQL's naming for unnamed table checks: <table>_check, <table>_check1, ...
+func defaultCheckName(tableName string, n int) string {
+	if n == 0 {
+		return tableName + "_check"
+	}
+	return fmt.Sprintf("%s_check%d", tableName, n)
+}
+
 func (g *SchemaGenerator) addImplicitConstraints(table *SchemaTable) {
 	var primaryKeyColumns []string
 
@@ -528,7 +788,7 @@ func (g *SchemaGenerator) addImplicitConstraints(table *SchemaTable) {
 		}
 
 		if column.ForeignKey != nil {
-			constraintName := fmt.Sprintf("%s_%s_fkey", table.Name, column.Name)
+			constraintName := foreignKeyName(table.Name, column.Name, column.ForeignKey)
 
 			definition := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)",
 				column.Name,
@@ -569,68 +829,98 @@ func (s *DatabaseSchema) GetTableNames() []string {
 	for name := range s.Tables {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 
 	sorted := s.sortTablesByDependencies(names)
 	return sorted
 }
 
-func (s *DatabaseSchema) sortTablesByDependencies(tables []string) []string {
-	dependencies := make(map[string][]string)
-	dependents := make(map[string][]string)
+// DeferredForeignKey is a foreign key that closes a reference cycle between tables.
+// It cannot be declared inline in CREATE TABLE and is added with ALTER TABLE once
+// every table in the cycle exists.
+type DeferredForeignKey struct {
+	Table  string
+	Column string
+	Ref    ForeignKeyRef
+}
 
-	for _, table := range tables {
-		dependencies[table] = []string{}
-		dependents[table] = []string{}
+// ConstraintName returns the configured name of the constraint, or the name PostgreSQL would
+// give it if it were declared inline
+func (fk DeferredForeignKey) ConstraintName() string {
+	return foreignKeyName(fk.Table, fk.Column, &fk.Ref)
+}
+
+// CyclicForeignKeys returns the foreign keys that must be deferred for the tables to be
+// created in the order returned by GetTableNames
+func (s *DatabaseSchema) CyclicForeignKeys() []DeferredForeignKey {
+	var names []string
+	for name := range s.Tables {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	for _, tableName := range tables {
-		table := s.Tables[tableName]
-		for _, col := range table.Columns {
-			if col.ForeignKey != nil {
-				refTable := col.ForeignKey.ReferencedTable
-				dependents[tableName] = append(dependents[tableName], refTable)
-				dependencies[refTable] = append(dependencies[refTable], tableName)
-			}
-		}
+	_, deferred := s.planTableOrder(names)
+	return deferred
+}
+
+func (s *DatabaseSchema) sortTablesByDependencies(tables []string) []string {
+	sorted, _ := s.planTableOrder(tables)
+	return sorted
+}
+
+// planTableOrder topologically sorts tables so referenced tables come first. Foreign keys
+// that would close a cycle are returned separately instead of failing the sort.
+// Self-references are not cycles, PostgreSQL accepts them inline.
+func (s *DatabaseSchema) planTableOrder(tables []string) ([]string, []DeferredForeignKey) {
+	included := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		included[table] = true
 	}
 
 	visited := make(map[string]bool)
 	visiting := make(map[string]bool)
 	var result []string
+	var deferred []DeferredForeignKey
 
-	var visit func(string) bool
-	visit = func(table string) bool {
-		if visiting[table] {
-			return false
-		}
-		if visited[table] {
-			return true
-		}
+	var visit func(string)
+	visit = func(tableName string) {
+		visiting[tableName] = true
+
+		for _, col := range s.Tables[tableName].Columns {
+			if col.ForeignKey == nil {
+				continue
+			}
 
-		visiting[table] = true
+			refTable := col.ForeignKey.ReferencedTable
+			if refTable == tableName || !included[refTable] || visited[refTable] {
+				continue
+			}
 
-		for _, dep := range dependents[table] {
-			if !visit(dep) {
-				return false
+			if visiting[refTable] {
+				logger.Schema().Debug("Deferring foreign key %s.%s -> %s to break a reference cycle", tableName, col.Name, refTable)
+				deferred = append(deferred, DeferredForeignKey{
+					Table:  tableName,
+					Column: col.Name,
+					Ref:    *col.ForeignKey,
+				})
+				continue
 			}
+
+			visit(refTable)
 		}
 
-		visiting[table] = false
-		visited[table] = true
-		result = append(result, table)
-		return true
+		visiting[tableName] = false
+		visited[tableName] = true
+		result = append(result, tableName)
 	}
 
 	for _, table := range tables {
 		if !visited[table] {
-			if !visit(table) {
-				sort.Strings(tables)
-				return tables
-			}
+			visit(table)
 		}
 	}
 
-	return result
+	return result, deferred
 }
 
 func (s *DatabaseSchema) HasTable(tableName string) bool {
@@ -652,9 +942,9 @@ func (g *SchemaGenerator) validateForeignKeys(schema *DatabaseSchema) error {
 				referencedTable := column.ForeignKey.ReferencedTable
 
 				if !schema.HasTable(referencedTable) {
-					errors = append(errors, fmt.Sprintf(
+					errors = append(errors, parser2.WithPosition(column.Pos, fmt.Errorf(
 						"table '%s', column '%s': foreign key references non-existent table '%s'",
-						tableName, column.Name, referencedTable))
+						tableName, column.Name, referencedTable)).Error())
 					continue
 				}
 
@@ -668,9 +958,9 @@ func (g *SchemaGenerator) validateForeignKeys(schema *DatabaseSchema) error {
 				}
 
 				if !columnExists {
-					errors = append(errors, fmt.Sprintf(
+					errors = append(errors, parser2.WithPosition(column.Pos, fmt.Errorf(
 						"table '%s', column '%s': foreign key references non-existent column '%s.%s'",
-						tableName, column.Name, referencedTable, column.ForeignKey.ReferencedColumn))
+						tableName, column.Name, referencedTable, column.ForeignKey.ReferencedColumn)).Error())
 				}
 			}
 		}
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseIndexDefinition(f *testing.F) {
+	seeds := []string{
+		"idx_users_email,email",
+		"idx_users_name_email,name,email",
+		"idx_active_users,email where:deleted_at IS NULL",
+		"idx_users_tags,tags using:gin",
+		"idx_a,a;idx_b,b DESC",
+		"idx_unique,email,unique",
+		";;idx_a,a;;",
+		",email",
+		"idx_only_name",
+		"idx_nested,email where:(a = 1 AND (b = 2 OR c = 3))",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	gen := NewSchemaGenerator()
+
+	f.Fuzz(func(t *testing.T, def string) {
+		indexes, err := gen.parseIndexDefinition(def, "users")
+		if err != nil {
+			if err.Error() == "" {
+				t.Fatalf("parseIndexDefinition(%q) returned an empty error message", def)
+			}
+			return
+		}
+
+		for _, index := range indexes {
+			if strings.TrimSpace(index.Name) == "" {
+				t.Fatalf("parseIndexDefinition(%q) accepted an index without a name", def)
+			}
+			if len(index.Columns) == 0 {
+				t.Fatalf("parseIndexDefinition(%q) accepted index %s without columns", def, index.Name)
+			}
+			for _, column := range index.Columns {
+				if strings.TrimSpace(column) == "" {
+					t.Fatalf("parseIndexDefinition(%q) produced an empty column in %s", def, index.Name)
+				}
+			}
+		}
+	})
+}
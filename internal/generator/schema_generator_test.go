@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"go/token"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -181,6 +183,32 @@ func TestSchemaGenerator_GenerateSchema(t *testing.T) {
 	})
 }
 
+func TestSchemaGenerator_GenerateSchema_SkipsMaterializedViews(t *testing.T) {
+	tables := []parser.TableDefinition{
+		{
+			TableName:  "users",
+			Fields:     []parser.FieldDefinition{{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": "true"}}},
+			TableLevel: map[string]string{},
+		},
+		{
+			TableName:  "daily_sales",
+			Fields:     []parser.FieldDefinition{{Name: "Day", Type: "time.Time", DBName: "day", DBDef: map[string]string{"primary_key": "true"}}},
+			TableLevel: map[string]string{"table": "daily_sales", "materialized_view": ""},
+		},
+	}
+
+	schema, err := NewSchemaGenerator().GenerateSchema(tables)
+	if err != nil {
+		t.Fatalf("GenerateSchema failed: %v", err)
+	}
+	if _, exists := schema.Tables["daily_sales"]; exists {
+		t.Error("expected no table for the materialized view model")
+	}
+	if _, exists := schema.Tables["users"]; !exists {
+		t.Error("expected the users table")
+	}
+}
+
 func TestSchemaGenerator_generateTable(t *testing.T) {
 	gen := NewSchemaGenerator()
 
@@ -330,6 +358,97 @@ func TestSchemaGenerator_generateColumn(t *testing.T) {
 		}
 	})
 
+	t.Run("generates storage settings", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:   "Body",
+			Type:   "[]byte",
+			DBName: "body",
+			DBDef:  map[string]string{"storage": "EXTERNAL", "compression": "lz4"},
+		}
+
+		column, err := gen.generateColumn(field, "documents")
+		if err != nil {
+			t.Fatalf("generateColumn failed: %v", err)
+		}
+		if column.Storage != "external" || column.Compression != "lz4" {
+			t.Errorf("expected external storage and lz4 compression, got %q and %q", column.Storage, column.Compression)
+		}
+
+		ddl := NewSQLGenerator().GenerateCreateTable(SchemaTable{Name: "documents", Columns: []SchemaColumn{column}})
+		for _, stmt := range []string{
+			"ALTER TABLE documents ALTER COLUMN body SET STORAGE EXTERNAL;",
+			"ALTER TABLE documents ALTER COLUMN body SET COMPRESSION lz4;",
+		} {
+			if !strings.Contains(ddl, stmt) {
+				t.Errorf("expected %q in:\n%s", stmt, ddl)
+			}
+		}
+
+		field.DBDef = map[string]string{"compression": "zstd"}
+		if _, err := gen.generateColumn(field, "documents"); err == nil || !strings.Contains(err.Error(), "invalid compression 'zstd'") {
+			t.Errorf("expected unknown compression to be refused, got %v", err)
+		}
+	})
+
+	t.Run("checks soft_delete columns", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:      "DeletedAt",
+			Type:      "time.Time",
+			DBName:    "deleted_at",
+			IsPointer: true,
+			DBDef:     map[string]string{"type": "timestamptz", "soft_delete": ""},
+		}
+		if _, err := gen.generateColumn(field, "posts"); err != nil {
+			t.Fatalf("generateColumn failed: %v", err)
+		}
+
+		field.DBDef = map[string]string{"type": "timestamptz", "soft_delete": "", "not_null": ""}
+		field.IsPointer = false
+		if _, err := gen.generateColumn(field, "posts"); err == nil || !strings.Contains(err.Error(), "must be nullable") {
+			t.Errorf("expected a NOT NULL soft_delete column to be refused, got %v", err)
+		}
+
+		field.DBDef = map[string]string{"type": "boolean", "soft_delete": ""}
+		if _, err := gen.generateColumn(field, "posts"); err == nil || !strings.Contains(err.Error(), "must be a timestamp") {
+			t.Errorf("expected a boolean soft_delete column to be refused, got %v", err)
+		}
+
+		_, err := gen.GenerateTable(parser.TableDefinition{
+			StructName: "Post",
+			TableName:  "posts",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int64", DBName: "id", DBDef: map[string]string{"type": "bigserial", "primary_key": ""}},
+				{Name: "DeletedAt", Type: "time.Time", DBName: "deleted_at", IsPointer: true, DBDef: map[string]string{"type": "timestamptz", "soft_delete": ""}},
+				{Name: "ArchivedAt", Type: "time.Time", DBName: "archived_at", IsPointer: true, DBDef: map[string]string{"type": "timestamptz", "soft_delete": ""}},
+			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "both soft_delete columns") {
+			t.Errorf("expected two soft_delete columns to be refused, got %v", err)
+		}
+	})
+
+	t.Run("checks auto timestamp columns", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:   "UpdatedAt",
+			Type:   "time.Time",
+			DBName: "updated_at",
+			DBDef:  map[string]string{"type": "timestamptz", "not_null": "", "auto_update_time": ""},
+		}
+		if _, err := gen.generateColumn(field, "posts"); err != nil {
+			t.Fatalf("generateColumn failed: %v", err)
+		}
+
+		field.DBDef = map[string]string{"type": "date", "auto_create_time": ""}
+		if _, err := gen.generateColumn(field, "posts"); err == nil || !strings.Contains(err.Error(), "auto_create_time column must be a timestamp") {
+			t.Errorf("expected a date auto_create_time column to be refused, got %v", err)
+		}
+
+		field.DBDef = map[string]string{"type": "timestamptz", "auto_create_time": "", "auto_update_time": ""}
+		if _, err := gen.generateColumn(field, "posts"); err == nil || !strings.Contains(err.Error(), "cannot both be set") {
+			t.Errorf("expected both flags on one column to be refused, got %v", err)
+		}
+	})
+
 	t.Run("generates primary key column", func(t *testing.T) {
 		field := parser.FieldDefinition{
 			Name:      "ID",
@@ -428,6 +547,24 @@ func TestSchemaGenerator_generateColumn(t *testing.T) {
 		}
 	})
 
+	t.Run("unwraps parenthesised check constraint", func(t *testing.T) {
+		field := parser.FieldDefinition{
+			Name:   "Discount",
+			Type:   "float64",
+			DBName: "discount",
+			DBDef:  map[string]string{"check": "(discount >= 0 AND discount <= price)"},
+		}
+
+		column, err := gen.generateColumn(field, "products")
+		if err != nil {
+			t.Fatalf("generateColumn failed: %v", err)
+		}
+
+		if column.CheckConstraint == nil || *column.CheckConstraint != "discount >= 0 AND discount <= price" {
+			t.Errorf("unexpected check constraint: %v", column.CheckConstraint)
+		}
+	})
+
 	t.Run("generates column with check constraint", func(t *testing.T) {
 		field := parser.FieldDefinition{
 			Name:      "Age",
@@ -521,6 +658,11 @@ func TestSchemaGenerator_mapGoTypeToPostgreSQL(t *testing.T) {
 		{"custom type with explicit db type", "CustomType", map[string]string{"type": "VARCHAR(255)"}, "VARCHAR(255)"},
 		{"CUID type", "string", map[string]string{"type": "cuid"}, "CHAR(25)"},
 		{"CUID2 type", "string", map[string]string{"type": "cuid2"}, "VARCHAR(32)"},
+		{"ULID type", "string", map[string]string{"type": "ulid"}, "CHAR(26)"},
+		{"NanoID type", "string", map[string]string{"type": "nanoid"}, "VARCHAR(21)"},
+		{"vector with dimensions", "storm.Vector", map[string]string{"dimensions": "1536"}, "vector(1536)"},
+		{"vector without dimensions", "storm.Vector", map[string]string{}, "vector"},
+		{"explicit vector type", "[]float32", map[string]string{"type": "vector", "dimensions": "3"}, "vector(3)"},
 		{"unknown type", "UnknownType", map[string]string{}, "TEXT"},
 	}
 
@@ -535,6 +677,89 @@ func TestSchemaGenerator_mapGoTypeToPostgreSQL(t *testing.T) {
 			}
 		})
 	}
+
+	if _, err := gen.mapGoTypeToPostgreSQL("storm.Vector", map[string]string{"dimensions": "0"}); err == nil {
+		t.Error("expected an error for non-positive vector dimensions")
+	}
+}
+
+func TestSchemaGenerator_StrictMode(t *testing.T) {
+	pos := token.Position{Filename: "models/user.go", Line: 12, Column: 2}
+
+	t.Run("unknown Go type is an error", func(t *testing.T) {
+		gen := NewSchemaGenerator()
+		gen.SetStrictMode(true)
+
+		_, err := gen.GenerateSchema([]parser.TableDefinition{{
+			TableName: "users",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+				{Name: "Address", Type: "Address", DBName: "address", DBDef: map[string]string{}, Pos: pos},
+			},
+			TableLevel: map[string]string{},
+		}})
+		if err == nil {
+			t.Fatal("expected error for unknown Go type in strict mode")
+		}
+		if !strings.Contains(err.Error(), "models/user.go:12:2") || !strings.Contains(err.Error(), "unknown Go type 'Address'") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unknown field attribute is an error", func(t *testing.T) {
+		gen := NewSchemaGenerator()
+		gen.SetStrictMode(true)
+
+		_, err := gen.GenerateSchema([]parser.TableDefinition{{
+			TableName: "users",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": "", "nullable": ""}, Pos: pos},
+			},
+			TableLevel: map[string]string{},
+		}})
+		if err == nil || !strings.Contains(err.Error(), "failed to generate column ID: unknown attribute 'nullable'") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("unknown table-level attribute is an error", func(t *testing.T) {
+		gen := NewSchemaGenerator()
+		gen.SetStrictMode(true)
+
+		_, err := gen.GenerateSchema([]parser.TableDefinition{{
+			TableName: "users",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+			},
+			TableLevel: map[string]string{"colour": "red"},
+			Pos:        pos,
+		}})
+		if err == nil {
+			t.Fatal("expected error for unknown table-level attribute in strict mode")
+		}
+		if !strings.Contains(err.Error(), "models/user.go:12:2") || !strings.Contains(err.Error(), "unknown table-level attribute 'colour'") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("non-strict mode falls back to TEXT", func(t *testing.T) {
+		gen := NewSchemaGenerator()
+
+		schema, err := gen.GenerateSchema([]parser.TableDefinition{{
+			TableName: "users",
+			Fields: []parser.FieldDefinition{
+				{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+				{Name: "Address", Type: "Address", DBName: "address", DBDef: map[string]string{}},
+			},
+			TableLevel: map[string]string{"colour": "red"},
+		}})
+		if err != nil {
+			t.Fatalf("GenerateSchema failed: %v", err)
+		}
+		if got := schema.Tables["users"].Columns[1].Type; got != "TEXT" {
+			t.Errorf("expected TEXT fallback, got %s", got)
+		}
+	})
 }
 
 func TestSchemaGenerator_parseForeignKeyRef(t *testing.T) {
@@ -678,6 +903,48 @@ func TestSchemaGenerator_processTableLevel(t *testing.T) {
 		}
 	})
 
+	t.Run("processes multiple and unnamed check constraints", func(t *testing.T) {
+		table := &SchemaTable{
+			Name:        "orders",
+			Columns:     []SchemaColumn{},
+			Indexes:     []SchemaIndex{},
+			Constraints: []SchemaConstraint{},
+		}
+
+		tableLevelDef := map[string]string{
+			"check": "ck_status,status IN ('open', 'closed');(price > 0 AND discount <= price);(quantity > 0)",
+		}
+
+		err := gen.processTableLevel(tableLevelDef, table)
+		if err != nil {
+			t.Fatalf("processTableLevel failed: %v", err)
+		}
+
+		expected := []SchemaConstraint{
+			{Name: "ck_status", Type: "CHECK", Definition: "status IN ('open', 'closed')"},
+			{Name: "orders_check", Type: "CHECK", Definition: "price > 0 AND discount <= price"},
+			{Name: "orders_check1", Type: "CHECK", Definition: "quantity > 0"},
+		}
+		if len(table.Constraints) != len(expected) {
+			t.Fatalf("expected %d constraints, got %d: %+v", len(expected), len(table.Constraints), table.Constraints)
+		}
+		for i, want := range expected {
+			got := table.Constraints[i]
+			if got.Name != want.Name || got.Type != want.Type || got.Definition != want.Definition {
+				t.Errorf("constraint %d = %+v, want %+v", i, got, want)
+			}
+		}
+	})
+
+	t.Run("rejects check constraint without expression", func(t *testing.T) {
+		table := &SchemaTable{Name: "orders"}
+
+		err := gen.processTableLevel(map[string]string{"check": "ck_missing"}, table)
+		if err == nil {
+			t.Fatal("expected error for check constraint without expression")
+		}
+	})
+
 	t.Run("handles unique constraint with where clause", func(t *testing.T) {
 		table := &SchemaTable{
 			Name:        "users",
@@ -708,6 +975,40 @@ func TestSchemaGenerator_processTableLevel(t *testing.T) {
 		}
 	})
 
+	t.Run("handles unique declarations with nulls_not_distinct", func(t *testing.T) {
+		table := &SchemaTable{Name: "users"}
+
+		tableLevelDef := map[string]string{
+			"unique": "uq_users_phone,country,phone,nulls_not_distinct;idx_active_users,email,nulls_not_distinct where:active = true",
+		}
+
+		err := gen.processTableLevel(tableLevelDef, table)
+		if err != nil {
+			t.Fatalf("processTableLevel failed: %v", err)
+		}
+
+		if len(table.Constraints) != 1 || len(table.Indexes) != 1 {
+			t.Fatalf("expected a constraint and a partial index, got %+v and %+v", table.Constraints, table.Indexes)
+		}
+		constraint := table.Constraints[0]
+		if !constraint.NullsNotDistinct || !reflect.DeepEqual(constraint.Columns, []string{"country", "phone"}) {
+			t.Errorf("unexpected constraint %+v", constraint)
+		}
+		index := table.Indexes[0]
+		if !index.NullsNotDistinct || !reflect.DeepEqual(index.Columns, []string{"email"}) || index.Where != "active = true" {
+			t.Errorf("unexpected index %+v", index)
+		}
+
+		ddl := NewSQLGenerator().GenerateCreateTable(SchemaTable{
+			Name:        "users",
+			Columns:     []SchemaColumn{{Name: "country", Type: "TEXT"}, {Name: "phone", Type: "TEXT"}},
+			Constraints: table.Constraints,
+		})
+		if !strings.Contains(ddl, "CONSTRAINT uq_users_phone UNIQUE NULLS NOT DISTINCT (country, phone)") {
+			t.Errorf("expected the constraint to treat NULLs as equal, got:\n%s", ddl)
+		}
+	})
+
 	t.Run("ignores unknown table-level attributes", func(t *testing.T) {
 		table := &SchemaTable{
 			Name:        "users",
@@ -795,6 +1096,50 @@ func TestSchemaGenerator_parseIndexDefinition(t *testing.T) {
 		}
 	})
 
+	t.Run("parses index with concurrent flag", func(t *testing.T) {
+		indexes, err := gen.parseIndexDefinition("idx_orders_created,created_at,concurrent", "orders")
+		if err != nil {
+			t.Fatalf("parseIndexDefinition failed: %v", err)
+		}
+
+		if len(indexes) != 1 {
+			t.Fatalf("expected 1 index, got %d", len(indexes))
+		}
+
+		index := indexes[0]
+		if !index.Concurrent {
+			t.Error("index should be built concurrently")
+		}
+		if len(index.Columns) != 1 || index.Columns[0] != "created_at" {
+			t.Errorf("expected columns [created_at], got %v", index.Columns)
+		}
+	})
+
+	t.Run("parses unique index with nulls_not_distinct", func(t *testing.T) {
+		indexes, err := gen.parseIndexDefinition("idx_users_phone,phone,unique,nulls_not_distinct", "users")
+		if err != nil {
+			t.Fatalf("parseIndexDefinition failed: %v", err)
+		}
+
+		index := indexes[0]
+		if !index.IsUnique || !index.NullsNotDistinct {
+			t.Errorf("expected a unique index treating NULLs as equal, got %+v", index)
+		}
+		if len(index.Columns) != 1 || index.Columns[0] != "phone" {
+			t.Errorf("expected columns [phone], got %v", index.Columns)
+		}
+
+		ddl := NewSQLGenerator().GenerateIndexDDL("users", index)
+		expected := "CREATE UNIQUE INDEX idx_users_phone ON users (phone) NULLS NOT DISTINCT;\n"
+		if ddl != expected {
+			t.Errorf("expected %q, got %q", expected, ddl)
+		}
+
+		if _, err := gen.parseIndexDefinition("idx_users_phone,phone,nulls_not_distinct", "users"); err == nil {
+			t.Error("expected nulls_not_distinct to be refused on a non-unique index")
+		}
+	})
+
 	t.Run("parses index with where clause", func(t *testing.T) {
 		indexes, err := gen.parseIndexDefinition("idx_active_users,email where:active = true", "users")
 		if err != nil {
@@ -827,6 +1172,27 @@ func TestSchemaGenerator_parseIndexDefinition(t *testing.T) {
 		}
 	})
 
+	t.Run("parses vector index with operator class and storage parameters", func(t *testing.T) {
+		indexes, err := gen.parseIndexDefinition("idx_items_embedding,embedding vector_cosine_ops using:hnsw with:m=16,ef_construction=64 where:embedding IS NOT NULL", "items")
+		if err != nil {
+			t.Fatalf("parseIndexDefinition failed: %v", err)
+		}
+
+		index := indexes[0]
+		if index.Type != "hnsw" || index.With != "m=16, ef_construction=64" || index.Where != "embedding IS NOT NULL" {
+			t.Errorf("unexpected index %+v", index)
+		}
+		if len(index.Columns) != 1 || index.Columns[0] != "embedding vector_cosine_ops" {
+			t.Errorf("expected the operator class to be kept with the column, got %v", index.Columns)
+		}
+
+		ddl := NewSQLGenerator().GenerateIndexDDL("items", index)
+		expected := "CREATE INDEX idx_items_embedding ON items USING hnsw (embedding vector_cosine_ops) WITH (m=16, ef_construction=64) WHERE embedding IS NOT NULL;\n"
+		if ddl != expected {
+			t.Errorf("expected %q, got %q", expected, ddl)
+		}
+	})
+
 	t.Run("parses multiple indexes", func(t *testing.T) {
 		indexes, err := gen.parseIndexDefinition("idx_users_email,email;idx_users_name,name", "users")
 		if err != nil {
@@ -1169,6 +1535,38 @@ func TestDatabaseSchema_sortTablesByDependencies(t *testing.T) {
 	}
 }
 
+func TestDatabaseSchema_CyclicForeignKeys(t *testing.T) {
+	fk := func(table string) *ForeignKeyRef {
+		return &ForeignKeyRef{ReferencedTable: table, ReferencedColumn: "id"}
+	}
+
+	schema := &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"a": {Name: "a", Columns: []SchemaColumn{{Name: "id"}, {Name: "b_id", ForeignKey: fk("b")}}},
+			"b": {Name: "b", Columns: []SchemaColumn{{Name: "id"}, {Name: "c_id", ForeignKey: fk("c")}}},
+			"c": {Name: "c", Columns: []SchemaColumn{{Name: "id"}, {Name: "a_id", ForeignKey: fk("a")}}},
+			"d": {Name: "d", Columns: []SchemaColumn{{Name: "id"}, {Name: "d_id", ForeignKey: fk("d")}, {Name: "a_id", ForeignKey: fk("a")}}},
+		},
+	}
+
+	deferred := schema.CyclicForeignKeys()
+	if len(deferred) != 1 {
+		t.Fatalf("expected 1 deferred foreign key, got %d: %+v", len(deferred), deferred)
+	}
+	if deferred[0].Table != "c" || deferred[0].Column != "a_id" {
+		t.Errorf("expected c.a_id to be deferred, got %s.%s", deferred[0].Table, deferred[0].Column)
+	}
+	if deferred[0].ConstraintName() != "c_a_id_fkey" {
+		t.Errorf("expected constraint name 'c_a_id_fkey', got '%s'", deferred[0].ConstraintName())
+	}
+
+	names := schema.GetTableNames()
+	expected := []string{"c", "b", "a", "d"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected order %v, got %v", expected, names)
+	}
+}
+
 func TestDatabaseSchema_GetTableNames(t *testing.T) {
 	schema := &DatabaseSchema{
 		Tables: map[string]SchemaTable{
@@ -1219,3 +1617,23 @@ func TestDatabaseSchema_GetTableNames(t *testing.T) {
 		t.Error("users should come before posts in dependency order")
 	}
 }
+
+func TestSchemaGenerator_SkipsNonColumnFields(t *testing.T) {
+	gen := NewSchemaGenerator()
+
+	schema, err := gen.GenerateSchema([]parser.TableDefinition{{
+		TableName: "users",
+		Fields: []parser.FieldDefinition{
+			{Name: "ID", Type: "int", DBName: "id", DBDef: map[string]string{"primary_key": ""}},
+			{Name: "Posts", Type: "Post", IsArray: true, DBName: "posts", StormTag: "relation:has_many:Post;foreign_key:user_id", DBDef: map[string]string{}},
+			{Name: "Cache", Type: "Cache", DBName: "-", DBDef: map[string]string{}},
+		},
+		TableLevel: map[string]string{},
+	}})
+	if err != nil {
+		t.Fatalf("GenerateSchema failed: %v", err)
+	}
+	if got := len(schema.Tables["users"].Columns); got != 1 {
+		t.Errorf("expected 1 column, got %d", got)
+	}
+}
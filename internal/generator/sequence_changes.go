@@ -0,0 +1,289 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// newSchemaSequence returns the sequence name before the options of its CREATE
+// SEQUENCE statement are applied
+func newSchemaSequence(name string) SchemaSequence {
+	return SchemaSequence{Name: name, DataType: "bigint", Increment: 1, Cache: 1}
+}
+
+// sequenceDefaultBounds returns the MINVALUE and MAXVALUE PostgreSQL picks for a
+// sequence of dataType counting by increment
+func sequenceDefaultBounds(dataType string, increment int64) (int64, int64) {
+	low, high := sequenceTypeRange(dataType)
+	if increment < 0 {
+		return low, -1
+	}
+	return 1, high
+}
+
+// sequenceTypeRange returns the values a sequence of dataType can reach
+func sequenceTypeRange(dataType string) (int64, int64) {
+	switch dataType {
+	case "smallint":
+		return math.MinInt16, math.MaxInt16
+	case "integer":
+		return math.MinInt32, math.MaxInt32
+	default:
+		return math.MinInt64, math.MaxInt64
+	}
+}
+
+// applySequenceOptions applies the options of a CREATE SEQUENCE or ALTER SEQUENCE
+// statement to seq. Bounds left out of a CREATE are derived from the type and
+// increment, and bounds still at the limits of the old type follow a changed type,
+// as PostgreSQL does.
+func applySequenceOptions(seq *SchemaSequence, options string, creating bool) error {
+	words := strings.Fields(options)
+	set := make(map[string]bool)
+	oldType := seq.DataType
+
+	number := func(i int, option string) (int64, error) {
+		if i >= len(words) {
+			return 0, fmt.Errorf("sequence %s: %s needs a value", seq.Name, option)
+		}
+		n, err := strconv.ParseInt(words[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("sequence %s: invalid %s %q", seq.Name, option, words[i])
+		}
+		return n, nil
+	}
+	optionalWord := func(i int, word string) int {
+		if i < len(words) && strings.EqualFold(words[i], word) {
+			return i + 1
+		}
+		return i
+	}
+
+	for i := 0; i < len(words); i++ {
+		var err error
+		switch word := strings.ToUpper(words[i]); word {
+		case "AS":
+			if i+1 >= len(words) {
+				return fmt.Errorf("sequence %s: AS needs a type", seq.Name)
+			}
+			i++
+			seq.DataType = normalizeColumnType(words[i])
+		case "INCREMENT":
+			i = optionalWord(i+1, "BY")
+			seq.Increment, err = number(i, "INCREMENT")
+			set["increment"] = true
+		case "MINVALUE":
+			i++
+			seq.MinValue, err = number(i, word)
+			set["min"] = true
+		case "MAXVALUE":
+			i++
+			seq.MaxValue, err = number(i, word)
+			set["max"] = true
+		case "START":
+			i = optionalWord(i+1, "WITH")
+			seq.Start, err = number(i, word)
+			set["start"] = true
+		case "RESTART":
+			// Moves the current value only; the definition keeps its start
+			i = optionalWord(i+1, "WITH")
+			if i >= len(words) || !isSequenceNumber(words[i]) {
+				i--
+			}
+		case "CACHE":
+			i++
+			seq.Cache, err = number(i, word)
+		case "CYCLE":
+			seq.Cycle = true
+		case "NO":
+			if i+1 >= len(words) {
+				return fmt.Errorf("sequence %s: NO needs an option", seq.Name)
+			}
+			i++
+			switch strings.ToUpper(words[i]) {
+			case "MINVALUE":
+				set["no min"] = true
+			case "MAXVALUE":
+				set["no max"] = true
+			case "CYCLE":
+				seq.Cycle = false
+			default:
+				return fmt.Errorf("sequence %s: unknown option NO %s", seq.Name, words[i])
+			}
+		case "OWNED":
+			i = optionalWord(i+1, "BY")
+			if i >= len(words) {
+				return fmt.Errorf("sequence %s: OWNED BY needs a column", seq.Name)
+			}
+			seq.OwnedBy = ""
+			if !strings.EqualFold(words[i], "NONE") {
+				seq.OwnedBy = strings.TrimPrefix(normalizeIdentifier(words[i]), "public.")
+			}
+		default:
+			return fmt.Errorf("sequence %s: unknown option %s", seq.Name, words[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	minValue, maxValue := sequenceDefaultBounds(seq.DataType, seq.Increment)
+	if creating {
+		set["no min"] = set["no min"] || !set["min"]
+		set["no max"] = set["no max"] || !set["max"]
+	} else if seq.DataType != oldType {
+		oldLow, oldHigh := sequenceTypeRange(oldType)
+		set["no min"] = set["no min"] || (!set["min"] && seq.MinValue == oldLow)
+		set["no max"] = set["no max"] || (!set["max"] && seq.MaxValue == oldHigh)
+	}
+	if set["no min"] {
+		seq.MinValue = minValue
+	}
+	if set["no max"] {
+		seq.MaxValue = maxValue
+	}
+	if creating && !set["start"] {
+		seq.Start = seq.MinValue
+		if seq.Increment < 0 {
+			seq.Start = seq.MaxValue
+		}
+	}
+	return nil
+}
+
+func isSequenceNumber(word string) bool {
+	_, err := strconv.ParseInt(word, 10, 64)
+	return err == nil
+}
+
+// GenerateCreateSequence returns the statement creating seq, leaving out the options
+// PostgreSQL would pick anyway. Ownership is not part of it, as the owning table may
+// not exist yet; see GenerateSequenceOwnership.
+func (g *SQLGenerator) GenerateCreateSequence(seq SchemaSequence) string {
+	minValue, maxValue := sequenceDefaultBounds(seq.DataType, seq.Increment)
+
+	var options []string
+	if seq.DataType != "bigint" {
+		options = append(options, "AS "+seq.DataType)
+	}
+	if seq.Increment != 1 {
+		options = append(options, fmt.Sprintf("INCREMENT BY %d", seq.Increment))
+	}
+	if seq.MinValue != minValue {
+		options = append(options, fmt.Sprintf("MINVALUE %d", seq.MinValue))
+	}
+	if seq.MaxValue != maxValue {
+		options = append(options, fmt.Sprintf("MAXVALUE %d", seq.MaxValue))
+	}
+	start := seq.MinValue
+	if seq.Increment < 0 {
+		start = seq.MaxValue
+	}
+	if seq.Start != start {
+		options = append(options, fmt.Sprintf("START WITH %d", seq.Start))
+	}
+	if seq.Cache != 1 {
+		options = append(options, fmt.Sprintf("CACHE %d", seq.Cache))
+	}
+	if seq.Cycle {
+		options = append(options, "CYCLE")
+	}
+
+	if len(options) == 0 {
+		return fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s;\n", seq.Name)
+	}
+	return fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s %s;\n", seq.Name, strings.Join(options, " "))
+}
+
+// GenerateSequenceOwnership returns the statement tying seq to the column owning it,
+// or releasing it when it has none
+func (g *SQLGenerator) GenerateSequenceOwnership(seq SchemaSequence) string {
+	owner := seq.OwnedBy
+	if owner == "" {
+		owner = "NONE"
+	}
+	return fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s;\n", seq.Name, owner)
+}
+
+// GenerateDropSequence returns the statement dropping seq
+func (g *SQLGenerator) GenerateDropSequence(seq SchemaSequence) string {
+	return fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;\n", seq.Name)
+}
+
+// GenerateSequenceChanges returns the statements turning the sequences of from into
+// those of to: dropped sequences are dropped, new ones created and changed options
+// altered in place. Ownership changes come last, after the sequences exist, so they
+// can run once the tables of the migration are in place.
+func (g *SQLGenerator) GenerateSequenceChanges(from, to *DatabaseSchema) []string {
+	var statements, ownership []string
+
+	for _, name := range sortedSequenceNames(from) {
+		if _, exists := to.Sequences[name]; !exists {
+			statements = append(statements, strings.TrimSpace(g.GenerateDropSequence(from.Sequences[name])))
+		}
+	}
+	for _, name := range sortedSequenceNames(to) {
+		toSeq := to.Sequences[name]
+		fromSeq, exists := from.Sequences[name]
+		if !exists {
+			statements = append(statements, strings.TrimSpace(g.GenerateCreateSequence(toSeq)))
+			if toSeq.OwnedBy != "" {
+				ownership = append(ownership, strings.TrimSpace(g.GenerateSequenceOwnership(toSeq)))
+			}
+			continue
+		}
+		if options := sequenceOptionChanges(fromSeq, toSeq); len(options) > 0 {
+			statements = append(statements, fmt.Sprintf("ALTER SEQUENCE %s %s;", name, strings.Join(options, " ")))
+		}
+		if fromSeq.OwnedBy != toSeq.OwnedBy {
+			ownership = append(ownership, strings.TrimSpace(g.GenerateSequenceOwnership(toSeq)))
+		}
+	}
+
+	return append(statements, ownership...)
+}
+
+// sortedSequenceNames returns the sequence names of schema in order
+func sortedSequenceNames(schema *DatabaseSchema) []string {
+	names := make([]string, 0, len(schema.Sequences))
+	for name := range schema.Sequences {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sequenceOptionChanges returns the ALTER SEQUENCE options turning from into to
+func sequenceOptionChanges(from, to SchemaSequence) []string {
+	var options []string
+	if from.DataType != to.DataType {
+		options = append(options, "AS "+to.DataType)
+	}
+	if from.Increment != to.Increment {
+		options = append(options, fmt.Sprintf("INCREMENT BY %d", to.Increment))
+	}
+	// Changing the type moves bounds at the limits of the old type along
+	if from.MinValue != to.MinValue || from.DataType != to.DataType {
+		options = append(options, fmt.Sprintf("MINVALUE %d", to.MinValue))
+	}
+	if from.MaxValue != to.MaxValue || from.DataType != to.DataType {
+		options = append(options, fmt.Sprintf("MAXVALUE %d", to.MaxValue))
+	}
+	if from.Start != to.Start {
+		options = append(options, fmt.Sprintf("START WITH %d", to.Start))
+	}
+	if from.Cache != to.Cache {
+		options = append(options, fmt.Sprintf("CACHE %d", to.Cache))
+	}
+	if from.Cycle != to.Cycle {
+		if to.Cycle {
+			options = append(options, "CYCLE")
+		} else {
+			options = append(options, "NO CYCLE")
+		}
+	}
+	return options
+}
@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSQLSchemaParser_Sequences(t *testing.T) {
+	schema, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE orders (id SERIAL PRIMARY KEY, number BIGINT NOT NULL);
+		CREATE SEQUENCE order_numbers START WITH 1000 INCREMENT BY 10 CACHE 20;
+		ALTER SEQUENCE order_numbers OWNED BY public.orders.number;
+		CREATE SEQUENCE IF NOT EXISTS order_numbers;
+		CREATE SEQUENCE countdown AS integer INCREMENT -1 NO CYCLE;
+		ALTER SEQUENCE countdown MAXVALUE 500 RESTART;
+		CREATE SEQUENCE tickets AS smallint;
+		ALTER SEQUENCE tickets AS integer RESTART WITH 5;
+		CREATE SEQUENCE old_seq OWNED BY orders.id;
+		ALTER TABLE orders DROP COLUMN id;
+		ALTER TABLE orders RENAME TO purchases;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]SchemaSequence{
+		"order_numbers": {Name: "order_numbers", DataType: "bigint", Start: 1000, Increment: 10,
+			MinValue: 1, MaxValue: 9223372036854775807, Cache: 20, OwnedBy: "purchases.number"},
+		"countdown": {Name: "countdown", DataType: "integer", Start: -1, Increment: -1,
+			MinValue: -2147483648, MaxValue: 500, Cache: 1},
+		"tickets": {Name: "tickets", DataType: "integer", Start: 1, Increment: 1,
+			MinValue: 1, MaxValue: 2147483647, Cache: 1},
+	}
+	if !reflect.DeepEqual(schema.Sequences, expected) {
+		t.Errorf("sequences = %+v\nwant %+v", schema.Sequences, expected)
+	}
+
+	schema, err = NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE orders (id SERIAL PRIMARY KEY);
+		CREATE SEQUENCE order_numbers OWNED BY orders.id;
+		DROP TABLE orders;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema.Sequences) != 0 {
+		t.Errorf("expected the owned sequence to go with its table, got %+v", schema.Sequences)
+	}
+}
+
+func TestCompareSchemas_Sequences(t *testing.T) {
+	from, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE orders (id SERIAL PRIMARY KEY, number BIGINT);
+		CREATE SEQUENCE order_numbers OWNED BY orders.number;
+		CREATE SEQUENCE legacy;
+		CREATE SEQUENCE same START 1;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE orders (id SERIAL PRIMARY KEY, number BIGINT);
+		CREATE SEQUENCE order_numbers INCREMENT BY 5 CYCLE;
+		CREATE SEQUENCE invoices;
+		CREATE SEQUENCE same MINVALUE 1 MAXVALUE 9223372036854775807;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, diff := range CompareSchemas(from, to) {
+		got = append(got, diff.String())
+	}
+
+	expected := []string{
+		"sequence legacy dropped",
+		"sequence invoices added",
+		"sequence order_numbers increment changed from 1 to 5",
+		"sequence order_numbers cycle changed from false to true",
+		"sequence order_numbers owner changed from orders.number to none",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected differences:\n got: %q\nwant: %q", got, expected)
+	}
+}
+
+func TestSQLGenerator_GenerateSequenceChanges(t *testing.T) {
+	from := &DatabaseSchema{Sequences: map[string]SchemaSequence{
+		"legacy":        {Name: "legacy", DataType: "bigint", Start: 1, Increment: 1, MinValue: 1, MaxValue: 9223372036854775807, Cache: 1},
+		"order_numbers": {Name: "order_numbers", DataType: "integer", Start: 1, Increment: 1, MinValue: 1, MaxValue: 2147483647, Cache: 1, OwnedBy: "orders.number"},
+	}}
+	to := &DatabaseSchema{Sequences: map[string]SchemaSequence{
+		"invoices":      {Name: "invoices", DataType: "bigint", Start: 100, Increment: 1, MinValue: 1, MaxValue: 9223372036854775807, Cache: 1, OwnedBy: "invoices.number"},
+		"order_numbers": {Name: "order_numbers", DataType: "bigint", Start: 1, Increment: 2, MinValue: 1, MaxValue: 9223372036854775807, Cache: 1},
+	}}
+
+	got := NewSQLGenerator().GenerateSequenceChanges(from, to)
+
+	expected := []string{
+		"DROP SEQUENCE IF EXISTS legacy;",
+		"CREATE SEQUENCE IF NOT EXISTS invoices START WITH 100;",
+		"ALTER SEQUENCE order_numbers AS bigint INCREMENT BY 2 MINVALUE 1 MAXVALUE 9223372036854775807;",
+		"ALTER SEQUENCE invoices OWNED BY invoices.number;",
+		"ALTER SEQUENCE order_numbers OWNED BY NONE;",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+
+	if changes := NewSQLGenerator().GenerateSequenceChanges(to, to); len(changes) != 0 {
+		t.Errorf("expected no changes between equal schemas, got %q", changes)
+	}
+}
+
+func TestSQLGenerator_GenerateSchema_Sequences(t *testing.T) {
+	schema, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE SEQUENCE order_numbers AS integer START WITH 1000 CACHE 10 CYCLE;
+		CREATE TABLE orders (id SERIAL PRIMARY KEY, number INTEGER DEFAULT nextval('order_numbers'));
+		ALTER SEQUENCE order_numbers OWNED BY orders.number;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql := NewSQLGenerator().GenerateSchema(schema)
+	create := strings.Index(sql, "CREATE SEQUENCE IF NOT EXISTS order_numbers AS integer START WITH 1000 CACHE 10 CYCLE;")
+	table := strings.Index(sql, "CREATE TABLE orders")
+	owned := strings.Index(sql, "ALTER SEQUENCE order_numbers OWNED BY orders.number;")
+	if create == -1 || table == -1 || owned == -1 || !(create < table && table < owned) {
+		t.Errorf("expected the sequence, then the table, then its ownership\n%s", sql)
+	}
+
+	parsed, err := NewSQLSchemaParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Sequences, schema.Sequences) {
+		t.Errorf("sequences do not round trip: got %+v, want %+v", parsed.Sequences, schema.Sequences)
+	}
+}
@@ -2,6 +2,7 @@ package generator
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/eleven-am/storm/internal/logger"
@@ -22,13 +23,19 @@ func NewSQLGenerator() *SQLGenerator {
 }
 
 func (g *SQLGenerator) GenerateCreateTable(table SchemaTable) string {
+	return g.generateCreateTable(table, nil)
+}
+
+// generateCreateTable renders CREATE TABLE, leaving out the inline references of the
+// columns in deferredFKs so they can be added once the referenced tables exist
+func (g *SQLGenerator) generateCreateTable(table SchemaTable, deferredFKs map[string]bool) string {
 	var sql strings.Builder
 
 	sql.WriteString(fmt.Sprintf("CREATE TABLE %s (\n", table.Name))
 
 	columns := make([]string, 0, len(table.Columns))
 	for _, col := range table.Columns {
-		columns = append(columns, g.generateColumnDDL(col))
+		columns = append(columns, g.generateColumnDDL(col, !deferredFKs[col.Name]))
 	}
 
 	constraints := make([]string, 0)
@@ -53,8 +60,8 @@ func (g *SQLGenerator) GenerateCreateTable(table SchemaTable) string {
 			for i, col := range constraint.Columns {
 				quotedColumns[i] = g.quoteColumnNameIfNeeded(col)
 			}
-			constraintSQL := fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)",
-				constraint.Name, strings.Join(quotedColumns, ", "))
+			constraintSQL := fmt.Sprintf("CONSTRAINT %s UNIQUE %s(%s)",
+				constraint.Name, nullsNotDistinct(constraint.NullsNotDistinct), strings.Join(quotedColumns, ", "))
 			logger.SQL().Debug("Generated UNIQUE constraint: %s", constraintSQL)
 			constraints = append(constraints, constraintSQL)
 		case "CHECK":
@@ -80,10 +87,16 @@ func (g *SQLGenerator) GenerateCreateTable(table SchemaTable) string {
 		}
 	}
 
+	for _, col := range table.Columns {
+		for _, stmt := range g.GenerateColumnStorageDDL(table.Name, col) {
+			sql.WriteString("\n" + stmt)
+		}
+	}
+
 	return sql.String()
 }
 
-func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
+func (g *SQLGenerator) generateColumnDDL(col SchemaColumn, inlineForeignKey bool) string {
 	var parts []string
 
 	// Quote column name if it's a reserved keyword
@@ -104,16 +117,11 @@ func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
 		parts = append(parts, "UNIQUE")
 	}
 
-	if col.ForeignKey != nil {
-		parts = append(parts, fmt.Sprintf("REFERENCES %s(%s)",
-			col.ForeignKey.ReferencedTable, col.ForeignKey.ReferencedColumn))
-
-		if col.ForeignKey.OnDelete != "" && col.ForeignKey.OnDelete != "NO ACTION" {
-			parts = append(parts, fmt.Sprintf("ON DELETE %s", col.ForeignKey.OnDelete))
-		}
-		if col.ForeignKey.OnUpdate != "" && col.ForeignKey.OnUpdate != "NO ACTION" {
-			parts = append(parts, fmt.Sprintf("ON UPDATE %s", col.ForeignKey.OnUpdate))
+	if col.ForeignKey != nil && inlineForeignKey {
+		if col.ForeignKey.Name != "" {
+			parts = append(parts, "CONSTRAINT "+col.ForeignKey.Name)
 		}
+		parts = append(parts, g.generateReferences(*col.ForeignKey))
 	}
 
 	if col.CheckConstraint != nil {
@@ -123,6 +131,25 @@ func (g *SQLGenerator) generateColumnDDL(col SchemaColumn) string {
 	return strings.Join(parts, " ")
 }
 
+func (g *SQLGenerator) generateReferences(fk ForeignKeyRef) string {
+	parts := []string{fmt.Sprintf("REFERENCES %s(%s)", fk.ReferencedTable, fk.ReferencedColumn)}
+
+	if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+		parts = append(parts, fmt.Sprintf("ON DELETE %s", fk.OnDelete))
+	}
+	if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+		parts = append(parts, fmt.Sprintf("ON UPDATE %s", fk.OnUpdate))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// GenerateAddForeignKey renders the ALTER TABLE statement for a foreign key deferred out of CREATE TABLE
+func (g *SQLGenerator) GenerateAddForeignKey(fk DeferredForeignKey) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) %s;\n",
+		fk.Table, fk.ConstraintName(), g.quoteColumnNameIfNeeded(fk.Column), g.generateReferences(fk.Ref))
+}
+
 func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) string {
 	var sql strings.Builder
 
@@ -150,6 +177,16 @@ func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) strin
 	sql.WriteString(strings.Join(quotedColumns, ", "))
 	sql.WriteString(")")
 
+	if idx.IsUnique && idx.NullsNotDistinct {
+		sql.WriteString(" NULLS NOT DISTINCT")
+	}
+
+	if idx.With != "" {
+		sql.WriteString(" WITH (")
+		sql.WriteString(idx.With)
+		sql.WriteString(")")
+	}
+
 	if idx.Where != "" {
 		sql.WriteString(" WHERE ")
 		sql.WriteString(idx.Where)
@@ -160,6 +197,43 @@ func (g *SQLGenerator) GenerateIndexDDL(tableName string, idx SchemaIndex) strin
 	return sql.String()
 }
 
+// GenerateColumnStorageDDL returns the statements setting the storage strategy and
+// compression method of col, which CREATE TABLE only accepts from PostgreSQL 16
+func (g *SQLGenerator) GenerateColumnStorageDDL(tableName string, col SchemaColumn) []string {
+	var statements []string
+	if col.Storage != "" {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STORAGE %s;\n",
+			tableName, g.quoteColumnNameIfNeeded(col.Name), strings.ToUpper(col.Storage)))
+	}
+	if col.Compression != "" {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET COMPRESSION %s;\n",
+			tableName, g.quoteColumnNameIfNeeded(col.Name), col.Compression))
+	}
+	return statements
+}
+
+// nullsNotDistinct returns the clause that makes a UNIQUE constraint treat NULLs as
+// equal, followed by a space, or "" when enabled is false
+func nullsNotDistinct(enabled bool) string {
+	if enabled {
+		return "NULLS NOT DISTINCT "
+	}
+	return ""
+}
+
+// usesVectorType reports whether any column is a pgvector vector, which needs the vector extension
+func usesVectorType(schema *DatabaseSchema) bool {
+	for _, table := range schema.Tables {
+		for _, col := range table.Columns {
+			t := strings.ToLower(col.Type)
+			if t == "vector" || strings.HasPrefix(t, "vector(") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (g *SQLGenerator) isImplicitIndex(idx SchemaIndex, table SchemaTable) bool {
 	if idx.IsPrimary {
 		return true
@@ -202,14 +276,23 @@ func (g *SQLGenerator) GenerateSchema(schema *DatabaseSchema) string {
 	sql.WriteString("-- Generated by webhook-router migration tool\n")
 	sql.WriteString("-- Enable required extensions\n")
 	sql.WriteString("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\";\n")
-	sql.WriteString("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\";\n\n")
+	sql.WriteString("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\";\n")
+	if usesVectorType(schema) {
+		sql.WriteString("CREATE EXTENSION IF NOT EXISTS vector;\n")
+	}
+	sql.WriteString("\n")
 	
 	logger.SQL().Debug("Added extensions")
 
 	if len(schema.EnumTypes) > 0 {
 		sql.WriteString("-- Enum types\n")
-		for typeName, values := range schema.EnumTypes {
-			sql.WriteString(g.generateEnumType(typeName, values))
+		enumNames := make([]string, 0, len(schema.EnumTypes))
+		for typeName := range schema.EnumTypes {
+			enumNames = append(enumNames, typeName)
+		}
+		sort.Strings(enumNames)
+		for _, typeName := range enumNames {
+			sql.WriteString(g.generateEnumType(typeName, schema.EnumTypes[typeName]))
 			sql.WriteString("\n")
 		}
 		sql.WriteString("\n")
@@ -220,19 +303,70 @@ func (g *SQLGenerator) GenerateSchema(schema *DatabaseSchema) string {
 		sql.WriteString("-- CUID functions will be generated by the migration system\n\n")
 	}
 
+	// Sequences before the tables whose defaults use them
+	for _, name := range sortedSequenceNames(schema) {
+		sql.WriteString(fmt.Sprintf("-- Sequence: %s\n", name))
+		sql.WriteString(g.GenerateCreateSequence(schema.Sequences[name]))
+		sql.WriteString("\n")
+	}
+
 	tableNames := schema.GetTableNames()
 	logger.SQL().Debug("Generating %d tables: %v", len(tableNames), tableNames)
 
+	cyclicFKs := schema.CyclicForeignKeys()
+	deferredFKs := make(map[string]map[string]bool)
+	for _, fk := range cyclicFKs {
+		if deferredFKs[fk.Table] == nil {
+			deferredFKs[fk.Table] = make(map[string]bool)
+		}
+		deferredFKs[fk.Table][fk.Column] = true
+	}
+
 	for _, tableName := range tableNames {
 		table := schema.Tables[tableName]
 		logger.SQL().Debug("Processing table %s with %d columns", tableName, len(table.Columns))
 		sql.WriteString(fmt.Sprintf("-- Table: %s\n", tableName))
-		tableSQL := g.GenerateCreateTable(table)
+		tableSQL := g.generateCreateTable(table, deferredFKs[tableName])
 		logger.SQL().Debug("Generated SQL for %s: %s", tableName, tableSQL[:min(200, len(tableSQL))])
 		sql.WriteString(tableSQL)
 		sql.WriteString("\n")
 	}
 
+	if len(cyclicFKs) > 0 {
+		sql.WriteString("-- Foreign keys deferred to break reference cycles\n")
+		for _, fk := range cyclicFKs {
+			sql.WriteString(g.GenerateAddForeignKey(fk))
+		}
+		sql.WriteString("\n")
+	}
+
+	// Ownership once the owning tables exist
+	for _, name := range sortedSequenceNames(schema) {
+		if schema.Sequences[name].OwnedBy != "" {
+			sql.WriteString(g.GenerateSequenceOwnership(schema.Sequences[name]))
+			sql.WriteString("\n")
+		}
+	}
+
+	for _, name := range sortedFunctionNames(schema) {
+		sql.WriteString(fmt.Sprintf("-- Function: %s\n", name))
+		sql.WriteString(g.GenerateCreateFunction(schema.Functions[name]))
+		sql.WriteString("\n")
+	}
+
+	for _, viewName := range sortViewsByDependencies(schema) {
+		sql.WriteString(fmt.Sprintf("-- View: %s\n", viewName))
+		sql.WriteString(g.GenerateCreateView(schema.Views[viewName]))
+		sql.WriteString("\n")
+	}
+
+	// Triggers last, as they may be defined on views
+	for _, key := range sortedTriggerKeys(schema) {
+		sql.WriteString(fmt.Sprintf("-- Trigger: %s\n", key))
+		sql.WriteString(g.GenerateCreateTrigger(schema.Triggers[key]))
+		sql.WriteString("\n")
+	}
+
 	finalSQL := sql.String()
 	logger.SQL().Debug("Final SQL length: %d characters", len(finalSQL))
 	logger.SQL().Debug("First 500 chars: %s", finalSQL[:min(500, len(finalSQL))])
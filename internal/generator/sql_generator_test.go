@@ -463,6 +463,80 @@ func TestSQLGenerator_GenerateSchema_Extensions(t *testing.T) {
 	}
 }
 
+func TestSQLGenerator_GenerateSchema_CircularForeignKeys(t *testing.T) {
+	gen := NewSQLGenerator()
+
+	schema := DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {
+				Name: "users",
+				Columns: []SchemaColumn{
+					{Name: "id", Type: "UUID", IsPrimaryKey: true},
+					{
+						Name:       "team_id",
+						Type:       "UUID",
+						IsNullable: true,
+						ForeignKey: &ForeignKeyRef{
+							ReferencedTable:  "teams",
+							ReferencedColumn: "id",
+							OnDelete:         "SET NULL",
+						},
+					},
+				},
+			},
+			"teams": {
+				Name: "teams",
+				Columns: []SchemaColumn{
+					{Name: "id", Type: "UUID", IsPrimaryKey: true},
+					{
+						Name:       "owner_id",
+						Type:       "UUID",
+						IsNullable: true,
+						ForeignKey: &ForeignKeyRef{
+							ReferencedTable:  "users",
+							ReferencedColumn: "id",
+						},
+					},
+					{
+						Name:       "parent_id",
+						Type:       "UUID",
+						IsNullable: true,
+						ForeignKey: &ForeignKeyRef{
+							ReferencedTable:  "teams",
+							ReferencedColumn: "id",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	sql := gen.GenerateSchema(&schema)
+
+	usersPos := strings.Index(sql, "CREATE TABLE users")
+	teamsPos := strings.Index(sql, "CREATE TABLE teams")
+	alterPos := strings.Index(sql, "ALTER TABLE users ADD CONSTRAINT users_team_id_fkey FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE SET NULL;")
+
+	if usersPos == -1 || teamsPos == -1 || alterPos == -1 {
+		t.Fatalf("expected both tables and the deferred foreign key, got:\n%s", sql)
+	}
+	if teamsPos < usersPos {
+		t.Error("users should be created before teams")
+	}
+	if alterPos < teamsPos {
+		t.Error("deferred foreign key should be added after all tables are created")
+	}
+	if strings.Contains(sql, "team_id UUID REFERENCES") {
+		t.Error("deferred foreign key should not be declared inline")
+	}
+	if !strings.Contains(sql, "owner_id UUID REFERENCES users(id)") {
+		t.Error("foreign key to an already created table should stay inline")
+	}
+	if !strings.Contains(sql, "parent_id UUID REFERENCES teams(id)") {
+		t.Error("self-referencing foreign key should stay inline")
+	}
+}
+
 // Helper function
 func strPtr(s string) *string {
 	return &s
@@ -0,0 +1,1001 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/logger"
+	parser2 "github.com/eleven-am/storm/internal/parser"
+)
+
+// SQLSchemaParser rebuilds a DatabaseSchema from DDL statements, either a single
+// schema.sql or a directory of migrations replayed in file name order. It understands
+// the tables, columns, constraints, indexes, enum types, views, functions, triggers and
+// sequences storm generates and skips statements it has no use for (extensions, data changes).
+type SQLSchemaParser struct {
+	schema *DatabaseSchema
+}
+
+func NewSQLSchemaParser() *SQLSchemaParser {
+	return &SQLSchemaParser{
+		schema: &DatabaseSchema{
+			Tables:    make(map[string]SchemaTable),
+			EnumTypes: make(map[string][]string),
+			Views:     make(map[string]SchemaView),
+			Functions: make(map[string]SchemaFunction),
+			Triggers:  make(map[string]SchemaTrigger),
+			Sequences: make(map[string]SchemaSequence),
+		},
+	}
+}
+
+// Schema returns the schema built from everything parsed so far
+func (p *SQLSchemaParser) Schema() *DatabaseSchema {
+	return p.schema
+}
+
+// ParsePath parses a SQL file, or every .sql file in a directory in name order.
+// Down migrations (*.down.sql) are skipped so a migrations directory replays to its latest state.
+func (p *SQLSchemaParser) ParsePath(path string) (*DatabaseSchema, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SQL files in %s: %w", path, err)
+		}
+		sort.Strings(files)
+	}
+
+	for _, file := range files {
+		if info.IsDir() && strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		if err := p.parse(file, string(content)); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.schema, nil
+}
+
+// ParseSQL parses the given DDL into the schema
+func (p *SQLSchemaParser) ParseSQL(sql string) (*DatabaseSchema, error) {
+	if err := p.parse("", sql); err != nil {
+		return nil, err
+	}
+	return p.schema, nil
+}
+
+func (p *SQLSchemaParser) parse(file, sql string) error {
+	for _, stmt := range splitSQLStatements(sql) {
+		if err := p.applyStatement(stmt.SQL); err != nil {
+			if file == "" {
+				return fmt.Errorf("line %d: %w", stmt.Line, err)
+			}
+			return fmt.Errorf("%s:%d: %w", file, stmt.Line, err)
+		}
+	}
+	return nil
+}
+
+var (
+	createTableRe  = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL\s+|LOCAL\s+)?(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))\s*\((.*)\)[^)]*$`)
+	createIndexRe  = regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))\s+ON\s+(?:ONLY\s+)?((?:"[^"]+"|[\w.]+))\s*(?:USING\s+(\w+)\s*)?\((.*?)\)\s*(NULLS\s+NOT\s+DISTINCT\s*)?(?:WITH\s*\(([^)]*)\)\s*)?(?:WHERE\s+(.*))?$`)
+	createEnumRe   = regexp.MustCompile(`(?is)^CREATE\s+TYPE\s+((?:"[^"]+"|[\w.]+))\s+AS\s+ENUM\s*\((.*)\)$`)
+	alterEnumRe    = regexp.MustCompile(`(?is)^ALTER\s+TYPE\s+((?:"[^"]+"|[\w.]+))\s+ADD\s+VALUE\s+(?:IF\s+NOT\s+EXISTS\s+)?('(?:[^']|'')*')\s*(?:(BEFORE|AFTER)\s+('(?:[^']|'')*'))?$`)
+	createViewRe   = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:(?:TEMP|TEMPORARY)\s+)?(MATERIALIZED\s+)?VIEW\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))(?:\s*\([^)]*\))?(?:\s+WITH\s*\([^)]*\))?\s+AS\s+(.*?)(?:\s+WITH\s+(?:NO\s+)?DATA)?$`)
+	alterTableRe   = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?((?:"[^"]+"|[\w.]+))\s+(.*)$`)
+	createFuncRe   = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?FUNCTION\s+((?:"[^"]+"|[\w.]+))\s*\(`)
+	createTrigRe   = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?TRIGGER\s+((?:"[^"]+"|\w+))\s+(.*?\bON\s+((?:"[^"]+"|[\w.]+)).*)$`)
+	dropFuncRe     = regexp.MustCompile(`(?is)^DROP\s+FUNCTION\s+(?:IF\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))`)
+	dropTrigRe     = regexp.MustCompile(`(?is)^DROP\s+TRIGGER\s+(?:IF\s+EXISTS\s+)?((?:"[^"]+"|\w+))\s+ON\s+((?:"[^"]+"|[\w.]+))`)
+	createSeqRe    = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?SEQUENCE\s+(IF\s+NOT\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))(.*)$`)
+	alterSeqRe     = regexp.MustCompile(`(?is)^ALTER\s+SEQUENCE\s+(?:IF\s+EXISTS\s+)?((?:"[^"]+"|[\w.]+))\s+(.*)$`)
+	dropRe         = regexp.MustCompile(`(?is)^DROP\s+(TABLE|INDEX|TYPE|VIEW|MATERIALIZED\s+VIEW|SEQUENCE)\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	referencesRe   = regexp.MustCompile(`(?is)^REFERENCES\s+((?:"[^"]+"|[\w.]+))\s*\(([^)]*)\)(.*)$`)
+	fkActionRe     = regexp.MustCompile(`(?is)\bON\s+(DELETE|UPDATE)\s+(SET\s+NULL|SET\s+DEFAULT|NO\s+ACTION|CASCADE|RESTRICT)`)
+	tableKeywordRe = regexp.MustCompile(`(?is)^(CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE)\b`)
+)
+
+func (p *SQLSchemaParser) applyStatement(stmt string) error {
+	if m := createTableRe.FindStringSubmatch(stmt); m != nil {
+		return p.createTable(normalizeIdentifier(m[1]), m[2])
+	}
+	if m := createIndexRe.FindStringSubmatch(stmt); m != nil {
+		return p.createIndex(m)
+	}
+	if m := createEnumRe.FindStringSubmatch(stmt); m != nil {
+		p.schema.EnumTypes[normalizeIdentifier(m[1])] = parseQuotedList(m[2])
+		return nil
+	}
+	if m := alterEnumRe.FindStringSubmatch(stmt); m != nil {
+		p.addEnumValue(normalizeIdentifier(m[1]), unquoteLiteral(m[2]), strings.ToUpper(m[3]), unquoteLiteral(m[4]))
+		return nil
+	}
+	if m := createViewRe.FindStringSubmatch(stmt); m != nil {
+		name := normalizeIdentifier(m[2])
+		p.schema.Views[name] = SchemaView{Name: name, Definition: strings.TrimSpace(m[3]), Materialized: m[1] != ""}
+		return nil
+	}
+	if m := createFuncRe.FindStringSubmatchIndex(stmt); m != nil {
+		return p.createFunction(normalizeIdentifier(stmt[m[2]:m[3]]), stmt[m[1]:])
+	}
+	if m := createTrigRe.FindStringSubmatch(stmt); m != nil {
+		trigger := SchemaTrigger{Name: normalizeIdentifier(m[1]), Table: normalizeIdentifier(m[3]), Definition: strings.TrimSpace(m[2])}
+		p.schema.Triggers[trigger.Table+"."+trigger.Name] = trigger
+		return nil
+	}
+	if m := dropFuncRe.FindStringSubmatch(stmt); m != nil {
+		delete(p.schema.Functions, normalizeIdentifier(m[1]))
+		return nil
+	}
+	if m := dropTrigRe.FindStringSubmatch(stmt); m != nil {
+		delete(p.schema.Triggers, normalizeIdentifier(m[2])+"."+normalizeIdentifier(m[1]))
+		return nil
+	}
+	if m := createSeqRe.FindStringSubmatch(stmt); m != nil {
+		return p.createSequence(normalizeIdentifier(m[2]), m[3], m[1] != "")
+	}
+	if m := alterSeqRe.FindStringSubmatch(stmt); m != nil {
+		return p.alterSequence(normalizeIdentifier(m[1]), m[2])
+	}
+	if m := alterTableRe.FindStringSubmatch(stmt); m != nil {
+		return p.alterTable(normalizeIdentifier(m[1]), m[2])
+	}
+	if m := dropRe.FindStringSubmatch(stmt); m != nil {
+		for _, name := range strings.Split(m[2], ",") {
+			p.drop(strings.Join(strings.Fields(strings.ToUpper(m[1])), " "), normalizeIdentifier(name))
+		}
+		return nil
+	}
+
+	logger.Schema().Debug("Skipping statement: %s", stmt[:min(80, len(stmt))])
+	return nil
+}
+
+func (p *SQLSchemaParser) createTable(name, body string) error {
+	table := SchemaTable{
+		Name:        name,
+		Columns:     make([]SchemaColumn, 0),
+		Indexes:     make([]SchemaIndex, 0),
+		Constraints: make([]SchemaConstraint, 0),
+	}
+
+	var tableConstraints []string
+	for _, def := range parser2.SplitTopLevel(body, ',') {
+		def = strings.TrimSpace(def)
+		switch {
+		case def == "":
+			continue
+		case tableKeywordRe.MatchString(def):
+			tableConstraints = append(tableConstraints, def)
+		case strings.HasPrefix(strings.ToUpper(def), "LIKE "):
+			return fmt.Errorf("table %s: LIKE clauses are not supported", name)
+		default:
+			column, err := parseColumnDefinition(def)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", name, err)
+			}
+			table.Columns = append(table.Columns, column)
+		}
+	}
+
+	// Table constraints may refer to any column, so they are applied once all columns exist
+	for _, def := range tableConstraints {
+		if err := addTableConstraint(&table, def); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+
+	addColumnConstraints(&table)
+	p.schema.Tables[name] = table
+	return nil
+}
+
+func (p *SQLSchemaParser) createIndex(m []string) error {
+	tableName := normalizeIdentifier(m[3])
+	table, exists := p.schema.Tables[tableName]
+	if !exists {
+		return fmt.Errorf("index %s references unknown table %s", normalizeIdentifier(m[2]), tableName)
+	}
+
+	index := SchemaIndex{
+		Name:             normalizeIdentifier(m[2]),
+		Columns:          parseIdentifierList(m[5]),
+		IsUnique:         strings.TrimSpace(m[1]) != "",
+		Type:             strings.ToLower(m[4]),
+		Where:            strings.TrimSpace(m[8]),
+		NullsNotDistinct: m[6] != "",
+	}
+	if m[7] != "" {
+		params := parser2.SplitTopLevel(m[7], ',')
+		for i := range params {
+			params[i] = strings.TrimSpace(params[i])
+		}
+		index.With = strings.Join(params, ", ")
+	}
+
+	table.Indexes = append(table.Indexes, index)
+	p.schema.Tables[tableName] = table
+	return nil
+}
+
+// createFunction adds the function name, given the statement after the opening
+// parenthesis of its argument list
+func (p *SQLSchemaParser) createFunction(name, rest string) error {
+	depth := 1
+	for i, r := range rest {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			p.schema.Functions[name] = SchemaFunction{
+				Name:       name,
+				Arguments:  strings.TrimSpace(rest[:i]),
+				Definition: strings.TrimSpace(rest[i+1:]),
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("function %s: unterminated argument list", name)
+}
+
+func (p *SQLSchemaParser) createSequence(name, options string, ifNotExists bool) error {
+	if _, exists := p.schema.Sequences[name]; exists && ifNotExists {
+		return nil
+	}
+
+	seq := newSchemaSequence(name)
+	if err := applySequenceOptions(&seq, options, true); err != nil {
+		return err
+	}
+	p.schema.Sequences[name] = seq
+	return nil
+}
+
+var (
+	renameSequenceRe = regexp.MustCompile(`(?is)^RENAME\s+TO\s+((?:"[^"]+"|\w+))$`)
+	sequenceSkipRe   = regexp.MustCompile(`(?is)^(?:OWNER\s+TO|SET\s+(?:SCHEMA|LOGGED|UNLOGGED))\b`)
+)
+
+func (p *SQLSchemaParser) alterSequence(name, actions string) error {
+	seq, exists := p.schema.Sequences[name]
+	if !exists {
+		return fmt.Errorf("ALTER SEQUENCE references unknown sequence %s", name)
+	}
+
+	actions = strings.TrimSpace(actions)
+	switch {
+	case sequenceSkipRe.MatchString(actions):
+		return nil
+	case renameSequenceRe.MatchString(actions):
+		delete(p.schema.Sequences, name)
+		seq.Name = normalizeIdentifier(renameSequenceRe.FindStringSubmatch(actions)[1])
+	default:
+		if err := applySequenceOptions(&seq, actions, false); err != nil {
+			return err
+		}
+	}
+
+	p.schema.Sequences[seq.Name] = seq
+	return nil
+}
+
+// dropOwnedSequences drops the sequences owned by column of table, or by any column of
+// table when column is empty, as PostgreSQL drops them along with their owner
+func (p *SQLSchemaParser) dropOwnedSequences(table, column string) {
+	for name, seq := range p.schema.Sequences {
+		owner, ownerColumn, _ := strings.Cut(seq.OwnedBy, ".")
+		if owner == table && (column == "" || ownerColumn == column) {
+			delete(p.schema.Sequences, name)
+		}
+	}
+}
+
+// renameSequenceOwner points the sequences owned by table.column at the renamed owner
+func (p *SQLSchemaParser) renameSequenceOwner(from, to string) {
+	for name, seq := range p.schema.Sequences {
+		if seq.OwnedBy == from {
+			seq.OwnedBy = to
+			p.schema.Sequences[name] = seq
+		}
+	}
+}
+
+func (p *SQLSchemaParser) addEnumValue(typeName, value, position, neighbour string) {
+	values := p.schema.EnumTypes[typeName]
+
+	at := len(values)
+	for i, v := range values {
+		if v == neighbour {
+			at = i
+			if position == "AFTER" {
+				at = i + 1
+			}
+			break
+		}
+	}
+
+	values = append(values[:at], append([]string{value}, values[at:]...)...)
+	p.schema.EnumTypes[typeName] = values
+}
+
+func (p *SQLSchemaParser) drop(kind, name string) {
+	switch kind {
+	case "TABLE":
+		delete(p.schema.Tables, name)
+		for key, trigger := range p.schema.Triggers {
+			if trigger.Table == name {
+				delete(p.schema.Triggers, key)
+			}
+		}
+		p.dropOwnedSequences(name, "")
+	case "SEQUENCE":
+		delete(p.schema.Sequences, name)
+	case "TYPE":
+		delete(p.schema.EnumTypes, name)
+	case "VIEW", "MATERIALIZED VIEW":
+		delete(p.schema.Views, name)
+	case "INDEX":
+		for tableName, table := range p.schema.Tables {
+			for i, idx := range table.Indexes {
+				if idx.Name == name {
+					table.Indexes = append(table.Indexes[:i], table.Indexes[i+1:]...)
+					p.schema.Tables[tableName] = table
+					return
+				}
+			}
+		}
+	}
+}
+
+var (
+	addColumnRe      = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(.*)$`)
+	dropColumnRe     = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?((?:"[^"]+"|\w+))(?:\s+(?:CASCADE|RESTRICT))?$`)
+	dropConstraintRe = regexp.MustCompile(`(?is)^DROP\s+CONSTRAINT\s+(?:IF\s+EXISTS\s+)?((?:"[^"]+"|\w+))(?:\s+(?:CASCADE|RESTRICT))?$`)
+	alterColumnRe    = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?((?:"[^"]+"|\w+))\s+(.*)$`)
+	renameColumnRe   = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?((?:"[^"]+"|\w+))\s+TO\s+((?:"[^"]+"|\w+))$`)
+	renameTableRe    = regexp.MustCompile(`(?is)^RENAME\s+TO\s+((?:"[^"]+"|\w+))$`)
+	setTypeRe        = regexp.MustCompile(`(?is)^(?:SET\s+DATA\s+)?TYPE\s+(.*?)(?:\s+USING\s+.*)?$`)
+	setDefaultRe     = regexp.MustCompile(`(?is)^SET\s+DEFAULT\s+(.*)$`)
+	setStorageRe     = regexp.MustCompile(`(?is)^SET\s+(STORAGE|COMPRESSION)\s+"?(\w+)"?$`)
+)
+
+func (p *SQLSchemaParser) alterTable(name, actions string) error {
+	table, exists := p.schema.Tables[name]
+	if !exists {
+		return fmt.Errorf("ALTER TABLE references unknown table %s", name)
+	}
+
+	for _, action := range parser2.SplitTopLevel(actions, ',') {
+		action = strings.TrimSpace(action)
+
+		if m := renameTableRe.FindStringSubmatch(action); m != nil {
+			delete(p.schema.Tables, name)
+			renamed := normalizeIdentifier(m[1])
+			for _, col := range table.Columns {
+				p.renameSequenceOwner(name+"."+col.Name, renamed+"."+col.Name)
+			}
+			name = renamed
+			table.Name = name
+			continue
+		}
+		if m := dropColumnRe.FindStringSubmatch(action); m != nil {
+			p.dropOwnedSequences(name, normalizeIdentifier(m[1]))
+		}
+		if m := renameColumnRe.FindStringSubmatch(action); m != nil {
+			p.renameSequenceOwner(name+"."+normalizeIdentifier(m[1]), name+"."+normalizeIdentifier(m[2]))
+		}
+
+		if err := alterTableAction(&table, action); err != nil {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+
+	p.schema.Tables[name] = table
+	return nil
+}
+
+func alterTableAction(table *SchemaTable, action string) error {
+	if m := dropConstraintRe.FindStringSubmatch(action); m != nil {
+		dropConstraint(table, normalizeIdentifier(m[1]))
+		return nil
+	}
+
+	if m := addColumnRe.FindStringSubmatch(action); m != nil {
+		def := strings.TrimSpace(m[1])
+		if tableKeywordRe.MatchString(def) {
+			return addTableConstraint(table, def)
+		}
+
+		column, err := parseColumnDefinition(def)
+		if err != nil {
+			return err
+		}
+		table.Columns = append(table.Columns, column)
+		addColumnConstraints(table)
+		return nil
+	}
+
+	if m := dropColumnRe.FindStringSubmatch(action); m != nil {
+		name := normalizeIdentifier(m[1])
+		for i, col := range table.Columns {
+			if col.Name == name {
+				table.Columns = append(table.Columns[:i], table.Columns[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+
+	if m := renameColumnRe.FindStringSubmatch(action); m != nil {
+		if column := findColumn(table, normalizeIdentifier(m[1])); column != nil {
+			column.Name = normalizeIdentifier(m[2])
+		}
+		return nil
+	}
+
+	if m := alterColumnRe.FindStringSubmatch(action); m != nil {
+		column := findColumn(table, normalizeIdentifier(m[1]))
+		if column == nil {
+			return fmt.Errorf("ALTER COLUMN references unknown column %s", normalizeIdentifier(m[1]))
+		}
+
+		change := strings.TrimSpace(m[2])
+		upper := strings.ToUpper(strings.Join(strings.Fields(change), " "))
+		switch {
+		case upper == "SET NOT NULL":
+			column.IsNullable = false
+		case upper == "DROP NOT NULL":
+			column.IsNullable = true
+		case upper == "DROP DEFAULT":
+			column.DefaultValue = nil
+		case setDefaultRe.MatchString(change):
+			value := strings.TrimSpace(setDefaultRe.FindStringSubmatch(change)[1])
+			column.DefaultValue = &value
+		case setStorageRe.MatchString(change):
+			m := setStorageRe.FindStringSubmatch(change)
+			setColumnStorage(column, m[1], m[2])
+		case setTypeRe.MatchString(change):
+			column.Type = strings.TrimSpace(setTypeRe.FindStringSubmatch(change)[1])
+		default:
+			logger.Schema().Debug("Skipping column change on %s.%s: %s", table.Name, column.Name, change)
+		}
+		return nil
+	}
+
+	logger.Schema().Debug("Skipping ALTER TABLE action on %s: %s", table.Name, action)
+	return nil
+}
+
+// columnConstraintWords end a column's type; everything after the first of them is a constraint
+var columnConstraintWords = map[string]bool{
+	"NOT": true, "NULL": true, "DEFAULT": true, "PRIMARY": true, "UNIQUE": true, "REFERENCES": true,
+	"CHECK": true, "CONSTRAINT": true, "COLLATE": true, "GENERATED": true, "STORAGE": true, "COMPRESSION": true,
+}
+
+func parseColumnDefinition(def string) (SchemaColumn, error) {
+	words := splitSQLWords(def)
+	if len(words) < 2 {
+		return SchemaColumn{}, fmt.Errorf("column definition '%s' has no type", def)
+	}
+
+	column := SchemaColumn{Name: normalizeIdentifier(words[0]), IsNullable: true}
+
+	i := 1
+	for i < len(words) && !columnConstraintWords[strings.ToUpper(words[i])] {
+		i++
+	}
+	column.Type = strings.Join(words[1:i], " ")
+	if column.Type == "" {
+		return SchemaColumn{}, fmt.Errorf("column definition '%s' has no type", def)
+	}
+	column.IsAutoIncrement = strings.Contains(strings.ToLower(column.Type), "serial")
+
+	for i < len(words) {
+		word := strings.ToUpper(words[i])
+		i++
+
+		switch word {
+		case "NOT":
+			if i < len(words) && strings.EqualFold(words[i], "NULL") {
+				column.IsNullable = false
+				i++
+			}
+		case "NULL":
+			column.IsNullable = true
+		case "PRIMARY":
+			if i < len(words) && strings.EqualFold(words[i], "KEY") {
+				i++
+			}
+			column.IsPrimaryKey = true
+			column.IsNullable = false
+		case "UNIQUE":
+			column.IsUnique = true
+		case "CONSTRAINT", "COLLATE":
+			i++
+		case "STORAGE", "COMPRESSION":
+			if i < len(words) {
+				setColumnStorage(&column, word, words[i])
+				i++
+			}
+		case "DEFAULT":
+			start := i
+			for i < len(words) && !columnConstraintWords[strings.ToUpper(words[i])] {
+				i++
+			}
+			value := strings.Join(words[start:i], " ")
+			column.DefaultValue = &value
+		case "CHECK":
+			if i < len(words) {
+				check := parser2.TrimEnclosingParens(words[i])
+				column.CheckConstraint = &check
+				i++
+			}
+		case "REFERENCES":
+			start := i - 1
+			// SET NULL and SET DEFAULT are referential actions, not column constraints
+			for i < len(words) && (!columnConstraintWords[strings.ToUpper(words[i])] || strings.EqualFold(words[i-1], "SET")) {
+				i++
+			}
+			fk, err := parseReferences(strings.Join(words[start:i], " "))
+			if err != nil {
+				return SchemaColumn{}, fmt.Errorf("column %s: %w", column.Name, err)
+			}
+			column.ForeignKey = fk
+		case "GENERATED":
+			// GENERATED ... AS IDENTITY / AS (expr) STORED, nothing we track beyond auto increment
+			for i < len(words) && !columnConstraintWords[strings.ToUpper(words[i])] {
+				if strings.EqualFold(words[i], "IDENTITY") {
+					column.IsAutoIncrement = true
+				}
+				i++
+			}
+		}
+	}
+
+	return column, nil
+}
+
+// setColumnStorage records the STORAGE or COMPRESSION setting of column, where
+// DEFAULT restores that of the type or server
+func setColumnStorage(column *SchemaColumn, setting, value string) {
+	value = strings.ToLower(strings.Trim(value, `"`))
+	if value == "default" {
+		value = ""
+	}
+	if strings.EqualFold(setting, "STORAGE") {
+		column.Storage = value
+	} else {
+		column.Compression = value
+	}
+}
+
+// parseReferences parses "REFERENCES table(column) [ON DELETE ...] [ON UPDATE ...]"
+func parseReferences(def string) (*ForeignKeyRef, error) {
+	m := referencesRe.FindStringSubmatch(strings.TrimSpace(def))
+	if m == nil {
+		return nil, fmt.Errorf("invalid REFERENCES clause '%s'", def)
+	}
+
+	columns := parseIdentifierList(m[2])
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("foreign keys must reference exactly one column: %s", def)
+	}
+
+	fk := &ForeignKeyRef{
+		ReferencedTable:  normalizeIdentifier(m[1]),
+		ReferencedColumn: columns[0],
+	}
+	for _, action := range fkActionRe.FindAllStringSubmatch(m[3], -1) {
+		value := strings.ToUpper(strings.Join(strings.Fields(action[2]), " "))
+		if strings.EqualFold(action[1], "DELETE") {
+			fk.OnDelete = value
+		} else {
+			fk.OnUpdate = value
+		}
+	}
+
+	return fk, nil
+}
+
+// addTableConstraint applies a table constraint, recording single-column primary keys,
+// unique constraints and foreign keys on the column the way the schema generator does
+func addTableConstraint(table *SchemaTable, def string) error {
+	words := splitSQLWords(def)
+
+	var name string
+	if len(words) >= 2 && strings.EqualFold(words[0], "CONSTRAINT") {
+		name = normalizeIdentifier(words[1])
+		words = words[2:]
+	}
+	if len(words) == 0 {
+		return fmt.Errorf("invalid constraint '%s'", def)
+	}
+
+	rest := strings.Join(words, " ")
+	keyword := strings.ToUpper(words[0])
+	switch keyword {
+	case "PRIMARY":
+		columns := parseIdentifierList(constraintColumns(words))
+		for _, colName := range columns {
+			if column := findColumn(table, colName); column != nil {
+				column.IsPrimaryKey = true
+				column.IsNullable = false
+			}
+		}
+		if name == "" {
+			name = table.Name + "_pkey"
+		}
+		table.Constraints = append(table.Constraints, SchemaConstraint{Name: name, Type: "PRIMARY KEY", Columns: columns})
+
+	case "UNIQUE":
+		columns := parseIdentifierList(constraintColumns(words))
+		if name == "" {
+			name = fmt.Sprintf("%s_%s_key", table.Name, strings.Join(columns, "_"))
+		}
+		table.Constraints = append(table.Constraints, SchemaConstraint{
+			Name:             name,
+			Type:             "UNIQUE",
+			Columns:          columns,
+			NullsNotDistinct: len(words) > 3 && strings.EqualFold(strings.Join(words[1:4], " "), "NULLS NOT DISTINCT"),
+		})
+
+	case "FOREIGN":
+		columns := parseIdentifierList(constraintColumns(words))
+		refStart := strings.Index(strings.ToUpper(rest), "REFERENCES")
+		if len(columns) != 1 || refStart == -1 {
+			return fmt.Errorf("only single-column foreign keys are supported: %s", def)
+		}
+		fk, err := parseReferences(rest[refStart:])
+		if err != nil {
+			return err
+		}
+		column := findColumn(table, columns[0])
+		if column == nil {
+			return fmt.Errorf("foreign key references unknown column %s", columns[0])
+		}
+		column.ForeignKey = fk
+		if name == "" {
+			name = fmt.Sprintf("%s_%s_fkey", table.Name, columns[0])
+		}
+		table.Constraints = append(table.Constraints, SchemaConstraint{Name: name, Type: "FOREIGN KEY", Columns: columns, Definition: rest})
+
+	case "CHECK":
+		if len(words) < 2 {
+			return fmt.Errorf("invalid check constraint '%s'", def)
+		}
+		if name == "" {
+			name = defaultCheckName(table.Name, countConstraints(table, "CHECK"))
+		}
+		table.Constraints = append(table.Constraints, SchemaConstraint{Name: name, Type: "CHECK", Definition: parser2.TrimEnclosingParens(words[1])})
+
+	default:
+		logger.Schema().Debug("Skipping unsupported constraint on %s: %s", table.Name, def)
+	}
+
+	return nil
+}
+
+// addColumnConstraints names the constraints declared inline on columns, mirroring addImplicitConstraints
+func addColumnConstraints(table *SchemaTable) {
+	for _, column := range table.Columns {
+		if column.IsUnique && !column.IsPrimaryKey && !hasConstraint(table, "UNIQUE", column.Name) {
+			table.Constraints = append(table.Constraints, SchemaConstraint{
+				Name:    fmt.Sprintf("%s_%s_key", table.Name, column.Name),
+				Type:    "UNIQUE",
+				Columns: []string{column.Name},
+			})
+		}
+		if column.ForeignKey != nil && !hasConstraint(table, "FOREIGN KEY", column.Name) {
+			table.Constraints = append(table.Constraints, SchemaConstraint{
+				Name:    fmt.Sprintf("%s_%s_fkey", table.Name, column.Name),
+				Type:    "FOREIGN KEY",
+				Columns: []string{column.Name},
+			})
+		}
+		if column.IsPrimaryKey && !hasConstraint(table, "PRIMARY KEY", column.Name) {
+			table.Constraints = append(table.Constraints, SchemaConstraint{
+				Name:    table.Name + "_pkey",
+				Type:    "PRIMARY KEY",
+				Columns: []string{column.Name},
+			})
+		}
+	}
+}
+
+// dropConstraint removes a named constraint and clears the column flags it set
+func dropConstraint(table *SchemaTable, name string) {
+	for i, constraint := range table.Constraints {
+		if constraint.Name != name {
+			continue
+		}
+
+		for _, colName := range constraint.Columns {
+			column := findColumn(table, colName)
+			if column == nil {
+				continue
+			}
+			switch constraint.Type {
+			case "PRIMARY KEY":
+				column.IsPrimaryKey = false
+			case "UNIQUE":
+				if len(constraint.Columns) == 1 {
+					column.IsUnique = false
+				}
+			case "FOREIGN KEY":
+				column.ForeignKey = nil
+			}
+		}
+
+		table.Constraints = append(table.Constraints[:i], table.Constraints[i+1:]...)
+		return
+	}
+}
+
+func hasConstraint(table *SchemaTable, constraintType, column string) bool {
+	for _, constraint := range table.Constraints {
+		if constraint.Type == constraintType && containsString(constraint.Columns, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func countConstraints(table *SchemaTable, constraintType string) int {
+	n := 0
+	for _, constraint := range table.Constraints {
+		if constraint.Type == constraintType {
+			n++
+		}
+	}
+	return n
+}
+
+func findColumn(table *SchemaTable, name string) *SchemaColumn {
+	for i := range table.Columns {
+		if table.Columns[i].Name == name {
+			return &table.Columns[i]
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// constraintColumns returns the parenthesised column list following a PRIMARY KEY,
+// UNIQUE or FOREIGN KEY keyword
+func constraintColumns(words []string) string {
+	for _, word := range words {
+		if strings.HasPrefix(word, "(") {
+			return parser2.TrimEnclosingParens(word)
+		}
+	}
+	return ""
+}
+
+func parseIdentifierList(list string) []string {
+	var names []string
+	for _, part := range parser2.SplitTopLevel(list, ',') {
+		if name := normalizeIdentifier(part); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func parseQuotedList(list string) []string {
+	var values []string
+	for _, part := range parser2.SplitTopLevel(list, ',') {
+		values = append(values, unquoteLiteral(part))
+	}
+	return values
+}
+
+func unquoteLiteral(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+	}
+	return value
+}
+
+// normalizeIdentifier unquotes a possibly schema-qualified identifier, lowercasing it unless
+// it was quoted and dropping the default public schema
+func normalizeIdentifier(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) && len(name) >= 2 && !strings.Contains(name[1:len(name)-1], `"`) {
+		return name[1 : len(name)-1]
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) && len(part) >= 2 {
+			parts[i] = part[1 : len(part)-1]
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	if len(parts) == 2 && parts[0] == "public" {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitSQLWords splits on whitespace outside parentheses and quotes, so "numeric(10, 2)"
+// and "CHECK (a > 0)" keep their parenthesised parts in one word each
+func splitSQLWords(s string) []string {
+	var words []string
+	var current strings.Builder
+	depth := 0
+	var quote rune
+
+	flush := func() {
+		if current.Len() > 0 {
+			words = append(words, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			current.WriteRune(r)
+		case r == '(':
+			if depth == 0 && isKeywordBeforeParen(current.String()) {
+				flush()
+			}
+			depth++
+			current.WriteRune(r)
+		case r == ')':
+			depth--
+			current.WriteRune(r)
+		case depth == 0 && (r == ' ' || r == '\t' || r == '\n' || r == '\r'):
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return words
+}
+
+// isKeywordBeforeParen reports whether a word directly followed by "(" is a keyword such as
+// CHECK(...) or KEY(...) rather than a type or reference like varchar(20) or users(id)
+func isKeywordBeforeParen(word string) bool {
+	switch strings.ToUpper(word) {
+	case "CHECK", "KEY", "UNIQUE":
+		return true
+	}
+	return false
+}
+
+// sqlStatement is a single statement and the line it starts on
+type sqlStatement struct {
+	SQL  string
+	Line int
+}
+
+// splitSQLStatements splits a script on semicolons outside quotes, dollar-quoted bodies
+// and comments, dropping the comments
+func splitSQLStatements(sql string) []sqlStatement {
+	var statements []sqlStatement
+	var current strings.Builder
+	line, startLine := 1, 0
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			statements = append(statements, sqlStatement{SQL: stmt, Line: startLine})
+		}
+		current.Reset()
+		startLine = 0
+	}
+
+	write := func(s string) {
+		if startLine == 0 && strings.TrimSpace(s) != "" {
+			startLine = line
+		}
+		current.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+
+	for i := 0; i < len(sql); {
+		rest := sql[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end == -1 {
+				end = len(rest)
+			}
+			i += end
+
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end == -1 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			line += strings.Count(rest[:min(end, len(rest))], "\n")
+			i += end
+
+		case rest[0] == '\'' || rest[0] == '"':
+			end := 1
+			for end < len(rest) {
+				if rest[end] == rest[0] {
+					if end+1 < len(rest) && rest[end+1] == rest[0] {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(rest))
+			write(rest[:end])
+			i += end
+
+		case rest[0] == '$':
+			tag := dollarQuoteTagRe.FindString(rest)
+			if tag == "" {
+				write("$")
+				i++
+				continue
+			}
+			end := strings.Index(rest[len(tag):], tag)
+			if end == -1 {
+				end = len(rest)
+			} else {
+				end += 2 * len(tag)
+			}
+			write(rest[:end])
+			i += end
+
+		case rest[0] == ';':
+			flush()
+			i++
+
+		default:
+			write(rest[:1])
+			i++
+		}
+	}
+	flush()
+
+	return statements
+}
+
+var dollarQuoteTagRe = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
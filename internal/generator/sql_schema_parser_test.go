@@ -0,0 +1,225 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSQLSchemaParser_ParseSQL(t *testing.T) {
+	sql := `-- Enum types
+CREATE TYPE "order_status" AS ENUM ('pending', 'shipped');
+
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    email VARCHAR(255) NOT NULL UNIQUE,
+    "displayName" TEXT,
+    balance NUMERIC(10, 2) DEFAULT 0 CHECK (balance >= 0)
+);
+
+CREATE TABLE public.orders (
+    id SERIAL,
+    user_id UUID NOT NULL,
+    status order_status NOT NULL DEFAULT 'pending',
+    note TEXT DEFAULT 'a; b',
+    PRIMARY KEY (id),
+    CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT orders_note_status UNIQUE (note, status)
+);
+
+CREATE INDEX idx_orders_status ON orders USING hash (status) WHERE status <> 'shipped';
+
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    NEW.note := 'touched;';
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+`
+
+	schema, err := NewSQLSchemaParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("ParseSQL() error = %v", err)
+	}
+
+	if got := schema.EnumTypes["order_status"]; !reflect.DeepEqual(got, []string{"pending", "shipped"}) {
+		t.Errorf("unexpected enum values: %v", got)
+	}
+
+	users, ok := schema.GetTable("users")
+	if !ok {
+		t.Fatal("users table not parsed")
+	}
+	if len(users.Columns) != 4 {
+		t.Fatalf("expected 4 user columns, got %d", len(users.Columns))
+	}
+	id := users.Columns[0]
+	if !id.IsPrimaryKey || id.IsNullable || id.DefaultValue == nil || *id.DefaultValue != "gen_random_uuid()" {
+		t.Errorf("unexpected id column: %+v", id)
+	}
+	email := users.Columns[1]
+	if email.Type != "VARCHAR(255)" || email.IsNullable || !email.IsUnique {
+		t.Errorf("unexpected email column: %+v", email)
+	}
+	if users.Columns[2].Name != "displayName" {
+		t.Errorf("expected quoted identifier to keep its case, got %s", users.Columns[2].Name)
+	}
+	balance := users.Columns[3]
+	if balance.Type != "NUMERIC(10, 2)" || balance.CheckConstraint == nil || *balance.CheckConstraint != "balance >= 0" {
+		t.Errorf("unexpected balance column: %+v", balance)
+	}
+
+	orders, ok := schema.GetTable("orders")
+	if !ok {
+		t.Fatal("orders table not parsed")
+	}
+	if !orders.Columns[0].IsPrimaryKey || !orders.Columns[0].IsAutoIncrement {
+		t.Errorf("expected serial primary key, got %+v", orders.Columns[0])
+	}
+	fk := orders.Columns[1].ForeignKey
+	if fk == nil || fk.ReferencedTable != "users" || fk.ReferencedColumn != "id" || fk.OnDelete != "SET NULL" || fk.OnUpdate != "CASCADE" {
+		t.Errorf("unexpected foreign key: %+v", fk)
+	}
+	if note := orders.Columns[3].DefaultValue; note == nil || *note != "'a; b'" {
+		t.Errorf("expected semicolon inside a literal to be kept, got %v", note)
+	}
+	if !hasConstraint(&orders, "UNIQUE", "status") {
+		t.Error("expected the table-level unique constraint to be recorded")
+	}
+
+	if len(orders.Indexes) != 1 {
+		t.Fatalf("expected 1 index, got %d", len(orders.Indexes))
+	}
+	idx := orders.Indexes[0]
+	if idx.Name != "idx_orders_status" || idx.Type != "hash" || idx.Where != "status <> 'shipped'" || !reflect.DeepEqual(idx.Columns, []string{"status"}) {
+		t.Errorf("unexpected index: %+v", idx)
+	}
+}
+
+func TestSQLSchemaParser_AlterStatements(t *testing.T) {
+	sql := `
+CREATE TABLE accounts (id INTEGER PRIMARY KEY, name TEXT, legacy TEXT UNIQUE);
+CREATE TABLE teams (id INTEGER PRIMARY KEY);
+CREATE INDEX idx_accounts_name ON accounts (name);
+ALTER TABLE accounts
+    ADD COLUMN team_id INTEGER REFERENCES teams(id),
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN name TYPE VARCHAR(100) USING name::varchar(100),
+    ALTER COLUMN name SET DEFAULT 'anonymous',
+    ALTER COLUMN name SET STORAGE MAIN,
+    ALTER COLUMN name SET COMPRESSION lz4,
+    DROP COLUMN legacy;
+ALTER TABLE accounts ALTER COLUMN name SET COMPRESSION DEFAULT;
+ALTER TABLE accounts RENAME COLUMN name TO display_name;
+ALTER TABLE accounts DROP CONSTRAINT accounts_team_id_fkey;
+DROP INDEX IF EXISTS idx_accounts_name;
+CREATE TYPE mood AS ENUM ('sad', 'happy');
+ALTER TYPE mood ADD VALUE 'ok' BEFORE 'happy';
+ALTER TABLE teams RENAME TO groups;
+`
+
+	schema, err := NewSQLSchemaParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatalf("ParseSQL() error = %v", err)
+	}
+
+	accounts := schema.Tables["accounts"]
+	names := make([]string, len(accounts.Columns))
+	for i, col := range accounts.Columns {
+		names[i] = col.Name
+	}
+	if !reflect.DeepEqual(names, []string{"id", "display_name", "team_id"}) {
+		t.Fatalf("unexpected columns: %v", names)
+	}
+
+	displayName := accounts.Columns[1]
+	if displayName.Type != "VARCHAR(100)" || displayName.IsNullable || displayName.DefaultValue == nil || *displayName.DefaultValue != "'anonymous'" ||
+		displayName.Storage != "main" || displayName.Compression != "" {
+		t.Errorf("unexpected display_name column: %+v", displayName)
+	}
+	if accounts.Columns[2].ForeignKey != nil {
+		t.Error("expected dropping the constraint to clear the foreign key")
+	}
+	if len(accounts.Indexes) != 0 {
+		t.Errorf("expected index to be dropped, got %v", accounts.Indexes)
+	}
+	if !reflect.DeepEqual(schema.EnumTypes["mood"], []string{"sad", "ok", "happy"}) {
+		t.Errorf("unexpected enum values: %v", schema.EnumTypes["mood"])
+	}
+	if schema.HasTable("teams") || !schema.HasTable("groups") {
+		t.Error("expected teams to be renamed to groups")
+	}
+}
+
+func TestSQLSchemaParser_ParsePathReplaysMigrations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"20240101000000_init.up.sql":       "CREATE TABLE users (id INTEGER PRIMARY KEY);",
+		"20240101000000_init.down.sql":     "DROP TABLE users;",
+		"20240201000000_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT;",
+		"20240201000000_email.down.sql":    "ALTER TABLE users DROP COLUMN email;",
+		"20240301000000_posts.up.sql":      "CREATE TABLE posts (id INTEGER PRIMARY KEY);",
+		"20240401000000_no_posts.up.sql":   "DROP TABLE posts;",
+		"20240401000000_no_posts.down.sql": "CREATE TABLE posts (id INTEGER PRIMARY KEY);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema, err := NewSQLSchemaParser().ParsePath(dir)
+	if err != nil {
+		t.Fatalf("ParsePath() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(schema.GetTableNames(), []string{"users"}) {
+		t.Fatalf("unexpected tables: %v", schema.GetTableNames())
+	}
+	if len(schema.Tables["users"].Columns) != 2 {
+		t.Errorf("expected users to have 2 columns, got %d", len(schema.Tables["users"].Columns))
+	}
+}
+
+func TestSQLSchemaParser_ErrorsReportLocation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.sql")
+	content := "CREATE TABLE users (id INTEGER);\n\nALTER TABLE missing\n    ADD COLUMN name TEXT;\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewSQLSchemaParser().ParsePath(path)
+	if err == nil {
+		t.Fatal("expected an error for ALTER TABLE on an unknown table")
+	}
+	if !strings.Contains(err.Error(), path+":3:") {
+		t.Errorf("expected error to point at %s:3, got %v", path, err)
+	}
+}
+
+func TestSQLSchemaParser_IndexRoundTrip(t *testing.T) {
+	want := SchemaIndex{
+		Name:    "idx_documents_embedding",
+		Columns: []string{"embedding vector_cosine_ops"},
+		Type:    "hnsw",
+		With:    "m = 16, ef_construction = 64",
+		Where:   "embedding IS NOT NULL",
+	}
+	ddl := "CREATE TABLE documents (id UUID PRIMARY KEY, embedding vector(3));\n" +
+		NewSQLGenerator().GenerateIndexDDL("documents", want)
+
+	schema, err := NewSQLSchemaParser().ParseSQL(ddl)
+	if err != nil {
+		t.Fatalf("ParseSQL() error = %v", err)
+	}
+	documents, ok := schema.GetTable("documents")
+	if !ok {
+		t.Fatal("documents table not parsed")
+	}
+	if len(documents.Indexes) != 1 || !reflect.DeepEqual(documents.Indexes[0], want) {
+		t.Errorf("index of %q = %+v, want %+v", ddl, documents.Indexes, want)
+	}
+}
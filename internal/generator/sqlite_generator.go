@@ -0,0 +1,500 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/logger"
+)
+
+// sqliteRebuildPrefix names the copy of a table while it is rebuilt
+const sqliteRebuildPrefix = "_storm_new_"
+
+// sqliteUUIDDefault generates a random version 4 UUID, standing in for gen_random_uuid()
+const sqliteUUIDDefault = "(lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || " +
+	"substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))))"
+
+// SQLiteMigration brings a SQLite database from one schema to another
+type SQLiteMigration struct {
+	Statements []string
+	// Rebuilt lists the tables that are recreated and copied, because SQLite's
+	// ALTER TABLE cannot make their changes
+	Rebuilt []string
+	// Destructive describes the changes that lose data: dropped tables and columns
+	Destructive []string
+	// DisableForeignKeys is set when tables are rebuilt or dropped. The statements
+	// must then run with PRAGMA foreign_keys off, or dropping a table would delete
+	// or reject the rows referencing it.
+	DisableForeignKeys bool
+}
+
+// IsEmpty reports whether the schemas are the same
+func (m *SQLiteMigration) IsEmpty() bool {
+	return len(m.Statements) == 0
+}
+
+// SQL renders the migration as a script for the sqlite3 shell or a migration file
+func (m *SQLiteMigration) SQL() string {
+	if m.IsEmpty() {
+		return ""
+	}
+
+	var sql strings.Builder
+	if m.DisableForeignKeys {
+		sql.WriteString("PRAGMA foreign_keys = OFF;\n")
+	}
+	sql.WriteString("BEGIN;\n\n")
+	for _, stmt := range m.Statements {
+		sql.WriteString(stmt + ";\n\n")
+	}
+	if m.DisableForeignKeys {
+		sql.WriteString("-- Lists the rows whose references the rebuilt or dropped tables broke\n")
+		sql.WriteString("PRAGMA foreign_key_check;\n")
+	}
+	sql.WriteString("COMMIT;\n")
+	if m.DisableForeignKeys {
+		sql.WriteString("PRAGMA foreign_keys = ON;\n")
+	}
+	return sql.String()
+}
+
+// SQLiteGenerator generates SQLite DDL from database schemas. Column types are
+// mapped to the types SQLite stores (INTEGER, REAL, NUMERIC, TEXT, BLOB) and
+// the BOOLEAN, DATE and TIMESTAMP names Go drivers convert; enums are TEXT with
+// their CHECK constraint.
+type SQLiteGenerator struct{}
+
+func NewSQLiteGenerator() *SQLiteGenerator {
+	return &SQLiteGenerator{}
+}
+
+// GenerateMigration returns the statements changing a database with schema from
+// into one with schema to. Tables and indexes are created and dropped, and
+// columns that SQLite can add in place are added with ALTER TABLE. Any other
+// change to a table, such as dropping or retyping a column, rebuilds it: a new
+// table is created, the rows are copied, and it replaces the old one. CHECK
+// constraints and enum values are not compared, since SQLite does not report
+// them.
+func (g *SQLiteGenerator) GenerateMigration(from, to *DatabaseSchema) *SQLiteMigration {
+	migration := &SQLiteMigration{}
+
+	for _, name := range sortedTableNames(to) {
+		for _, idx := range to.Tables[name].Indexes {
+			if !sqliteSupportsIndex(idx) {
+				logger.SQL().Warn("Skipping %s index %s of %s: SQLite only has b-tree indexes", idx.Type, idx.Name, name)
+			}
+		}
+	}
+
+	var created, dropped []string
+	for _, name := range sortedTableNames(from, to) {
+		fromTable, inFrom := from.Tables[name]
+		toTable, inTo := to.Tables[name]
+
+		switch {
+		case !inFrom:
+			created = append(created, name)
+		case !inTo:
+			dropped = append(dropped, name)
+		default:
+			g.alterTable(migration, fromTable, toTable)
+		}
+	}
+
+	var statements []string
+	for _, name := range created {
+		statements = append(statements, g.createTable(to.Tables[name], name)...)
+	}
+	migration.Statements = append(statements, migration.Statements...)
+
+	for _, name := range dropped {
+		migration.Statements = append(migration.Statements, "DROP TABLE "+sqliteQuote(name))
+		migration.Destructive = append(migration.Destructive, "drop table "+name)
+		migration.DisableForeignKeys = true
+	}
+
+	return migration
+}
+
+// alterTable adds the statements changing from into to
+func (g *SQLiteGenerator) alterTable(migration *SQLiteMigration, from, to SchemaTable) {
+	fromColumns := make(map[string]SchemaColumn, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+	}
+	toColumns := make(map[string]bool, len(to.Columns))
+
+	rebuild := !sameNames(sqlitePrimaryKey(from), sqlitePrimaryKey(to)) ||
+		!sameKeys(uniqueColumnSets(from), uniqueColumnSets(to))
+
+	var added []SchemaColumn
+	for _, toCol := range to.Columns {
+		toColumns[toCol.Name] = true
+		fromCol, exists := fromColumns[toCol.Name]
+		if !exists {
+			added = append(added, toCol)
+			if !sqliteCanAddColumn(toCol) {
+				rebuild = true
+			}
+			continue
+		}
+		if sqliteColumnType(fromCol.Type) != sqliteColumnType(toCol.Type) ||
+			fromCol.IsNullable != toCol.IsNullable ||
+			sqliteDefault(fromCol) != sqliteDefault(toCol) ||
+			describeForeignKey(fromCol.ForeignKey) != describeForeignKey(toCol.ForeignKey) {
+			rebuild = true
+		}
+	}
+
+	var droppedColumns []string
+	for _, fromCol := range from.Columns {
+		if !toColumns[fromCol.Name] {
+			droppedColumns = append(droppedColumns, fromCol.Name)
+			rebuild = true
+		}
+	}
+
+	if rebuild {
+		for _, name := range droppedColumns {
+			migration.Destructive = append(migration.Destructive, fmt.Sprintf("drop column %s.%s", to.Name, name))
+		}
+		migration.Rebuilt = append(migration.Rebuilt, to.Name)
+		migration.DisableForeignKeys = true
+		migration.Statements = append(migration.Statements, g.rebuildTable(from, to)...)
+		return
+	}
+
+	fromIndexes := make(map[string]SchemaIndex)
+	for _, idx := range g.indexes(from) {
+		fromIndexes[idx.Name] = idx
+	}
+	toIndexes := make(map[string]SchemaIndex)
+	for _, idx := range g.indexes(to) {
+		toIndexes[idx.Name] = idx
+	}
+
+	for _, idx := range g.indexes(from) {
+		if toIdx, exists := toIndexes[idx.Name]; !exists || describeIndex(toIdx) != describeIndex(idx) {
+			migration.Statements = append(migration.Statements, "DROP INDEX "+sqliteQuote(idx.Name))
+		}
+	}
+	for _, col := range added {
+		migration.Statements = append(migration.Statements,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", sqliteQuote(to.Name), g.columnDDL(col, false)))
+	}
+	for _, idx := range g.indexes(to) {
+		if fromIdx, exists := fromIndexes[idx.Name]; !exists || describeIndex(fromIdx) != describeIndex(idx) {
+			migration.Statements = append(migration.Statements, g.indexDDL(to.Name, idx))
+		}
+	}
+}
+
+// rebuildTable recreates from as to under a temporary name, copies the rows of
+// the columns both have, and replaces from with it. Columns that become NOT NULL
+// take their default where they were NULL.
+func (g *SQLiteGenerator) rebuildTable(from, to SchemaTable) []string {
+	tempName := sqliteRebuildPrefix + to.Name
+
+	fromColumns := make(map[string]SchemaColumn, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+	}
+
+	var columns, values []string
+	for _, col := range to.Columns {
+		fromCol, exists := fromColumns[col.Name]
+		if !exists {
+			continue
+		}
+		value := sqliteQuote(col.Name)
+		if def := sqliteDefault(col); fromCol.IsNullable && !col.IsNullable && def != "" {
+			value = fmt.Sprintf("COALESCE(%s, %s)", value, def)
+		}
+		columns = append(columns, sqliteQuote(col.Name))
+		values = append(values, value)
+	}
+
+	statements := []string{g.createTableDDL(to, tempName)}
+	if len(columns) > 0 {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			sqliteQuote(tempName), strings.Join(columns, ", "), strings.Join(values, ", "), sqliteQuote(from.Name)))
+	}
+	statements = append(statements,
+		"DROP TABLE "+sqliteQuote(from.Name),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", sqliteQuote(tempName), sqliteQuote(to.Name)),
+	)
+	for _, idx := range g.indexes(to) {
+		statements = append(statements, g.indexDDL(to.Name, idx))
+	}
+	return statements
+}
+
+// createTable returns CREATE TABLE and CREATE INDEX statements for table
+func (g *SQLiteGenerator) createTable(table SchemaTable, name string) []string {
+	statements := []string{g.createTableDDL(table, name)}
+	for _, idx := range g.indexes(table) {
+		statements = append(statements, g.indexDDL(table.Name, idx))
+	}
+	return statements
+}
+
+func (g *SQLiteGenerator) createTableDDL(table SchemaTable, name string) string {
+	pk := sqlitePrimaryKey(table)
+	rowid := len(pk) == 1 && sqliteIsRowID(table, pk[0])
+
+	// Unique columns also have a unique constraint, which is declared once below
+	constrained := make(map[string]bool)
+	for _, constraint := range table.Constraints {
+		if constraint.Type == "UNIQUE" && len(constraint.Columns) == 1 {
+			constrained[constraint.Columns[0]] = true
+		}
+	}
+
+	var defs []string
+	for _, col := range table.Columns {
+		if constrained[col.Name] {
+			col.IsUnique = false
+		}
+		defs = append(defs, g.columnDDL(col, rowid && col.Name == pk[0]))
+	}
+
+	if len(pk) > 0 && !rowid {
+		quoted := make([]string, len(pk))
+		for i, col := range pk {
+			quoted[i] = sqliteQuote(col)
+		}
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(quoted, ", ")))
+	}
+
+	for _, constraint := range table.Constraints {
+		switch constraint.Type {
+		case "UNIQUE":
+			quoted := make([]string, len(constraint.Columns))
+			for i, col := range constraint.Columns {
+				quoted[i] = sqliteQuote(col)
+			}
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", sqliteQuote(constraint.Name), strings.Join(quoted, ", ")))
+		case "CHECK":
+			defs = append(defs, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", sqliteQuote(constraint.Name), constraint.Definition))
+		}
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", sqliteQuote(name), strings.Join(defs, ",\n    "))
+}
+
+// columnDDL renders a column; the rowid column is declared INTEGER PRIMARY KEY
+// inline, so SQLite assigns it
+func (g *SQLiteGenerator) columnDDL(col SchemaColumn, rowid bool) string {
+	parts := []string{sqliteQuote(col.Name), sqliteColumnType(col.Type)}
+
+	if rowid {
+		parts = append(parts, "PRIMARY KEY")
+		if col.IsAutoIncrement {
+			parts = append(parts, "AUTOINCREMENT")
+		}
+	}
+	if !col.IsNullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if def := sqliteDefault(col); def != "" {
+		parts = append(parts, "DEFAULT "+def)
+	}
+	if col.IsUnique && !col.IsPrimaryKey {
+		parts = append(parts, "UNIQUE")
+	}
+	if col.CheckConstraint != nil {
+		parts = append(parts, fmt.Sprintf("CHECK (%s)", *col.CheckConstraint))
+	}
+	if fk := col.ForeignKey; fk != nil {
+		parts = append(parts, fmt.Sprintf("REFERENCES %s(%s)", sqliteQuote(fk.ReferencedTable), sqliteQuote(fk.ReferencedColumn)))
+		if action := normalizeReferentialAction(fk.OnDelete); action != "NO ACTION" {
+			parts = append(parts, "ON DELETE "+action)
+		}
+		if action := normalizeReferentialAction(fk.OnUpdate); action != "NO ACTION" {
+			parts = append(parts, "ON UPDATE "+action)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (g *SQLiteGenerator) indexDDL(tableName string, idx SchemaIndex) string {
+	quoted := make([]string, len(idx.Columns))
+	for i, col := range idx.Columns {
+		quoted[i] = sqliteQuote(col)
+	}
+
+	unique := ""
+	if idx.IsUnique {
+		unique = "UNIQUE "
+	}
+	stmt := fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, sqliteQuote(idx.Name), sqliteQuote(tableName), strings.Join(quoted, ", "))
+	if idx.Where != "" {
+		stmt += " WHERE " + idx.Where
+	}
+	return stmt
+}
+
+// indexes returns the indexes of table SQLite can build, sorted by name. Indexes
+// backing the primary key or a unique column are left out, and so are the GIN,
+// GiST and vector indexes SQLite has no equivalent for.
+func (g *SQLiteGenerator) indexes(table SchemaTable) []SchemaIndex {
+	var indexes []SchemaIndex
+	for _, idx := range table.Indexes {
+		if NewSQLGenerator().isImplicitIndex(idx, table) {
+			continue
+		}
+		if !sqliteSupportsIndex(idx) {
+			continue
+		}
+		indexes = append(indexes, idx)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+	return indexes
+}
+
+func sqliteSupportsIndex(idx SchemaIndex) bool {
+	return idx.Type == "" || strings.EqualFold(idx.Type, "btree")
+}
+
+// sqliteColumnType maps a PostgreSQL column type to the SQLite type declared for
+// it. SQLite type names map to themselves.
+func sqliteColumnType(columnType string) string {
+	t := normalizeColumnType(columnType)
+	if strings.HasSuffix(t, "[]") {
+		return "TEXT"
+	}
+	if idx := strings.Index(t, "("); idx != -1 {
+		t = t[:idx]
+	}
+
+	switch t {
+	case "smallint", "integer", "bigint":
+		return "INTEGER"
+	case "boolean":
+		return "BOOLEAN"
+	case "real", "double precision":
+		return "REAL"
+	case "numeric":
+		return "NUMERIC"
+	case "timestamp with time zone", "timestamp without time zone", "datetime":
+		return "TIMESTAMP"
+	case "date":
+		return "DATE"
+	case "bytea", "blob":
+		return "BLOB"
+	default:
+		return "TEXT"
+	}
+}
+
+// sqliteDefault renders the default of col as a SQLite expression. PostgreSQL
+// functions with a SQLite equivalent are translated, and other function calls
+// are enclosed in the parentheses SQLite requires.
+func sqliteDefault(col SchemaColumn) string {
+	if col.DefaultValue == nil {
+		return ""
+	}
+
+	value := strings.TrimSpace(defaultCastRe.ReplaceAllString(*col.DefaultValue, ""))
+	switch strings.ToLower(value) {
+	case "":
+		return ""
+	case "now()", "current_timestamp", "localtimestamp":
+		return "CURRENT_TIMESTAMP"
+	case "current_date":
+		return "CURRENT_DATE"
+	case "current_time":
+		return "CURRENT_TIME"
+	case "gen_random_uuid()", "uuid_generate_v4()":
+		return sqliteUUIDDefault
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	case "null":
+		return "NULL"
+	}
+
+	switch {
+	case strings.HasPrefix(value, "'"), strings.HasPrefix(value, "("):
+		return value
+	case strings.Contains(value, "("):
+		return "(" + value + ")"
+	}
+	if _, err := fmt.Sscanf(value, "%f", new(float64)); err == nil && len(strings.Fields(value)) == 1 {
+		return value
+	}
+	if sqliteColumnType(col.Type) == "TEXT" {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return value
+}
+
+// sqliteCanAddColumn reports whether ALTER TABLE ADD COLUMN can add col: it may
+// not be part of a key, a NOT NULL column needs a default, a column with a
+// foreign key may not have one, and the default must be a constant
+func sqliteCanAddColumn(col SchemaColumn) bool {
+	def := sqliteDefault(col)
+	switch {
+	case col.IsPrimaryKey, col.IsUnique:
+		return false
+	case !col.IsNullable && (def == "" || def == "NULL"):
+		return false
+	case col.ForeignKey != nil && def != "" && def != "NULL":
+		return false
+	case strings.HasPrefix(def, "("), strings.HasPrefix(def, "CURRENT_"):
+		return false
+	}
+	return true
+}
+
+// sqlitePrimaryKey returns the primary key columns of table in column order
+func sqlitePrimaryKey(table SchemaTable) []string {
+	var pk []string
+	for _, col := range table.Columns {
+		if col.IsPrimaryKey {
+			pk = append(pk, col.Name)
+		}
+	}
+	return pk
+}
+
+// sqliteIsRowID reports whether column is an integer that can be the rowid
+func sqliteIsRowID(table SchemaTable, column string) bool {
+	for _, col := range table.Columns {
+		if col.Name == column {
+			return sqliteColumnType(col.Type) == "INTEGER"
+		}
+	}
+	return false
+}
+
+func sameKeys(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if !b[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sqliteQuote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,165 @@
+package generator
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func sqliteUsers(columns ...SchemaColumn) SchemaTable {
+	return SchemaTable{
+		Name: "users",
+		Columns: append([]SchemaColumn{
+			{Name: "id", Type: "BIGSERIAL", IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "email", Type: "VARCHAR(255)", IsUnique: true},
+		}, columns...),
+		Indexes: []SchemaIndex{
+			{Name: "idx_users_email_lower", Columns: []string{"email"}, Where: "email IS NOT NULL"},
+		},
+	}
+}
+
+func sqliteSchema(tables ...SchemaTable) *DatabaseSchema {
+	schema := &DatabaseSchema{Tables: make(map[string]SchemaTable)}
+	for _, table := range tables {
+		schema.Tables[table.Name] = table
+	}
+	return schema
+}
+
+func TestSQLiteGenerator_CreateTable(t *testing.T) {
+	to := sqliteSchema(sqliteUsers(
+		SchemaColumn{Name: "active", Type: "BOOLEAN", DefaultValue: strPtr("true")},
+		SchemaColumn{Name: "created_at", Type: "TIMESTAMPTZ", DefaultValue: strPtr("now()")},
+	))
+
+	migration := NewSQLiteGenerator().GenerateMigration(sqliteSchema(), to)
+	sql := migration.SQL()
+
+	for _, want := range []string{
+		`"id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL`,
+		`"email" TEXT NOT NULL UNIQUE`,
+		`"active" BOOLEAN NOT NULL DEFAULT 1`,
+		`"created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+		`CREATE INDEX "idx_users_email_lower" ON "users" ("email") WHERE email IS NOT NULL`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %q in\n%s", want, sql)
+		}
+	}
+	if migration.DisableForeignKeys || len(migration.Rebuilt) != 0 {
+		t.Errorf("creating tables should not rebuild any, got %+v", migration)
+	}
+}
+
+func TestSQLiteGenerator_AddsColumnsInPlace(t *testing.T) {
+	from := sqliteSchema(sqliteUsers())
+	to := sqliteSchema(sqliteUsers(
+		SchemaColumn{Name: "bio", Type: "TEXT", IsNullable: true},
+		SchemaColumn{Name: "score", Type: "INTEGER", DefaultValue: strPtr("0")},
+	))
+
+	migration := NewSQLiteGenerator().GenerateMigration(from, to)
+
+	want := []string{
+		`ALTER TABLE "users" ADD COLUMN "bio" TEXT`,
+		`ALTER TABLE "users" ADD COLUMN "score" INTEGER NOT NULL DEFAULT 0`,
+	}
+	if strings.Join(migration.Statements, "\n") != strings.Join(want, "\n") {
+		t.Errorf("statements = %q, want %q", migration.Statements, want)
+	}
+	if len(migration.Rebuilt) != 0 {
+		t.Errorf("expected no rebuild, got %v", migration.Rebuilt)
+	}
+}
+
+func TestSQLiteGenerator_RebuildsTables(t *testing.T) {
+	from := sqliteSchema(sqliteUsers(
+		SchemaColumn{Name: "nickname", Type: "TEXT", IsNullable: true},
+		SchemaColumn{Name: "score", Type: "INTEGER", IsNullable: true},
+	))
+	to := sqliteSchema(sqliteUsers(
+		SchemaColumn{Name: "score", Type: "INTEGER", DefaultValue: strPtr("0")},
+	))
+
+	migration := NewSQLiteGenerator().GenerateMigration(from, to)
+
+	if len(migration.Rebuilt) != 1 || migration.Rebuilt[0] != "users" {
+		t.Fatalf("expected users to be rebuilt, got %v", migration.Rebuilt)
+	}
+	if !migration.DisableForeignKeys {
+		t.Error("expected foreign keys to be turned off for the rebuild")
+	}
+	if len(migration.Destructive) != 1 || migration.Destructive[0] != "drop column users.nickname" {
+		t.Errorf("destructive = %v, want the dropped nickname column", migration.Destructive)
+	}
+	if !strings.Contains(migration.SQL(), `SELECT "id", "email", COALESCE("score", 0) FROM "users"`) {
+		t.Errorf("expected the rows to be copied with the new default\n%s", migration.SQL())
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	create := NewSQLiteGenerator().GenerateMigration(sqliteSchema(), from)
+	for _, stmt := range append(create.Statements, `INSERT INTO users (email, nickname) VALUES ('a@example.com', 'a')`) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	for _, stmt := range migration.Statements {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	var email string
+	var score int
+	if err := db.QueryRow(`SELECT email, score FROM users`).Scan(&email, &score); err != nil {
+		t.Fatal(err)
+	}
+	if email != "a@example.com" || score != 0 {
+		t.Errorf("got row (%q, %d), want the copied row with score 0", email, score)
+	}
+}
+
+func TestSQLiteGenerator_DropTable(t *testing.T) {
+	migration := NewSQLiteGenerator().GenerateMigration(sqliteSchema(sqliteUsers()), sqliteSchema())
+
+	if len(migration.Statements) != 1 || migration.Statements[0] != `DROP TABLE "users"` {
+		t.Errorf("statements = %q, want DROP TABLE users", migration.Statements)
+	}
+	if len(migration.Destructive) != 1 || !migration.DisableForeignKeys {
+		t.Errorf("expected a destructive drop with foreign keys off, got %+v", migration)
+	}
+}
+
+func TestSQLiteDefault(t *testing.T) {
+	tests := []struct {
+		colType string
+		value   string
+		want    string
+	}{
+		{"TIMESTAMPTZ", "now()", "CURRENT_TIMESTAMP"},
+		{"BOOLEAN", "false", "0"},
+		{"TEXT", "active", "'active'"},
+		{"TEXT", "'draft'::text", "'draft'"},
+		{"INTEGER", "42", "42"},
+		{"UUID", "gen_random_uuid()", sqliteUUIDDefault},
+		{"TEXT", "upper('a')", "(upper('a'))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			col := SchemaColumn{Name: "c", Type: tt.colType, DefaultValue: strPtr(tt.value)}
+			if got := sqliteDefault(col); got != tt.want {
+				t.Errorf("sqliteDefault(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
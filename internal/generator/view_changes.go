@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	viewSourceRe      = regexp.MustCompile(`(?is)\b(?:FROM|JOIN)\s+((?:"[^"]+"|[\w.]+))`)
+	viewPunctuationRe = regexp.MustCompile(`\s*([(),])\s*`)
+	viewQuotedNameRe  = regexp.MustCompile(`"([a-z_][a-z0-9_]*)"`)
+)
+
+// GenerateCreateView returns the statement creating view. Plain views use CREATE OR
+// REPLACE, so the statement also updates an existing view whose columns it keeps.
+func (g *SQLGenerator) GenerateCreateView(view SchemaView) string {
+	if view.Materialized {
+		return fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s;\n", view.Name, view.Definition)
+	}
+	return fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s;\n", view.Name, view.Definition)
+}
+
+// GenerateDropView returns the statement dropping view
+func (g *SQLGenerator) GenerateDropView(view SchemaView) string {
+	if view.Materialized {
+		return fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %s;\n", view.Name)
+	}
+	return fmt.Sprintf("DROP VIEW IF EXISTS %s;\n", view.Name)
+}
+
+// GenerateViewChanges returns the statements turning the views of from into those of
+// to. Views are dropped before the views they read from, and created after them.
+// Changed plain views are replaced in place; materialized views, and views changing
+// between plain and materialized, cannot be, so they are dropped and created again,
+// together with the views reading from them.
+func (g *SQLGenerator) GenerateViewChanges(from, to *DatabaseSchema) []string {
+	drop := make(map[string]bool)
+	create := make(map[string]bool)
+
+	for name, fromView := range from.Views {
+		toView, exists := to.Views[name]
+		switch {
+		case !exists:
+			drop[name] = true
+		case fromView.Materialized || toView.Materialized:
+			if fromView.Materialized != toView.Materialized ||
+				normalizeViewDefinition(fromView.Definition) != normalizeViewDefinition(toView.Definition) {
+				drop[name] = true
+				create[name] = true
+			}
+		case normalizeViewDefinition(fromView.Definition) != normalizeViewDefinition(toView.Definition):
+			create[name] = true
+		}
+	}
+	for name := range to.Views {
+		if _, exists := from.Views[name]; !exists {
+			create[name] = true
+		}
+	}
+
+	// A view reading from a dropped view goes with it, and is created again
+	for changed := true; changed; {
+		changed = false
+		for name, view := range from.Views {
+			if drop[name] {
+				continue
+			}
+			for _, dep := range viewDependencies(view, from) {
+				if drop[dep] {
+					drop[name] = true
+					if _, exists := to.Views[name]; exists {
+						create[name] = true
+					}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var statements []string
+	order := sortViewsByDependencies(from)
+	for i := len(order) - 1; i >= 0; i-- {
+		if drop[order[i]] {
+			statements = append(statements, strings.TrimSpace(g.GenerateDropView(from.Views[order[i]])))
+		}
+	}
+	for _, name := range sortViewsByDependencies(to) {
+		if create[name] {
+			statements = append(statements, strings.TrimSpace(g.GenerateCreateView(to.Views[name])))
+		}
+	}
+	return statements
+}
+
+// sortViewsByDependencies returns the view names of schema ordered so that every view
+// comes after the views it reads from, and by name otherwise
+func sortViewsByDependencies(schema *DatabaseSchema) []string {
+	names := make([]string, 0, len(schema.Views))
+	for name := range schema.Views {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		// Marked before the dependencies are visited, so a cycle ends here
+		visited[name] = true
+		for _, dep := range viewDependencies(schema.Views[name], schema) {
+			visit(dep)
+		}
+		order = append(order, name)
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return order
+}
+
+// viewDependencies returns the other views of schema that view reads from
+func viewDependencies(view SchemaView, schema *DatabaseSchema) []string {
+	var deps []string
+	seen := make(map[string]bool)
+	for _, m := range viewSourceRe.FindAllStringSubmatch(view.Definition, -1) {
+		name := normalizeIdentifier(m[1])
+		if _, isView := schema.Views[name]; isView && name != view.Name && !seen[name] {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// normalizeViewDefinition makes definitions that differ only in spelling compare equal:
+// keywords and unquoted names are lowercased, whitespace is collapsed, the default
+// public schema, needless identifier quotes and a trailing semicolon are dropped.
+// String literals are kept as they are.
+func normalizeViewDefinition(definition string) string {
+	definition = strings.TrimSuffix(strings.TrimSpace(definition), ";")
+
+	var normalized strings.Builder
+	for i, part := range strings.Split(definition, "'") {
+		// Odd parts are inside string literals
+		if i%2 == 1 {
+			normalized.WriteString("'" + part + "'")
+			continue
+		}
+		part = strings.Join(strings.Fields(strings.ToLower(part)), " ")
+		part = viewPunctuationRe.ReplaceAllString(part, "$1")
+		part = viewQuotedNameRe.ReplaceAllString(part, "$1")
+		part = strings.ReplaceAll(part, "public.", "")
+		normalized.WriteString(part)
+	}
+	return strings.TrimSpace(normalized.String())
+}
@@ -0,0 +1,144 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func viewSchema(views ...SchemaView) *DatabaseSchema {
+	schema := &DatabaseSchema{Views: make(map[string]SchemaView)}
+	for _, view := range views {
+		schema.Views[view.Name] = view
+	}
+	return schema
+}
+
+func TestSQLSchemaParser_Views(t *testing.T) {
+	schema, err := NewSQLSchemaParser().ParseSQL(`
+		CREATE TABLE users (id SERIAL PRIMARY KEY, active BOOLEAN NOT NULL);
+		CREATE VIEW active_users AS SELECT id FROM users WHERE active;
+		CREATE MATERIALIZED VIEW IF NOT EXISTS public.user_counts AS SELECT count(*) AS n FROM active_users WITH DATA;
+		CREATE VIEW old_users AS SELECT id FROM users;
+		DROP VIEW old_users;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]SchemaView{
+		"active_users": {Name: "active_users", Definition: "SELECT id FROM users WHERE active"},
+		"user_counts":  {Name: "user_counts", Definition: "SELECT count(*) AS n FROM active_users", Materialized: true},
+	}
+	if !reflect.DeepEqual(schema.Views, expected) {
+		t.Errorf("views = %+v, want %+v", schema.Views, expected)
+	}
+}
+
+func TestNormalizeViewDefinition(t *testing.T) {
+	a := `SELECT "users"."id", users.name
+	FROM public.users WHERE status = 'Active';`
+	b := `select users.id,users.name from users where status = 'Active'`
+	if normalizeViewDefinition(a) != normalizeViewDefinition(b) {
+		t.Errorf("expected %q and %q to normalize equally, got %q and %q", a, b, normalizeViewDefinition(a), normalizeViewDefinition(b))
+	}
+
+	c := `select users.id, users.name from users where status = 'active'`
+	if normalizeViewDefinition(a) == normalizeViewDefinition(c) {
+		t.Error("expected string literals to keep their case")
+	}
+}
+
+func TestCompareSchemas_Views(t *testing.T) {
+	from := viewSchema(
+		SchemaView{Name: "active_users", Definition: "SELECT id FROM users WHERE active"},
+		SchemaView{Name: "old_users", Definition: "SELECT id FROM users"},
+		SchemaView{Name: "user_counts", Definition: "SELECT count(*) FROM users"},
+	)
+	to := viewSchema(
+		SchemaView{Name: "active_users", Definition: "select id from users where active and not banned"},
+		SchemaView{Name: "new_users", Definition: "SELECT id FROM users"},
+		SchemaView{Name: "user_counts", Definition: "SELECT count(*) FROM users", Materialized: true},
+	)
+
+	var got []string
+	for _, diff := range CompareSchemas(from, to) {
+		got = append(got, diff.String())
+	}
+
+	expected := []string{
+		"view active_users definition changed",
+		"view new_users added",
+		"view old_users dropped",
+		"view user_counts changed from view to materialized view",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected differences:\n got: %q\nwant: %q", got, expected)
+	}
+}
+
+func TestSQLGenerator_GenerateViewChanges(t *testing.T) {
+	from := viewSchema(
+		SchemaView{Name: "active_users", Definition: "SELECT id, name FROM users WHERE active"},
+		SchemaView{Name: "active_user_names", Definition: "SELECT name FROM active_users"},
+		SchemaView{Name: "user_stats", Definition: "SELECT count(*) AS n FROM users", Materialized: true},
+		SchemaView{Name: "stat_report", Definition: "SELECT n FROM user_stats"},
+		SchemaView{Name: "legacy", Definition: "SELECT 1"},
+	)
+	to := viewSchema(
+		SchemaView{Name: "active_users", Definition: "SELECT id, name FROM users WHERE active AND NOT banned"},
+		SchemaView{Name: "active_user_names", Definition: "SELECT name FROM active_users"},
+		SchemaView{Name: "user_stats", Definition: "SELECT count(*) AS n, max(id) AS last FROM users", Materialized: true},
+		SchemaView{Name: "stat_report", Definition: "SELECT n FROM user_stats"},
+		SchemaView{Name: "banned_names", Definition: "SELECT name FROM banned_users"},
+		SchemaView{Name: "banned_users", Definition: "SELECT id, name FROM users WHERE banned"},
+	)
+
+	got := NewSQLGenerator().GenerateViewChanges(from, to)
+
+	expected := []string{
+		"DROP VIEW IF EXISTS stat_report;",
+		"DROP MATERIALIZED VIEW IF EXISTS user_stats;",
+		"DROP VIEW IF EXISTS legacy;",
+		"CREATE OR REPLACE VIEW active_users AS\nSELECT id, name FROM users WHERE active AND NOT banned;",
+		"CREATE OR REPLACE VIEW banned_users AS\nSELECT id, name FROM users WHERE banned;",
+		"CREATE OR REPLACE VIEW banned_names AS\nSELECT name FROM banned_users;",
+		"CREATE MATERIALIZED VIEW user_stats AS\nSELECT count(*) AS n, max(id) AS last FROM users;",
+		"CREATE OR REPLACE VIEW stat_report AS\nSELECT n FROM user_stats;",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n got: %q\nwant: %q", got, expected)
+	}
+
+	if changes := NewSQLGenerator().GenerateViewChanges(to, to); len(changes) != 0 {
+		t.Errorf("expected no changes between equal schemas, got %q", changes)
+	}
+}
+
+func TestSQLGenerator_GenerateSchema_Views(t *testing.T) {
+	schema := &DatabaseSchema{
+		Tables: map[string]SchemaTable{
+			"users": {Name: "users", Columns: []SchemaColumn{{Name: "id", Type: "SERIAL", IsPrimaryKey: true}}},
+		},
+		Views: map[string]SchemaView{
+			"a_recent":     {Name: "a_recent", Definition: "SELECT id FROM z_users_view"},
+			"z_users_view": {Name: "z_users_view", Definition: "SELECT id FROM users"},
+		},
+	}
+
+	sql := NewSQLGenerator().GenerateSchema(schema)
+	table := strings.Index(sql, "CREATE TABLE users")
+	base := strings.Index(sql, "CREATE OR REPLACE VIEW z_users_view")
+	dependent := strings.Index(sql, "CREATE OR REPLACE VIEW a_recent")
+	if table == -1 || base == -1 || dependent == -1 || !(table < base && base < dependent) {
+		t.Errorf("expected the table, then z_users_view, then a_recent\n%s", sql)
+	}
+
+	parsed, err := NewSQLSchemaParser().ParseSQL(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Views, schema.Views) {
+		t.Errorf("views do not round trip: got %+v", parsed.Views)
+	}
+}
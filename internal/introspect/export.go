@@ -238,8 +238,20 @@ func exportSQL(schema *DatabaseSchema) ([]byte, error) {
 			b.WriteString(";\n")
 		}
 
+		for _, col := range table.Columns {
+			if col.Storage != "" {
+				b.WriteString(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STORAGE %s;\n",
+					table.Name, col.Name, strings.ToUpper(col.Storage)))
+			}
+			if col.Compression != "" {
+				b.WriteString(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET COMPRESSION %s;\n",
+					table.Name, col.Name, col.Compression))
+			}
+		}
+
 		for _, idx := range table.Indexes {
-			if idx.IsPrimary {
+			// The indexes of constraints are created with the constraints above
+			if idx.IsPrimary || idx.Constraint != "" {
 				continue
 			}
 
@@ -259,6 +271,9 @@ func exportSQL(schema *DatabaseSchema) ([]byte, error) {
 
 			b.WriteString(fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)",
 				unique, idx.Name, table.Name, strings.Join(cols, ", ")))
+			if idx.IsUnique && idx.NullsNotDistinct {
+				b.WriteString(" NULLS NOT DISTINCT")
+			}
 
 			if idx.Where != "" {
 				b.WriteString(fmt.Sprintf(" WHERE %s", idx.Where))
@@ -273,6 +288,9 @@ func exportSQL(schema *DatabaseSchema) ([]byte, error) {
 		b.WriteString("-- Sequences\n")
 		for _, seq := range schema.Sequences {
 			b.WriteString(fmt.Sprintf("CREATE SEQUENCE %s\n", seq.Name))
+			if seq.DataType != "" {
+				b.WriteString(fmt.Sprintf("    AS %s\n", seq.DataType))
+			}
 			b.WriteString(fmt.Sprintf("    INCREMENT BY %d\n", seq.Increment))
 			b.WriteString(fmt.Sprintf("    MINVALUE %d\n", seq.MinValue))
 			b.WriteString(fmt.Sprintf("    MAXVALUE %d\n", seq.MaxValue))
@@ -282,6 +300,9 @@ func exportSQL(schema *DatabaseSchema) ([]byte, error) {
 			} else {
 				b.WriteString("    NO CYCLE\n")
 			}
+			if seq.OwnedBy != "" {
+				b.WriteString(fmt.Sprintf("    OWNED BY %s\n", seq.OwnedBy))
+			}
 			b.WriteString(";\n\n")
 		}
 	}
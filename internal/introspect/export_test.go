@@ -235,6 +235,63 @@ func TestExportSQL_WithSequences(t *testing.T) {
 	if !strings.Contains(outputStr, "CREATE SEQUENCE users_id_seq") {
 		t.Error("Expected SQL to contain sequence creation")
 	}
+	if !strings.Contains(outputStr, "    AS bigint\n") || !strings.Contains(outputStr, "    OWNED BY users.id\n") {
+		t.Errorf("Expected the sequence type and owner in:\n%s", outputStr)
+	}
+}
+
+func TestExportSQL_ConstraintIndexes(t *testing.T) {
+	schema := createTestSchema()
+	users := schema.Tables["users"]
+	users.Constraints = []*ConstraintSchema{
+		{Name: "users_email_key", Type: "UNIQUE", Definition: "UNIQUE (email)", Columns: []string{"email"}},
+	}
+	users.Indexes = append(users.Indexes, &IndexSchema{
+		Name:       "users_email_key",
+		Columns:    []IndexColumn{{Name: "email"}},
+		IsUnique:   true,
+		Constraint: "users_email_key",
+	})
+
+	inspector := &Inspector{}
+	output, err := inspector.ExportSchema(schema, ExportFormatSQL)
+	if err != nil {
+		t.Fatalf("Failed to export SQL: %v", err)
+	}
+
+	outputStr := string(output)
+	if !strings.Contains(outputStr, "CONSTRAINT users_email_key UNIQUE (email)") {
+		t.Errorf("Expected the unique constraint in:\n%s", outputStr)
+	}
+	if strings.Contains(outputStr, "INDEX users_email_key") {
+		t.Errorf("Expected the index of the constraint to be left out of:\n%s", outputStr)
+	}
+	if !strings.Contains(outputStr, "CREATE UNIQUE INDEX idx_users_email ON users (email)") {
+		t.Errorf("Expected the standalone index in:\n%s", outputStr)
+	}
+}
+
+func TestExportSQL_ColumnStorage(t *testing.T) {
+	schema := createTestSchema()
+	email := schema.Tables["users"].Columns[1]
+	email.Storage = "external"
+	email.Compression = "lz4"
+
+	inspector := &Inspector{}
+	output, err := inspector.ExportSchema(schema, ExportFormatSQL)
+	if err != nil {
+		t.Fatalf("Failed to export SQL: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, stmt := range []string{
+		"ALTER TABLE users ALTER COLUMN email SET STORAGE EXTERNAL;",
+		"ALTER TABLE users ALTER COLUMN email SET COMPRESSION lz4;",
+	} {
+		if !strings.Contains(outputStr, stmt) {
+			t.Errorf("Expected %q in:\n%s", stmt, outputStr)
+		}
+	}
 }
 
 func TestExportSQL_WithFunctions(t *testing.T) {
@@ -9,10 +9,16 @@ import (
 
 // Inspector provides methods to inspect database schema
 type Inspector struct {
-	db     *sql.DB
-	driver string
+	db       *sql.DB
+	driver   string
+	progress ProgressFunc
 }
 
+// ProgressFunc is called after each table is inspected, with the number of tables
+// inspected so far and the total
+type ProgressFunc func(done, total int, table string)
+
+// NewInspector creates an inspector for db, whose driver is "postgres", "mysql" or "sqlite"
 func NewInspector(db *sql.DB, driver string) *Inspector {
 	return &Inspector{
 		db:     db,
@@ -20,10 +26,19 @@ func NewInspector(db *sql.DB, driver string) *Inspector {
 	}
 }
 
+// SetProgress sets the function notified as tables are inspected
+func (i *Inspector) SetProgress(progress ProgressFunc) {
+	i.progress = progress
+}
+
 func (i *Inspector) GetSchema(ctx context.Context) (*DatabaseSchema, error) {
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLSchema(ctx)
+	case "mysql":
+		return i.getMySQLSchema(ctx)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteSchema(ctx)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -33,6 +48,10 @@ func (i *Inspector) GetTable(ctx context.Context, schemaName, tableName string)
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLTable(ctx, schemaName, tableName)
+	case "mysql":
+		return i.getMySQLTable(ctx, schemaName, tableName)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteTable(ctx, schemaName, tableName)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -42,6 +61,10 @@ func (i *Inspector) GetTables(ctx context.Context) ([]*TableSchema, error) {
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLTables(ctx)
+	case "mysql":
+		return i.getMySQLTables(ctx)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteTables(ctx)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -51,6 +74,10 @@ func (i *Inspector) GetDatabaseMetadata(ctx context.Context) (*DatabaseMetadata,
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLMetadata(ctx)
+	case "mysql":
+		return i.getMySQLMetadata(ctx)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteMetadata(ctx)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -60,6 +87,11 @@ func (i *Inspector) GetEnums(ctx context.Context) (map[string]*EnumSchema, error
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLEnums(ctx)
+	case "mysql":
+		return i.getMySQLEnums(ctx)
+	case "sqlite", "sqlite3":
+		// SQLite has no enums; storm writes them as CHECK constraints
+		return make(map[string]*EnumSchema), nil
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -69,6 +101,11 @@ func (i *Inspector) GetFunctions(ctx context.Context) (map[string]*FunctionSchem
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLFunctions(ctx)
+	case "mysql":
+		return i.getMySQLFunctions(ctx)
+	case "sqlite", "sqlite3":
+		// SQLite has no stored functions
+		return make(map[string]*FunctionSchema), nil
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -78,6 +115,12 @@ func (i *Inspector) GetSequences(ctx context.Context) (map[string]*SequenceSchem
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLSequences(ctx)
+	case "mysql":
+		// MySQL has no sequences; AUTO_INCREMENT columns are reported as identity columns
+		return make(map[string]*SequenceSchema), nil
+	case "sqlite", "sqlite3":
+		// SQLite has no sequences; INTEGER PRIMARY KEY columns are reported as identity columns
+		return make(map[string]*SequenceSchema), nil
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -87,6 +130,10 @@ func (i *Inspector) GetViews(ctx context.Context) (map[string]*ViewSchema, error
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLViews(ctx)
+	case "mysql":
+		return i.getMySQLViews(ctx)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteViews(ctx)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
@@ -96,6 +143,10 @@ func (i *Inspector) GetTableStatistics(ctx context.Context, schemaName, tableNam
 	switch i.driver {
 	case "postgres":
 		return i.getPostgreSQLTableStatistics(ctx, schemaName, tableName)
+	case "mysql":
+		return i.getMySQLTableStatistics(ctx, schemaName, tableName)
+	case "sqlite", "sqlite3":
+		return i.getSQLiteTableStatistics(ctx, schemaName, tableName)
 	default:
 		return nil, fmt.Errorf("unsupported database driver: %s", i.driver)
 	}
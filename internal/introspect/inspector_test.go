@@ -24,7 +24,7 @@ func TestNewInspector(t *testing.T) {
 
 func TestInspector_UnsupportedDriver(t *testing.T) {
 	var db *sql.DB
-	inspector := NewInspector(db, "mysql")
+	inspector := NewInspector(db, "oracle")
 
 	ctx := context.Background()
 
@@ -32,7 +32,7 @@ func TestInspector_UnsupportedDriver(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for unsupported driver")
 	}
-	if err.Error() != "unsupported database driver: mysql" {
+	if err.Error() != "unsupported database driver: oracle" {
 		t.Errorf("Unexpected error message: %v", err)
 	}
 
@@ -168,3 +168,39 @@ func TestInspector_PostgresDriver(t *testing.T) {
 		_ = err
 	})
 }
+
+func TestInspector_TableProgress(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM information_schema.tables").WillReturnRows(
+		sqlmock.NewRows([]string{"table_schema", "table_name", "table_comment"}).
+			AddRow("public", "posts", nil).
+			AddRow("public", "users", nil))
+	for range 2 {
+		// Columns, primary key, foreign keys, indexes, constraints, triggers, parents and statistics
+		for range 8 {
+			mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"name"}))
+		}
+	}
+
+	var reported []string
+	inspector := NewInspector(db, "postgres")
+	inspector.SetProgress(func(done, total int, table string) {
+		if total != 2 || done != len(reported)+1 {
+			t.Errorf("unexpected progress %d/%d for %s", done, total, table)
+		}
+		reported = append(reported, table)
+	})
+
+	tables, err := inspector.GetTables(context.Background())
+	if err != nil {
+		t.Fatalf("GetTables() error = %v", err)
+	}
+	if len(tables) != 2 || len(reported) != 2 || reported[1] != "users" {
+		t.Errorf("expected progress for both tables, got %v", reported)
+	}
+}
@@ -0,0 +1,845 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The MySQL backend reads information_schema of the current database, which
+// MySQL calls a schema. It needs MySQL 8.0.16 or MariaDB 10.2 for CHECK
+// constraints. Column types are reported in the PostgreSQL vocabulary of
+// information_schema (integer, character varying, timestamp without time zone,
+// ...) so that the struct generator and the exporters handle both databases
+// alike; UDTName keeps the MySQL type. ENUM columns are USER-DEFINED, with an
+// EnumSchema named <table>_<column>.
+
+func (i *Inspector) getMySQLSchema(ctx context.Context) (*DatabaseSchema, error) {
+	schema := &DatabaseSchema{
+		Tables:    make(map[string]*TableSchema),
+		Views:     make(map[string]*ViewSchema),
+		Enums:     make(map[string]*EnumSchema),
+		Functions: make(map[string]*FunctionSchema),
+		Sequences: make(map[string]*SequenceSchema),
+	}
+
+	dbName, err := i.mySQLSchemaName(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+	schema.Name = dbName
+
+	metadata, err := i.getMySQLMetadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	schema.Metadata = *metadata
+
+	tables, err := i.getMySQLTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	for _, table := range tables {
+		schema.Tables[table.Name] = table
+	}
+
+	schema.Views, err = i.getMySQLViews(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get views: %w", err)
+	}
+
+	schema.Enums, err = i.getMySQLEnums(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enums: %w", err)
+	}
+
+	schema.Functions, err = i.getMySQLFunctions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions: %w", err)
+	}
+
+	return schema, nil
+}
+
+// mySQLSchemaName returns schemaName, or the current database when it is empty
+func (i *Inspector) mySQLSchemaName(ctx context.Context, schemaName string) (string, error) {
+	if schemaName != "" {
+		return schemaName, nil
+	}
+
+	var dbName sql.NullString
+	if err := i.db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&dbName); err != nil {
+		return "", err
+	}
+	if !dbName.Valid {
+		return "", fmt.Errorf("no database selected")
+	}
+	return dbName.String, nil
+}
+
+func (i *Inspector) getMySQLMetadata(ctx context.Context) (*DatabaseMetadata, error) {
+	metadata := &DatabaseMetadata{
+		InspectedAt: time.Now(),
+	}
+
+	err := i.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&metadata.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	query := `
+		SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME
+		FROM information_schema.SCHEMATA
+		WHERE SCHEMA_NAME = DATABASE()
+	`
+	err = i.db.QueryRowContext(ctx, query).Scan(&metadata.Encoding, &metadata.Collation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0), COUNT(*)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_TYPE = 'BASE TABLE'
+	`).Scan(&metadata.Size, &metadata.TableCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, `
+		SELECT COUNT(DISTINCT TABLE_NAME, INDEX_NAME) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+	`).Scan(&metadata.IndexCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index count: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.TABLE_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE()
+	`).Scan(&metadata.ConstraintCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get constraint count: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func (i *Inspector) getMySQLTables(ctx context.Context) ([]*TableSchema, error) {
+	query := `
+		SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_COMMENT
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+
+	type tableRef struct {
+		schema, name string
+		comment      sql.NullString
+	}
+
+	var refs []tableRef
+	for rows.Next() {
+		var ref tableRef
+		if err := rows.Scan(&ref.schema, &ref.name, &ref.comment); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	tables := make([]*TableSchema, 0, len(refs))
+	for n, ref := range refs {
+		table, err := i.getMySQLTable(ctx, ref.schema, ref.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get table %s.%s: %w", ref.schema, ref.name, err)
+		}
+
+		table.Comment = ref.comment.String
+
+		tables = append(tables, table)
+		if i.progress != nil {
+			i.progress(n+1, len(refs), ref.name)
+		}
+	}
+
+	return tables, nil
+}
+
+func (i *Inspector) getMySQLTable(ctx context.Context, schemaName, tableName string) (*TableSchema, error) {
+	schemaName, err := i.mySQLSchemaName(ctx, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+
+	table := &TableSchema{
+		Name:        tableName,
+		Schema:      schemaName,
+		Columns:     make([]*ColumnSchema, 0),
+		ForeignKeys: make([]*ForeignKeySchema, 0),
+		Indexes:     make([]*IndexSchema, 0),
+		Constraints: make([]*ConstraintSchema, 0),
+		Triggers:    make([]*TriggerSchema, 0),
+	}
+
+	columns, err := i.getMySQLColumns(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	table.Columns = columns
+
+	pk, err := i.getMySQLPrimaryKey(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary key: %w", err)
+	}
+	table.PrimaryKey = pk
+
+	fks, err := i.getMySQLForeignKeys(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get foreign keys: %w", err)
+	}
+	table.ForeignKeys = fks
+
+	indexes, err := i.getMySQLIndexes(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexes: %w", err)
+	}
+	table.Indexes = indexes
+
+	constraints, err := i.getMySQLConstraints(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get constraints: %w", err)
+	}
+	table.Constraints = constraints
+
+	triggers, err := i.getMySQLTriggers(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get triggers: %w", err)
+	}
+	table.Triggers = triggers
+
+	stats, err := i.getMySQLTableStatistics(ctx, schemaName, tableName)
+	if err == nil {
+		table.RowCount = stats.RowCount
+		table.SizeBytes = stats.TotalSizeBytes
+	}
+
+	return table, nil
+}
+
+func (i *Inspector) getMySQLColumns(ctx context.Context, schemaName, tableName string) ([]*ColumnSchema, error) {
+	query := `
+		SELECT
+			COLUMN_NAME,
+			ORDINAL_POSITION,
+			DATA_TYPE,
+			COLUMN_TYPE,
+			IS_NULLABLE = 'YES',
+			COLUMN_DEFAULT,
+			CHARACTER_MAXIMUM_LENGTH,
+			NUMERIC_PRECISION,
+			NUMERIC_SCALE,
+			EXTRA,
+			GENERATION_EXPRESSION,
+			COLUMN_COMMENT
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []*ColumnSchema
+	for rows.Next() {
+		col := &ColumnSchema{}
+		var dataType, columnType, extra string
+		var defaultValue, generationExpr, comment sql.NullString
+		var charMaxLength, numericPrecision, numericScale sql.NullInt64
+
+		err := rows.Scan(
+			&col.Name,
+			&col.OrdinalPosition,
+			&dataType,
+			&columnType,
+			&col.IsNullable,
+			&defaultValue,
+			&charMaxLength,
+			&numericPrecision,
+			&numericScale,
+			&extra,
+			&generationExpr,
+			&comment,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		col.DataType = mysqlDataType(dataType, columnType)
+		col.UDTName = strings.ToLower(dataType)
+		if col.DataType == "USER-DEFINED" {
+			col.UDTName = mysqlEnumName(tableName, col.Name)
+		}
+
+		extra = strings.ToLower(extra)
+		col.IsIdentity = strings.Contains(extra, "auto_increment")
+		col.IsGenerated = strings.Contains(extra, "virtual generated") || strings.Contains(extra, "stored generated")
+
+		if defaultValue.Valid {
+			col.DefaultValue = &defaultValue.String
+		}
+		if charMaxLength.Valid {
+			val := int(charMaxLength.Int64)
+			col.CharMaxLength = &val
+		}
+		if numericPrecision.Valid {
+			val := int(numericPrecision.Int64)
+			col.NumericPrecision = &val
+		}
+		if numericScale.Valid {
+			val := int(numericScale.Int64)
+			col.NumericScale = &val
+		}
+		if col.DataType == "numeric" && strings.HasPrefix(strings.ToLower(columnType), "bigint") {
+			precision, scale := 20, 0
+			col.NumericPrecision, col.NumericScale = &precision, &scale
+		}
+		if generationExpr.Valid && generationExpr.String != "" {
+			col.GenerationExpr = &generationExpr.String
+		}
+		col.Comment = comment.String
+
+		columns = append(columns, col)
+	}
+
+	return columns, rows.Err()
+}
+
+// mysqlDataType maps a MySQL DATA_TYPE and COLUMN_TYPE to the name the
+// PostgreSQL information_schema uses for the type holding the same values.
+// Unsigned integers get the next larger type, and tinyint(1) and bit(1),
+// which MySQL uses for booleans, become boolean.
+func mysqlDataType(dataType, columnType string) string {
+	dataType = strings.ToLower(dataType)
+	columnType = strings.ToLower(columnType)
+	unsigned := strings.Contains(columnType, "unsigned")
+
+	switch dataType {
+	case "tinyint":
+		if strings.HasPrefix(columnType, "tinyint(1)") && !unsigned {
+			return "boolean"
+		}
+		return "smallint"
+	case "smallint":
+		if unsigned {
+			return "integer"
+		}
+		return "smallint"
+	case "mediumint":
+		return "integer"
+	case "int", "integer":
+		if unsigned {
+			return "bigint"
+		}
+		return "integer"
+	case "bigint":
+		if unsigned {
+			return "numeric"
+		}
+		return "bigint"
+	case "decimal", "numeric":
+		return "numeric"
+	case "float":
+		return "real"
+	case "double", "real":
+		return "double precision"
+	case "bit":
+		if columnType == "bit(1)" {
+			return "boolean"
+		}
+		return "bytea"
+	case "year":
+		return "smallint"
+	case "char":
+		return "character"
+	case "varchar":
+		return "character varying"
+	case "tinytext", "text", "mediumtext", "longtext", "set":
+		return "text"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		return "bytea"
+	case "date":
+		return "date"
+	case "datetime":
+		return "timestamp without time zone"
+	case "timestamp":
+		// TIMESTAMP values are stored in UTC and converted to the session time zone
+		return "timestamp with time zone"
+	case "time":
+		return "time without time zone"
+	case "json":
+		return "json"
+	case "enum":
+		return "USER-DEFINED"
+	default:
+		return dataType
+	}
+}
+
+// mysqlEnumName names the enum type of an ENUM column
+func mysqlEnumName(tableName, columnName string) string {
+	return tableName + "_" + columnName
+}
+
+// parseMySQLEnumValues returns the values of a COLUMN_TYPE such as
+// enum('draft','published'), in which quotes inside a value are doubled
+func parseMySQLEnumValues(columnType string) []string {
+	start := strings.Index(columnType, "(")
+	end := strings.LastIndex(columnType, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+
+	var values []string
+	list := columnType[start+1 : end]
+	for pos := 0; pos < len(list); pos++ {
+		if list[pos] != '\'' {
+			continue
+		}
+
+		var value strings.Builder
+		for pos++; pos < len(list); pos++ {
+			if list[pos] == '\'' {
+				if pos+1 < len(list) && list[pos+1] == '\'' {
+					value.WriteByte('\'')
+					pos++
+					continue
+				}
+				break
+			}
+			value.WriteByte(list[pos])
+		}
+		values = append(values, value.String())
+	}
+
+	return values
+}
+
+func (i *Inspector) getMySQLPrimaryKey(ctx context.Context, schemaName, tableName string) (*PrimaryKeySchema, error) {
+	query := `
+		SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query primary key: %w", err)
+	}
+	defer rows.Close()
+
+	pk := &PrimaryKeySchema{Name: "PRIMARY"}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key: %w", err)
+		}
+		pk.Columns = append(pk.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(pk.Columns) == 0 {
+		return nil, nil
+	}
+	return pk, nil
+}
+
+func (i *Inspector) getMySQLForeignKeys(ctx context.Context, schemaName, tableName string) ([]*ForeignKeySchema, error) {
+	query := `
+		SELECT
+			kcu.CONSTRAINT_NAME,
+			kcu.COLUMN_NAME,
+			kcu.REFERENCED_TABLE_SCHEMA,
+			kcu.REFERENCED_TABLE_NAME,
+			kcu.REFERENCED_COLUMN_NAME,
+			rc.DELETE_RULE,
+			rc.UPDATE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE kcu
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS rc
+			ON rc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA
+			AND rc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+			AND rc.TABLE_NAME = kcu.TABLE_NAME
+		WHERE kcu.TABLE_SCHEMA = ? AND kcu.TABLE_NAME = ?
+		ORDER BY kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []*ForeignKeySchema
+	for rows.Next() {
+		var name, column, refSchema, refTable, refColumn, onDelete, onUpdate string
+		err := rows.Scan(&name, &column, &refSchema, &refTable, &refColumn, &onDelete, &onUpdate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		// One row per column; the rows of a key are adjacent
+		if n := len(foreignKeys); n == 0 || foreignKeys[n-1].Name != name {
+			foreignKeys = append(foreignKeys, &ForeignKeySchema{
+				Name:             name,
+				ReferencedSchema: refSchema,
+				ReferencedTable:  refTable,
+				OnDelete:         onDelete,
+				OnUpdate:         onUpdate,
+			})
+		}
+		fk := foreignKeys[len(foreignKeys)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, refColumn)
+	}
+
+	return foreignKeys, rows.Err()
+}
+
+func (i *Inspector) getMySQLIndexes(ctx context.Context, schemaName, tableName string) ([]*IndexSchema, error) {
+	query := `
+		SELECT
+			INDEX_NAME,
+			NON_UNIQUE = 0,
+			COLUMN_NAME,
+			COLLATION,
+			SUB_PART,
+			INDEX_TYPE
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		AND INDEX_NAME <> 'PRIMARY'
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []*IndexSchema
+	for rows.Next() {
+		var name, indexType string
+		var isUnique bool
+		var column, collation sql.NullString
+		var subPart sql.NullInt64
+
+		err := rows.Scan(&name, &isUnique, &column, &collation, &subPart, &indexType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		// One row per column; the rows of an index are adjacent
+		if n := len(indexes); n == 0 || indexes[n-1].Name != name {
+			indexes = append(indexes, &IndexSchema{
+				Name:     name,
+				IsUnique: isUnique,
+				Type:     strings.ToLower(indexType),
+				Columns:  make([]IndexColumn, 0),
+			})
+		}
+		idx := indexes[len(indexes)-1]
+
+		// COLUMN_NAME is NULL for the parts of functional indexes
+		col := IndexColumn{Name: column.String, Expression: column.String}
+		if subPart.Valid {
+			col.Expression = fmt.Sprintf("%s(%d)", column.String, subPart.Int64)
+		}
+		if collation.String == "D" {
+			col.Order = "DESC"
+		}
+		idx.Columns = append(idx.Columns, col)
+	}
+
+	return indexes, rows.Err()
+}
+
+func (i *Inspector) getMySQLConstraints(ctx context.Context, schemaName, tableName string) ([]*ConstraintSchema, error) {
+	query := `
+		SELECT
+			tc.CONSTRAINT_NAME,
+			tc.CONSTRAINT_TYPE,
+			kcu.COLUMN_NAME,
+			cc.CHECK_CLAUSE
+		FROM information_schema.TABLE_CONSTRAINTS tc
+		LEFT JOIN information_schema.KEY_COLUMN_USAGE kcu
+			ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+			AND kcu.TABLE_NAME = tc.TABLE_NAME
+		LEFT JOIN information_schema.CHECK_CONSTRAINTS cc
+			ON cc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+			AND cc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ?
+		AND tc.CONSTRAINT_TYPE IN ('CHECK', 'UNIQUE')
+		ORDER BY tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query constraints: %w", err)
+	}
+	defer rows.Close()
+
+	var constraints []*ConstraintSchema
+	for rows.Next() {
+		var name, constraintType string
+		var column, checkClause sql.NullString
+
+		if err := rows.Scan(&name, &constraintType, &column, &checkClause); err != nil {
+			return nil, fmt.Errorf("failed to scan constraint: %w", err)
+		}
+
+		if n := len(constraints); n == 0 || constraints[n-1].Name != name {
+			constraints = append(constraints, &ConstraintSchema{
+				Name:    name,
+				Type:    constraintType,
+				Columns: make([]string, 0),
+			})
+		}
+		c := constraints[len(constraints)-1]
+		if column.Valid {
+			c.Columns = append(c.Columns, column.String)
+		}
+		if checkClause.Valid {
+			c.Definition = fmt.Sprintf("CHECK (%s)", checkClause.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, c := range constraints {
+		if c.Type == "UNIQUE" {
+			c.Definition = fmt.Sprintf("UNIQUE (%s)", strings.Join(c.Columns, ", "))
+		}
+	}
+
+	return constraints, nil
+}
+
+func (i *Inspector) getMySQLTriggers(ctx context.Context, schemaName, tableName string) ([]*TriggerSchema, error) {
+	query := `
+		SELECT
+			TRIGGER_NAME,
+			ACTION_TIMING,
+			EVENT_MANIPULATION,
+			ACTION_ORIENTATION,
+			ACTION_STATEMENT
+		FROM information_schema.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ?
+		ORDER BY TRIGGER_NAME
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var triggers []*TriggerSchema
+	for rows.Next() {
+		tr := &TriggerSchema{IsEnabled: true}
+		var event, statement string
+
+		if err := rows.Scan(&tr.Name, &tr.Timing, &event, &tr.Level, &statement); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger: %w", err)
+		}
+
+		// MySQL triggers have their body inline instead of calling a function
+		tr.Events = []string{event}
+		tr.Definition = fmt.Sprintf("CREATE TRIGGER %s %s %s ON %s FOR EACH %s %s",
+			tr.Name, tr.Timing, event, tableName, tr.Level, statement)
+		triggers = append(triggers, tr)
+	}
+
+	return triggers, rows.Err()
+}
+
+// getMySQLTableStatistics reads the estimates of information_schema.TABLES;
+// MySQL keeps no vacuum or analyze history
+func (i *Inspector) getMySQLTableStatistics(ctx context.Context, schemaName, tableName string) (*TableStatistics, error) {
+	schemaName, err := i.mySQLSchemaName(ctx, schemaName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+
+	query := `
+		SELECT
+			COALESCE(TABLE_ROWS, 0),
+			COALESCE(DATA_LENGTH, 0),
+			COALESCE(INDEX_LENGTH, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`
+
+	stats := &TableStatistics{
+		TableName: tableName,
+	}
+
+	err = i.db.QueryRowContext(ctx, query, schemaName, tableName).Scan(
+		&stats.LiveTuples,
+		&stats.DataSizeBytes,
+		&stats.IndexSizeBytes,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table statistics: %w", err)
+	}
+
+	stats.RowCount = stats.LiveTuples
+	stats.TotalSizeBytes = stats.DataSizeBytes + stats.IndexSizeBytes
+
+	return stats, nil
+}
+
+func (i *Inspector) getMySQLViews(ctx context.Context) (map[string]*ViewSchema, error) {
+	query := `
+		SELECT TABLE_SCHEMA, TABLE_NAME, VIEW_DEFINITION
+		FROM information_schema.VIEWS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []*ViewSchema
+	for rows.Next() {
+		view := &ViewSchema{}
+		if err := rows.Scan(&view.Schema, &view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	result := make(map[string]*ViewSchema, len(views))
+	for _, view := range views {
+		view.Columns, err = i.getMySQLColumns(ctx, view.Schema, view.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns for view %s.%s: %w", view.Schema, view.Name, err)
+		}
+		result[fmt.Sprintf("%s.%s", view.Schema, view.Name)] = view
+	}
+
+	return result, nil
+}
+
+func (i *Inspector) getMySQLEnums(ctx context.Context) (map[string]*EnumSchema, error) {
+	query := `
+		SELECT c.TABLE_SCHEMA, c.TABLE_NAME, c.COLUMN_NAME, c.COLUMN_TYPE
+		FROM information_schema.COLUMNS c
+		JOIN information_schema.TABLES t
+			ON t.TABLE_SCHEMA = c.TABLE_SCHEMA
+			AND t.TABLE_NAME = c.TABLE_NAME
+		WHERE c.TABLE_SCHEMA = DATABASE()
+		AND t.TABLE_TYPE = 'BASE TABLE'
+		AND c.DATA_TYPE = 'enum'
+		ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enums: %w", err)
+	}
+	defer rows.Close()
+
+	enums := make(map[string]*EnumSchema)
+	for rows.Next() {
+		var schemaName, tableName, columnName, columnType string
+		if err := rows.Scan(&schemaName, &tableName, &columnName, &columnType); err != nil {
+			return nil, fmt.Errorf("failed to scan enum: %w", err)
+		}
+
+		enum := &EnumSchema{
+			Name:   mysqlEnumName(tableName, columnName),
+			Schema: schemaName,
+			Values: parseMySQLEnumValues(columnType),
+		}
+		enums[fmt.Sprintf("%s.%s", enum.Schema, enum.Name)] = enum
+	}
+
+	return enums, rows.Err()
+}
+
+func (i *Inspector) getMySQLFunctions(ctx context.Context) (map[string]*FunctionSchema, error) {
+	query := `
+		SELECT
+			ROUTINE_SCHEMA,
+			ROUTINE_NAME,
+			DTD_IDENTIFIER,
+			ROUTINE_BODY,
+			ROUTINE_DEFINITION,
+			IS_DETERMINISTIC = 'NO'
+		FROM information_schema.ROUTINES
+		WHERE ROUTINE_SCHEMA = DATABASE()
+		ORDER BY ROUTINE_NAME
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query functions: %w", err)
+	}
+	defer rows.Close()
+
+	functions := make(map[string]*FunctionSchema)
+	for rows.Next() {
+		fn := &FunctionSchema{
+			Arguments: make([]FunctionArgument, 0),
+		}
+		// DTD_IDENTIFIER is NULL for procedures, ROUTINE_DEFINITION without privileges
+		var returnType, definition sql.NullString
+
+		err := rows.Scan(
+			&fn.Schema,
+			&fn.Name,
+			&returnType,
+			&fn.Language,
+			&definition,
+			&fn.IsVolatile,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan function: %w", err)
+		}
+
+		fn.ReturnType = returnType.String
+		fn.Definition = definition.String
+		functions[fmt.Sprintf("%s.%s", fn.Schema, fn.Name)] = fn
+	}
+
+	return functions, rows.Err()
+}
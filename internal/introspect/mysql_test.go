@@ -0,0 +1,119 @@
+package introspect
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestMySQLDataType(t *testing.T) {
+	tests := []struct {
+		dataType   string
+		columnType string
+		want       string
+	}{
+		{"tinyint", "tinyint(1)", "boolean"},
+		{"tinyint", "tinyint(4)", "smallint"},
+		{"int", "int(11)", "integer"},
+		{"int", "int unsigned", "bigint"},
+		{"bigint", "bigint unsigned", "numeric"},
+		{"varchar", "varchar(255)", "character varying"},
+		{"longtext", "longtext", "text"},
+		{"datetime", "datetime(6)", "timestamp without time zone"},
+		{"timestamp", "timestamp", "timestamp with time zone"},
+		{"blob", "blob", "bytea"},
+		{"decimal", "decimal(10,2)", "numeric"},
+		{"double", "double", "double precision"},
+		{"json", "json", "json"},
+		{"enum", "enum('draft','published')", "USER-DEFINED"},
+		{"geometry", "geometry", "geometry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			if got := mysqlDataType(tt.dataType, tt.columnType); got != tt.want {
+				t.Errorf("mysqlDataType(%q, %q) = %q, want %q", tt.dataType, tt.columnType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMySQLEnumValues(t *testing.T) {
+	got := parseMySQLEnumValues("enum('draft','it''s live','a,b')")
+	want := []string{"draft", "it's live", "a,b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseMySQLEnumValues() = %q, want %q", got, want)
+	}
+}
+
+func TestInspector_MySQLTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT DATABASE\(\)`).WillReturnRows(sqlmock.NewRows([]string{"db"}).AddRow("shop"))
+	mock.ExpectQuery("FROM information_schema.COLUMNS").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"name", "pos", "data_type", "column_type", "nullable", "default", "len", "precision", "scale", "extra", "generation", "comment"}).
+			AddRow("id", 1, "bigint", "bigint unsigned", false, nil, nil, 20, 0, "auto_increment", "", "").
+			AddRow("status", 2, "enum", "enum('open','paid')", false, "open", 4, nil, nil, "", "", "").
+			AddRow("customer_id", 3, "int", "int", false, nil, nil, 10, 0, "", "", "").
+			AddRow("created_at", 4, "datetime", "datetime", true, "CURRENT_TIMESTAMP", nil, nil, nil, "DEFAULT_GENERATED", "", ""))
+	mock.ExpectQuery("FROM information_schema.KEY_COLUMN_USAGE").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"column"}).AddRow("id"))
+	mock.ExpectQuery("FROM information_schema.KEY_COLUMN_USAGE kcu").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"name", "column", "ref_schema", "ref_table", "ref_column", "on_delete", "on_update"}).
+			AddRow("fk_orders_customer", "customer_id", "shop", "customers", "id", "CASCADE", "NO ACTION"))
+	mock.ExpectQuery("FROM information_schema.STATISTICS").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"name", "unique", "column", "collation", "sub_part", "type"}).
+			AddRow("idx_orders_customer_created", false, "customer_id", "A", nil, "BTREE").
+			AddRow("idx_orders_customer_created", false, "created_at", "D", nil, "BTREE"))
+	mock.ExpectQuery("FROM information_schema.TABLE_CONSTRAINTS").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"name", "type", "column", "check_clause"}).
+			AddRow("chk_orders_status", "CHECK", nil, "`status` <> ''"))
+	mock.ExpectQuery("FROM information_schema.TRIGGERS").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"name", "timing", "event", "level", "statement"}))
+	mock.ExpectQuery("FROM information_schema.TABLES").WithArgs("shop", "orders").WillReturnRows(
+		sqlmock.NewRows([]string{"rows", "data", "index"}).AddRow(42, 16384, 8192))
+
+	table, err := NewInspector(db, "mysql").GetTable(context.Background(), "", "orders")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+
+	if table.Schema != "shop" {
+		t.Errorf("expected the current database as schema, got %q", table.Schema)
+	}
+	id, status := table.Columns[0], table.Columns[1]
+	if id.DataType != "numeric" || !id.IsIdentity || *id.NumericPrecision != 20 {
+		t.Errorf("unexpected id column %+v", id)
+	}
+	if status.DataType != "USER-DEFINED" || status.UDTName != "orders_status" {
+		t.Errorf("expected status to use the orders_status enum, got %+v", status)
+	}
+	if table.Columns[3].IsGenerated {
+		t.Error("a column with a default expression is not a generated column")
+	}
+	if table.PrimaryKey == nil || !reflect.DeepEqual(table.PrimaryKey.Columns, []string{"id"}) {
+		t.Errorf("unexpected primary key %+v", table.PrimaryKey)
+	}
+	if len(table.ForeignKeys) != 1 || table.ForeignKeys[0].ReferencedTable != "customers" || table.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Errorf("unexpected foreign keys %+v", table.ForeignKeys)
+	}
+	if len(table.Indexes) != 1 || len(table.Indexes[0].Columns) != 2 || table.Indexes[0].Columns[1].Order != "DESC" || table.Indexes[0].Type != "btree" {
+		t.Errorf("expected one two-column index, got %+v", table.Indexes)
+	}
+	if len(table.Constraints) != 1 || table.Constraints[0].Definition != "CHECK (`status` <> '')" {
+		t.Errorf("unexpected constraints %+v", table.Constraints)
+	}
+	if table.RowCount != 42 || table.SizeBytes != 24576 {
+		t.Errorf("unexpected statistics: %d rows, %d bytes", table.RowCount, table.SizeBytes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
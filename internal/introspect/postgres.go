@@ -136,28 +136,42 @@ func (i *Inspector) getPostgreSQLTables(ctx context.Context) ([]*TableSchema, er
 	}
 	defer rows.Close()
 
-	var tables []*TableSchema
-	for rows.Next() {
-		var schema, name string
-		var comment sql.NullString
+	type tableRef struct {
+		schema, name string
+		comment      sql.NullString
+	}
 
-		if err := rows.Scan(&schema, &name, &comment); err != nil {
+	var refs []tableRef
+	for rows.Next() {
+		var ref tableRef
+		if err := rows.Scan(&ref.schema, &ref.name, &ref.comment); err != nil {
 			return nil, fmt.Errorf("failed to scan table: %w", err)
 		}
+		refs = append(refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-		table, err := i.getPostgreSQLTable(ctx, schema, name)
+	tables := make([]*TableSchema, 0, len(refs))
+	for n, ref := range refs {
+		table, err := i.getPostgreSQLTable(ctx, ref.schema, ref.name)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get table %s.%s: %w", schema, name, err)
+			return nil, fmt.Errorf("failed to get table %s.%s: %w", ref.schema, ref.name, err)
 		}
 
-		if comment.Valid {
-			table.Comment = comment.String
+		if ref.comment.Valid {
+			table.Comment = ref.comment.String
 		}
 
 		tables = append(tables, table)
+		if i.progress != nil {
+			i.progress(n+1, len(refs), ref.name)
+		}
 	}
 
-	return tables, rows.Err()
+	return tables, nil
 }
 
 func (i *Inspector) getPostgreSQLTable(ctx context.Context, schemaName, tableName string) (*TableSchema, error) {
@@ -207,6 +221,12 @@ func (i *Inspector) getPostgreSQLTable(ctx context.Context, schemaName, tableNam
 	}
 	table.Triggers = triggers
 
+	parents, err := i.getPostgreSQLParents(ctx, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent tables: %w", err)
+	}
+	table.Inherits = parents
+
 	stats, err := i.getPostgreSQLTableStatistics(ctx, schemaName, tableName)
 	if err == nil {
 		table.RowCount = stats.RowCount
@@ -231,10 +251,15 @@ func (i *Inspector) getPostgreSQLColumns(ctx context.Context, schemaName, tableN
 			c.is_identity = 'YES' as is_identity,
 			c.is_generated = 'ALWAYS' as is_generated,
 			c.generation_expression,
-			col_description(pgc.oid, c.ordinal_position) as column_comment
+			col_description(pgc.oid, c.ordinal_position) as column_comment,
+			CASE WHEN a.attstorage <> t.typstorage THEN a.attstorage::text END as storage,
+			-- attcompression is new in PostgreSQL 14, read through to_jsonb for older servers
+			NULLIF(to_jsonb(a) ->> 'attcompression', '') as compression
 		FROM information_schema.columns c
 		JOIN pg_class pgc ON pgc.relname = c.table_name
 		JOIN pg_namespace n ON n.oid = pgc.relnamespace AND n.nspname = c.table_schema
+		JOIN pg_attribute a ON a.attrelid = pgc.oid AND a.attname = c.column_name
+		JOIN pg_type t ON t.oid = a.atttypid
 		WHERE c.table_schema = $1 AND c.table_name = $2
 		ORDER BY c.ordinal_position
 	`
@@ -248,7 +273,7 @@ func (i *Inspector) getPostgreSQLColumns(ctx context.Context, schemaName, tableN
 	var columns []*ColumnSchema
 	for rows.Next() {
 		col := &ColumnSchema{}
-		var defaultValue, generationExpr, comment sql.NullString
+		var defaultValue, generationExpr, comment, storage, compression sql.NullString
 		var charMaxLength, numericPrecision, numericScale sql.NullInt64
 
 		err := rows.Scan(
@@ -265,6 +290,8 @@ func (i *Inspector) getPostgreSQLColumns(ctx context.Context, schemaName, tableN
 			&col.IsGenerated,
 			&generationExpr,
 			&comment,
+			&storage,
+			&compression,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan column: %w", err)
@@ -291,6 +318,8 @@ func (i *Inspector) getPostgreSQLColumns(ctx context.Context, schemaName, tableN
 		if comment.Valid {
 			col.Comment = comment.String
 		}
+		col.Storage = StorageMode(storage.String)
+		col.Compression = CompressionMethod(compression.String)
 
 		columns = append(columns, col)
 	}
@@ -298,6 +327,24 @@ func (i *Inspector) getPostgreSQLColumns(ctx context.Context, schemaName, tableN
 	return columns, rows.Err()
 }
 
+// storageModes names the attstorage codes of pg_attribute
+var storageModes = map[string]string{"p": "plain", "m": "main", "e": "external", "x": "extended"}
+
+// compressionMethods names the attcompression codes of pg_attribute
+var compressionMethods = map[string]string{"p": "pglz", "l": "lz4"}
+
+// StorageMode names an attstorage code of pg_attribute or pg_type, empty for an
+// unknown code
+func StorageMode(code string) string {
+	return storageModes[code]
+}
+
+// CompressionMethod names an attcompression code of pg_attribute, empty for the
+// server's default
+func CompressionMethod(code string) string {
+	return compressionMethods[code]
+}
+
 func (i *Inspector) getPostgreSQLPrimaryKey(ctx context.Context, schemaName, tableName string) (*PrimaryKeySchema, error) {
 	query := `
 		SELECT 
@@ -394,6 +441,8 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 			i.relname as index_name,
 			idx.indisunique as is_unique,
 			idx.indisprimary as is_primary,
+			-- indnullsnotdistinct is new in PostgreSQL 15, read through to_jsonb for older servers
+			COALESCE((to_jsonb(idx) ->> 'indnullsnotdistinct')::boolean, false) as nulls_not_distinct,
 			idx.indpred IS NOT NULL as is_partial,
 			pg_get_expr(idx.indpred, idx.indrelid) as where_clause,
 			am.amname as index_type,
@@ -402,13 +451,30 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 				FROM generate_subscripts(idx.indkey, 1) as k
 				ORDER BY k
 			) as columns,
-			ts.spcname as tablespace
+			ts.spcname as tablespace,
+			own.conname as constraint_name,
+			ARRAY(
+				SELECT fk.conname
+				FROM pg_constraint fk
+				WHERE fk.contype = 'f' AND fk.conindid = idx.indexrelid
+				ORDER BY fk.conname
+			) as referenced_by,
+			EXISTS (
+				SELECT 1
+				FROM pg_constraint fk
+				WHERE fk.contype = 'f'
+				AND fk.conrelid = idx.indrelid
+				AND (idx.indkey::int2[])[0:cardinality(fk.conkey) - 1] = fk.conkey
+			) as fk_helper
 		FROM pg_index idx
 		JOIN pg_class i ON i.oid = idx.indexrelid
 		JOIN pg_class t ON t.oid = idx.indrelid
 		JOIN pg_namespace n ON n.oid = t.relnamespace
 		JOIN pg_am am ON am.oid = i.relam
 		LEFT JOIN pg_tablespace ts ON ts.oid = i.reltablespace
+		LEFT JOIN pg_constraint own ON own.conindid = idx.indexrelid
+			AND own.conrelid = idx.indrelid
+			AND own.contype IN ('p', 'u', 'x')
 		WHERE n.nspname = $1
 		AND t.relname = $2
 		AND NOT idx.indisprimary
@@ -428,21 +494,28 @@ func (i *Inspector) getPostgreSQLIndexes(ctx context.Context, schemaName, tableN
 		}
 		var whereClause sql.NullString
 		var tablespace sql.NullString
-		var columnExprs pq.StringArray
+		var constraint sql.NullString
+		var columnExprs, referencedBy pq.StringArray
 
 		err := rows.Scan(
 			&idx.Name,
 			&idx.IsUnique,
 			&idx.IsPrimary,
+			&idx.NullsNotDistinct,
 			&idx.IsPartial,
 			&whereClause,
 			&idx.Type,
 			&columnExprs,
 			&tablespace,
+			&constraint,
+			&referencedBy,
+			&idx.ForeignKeyHelper,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan index: %w", err)
 		}
+		idx.Constraint = constraint.String
+		idx.ReferencedBy = []string(referencedBy)
 
 		if whereClause.Valid {
 			idx.Where = whereClause.String
@@ -573,6 +646,43 @@ func (i *Inspector) getPostgreSQLTriggers(ctx context.Context, schemaName, table
 	return triggers, rows.Err()
 }
 
+// getPostgreSQLParents returns the tables a table inherits from, leaving out the parent
+// of a declarative partition
+func (i *Inspector) getPostgreSQLParents(ctx context.Context, schemaName, tableName string) ([]string, error) {
+	query := `
+		SELECT pn.nspname, p.relname
+		FROM pg_inherits inh
+		JOIN pg_class c ON c.oid = inh.inhrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_class p ON p.oid = inh.inhparent
+		JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		WHERE n.nspname = $1
+		AND c.relname = $2
+		AND NOT c.relispartition
+		ORDER BY inh.inhseqno
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, schemaName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query parent tables: %w", err)
+	}
+	defer rows.Close()
+
+	var parents []string
+	for rows.Next() {
+		var parentSchema, parent string
+		if err := rows.Scan(&parentSchema, &parent); err != nil {
+			return nil, fmt.Errorf("failed to scan parent table: %w", err)
+		}
+		if parentSchema != schemaName {
+			parent = parentSchema + "." + parent
+		}
+		parents = append(parents, parent)
+	}
+
+	return parents, rows.Err()
+}
+
 func (i *Inspector) getPostgreSQLTableStatistics(ctx context.Context, schemaName, tableName string) (*TableStatistics, error) {
 	query := `
 		SELECT 
@@ -771,12 +881,16 @@ func (i *Inspector) getPostgreSQLSequences(ctx context.Context) (map[string]*Seq
 			s.seqmax as max_value,
 			s.seqincrement as increment,
 			s.seqcycle as cycle_option,
-			pg_get_serial_sequence(dc.table_schema||'.'||dc.table_name, dc.column_name) as owned_by
+			t.relname||'.'||a.attname as owned_by
 		FROM pg_sequence s
 		JOIN pg_class c ON c.oid = s.seqrelid
 		JOIN pg_namespace n ON n.oid = c.relnamespace
-		LEFT JOIN information_schema.columns dc 
-			ON pg_get_serial_sequence(dc.table_schema||'.'||dc.table_name, dc.column_name) = n.nspname||'.'||c.relname
+		-- OWNED BY, set for serial columns too, is an auto dependency on the column
+		LEFT JOIN pg_depend d
+			ON d.classid = 'pg_class'::regclass AND d.objid = c.oid
+			AND d.refclassid = 'pg_class'::regclass AND d.refobjsubid > 0 AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
 		WHERE n.nspname NOT IN ('pg_catalog', 'information_schema')
 		ORDER BY n.nspname, c.relname
 	`
@@ -0,0 +1,519 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The SQLite backend reads sqlite_master and the table-valued PRAGMA functions
+// (pragma_table_xinfo, pragma_foreign_key_list, pragma_index_list, ...), which
+// need SQLite 3.26. Like the MySQL backend it reports column types in the
+// PostgreSQL vocabulary of information_schema, keeping the declared type in
+// UDTName. SQLite keeps no names for unique and foreign key constraints, so
+// they are named the way PostgreSQL names them: <table>_<columns>_key and
+// <table>_<columns>_fkey. CHECK constraints are not reported, and SQLite has no
+// enums, functions or sequences.
+
+// sqliteSchemaName is the name SQLite gives the main database of a connection
+const sqliteSchemaName = "main"
+
+func (i *Inspector) getSQLiteSchema(ctx context.Context) (*DatabaseSchema, error) {
+	schema := &DatabaseSchema{
+		Name:      sqliteSchemaName,
+		Tables:    make(map[string]*TableSchema),
+		Views:     make(map[string]*ViewSchema),
+		Enums:     make(map[string]*EnumSchema),
+		Functions: make(map[string]*FunctionSchema),
+		Sequences: make(map[string]*SequenceSchema),
+	}
+
+	metadata, err := i.getSQLiteMetadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	schema.Metadata = *metadata
+
+	tables, err := i.getSQLiteTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tables: %w", err)
+	}
+	for _, table := range tables {
+		schema.Tables[table.Name] = table
+	}
+
+	schema.Views, err = i.getSQLiteViews(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get views: %w", err)
+	}
+
+	return schema, nil
+}
+
+func (i *Inspector) getSQLiteMetadata(ctx context.Context) (*DatabaseMetadata, error) {
+	metadata := &DatabaseMetadata{
+		InspectedAt: time.Now(),
+		Collation:   "BINARY",
+	}
+
+	err := i.db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&metadata.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, "PRAGMA encoding").Scan(&metadata.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, `
+		SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()
+	`).Scan(&metadata.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database size: %w", err)
+	}
+
+	err = i.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(type = 'table'), 0),
+			COALESCE(SUM(type = 'index'), 0)
+		FROM sqlite_master
+		WHERE name NOT LIKE 'sqlite_%'
+	`).Scan(&metadata.TableCount, &metadata.IndexCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tables: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func (i *Inspector) getSQLiteTables(ctx context.Context) ([]*TableSchema, error) {
+	query := `
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table'
+		AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	tables := make([]*TableSchema, 0, len(names))
+	for n, name := range names {
+		table, err := i.getSQLiteTable(ctx, sqliteSchemaName, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get table %s: %w", name, err)
+		}
+
+		tables = append(tables, table)
+		if i.progress != nil {
+			i.progress(n+1, len(names), name)
+		}
+	}
+
+	return tables, nil
+}
+
+func (i *Inspector) getSQLiteTable(ctx context.Context, schemaName, tableName string) (*TableSchema, error) {
+	if schemaName == "" || schemaName == "public" {
+		schemaName = sqliteSchemaName
+	}
+
+	var createSQL sql.NullString
+	err := i.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", tableName).Scan(&createSQL)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table %s does not exist", tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table: %w", err)
+	}
+
+	table := &TableSchema{
+		Name:        tableName,
+		Schema:      schemaName,
+		Columns:     make([]*ColumnSchema, 0),
+		ForeignKeys: make([]*ForeignKeySchema, 0),
+		Indexes:     make([]*IndexSchema, 0),
+		Constraints: make([]*ConstraintSchema, 0),
+		Triggers:    make([]*TriggerSchema, 0),
+	}
+
+	columns, pk, err := i.getSQLiteColumns(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	table.Columns = columns
+	table.PrimaryKey = pk
+
+	// An INTEGER PRIMARY KEY is the rowid, which SQLite assigns on insert
+	if pk != nil && len(pk.Columns) == 1 {
+		for _, col := range columns {
+			if col.Name == pk.Columns[0] && strings.EqualFold(col.UDTName, "integer") {
+				col.IsIdentity = true
+			}
+		}
+	}
+
+	fks, err := i.getSQLiteForeignKeys(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get foreign keys: %w", err)
+	}
+	table.ForeignKeys = fks
+
+	indexes, err := i.getSQLiteIndexes(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get indexes: %w", err)
+	}
+	table.Indexes = indexes
+
+	for _, idx := range indexes {
+		if !idx.IsUnique || idx.IsPrimary || !strings.HasPrefix(idx.Name, "sqlite_autoindex_") {
+			continue
+		}
+		columns := make([]string, len(idx.Columns))
+		for n, col := range idx.Columns {
+			columns[n] = col.Name
+		}
+		table.Constraints = append(table.Constraints, &ConstraintSchema{
+			Name:       fmt.Sprintf("%s_%s_key", tableName, strings.Join(columns, "_")),
+			Type:       "UNIQUE",
+			Definition: fmt.Sprintf("UNIQUE (%s)", strings.Join(columns, ", ")),
+			Columns:    columns,
+		})
+	}
+
+	triggers, err := i.getSQLiteTriggers(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get triggers: %w", err)
+	}
+	table.Triggers = triggers
+
+	stats, err := i.getSQLiteTableStatistics(ctx, schemaName, tableName)
+	if err == nil {
+		table.RowCount = stats.RowCount
+	}
+
+	return table, nil
+}
+
+// getSQLiteColumns returns the columns of tableName and its primary key, which
+// pragma_table_xinfo reports per column
+func (i *Inspector) getSQLiteColumns(ctx context.Context, tableName string) ([]*ColumnSchema, *PrimaryKeySchema, error) {
+	query := `
+		SELECT cid, name, type, "notnull", dflt_value, pk, hidden
+		FROM pragma_table_xinfo(?)
+		ORDER BY cid
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []*ColumnSchema
+	pkColumns := make(map[int]string)
+	for rows.Next() {
+		col := &ColumnSchema{}
+		var declaredType string
+		var notNull bool
+		var pkPosition, hidden int
+		var defaultValue sql.NullString
+
+		if err := rows.Scan(&col.OrdinalPosition, &col.Name, &declaredType, &notNull, &defaultValue, &pkPosition, &hidden); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+
+		col.OrdinalPosition++
+		col.DataType = sqliteDataType(declaredType)
+		col.UDTName = strings.ToLower(declaredType)
+		col.IsNullable = !notNull
+		// hidden is 1 for the hidden columns of virtual tables, 2 and 3 for generated columns
+		col.IsGenerated = hidden == 2 || hidden == 3
+		if defaultValue.Valid {
+			col.DefaultValue = &defaultValue.String
+		}
+		if pkPosition > 0 {
+			pkColumns[pkPosition] = col.Name
+		}
+
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(pkColumns) == 0 {
+		return columns, nil, nil
+	}
+	pk := &PrimaryKeySchema{Name: tableName + "_pkey"}
+	for n := 1; n <= len(pkColumns); n++ {
+		pk.Columns = append(pk.Columns, pkColumns[n])
+	}
+	return columns, pk, nil
+}
+
+// sqliteDataType maps a declared SQLite column type to the name the PostgreSQL
+// information_schema uses for the type holding the same values. SQLite accepts
+// any type name and decides by substring which values a column prefers
+// (https://www.sqlite.org/datatype3.html); the same rules are applied here.
+func sqliteDataType(declaredType string) string {
+	t := strings.ToUpper(declaredType)
+	if idx := strings.Index(t, "("); idx != -1 {
+		t = strings.TrimSpace(t[:idx])
+	}
+
+	switch {
+	case t == "":
+		return "bytea"
+	case strings.Contains(t, "BOOL"):
+		return "boolean"
+	case strings.Contains(t, "INT"):
+		return "bigint"
+	case t == "DATETIME" || strings.HasPrefix(t, "TIMESTAMP"):
+		return "timestamp without time zone"
+	case t == "DATE":
+		return "date"
+	case strings.Contains(t, "JSON"):
+		return "jsonb"
+	case strings.Contains(t, "UUID"):
+		return "uuid"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		return "text"
+	case strings.Contains(t, "BLOB"):
+		return "bytea"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		return "double precision"
+	default:
+		return "numeric"
+	}
+}
+
+func (i *Inspector) getSQLiteForeignKeys(ctx context.Context, tableName string) ([]*ForeignKeySchema, error) {
+	query := `
+		SELECT id, "table", "from", "to", on_update, on_delete
+		FROM pragma_foreign_key_list(?)
+		ORDER BY id, seq
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []*ForeignKeySchema
+	lastID := -1
+	for rows.Next() {
+		var id int
+		var referencedTable, column, onUpdate, onDelete string
+		var referencedColumn sql.NullString
+
+		if err := rows.Scan(&id, &referencedTable, &column, &referencedColumn, &onUpdate, &onDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+
+		// One row per column; the rows of a foreign key share its id
+		if id != lastID {
+			fks = append(fks, &ForeignKeySchema{
+				ReferencedTable:  referencedTable,
+				ReferencedSchema: sqliteSchemaName,
+				OnDelete:         onDelete,
+				OnUpdate:         onUpdate,
+			})
+			lastID = id
+		}
+		fk := fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn.String)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, fk := range fks {
+		fk.Name = fmt.Sprintf("%s_%s_fkey", tableName, strings.Join(fk.Columns, "_"))
+
+		// REFERENCES parent without columns references the primary key of parent
+		if fk.ReferencedColumns[0] != "" {
+			continue
+		}
+		_, pk, err := i.getSQLiteColumns(ctx, fk.ReferencedTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get primary key of %s: %w", fk.ReferencedTable, err)
+		}
+		if pk != nil {
+			fk.ReferencedColumns = pk.Columns
+		}
+	}
+
+	return fks, nil
+}
+
+var sqliteIndexWhereRe = regexp.MustCompile(`(?is)\)\s*WHERE\s+(.+?);?\s*$`)
+
+func (i *Inspector) getSQLiteIndexes(ctx context.Context, tableName string) ([]*IndexSchema, error) {
+	query := `
+		SELECT il.name, il."unique", il.origin, il.partial, ii.name, ii."desc", m.sql
+		FROM pragma_index_list(?) il
+		JOIN pragma_index_xinfo(il.name) ii
+		LEFT JOIN sqlite_master m ON m.type = 'index' AND m.name = il.name
+		WHERE ii.key = 1
+		ORDER BY il.name, ii.seqno
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []*IndexSchema
+	for rows.Next() {
+		var name, origin string
+		var isUnique, isPartial, isDesc bool
+		var column, createSQL sql.NullString
+
+		if err := rows.Scan(&name, &isUnique, &origin, &isPartial, &column, &isDesc, &createSQL); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+
+		// One row per column; the rows of an index are adjacent
+		if n := len(indexes); n == 0 || indexes[n-1].Name != name {
+			idx := &IndexSchema{
+				Name:      name,
+				IsUnique:  isUnique,
+				IsPrimary: origin == "pk",
+				IsPartial: isPartial,
+				Type:      "btree",
+				Columns:   make([]IndexColumn, 0),
+			}
+			if m := sqliteIndexWhereRe.FindStringSubmatch(createSQL.String); isPartial && m != nil {
+				idx.Where = strings.TrimSpace(m[1])
+			}
+			indexes = append(indexes, idx)
+		}
+		idx := indexes[len(indexes)-1]
+
+		// The name is NULL for the expressions of expression indexes
+		col := IndexColumn{Name: column.String, Expression: column.String}
+		if isDesc {
+			col.Order = "DESC"
+		}
+		idx.Columns = append(idx.Columns, col)
+	}
+
+	return indexes, rows.Err()
+}
+
+var sqliteTriggerRe = regexp.MustCompile(`(?is)\b(BEFORE|AFTER|INSTEAD\s+OF)?\s*(INSERT|UPDATE|DELETE)\b(?:\s+OF\s+[^\n]+?)?\s+ON\s`)
+
+func (i *Inspector) getSQLiteTriggers(ctx context.Context, tableName string) ([]*TriggerSchema, error) {
+	query := `
+		SELECT name, sql
+		FROM sqlite_master
+		WHERE type = 'trigger' AND tbl_name = ?
+		ORDER BY name
+	`
+
+	rows, err := i.db.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var triggers []*TriggerSchema
+	for rows.Next() {
+		tr := &TriggerSchema{IsEnabled: true, Level: "ROW", Timing: "BEFORE"}
+		if err := rows.Scan(&tr.Name, &tr.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger: %w", err)
+		}
+
+		// SQLite triggers have their body inline and always fire for each row
+		if m := sqliteTriggerRe.FindStringSubmatch(tr.Definition); m != nil {
+			if m[1] != "" {
+				tr.Timing = strings.ToUpper(strings.Join(strings.Fields(m[1]), " "))
+			}
+			tr.Events = []string{strings.ToUpper(m[2])}
+		}
+		triggers = append(triggers, tr)
+	}
+
+	return triggers, rows.Err()
+}
+
+// getSQLiteTableStatistics counts the rows of the table; SQLite keeps no size,
+// vacuum or analyze statistics per table
+func (i *Inspector) getSQLiteTableStatistics(ctx context.Context, schemaName, tableName string) (*TableStatistics, error) {
+	stats := &TableStatistics{
+		TableName: tableName,
+	}
+
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, strings.ReplaceAll(tableName, `"`, `""`))
+	if err := i.db.QueryRowContext(ctx, query).Scan(&stats.LiveTuples); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	stats.RowCount = stats.LiveTuples
+
+	return stats, nil
+}
+
+func (i *Inspector) getSQLiteViews(ctx context.Context) (map[string]*ViewSchema, error) {
+	query := `
+		SELECT name, sql
+		FROM sqlite_master
+		WHERE type = 'view'
+		ORDER BY name
+	`
+
+	rows, err := i.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []*ViewSchema
+	for rows.Next() {
+		view := &ViewSchema{Schema: sqliteSchemaName}
+		if err := rows.Scan(&view.Name, &view.Definition); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	result := make(map[string]*ViewSchema, len(views))
+	for _, view := range views {
+		view.Columns, _, err = i.getSQLiteColumns(ctx, view.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns for view %s: %w", view.Name, err)
+		}
+		result[fmt.Sprintf("%s.%s", view.Schema, view.Name)] = view
+	}
+
+	return result, nil
+}
@@ -0,0 +1,119 @@
+package introspect
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteDataType(t *testing.T) {
+	tests := []struct {
+		declaredType string
+		want         string
+	}{
+		{"INTEGER", "bigint"},
+		{"BOOLEAN", "boolean"},
+		{"VARCHAR(255)", "text"},
+		{"TEXT", "text"},
+		{"TIMESTAMP", "timestamp without time zone"},
+		{"DATETIME", "timestamp without time zone"},
+		{"DATE", "date"},
+		{"REAL", "double precision"},
+		{"BLOB", "bytea"},
+		{"", "bytea"},
+		{"DECIMAL(10,2)", "numeric"},
+		{"JSON", "jsonb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.declaredType, func(t *testing.T) {
+			if got := sqliteDataType(tt.declaredType); got != tt.want {
+				t.Errorf("sqliteDataType(%q) = %q, want %q", tt.declaredType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspector_SQLiteTable(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, stmt := range []string{
+		`CREATE TABLE users (id TEXT PRIMARY KEY, email TEXT NOT NULL UNIQUE)`,
+		`CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+			status TEXT NOT NULL DEFAULT 'pending',
+			total REAL
+		)`,
+		`CREATE INDEX idx_orders_status ON orders (status DESC) WHERE total > 0`,
+		`INSERT INTO users VALUES ('u1', 'a@example.com')`,
+		`INSERT INTO orders (user_id) VALUES ('u1')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inspector := NewInspector(db, "sqlite")
+	ctx := context.Background()
+
+	orders, err := inspector.GetTable(ctx, "", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders.Columns) != 4 {
+		t.Fatalf("got %d columns, want 4", len(orders.Columns))
+	}
+	id := orders.Columns[0]
+	if !id.IsIdentity || id.DataType != "bigint" {
+		t.Errorf("id = %+v, want an identity bigint", id)
+	}
+	status := orders.Columns[2]
+	if status.IsNullable || status.DefaultValue == nil || *status.DefaultValue != "'pending'" {
+		t.Errorf("status = %+v, want NOT NULL DEFAULT 'pending'", status)
+	}
+	if orders.PrimaryKey == nil || !reflect.DeepEqual(orders.PrimaryKey.Columns, []string{"id"}) {
+		t.Errorf("primary key = %+v, want (id)", orders.PrimaryKey)
+	}
+	if orders.RowCount != 1 {
+		t.Errorf("row count = %d, want 1", orders.RowCount)
+	}
+
+	if len(orders.ForeignKeys) != 1 {
+		t.Fatalf("got %d foreign keys, want 1", len(orders.ForeignKeys))
+	}
+	fk := orders.ForeignKeys[0]
+	if fk.Name != "orders_user_id_fkey" || fk.ReferencedTable != "users" ||
+		!reflect.DeepEqual(fk.ReferencedColumns, []string{"id"}) || fk.OnDelete != "CASCADE" {
+		t.Errorf("foreign key = %+v, want orders_user_id_fkey referencing users(id) ON DELETE CASCADE", fk)
+	}
+
+	if len(orders.Indexes) != 1 {
+		t.Fatalf("got %d indexes, want 1", len(orders.Indexes))
+	}
+	idx := orders.Indexes[0]
+	if idx.Name != "idx_orders_status" || !idx.IsPartial || idx.Where != "total > 0" ||
+		len(idx.Columns) != 1 || idx.Columns[0].Order != "DESC" {
+		t.Errorf("index = %+v, want idx_orders_status (status DESC) WHERE total > 0", idx)
+	}
+
+	users, err := inspector.GetTable(ctx, "", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users.Constraints) != 1 || users.Constraints[0].Name != "users_email_key" {
+		t.Errorf("constraints = %+v, want users_email_key only", users.Constraints)
+	}
+	if users.Columns[0].IsIdentity {
+		t.Error("a TEXT primary key should not be an identity column")
+	}
+}
@@ -93,6 +93,9 @@ func (g *StructGenerator) generateTableStruct(table *TableSchema) (string, error
 	} else {
 		b.WriteString(fmt.Sprintf("// %s represents the %s table\n", structNameFromTable(table.Name), table.Name))
 	}
+	if len(table.Inherits) > 0 {
+		b.WriteString(fmt.Sprintf("// Inherits from %s\n", strings.Join(table.Inherits, ", ")))
+	}
 
 	b.WriteString(fmt.Sprintf("type %s struct {\n", structNameFromTable(table.Name)))
 
@@ -122,6 +125,9 @@ func (g *StructGenerator) generateTableStruct(table *TableSchema) (string, error
 					cols = append(cols, c.Name)
 				}
 			}
+			if idx.NullsNotDistinct {
+				cols = append(cols, "nulls_not_distinct")
+			}
 			tableDefParts = append(tableDefParts, fmt.Sprintf("unique:%s,%s", idx.Name, strings.Join(cols, ",")))
 		}
 	}
@@ -284,7 +290,7 @@ func (g *StructGenerator) buildStormTag(col *ColumnSchema, table *TableSchema) [
 	}
 
 	for _, idx := range table.Indexes {
-		if idx.IsUnique && !idx.IsPrimary && len(idx.Columns) == 1 && idx.Columns[0].Name == col.Name {
+		if idx.IsUnique && !idx.IsPrimary && !idx.NullsNotDistinct && len(idx.Columns) == 1 && idx.Columns[0].Name == col.Name {
 			parts = append(parts, "unique")
 			break
 		}
@@ -305,6 +311,13 @@ func (g *StructGenerator) buildStormTag(col *ColumnSchema, table *TableSchema) [
 		}
 	}
 
+	if col.Storage != "" {
+		parts = append(parts, fmt.Sprintf("storage:%s", col.Storage))
+	}
+	if col.Compression != "" {
+		parts = append(parts, fmt.Sprintf("compression:%s", col.Compression))
+	}
+
 	return parts
 }
 
@@ -432,7 +445,10 @@ func postgresTypeToGoType(dataType, udtName string, isNullable bool) (string, er
 		case "bytea":
 			goType = "[]byte"
 		case "USER-DEFINED":
-
+			if udtName == "vector" {
+				goType = "storm.Vector"
+				break
+			}
 			goType = "string"
 		default:
 
@@ -440,7 +456,7 @@ func postgresTypeToGoType(dataType, udtName string, isNullable bool) (string, er
 		}
 	}
 
-	if isNullable && !strings.HasPrefix(goType, "[]") && goType != "storm.StringArray" {
+	if isNullable && !strings.HasPrefix(goType, "[]") && goType != "storm.StringArray" && goType != "storm.Vector" {
 		goType = "*" + goType
 	}
 
@@ -525,7 +541,7 @@ func (g *StructGenerator) collectImports() []string {
 			if strings.Contains(col.DataType, "time") || col.DataType == "date" {
 				imports["time"] = true
 			}
-			if col.DataType == "json" || col.DataType == "jsonb" {
+			if col.DataType == "json" || col.DataType == "jsonb" || (col.DataType == "USER-DEFINED" && col.UDTName == "vector") {
 				imports["github.com/eleven-am/storm/pkg/storm-orm"] = true
 			}
 			// Check for PostgreSQL array types that use storm.StringArray
@@ -83,6 +83,72 @@ func TestStructGenerator_GenerateStructs(t *testing.T) {
 	}
 }
 
+func TestStructGenerator_NullsNotDistinct(t *testing.T) {
+	schema := &DatabaseSchema{
+		Name: "test_db",
+		Tables: map[string]*TableSchema{
+			"users": {
+				Name:   "users",
+				Schema: "public",
+				Columns: []*ColumnSchema{
+					{Name: "id", DataType: "integer", IsNullable: false},
+					{Name: "phone", DataType: "text", IsNullable: true},
+				},
+				PrimaryKey: &PrimaryKeySchema{Name: "users_pkey", Columns: []string{"id"}},
+				Indexes: []*IndexSchema{
+					{
+						Name:             "users_phone_key",
+						Columns:          []IndexColumn{{Name: "phone"}},
+						IsUnique:         true,
+						NullsNotDistinct: true,
+						Constraint:       "users_phone_key",
+					},
+				},
+			},
+		},
+		Metadata: DatabaseMetadata{InspectedAt: time.Now(), TableCount: 1},
+	}
+
+	result, err := NewStructGenerator(schema, "models").GenerateStructs()
+	if err != nil {
+		t.Fatalf("Failed to generate structs: %v", err)
+	}
+
+	if !strings.Contains(result, "unique:users_phone_key,phone,nulls_not_distinct") {
+		t.Errorf("Expected the unique declaration to keep nulls_not_distinct.\nGenerated:\n%s", result)
+	}
+	if strings.Contains(result, "type:text;unique") {
+		t.Errorf("Expected the column to leave the uniqueness to the table-level declaration.\nGenerated:\n%s", result)
+	}
+}
+
+func TestStructGenerator_ColumnStorage(t *testing.T) {
+	schema := &DatabaseSchema{
+		Name: "test_db",
+		Tables: map[string]*TableSchema{
+			"documents": {
+				Name:   "documents",
+				Schema: "public",
+				Columns: []*ColumnSchema{
+					{Name: "id", DataType: "integer", IsNullable: false},
+					{Name: "body", DataType: "text", IsNullable: true, Storage: "external", Compression: "lz4"},
+				},
+				PrimaryKey: &PrimaryKeySchema{Name: "documents_pkey", Columns: []string{"id"}},
+			},
+		},
+		Metadata: DatabaseMetadata{InspectedAt: time.Now(), TableCount: 1},
+	}
+
+	result, err := NewStructGenerator(schema, "models").GenerateStructs()
+	if err != nil {
+		t.Fatalf("Failed to generate structs: %v", err)
+	}
+
+	if !strings.Contains(result, "type:text;storage:external;compression:lz4") {
+		t.Errorf("Expected the storage settings in the tag.\nGenerated:\n%s", result)
+	}
+}
+
 func TestStructGenerator_ComplexTypes(t *testing.T) {
 	schema := &DatabaseSchema{
 		Name: "test_db",
@@ -240,6 +306,31 @@ func TestStructGenerator_ForeignKeys(t *testing.T) {
 	}
 }
 
+func TestStructGenerator_InheritedTable(t *testing.T) {
+	schema := &DatabaseSchema{
+		Name: "test_db",
+		Tables: map[string]*TableSchema{
+			"measurements_2019": {
+				Name:   "measurements_2019",
+				Schema: "public",
+				Columns: []*ColumnSchema{
+					{Name: "id", DataType: "bigint", IsNullable: false},
+				},
+				PrimaryKey: &PrimaryKeySchema{Name: "measurements_2019_pkey", Columns: []string{"id"}},
+				Inherits:   []string{"measurements"},
+			},
+		},
+	}
+
+	result, err := NewStructGenerator(schema, "models").GenerateStructs()
+	if err != nil {
+		t.Fatalf("Failed to generate structs: %v", err)
+	}
+	if !strings.Contains(result, "// Inherits from measurements\ntype Measurements2019 struct") {
+		t.Errorf("Expected the parent table to be noted on the struct.\nGenerated:\n%s", result)
+	}
+}
+
 func TestStructGenerator_TableNameConversion(t *testing.T) {
 	tests := []struct {
 		tableName    string
@@ -290,6 +381,8 @@ func TestPostgresTypeToGoType(t *testing.T) {
 		{"bytea", "", true, "[]byte"},
 		{"USER-DEFINED", "custom_type", false, "string"},
 		{"USER-DEFINED", "custom_type", true, "*string"},
+		{"USER-DEFINED", "vector", false, "storm.Vector"},
+		{"USER-DEFINED", "vector", true, "storm.Vector"},
 	}
 
 	for _, tt := range tests {
@@ -37,6 +37,7 @@ type TableSchema struct {
 	Indexes     []*IndexSchema
 	Constraints []*ConstraintSchema
 	Triggers    []*TriggerSchema
+	Inherits    []string // Parent tables of a table created with INHERITS
 	Comment     string
 	RowCount    int64
 	SizeBytes   int64
@@ -57,6 +58,12 @@ type ColumnSchema struct {
 	IsGenerated      bool
 	GenerationExpr   *string
 	Comment          string
+	// Storage is the storage strategy set with SET STORAGE, empty when the column
+	// keeps that of its type
+	Storage string
+	// Compression is the compression method set with SET COMPRESSION, empty for the
+	// server's default
+	Compression string
 }
 
 // PrimaryKeySchema represents a primary key constraint
@@ -86,6 +93,18 @@ type IndexSchema struct {
 	Where      string
 	Type       string
 	TableSpace string
+	// NullsNotDistinct is set for unique indexes that treat NULLs as equal, which
+	// PostgreSQL 15 and later support
+	NullsNotDistinct bool
+	// Constraint names the unique, exclusion or primary key constraint the index
+	// implements, which dropping the index would drop with it
+	Constraint string
+	// ReferencedBy names the foreign keys whose referenced columns the index enforces.
+	// PostgreSQL refuses to drop the index while they exist.
+	ReferencedBy []string
+	// ForeignKeyHelper is set when the leading columns of the index are the columns of
+	// a foreign key of its table, so the index serves lookups on that foreign key
+	ForeignKeyHelper bool
 }
 
 // IndexColumn represents a column in an index
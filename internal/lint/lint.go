@@ -0,0 +1,230 @@
+package lint
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	orm_generator "github.com/eleven-am/storm/internal/orm-generator"
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+// Issue represents a single problem found in a model definition
+type Issue struct {
+	Pos     token.Position
+	Struct  string
+	Field   string
+	Message string
+}
+
+func (i Issue) String() string {
+	location := i.Struct
+	if i.Field != "" {
+		location = fmt.Sprintf("%s.%s", i.Struct, i.Field)
+	}
+	if i.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s: %s", i.Pos, location, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, i.Message)
+}
+
+// Linter validates dbdef, storm and orm tags across a set of models
+type Linter struct {
+	tagParser   *parser.TagParser
+	stormParser *parser.StormTagParser
+	ormParser   *orm_generator.ORMTagParser
+}
+
+func NewLinter() *Linter {
+	return &Linter{
+		tagParser:   parser.NewTagParser(),
+		stormParser: parser.NewStormTagParser(),
+		ormParser:   orm_generator.NewORMTagParser(),
+	}
+}
+
+// LintDirectory parses every model in dir and lints them together
+func (l *Linter) LintDirectory(dir string) ([]Issue, error) {
+	tables, err := parser.NewStructParser().ParseDirectory(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse models: %w", err)
+	}
+
+	return l.Lint(tables), nil
+}
+
+// Lint checks the given tables for unknown attributes, dangling foreign keys,
+// duplicate index names, missing relationship targets and missing primary keys
+func (l *Linter) Lint(tables []parser.TableDefinition) []Issue {
+	var issues []Issue
+
+	models := make(map[string]bool)
+	columns := make(map[string]map[string]bool)
+	for _, table := range tables {
+		models[table.StructName] = true
+		models[table.TableName] = true
+
+		columns[table.TableName] = make(map[string]bool)
+		for _, field := range table.Fields {
+			if field.IsColumn() {
+				columns[table.TableName][field.DBName] = true
+			}
+		}
+	}
+
+	indexOwners := make(map[string]string)
+
+	for _, table := range tables {
+		for _, err := range table.TagErrors {
+			issues = append(issues, Issue{Pos: table.Pos, Struct: table.StructName, Message: err.Error()})
+		}
+
+		for _, key := range sortedKeys(table.TableLevel) {
+			if !parser.IsKnownTableLevelAttribute(key) {
+				issues = append(issues, Issue{
+					Pos:     table.Pos,
+					Struct:  table.StructName,
+					Message: fmt.Sprintf("unknown table-level attribute '%s'", key),
+				})
+			}
+		}
+
+		for _, name := range indexNames(table.TableLevel) {
+			if owner, exists := indexOwners[name]; exists {
+				issues = append(issues, Issue{
+					Pos:     table.Pos,
+					Struct:  table.StructName,
+					Message: fmt.Sprintf("duplicate index name '%s' (already defined on %s)", name, owner),
+				})
+				continue
+			}
+			indexOwners[name] = table.StructName
+		}
+
+		hasPrimaryKey := false
+		for _, field := range table.Fields {
+			if l.tagParser.HasFlag(field.DBDef, "primary_key") {
+				hasPrimaryKey = true
+			}
+			issues = append(issues, l.lintField(table, field, models, columns)...)
+		}
+
+		if !hasPrimaryKey {
+			issues = append(issues, Issue{
+				Pos:     table.Pos,
+				Struct:  table.StructName,
+				Message: "model has no primary key",
+			})
+		}
+	}
+
+	return issues
+}
+
+func (l *Linter) lintField(table parser.TableDefinition, field parser.FieldDefinition, models map[string]bool, columns map[string]map[string]bool) []Issue {
+	var issues []Issue
+
+	report := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{
+			Pos:     field.Pos,
+			Struct:  table.StructName,
+			Field:   field.Name,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	var relationTarget string
+
+	switch {
+	case field.IsTransient():
+		// db:"-", dbdef:"-", storm:"-" and storm:"ignore" carry no column definition
+	case field.StormTag != "":
+		parsed, err := l.stormParser.ParseStormTag(field.StormTag, field.IsRelationship())
+		if err != nil {
+			report("invalid storm tag: %v", err)
+			break
+		}
+		if parsed.IsRelationship {
+			relationTarget = parsed.RelationTarget
+		}
+	case field.DBDefTag != "":
+		attrs := l.tagParser.ParseDBDefTag(field.DBDefTag)
+		unknown := false
+		for _, key := range sortedKeys(attrs) {
+			if !parser.IsKnownFieldAttribute(key) {
+				report("unknown dbdef attribute '%s'", key)
+				unknown = true
+			}
+		}
+		if !unknown {
+			if err := l.tagParser.ValidateDBDefTag(field.DBDefTag); err != nil {
+				report("invalid dbdef tag: %v", err)
+			}
+		}
+	}
+
+	if field.ORMTag != "" {
+		parsed, err := l.ormParser.ParseORMTag(field.ORMTag)
+		if err != nil {
+			report("invalid orm tag: %v", err)
+		} else {
+			relationTarget = parsed.Target
+		}
+	}
+
+	if relationTarget != "" && !models[relationTarget] {
+		report("relationship target '%s' does not exist", relationTarget)
+	}
+
+	if fkRef := l.tagParser.GetForeignKey(field.DBDef); fkRef != "" {
+		parts := strings.Split(fkRef, ".")
+		if len(parts) != 2 {
+			report("foreign key must be in format 'table.column', got: %s", fkRef)
+		} else {
+			refTable := strings.TrimSpace(parts[0])
+			refColumn := strings.TrimSpace(parts[1])
+			if refColumns, exists := columns[refTable]; !exists {
+				report("foreign key references nonexistent table '%s'", refTable)
+			} else if !refColumns[refColumn] {
+				report("foreign key references nonexistent column '%s.%s'", refTable, refColumn)
+			}
+		}
+	}
+
+	return issues
+}
+
+// indexNames returns the names of all indexes and unique constraints declared at table level
+func indexNames(tableLevel map[string]string) []string {
+	var names []string
+
+	for _, key := range []string{"index", "unique"} {
+		value, exists := tableLevel[key]
+		if !exists {
+			continue
+		}
+		for _, def := range strings.Split(value, ";") {
+			def = strings.TrimSpace(def)
+			if def == "" {
+				continue
+			}
+			name := strings.TrimSpace(strings.SplitN(def, ",", 2)[0])
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
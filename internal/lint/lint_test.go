@@ -0,0 +1,96 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintSource(t *testing.T, src string) []Issue {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(src), 0644))
+
+	issues, err := NewLinter().LintDirectory(dir)
+	require.NoError(t, err)
+	return issues
+}
+
+func messages(issues []Issue) string {
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLinter_CleanModels(t *testing.T) {
+	issues := lintSource(t, "package models\n\n"+
+		"type User struct {\n"+
+		"\t_  struct{} `dbdef:\"table:users;index:idx_users_email,email\"`\n"+
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n"+
+		"\tEmail string `db:\"email\" dbdef:\"type:text;not_null\"`\n"+
+		"}\n\n"+
+		"type Post struct {\n"+
+		"\tID     string `db:\"id\" storm:\"type:uuid;primary_key\"`\n"+
+		"\tUserID string `db:\"user_id\" storm:\"type:uuid;foreign_key:users.id\"`\n"+
+		"\tAuthor *User  `storm:\"relation:belongs_to:User;foreign_key:user_id\"`\n"+
+		"}\n")
+
+	assert.Empty(t, issues, messages(issues))
+}
+
+func TestLinter_ReportsProblems(t *testing.T) {
+	issues := lintSource(t, "package models\n\n"+
+		"type User struct {\n"+
+		"\t_  struct{} `dbdef:\"table:users;index:idx_email,email;colour:red\"`\n"+
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key;nullable\"`\n"+
+		"\tEmail string `db:\"email\" dbdef:\"type:text\"`\n"+
+		"}\n\n"+
+		"type Post struct {\n"+
+		"\t_  struct{} `dbdef:\"table:posts;index:idx_email,title\"`\n"+
+		"\tTitle  string `db:\"title\" dbdef:\"type:text\"`\n"+
+		"\tUserID string `db:\"user_id\" dbdef:\"type:uuid;fk:accounts.id\"`\n"+
+		"\tEditorID string `db:\"editor_id\" dbdef:\"type:uuid;fk:users.uuid\"`\n"+
+		"\tTags   []Tag  `storm:\"relation:has_many:Tag;foreign_key:post_id\"`\n"+
+		"\tOwner  *User  `storm:\"relation:belongs_to:User;colour:red\"`\n"+
+		"}\n")
+
+	output := messages(issues)
+	assert.Contains(t, output, "User: unknown table-level attribute 'colour'")
+	assert.Contains(t, output, "User.ID: unknown dbdef attribute 'nullable'")
+	assert.Contains(t, output, "Post: duplicate index name 'idx_email' (already defined on User)")
+	assert.Contains(t, output, "Post: model has no primary key")
+	assert.Contains(t, output, "Post.UserID: foreign key references nonexistent table 'accounts'")
+	assert.Contains(t, output, "Post.EditorID: foreign key references nonexistent column 'users.uuid'")
+	assert.Contains(t, output, "Post.Tags: relationship target 'Tag' does not exist")
+	assert.Contains(t, output, "Post.Owner: invalid storm tag")
+	assert.Len(t, issues, 8, output)
+}
+
+func TestLinter_TableLevelStormTagErrors(t *testing.T) {
+	issues := lintSource(t, "package models\n\n"+
+		"type User struct {\n"+
+		"\t_  struct{} `storm:\"table:users;colour:red\"`\n"+
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n"+
+		"}\n")
+
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].String(), "User: invalid table-level storm tag")
+}
+
+func TestLinter_ReportsPositions(t *testing.T) {
+	issues := lintSource(t, "package models\n\n"+
+		"type User struct {\n"+
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key;nullable\"`\n"+
+		"}\n")
+
+	require.Len(t, issues, 1)
+	assert.Equal(t, 4, issues[0].Pos.Line)
+	assert.Equal(t, 2, issues[0].Pos.Column)
+	assert.True(t, strings.HasSuffix(issues[0].Pos.Filename, "models.go"))
+	assert.Contains(t, issues[0].String(), "models.go:4:2: User.ID: unknown dbdef attribute 'nullable'")
+}
@@ -0,0 +1,70 @@
+package migrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAfterPush_Validate(t *testing.T) {
+	valid := AfterPush{PgBouncer: []PgBouncerTarget{{AdminURL: "postgres://pgbouncer@localhost:6432/pgbouncer", Database: "app_db"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid hooks, got %v", err)
+	}
+
+	missingURL := AfterPush{PgBouncer: []PgBouncerTarget{{Database: "app"}}}
+	if err := missingURL.Validate(); err == nil {
+		t.Error("Expected an error for a target without admin_url")
+	}
+
+	injected := AfterPush{PgBouncer: []PgBouncerTarget{{AdminURL: "postgres://localhost/pgbouncer", Database: "app; SHUTDOWN"}}}
+	if err := injected.Validate(); err == nil {
+		t.Error("Expected an error for a database name that is not a plain name")
+	}
+
+	if !(AfterPush{}).IsZero() || valid.IsZero() {
+		t.Error("Expected only empty hooks to be zero")
+	}
+}
+
+func TestAfterPush_Run(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	out := filepath.Join(t.TempDir(), "event")
+	hooks := AfterPush{
+		Notify: "storm_schema_changed",
+		Commands: []string{
+			"exit 3",
+			`echo "$STORM_DATABASE $STORM_SCHEMA_HASH" > ` + out,
+		},
+	}
+
+	mock.ExpectExec(`SELECT pg_notify\(\$1, \$2\)`).
+		WithArgs("storm_schema_changed", `{"database":"app","schema_hash":"abc123"}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = hooks.Run(context.Background(), db, AfterPushEvent{Database: "app", SchemaHash: "abc123"})
+	if err == nil || !strings.Contains(err.Error(), `command "exit 3" failed`) {
+		t.Errorf("Expected the failing command to be reported, got %v", err)
+	}
+
+	content, readErr := os.ReadFile(out)
+	if readErr != nil {
+		t.Fatalf("Expected the commands after a failing one to run: %v", readErr)
+	}
+	if got := strings.TrimSpace(string(content)); got != "app abc123" {
+		t.Errorf("Expected the event in the environment, got %q", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package migrator
+
+import (
+	"regexp"
+	"strings"
+)
+
+const tableRefPattern = `((?:"[^"]+"|\w+)(?:\.(?:"[^"]+"|\w+))?)`
+
+var (
+	analyzeCreateIndexRe = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:(?:"[^"]+"|\w+)\s+)?ON\s+(?:ONLY\s+)?` + tableRefPattern)
+	analyzeAlterTableRe  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + tableRefPattern + `(.*)$`)
+	analyzeUpdateRe      = regexp.MustCompile(`(?is)^UPDATE\s+(?:ONLY\s+)?` + tableRefPattern)
+	analyzeCreateTableRe = regexp.MustCompile(`(?is)^CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + tableRefPattern)
+
+	// Column changes that fill or rewrite every row
+	analyzeAddColumnRe  = regexp.MustCompile(`(?is)\bADD\s+COLUMN\b(?:[^,(]|\([^)]*\))*\bDEFAULT\b`)
+	analyzeTypeChangeRe = regexp.MustCompile(`(?is)\bALTER\s+COLUMN\s+(?:"[^"]+"|\w+)\s+(?:SET\s+DATA\s+)?TYPE\b`)
+)
+
+// analyzeTargets returns the tables whose statistics statements make stale, in
+// the order they are first touched: tables that get an index, a column filled
+// with a default, a column of a new type or updated rows. Tables created by the
+// statements are left out, as they start empty.
+func analyzeTargets(statements []string) []string {
+	created := make(map[string]bool)
+	for _, stmt := range statements {
+		if match := analyzeCreateTableRe.FindStringSubmatch(stripLeadingComments(stmt)); match != nil {
+			created[normalizeTableRef(match[1])] = true
+		}
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	for _, stmt := range statements {
+		table := analyzeTarget(stripLeadingComments(stmt))
+		if table == "" {
+			continue
+		}
+		name := normalizeTableRef(table)
+		if created[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		targets = append(targets, table)
+	}
+	return targets
+}
+
+// analyzeTarget returns the table stmt makes the statistics of stale, if any
+func analyzeTarget(stmt string) string {
+	if match := analyzeCreateIndexRe.FindStringSubmatch(stmt); match != nil {
+		return match[1]
+	}
+	if match := analyzeUpdateRe.FindStringSubmatch(stmt); match != nil {
+		return match[1]
+	}
+	if match := analyzeAlterTableRe.FindStringSubmatch(stmt); match != nil {
+		if analyzeAddColumnRe.MatchString(match[2]) || analyzeTypeChangeRe.MatchString(match[2]) {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// normalizeTableRef drops the quotes and the public schema of a table reference
+func normalizeTableRef(table string) string {
+	name := strings.ReplaceAll(table, `"`, "")
+	return strings.TrimPrefix(name, "public.")
+}
@@ -0,0 +1,61 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeTargets(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		want       []string
+	}{
+		{
+			name: "indexes, filled and retyped columns, and updates",
+			statements: []string{
+				`CREATE INDEX CONCURRENTLY "idx_users_email" ON "users" ("email")`,
+				`ALTER TABLE "orders" ADD COLUMN "price" numeric(10,2) NOT NULL DEFAULT 0`,
+				`ALTER TABLE "posts" ALTER COLUMN "views" TYPE bigint`,
+				`UPDATE "accounts" SET "status" = 'active' WHERE "status" IS NULL`,
+			},
+			want: []string{`"users"`, `"orders"`, `"posts"`, `"accounts"`},
+		},
+		{
+			name: "tables created by the migration start empty",
+			statements: []string{
+				`CREATE TABLE "teams" ("id" uuid NOT NULL, PRIMARY KEY ("id"))`,
+				`CREATE UNIQUE INDEX "idx_teams_slug" ON "teams" ("slug")`,
+				`-- Create index "idx_users_team" to table: "users"
+CREATE INDEX "idx_users_team" ON "public"."users" ("team_id")`,
+			},
+			want: []string{`"public"."users"`},
+		},
+		{
+			name: "each table once",
+			statements: []string{
+				`CREATE INDEX "idx_users_email" ON "users" ("email")`,
+				`CREATE INDEX "idx_users_name" ON "public"."users" ("name")`,
+			},
+			want: []string{`"users"`},
+		},
+		{
+			name: "changes that leave the data alone",
+			statements: []string{
+				`ALTER TABLE "users" ADD COLUMN "bio" text NULL`,
+				`ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL`,
+				`ALTER TABLE "users" RENAME COLUMN "legacy" TO "old_legacy"`,
+				`DROP INDEX "idx_users_name"`,
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analyzeTargets(tt.statements); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("analyzeTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"ariga.io/atlas/sql/schema"
 	"github.com/eleven-am/storm/internal/generator"
 	"github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
 )
 
 // MigrationOptions contains options for migration generation
@@ -23,6 +25,20 @@ type MigrationOptions struct {
 	AllowDestructive    bool
 	PushToDB            bool
 	CreateDBIfNotExists bool
+	Strict              bool                            // Fail on unknown tag attributes and Go types instead of warning
+	DataPreservation    DataPreservation                // Keep the data of dropped tables and columns instead of discarding it
+	RetentionPeriod     time.Duration                   // Drop preserved tables and columns once they are this old, zero keeps them
+	ConcurrentIndexes   bool                            // Build and drop indexes of existing tables with CONCURRENTLY
+	SafeConstraints     bool                            // Add constraints to existing tables NOT VALID and validate them separately
+	Analyze             bool                            // ANALYZE the existing tables that get indexes, filled or retyped columns, or updated rows
+	ForeignKeys         generator.ForeignKeyConventions // Default actions and naming of foreign keys
+	Ignore              IgnoreRules                     // Objects managed by extensions and other tools, left out of migrations
+	UpdatedAtTriggers   bool                            // Set updated_at columns with BEFORE UPDATE triggers
+	StormVersion        string                          // Recorded in storm_schema_info once PushToDB has applied the models
+	LockRetry           LockRetry                       // Retry pushed statements that fail on lock_timeout
+
+	// Confirm is shown the plan before PushToDB executes it; returning false applies nothing
+	Confirm func(result *MigrationResult) (bool, error)
 }
 
 // MigrationResult contains the results of migration generation
@@ -32,8 +48,12 @@ type MigrationResult struct {
 	Changes        []schema.Change
 	HasDestructive bool
 	DestructiveOps []string
+	Warnings       []string
 	UpFilePath     string
 	DownFilePath   string
+	Applied        bool            // The statements were executed on the database
+	ModelsHash     string          // Hash of the models the statements migrate the database to, recorded in storm_schema_info once Applied
+	Statements     []PlanStatement // The statements in the order a push executes them
 }
 
 // AtlasMigrator handles migration generation using Atlas with simplified approach
@@ -58,6 +78,9 @@ func NewAtlasMigrator(config *DBConfig) *AtlasMigrator {
 }
 
 func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB, opts MigrationOptions) (*MigrationResult, error) {
+	if err := opts.Ignore.Validate(); err != nil {
+		return nil, err
+	}
 
 	fmt.Println("Parsing Go structs...")
 	models, err := m.structParser.ParseDirectory(opts.PackagePath)
@@ -67,6 +90,8 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	fmt.Printf("Found %d models in %s\n", len(models), opts.PackagePath)
 
 	fmt.Println("Generating DDL SQL from Go structs...")
+	m.schemaGenerator.SetStrictMode(opts.Strict)
+	m.schemaGenerator.SetForeignKeyConventions(opts.ForeignKeys)
 	schema, err := m.schemaGenerator.GenerateSchema(models)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate schema: %w", err)
@@ -75,15 +100,40 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	ddlSQL := m.sqlGenerator.GenerateSchema(schema)
 	fmt.Printf("Generated DDL for %d tables\n", len(schema.Tables))
 
+	// A push holds the migration lock from the diff until the schema is
+	// stamped, so no plan apply or other push changes the schema in between
+	if opts.PushToDB && !opts.DryRun {
+		unlock, err := LockMigrations(ctx, sourceDB)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
 	simpleMigrator := NewSimplifiedAtlasMigrator(m.config)
+	simpleMigrator.SetDataPreservation(opts.DataPreservation)
+	simpleMigrator.SetRetentionPeriod(opts.RetentionPeriod)
+	simpleMigrator.SetConcurrentIndexes(opts.ConcurrentIndexes)
+	simpleMigrator.SetConcurrentIndexNames(concurrentIndexNames(schema))
+	simpleMigrator.SetSafeConstraints(opts.SafeConstraints)
+	// storm_schema_info and storm_backfills belong to storm rather than the models
+	ignore := opts.Ignore
+	ignore.Tables = append(append([]string{}, ignore.Tables...), orm.SchemaInfoTable, orm.BackfillsTable)
+	simpleMigrator.SetIgnoreRules(ignore)
+	simpleMigrator.SetUpdatedAtTriggers(opts.UpdatedAtTriggers)
 	upStatements, changes, err := simpleMigrator.GenerateMigrationSimple(ctx, sourceDB, ddlSQL, opts.CreateDBIfNotExists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate migration: %w", err)
 	}
 
-	if len(changes) == 0 {
+	if len(upStatements) == 0 && len(simpleMigrator.idFunctions) == 0 {
 		fmt.Println("No schema changes detected! Database is up to date.")
-		return &MigrationResult{}, nil
+		if opts.PushToDB && !opts.DryRun {
+			if err := stampSchema(ctx, sourceDB, hashDDL(ddlSQL), opts.StormVersion); err != nil {
+				return nil, err
+			}
+		}
+		return &MigrationResult{ModelsHash: hashDDL(ddlSQL)}, nil
 	}
 
 	fmt.Printf("Found %d migration statements:\n", len(changes))
@@ -93,6 +143,12 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	var upBuilder strings.Builder
 	upBuilder.WriteString("-- Migration UP generated by db-migrator using Atlas\n")
 	upBuilder.WriteString("-- Generated at: " + time.Now().UTC().Format(time.RFC3339) + "\n\n")
+	for _, warning := range simpleMigrator.warnings {
+		upBuilder.WriteString("-- WARNING: " + warning + "\n")
+	}
+	if len(simpleMigrator.warnings) > 0 {
+		upBuilder.WriteString("\n")
+	}
 
 	// Add database creation if requested (but not for push, as it's handled separately)
 	if opts.CreateDBIfNotExists && !opts.PushToDB {
@@ -102,19 +158,61 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 		}
 	}
 
-	// Check if CUID functions are needed and add them if so
-	if needsCUIDFunctions(upStatements) {
-		upBuilder.WriteString(generateCUIDFunctions())
+	// Create the ID functions column defaults call before the tables using them
+	if len(simpleMigrator.idFunctions) > 0 {
+		upBuilder.WriteString("-- ID generation functions\n")
+		for _, stmt := range idFunctionStatements(simpleMigrator.idFunctions) {
+			upBuilder.WriteString(stmt + ";\n\n")
+		}
+	}
+
+	if needsVectorExtension(upStatements) {
+		upBuilder.WriteString("-- Enable pgvector for vector columns\n")
+		upBuilder.WriteString(vectorExtensionSQL)
 		upBuilder.WriteString("\n")
 	}
 
-	for i, stmt := range upStatements {
-		var description string
-		if i < len(changes) {
-			description = DescribeChange(changes[i])
-		} else {
-			description = "Generated statement"
+	for up, down := range simpleMigrator.reversals {
+		m.migrationReverser.RegisterReversal(up, down)
+	}
+
+	descriptions := make([]string, len(upStatements))
+	atlasStatements := len(upStatements) - len(simpleMigrator.steps)
+	for i := range upStatements {
+		switch {
+		case i >= atlasStatements:
+			step := simpleMigrator.steps[i-atlasStatements]
+			descriptions[i] = step.Description
+			m.migrationReverser.RegisterReversal(upStatements[i], step.DownSQL())
+		case i < len(changes):
+			descriptions[i] = DescribeChange(changes[i])
+		default:
+			descriptions[i] = "Generated statement"
 		}
+	}
+
+	order := orderChangesForUpMigration(upStatements)
+	orderedStatements := make([]string, len(order))
+	orderedDescriptions := make([]string, len(order))
+	for i, idx := range order {
+		orderedStatements[i] = upStatements[idx]
+		orderedDescriptions[i] = descriptions[idx]
+	}
+	upStatements = orderedStatements
+
+	// Fresh statistics, so that queries do not plan with the old ones until
+	// autovacuum gets to the tables
+	if opts.Analyze {
+		for _, table := range analyzeTargets(upStatements) {
+			stmt := "ANALYZE " + table
+			m.migrationReverser.RegisterReversal(stmt, "")
+			upStatements = append(upStatements, stmt)
+			orderedDescriptions = append(orderedDescriptions, "Refresh the planner statistics of "+table)
+		}
+	}
+
+	for i, stmt := range upStatements {
+		description := orderedDescriptions[i]
 		upBuilder.WriteString(fmt.Sprintf("-- Statement %d: %s\n", i+1, description))
 		upBuilder.WriteString(stmt)
 		if !strings.HasSuffix(stmt, ";") {
@@ -147,12 +245,33 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	upSQL := upBuilder.String()
 	downSQL := downBuilder.String()
 
+	var statements []PlanStatement
+	for _, stmt := range idFunctionStatements(simpleMigrator.idFunctions) {
+		statements = append(statements, PlanStatement{Description: "ID generation function", SQL: stmt})
+	}
+	if needsVectorExtension(upStatements) {
+		statements = append(statements, PlanStatement{Description: "Enable pgvector for vector columns", SQL: strings.TrimSpace(vectorExtensionSQL)})
+	}
+	for i, stmt := range upStatements {
+		statements = append(statements, PlanStatement{Description: orderedDescriptions[i], SQL: stmt})
+	}
+
 	result := &MigrationResult{
 		UpSQL:          upSQL,
 		DownSQL:        downSQL,
 		Changes:        changes,
 		HasDestructive: destructiveCount > 0,
 		DestructiveOps: destructiveOps,
+		Warnings:       simpleMigrator.warnings,
+		ModelsHash:     hashDDL(ddlSQL),
+		Statements:     statements,
+	}
+
+	if len(result.Warnings) > 0 {
+		fmt.Println("\nPRIMARY KEY CHANGES:")
+		for _, warning := range result.Warnings {
+			fmt.Printf("  - %s\n", warning)
+		}
 	}
 
 	if result.HasDestructive && !opts.AllowDestructive {
@@ -174,46 +293,65 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	}
 
 	if opts.PushToDB {
+		if opts.Confirm != nil {
+			confirmed, err := opts.Confirm(result)
+			if err != nil {
+				return nil, err
+			}
+			if !confirmed {
+				fmt.Println("Migration not applied.")
+				return result, nil
+			}
+		}
+
 		fmt.Println("Executing migration on database...")
 
 		// Prepare statements for execution, including CUID functions if needed
 		var execStatements []string
 
-		// Add CUID functions first if needed
-		if needsCUIDFunctions(upStatements) {
-			cuidSQL := generateCUIDFunctions()
-			// Execute CUID functions as a single block to handle dollar-quoted strings properly
-			fmt.Printf("Executing CUID functions...\n")
-			if _, err := sourceDB.ExecContext(ctx, cuidSQL); err != nil {
-				return nil, fmt.Errorf("failed to execute CUID functions: %w", err)
+		// Add ID functions first if needed
+		if len(simpleMigrator.idFunctions) > 0 {
+			fmt.Printf("Executing ID functions...\n")
+			for _, stmt := range idFunctionStatements(simpleMigrator.idFunctions) {
+				if _, err := sourceDB.ExecContext(ctx, stmt); err != nil {
+					return nil, fmt.Errorf("failed to execute ID functions: %w", err)
+				}
+			}
+		}
+
+		if needsVectorExtension(upStatements) {
+			if _, err := sourceDB.ExecContext(ctx, vectorExtensionSQL); err != nil {
+				return nil, fmt.Errorf("failed to enable the vector extension: %w", err)
 			}
 		}
 
 		// Add the main migration statements
 		execStatements = append(execStatements, upStatements...)
 
-		// Execute all statements
-		for i, stmt := range execStatements {
-			fmt.Printf("Executing statement %d/%d...\n", i+1, len(execStatements))
-			if _, err := sourceDB.ExecContext(ctx, stmt); err != nil {
-				return nil, fmt.Errorf("failed to execute statement %d: %s\nError: %w", i+1, stmt, err)
-			}
+		if err := executeStatements(ctx, sourceDB, execStatements, opts.LockRetry); err != nil {
+			return nil, err
 		}
 		fmt.Printf("\nMigration executed successfully! Applied %d changes.\n", len(execStatements))
+		result.Applied = true
+
+		if err := stampSchema(ctx, sourceDB, result.ModelsHash, opts.StormVersion); err != nil {
+			return nil, err
+		}
 		return result, nil
 	}
 
 	if opts.OutputDir != "" {
-		if err := m.writeMigrationFiles(opts.OutputDir, opts.MigrationName, upSQL, downSQL); err != nil {
+		meta := MigrationMeta{
+			Changes:     orderedDescriptions,
+			Warnings:    result.Warnings,
+			Destructive: result.DestructiveOps,
+			ModelsHash:  result.ModelsHash,
+		}
+		baseName, err := m.writeMigrationFiles(opts.OutputDir, opts.MigrationName, upSQL, downSQL, meta)
+		if err != nil {
 			return nil, fmt.Errorf("failed to write migration files: %w", err)
 		}
 
-		timestamp := time.Now().UTC().Format("20060102150405")
-		migrationName := opts.MigrationName
-		if migrationName == "" {
-			migrationName = "schema_update"
-		}
-		baseName := fmt.Sprintf("%s_%s", timestamp, migrationName)
 		result.UpFilePath = filepath.Join(opts.OutputDir, fmt.Sprintf("%s.up.sql", baseName))
 		result.DownFilePath = filepath.Join(opts.OutputDir, fmt.Sprintf("%s.down.sql", baseName))
 
@@ -225,10 +363,12 @@ func (m *AtlasMigrator) GenerateMigration(ctx context.Context, sourceDB *sql.DB,
 	return result, nil
 }
 
-func (m *AtlasMigrator) writeMigrationFiles(outputDir, migrationName, upSQL, downSQL string) error {
+// writeMigrationFiles writes the up and down files of a migration, and meta next to
+// them, and returns their name without the suffixes
+func (m *AtlasMigrator) writeMigrationFiles(outputDir, migrationName, upSQL, downSQL string, meta MigrationMeta) (string, error) {
 
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return "", fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	timestamp := time.Now().UTC().Format("20060102150405")
@@ -241,96 +381,56 @@ func (m *AtlasMigrator) writeMigrationFiles(outputDir, migrationName, upSQL, dow
 	downFile := filepath.Join(outputDir, fmt.Sprintf("%s.down.sql", baseName))
 
 	if err := os.WriteFile(upFile, []byte(upSQL), 0644); err != nil {
-		return fmt.Errorf("failed to write UP migration: %w", err)
+		return "", fmt.Errorf("failed to write UP migration: %w", err)
 	}
 
 	if err := os.WriteFile(downFile, []byte(downSQL), 0644); err != nil {
-		return fmt.Errorf("failed to write DOWN migration: %w", err)
+		return "", fmt.Errorf("failed to write DOWN migration: %w", err)
 	}
 
-	return nil
+	if err := WriteMigrationMeta(outputDir, baseName, meta); err != nil {
+		return "", err
+	}
+
+	return baseName, nil
 }
 
-// needsCUIDFunctions checks if any SQL statements contain gen_cuid() function calls
-func needsCUIDFunctions(statements []string) bool {
+var vectorTypeRe = regexp.MustCompile(`(?i)\s(?:"?\w+"?\.)?vector(?:\(\d+\))?(?:\s|,|\)|$)|\bvector_\w+_ops\b`)
+
+// needsVectorExtension checks if any SQL statements use the pgvector vector type or its operator classes
+func needsVectorExtension(statements []string) bool {
 	for _, stmt := range statements {
-		if strings.Contains(strings.ToLower(stmt), "gen_cuid()") {
+		if vectorTypeRe.MatchString(stmt) {
 			return true
 		}
 	}
 	return false
 }
 
-// generateCUIDFunctions returns the SQL for creating CUID generation functions
-func generateCUIDFunctions() string {
-	var sql strings.Builder
-
-	sql.WriteString("-- CUID generation functions\n")
-	sql.WriteString("-- These functions provide collision-resistant unique identifier generation\n\n")
-
-	// Enable pgcrypto extension for digest function
-	sql.WriteString("-- Enable pgcrypto extension for cryptographic functions\n")
-	sql.WriteString("CREATE EXTENSION IF NOT EXISTS pgcrypto;\n\n")
-
-	// Create sequence for CUID counter
-	sql.WriteString("-- Create sequence for CUID counter if it doesn't exist\n")
-	sql.WriteString("CREATE SEQUENCE IF NOT EXISTS cuid_counter_seq;\n\n")
-
-	// Base36 encoding function
-	sql.WriteString("-- Base36 encoding function for CUID generation\n")
-	sql.WriteString("CREATE OR REPLACE FUNCTION to_base36(num BIGINT) RETURNS TEXT AS $$\n")
-	sql.WriteString("DECLARE\n")
-	sql.WriteString("    v_base36 TEXT := '0123456789abcdefghijklmnopqrstuvwxyz';\n")
-	sql.WriteString("    v_result TEXT := '';\n")
-	sql.WriteString("    v_remainder INT;\n")
-	sql.WriteString("BEGIN\n")
-	sql.WriteString("    IF num = 0 THEN\n")
-	sql.WriteString("        RETURN '0';\n")
-	sql.WriteString("    END IF;\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    WHILE num > 0 LOOP\n")
-	sql.WriteString("        v_remainder := num % 36;\n")
-	sql.WriteString("        v_result := substr(v_base36, v_remainder + 1, 1) || v_result;\n")
-	sql.WriteString("        num := num / 36;\n")
-	sql.WriteString("    END LOOP;\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    RETURN v_result;\n")
-	sql.WriteString("END;\n")
-	sql.WriteString("$$ LANGUAGE plpgsql IMMUTABLE;\n\n")
-
-	// Main CUID generation function
-	sql.WriteString("-- Production-ready CUID generator function\n")
-	sql.WriteString("CREATE OR REPLACE FUNCTION gen_cuid() RETURNS CHAR(25) AS $$\n")
-	sql.WriteString("DECLARE\n")
-	sql.WriteString("    v_timestamp BIGINT;\n")
-	sql.WriteString("    v_counter BIGINT;\n")
-	sql.WriteString("    v_fingerprint TEXT;\n")
-	sql.WriteString("    v_random TEXT;\n")
-	sql.WriteString("    v_result TEXT := 'c';\n")
-	sql.WriteString("BEGIN\n")
-	sql.WriteString("    v_timestamp := FLOOR(EXTRACT(EPOCH FROM clock_timestamp()) * 1000);\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    v_counter := nextval('cuid_counter_seq');\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    -- Handle potential NULL from inet_server_addr()\n")
-	sql.WriteString("    v_fingerprint := encode(digest(current_database() || COALESCE(inet_server_addr()::TEXT, 'localhost'), 'sha256'), 'hex');\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    v_result := v_result || lpad(to_base36(v_timestamp), 8, '0');\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    v_result := v_result || lpad(to_base36(v_counter % 1679616), 4, '0');\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    v_result := v_result || substr(v_fingerprint, 1, 4);\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    v_random := encode(gen_random_bytes(6), 'hex');\n")
-	sql.WriteString("    v_result := v_result || substr(v_random, 1, 8);\n")
-	sql.WriteString("    \n")
-	sql.WriteString("    RETURN v_result;\n")
-	sql.WriteString("END;\n")
-	sql.WriteString("$$ LANGUAGE plpgsql VOLATILE;\n\n")
-
-	return sql.String()
+// executeStatements runs statements one by one outside a transaction, so that one
+// that gave up on a lock can be retried on its own
+func executeStatements(ctx context.Context, db *sql.DB, statements []string, lockRetry LockRetry) error {
+	for i, stmt := range statements {
+		fmt.Printf("Executing statement %d/%d...\n", i+1, len(statements))
+		retry := lockRetry
+		if retry.OnRetry == nil {
+			retry.OnRetry = func(attempt int, wait time.Duration, err error) {
+				fmt.Printf("Statement %d/%d could not get its lock (attempt %d), retrying in %s...\n", i+1, len(statements), attempt, wait)
+			}
+		}
+		err := retry.DoStatement(ctx, stmt, func() error {
+			_, err := db.ExecContext(ctx, stmt)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute statement %d: %s\nError: %w", i+1, stmt, err)
+		}
+	}
+	return nil
 }
 
+const vectorExtensionSQL = "CREATE EXTENSION IF NOT EXISTS vector;\n"
+
 // ensureDatabaseExists creates the database if it doesn't exist
 func (m *AtlasMigrator) ensureDatabaseExists(ctx context.Context) error {
 	dbName := extractDatabaseName(m.config.URL)
@@ -342,7 +442,8 @@ func (m *AtlasMigrator) ensureDatabaseExists(ctx context.Context) error {
 	adminURL := buildAdminDatabaseURL(m.config.URL)
 
 	// Connect to admin database
-	adminConfig := NewDBConfig(adminURL)
+	adminConfig := *m.config
+	adminConfig.URL = adminURL
 	adminDB, err := adminConfig.Connect(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to connect to admin database: %w", err)
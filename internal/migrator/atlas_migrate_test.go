@@ -201,3 +201,22 @@ func validateOptions(opts *MigrationOptions) error {
 
 	return nil
 }
+
+func TestNeedsVectorExtension(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected bool
+	}{
+		{`ALTER TABLE "items" ADD COLUMN "embedding" vector(1536) NOT NULL`, true},
+		{`CREATE TABLE "items" ("id" integer NOT NULL, "embedding" public.vector(3) NULL)`, true},
+		{`CREATE INDEX "idx" ON "items" USING hnsw ("embedding" vector_l2_ops)`, true},
+		{`ALTER TABLE "posts" ADD COLUMN "vector" text NOT NULL`, false},
+		{`ALTER TABLE "posts" ADD COLUMN "search_vector" tsvector NULL`, false},
+	}
+
+	for _, tt := range tests {
+		if got := needsVectorExtension([]string{tt.sql}); got != tt.expected {
+			t.Errorf("needsVectorExtension(%q) = %v, want %v", tt.sql, got, tt.expected)
+		}
+	}
+}
@@ -21,27 +21,49 @@ func min(a, b int) int {
 }
 
 func GenerateAtlasSQL(ctx context.Context, driver migrate.Driver, changes []schema.Change) ([]string, error) {
+	statements, _, err := generateAtlasStatements(ctx, driver, changes)
+	return statements, err
+}
+
+// generateAtlasStatements plans changes and returns each statement alongside the statement
+// Atlas would run to reverse it, or "" when Atlas has none
+func generateAtlasStatements(ctx context.Context, driver migrate.Driver, changes []schema.Change) ([]string, []string, error) {
 
 	plan, err := driver.PlanChanges(ctx, "", changes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate plan: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate plan: %w", err)
 	}
 
 	statements := make([]string, len(plan.Changes))
+	reverses := make([]string, len(plan.Changes))
 	for i, change := range plan.Changes {
 		statements[i] = change.Cmd
 		if change.Comment != "" {
 			statements[i] = fmt.Sprintf("-- %s\n%s", change.Comment, change.Cmd)
 		}
+		if reverse, ok := change.Reverse.(string); ok {
+			reverses[i] = reverse
+		}
 	}
 
-	return statements, nil
+	return statements, reverses, nil
 }
 
 // SimplifiedAtlasMigrator provides a simpler Atlas-based migration
 type SimplifiedAtlasMigrator struct {
-	config        *DBConfig
-	tempDBManager *TempDBManager
+	config           *DBConfig
+	tempDBManager    *TempDBManager
+	dataPreservation DataPreservation
+	retention        time.Duration
+	concurrent       bool
+	concurrentNames  map[string]bool // Indexes declared concurrent in the models
+	steps            []migrationStep
+	reversals        map[string]string
+	warnings         []string
+	ignore           IgnoreRules
+	touchUpdatedAt   bool
+	safeConstraints  bool
+	idFunctions      []idFunction // ID functions to create or update before the other statements
 }
 
 func NewSimplifiedAtlasMigrator(config *DBConfig) *SimplifiedAtlasMigrator {
@@ -51,6 +73,46 @@ func NewSimplifiedAtlasMigrator(config *DBConfig) *SimplifiedAtlasMigrator {
 	}
 }
 
+// SetDataPreservation makes dropped tables and columns keep their data, see DataPreservation
+func (m *SimplifiedAtlasMigrator) SetDataPreservation(mode DataPreservation) {
+	m.dataPreservation = mode
+}
+
+// SetConcurrentIndexes makes index changes on existing tables use CREATE/DROP INDEX CONCURRENTLY
+func (m *SimplifiedAtlasMigrator) SetConcurrentIndexes(enabled bool) {
+	m.concurrent = enabled
+}
+
+// SetConcurrentIndexNames makes the named indexes use CREATE INDEX CONCURRENTLY when
+// they are added to or rebuilt on existing tables, whatever SetConcurrentIndexes says
+func (m *SimplifiedAtlasMigrator) SetConcurrentIndexNames(names []string) {
+	m.concurrentNames = make(map[string]bool, len(names))
+	for _, name := range names {
+		m.concurrentNames[name] = true
+	}
+}
+
+// SetSafeConstraints makes constraints added to existing tables skip the locking table
+// scan, see planSafeConstraints
+func (m *SimplifiedAtlasMigrator) SetSafeConstraints(enabled bool) {
+	m.safeConstraints = enabled
+}
+
+// SetIgnoreRules leaves the objects managed by extensions and other tools out of migrations
+func (m *SimplifiedAtlasMigrator) SetIgnoreRules(rules IgnoreRules) {
+	m.ignore = rules
+}
+
+// SetUpdatedAtTriggers makes the database set updated_at columns with BEFORE UPDATE triggers
+func (m *SimplifiedAtlasMigrator) SetUpdatedAtTriggers(enabled bool) {
+	m.touchUpdatedAt = enabled
+}
+
+// SetRetentionPeriod sets how long preserved tables and columns are kept before being dropped
+func (m *SimplifiedAtlasMigrator) SetRetentionPeriod(retention time.Duration) {
+	m.retention = retention
+}
+
 func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, sourceDB *sql.DB, targetDDL string, createDBIfNotExists bool) (upSQL []string, changes []schema.Change, err error) {
 
 	var currentRealm *schema.Realm
@@ -84,15 +146,28 @@ func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, s
 	}
 	defer cleanup()
 
-	// Check if DDL uses CUID functions and create them in temp DB if needed
-	if strings.Contains(targetDDL, "gen_cuid()") {
-		logger.Atlas().Debug("DDL uses CUID functions, creating them in temp database")
-		cuidSQL := generateCUIDFunctions()
-		if _, err = tempDB.ExecContext(ctx, cuidSQL); err != nil {
-			logger.Atlas().Error("Failed to create CUID functions: %v", err)
-			return nil, nil, fmt.Errorf("failed to create CUID functions in temp database: %w", err)
+	// Create the ID functions column defaults call, such as gen_cuid(), in the temp DB
+	usedFunctions := usedIDFunctions(targetDDL)
+	if len(usedFunctions) > 0 {
+		logger.Atlas().Debug("DDL uses ID functions %s, creating them in temp database", idFunctionNames(usedFunctions))
+	}
+	for _, stmt := range idFunctionStatements(usedFunctions) {
+		if _, err = tempDB.ExecContext(ctx, stmt); err != nil {
+			logger.Atlas().Error("Failed to create ID functions: %v", err)
+			return nil, nil, fmt.Errorf("failed to create ID functions in temp database: %w", err)
 		}
-		logger.Atlas().Debug("CUID functions created successfully")
+	}
+	m.idFunctions = usedFunctions
+	if !createDBIfNotExists && len(usedFunctions) > 0 {
+		installed, err := installedIDFunctions(ctx, sourceDB)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.idFunctions = outdatedIDFunctions(usedFunctions, installed)
+	}
+
+	if err = CheckServerFeatures(ctx, tempDB, targetDDL); err != nil {
+		return nil, nil, err
 	}
 
 	logger.Atlas().Debug("Executing DDL in temp database, DDL length: %d", len(targetDDL))
@@ -116,6 +191,38 @@ func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, s
 		return nil, nil, fmt.Errorf("failed to inspect target schema: %w", err)
 	}
 
+	targetOptions, err := indexStorageOptions(ctx, tempDB)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetStorage, err := inspectColumnStorage(ctx, tempDB)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentOptions := map[string]string{}
+	currentStorage := map[string]columnStorage{}
+	inheritance := map[string]*inheritedTable{}
+	references := map[string][]indexReference{}
+	triggers := updatedAtTriggers{Tables: map[string]bool{}, Functions: map[string]bool{}}
+	if !createDBIfNotExists {
+		if currentOptions, err = indexStorageOptions(ctx, sourceDB); err != nil {
+			return nil, nil, err
+		}
+		if currentStorage, err = inspectColumnStorage(ctx, sourceDB); err != nil {
+			return nil, nil, err
+		}
+		if references, err = inspectIndexReferences(ctx, sourceDB); err != nil {
+			return nil, nil, err
+		}
+		if inheritance, err = inspectInheritance(ctx, sourceDB); err != nil {
+			return nil, nil, err
+		}
+		// Read even when the option is off, so that its triggers are dropped
+		if triggers, err = inspectUpdatedAtTriggers(ctx, sourceDB); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Use target driver for diff calculation when createDBIfNotExists is true
 	var diffDriver migrate.Driver = targetDriver
 	if !createDBIfNotExists {
@@ -132,13 +239,65 @@ func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, s
 		return nil, nil, fmt.Errorf("failed to calculate diff: %w", err)
 	}
 
-	if len(changes) == 0 {
-		return []string{}, changes, nil
+	changes = filterEquivalentCheckChanges(changes)
+	changes = filterIgnoredChanges(changes, m.ignore)
+	changes = filterInheritedChanges(changes, inheritance)
+	changes, enumWarnings := filterUnsafeEnumChanges(changes)
+	changes, indexWarnings := keepReferencedIndexes(changes, references)
+
+	var dropFKs, addFKs []schema.Change
+	dropFKs, changes, addFKs, m.warnings = planPrimaryKeyChanges(currentRealm, targetRealm, changes)
+	m.warnings = append(append(m.warnings, enumWarnings...), indexWarnings...)
+	changes, renames := planForeignKeyRenames(changes)
+	changes, preserved := planDataPreservation(changes, m.dataPreservation, m.retention, time.Now())
+	m.steps = append(renames, preserved...)
+	m.steps = append(m.steps, planUpdatedAtTriggers(targetRealm, triggers, m.touchUpdatedAt)...)
+	m.steps = append(m.steps, planColumnStorage(targetRealm, targetStorage, currentStorage)...)
+
+	if m.safeConstraints && !createDBIfNotExists {
+		version, err := serverVersionNum(ctx, sourceDB)
+		if err != nil {
+			return nil, nil, err
+		}
+		var validations, fkValidations []migrationStep
+		changes, validations = planSafeConstraints(changes, version)
+		addFKs, fkValidations = planSafeConstraints(addFKs, version)
+		m.steps = append(append(m.steps, validations...), fkValidations...)
 	}
 
-	upSQL, err = GenerateAtlasSQL(ctx, diffDriver, changes)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate SQL: %w", err)
+	if m.concurrent {
+		m.warnings = append(m.warnings, useConcurrentIndexes(changes, nil)...)
+	} else if len(m.concurrentNames) > 0 {
+		m.warnings = append(m.warnings, useConcurrentIndexes(changes, m.concurrentNames)...)
+	}
+
+	// Foreign keys depending on a changed primary key are dropped before and added after it
+	upSQL = []string{}
+	var reverses []string
+	for _, phase := range [][]schema.Change{dropFKs, changes, addFKs} {
+		if len(phase) == 0 {
+			continue
+		}
+		statements, phaseReverses, err := generateAtlasStatements(ctx, diffDriver, phase)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate SQL: %w", err)
+		}
+		upSQL = append(upSQL, withIndexStorageOptions(statements, targetOptions)...)
+		reverses = append(reverses, withIndexStorageOptions(phaseReverses, currentOptions)...)
+	}
+	m.reversals = indexReversals(upSQL, reverses)
+	for up, down := range constraintReversals(upSQL, reverses) {
+		m.reversals[up] = down
+	}
+	for up, down := range enumReversals(upSQL, reverses) {
+		m.reversals[up] = down
+	}
+	changes = append(append(dropFKs, changes...), addFKs...)
+
+	// Renames and preserved drops run last so Atlas has already removed indexes and
+	// foreign keys on the dropped objects
+	for _, step := range m.steps {
+		upSQL = append(upSQL, step.UpSQL())
 	}
 
 	return upSQL, changes, nil
@@ -146,7 +305,8 @@ func (m *SimplifiedAtlasMigrator) GenerateMigrationSimple(ctx context.Context, s
 
 func IsDestructiveChange(change schema.Change) bool {
 	switch change.(type) {
-	case *schema.DropTable, *schema.DropColumn, *schema.DropIndex, *schema.DropForeignKey:
+	case *schema.DropTable, *schema.DropColumn, *schema.DropIndex, *schema.DropForeignKey,
+		*schema.DropPrimaryKey, *schema.ModifyPrimaryKey:
 		return true
 	case *schema.ModifyTable:
 
@@ -178,10 +338,18 @@ func DescribeChange(change schema.Change) string {
 		return fmt.Sprintf("Add index %s", c.I.Name)
 	case *schema.DropIndex:
 		return fmt.Sprintf("Drop index %s", c.I.Name)
+	case *schema.ModifyIndex:
+		return fmt.Sprintf("Rebuild index %s", c.To.Name)
 	case *schema.AddForeignKey:
 		return fmt.Sprintf("Add foreign key %s", c.F.Symbol)
 	case *schema.DropForeignKey:
 		return fmt.Sprintf("Drop foreign key %s", c.F.Symbol)
+	case *schema.AddPrimaryKey:
+		return "Add primary key"
+	case *schema.DropPrimaryKey:
+		return "Drop primary key"
+	case *schema.ModifyPrimaryKey:
+		return "Change primary key"
 	default:
 		return fmt.Sprintf("Change type %T", change)
 	}
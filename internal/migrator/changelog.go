@@ -0,0 +1,185 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MigrationMeta is what storm migrate knows about a migration it generated. It is
+// written to <version>.meta.json next to the .up.sql file, so that the changelog
+// does not depend on the comments of the SQL, which may be edited.
+type MigrationMeta struct {
+	Changes     []string `json:"changes"`               // Descriptions of the statements, in order
+	Warnings    []string `json:"warnings,omitempty"`    // Warnings about the changes, such as table rewrites
+	Destructive []string `json:"destructive,omitempty"` // Changes that lose data
+	ModelsHash  string   `json:"models_hash,omitempty"` // Hash of the models the migration brings the database to
+}
+
+// migrationMetaPath returns the path of the metadata of version in dir
+func migrationMetaPath(dir, version string) string {
+	return filepath.Join(dir, version+".meta.json")
+}
+
+// WriteMigrationMeta writes the metadata of the migration version to dir
+func WriteMigrationMeta(dir, version string, meta MigrationMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode migration metadata: %w", err)
+	}
+	if err := os.WriteFile(migrationMetaPath(dir, version), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write migration metadata: %w", err)
+	}
+	return nil
+}
+
+// ReadMigrationMeta reads the metadata of the migration version in dir, or returns
+// nil when it has none, as for hand-written migrations
+func ReadMigrationMeta(dir, version string) (*MigrationMeta, error) {
+	data, err := os.ReadFile(migrationMetaPath(dir, version))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration metadata: %w", err)
+	}
+
+	var meta MigrationMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse migration metadata of %s: %w", version, err)
+	}
+	return &meta, nil
+}
+
+// ChangelogEntry is a migration file as the changelog lists it
+type ChangelogEntry struct {
+	Version    string
+	Changes    []string   // Descriptions of the generated statements
+	Warnings   []string   // WARNING comments of the migration
+	Statements int        // Number of statements of the up file
+	Release    string     // First release tag holding the migration, empty while unreleased
+	AppliedAt  *time.Time // When the database applied it, nil while pending
+}
+
+// ReadChangelogEntries summarizes the .up.sql files of dir in version order. The
+// changes and warnings come from the metadata storm migrate writes next to the
+// migrations it generates, so hand-written migrations only list their number of
+// statements.
+func ReadChangelogEntries(dir string) ([]ChangelogEntry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob migration files: %w", err)
+	}
+
+	var entries []ChangelogEntry
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file: %w", err)
+		}
+
+		entry := ChangelogEntry{
+			Version:    strings.TrimSuffix(filepath.Base(file), ".up.sql"),
+			Statements: len(SplitStatements(string(content))),
+		}
+		meta, err := ReadMigrationMeta(dir, entry.Version)
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			entry.Changes = meta.Changes
+			entry.Warnings = meta.Warnings
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ReleaseTags maps the versions of the migration files in dir to the first git tag,
+// by creation date, whose tree holds their up file. Versions no tag holds are left out.
+func ReleaseTags(ctx context.Context, dir string) (map[string]string, error) {
+	output, err := exec.CommandContext(ctx, "git", "-C", dir, "tag", "--sort=creatordate").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list git tags: %w", err)
+	}
+
+	releases := make(map[string]string)
+	for _, tag := range strings.Fields(string(output)) {
+		// -z leaves the names unquoted and separates them with NUL, so that names
+		// with spaces or special characters come through as they are
+		files, err := exec.CommandContext(ctx, "git", "-C", dir, "ls-tree", "-r", "-z", "--name-only", tag, ".").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the migrations of tag %s: %w", tag, err)
+		}
+		for _, file := range strings.Split(string(files), "\x00") {
+			if !strings.HasSuffix(file, ".up.sql") || strings.Contains(file, "/") {
+				continue
+			}
+			version := strings.TrimSuffix(file, ".up.sql")
+			if _, ok := releases[version]; !ok {
+				releases[version] = tag
+			}
+		}
+	}
+	return releases, nil
+}
+
+// WriteChangelog renders entries as Markdown, grouped by release with the unreleased
+// migrations and the newest release first. The status of each migration is only
+// shown when withStatus is set, as it needs the migrations table of a database.
+func WriteChangelog(w io.Writer, entries []ChangelogEntry, withStatus bool) error {
+	var releases []string
+	grouped := make(map[string][]ChangelogEntry)
+	for _, entry := range entries {
+		if _, ok := grouped[entry.Release]; !ok {
+			releases = append(releases, entry.Release)
+		}
+		grouped[entry.Release] = append(grouped[entry.Release], entry)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Migration Changelog\n")
+	if unreleased, ok := grouped[""]; ok {
+		writeChangelogRelease(&b, "Unreleased", unreleased, withStatus)
+	}
+	for i := len(releases) - 1; i >= 0; i-- {
+		if release := releases[i]; release != "" {
+			writeChangelogRelease(&b, release, grouped[release], withStatus)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeChangelogRelease renders the migrations of one release
+func writeChangelogRelease(b *strings.Builder, release string, entries []ChangelogEntry, withStatus bool) {
+	b.WriteString("\n## " + release + "\n")
+	for _, entry := range entries {
+		b.WriteString("\n### " + entry.Version)
+		if withStatus {
+			if entry.AppliedAt != nil {
+				b.WriteString(" (applied " + entry.AppliedAt.UTC().Format("2006-01-02") + ")")
+			} else {
+				b.WriteString(" (pending)")
+			}
+		}
+		b.WriteString("\n\n")
+
+		if len(entry.Changes) == 0 && entry.Statements > 0 {
+			fmt.Fprintf(b, "- %d hand-written statements\n", entry.Statements)
+		}
+		for _, change := range entry.Changes {
+			b.WriteString("- " + change + "\n")
+		}
+		for _, warning := range entry.Warnings {
+			b.WriteString("- **Warning:** " + warning + "\n")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadChangelogEntries(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"20240201000000_add_tags.up.sql": `-- Statement 1: Edited by hand
+CREATE TABLE "tags" ("id" text NOT NULL, PRIMARY KEY ("id"));
+
+-- Statement 2: Add column "tag_id" to table "posts"
+ALTER TABLE "posts" ADD COLUMN "tag_id" text NULL;
+`,
+		"20240301000000_backfill.up.sql": "UPDATE posts SET slug = id;\nDELETE FROM tags WHERE id = '';",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := MigrationMeta{
+		Changes:  []string{`Create table "tags"`, `Add column "tag_id" to table "posts"`},
+		Warnings: []string{"Primary key of orders changes: rewrites the table"},
+	}
+	if err := WriteMigrationMeta(dir, "20240201000000_add_tags", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadChangelogEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+
+	generated := entries[0]
+	if generated.Statements != 2 || !reflect.DeepEqual(generated.Changes, meta.Changes) || !reflect.DeepEqual(generated.Warnings, meta.Warnings) {
+		t.Errorf("expected the changes and warnings of the metadata, got %+v", generated)
+	}
+
+	handWritten := entries[1]
+	if len(handWritten.Changes) != 0 || handWritten.Statements != 2 {
+		t.Errorf("expected a hand-written migration to only count its statements, got %+v", handWritten)
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	applied := time.Date(2024, 2, 3, 12, 0, 0, 0, time.UTC)
+	entries := []ChangelogEntry{
+		{Version: "20240101000000_create_users", Changes: []string{`Create table "users"`}, Release: "v1.0.0", AppliedAt: &applied},
+		{Version: "20240201000000_add_tags", Changes: []string{`Create table "tags"`}, Warnings: []string{"rewrites the table"}, Release: "v1.1.0", AppliedAt: &applied},
+		{Version: "20240301000000_backfill", Statements: 2},
+		{Version: "20240401000000_empty"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteChangelog(&buf, entries, true); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	unreleased := strings.Index(out, "## Unreleased")
+	newest := strings.Index(out, "## v1.1.0")
+	oldest := strings.Index(out, "## v1.0.0")
+	if unreleased == -1 || newest == -1 || oldest == -1 || !(unreleased < newest && newest < oldest) {
+		t.Fatalf("expected the unreleased migrations and then the releases newest first, got:\n%s", out)
+	}
+	for _, want := range []string{
+		"### 20240101000000_create_users (applied 2024-02-03)",
+		"### 20240301000000_backfill (pending)",
+		"- **Warning:** rewrites the table",
+		"- 2 hand-written statements",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	if strings.Contains(out, "- 0 hand-written statements") {
+		t.Errorf("expected no line for an empty migration, got:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WriteChangelog(&buf, entries, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "(pending)") || strings.Contains(buf.String(), "(applied") {
+		t.Errorf("expected no status without a database, got:\n%s", buf.String())
+	}
+}
+
+func TestReleaseTags(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	dir := filepath.Join(repo, "migrations")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	commit := func(version string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, version+".up.sql"), []byte("SELECT 1;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", ".")
+		git("commit", "-q", "-m", version)
+	}
+
+	git("init", "-q")
+	commit("20240101000000_create_users")
+	commit("20240115000000_rename \"notes\"")
+	git("tag", "v1.0.0")
+	commit("20240201000000_add_tags")
+
+	releases, err := ReleaseTags(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releases["20240101000000_create_users"] != "v1.0.0" {
+		t.Errorf("expected the first migration in v1.0.0, got %q", releases["20240101000000_create_users"])
+	}
+	if releases[`20240115000000_rename "notes"`] != "v1.0.0" {
+		t.Errorf("expected the migration with a quoted name in v1.0.0, got %v", releases)
+	}
+	if release, ok := releases["20240201000000_add_tags"]; ok {
+		t.Errorf("expected the untagged migration to be unreleased, got %q", release)
+	}
+}
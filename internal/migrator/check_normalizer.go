@@ -0,0 +1,400 @@
+package migrator
+
+import (
+	"strings"
+	"unicode"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+// NormalizeCheckExpr reduces a CHECK expression to a canonical form so that an
+// expression written in a model and the same expression read back through
+// pg_get_constraintdef compare equal. It lowercases keywords and identifiers,
+// drops type casts and redundant parentheses, and rewrites
+// "= ANY (ARRAY[...])" as "IN (...)". String literals are left untouched.
+func NormalizeCheckExpr(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if len(expr) > 5 && strings.EqualFold(expr[:5], "check") && (expr[5] == '(' || unicode.IsSpace(rune(expr[5]))) {
+		expr = strings.TrimSpace(expr[5:])
+	}
+
+	tokens := tokenizeCheckExpr(expr)
+	tokens = stripCasts(tokens)
+	tokens = rewriteArrayComparisons(tokens)
+	tokens = stripRedundantParens(tokens)
+
+	return strings.Join(tokens, " ")
+}
+
+// CheckExprsEqual reports whether two CHECK expressions are equivalent after normalization
+func CheckExprsEqual(a, b string) bool {
+	return a == b || NormalizeCheckExpr(a) == NormalizeCheckExpr(b)
+}
+
+// filterEquivalentCheckChanges removes check changes whose expressions only differ in
+// formatting. Atlas compares CHECK expressions as raw strings, so without this every
+// migration would drop and re-add checks whose text PostgreSQL rewrote.
+func filterEquivalentCheckChanges(changes []schema.Change) []schema.Change {
+	filtered := make([]schema.Change, 0, len(changes))
+
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			filtered = append(filtered, change)
+			continue
+		}
+
+		modify.Changes = filterTableCheckChanges(modify.Changes)
+		if len(modify.Changes) > 0 {
+			filtered = append(filtered, modify)
+		}
+	}
+
+	return filtered
+}
+
+func filterTableCheckChanges(changes []schema.Change) []schema.Change {
+	var drops []*schema.DropCheck
+	var adds []*schema.AddCheck
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.DropCheck:
+			drops = append(drops, c)
+		case *schema.AddCheck:
+			adds = append(adds, c)
+		}
+	}
+
+	// An unnamed check whose expression changed formatting shows up as a drop/add pair
+	skip := make(map[schema.Change]bool)
+	for _, drop := range drops {
+		for _, add := range adds {
+			if skip[add] || (drop.C.Name != "" && add.C.Name != "" && drop.C.Name != add.C.Name) {
+				continue
+			}
+			if CheckExprsEqual(drop.C.Expr, add.C.Expr) {
+				skip[drop] = true
+				skip[add] = true
+				break
+			}
+		}
+	}
+
+	filtered := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		if skip[change] {
+			continue
+		}
+		if modify, ok := change.(*schema.ModifyCheck); ok && CheckExprsEqual(modify.From.Expr, modify.To.Expr) {
+			continue
+		}
+		filtered = append(filtered, change)
+	}
+
+	return filtered
+}
+
+func tokenizeCheckExpr(expr string) []string {
+	var tokens []string
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '\'':
+			j := i + 1
+			for j < len(runes) {
+				if runes[j] == '\'' {
+					if j+1 < len(runes) && runes[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			end := min(j+1, len(runes))
+			tokens = append(tokens, string(runes[i:end]))
+			i = end
+
+		case r == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				j++
+			}
+			end := min(j+1, len(runes))
+			ident := string(runes[i+1 : min(j, len(runes))])
+			if isSimpleIdentifier(ident) {
+				tokens = append(tokens, ident)
+			} else {
+				tokens = append(tokens, string(runes[i:end]))
+			}
+			i = end
+
+		case r == '(' || r == ')' || r == ',' || r == '[' || r == ']':
+			tokens = append(tokens, string(r))
+			i++
+
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, "::")
+			i += 2
+
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '.' || runes[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(string(runes[i:j])))
+			i = j
+
+		default:
+			j := i
+			for j < len(runes) && strings.ContainsRune("<>=!~+-*/%|&^#@?", runes[j]) {
+				j++
+			}
+			if j == i {
+				j = i + 1
+			}
+			tokens = append(tokens, normalizeOperator(string(runes[i:j])))
+			i = j
+		}
+	}
+
+	return tokens
+}
+
+func normalizeOperator(op string) string {
+	if op == "!=" {
+		return "<>"
+	}
+	return op
+}
+
+func isSimpleIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// castTypeWords are words that may continue a multi-word type name after "::"
+var castTypeWords = map[string]bool{
+	"varying": true, "precision": true, "with": true, "without": true, "time": true, "zone": true,
+}
+
+// stripCasts removes "::type" casts, including multi-word, sized and array types
+func stripCasts(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] != "::" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		i++
+		for i+1 < len(tokens) && castTypeWords[tokens[i+1]] {
+			i++
+		}
+		if i+1 < len(tokens) && tokens[i+1] == "(" {
+			for i < len(tokens) && tokens[i] != ")" {
+				i++
+			}
+		}
+		for i+2 < len(tokens) && tokens[i+1] == "[" && tokens[i+2] == "]" {
+			i += 2
+		}
+	}
+
+	return out
+}
+
+// rewriteArrayComparisons turns "= ANY (ARRAY[a, b])" into "IN (a, b)" and
+// "<> ALL (ARRAY[a, b])" into "NOT IN (a, b)", which is how PostgreSQL stores IN lists
+func rewriteArrayComparisons(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+
+	for i := 0; i < len(tokens); i++ {
+		replacement := ""
+		switch {
+		case tokens[i] == "=" && i+1 < len(tokens) && tokens[i+1] == "any":
+			replacement = "in"
+		case tokens[i] == "<>" && i+1 < len(tokens) && tokens[i+1] == "all":
+			replacement = "not in"
+		}
+
+		if replacement == "" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		// PostgreSQL may wrap the array in extra parentheses, e.g. ANY ((ARRAY[...])::text[])
+		open := i + 2
+		for open < len(tokens) && tokens[open] == "(" {
+			open++
+		}
+		wrapping := open - (i + 2)
+
+		if wrapping == 0 || open+1 >= len(tokens) || tokens[open] != "array" || tokens[open+1] != "[" {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		end := -1
+		depth := 0
+		for j := open + 1; j < len(tokens); j++ {
+			if tokens[j] == "[" {
+				depth++
+			} else if tokens[j] == "]" {
+				depth--
+				if depth == 0 {
+					end = j
+					break
+				}
+			}
+		}
+
+		closed := end != -1 && end+wrapping < len(tokens)
+		for k := 1; closed && k <= wrapping; k++ {
+			closed = tokens[end+k] == ")"
+		}
+		if !closed {
+			out = append(out, tokens[i])
+			continue
+		}
+
+		out = append(out, strings.Fields(replacement)...)
+		out = append(out, "(")
+		out = append(out, tokens[open+2:end]...)
+		out = append(out, ")")
+		i = end + wrapping
+	}
+
+	return out
+}
+
+// stripRedundantParens repeatedly removes parentheses that wrap a single token, or that
+// stand between boolean operators where operator precedence makes them meaningless
+func stripRedundantParens(tokens []string) []string {
+	for {
+		start, end := findRedundantParens(tokens)
+		if start == -1 {
+			return tokens
+		}
+		stripped := make([]string, 0, len(tokens)-2)
+		stripped = append(stripped, tokens[:start]...)
+		stripped = append(stripped, tokens[start+1:end]...)
+		stripped = append(stripped, tokens[end+1:]...)
+		tokens = stripped
+	}
+}
+
+func findRedundantParens(tokens []string) (int, int) {
+	for start, token := range tokens {
+		if token != "(" {
+			continue
+		}
+
+		end := matchingParen(tokens, start)
+		if end == -1 {
+			continue
+		}
+
+		var prev, next string
+		if start > 0 {
+			prev = tokens[start-1]
+		}
+		if end+1 < len(tokens) {
+			next = tokens[end+1]
+		}
+
+		// Parentheses directly after a word belong to a function call or IN list
+		if prev != "" && prev != "(" && prev != "," && isWordToken(prev) && !isBooleanKeyword(prev) {
+			continue
+		}
+
+		inner := tokens[start+1 : end]
+		if len(inner) == 1 && inner[0] != "," {
+			return start, end
+		}
+
+		if hasTopLevel(inner, ",") || !isBooleanBoundary(prev) || !isBooleanBoundary(next) {
+			continue
+		}
+
+		switch {
+		case hasTopLevel(inner, "or"):
+			// OR binds loosest, so only an OR chain or the whole expression can absorb it
+			if (prev == "" || prev == "(" || prev == "or") && (next == "" || next == ")" || next == "or") {
+				return start, end
+			}
+		case hasTopLevel(inner, "and"):
+			if prev != "not" {
+				return start, end
+			}
+		default:
+			return start, end
+		}
+	}
+
+	return -1, -1
+}
+
+func matchingParen(tokens []string, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func hasTopLevel(tokens []string, target string) bool {
+	depth := 0
+	for _, token := range tokens {
+		switch token {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		default:
+			if depth == 0 && token == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isWordToken(token string) bool {
+	if token == "" || strings.HasPrefix(token, "'") {
+		return false
+	}
+	r := []rune(token)[0]
+	return unicode.IsLetter(r) || r == '_' || r == '"'
+}
+
+func isBooleanKeyword(token string) bool {
+	return token == "and" || token == "or" || token == "not"
+}
+
+func isBooleanBoundary(token string) bool {
+	return token == "" || token == "(" || token == ")" || isBooleanKeyword(token)
+}
@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+func TestCheckExprsEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected bool
+	}{
+		{"identical", "age > 0", "age > 0", true},
+		{"deparsed parentheses", "age > 0", "(age > 0)", true},
+		{"check prefix", "CHECK ((age > 0))", "age > 0", true},
+		{"numeric cast", "price > 0", "(price > (0)::numeric)", true},
+		{"boolean chain", "price > 0 AND discount <= price", "((price > (0)::numeric) AND (discount <= price))", true},
+		{"in list", "status IN ('active', 'inactive')", "((status)::text = ANY ((ARRAY['active'::character varying, 'inactive'::character varying])::text[]))", true},
+		{"not in list", "status NOT IN ('a', 'b')", "(status <> ALL (ARRAY['a'::text, 'b'::text]))", true},
+		{"keyword case", "email like '%@%'", "(email LIKE '%@%'::text)", true},
+		{"not equal operator", "a != b", "(a <> b)", true},
+		{"quoted identifier", `"age" >= 18`, "age >= 18", true},
+		{"function call", "length(name) > 0", "(length(name) > 0)", true},
+		{"or inside and", "(a > 0 OR b > 0) AND c > 0", "(((a > 0) OR (b > 0)) AND (c > 0))", true},
+		{"identifier starting with check", "check_in < check_out", "(check_in < check_out)", true},
+		{"different literal case", "status = 'Active'", "status = 'active'", false},
+		{"different operator", "age > 0", "age >= 0", false},
+		{"precedence preserved", "(a > 0 OR b > 0) AND c > 0", "a > 0 OR b > 0 AND c > 0", false},
+		{"not over and preserved", "NOT (a AND b)", "NOT a AND b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := CheckExprsEqual(tt.a, tt.b); result != tt.expected {
+				t.Errorf("CheckExprsEqual(%q, %q) = %v, want %v (normalized %q vs %q)",
+					tt.a, tt.b, result, tt.expected, NormalizeCheckExpr(tt.a), NormalizeCheckExpr(tt.b))
+			}
+		})
+	}
+}
+
+func TestFilterEquivalentCheckChanges(t *testing.T) {
+	table := &schema.Table{Name: "products"}
+
+	changes := []schema.Change{
+		&schema.AddTable{T: &schema.Table{Name: "orders"}},
+		&schema.ModifyTable{T: table, Changes: []schema.Change{
+			&schema.ModifyCheck{
+				From: &schema.Check{Name: "ck_price", Expr: "(price > (0)::numeric)"},
+				To:   &schema.Check{Name: "ck_price", Expr: "price > 0"},
+			},
+			&schema.DropCheck{C: &schema.Check{Expr: "(qty > 0)"}},
+			&schema.AddCheck{C: &schema.Check{Expr: "qty > 0"}},
+		}},
+		&schema.ModifyTable{T: table, Changes: []schema.Change{
+			&schema.ModifyCheck{
+				From: &schema.Check{Name: "ck_discount", Expr: "(discount >= 0)"},
+				To:   &schema.Check{Name: "ck_discount", Expr: "discount > 0"},
+			},
+			&schema.AddColumn{C: &schema.Column{Name: "sku"}},
+		}},
+	}
+
+	filtered := filterEquivalentCheckChanges(changes)
+
+	if len(filtered) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(filtered))
+	}
+
+	if _, ok := filtered[0].(*schema.AddTable); !ok {
+		t.Errorf("expected AddTable to be kept, got %T", filtered[0])
+	}
+
+	modify, ok := filtered[1].(*schema.ModifyTable)
+	if !ok {
+		t.Fatalf("expected ModifyTable, got %T", filtered[1])
+	}
+	if len(modify.Changes) != 2 {
+		t.Fatalf("expected real check change and column addition to be kept, got %d changes", len(modify.Changes))
+	}
+	if _, ok := modify.Changes[0].(*schema.ModifyCheck); !ok {
+		t.Errorf("expected ModifyCheck to be kept, got %T", modify.Changes[0])
+	}
+}
+
+func FuzzNormalizeCheckExpr(f *testing.F) {
+	for _, seed := range []string{
+		"", "(", ")", "::", "'", `"`, "= ANY (", "= ANY ((ARRAY[", "x::character varying(",
+		"CHECK ((status)::text = ANY ((ARRAY['a'::character varying])::text[]))",
+		"((a > 0) OR (b > 0)) AND NOT (c)",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, expr string) {
+		normalized := NormalizeCheckExpr(expr)
+		if again := NormalizeCheckExpr(expr); again != normalized {
+			t.Fatalf("normalization of %q is not deterministic: %q vs %q", expr, normalized, again)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/introspect"
+)
+
+// columnStorage is how a column stores large values, where it differs from the defaults
+type columnStorage struct {
+	Storage     string // Storage strategy, empty when the column keeps TypeStorage
+	TypeStorage string // Storage strategy of the column's type
+	Compression string // Compression method, empty for the server's default
+}
+
+// inspectColumnStorage reads the columns whose storage strategy or compression method
+// was changed from the default, keyed by schema qualified table and column name.
+// Atlas does not inspect either.
+func inspectColumnStorage(ctx context.Context, db *sql.DB) (map[string]columnStorage, error) {
+	// attcompression is new in PostgreSQL 14, read through to_jsonb for older servers
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, a.attname, a.attstorage::text, t.typstorage::text,
+			COALESCE(to_jsonb(a) ->> 'attcompression', '')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'
+			AND (a.attstorage <> t.typstorage OR COALESCE(to_jsonb(a) ->> 'attcompression', '') <> '')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column storage: %w", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]columnStorage)
+	for rows.Next() {
+		var schemaName, table, column, storage, typeStorage, compression string
+		if err := rows.Scan(&schemaName, &table, &column, &storage, &typeStorage, &compression); err != nil {
+			return nil, fmt.Errorf("failed to read column storage: %w", err)
+		}
+		setting := columnStorage{
+			TypeStorage: introspect.StorageMode(typeStorage),
+			Compression: introspect.CompressionMethod(compression),
+		}
+		if storage != typeStorage {
+			setting.Storage = introspect.StorageMode(storage)
+		}
+		settings[schemaName+"."+table+"."+column] = setting
+	}
+	return settings, rows.Err()
+}
+
+// planColumnStorage returns the steps giving the columns of target the storage
+// strategy and compression method they have in the target database, which holds
+// wanted, when the current database, holding current, differs. Columns losing their
+// setting return to the default, and each step restores the previous setting.
+func planColumnStorage(target *schema.Realm, wanted, current map[string]columnStorage) []migrationStep {
+	var steps []migrationStep
+	for _, s := range target.Schemas {
+		for _, table := range s.Tables {
+			for _, column := range table.Columns {
+				key := qualifiedName(table) + "." + column.Name
+				want, have := wanted[key], current[key]
+
+				var up, down []string
+				alter := func(setting, value string) string {
+					return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET %s %s",
+						quotedTable(table), quoteIdentifier(column.Name), setting, value)
+				}
+				if want.Storage != have.Storage {
+					up = append(up, alter("STORAGE", storageOrDefault(want.Storage, have.TypeStorage)))
+					down = append(down, alter("STORAGE", storageOrDefault(have.Storage, want.TypeStorage)))
+				}
+				if want.Compression != have.Compression {
+					up = append(up, alter("COMPRESSION", compressionOrDefault(want.Compression)))
+					down = append(down, alter("COMPRESSION", compressionOrDefault(have.Compression)))
+				}
+				if len(up) == 0 {
+					continue
+				}
+
+				steps = append(steps, migrationStep{
+					Description: fmt.Sprintf("Change storage of %s", key),
+					Up:          up,
+					Down:        down,
+				})
+			}
+		}
+	}
+	return steps
+}
+
+// storageOrDefault returns the storage strategy to set, or that of the column's
+// type when storage is empty
+func storageOrDefault(storage, typeStorage string) string {
+	if storage == "" {
+		storage = typeStorage
+	}
+	if storage == "" {
+		return "DEFAULT"
+	}
+	return strings.ToUpper(storage)
+}
+
+func compressionOrDefault(compression string) string {
+	if compression == "" {
+		return "DEFAULT"
+	}
+	return compression
+}
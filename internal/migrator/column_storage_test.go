@@ -0,0 +1,76 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPlanColumnStorage(t *testing.T) {
+	billing := schema.New("billing")
+	billing.AddTables(schema.NewTable("documents").AddColumns(
+		schema.NewStringColumn("title", "text"),
+		schema.NewStringColumn("body", "text"),
+		schema.NewStringColumn("summary", "text"),
+	))
+	target := schema.NewRealm(billing)
+
+	wanted := map[string]columnStorage{
+		"billing.documents.body":  {Storage: "external", TypeStorage: "extended", Compression: "lz4"},
+		"billing.documents.title": {TypeStorage: "extended", Compression: "lz4"},
+	}
+	current := map[string]columnStorage{
+		"billing.documents.title":   {TypeStorage: "extended", Compression: "lz4"},
+		"billing.documents.summary": {Storage: "main", TypeStorage: "extended"},
+		"billing.documents.dropped": {Storage: "plain", TypeStorage: "extended"},
+	}
+
+	steps := planColumnStorage(target, wanted, current)
+
+	expected := []struct{ up, down string }{
+		{
+			`ALTER TABLE "billing"."documents" ALTER COLUMN "body" SET STORAGE EXTERNAL;
+ALTER TABLE "billing"."documents" ALTER COLUMN "body" SET COMPRESSION lz4`,
+			`ALTER TABLE "billing"."documents" ALTER COLUMN "body" SET STORAGE EXTENDED;
+ALTER TABLE "billing"."documents" ALTER COLUMN "body" SET COMPRESSION DEFAULT`,
+		},
+		{
+			`ALTER TABLE "billing"."documents" ALTER COLUMN "summary" SET STORAGE EXTENDED`,
+			`ALTER TABLE "billing"."documents" ALTER COLUMN "summary" SET STORAGE MAIN`,
+		},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d: %+v", len(expected), len(steps), steps)
+	}
+	for i, want := range expected {
+		if steps[i].UpSQL() != want.up || steps[i].DownSQL() != want.down {
+			t.Errorf("step %d: got up %q down %q", i, steps[i].UpSQL(), steps[i].DownSQL())
+		}
+	}
+}
+
+func TestInspectColumnStorage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM pg_attribute").WillReturnRows(
+		sqlmock.NewRows([]string{"nspname", "relname", "attname", "attstorage", "typstorage", "attcompression"}).
+			AddRow("public", "documents", "body", "e", "x", "l").
+			AddRow("public", "documents", "title", "x", "x", "p"))
+
+	settings, err := inspectColumnStorage(context.Background(), db)
+	if err != nil {
+		t.Fatalf("inspectColumnStorage() error = %v", err)
+	}
+	if got := settings["public.documents.body"]; got != (columnStorage{Storage: "external", TypeStorage: "extended", Compression: "lz4"}) {
+		t.Errorf("unexpected body storage %+v", got)
+	}
+	if got := settings["public.documents.title"]; got != (columnStorage{TypeStorage: "extended", Compression: "pglz"}) {
+		t.Errorf("unexpected title storage %+v", got)
+	}
+}
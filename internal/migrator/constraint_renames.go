@@ -0,0 +1,102 @@
+package migrator
+
+import (
+	"fmt"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+// planForeignKeyRenames finds foreign keys that Atlas drops and adds again although only
+// their name changed, typically after a new naming convention was configured, and
+// returns renames for them instead. Renaming keeps the constraint and skips the full
+// table scan that validating a new foreign key costs.
+func planForeignKeyRenames(changes []schema.Change) ([]schema.Change, []migrationStep) {
+	var steps []migrationStep
+	remaining := make([]schema.Change, 0, len(changes))
+
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			remaining = append(remaining, change)
+			continue
+		}
+
+		renamed := make(map[schema.Change]bool)
+		for _, sub := range modify.Changes {
+			drop, ok := sub.(*schema.DropForeignKey)
+			if !ok {
+				continue
+			}
+			for _, candidate := range modify.Changes {
+				add, ok := candidate.(*schema.AddForeignKey)
+				if !ok || renamed[add] || add.F.Symbol == drop.F.Symbol || !sameForeignKey(drop.F, add.F) {
+					continue
+				}
+				renamed[drop], renamed[add] = true, true
+				steps = append(steps, renameForeignKey(modify.T.Name, drop.F.Symbol, add.F.Symbol))
+				break
+			}
+		}
+
+		if len(renamed) == 0 {
+			remaining = append(remaining, modify)
+			continue
+		}
+
+		kept := make([]schema.Change, 0, len(modify.Changes))
+		for _, sub := range modify.Changes {
+			if !renamed[sub] {
+				kept = append(kept, sub)
+			}
+		}
+		modify.Changes = kept
+		if len(kept) > 0 {
+			remaining = append(remaining, modify)
+		}
+	}
+
+	return remaining, steps
+}
+
+// sameForeignKey reports whether two foreign keys are identical apart from their names
+func sameForeignKey(a, b *schema.ForeignKey) bool {
+	if a.RefTable == nil || b.RefTable == nil || a.RefTable.Name != b.RefTable.Name || schemaName(a.RefTable) != schemaName(b.RefTable) {
+		return false
+	}
+	if referenceAction(a.OnDelete) != referenceAction(b.OnDelete) || referenceAction(a.OnUpdate) != referenceAction(b.OnUpdate) {
+		return false
+	}
+	return sameNames(columnNames(a.Columns), columnNames(b.Columns)) &&
+		sameNames(columnNames(a.RefColumns), columnNames(b.RefColumns))
+}
+
+func referenceAction(action schema.ReferenceOption) schema.ReferenceOption {
+	if action == "" {
+		return schema.NoAction
+	}
+	return action
+}
+
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func renameForeignKey(table, from, to string) migrationStep {
+	return migrationStep{
+		Description: fmt.Sprintf("Rename foreign key %s to %s", from, to),
+		Up: []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", quoteIdentifier(table), quoteIdentifier(from), quoteIdentifier(to)),
+		},
+		Down: []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", quoteIdentifier(table), quoteIdentifier(to), quoteIdentifier(from)),
+		},
+	}
+}
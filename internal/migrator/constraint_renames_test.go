@@ -0,0 +1,71 @@
+package migrator
+
+import (
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+func renameFixture(symbol string, onDelete schema.ReferenceOption) (*schema.Table, *schema.ForeignKey) {
+	public := schema.New("public")
+	id := schema.NewIntColumn("id", "integer")
+	users := schema.NewTable("users").SetSchema(public).AddColumns(id)
+
+	userID := schema.NewIntColumn("user_id", "integer")
+	orders := schema.NewTable("orders").SetSchema(public).AddColumns(userID)
+	fk := schema.NewForeignKey(symbol).AddColumns(userID).SetRefTable(users).AddRefColumns(id).SetOnDelete(onDelete)
+	orders.AddForeignKeys(fk)
+	return orders, fk
+}
+
+func TestPlanForeignKeyRenames(t *testing.T) {
+	orders, old := renameFixture("orders_user_id_fkey", schema.Cascade)
+	_, renamed := renameFixture("fk_orders_user_id", schema.Cascade)
+	added := schema.NewStringColumn("note", "text")
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{
+			&schema.DropForeignKey{F: old},
+			&schema.AddColumn{C: added},
+			&schema.AddForeignKey{F: renamed},
+		}},
+	}
+
+	remaining, steps := planForeignKeyRenames(changes)
+	if len(steps) != 1 {
+		t.Fatalf("expected 1 rename, got %d", len(steps))
+	}
+	if got := steps[0].UpSQL(); got != `ALTER TABLE "orders" RENAME CONSTRAINT "orders_user_id_fkey" TO "fk_orders_user_id"` {
+		t.Errorf("unexpected up SQL: %s", got)
+	}
+	if got := steps[0].DownSQL(); got != `ALTER TABLE "orders" RENAME CONSTRAINT "fk_orders_user_id" TO "orders_user_id_fkey"` {
+		t.Errorf("unexpected down SQL: %s", got)
+	}
+
+	if len(remaining) != 1 {
+		t.Fatalf("expected the table modification to be kept, got %d changes", len(remaining))
+	}
+	if modify := remaining[0].(*schema.ModifyTable); len(modify.Changes) != 1 || modify.Changes[0].(*schema.AddColumn).C != added {
+		t.Errorf("expected only the added column to remain, got %#v", modify.Changes)
+	}
+}
+
+func TestPlanForeignKeyRenames_DefinitionChanged(t *testing.T) {
+	orders, old := renameFixture("orders_user_id_fkey", schema.Cascade)
+	_, changed := renameFixture("fk_orders_user_id", schema.Restrict)
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{
+			&schema.DropForeignKey{F: old},
+			&schema.AddForeignKey{F: changed},
+		}},
+	}
+
+	remaining, steps := planForeignKeyRenames(changes)
+	if len(steps) != 0 {
+		t.Errorf("expected a foreign key with a different action to be recreated, got %d renames", len(steps))
+	}
+	if len(remaining[0].(*schema.ModifyTable).Changes) != 2 {
+		t.Error("expected the drop and add to be kept")
+	}
+}
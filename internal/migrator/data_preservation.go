@@ -0,0 +1,302 @@
+package migrator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/logger"
+)
+
+// DataPreservation controls what happens to the data held by dropped tables and columns
+type DataPreservation string
+
+const (
+	// PreserveNone drops tables and columns outright
+	PreserveNone DataPreservation = ""
+	// PreserveRename renames dropped tables and columns with a _deprecated_<timestamp>_ prefix
+	PreserveRename DataPreservation = "rename"
+	// PreserveArchive copies dropped tables and columns into _archive_<timestamp>_ tables before dropping them
+	PreserveArchive DataPreservation = "archive"
+)
+
+// preservationStampFormat is the timestamp embedded in the names of preserved objects
+const preservationStampFormat = "20060102150405"
+
+// preservedPrefixes are the name prefixes given to renamed and archived objects
+var preservedPrefixes = []string{"_deprecated_", "_archive_"}
+
+// ParseDataPreservation validates a data preservation mode name
+func ParseDataPreservation(mode string) (DataPreservation, error) {
+	switch DataPreservation(strings.ToLower(strings.TrimSpace(mode))) {
+	case PreserveNone, "none":
+		return PreserveNone, nil
+	case PreserveRename:
+		return PreserveRename, nil
+	case PreserveArchive:
+		return PreserveArchive, nil
+	default:
+		return PreserveNone, fmt.Errorf("unknown data preservation mode '%s' (expected rename or archive)", mode)
+	}
+}
+
+// ParseRetentionPeriod parses how long preserved tables and columns are kept before a
+// later migration drops them. It accepts Go durations ("72h") and whole days ("30d").
+func ParseRetentionPeriod(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	var retention time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention period '%s': %w", value, err)
+		}
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid retention period '%s': %w", value, err)
+		}
+		retention = parsed
+	}
+
+	if retention < 0 {
+		return 0, fmt.Errorf("invalid retention period '%s': must not be negative", value)
+	}
+	return retention, nil
+}
+
+// migrationStep is a change planned by storm instead of Atlas, such as a DROP replaced with
+// statements that keep the data around, together with the statements that undo it in
+// the down migration
+type migrationStep struct {
+	Description string
+	Up          []string
+	Down        []string
+}
+
+// UpSQL joins the up statements into a single migration statement
+func (s migrationStep) UpSQL() string {
+	return strings.Join(s.Up, ";\n")
+}
+
+// DownSQL joins the restore statements into a single migration statement
+func (s migrationStep) DownSQL() string {
+	return strings.Join(s.Down, ";\n")
+}
+
+// planDataPreservation removes table and column drops from changes and returns the
+// steps that preserve their data instead. Objects preserved by an earlier migration are
+// left alone until retention has elapsed, at which point their drop goes through; a
+// retention of zero keeps them forever. A retention without a mode soft drops: objects
+// are renamed now and dropped by the first migration generated after the period.
+func planDataPreservation(changes []schema.Change, mode DataPreservation, retention time.Duration, now time.Time) ([]schema.Change, []migrationStep) {
+	if mode == PreserveNone {
+		if retention <= 0 {
+			return changes, nil
+		}
+		mode = PreserveRename
+	}
+
+	stamp := now.UTC().Format(preservationStampFormat)
+	var steps []migrationStep
+	remaining := make([]schema.Change, 0, len(changes))
+
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.DropTable:
+			if preserved, expired := retentionState(c.T.Name, retention, now); preserved {
+				if expired {
+					logger.Atlas().Info("Retention period of %s has elapsed, dropping it", c.T.Name)
+					remaining = append(remaining, c)
+				}
+				continue
+			}
+			steps = append(steps, preserveTable(c.T.Name, mode, stamp))
+
+		case *schema.ModifyTable:
+			kept := make([]schema.Change, 0, len(c.Changes))
+			for _, sub := range c.Changes {
+				drop, ok := sub.(*schema.DropColumn)
+				if !ok {
+					kept = append(kept, sub)
+					continue
+				}
+				if preserved, expired := retentionState(drop.C.Name, retention, now); preserved {
+					if expired {
+						logger.Atlas().Info("Retention period of %s.%s has elapsed, dropping it", c.T.Name, drop.C.Name)
+						kept = append(kept, sub)
+					}
+					continue
+				}
+				steps = append(steps, preserveColumn(c.T, drop.C, mode, stamp))
+			}
+			c.Changes = kept
+			if len(kept) > 0 {
+				remaining = append(remaining, c)
+			}
+
+		default:
+			remaining = append(remaining, change)
+		}
+	}
+
+	return remaining, steps
+}
+
+// retentionState reports whether name belongs to an object preserved by an earlier
+// migration and, if so, whether its retention period has elapsed
+func retentionState(name string, retention time.Duration, now time.Time) (preserved, expired bool) {
+	at, ok := preservedAt(name)
+	if !ok {
+		return false, false
+	}
+	return true, retention > 0 && now.Sub(at) >= retention
+}
+
+// preservedAt extracts the time an object was preserved from its name
+func preservedAt(name string) (time.Time, bool) {
+	for _, prefix := range preservedPrefixes {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || len(rest) < len(preservationStampFormat) {
+			continue
+		}
+		at, err := time.Parse(preservationStampFormat, rest[:len(preservationStampFormat)])
+		if err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func preserveTable(table string, mode DataPreservation, stamp string) migrationStep {
+	if mode == PreserveArchive {
+		archive := preservedName("_archive_", stamp, table)
+		return migrationStep{
+			Description: fmt.Sprintf("Archive and drop table %s", table),
+			Up: []string{
+				fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL)", quoteIdentifier(archive), quoteIdentifier(table)),
+				fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", quoteIdentifier(archive), quoteIdentifier(table)),
+				fmt.Sprintf("DROP TABLE %s", quoteIdentifier(table)),
+			},
+			Down: []string{
+				fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL)", quoteIdentifier(table), quoteIdentifier(archive)),
+				fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", quoteIdentifier(table), quoteIdentifier(archive)),
+				fmt.Sprintf("DROP TABLE %s", quoteIdentifier(archive)),
+			},
+		}
+	}
+
+	deprecated := preservedName("_deprecated_", stamp, table)
+	return migrationStep{
+		Description: fmt.Sprintf("Rename dropped table %s to %s", table, deprecated),
+		Up:          []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdentifier(table), quoteIdentifier(deprecated))},
+		Down:        []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoteIdentifier(deprecated), quoteIdentifier(table))},
+	}
+}
+
+func preserveColumn(table *schema.Table, column *schema.Column, mode DataPreservation, stamp string) migrationStep {
+	tableName := quoteIdentifier(table.Name)
+	notNull := column.Type != nil && !column.Type.Null
+
+	if mode == PreserveArchive {
+		keys := primaryKeyColumns(table, column.Name)
+		if len(keys) == 0 {
+			logger.Atlas().Warn("Table %s has no primary key to archive column %s by, renaming it instead", table.Name, column.Name)
+		} else {
+			return archiveColumn(table.Name, column, keys, notNull, stamp)
+		}
+	}
+
+	deprecated := preservedName("_deprecated_", stamp, column.Name)
+	step := migrationStep{
+		Description: fmt.Sprintf("Rename dropped column %s.%s to %s", table.Name, column.Name, deprecated),
+		Up: []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", tableName, quoteIdentifier(column.Name), quoteIdentifier(deprecated)),
+		},
+	}
+
+	// A deprecated NOT NULL column would reject every insert that no longer sets it
+	if notNull {
+		step.Up = append(step.Up, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", tableName, quoteIdentifier(deprecated)))
+		step.Down = append(step.Down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", tableName, quoteIdentifier(deprecated)))
+	}
+	step.Down = append(step.Down, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", tableName, quoteIdentifier(deprecated), quoteIdentifier(column.Name)))
+
+	return step
+}
+
+func archiveColumn(table string, column *schema.Column, keys []string, notNull bool, stamp string) migrationStep {
+	archive := preservedName("_archive_", stamp, table+"_"+column.Name)
+	tableName := quoteIdentifier(table)
+	columnName := quoteIdentifier(column.Name)
+
+	quotedKeys := make([]string, len(keys))
+	joins := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = quoteIdentifier(key)
+		joins[i] = fmt.Sprintf("t.%s = a.%s", quoteIdentifier(key), quoteIdentifier(key))
+	}
+
+	step := migrationStep{
+		Description: fmt.Sprintf("Archive and drop column %s.%s", table, column.Name),
+		Up: []string{
+			fmt.Sprintf("CREATE TABLE %s AS SELECT %s, %s FROM %s", quoteIdentifier(archive), strings.Join(quotedKeys, ", "), columnName, tableName),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", tableName, columnName),
+		},
+		Down: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, columnName, columnTypeSQL(column)),
+			fmt.Sprintf("UPDATE %s AS t SET %s = a.%s FROM %s AS a WHERE %s", tableName, columnName, columnName, quoteIdentifier(archive), strings.Join(joins, " AND ")),
+		},
+	}
+
+	if notNull {
+		step.Down = append(step.Down, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", tableName, columnName))
+	}
+	step.Down = append(step.Down, fmt.Sprintf("DROP TABLE %s", quoteIdentifier(archive)))
+
+	return step
+}
+
+// primaryKeyColumns returns the primary key columns of table, or nil if it has none or the
+// key includes the column being dropped
+func primaryKeyColumns(table *schema.Table, dropped string) []string {
+	if table.PrimaryKey == nil {
+		return nil
+	}
+
+	var keys []string
+	for _, part := range table.PrimaryKey.Parts {
+		if part.C == nil || part.C.Name == dropped {
+			return nil
+		}
+		keys = append(keys, part.C.Name)
+	}
+	return keys
+}
+
+func columnTypeSQL(column *schema.Column) string {
+	if column.Type == nil {
+		return "text"
+	}
+	if column.Type.Raw != "" {
+		return column.Type.Raw
+	}
+	if formatted, err := postgres.FormatType(column.Type.Type); err == nil {
+		return formatted
+	}
+	return "text"
+}
+
+// preservedName builds prefix+stamp+"_"+name, truncated to PostgreSQL's identifier limit so
+// the down migration refers to the same name the database stored
+func preservedName(prefix, stamp, name string) string {
+	return generator.TruncateIdentifier(prefix + stamp + "_" + name)
+}
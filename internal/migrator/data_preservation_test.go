@@ -0,0 +1,253 @@
+package migrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+var preservationNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func preservationFixture() []schema.Change {
+	id := &schema.Column{Name: "id", Type: &schema.ColumnType{Raw: "uuid"}}
+	legacy := &schema.Column{Name: "legacy_code", Type: &schema.ColumnType{Raw: "character varying(20)", Null: false}}
+	users := &schema.Table{
+		Name:       "users",
+		Columns:    []*schema.Column{id},
+		PrimaryKey: &schema.Index{Parts: []*schema.IndexPart{{C: id}}},
+	}
+
+	return []schema.Change{
+		&schema.DropTable{T: &schema.Table{Name: "sessions"}},
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.DropColumn{C: legacy},
+			&schema.AddColumn{C: &schema.Column{Name: "code"}},
+		}},
+		&schema.AddTable{T: &schema.Table{Name: "teams"}},
+	}
+}
+
+func TestPlanDataPreservation_None(t *testing.T) {
+	changes := preservationFixture()
+
+	remaining, steps := planDataPreservation(changes, PreserveNone, 0, preservationNow)
+	if len(steps) != 0 {
+		t.Errorf("expected no preservation steps, got %d", len(steps))
+	}
+	if !reflect.DeepEqual(remaining, changes) {
+		t.Error("expected changes to be returned untouched")
+	}
+}
+
+func TestPlanDataPreservation_Rename(t *testing.T) {
+	remaining, steps := planDataPreservation(preservationFixture(), PreserveRename, 0, preservationNow)
+
+	if len(remaining) != 2 {
+		t.Fatalf("expected the drops to be removed from the changes, got %d changes", len(remaining))
+	}
+	modify := remaining[0].(*schema.ModifyTable)
+	if len(modify.Changes) != 1 {
+		t.Errorf("expected only the column addition to remain, got %d", len(modify.Changes))
+	}
+
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 preservation steps, got %d", len(steps))
+	}
+
+	tableStep := steps[0]
+	if tableStep.UpSQL() != `ALTER TABLE "sessions" RENAME TO "_deprecated_20260101000000_sessions"` {
+		t.Errorf("unexpected table up SQL: %s", tableStep.UpSQL())
+	}
+	if tableStep.DownSQL() != `ALTER TABLE "_deprecated_20260101000000_sessions" RENAME TO "sessions"` {
+		t.Errorf("unexpected table down SQL: %s", tableStep.DownSQL())
+	}
+
+	columnStep := steps[1]
+	expectedUp := []string{
+		`ALTER TABLE "users" RENAME COLUMN "legacy_code" TO "_deprecated_20260101000000_legacy_code"`,
+		`ALTER TABLE "users" ALTER COLUMN "_deprecated_20260101000000_legacy_code" DROP NOT NULL`,
+	}
+	expectedDown := []string{
+		`ALTER TABLE "users" ALTER COLUMN "_deprecated_20260101000000_legacy_code" SET NOT NULL`,
+		`ALTER TABLE "users" RENAME COLUMN "_deprecated_20260101000000_legacy_code" TO "legacy_code"`,
+	}
+	if !reflect.DeepEqual(columnStep.Up, expectedUp) {
+		t.Errorf("unexpected column up SQL:\n got: %q\nwant: %q", columnStep.Up, expectedUp)
+	}
+	if !reflect.DeepEqual(columnStep.Down, expectedDown) {
+		t.Errorf("unexpected column down SQL:\n got: %q\nwant: %q", columnStep.Down, expectedDown)
+	}
+}
+
+func TestPlanDataPreservation_Archive(t *testing.T) {
+	_, steps := planDataPreservation(preservationFixture(), PreserveArchive, 0, preservationNow)
+
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 preservation steps, got %d", len(steps))
+	}
+
+	expectedTableUp := []string{
+		`CREATE TABLE "_archive_20260101000000_sessions" (LIKE "sessions" INCLUDING ALL)`,
+		`INSERT INTO "_archive_20260101000000_sessions" SELECT * FROM "sessions"`,
+		`DROP TABLE "sessions"`,
+	}
+	if !reflect.DeepEqual(steps[0].Up, expectedTableUp) {
+		t.Errorf("unexpected table up SQL:\n got: %q\nwant: %q", steps[0].Up, expectedTableUp)
+	}
+	if last := steps[0].Down[len(steps[0].Down)-1]; last != `DROP TABLE "_archive_20260101000000_sessions"` {
+		t.Errorf("expected the archive to be dropped after restoring, got %s", last)
+	}
+
+	expectedColumnUp := []string{
+		`CREATE TABLE "_archive_20260101000000_users_legacy_code" AS SELECT "id", "legacy_code" FROM "users"`,
+		`ALTER TABLE "users" DROP COLUMN "legacy_code"`,
+	}
+	expectedColumnDown := []string{
+		`ALTER TABLE "users" ADD COLUMN "legacy_code" character varying(20)`,
+		`UPDATE "users" AS t SET "legacy_code" = a."legacy_code" FROM "_archive_20260101000000_users_legacy_code" AS a WHERE t."id" = a."id"`,
+		`ALTER TABLE "users" ALTER COLUMN "legacy_code" SET NOT NULL`,
+		`DROP TABLE "_archive_20260101000000_users_legacy_code"`,
+	}
+	if !reflect.DeepEqual(steps[1].Up, expectedColumnUp) {
+		t.Errorf("unexpected column up SQL:\n got: %q\nwant: %q", steps[1].Up, expectedColumnUp)
+	}
+	if !reflect.DeepEqual(steps[1].Down, expectedColumnDown) {
+		t.Errorf("unexpected column down SQL:\n got: %q\nwant: %q", steps[1].Down, expectedColumnDown)
+	}
+}
+
+func TestPlanDataPreservation_ArchiveWithoutPrimaryKeyRenames(t *testing.T) {
+	changes := []schema.Change{
+		&schema.ModifyTable{T: &schema.Table{Name: "events"}, Changes: []schema.Change{
+			&schema.DropColumn{C: &schema.Column{Name: "payload", Type: &schema.ColumnType{Raw: "jsonb", Null: true}}},
+		}},
+	}
+
+	remaining, steps := planDataPreservation(changes, PreserveArchive, 0, preservationNow)
+	if len(remaining) != 0 {
+		t.Errorf("expected the emptied table change to be removed, got %d changes", len(remaining))
+	}
+	if len(steps) != 1 || !strings.Contains(steps[0].UpSQL(), "RENAME COLUMN") {
+		t.Errorf("expected a rename fallback, got %+v", steps)
+	}
+}
+
+func TestPlanDataPreservation_Retention(t *testing.T) {
+	changes := func() []schema.Change {
+		return []schema.Change{
+			&schema.DropTable{T: &schema.Table{Name: "_deprecated_20251201000000_sessions"}},
+			&schema.DropTable{T: &schema.Table{Name: "_archive_20251225000000_audit"}},
+			&schema.ModifyTable{T: &schema.Table{Name: "users"}, Changes: []schema.Change{
+				&schema.DropColumn{C: &schema.Column{Name: "_deprecated_20251201000000_nickname", Type: &schema.ColumnType{Raw: "text", Null: true}}},
+			}},
+		}
+	}
+
+	remaining, steps := planDataPreservation(changes(), PreserveRename, 0, preservationNow)
+	if len(remaining) != 0 || len(steps) != 0 {
+		t.Errorf("expected preserved objects to be kept without a retention period, got %d changes and %d steps", len(remaining), len(steps))
+	}
+
+	remaining, steps = planDataPreservation(changes(), PreserveRename, 14*24*time.Hour, preservationNow)
+	if len(steps) != 0 {
+		t.Errorf("expected preserved objects not to be renamed again, got %d steps", len(steps))
+	}
+	if len(remaining) != 2 {
+		t.Fatalf("expected the expired table and column drops to go through, got %d changes", len(remaining))
+	}
+	if drop := remaining[0].(*schema.DropTable); drop.T.Name != "_deprecated_20251201000000_sessions" {
+		t.Errorf("expected the expired table to be dropped, got %s", drop.T.Name)
+	}
+	if modify := remaining[1].(*schema.ModifyTable); len(modify.Changes) != 1 {
+		t.Errorf("expected the expired column to be dropped, got %d changes", len(modify.Changes))
+	}
+}
+
+func TestPlanDataPreservation_RetentionAloneSoftDrops(t *testing.T) {
+	remaining, steps := planDataPreservation(preservationFixture(), PreserveNone, 7*24*time.Hour, preservationNow)
+
+	if len(remaining) != 2 || len(steps) != 2 {
+		t.Fatalf("expected drops to be replaced by 2 steps, got %d changes and %d steps", len(remaining), len(steps))
+	}
+	if !strings.HasPrefix(steps[0].UpSQL(), `ALTER TABLE "sessions" RENAME TO "_deprecated_`) {
+		t.Errorf("expected the table to be renamed, got %s", steps[0].UpSQL())
+	}
+}
+
+func TestParseRetentionPeriod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"72h", 72 * time.Hour, false},
+		{"-1h", 0, true},
+		{"soon", 0, true},
+		{"xd", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			retention, err := ParseRetentionPeriod(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRetentionPeriod(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if retention != tt.expected {
+				t.Errorf("ParseRetentionPeriod(%q) = %v, want %v", tt.input, retention, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPreservedName(t *testing.T) {
+	name := preservedName("_deprecated_", "20260101000000", strings.Repeat("x", 60))
+	if len(name) != generator.MaxIdentifierLength {
+		t.Errorf("expected name truncated to %d characters, got %d", generator.MaxIdentifierLength, len(name))
+	}
+}
+
+func TestParseDataPreservation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected DataPreservation
+		wantErr  bool
+	}{
+		{"", PreserveNone, false},
+		{"none", PreserveNone, false},
+		{"rename", PreserveRename, false},
+		{" Archive ", PreserveArchive, false},
+		{"copy", PreserveNone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mode, err := ParseDataPreservation(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDataPreservation(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if mode != tt.expected {
+				t.Errorf("ParseDataPreservation(%q) = %q, want %q", tt.input, mode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMigrationReverser_RegisterReversal(t *testing.T) {
+	reverser := NewMigrationReverser()
+	up := `ALTER TABLE "sessions" RENAME TO "_deprecated_20260101000000_sessions"`
+	reverser.RegisterReversal(up, "restore sessions")
+
+	got, err := reverser.ReverseSQL("  " + up + "\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "restore sessions" {
+		t.Errorf("expected registered reversal, got %q", got)
+	}
+}
@@ -4,29 +4,43 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// DefaultApplicationName identifies storm connections in pg_stat_activity
+const DefaultApplicationName = "storm"
+
 type DBConfig struct {
 	URL             string
 	ConnMaxLifetime time.Duration
 	MaxOpenConns    int
 	MaxIdleConns    int
+
+	// Session settings of every connection. A zero timeout leaves the server
+	// default in place, and settings already in URL win.
+	LockTimeout      time.Duration // Fail a statement that waits this long for a lock instead of queueing traffic behind it
+	StatementTimeout time.Duration // Fail a statement that runs this long
+	ApplicationName  string        // Shown in pg_stat_activity and the server logs
 }
 
 func NewDBConfig(url string) *DBConfig {
 	return &DBConfig{
-		URL:             url,
-		ConnMaxLifetime: 10 * time.Minute,
-		MaxOpenConns:    10,
-		MaxIdleConns:    5,
+		URL:              url,
+		ConnMaxLifetime:  10 * time.Minute,
+		MaxOpenConns:     10,
+		MaxIdleConns:     5,
+		LockTimeout:      10 * time.Second,
+		StatementTimeout: 5 * time.Minute,
+		ApplicationName:  DefaultApplicationName,
 	}
 }
 
 func (cfg *DBConfig) Connect(ctx context.Context) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.URL)
+	db, err := sql.Open("postgres", cfg.ConnectionString())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
@@ -40,11 +54,69 @@ func (cfg *DBConfig) Connect(ctx context.Context) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	_, err = db.ExecContext(ctx, "SET statement_timeout = '300s'")
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+	return db, nil
+}
+
+// SessionSetting is a server setting of a session, e.g. lock_timeout
+type SessionSetting struct {
+	Name  string
+	Value string
+}
+
+// SessionSettings returns the settings to start every connection with
+func (cfg *DBConfig) SessionSettings() []SessionSetting {
+	var settings []SessionSetting
+	if cfg.ApplicationName != "" {
+		settings = append(settings, SessionSetting{"application_name", cfg.ApplicationName})
+	}
+	if cfg.LockTimeout > 0 {
+		settings = append(settings, SessionSetting{"lock_timeout", fmt.Sprintf("%dms", cfg.LockTimeout.Milliseconds())})
 	}
+	if cfg.StatementTimeout > 0 {
+		settings = append(settings, SessionSetting{"statement_timeout", fmt.Sprintf("%dms", cfg.StatementTimeout.Milliseconds())})
+	}
+	return settings
+}
 
-	return db, nil
+// ConnectionString returns URL with the session settings added as connection
+// parameters, so that the server applies them to each pooled connection rather
+// than to whichever one runs a SET
+func (cfg *DBConfig) ConnectionString() string {
+	settings := cfg.SessionSettings()
+	if len(settings) == 0 {
+		return cfg.URL
+	}
+
+	if strings.HasPrefix(cfg.URL, "postgres://") || strings.HasPrefix(cfg.URL, "postgresql://") {
+		parsed, err := url.Parse(cfg.URL)
+		if err != nil {
+			// Leave the error to the driver
+			return cfg.URL
+		}
+		query := parsed.Query()
+		for _, setting := range settings {
+			if _, ok := query[setting.Name]; !ok {
+				query.Set(setting.Name, setting.Value)
+			}
+		}
+		parsed.RawQuery = query.Encode()
+		return parsed.String()
+	}
+
+	// key=value connection string
+	present := make(map[string]bool)
+	for _, field := range strings.Fields(cfg.URL) {
+		if key, _, ok := strings.Cut(field, "="); ok {
+			present[strings.TrimSpace(key)] = true
+		}
+	}
+	dsn := strings.TrimSpace(cfg.URL)
+	for _, setting := range settings {
+		if present[setting.Name] {
+			continue
+		}
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(setting.Value)
+		dsn += fmt.Sprintf(" %s='%s'", setting.Name, value)
+	}
+	return strings.TrimSpace(dsn)
 }
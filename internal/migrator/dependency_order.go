@@ -0,0 +1,228 @@
+package migrator
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// statementAction classifies what a migration statement does to the object it names
+type statementAction int
+
+const (
+	actionOther statementAction = iota
+	actionCreate
+	actionDrop
+)
+
+// statementInfo is the dependency information extracted from a single migration statement
+type statementInfo struct {
+	action statementAction
+	kind   string   // table, view, function, trigger, type, index, sequence
+	name   string   // normalized name of the object created, dropped or altered
+	deps   []string // normalized names of the objects the statement relies on
+}
+
+var (
+	createObjectRe = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:UNIQUE\s+)?(?:CONSTRAINT\s+)?(TABLE|MATERIALIZED\s+VIEW|VIEW|FUNCTION|PROCEDURE|TRIGGER|TYPE|INDEX|SEQUENCE)\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	dropObjectRe   = regexp.MustCompile(`(?is)^DROP\s+(TABLE|MATERIALIZED\s+VIEW|VIEW|FUNCTION|PROCEDURE|TRIGGER|TYPE|INDEX|SEQUENCE)\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?([^\s(;,]+)`)
+	alterTableRe   = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?([^\s]+)`)
+	referencesRe   = regexp.MustCompile(`(?is)\bREFERENCES\s+([^\s(]+)`)
+	onTableRe      = regexp.MustCompile(`(?is)\bON\s+(?:ONLY\s+)?([^\s(]+)`)
+	executeFuncRe  = regexp.MustCompile(`(?is)\bEXECUTE\s+(?:FUNCTION|PROCEDURE)\s+([^\s(]+)`)
+	viewSourceRe   = regexp.MustCompile(`(?is)\b(?:FROM|JOIN)\s+([a-zA-Z_"][\w."$]*)`)
+	viewBodyRe     = regexp.MustCompile(`(?is)\bAS\s+(.*)$`)
+)
+
+// dependentDropRank lists the objects whose drops are hoisted to the start of a migration,
+// triggers before views since a trigger may be defined on a view
+var dependentDropRank = map[string]int{
+	"trigger":           0,
+	"materialized view": 1,
+	"view":              1,
+}
+
+// orderChangesForUpMigration returns the indexes of statements in an order where objects
+// are created after the objects they depend on (views after their tables and views,
+// triggers after their functions and tables) and dependent objects are dropped first.
+// Statements with no dependency between them keep their original relative order.
+func orderChangesForUpMigration(statements []string) []int {
+	infos := make([]statementInfo, len(statements))
+	for i, stmt := range statements {
+		infos[i] = analyzeStatement(stmt)
+	}
+
+	// Dependencies declared by objects created in this migration also tell us the order
+	// in which their previous versions must be dropped
+	createdDeps := make(map[string][]string)
+	for _, info := range infos {
+		if info.action == actionCreate {
+			createdDeps[info.name] = append(createdDeps[info.name], info.deps...)
+		}
+	}
+
+	var drops, rest []int
+	for i, info := range infos {
+		if _, dependent := dependentDropRank[info.kind]; dependent && info.action == actionDrop {
+			drops = append(drops, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+
+	// Drop triggers and views up front: a view or trigger would otherwise block
+	// altering or dropping the table or function it depends on
+	sort.SliceStable(drops, func(a, b int) bool {
+		return dependentDropRank[infos[drops[a]].kind] < dependentDropRank[infos[drops[b]].kind]
+	})
+	drops = stableTopoSort(drops, func(before, after int) bool {
+		// A dependent view must be dropped before the view it reads from
+		return containsName(createdDeps[infos[before].name], infos[after].name)
+	})
+
+	rest = stableTopoSort(rest, func(before, after int) bool {
+		if infos[before].action != actionCreate || infos[before].name == "" {
+			return false
+		}
+		return containsName(infos[after].deps, infos[before].name)
+	})
+
+	return append(drops, rest...)
+}
+
+// stableTopoSort orders items so that every pair where mustPrecede(a, b) holds has a before b.
+// Among items that are free to go, the one that appeared first is emitted first. Items that
+// take part in a cycle are emitted in their original order.
+func stableTopoSort(items []int, mustPrecede func(before, after int) bool) []int {
+	inDegree := make([]int, len(items))
+	for i := range items {
+		for j := range items {
+			if i != j && mustPrecede(items[j], items[i]) {
+				inDegree[i]++
+			}
+		}
+	}
+
+	emitted := make([]bool, len(items))
+	result := make([]int, 0, len(items))
+
+	for len(result) < len(items) {
+		next := -1
+		for i := range items {
+			if !emitted[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			for i := range items {
+				if !emitted[i] {
+					next = i
+					break
+				}
+			}
+		}
+
+		emitted[next] = true
+		result = append(result, items[next])
+		for i := range items {
+			if !emitted[i] && mustPrecede(items[next], items[i]) {
+				inDegree[i]--
+			}
+		}
+	}
+
+	return result
+}
+
+// analyzeStatement extracts the object a statement creates, drops or alters and the
+// objects it depends on
+func analyzeStatement(stmt string) statementInfo {
+	stmt = strings.TrimSpace(stripLeadingComments(stmt))
+
+	if m := createObjectRe.FindStringSubmatch(stmt); m != nil {
+		info := statementInfo{
+			action: actionCreate,
+			kind:   normalizeKind(m[1]),
+			name:   normalizeObjectName(m[2]),
+		}
+
+		switch info.kind {
+		case "table":
+			info.deps = captureNames(referencesRe, stmt)
+		case "view", "materialized view":
+			if body := viewBodyRe.FindStringSubmatch(stmt); body != nil {
+				info.deps = captureNames(viewSourceRe, body[1])
+			}
+		case "trigger":
+			info.deps = append(captureNames(onTableRe, stmt), captureNames(executeFuncRe, stmt)...)
+		case "index":
+			info.deps = captureNames(onTableRe, stmt)
+		}
+
+		return info
+	}
+
+	if m := dropObjectRe.FindStringSubmatch(stmt); m != nil {
+		return statementInfo{
+			action: actionDrop,
+			kind:   normalizeKind(m[1]),
+			name:   normalizeObjectName(m[2]),
+		}
+	}
+
+	if m := alterTableRe.FindStringSubmatch(stmt); m != nil {
+		table := normalizeObjectName(m[1])
+		return statementInfo{
+			kind: "table",
+			name: table,
+			deps: append([]string{table}, captureNames(referencesRe, stmt)...),
+		}
+	}
+
+	return statementInfo{}
+}
+
+func captureNames(re *regexp.Regexp, s string) []string {
+	var names []string
+	for _, m := range re.FindAllStringSubmatch(s, -1) {
+		names = append(names, normalizeObjectName(m[1]))
+	}
+	return names
+}
+
+// normalizeObjectName lowercases a possibly quoted, possibly schema-qualified name and
+// drops the default public schema so "public"."Users" and users compare equal
+func normalizeObjectName(name string) string {
+	name = strings.TrimRight(name, ";,")
+	name = strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+	return strings.TrimPrefix(name, "public.")
+}
+
+func normalizeKind(kind string) string {
+	return strings.Join(strings.Fields(strings.ToLower(kind)), " ")
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func stripLeadingComments(stmt string) string {
+	for {
+		stmt = strings.TrimSpace(stmt)
+		if !strings.HasPrefix(stmt, "--") {
+			return stmt
+		}
+		newline := strings.Index(stmt, "\n")
+		if newline == -1 {
+			return ""
+		}
+		stmt = stmt[newline+1:]
+	}
+}
@@ -0,0 +1,114 @@
+package migrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func orderedStatements(statements []string) []string {
+	var ordered []string
+	for _, idx := range orderChangesForUpMigration(statements) {
+		ordered = append(ordered, statements[idx])
+	}
+	return ordered
+}
+
+func TestOrderChangesForUpMigration(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []string
+		expected   []string
+	}{
+		{
+			name: "keeps independent statements in place",
+			statements: []string{
+				`CREATE TABLE "public"."users" ("id" uuid NOT NULL)`,
+				`ALTER TABLE "public"."posts" ADD COLUMN "title" text`,
+				`CREATE INDEX "idx_posts_title" ON "public"."posts" ("title")`,
+			},
+			expected: []string{
+				`CREATE TABLE "public"."users" ("id" uuid NOT NULL)`,
+				`ALTER TABLE "public"."posts" ADD COLUMN "title" text`,
+				`CREATE INDEX "idx_posts_title" ON "public"."posts" ("title")`,
+			},
+		},
+		{
+			name: "creates views after their tables and views",
+			statements: []string{
+				`CREATE VIEW active_admins AS SELECT * FROM active_users WHERE role = 'admin'`,
+				`CREATE VIEW active_users AS SELECT u.* FROM users u JOIN teams t ON t.id = u.team_id WHERE u.active`,
+				`CREATE TABLE "public"."users" ("id" uuid NOT NULL, "team_id" uuid REFERENCES teams(id))`,
+				`CREATE TABLE "public"."teams" ("id" uuid NOT NULL)`,
+			},
+			expected: []string{
+				`CREATE TABLE "public"."teams" ("id" uuid NOT NULL)`,
+				`CREATE TABLE "public"."users" ("id" uuid NOT NULL, "team_id" uuid REFERENCES teams(id))`,
+				`CREATE VIEW active_users AS SELECT u.* FROM users u JOIN teams t ON t.id = u.team_id WHERE u.active`,
+				`CREATE VIEW active_admins AS SELECT * FROM active_users WHERE role = 'admin'`,
+			},
+		},
+		{
+			name: "creates triggers after their functions",
+			statements: []string{
+				`CREATE TRIGGER set_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at()`,
+				`CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $$ LANGUAGE plpgsql`,
+			},
+			expected: []string{
+				`CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$ BEGIN NEW.updated_at = now(); RETURN NEW; END; $$ LANGUAGE plpgsql`,
+				`CREATE TRIGGER set_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch_updated_at()`,
+			},
+		},
+		{
+			name: "drops triggers and views before what they depend on",
+			statements: []string{
+				`DROP FUNCTION touch_updated_at()`,
+				`ALTER TABLE "public"."users" DROP COLUMN "legacy"`,
+				`DROP VIEW active_users`,
+				`DROP VIEW active_admins`,
+				`DROP TRIGGER set_updated_at ON users`,
+				`CREATE VIEW active_users AS SELECT * FROM users`,
+				`CREATE VIEW active_admins AS SELECT * FROM active_users WHERE role = 'admin'`,
+			},
+			expected: []string{
+				`DROP TRIGGER set_updated_at ON users`,
+				`DROP VIEW active_admins`,
+				`DROP VIEW active_users`,
+				`DROP FUNCTION touch_updated_at()`,
+				`ALTER TABLE "public"."users" DROP COLUMN "legacy"`,
+				`CREATE VIEW active_users AS SELECT * FROM users`,
+				`CREATE VIEW active_admins AS SELECT * FROM active_users WHERE role = 'admin'`,
+			},
+		},
+		{
+			name: "falls back to original order on cycles",
+			statements: []string{
+				`CREATE VIEW a AS SELECT * FROM b`,
+				`CREATE VIEW b AS SELECT * FROM a`,
+			},
+			expected: []string{
+				`CREATE VIEW a AS SELECT * FROM b`,
+				`CREATE VIEW b AS SELECT * FROM a`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := orderedStatements(tt.statements)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("unexpected order:\n got: %q\nwant: %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAnalyzeStatement(t *testing.T) {
+	info := analyzeStatement("-- Create \"users\" table\nCREATE MATERIALIZED VIEW IF NOT EXISTS \"public\".\"user_stats\" AS SELECT count(*) FROM \"public\".\"users\"")
+
+	if info.action != actionCreate || info.kind != "materialized view" || info.name != "user_stats" {
+		t.Errorf("unexpected statement info: %+v", info)
+	}
+	if !reflect.DeepEqual(info.deps, []string{"users"}) {
+		t.Errorf("expected dependency on users, got %v", info.deps)
+	}
+}
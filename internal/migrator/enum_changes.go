@@ -0,0 +1,57 @@
+package migrator
+
+import (
+	"strings"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+// filterUnsafeEnumChanges removes the enum type changes PostgreSQL cannot apply in place
+// from changes and returns a warning for each. Values can only be added to an enum
+// type; Atlas would fail on reordered values and silently keep removed ones.
+func filterUnsafeEnumChanges(changes []schema.Change) ([]schema.Change, []string) {
+	var warnings []string
+
+	kept := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		if modify, ok := change.(*schema.ModifyObject); ok {
+			from, isEnum := modify.From.(*schema.EnumType)
+			to, toEnum := modify.To.(*schema.EnumType)
+			if isEnum && toEnum {
+				enums := generator.NewSQLGenerator().GenerateEnumChanges(
+					&generator.DatabaseSchema{EnumTypes: map[string][]string{from.T: from.Values}},
+					&generator.DatabaseSchema{EnumTypes: map[string][]string{to.T: to.Values}},
+				)
+				if len(enums.Unsafe) > 0 {
+					for _, unsafe := range enums.Unsafe {
+						warnings = append(warnings, "Skipped unsafe change to "+unsafe+"; write this migration by hand")
+					}
+					continue
+				}
+			}
+		}
+		kept = append(kept, change)
+	}
+
+	return kept, warnings
+}
+
+// enumReversals maps the statements creating and dropping enum types to the statements
+// Atlas uses to undo them, so that a dropped type comes back with all its values
+func enumReversals(statements, reverses []string) map[string]string {
+	reversals := make(map[string]string)
+
+	for i, stmt := range statements {
+		if i >= len(reverses) || reverses[i] == "" {
+			continue
+		}
+
+		cmd := strings.ToUpper(stripLeadingComments(stmt))
+		if strings.HasPrefix(cmd, "DROP TYPE") || (strings.HasPrefix(cmd, "CREATE TYPE") && strings.Contains(cmd, " AS ENUM")) {
+			reversals[stmt] = reverses[i]
+		}
+	}
+
+	return reversals
+}
@@ -0,0 +1,63 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+func enumChange(from, to []string) *schema.ModifyObject {
+	public := schema.New("public")
+	return &schema.ModifyObject{
+		From: &schema.EnumType{T: "status", Values: from, Schema: public},
+		To:   &schema.EnumType{T: "status", Values: to, Schema: public},
+	}
+}
+
+func TestFilterUnsafeEnumChanges(t *testing.T) {
+	added := enumChange([]string{"active", "inactive"}, []string{"active", "pending", "inactive"})
+	removed := enumChange([]string{"active", "inactive"}, []string{"active"})
+	reordered := enumChange([]string{"active", "inactive"}, []string{"inactive", "active"})
+
+	kept, warnings := filterUnsafeEnumChanges([]schema.Change{added, removed, reordered})
+
+	if len(kept) != 1 || kept[0] != added {
+		t.Errorf("expected only the added value to be kept, got %v", kept)
+	}
+	if len(warnings) != 2 ||
+		!strings.Contains(warnings[0], "removing values (inactive)") ||
+		!strings.Contains(warnings[1], "reordering values") {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+
+	statements, _, err := generateAtlasStatements(context.Background(), planningDriver(t), kept)
+	if err != nil {
+		t.Fatalf("generateAtlasStatements() error = %v", err)
+	}
+	if len(statements) != 1 || !strings.Contains(statements[0], `ADD VALUE 'pending' AFTER 'active'`) {
+		t.Errorf("expected the value to be added in place, got %q", statements)
+	}
+}
+
+func TestEnumReversals(t *testing.T) {
+	enum := &schema.EnumType{T: "status", Values: []string{"active", "inactive"}, Schema: schema.New("public")}
+
+	statements, reverses, err := generateAtlasStatements(context.Background(), planningDriver(t), []schema.Change{&schema.DropObject{O: enum}})
+	if err != nil {
+		t.Fatalf("generateAtlasStatements() error = %v", err)
+	}
+
+	reverser := NewMigrationReverser()
+	for up, down := range enumReversals(statements, reverses) {
+		reverser.RegisterReversal(up, down)
+	}
+	down, err := reverser.ReverseSQL(statements[0])
+	if err != nil {
+		t.Fatalf("ReverseSQL() error = %v", err)
+	}
+	if !strings.Contains(down, `CREATE TYPE "public"."status" AS ENUM ('active', 'inactive')`) {
+		t.Errorf("expected the dropped type to be created again, got %s", down)
+	}
+}
@@ -0,0 +1,250 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// idFunction is a SQL function generating identifiers. Column defaults select it by
+// calling it, e.g. default:gen_ulid(), and migrations create it the first time it is
+// used. Its definition is stamped with a hash in the function comment, so a function
+// created by an older version is replaced when the definition changes.
+type idFunction struct {
+	Name      string   // Function called by column defaults
+	Signature string   // Argument types, as used by COMMENT ON FUNCTION
+	Helpers   []string // Helpers created before it, see idHelpers
+	SQL       string
+}
+
+// idHelpers are the extensions, sequences and functions the ID functions share. Each is
+// emitted once however many functions need it.
+var idHelpers = map[string]string{
+	"pgcrypto": "CREATE EXTENSION IF NOT EXISTS pgcrypto",
+
+	"cuid_counter_seq": "CREATE SEQUENCE IF NOT EXISTS cuid_counter_seq",
+
+	"to_base36": `CREATE OR REPLACE FUNCTION to_base36(num BIGINT) RETURNS TEXT AS $$
+DECLARE
+    v_base36 TEXT := '0123456789abcdefghijklmnopqrstuvwxyz';
+    v_result TEXT := '';
+    v_remainder INT;
+BEGIN
+    IF num = 0 THEN
+        RETURN '0';
+    END IF;
+
+    WHILE num > 0 LOOP
+        v_remainder := num % 36;
+        v_result := substr(v_base36, v_remainder + 1, 1) || v_result;
+        num := num / 36;
+    END LOOP;
+
+    RETURN v_result;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE`,
+}
+
+// idFunctions is the registry of ID functions, in the order they are emitted
+var idFunctions = []idFunction{
+	{
+		Name:    "gen_cuid",
+		Helpers: []string{"pgcrypto", "cuid_counter_seq", "to_base36"},
+		SQL: `CREATE OR REPLACE FUNCTION gen_cuid() RETURNS CHAR(25) AS $$
+DECLARE
+    v_timestamp BIGINT;
+    v_counter BIGINT;
+    v_fingerprint TEXT;
+    v_random TEXT;
+    v_result TEXT := 'c';
+BEGIN
+    v_timestamp := FLOOR(EXTRACT(EPOCH FROM clock_timestamp()) * 1000);
+    v_counter := nextval('cuid_counter_seq');
+
+    -- Handle potential NULL from inet_server_addr()
+    v_fingerprint := encode(digest(current_database() || COALESCE(inet_server_addr()::TEXT, 'localhost'), 'sha256'), 'hex');
+
+    v_result := v_result || lpad(to_base36(v_timestamp), 8, '0');
+    v_result := v_result || lpad(to_base36(v_counter % 1679616), 4, '0');
+    v_result := v_result || substr(v_fingerprint, 1, 4);
+
+    v_random := encode(gen_random_bytes(6), 'hex');
+    v_result := v_result || substr(v_random, 1, 8);
+
+    RETURN v_result;
+END;
+$$ LANGUAGE plpgsql VOLATILE`,
+	},
+	{
+		Name:    "gen_cuid2",
+		Helpers: []string{"pgcrypto", "cuid_counter_seq", "to_base36"},
+		SQL: `CREATE OR REPLACE FUNCTION gen_cuid2() RETURNS VARCHAR(32) AS $$
+DECLARE
+    v_alphabet TEXT := 'abcdefghijklmnopqrstuvwxyz';
+    v_hash TEXT;
+    v_result TEXT;
+BEGIN
+    v_hash := encode(digest(clock_timestamp()::TEXT || nextval('cuid_counter_seq')::TEXT || encode(gen_random_bytes(32), 'hex'), 'sha512'), 'hex');
+    v_result := substr(v_alphabet, get_byte(gen_random_bytes(1), 0) % 26 + 1, 1);
+
+    FOR i IN 0..3 LOOP
+        v_result := v_result || lpad(to_base36(('x' || substr(v_hash, i * 12 + 1, 12))::BIT(48)::BIGINT), 10, '0');
+    END LOOP;
+
+    RETURN substr(v_result, 1, 24);
+END;
+$$ LANGUAGE plpgsql VOLATILE`,
+	},
+	{
+		Name:    "gen_ulid",
+		Helpers: []string{"pgcrypto"},
+		SQL: `CREATE OR REPLACE FUNCTION gen_ulid() RETURNS CHAR(26) AS $$
+DECLARE
+    v_encoding TEXT := '0123456789ABCDEFGHJKMNPQRSTVWXYZ';
+    v_time BYTEA := substring(int8send(FLOOR(EXTRACT(EPOCH FROM clock_timestamp()) * 1000)::BIGINT) FROM 3);
+    v_bits BIT(130);
+    v_result TEXT := '';
+BEGIN
+    v_bits := B'00' || ('x' || encode(v_time || gen_random_bytes(10), 'hex'))::BIT(128);
+
+    FOR i IN 0..25 LOOP
+        v_result := v_result || substr(v_encoding, substring(v_bits FROM i * 5 + 1 FOR 5)::BIT(5)::INT + 1, 1);
+    END LOOP;
+
+    RETURN v_result;
+END;
+$$ LANGUAGE plpgsql VOLATILE`,
+	},
+	{
+		Name:      "gen_nanoid",
+		Signature: "integer",
+		Helpers:   []string{"pgcrypto"},
+		SQL: `CREATE OR REPLACE FUNCTION gen_nanoid(size INT DEFAULT 21) RETURNS TEXT AS $$
+DECLARE
+    v_alphabet TEXT := '_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ';
+    v_bytes BYTEA := gen_random_bytes(size);
+    v_result TEXT := '';
+BEGIN
+    FOR i IN 0..size - 1 LOOP
+        v_result := v_result || substr(v_alphabet, (get_byte(v_bytes, i) & 63) + 1, 1);
+    END LOOP;
+
+    RETURN v_result;
+END;
+$$ LANGUAGE plpgsql VOLATILE`,
+	},
+	{
+		Name:    "gen_uuidv7",
+		Helpers: []string{"pgcrypto"},
+		SQL: `CREATE OR REPLACE FUNCTION gen_uuidv7() RETURNS UUID AS $$
+DECLARE
+    v_bytes BYTEA;
+BEGIN
+    v_bytes := substring(int8send(FLOOR(EXTRACT(EPOCH FROM clock_timestamp()) * 1000)::BIGINT) FROM 3) || gen_random_bytes(10);
+    v_bytes := set_byte(v_bytes, 6, (get_byte(v_bytes, 6) & 15) | 112);
+    v_bytes := set_byte(v_bytes, 8, (get_byte(v_bytes, 8) & 63) | 128);
+
+    RETURN encode(v_bytes, 'hex')::UUID;
+END;
+$$ LANGUAGE plpgsql VOLATILE`,
+	},
+}
+
+// Version is the hash of the function definition stored in its comment
+func (f idFunction) Version() string {
+	sum := sha256.Sum256([]byte(f.SQL))
+	return "storm:" + hex.EncodeToString(sum[:6])
+}
+
+// Statements returns the statements creating the function and stamping its version
+func (f idFunction) Statements() []string {
+	return []string{
+		f.SQL,
+		fmt.Sprintf("COMMENT ON FUNCTION %s(%s) IS '%s'", f.Name, f.Signature, f.Version()),
+	}
+}
+
+// usedIDFunctions returns the ID functions called by the SQL, e.g. in column defaults
+func usedIDFunctions(sql string) []idFunction {
+	lower := strings.ToLower(sql)
+
+	var used []idFunction
+	for _, f := range idFunctions {
+		if strings.Contains(lower, f.Name+"(") {
+			used = append(used, f)
+		}
+	}
+	return used
+}
+
+func idFunctionNames(functions []idFunction) string {
+	names := make([]string, len(functions))
+	for i, f := range functions {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// installedIDFunctions reads the versions of the ID functions in the database, keyed by name.
+// A function created before versions were recorded has an empty version.
+func installedIDFunctions(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	names := make([]string, len(idFunctions))
+	for i, f := range idFunctions {
+		names[i] = f.Name
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT p.proname, COALESCE(obj_description(p.oid, 'pg_proc'), '')
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.proname = ANY($1) AND n.nspname NOT IN ('pg_catalog', 'information_schema')`, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID functions: %w", err)
+	}
+	defer rows.Close()
+
+	installed := make(map[string]string)
+	for rows.Next() {
+		var name, comment string
+		if err := rows.Scan(&name, &comment); err != nil {
+			return nil, fmt.Errorf("failed to read ID functions: %w", err)
+		}
+		installed[name] = comment
+	}
+	return installed, rows.Err()
+}
+
+// outdatedIDFunctions returns the functions that are missing from the database or whose
+// definition changed since they were created
+func outdatedIDFunctions(used []idFunction, installed map[string]string) []idFunction {
+	var outdated []idFunction
+	for _, f := range used {
+		if installed[f.Name] != f.Version() {
+			outdated = append(outdated, f)
+		}
+	}
+	return outdated
+}
+
+// idFunctionStatements returns the statements creating the functions, with each helper
+// they share emitted once
+func idFunctionStatements(functions []idFunction) []string {
+	var statements []string
+	emitted := make(map[string]bool)
+
+	for _, f := range functions {
+		for _, helper := range f.Helpers {
+			if !emitted[helper] {
+				emitted[helper] = true
+				statements = append(statements, idHelpers[helper])
+			}
+		}
+		statements = append(statements, f.Statements()...)
+	}
+	return statements
+}
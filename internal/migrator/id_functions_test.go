@@ -0,0 +1,79 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsedIDFunctions(t *testing.T) {
+	ddl := `CREATE TABLE users (id VARCHAR(32) DEFAULT gen_cuid2() NOT NULL);
+CREATE TABLE events (id UUID DEFAULT GEN_UUIDV7() NOT NULL, slug TEXT DEFAULT gen_nanoid(10));`
+
+	var names []string
+	for _, f := range usedIDFunctions(ddl) {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "gen_cuid2,gen_nanoid,gen_uuidv7" {
+		t.Errorf("unexpected functions %v", names)
+	}
+}
+
+func TestOutdatedIDFunctions(t *testing.T) {
+	used := usedIDFunctions("DEFAULT gen_cuid(), DEFAULT gen_ulid(), DEFAULT gen_uuidv7()")
+	cuid, ulid := used[0], used[1]
+
+	outdated := outdatedIDFunctions(used, map[string]string{
+		cuid.Name: "",             // Created before versions were recorded
+		ulid.Name: ulid.Version(), // Up to date
+	})
+
+	if len(outdated) != 2 || outdated[0].Name != "gen_cuid" || outdated[1].Name != "gen_uuidv7" {
+		t.Errorf("expected gen_cuid to be replaced and gen_uuidv7 created, got %+v", outdated)
+	}
+}
+
+func TestIDFunctionStatements(t *testing.T) {
+	statements := idFunctionStatements(usedIDFunctions("gen_cuid() gen_cuid2() gen_nanoid()"))
+
+	counts := make(map[string]int)
+	for _, stmt := range statements {
+		for _, marker := range []string{"EXTENSION IF NOT EXISTS pgcrypto", "FUNCTION to_base36", "cuid_counter_seq\n", "COMMENT ON FUNCTION"} {
+			if strings.Contains(stmt+"\n", marker) {
+				counts[marker]++
+			}
+		}
+	}
+	if counts["EXTENSION IF NOT EXISTS pgcrypto"] != 1 || counts["FUNCTION to_base36"] != 1 {
+		t.Errorf("expected shared helpers to be emitted once, got %v", counts)
+	}
+	if counts["COMMENT ON FUNCTION"] != 3 {
+		t.Errorf("expected every function to be stamped with its version, got %v", counts)
+	}
+
+	last := statements[len(statements)-1]
+	if !strings.HasPrefix(last, "COMMENT ON FUNCTION gen_nanoid(integer) IS 'storm:") {
+		t.Errorf("expected the comment to use the function signature, got %s", last)
+	}
+}
+
+func TestInstalledIDFunctions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM pg_proc").WillReturnRows(
+		sqlmock.NewRows([]string{"proname", "comment"}).AddRow("gen_cuid", "").AddRow("gen_ulid", "storm:abc"))
+
+	installed, err := installedIDFunctions(context.Background(), db)
+	if err != nil {
+		t.Fatalf("installedIDFunctions() error = %v", err)
+	}
+	if v, ok := installed["gen_cuid"]; !ok || v != "" || installed["gen_ulid"] != "storm:abc" {
+		t.Errorf("unexpected installed functions %v", installed)
+	}
+}
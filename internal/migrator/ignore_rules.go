@@ -0,0 +1,202 @@
+package migrator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/logger"
+)
+
+// IgnoreRules lists database objects that are managed outside storm, by extensions or
+// other schema tools, and are left out of migrations. Patterns use path.Match syntax,
+// so "_timescaledb_*" matches every schema with that prefix.
+type IgnoreRules struct {
+	Extensions []string `yaml:"extensions"` // Ignore the objects these extensions create: postgis, timescaledb
+	Schemas    []string `yaml:"schemas"`    // Schema names
+	Tables     []string `yaml:"tables"`     // Table names, optionally schema qualified
+	Columns    []string `yaml:"columns"`    // table.column
+	Indexes    []string `yaml:"indexes"`    // Index names
+}
+
+// extensionIgnoreRules are the objects well-known extensions create in the databases they are installed in
+var extensionIgnoreRules = map[string]IgnoreRules{
+	"postgis": {
+		Schemas: []string{"tiger", "tiger_data", "topology"},
+		Tables:  []string{"spatial_ref_sys"},
+	},
+	"timescaledb": {
+		Schemas: []string{"_timescaledb_*", "timescaledb_information", "timescaledb_experimental"},
+	},
+}
+
+// Validate checks the extensions are known and every pattern is well-formed
+func (r IgnoreRules) Validate() error {
+	for _, extension := range r.Extensions {
+		if _, ok := extensionIgnoreRules[strings.ToLower(extension)]; !ok {
+			known := make([]string, 0, len(extensionIgnoreRules))
+			for name := range extensionIgnoreRules {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown extension '%s' in ignore rules (expected one of %s)", extension, strings.Join(known, ", "))
+		}
+	}
+
+	for _, patterns := range [][]string{r.Schemas, r.Tables, r.Columns, r.Indexes} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid ignore pattern '%s': %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// IsEmpty reports whether no object is ignored
+func (r IgnoreRules) IsEmpty() bool {
+	return len(r.Extensions) == 0 && len(r.Schemas) == 0 && len(r.Tables) == 0 && len(r.Columns) == 0 && len(r.Indexes) == 0
+}
+
+// IgnoresSchema reports whether the schema and everything in it is ignored
+func (r IgnoreRules) IgnoresSchema(name string) bool {
+	if matchesAny(r.Schemas, name) {
+		return true
+	}
+	for _, extension := range r.Extensions {
+		if matchesAny(extensionIgnoreRules[strings.ToLower(extension)].Schemas, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoresTable reports whether a table is ignored, by its own name, its schema qualified
+// name or its schema. schemaName may be empty when the schema is not known.
+func (r IgnoreRules) IgnoresTable(schemaName, name string) bool {
+	if schemaName != "" && r.IgnoresSchema(schemaName) {
+		return true
+	}
+
+	tables := append([]string{}, r.Tables...)
+	for _, extension := range r.Extensions {
+		tables = append(tables, extensionIgnoreRules[strings.ToLower(extension)].Tables...)
+	}
+	return matchesAny(tables, name) || (schemaName != "" && matchesAny(tables, schemaName+"."+name))
+}
+
+// IgnoresColumn reports whether a column is ignored
+func (r IgnoreRules) IgnoresColumn(table, column string) bool {
+	return matchesAny(r.Columns, table+"."+column)
+}
+
+// IgnoresIndex reports whether an index is ignored
+func (r IgnoreRules) IgnoresIndex(name string) bool {
+	return matchesAny(r.Indexes, name)
+}
+
+// filterIgnoredChanges removes the changes to ignored objects, so that objects managed
+// by other tools are neither dropped nor altered
+func filterIgnoredChanges(changes []schema.Change, rules IgnoreRules) []schema.Change {
+	if rules.IsEmpty() {
+		return changes
+	}
+
+	filtered := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.AddSchema:
+			if rules.IgnoresSchema(c.S.Name) {
+				logger.Atlas().Debug("Ignoring schema %s", c.S.Name)
+				continue
+			}
+		case *schema.DropSchema:
+			if rules.IgnoresSchema(c.S.Name) {
+				logger.Atlas().Debug("Ignoring schema %s", c.S.Name)
+				continue
+			}
+		case *schema.ModifySchema:
+			if rules.IgnoresSchema(c.S.Name) {
+				logger.Atlas().Debug("Ignoring schema %s", c.S.Name)
+				continue
+			}
+		case *schema.AddTable:
+			if ignoresTable(rules, c.T) {
+				continue
+			}
+		case *schema.DropTable:
+			if ignoresTable(rules, c.T) {
+				continue
+			}
+		case *schema.RenameTable:
+			if ignoresTable(rules, c.From) || ignoresTable(rules, c.To) {
+				continue
+			}
+		case *schema.ModifyTable:
+			if ignoresTable(rules, c.T) {
+				continue
+			}
+			c.Changes = filterIgnoredTableChanges(c.T.Name, c.Changes, rules)
+			if len(c.Changes) == 0 {
+				continue
+			}
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered
+}
+
+func filterIgnoredTableChanges(table string, changes []schema.Change, rules IgnoreRules) []schema.Change {
+	kept := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		var column, index string
+		switch c := change.(type) {
+		case *schema.AddColumn:
+			column = c.C.Name
+		case *schema.DropColumn:
+			column = c.C.Name
+		case *schema.ModifyColumn:
+			column = c.From.Name
+		case *schema.RenameColumn:
+			column = c.From.Name
+		case *schema.AddIndex:
+			index = c.I.Name
+		case *schema.DropIndex:
+			index = c.I.Name
+		case *schema.ModifyIndex:
+			index = c.From.Name
+		case *schema.RenameIndex:
+			index = c.From.Name
+		}
+
+		if column != "" && rules.IgnoresColumn(table, column) {
+			logger.Atlas().Debug("Ignoring column %s.%s", table, column)
+			continue
+		}
+		if index != "" && rules.IgnoresIndex(index) {
+			logger.Atlas().Debug("Ignoring index %s on %s", index, table)
+			continue
+		}
+		kept = append(kept, change)
+	}
+	return kept
+}
+
+func ignoresTable(rules IgnoreRules, table *schema.Table) bool {
+	if rules.IgnoresTable(schemaName(table), table.Name) {
+		logger.Atlas().Debug("Ignoring table %s", table.Name)
+		return true
+	}
+	return false
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
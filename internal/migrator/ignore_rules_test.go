@@ -0,0 +1,112 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+func TestIgnoreRules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   IgnoreRules
+		wantErr string
+	}{
+		{name: "empty", rules: IgnoreRules{}},
+		{name: "extensions and patterns", rules: IgnoreRules{Extensions: []string{"PostGIS", "timescaledb"}, Tables: []string{"audit_*"}, Indexes: []string{"*_time_idx"}}},
+		{name: "unknown extension", rules: IgnoreRules{Extensions: []string{"pgvector"}}, wantErr: "unknown extension 'pgvector' in ignore rules (expected one of postgis, timescaledb)"},
+		{name: "malformed pattern", rules: IgnoreRules{Columns: []string{"users.[geom"}}, wantErr: "invalid ignore pattern 'users.[geom'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIgnoreRules_Matching(t *testing.T) {
+	rules := IgnoreRules{
+		Extensions: []string{"postgis", "timescaledb"},
+		Tables:     []string{"legacy.*", "audit_*"},
+		Columns:    []string{"*.search_vector"},
+		Indexes:    []string{"*_time_idx"},
+	}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"extension table", rules.IgnoresTable("public", "spatial_ref_sys"), true},
+		{"extension schema", rules.IgnoresSchema("_timescaledb_internal"), true},
+		{"table in extension schema", rules.IgnoresTable("topology", "layer"), true},
+		{"table pattern", rules.IgnoresTable("public", "audit_log"), true},
+		{"schema qualified pattern", rules.IgnoresTable("legacy", "users"), true},
+		{"schema qualified pattern needs the schema", rules.IgnoresTable("", "users"), false},
+		{"managed table", rules.IgnoresTable("public", "users"), false},
+		{"column pattern", rules.IgnoresColumn("posts", "search_vector"), true},
+		{"managed column", rules.IgnoresColumn("posts", "title"), false},
+		{"index pattern", rules.IgnoresIndex("metrics_time_idx"), true},
+		{"managed index", rules.IgnoresIndex("idx_users_email"), false},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %t, want %t", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestFilterIgnoredChanges(t *testing.T) {
+	public := schema.New("public")
+	users := schema.NewTable("users").SetSchema(public)
+	metrics := schema.NewTable("metrics").SetSchema(public)
+	spatial := schema.NewTable("spatial_ref_sys").SetSchema(public)
+	email := schema.NewStringColumn("email", "text")
+	addIndex := &schema.AddIndex{I: schema.NewIndex("idx_users_email")}
+
+	changes := []schema.Change{
+		&schema.DropSchema{S: schema.New("_timescaledb_cache")},
+		&schema.DropTable{T: spatial},
+		&schema.ModifyTable{T: metrics, Changes: []schema.Change{
+			&schema.DropIndex{I: schema.NewIndex("metrics_time_idx")},
+		}},
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.DropColumn{C: schema.NewColumn("search_vector")},
+			&schema.AddColumn{C: email},
+			addIndex,
+		}},
+	}
+
+	filtered := filterIgnoredChanges(changes, IgnoreRules{
+		Extensions: []string{"postgis", "timescaledb"},
+		Columns:    []string{"*.search_vector"},
+		Indexes:    []string{"*_time_idx"},
+	})
+
+	if len(filtered) != 1 {
+		t.Fatalf("expected only the users changes to remain, got %d changes", len(filtered))
+	}
+	modify := filtered[0].(*schema.ModifyTable)
+	if modify.T != users || len(modify.Changes) != 2 {
+		t.Fatalf("expected the ignored column to be dropped from the users changes, got %#v", modify.Changes)
+	}
+	if modify.Changes[0].(*schema.AddColumn).C != email || modify.Changes[1] != addIndex {
+		t.Errorf("unexpected remaining changes %#v", modify.Changes)
+	}
+
+	if got := filterIgnoredChanges(changes[:1], IgnoreRules{}); len(got) != 1 {
+		t.Error("expected no change to be filtered without rules")
+	}
+}
@@ -0,0 +1,376 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+// useConcurrentIndexes makes the index changes on existing tables build and drop their
+// indexes with CONCURRENTLY, so writes to the table are not blocked while they run.
+// Atlas rebuilds a modified index as a drop and an add without carrying options over,
+// so ModifyIndex is split into that pair here. Indexes of new tables are left alone.
+// When only is not nil, just the indexes it names are built concurrently, and dropped
+// indexes are left alone as their models no longer declare them. PostgreSQL builds
+// no index of a partitioned table concurrently, so those are built as usual and a
+// warning is returned for each.
+func useConcurrentIndexes(changes []schema.Change, only map[string]bool) []string {
+	var warnings []string
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			continue
+		}
+		partitioned := isPartitioned(modify.T)
+
+		rewritten := make([]schema.Change, 0, len(modify.Changes))
+		for _, sub := range modify.Changes {
+			index := concurrentCandidate(sub, only)
+			if index != nil && partitioned {
+				warnings = append(warnings, fmt.Sprintf("Index %s on partitioned table %s is changed without CONCURRENTLY, which PostgreSQL does not support on partitioned tables; writes to every partition are blocked while it runs", index.Name, qualifiedName(modify.T)))
+				index = nil
+			}
+			if index == nil {
+				rewritten = append(rewritten, sub)
+				continue
+			}
+
+			switch c := sub.(type) {
+			case *schema.AddIndex:
+				c.Extra = append(c.Extra, &postgres.Concurrently{})
+			case *schema.DropIndex:
+				c.Extra = append(c.Extra, &postgres.Concurrently{})
+			case *schema.ModifyIndex:
+				rewritten = append(rewritten,
+					&schema.DropIndex{I: c.From, Extra: []schema.Clause{&postgres.Concurrently{}}},
+					&schema.AddIndex{I: c.To, Extra: []schema.Clause{&postgres.Concurrently{}}},
+				)
+				continue
+			}
+			rewritten = append(rewritten, sub)
+		}
+		modify.Changes = rewritten
+	}
+	return warnings
+}
+
+// concurrentCandidate returns the index change builds or drops, when it is one
+// useConcurrentIndexes makes concurrent, or nil
+func concurrentCandidate(change schema.Change, only map[string]bool) *schema.Index {
+	switch c := change.(type) {
+	case *schema.AddIndex:
+		if only == nil || only[c.I.Name] {
+			return c.I
+		}
+	case *schema.DropIndex:
+		if only == nil {
+			return c.I
+		}
+	case *schema.ModifyIndex:
+		// A change to the comment alone is applied in place
+		if c.Change&^schema.ChangeComment != schema.NoChange && (only == nil || only[c.To.Name]) {
+			return c.To
+		}
+	}
+	return nil
+}
+
+// isPartitioned reports whether table is declared with PARTITION BY
+func isPartitioned(table *schema.Table) bool {
+	for _, attr := range table.Attrs {
+		if _, ok := attr.(*postgres.Partition); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// concurrentIndexNames returns the indexes the models of s declare concurrent
+func concurrentIndexNames(s *generator.DatabaseSchema) []string {
+	var names []string
+	for _, table := range s.Tables {
+		for _, index := range table.Indexes {
+			if index.Concurrent {
+				names = append(names, index.Name)
+			}
+		}
+	}
+	return names
+}
+
+// indexReversals maps the index statements Atlas planned to the statements Atlas itself
+// uses to undo them. A dropped or rebuilt index can only be recreated from its previous
+// definition, which the SQL of the DROP INDEX no longer holds.
+func indexReversals(statements, reverses []string) map[string]string {
+	reversals := make(map[string]string)
+
+	for i, stmt := range statements {
+		if i >= len(reverses) || reverses[i] == "" {
+			continue
+		}
+
+		cmd := strings.ToUpper(stripLeadingComments(stmt))
+		if strings.HasPrefix(cmd, "DROP INDEX") || strings.HasPrefix(cmd, "CREATE INDEX") || strings.HasPrefix(cmd, "CREATE UNIQUE INDEX") {
+			reversals[stmt] = reverses[i]
+		}
+	}
+
+	return reversals
+}
+
+var concurrentIndexRe = regexp.MustCompile(`(?i)\b(?:CREATE\s+(?:UNIQUE\s+)?|DROP\s+)INDEX\s+CONCURRENTLY\b`)
+
+// RequiresNoTransaction reports whether a migration contains statements PostgreSQL refuses
+// to run inside a transaction block, such as CREATE INDEX CONCURRENTLY
+func RequiresNoTransaction(sql string) bool {
+	return concurrentIndexRe.MatchString(sql)
+}
+
+// RunsInPhases reports whether a migration has to be applied in several phases rather
+// than a single transaction, see TransactionPhases
+func RunsInPhases(sql string) bool {
+	return RequiresNoTransaction(sql) || validatesConstraint(sql)
+}
+
+// MigrationPhase is a run of migration statements applied together, either in a single
+// transaction or, for statements PostgreSQL refuses to run in one, on their own
+type MigrationPhase struct {
+	Statements    []string
+	Transactional bool
+}
+
+// TransactionPhases splits the statements of a migration into the phases they can be
+// applied in. Consecutive statements that can run in a transaction share one, while each
+// statement that cannot, such as CREATE INDEX CONCURRENTLY, gets a phase of its own, so
+// the rest of the migration keeps its transactional guarantees. VALIDATE CONSTRAINT
+// also gets a transaction of its own, so the ACCESS EXCLUSIVE locks taken before it
+// are released while it scans the table.
+func TransactionPhases(statements []string) []MigrationPhase {
+	var phases []MigrationPhase
+	isolated := false
+	for _, stmt := range statements {
+		if RequiresNoTransaction(stmt) {
+			phases = append(phases, MigrationPhase{Statements: []string{stmt}})
+			continue
+		}
+		validates := validatesConstraint(stmt)
+		if n := len(phases); n > 0 && phases[n-1].Transactional && !isolated && !validates {
+			phases[n-1].Statements = append(phases[n-1].Statements, stmt)
+			continue
+		}
+		phases = append(phases, MigrationPhase{Statements: []string{stmt}, Transactional: true})
+		isolated = validates
+	}
+	return phases
+}
+
+var createIndexNameRe = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?"([^"]+)"`)
+
+// indexStorageOptions reads the WITH options of every index, keyed by index name. Atlas
+// only keeps the options of the built-in index methods, so the parameters of extension
+// methods, such as m and ef_construction of pgvector's hnsw, are read here directly.
+func indexStorageOptions(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname, array_to_string(c.reloptions, ', ')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind = 'i'
+		  AND c.reloptions IS NOT NULL
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index storage options: %w", err)
+	}
+	defer rows.Close()
+
+	options := make(map[string]string)
+	for rows.Next() {
+		var name, opts string
+		if err := rows.Scan(&name, &opts); err != nil {
+			return nil, fmt.Errorf("failed to read index storage options: %w", err)
+		}
+		options[name] = opts
+	}
+	return options, rows.Err()
+}
+
+// withIndexStorageOptions adds the WITH options Atlas left out to the CREATE INDEX statements
+func withIndexStorageOptions(statements []string, options map[string]string) []string {
+	if len(options) == 0 {
+		return statements
+	}
+
+	result := make([]string, len(statements))
+	for i, stmt := range statements {
+		result[i] = stmt
+		match := createIndexNameRe.FindStringSubmatch(stmt)
+		if match == nil || options[match[1]] == "" || strings.Contains(strings.ToUpper(stmt), " WITH (") {
+			continue
+		}
+
+		with := " WITH (" + options[match[1]] + ")"
+		if where := strings.Index(stmt, " WHERE "); where != -1 {
+			result[i] = stmt[:where] + with + stmt[where:]
+		} else {
+			result[i] = stmt + with
+		}
+	}
+	return result
+}
+
+// indexReference is a foreign key whose referenced columns an index enforces
+type indexReference struct {
+	ForeignKey string
+	Table      string // Schema-qualified table of the foreign key
+}
+
+// inspectIndexReferences reads the foreign keys each index enforces the referenced side
+// of, keyed by the schema-qualified index name. pg_constraint.conindid points a foreign
+// key at the unique index of the referenced columns, which PostgreSQL then refuses to
+// drop, be it a plain index or the index of a unique constraint.
+func inspectIndexReferences(ctx context.Context, db *sql.DB) (map[string][]indexReference, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.nspname, i.relname, c.conname, tn.nspname, t.relname
+		FROM pg_constraint c
+		JOIN pg_class i ON i.oid = c.conindid
+		JOIN pg_namespace n ON n.oid = i.relnamespace
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace tn ON tn.oid = t.relnamespace
+		WHERE c.contype = 'f'
+		  AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY n.nspname, i.relname, c.conname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index references: %w", err)
+	}
+	defer rows.Close()
+
+	references := make(map[string][]indexReference)
+	for rows.Next() {
+		var indexSchema, index, fk, tableSchema, table string
+		if err := rows.Scan(&indexSchema, &index, &fk, &tableSchema, &table); err != nil {
+			return nil, fmt.Errorf("failed to read index references: %w", err)
+		}
+		key := indexSchema + "." + index
+		references[key] = append(references[key], indexReference{ForeignKey: fk, Table: tableSchema + "." + table})
+	}
+	return references, rows.Err()
+}
+
+// keepReferencedIndexes removes from changes the drops and rebuilds of the indexes that
+// foreign keys surviving the migration reference, and returns a warning for each. Such
+// an index may look redundant to the models, but dropping it would fail, or drop the
+// unique constraint the foreign keys rely on. Dropping an index that serves lookups on
+// a foreign key of its table, with no other index left to do so, is kept but warned
+// about, as the foreign key then scans the table on every change to the referenced rows.
+func keepReferencedIndexes(changes []schema.Change, references map[string][]indexReference) ([]schema.Change, []string) {
+	droppedTables := map[string]bool{}
+	droppedFKs := map[string]bool{}
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.DropTable:
+			droppedTables[qualifiedName(c.T)] = true
+		case *schema.ModifyTable:
+			for _, sub := range c.Changes {
+				if drop, ok := sub.(*schema.DropForeignKey); ok {
+					droppedFKs[qualifiedName(c.T)+"."+drop.F.Symbol] = true
+				}
+			}
+		}
+	}
+
+	survivors := func(table *schema.Table, index *schema.Index) []string {
+		var names []string
+		for _, ref := range references[indexName(table, index)] {
+			if !droppedTables[ref.Table] && !droppedFKs[ref.Table+"."+ref.ForeignKey] {
+				names = append(names, ref.ForeignKey)
+			}
+		}
+		return names
+	}
+
+	var warnings []string
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			continue
+		}
+
+		kept := make([]schema.Change, 0, len(modify.Changes))
+		for _, sub := range modify.Changes {
+			var index *schema.Index
+			switch c := sub.(type) {
+			case *schema.DropIndex:
+				index = c.I
+			case *schema.ModifyIndex:
+				// A change to the comment alone is applied in place
+				if c.Change&^schema.ChangeComment != schema.NoChange {
+					index = c.From
+				}
+			}
+
+			if index != nil {
+				if fks := survivors(modify.T, index); len(fks) > 0 {
+					warnings = append(warnings, fmt.Sprintf("Kept index %s on %s as foreign key %s references its columns; drop the foreign key first",
+						index.Name, modify.T.Name, strings.Join(fks, ", ")))
+					continue
+				}
+			}
+			if drop, ok := sub.(*schema.DropIndex); ok {
+				for _, fk := range unindexedForeignKeys(modify.T, drop.I) {
+					warnings = append(warnings, fmt.Sprintf("Dropping index %s leaves foreign key %s on %s without an index",
+						drop.I.Name, fk, modify.T.Name))
+				}
+			}
+			kept = append(kept, sub)
+		}
+		modify.Changes = kept
+	}
+
+	return changes, warnings
+}
+
+// unindexedForeignKeys returns the foreign keys of table that dropped served and no index
+// of table, as the migration leaves it, still does
+func unindexedForeignKeys(table *schema.Table, dropped *schema.Index) []string {
+	var names []string
+	for _, fk := range table.ForeignKeys {
+		if !indexCovers(dropped, fk.Columns) {
+			continue
+		}
+		covered := table.PrimaryKey != nil && indexCovers(table.PrimaryKey, fk.Columns)
+		for _, index := range table.Indexes {
+			covered = covered || indexCovers(index, fk.Columns)
+		}
+		if !covered {
+			names = append(names, fk.Symbol)
+		}
+	}
+	return names
+}
+
+// indexCovers reports whether columns lead the parts of index, in order
+func indexCovers(index *schema.Index, columns []*schema.Column) bool {
+	if len(columns) == 0 || len(index.Parts) < len(columns) {
+		return false
+	}
+	for i, column := range columns {
+		if index.Parts[i].C == nil || index.Parts[i].C.Name != column.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// indexName returns the schema-qualified name of index, which lives in the schema of table
+func indexName(table *schema.Table, index *schema.Index) string {
+	name := schemaName(table)
+	if name == "" {
+		name = "public"
+	}
+	return name + "." + index.Name
+}
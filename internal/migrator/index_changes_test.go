@@ -0,0 +1,368 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ariga.io/atlas/sql/migrate"
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// planningDriver opens an Atlas driver that can plan changes without a database
+func planningDriver(t *testing.T) migrate.Driver {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	mock.ExpectQuery("SELECT current_setting").WillReturnRows(
+		sqlmock.NewRows([]string{"server_version_num", "default_table_access_method", "crdb_version"}).
+			AddRow("150000", "heap", nil))
+
+	driver, err := postgres.Open(db)
+	if err != nil {
+		t.Fatalf("failed to open driver: %v", err)
+	}
+	return driver
+}
+
+func indexFixture() (*schema.Table, *schema.Index, *schema.Index) {
+	public := schema.New("public")
+	email := schema.NewStringColumn("email", "text")
+	name := schema.NewStringColumn("name", "text")
+	users := schema.NewTable("users").SetSchema(public).AddColumns(email, name)
+
+	from := schema.NewIndex("idx_users_email").AddColumns(email)
+	from.Table = users
+	to := schema.NewUniqueIndex("idx_users_email").AddColumns(email, name)
+	to.Table = users
+
+	return users, from, to
+}
+
+func TestUseConcurrentIndexes(t *testing.T) {
+	users, from, to := indexFixture()
+	comment := &schema.ModifyIndex{From: from, To: to, Change: schema.ChangeComment}
+	created := &schema.AddTable{T: schema.NewTable("teams")}
+
+	changes := []schema.Change{
+		created,
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyIndex{From: from, To: to, Change: schema.ChangeParts | schema.ChangeUnique},
+			comment,
+			&schema.AddIndex{I: schema.NewIndex("idx_users_name")},
+		}},
+	}
+
+	useConcurrentIndexes(changes, nil)
+
+	modify := changes[1].(*schema.ModifyTable)
+	if len(modify.Changes) != 4 {
+		t.Fatalf("expected the rebuilt index to be split into a drop and an add, got %d changes", len(modify.Changes))
+	}
+	if drop, ok := modify.Changes[0].(*schema.DropIndex); !ok || drop.I != from || len(drop.Extra) != 1 {
+		t.Errorf("expected a concurrent drop of the old index, got %#v", modify.Changes[0])
+	}
+	if add, ok := modify.Changes[1].(*schema.AddIndex); !ok || add.I != to || len(add.Extra) != 1 {
+		t.Errorf("expected a concurrent add of the new index, got %#v", modify.Changes[1])
+	}
+	if modify.Changes[2] != comment {
+		t.Error("expected a comment-only change to be kept in place")
+	}
+	if add := modify.Changes[3].(*schema.AddIndex); len(add.Extra) != 1 {
+		t.Error("expected added indexes to be built concurrently")
+	}
+}
+
+func TestUseConcurrentIndexes_OnlyNamed(t *testing.T) {
+	users, from, to := indexFixture()
+	dropped := &schema.DropIndex{I: schema.NewIndex("idx_users_old")}
+	plain := &schema.AddIndex{I: schema.NewIndex("idx_users_name")}
+	concurrent := &schema.AddIndex{I: schema.NewIndex("idx_users_created")}
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyIndex{From: from, To: to, Change: schema.ChangeParts},
+			dropped,
+			plain,
+			concurrent,
+		}},
+	}
+
+	useConcurrentIndexes(changes, map[string]bool{"idx_users_created": true})
+
+	modify := changes[0].(*schema.ModifyTable)
+	if len(modify.Changes) != 4 {
+		t.Fatalf("expected only named indexes to be rebuilt, got %d changes", len(modify.Changes))
+	}
+	if _, ok := modify.Changes[0].(*schema.ModifyIndex); !ok {
+		t.Errorf("expected an index not named to be modified in place, got %#v", modify.Changes[0])
+	}
+	if len(dropped.Extra) != 0 || len(plain.Extra) != 0 {
+		t.Error("expected indexes not named to be left alone")
+	}
+	if len(concurrent.Extra) != 1 {
+		t.Error("expected the named index to be built concurrently")
+	}
+}
+
+func TestUseConcurrentIndexes_Partitioned(t *testing.T) {
+	users, from, to := indexFixture()
+	users.AddAttrs(&postgres.Partition{T: "RANGE"})
+	added := &schema.AddIndex{I: schema.NewIndex("idx_users_name")}
+	rebuilt := &schema.ModifyIndex{From: from, To: to, Change: schema.ChangeParts}
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{rebuilt, added}},
+	}
+
+	warnings := useConcurrentIndexes(changes, nil)
+
+	modify := changes[0].(*schema.ModifyTable)
+	if len(modify.Changes) != 2 || modify.Changes[0] != rebuilt || len(added.Extra) != 0 {
+		t.Errorf("expected the indexes of a partitioned table to be changed as usual, got %#v", modify.Changes)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[1], "Index idx_users_name on partitioned table public.users is changed without CONCURRENTLY") {
+		t.Errorf("expected a warning for each index, got %q", warnings)
+	}
+}
+
+func TestAlteredIndex_RecreatesAndReverses(t *testing.T) {
+	driver := planningDriver(t)
+	users, from, to := indexFixture()
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyIndex{From: from, To: to, Change: schema.ChangeParts | schema.ChangeUnique},
+		}},
+	}
+	useConcurrentIndexes(changes, nil)
+
+	statements, reverses, err := generateAtlasStatements(context.Background(), driver, changes)
+	if err != nil {
+		t.Fatalf("generateAtlasStatements() error = %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("expected a drop and a create, got %q", statements)
+	}
+	if !strings.Contains(statements[0], `DROP INDEX CONCURRENTLY "public"."idx_users_email"`) {
+		t.Errorf("unexpected drop statement: %s", statements[0])
+	}
+	if !strings.Contains(statements[1], `CREATE UNIQUE INDEX CONCURRENTLY "idx_users_email" ON "public"."users" ("email", "name")`) {
+		t.Errorf("unexpected create statement: %s", statements[1])
+	}
+
+	reverser := NewMigrationReverser()
+	for up, down := range indexReversals(statements, reverses) {
+		reverser.RegisterReversal(up, down)
+	}
+
+	down, err := reverser.ReverseSQL(statements[0])
+	if err != nil {
+		t.Fatalf("ReverseSQL() error = %v", err)
+	}
+	if !strings.Contains(down, `CREATE INDEX CONCURRENTLY "idx_users_email" ON "public"."users" ("email")`) {
+		t.Errorf("expected the old index to be recreated on rollback, got %s", down)
+	}
+}
+
+func TestTransactionPhases(t *testing.T) {
+	statements := []string{
+		`ALTER TABLE "orders" ADD COLUMN "total" integer`,
+		`COMMENT ON COLUMN "orders"."total" IS 'cents'`,
+		`CREATE INDEX CONCURRENTLY "idx_orders_total" ON "orders" ("total")`,
+		`DROP INDEX CONCURRENTLY "idx_orders_old"`,
+		`ALTER TABLE "orders" DROP COLUMN "legacy"`,
+	}
+
+	phases := TransactionPhases(statements)
+	if len(phases) != 4 {
+		t.Fatalf("expected 4 phases, got %d: %+v", len(phases), phases)
+	}
+
+	expected := []struct {
+		statements    int
+		transactional bool
+	}{
+		{2, true},
+		{1, false},
+		{1, false},
+		{1, true},
+	}
+	for i, want := range expected {
+		if len(phases[i].Statements) != want.statements || phases[i].Transactional != want.transactional {
+			t.Errorf("phase %d = %+v, want %d statements, transactional %v", i, phases[i], want.statements, want.transactional)
+		}
+	}
+
+	if phases := TransactionPhases(statements[:2]); len(phases) != 1 || !phases[0].Transactional {
+		t.Errorf("expected a migration without concurrent indexes to run in one transaction, got %+v", phases)
+	}
+}
+
+func TestTransactionPhases_ValidateConstraint(t *testing.T) {
+	phases := TransactionPhases([]string{
+		`ALTER TABLE "orders" ADD CONSTRAINT "orders_total_check" CHECK (total >= 0) NOT VALID`,
+		`ALTER TABLE "orders" VALIDATE CONSTRAINT "orders_total_check"`,
+		`ALTER TABLE "orders" VALIDATE CONSTRAINT "orders_user_id_fkey"`,
+		`ALTER TABLE "orders" ALTER COLUMN "total" SET NOT NULL`,
+	})
+
+	if len(phases) != 4 {
+		t.Fatalf("expected each validation in a transaction of its own, got %d phases: %+v", len(phases), phases)
+	}
+	for i, phase := range phases {
+		if len(phase.Statements) != 1 || !phase.Transactional {
+			t.Errorf("phase %d = %+v, want 1 statement in a transaction", i, phase)
+		}
+	}
+
+	if !RunsInPhases(`ALTER TABLE "orders" VALIDATE CONSTRAINT "orders_total_check";`) {
+		t.Error("expected a migration validating a constraint to run in phases")
+	}
+}
+
+func TestRequiresNoTransaction(t *testing.T) {
+	tests := []struct {
+		sql      string
+		expected bool
+	}{
+		{`CREATE INDEX CONCURRENTLY "idx" ON "users" ("email")`, true},
+		{`create unique index concurrently idx on users (email)`, true},
+		{`DROP INDEX CONCURRENTLY "public"."idx"`, true},
+		{`CREATE INDEX "idx" ON "users" ("email")`, false},
+		{`COMMENT ON TABLE users IS 'not concurrently'`, false},
+	}
+
+	for _, tt := range tests {
+		if got := RequiresNoTransaction(tt.sql); got != tt.expected {
+			t.Errorf("RequiresNoTransaction(%q) = %v, want %v", tt.sql, got, tt.expected)
+		}
+	}
+}
+
+func TestWithIndexStorageOptions(t *testing.T) {
+	statements := []string{
+		"-- create index \"idx_items_embedding\" to table: \"items\"\nCREATE INDEX \"idx_items_embedding\" ON \"public\".\"items\" USING hnsw (\"embedding\" vector_cosine_ops)",
+		`CREATE INDEX CONCURRENTLY "idx_items_recent" ON "public"."items" USING ivfflat ("embedding") WHERE (archived = false)`,
+		`CREATE INDEX "idx_items_name" ON "public"."items" ("name")`,
+		`ALTER TABLE "public"."items" ADD COLUMN "embedding" vector(3) NOT NULL`,
+	}
+
+	got := withIndexStorageOptions(statements, map[string]string{
+		"idx_items_embedding": "m=16, ef_construction=64",
+		"idx_items_recent":    "lists=100",
+	})
+
+	if !strings.HasSuffix(got[0], `USING hnsw ("embedding" vector_cosine_ops) WITH (m=16, ef_construction=64)`) {
+		t.Errorf("expected the hnsw parameters to be added, got %s", got[0])
+	}
+	if !strings.HasSuffix(got[1], `("embedding") WITH (lists=100) WHERE (archived = false)`) {
+		t.Errorf("expected the parameters before the predicate, got %s", got[1])
+	}
+	if got[2] != statements[2] || got[3] != statements[3] {
+		t.Errorf("expected other statements to be unchanged, got %v", got[2:])
+	}
+}
+
+func TestInspectIndexReferences(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("c.contype = 'f'").WillReturnRows(
+		sqlmock.NewRows([]string{"index_schema", "index", "fk", "table_schema", "table"}).
+			AddRow("public", "idx_users_email", "fk_invites_email", "public", "invites").
+			AddRow("public", "idx_users_email", "fk_orders_email", "billing", "orders"))
+
+	references, err := inspectIndexReferences(context.Background(), db)
+	if err != nil {
+		t.Fatalf("inspectIndexReferences() error = %v", err)
+	}
+
+	refs := references["public.idx_users_email"]
+	if len(references) != 1 || len(refs) != 2 {
+		t.Fatalf("expected two references to one index, got %v", references)
+	}
+	if refs[1].ForeignKey != "fk_orders_email" || refs[1].Table != "billing.orders" {
+		t.Errorf("unexpected reference %+v", refs[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeepReferencedIndexes(t *testing.T) {
+	public := schema.New("public")
+	users, from, to := indexFixture()
+	invites := schema.NewTable("invites").SetSchema(public)
+	legacy := schema.NewUniqueIndex("idx_users_legacy").AddColumns(users.Columns[1])
+	orphan := schema.NewIndex("idx_users_orphan").AddColumns(users.Columns[1])
+
+	references := map[string][]indexReference{
+		"public.idx_users_email":  {{ForeignKey: "fk_invites_email", Table: "public.invites"}},
+		"public.idx_users_legacy": {{ForeignKey: "fk_invites_name", Table: "public.invites"}},
+		"public.idx_users_orphan": {{ForeignKey: "fk_archive_name", Table: "public.archive"}},
+	}
+
+	changes := []schema.Change{
+		&schema.DropTable{T: schema.NewTable("archive").SetSchema(public)},
+		&schema.ModifyTable{T: invites, Changes: []schema.Change{
+			&schema.DropForeignKey{F: schema.NewForeignKey("fk_invites_name")},
+		}},
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyIndex{From: from, To: to, Change: schema.ChangeParts},
+			&schema.ModifyIndex{From: from, To: to, Change: schema.ChangeComment},
+			&schema.DropIndex{I: legacy},
+			&schema.DropIndex{I: orphan},
+		}},
+	}
+
+	changes, warnings := keepReferencedIndexes(changes, references)
+
+	modify := changes[2].(*schema.ModifyTable)
+	if len(modify.Changes) != 3 {
+		t.Fatalf("expected the rebuild of the referenced index to be left out, got %d changes", len(modify.Changes))
+	}
+	if c, ok := modify.Changes[0].(*schema.ModifyIndex); !ok || c.Change != schema.ChangeComment {
+		t.Errorf("expected a comment-only change to be kept, got %#v", modify.Changes[0])
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "idx_users_email") || !strings.Contains(warnings[0], "fk_invites_email") {
+		t.Errorf("expected a warning about the kept index, got %q", warnings)
+	}
+}
+
+func TestKeepReferencedIndexes_ForeignKeyHelper(t *testing.T) {
+	public := schema.New("public")
+	id := schema.NewIntColumn("id", "integer")
+	teamID := schema.NewIntColumn("team_id", "integer")
+	teams := schema.NewTable("teams").SetSchema(public).AddColumns(id)
+	members := schema.NewTable("members").SetSchema(public).AddColumns(id, teamID)
+	members.AddForeignKeys(schema.NewForeignKey("fk_members_team").AddColumns(teamID).SetRefTable(teams).AddRefColumns(id))
+	helper := schema.NewIndex("idx_members_team").AddColumns(teamID)
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: members, Changes: []schema.Change{&schema.DropIndex{I: helper}}},
+	}
+	changes, warnings := keepReferencedIndexes(changes, nil)
+
+	if len(changes[0].(*schema.ModifyTable).Changes) != 1 {
+		t.Error("expected the drop of an unreferenced index to be kept")
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "fk_members_team") {
+		t.Errorf("expected a warning about the unindexed foreign key, got %q", warnings)
+	}
+
+	members.AddIndexes(schema.NewIndex("idx_members_team_id").AddColumns(teamID, id))
+	if _, warnings := keepReferencedIndexes(changes, nil); len(warnings) != 0 {
+		t.Errorf("expected no warning while another index leads with the foreign key, got %q", warnings)
+	}
+}
@@ -0,0 +1,162 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/logger"
+)
+
+// inheritedTable describes a table created with INHERITS. Atlas inspects such a table
+// like any other, so the columns and checks it inherits look like its own.
+type inheritedTable struct {
+	Parents          []string        // Schema qualified parent tables
+	InheritedColumns map[string]bool // Columns defined by a parent
+	InheritedChecks  map[string]bool // Check constraints defined by a parent
+}
+
+// inspectInheritance reads the tables that inherit from other tables, keyed by their
+// schema qualified name. Declarative partitions are left out: Atlas handles them.
+func inspectInheritance(ctx context.Context, db *sql.DB) (map[string]*inheritedTable, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT cn.nspname, c.relname, pn.nspname, p.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_namespace cn ON cn.oid = c.relnamespace
+		JOIN pg_class p ON p.oid = i.inhparent
+		JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		WHERE c.relkind = 'r' AND NOT c.relispartition
+		ORDER BY cn.nspname, c.relname, i.inhseqno`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table inheritance: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*inheritedTable)
+	for rows.Next() {
+		var childSchema, child, parentSchema, parent string
+		if err := rows.Scan(&childSchema, &child, &parentSchema, &parent); err != nil {
+			return nil, fmt.Errorf("failed to read table inheritance: %w", err)
+		}
+		key := childSchema + "." + child
+		if tables[key] == nil {
+			tables[key] = &inheritedTable{InheritedColumns: map[string]bool{}, InheritedChecks: map[string]bool{}}
+		}
+		tables[key].Parents = append(tables[key].Parents, parentSchema+"."+parent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(tables) == 0 {
+		return tables, nil
+	}
+
+	members, err := db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname, 'column', a.attname
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE a.attnum > 0 AND NOT a.attisdropped AND NOT a.attislocal
+		  AND c.relkind = 'r' AND NOT c.relispartition
+		UNION ALL
+		SELECT n.nspname, c.relname, 'check', con.conname
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'c' AND NOT con.conislocal
+		  AND c.relkind = 'r' AND NOT c.relispartition`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inherited columns: %w", err)
+	}
+	defer members.Close()
+
+	for members.Next() {
+		var schemaName, table, kind, name string
+		if err := members.Scan(&schemaName, &table, &kind, &name); err != nil {
+			return nil, fmt.Errorf("failed to read inherited columns: %w", err)
+		}
+		inherited := tables[schemaName+"."+table]
+		if inherited == nil {
+			continue
+		}
+		if kind == "column" {
+			inherited.InheritedColumns[name] = true
+		} else {
+			inherited.InheritedChecks[name] = true
+		}
+	}
+	return tables, members.Err()
+}
+
+// filterInheritedChanges keeps tables that inherit from other tables out of the diff.
+// Such a table is usually created by a legacy partitioning scheme rather than declared
+// in the models, and a child cannot drop or alter what it inherits: those changes are
+// made on the parent and PostgreSQL applies them to the children.
+func filterInheritedChanges(changes []schema.Change, inheritance map[string]*inheritedTable) []schema.Change {
+	if len(inheritance) == 0 {
+		return changes
+	}
+
+	filtered := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.DropTable:
+			if inherited := inheritance[qualifiedName(c.T)]; inherited != nil {
+				logger.Atlas().Info("Leaving table %s alone: it inherits from %v", c.T.Name, inherited.Parents)
+				continue
+			}
+		case *schema.ModifyTable:
+			inherited := inheritance[qualifiedName(c.T)]
+			if inherited == nil {
+				break
+			}
+			c.Changes = withoutInheritedChanges(c.T.Name, c.Changes, inherited)
+			if len(c.Changes) == 0 {
+				continue
+			}
+		}
+		filtered = append(filtered, change)
+	}
+	return filtered
+}
+
+func withoutInheritedChanges(table string, changes []schema.Change, inherited *inheritedTable) []schema.Change {
+	kept := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		switch c := change.(type) {
+		case *schema.DropColumn:
+			if inherited.InheritedColumns[c.C.Name] {
+				logger.Atlas().Debug("Skipping inherited column %s.%s", table, c.C.Name)
+				continue
+			}
+		case *schema.ModifyColumn:
+			if inherited.InheritedColumns[c.From.Name] {
+				logger.Atlas().Debug("Skipping inherited column %s.%s", table, c.From.Name)
+				continue
+			}
+		case *schema.DropCheck:
+			if inherited.InheritedChecks[c.C.Name] {
+				logger.Atlas().Debug("Skipping inherited check %s on %s", c.C.Name, table)
+				continue
+			}
+		}
+		kept = append(kept, change)
+	}
+	return kept
+}
+
+func qualifiedName(table *schema.Table) string {
+	name := schemaName(table)
+	if name == "" {
+		name = "public"
+	}
+	return name + "." + table.Name
+}
+
+// quotedTable is the quoted, schema-qualified name of table, in public when it has
+// no schema
+func quotedTable(table *schema.Table) string {
+	return quoteIdentifier(triggerSchema(table)) + "." + quoteIdentifier(table.Name)
+}
@@ -0,0 +1,88 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestInspectInheritance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM pg_inherits").WillReturnRows(
+		sqlmock.NewRows([]string{"child_schema", "child", "parent_schema", "parent"}).
+			AddRow("public", "measurements_2019", "public", "measurements"))
+	mock.ExpectQuery("NOT a.attislocal").WillReturnRows(
+		sqlmock.NewRows([]string{"schema", "table", "kind", "name"}).
+			AddRow("public", "measurements_2019", "column", "logged_at").
+			AddRow("public", "measurements_2019", "check", "measurements_value_check").
+			AddRow("public", "unrelated", "column", "id"))
+
+	inheritance, err := inspectInheritance(context.Background(), db)
+	if err != nil {
+		t.Fatalf("inspectInheritance() error = %v", err)
+	}
+
+	child := inheritance["public.measurements_2019"]
+	if child == nil || len(inheritance) != 1 {
+		t.Fatalf("expected one inheriting table, got %v", inheritance)
+	}
+	if len(child.Parents) != 1 || child.Parents[0] != "public.measurements" {
+		t.Errorf("unexpected parents %v", child.Parents)
+	}
+	if !child.InheritedColumns["logged_at"] || !child.InheritedChecks["measurements_value_check"] {
+		t.Errorf("expected the inherited column and check to be recorded, got %+v", child)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFilterInheritedChanges(t *testing.T) {
+	public := schema.New("public")
+	parent := schema.NewTable("measurements").SetSchema(public)
+	legacy := schema.NewTable("measurements_2019").SetSchema(public)
+	modelled := schema.NewTable("measurements_2020").SetSchema(public)
+	note := schema.NewStringColumn("note", "text")
+
+	changes := []schema.Change{
+		&schema.DropTable{T: legacy},
+		&schema.ModifyTable{T: modelled, Changes: []schema.Change{
+			&schema.DropColumn{C: schema.NewColumn("logged_at")},
+			&schema.DropCheck{C: schema.NewCheck().SetName("measurements_value_check")},
+			&schema.AddColumn{C: note},
+		}},
+		&schema.ModifyTable{T: parent, Changes: []schema.Change{
+			&schema.DropColumn{C: schema.NewColumn("logged_at")},
+		}},
+	}
+
+	inherited := func() *inheritedTable {
+		return &inheritedTable{
+			Parents:          []string{"public.measurements"},
+			InheritedColumns: map[string]bool{"logged_at": true},
+			InheritedChecks:  map[string]bool{"measurements_value_check": true},
+		}
+	}
+	filtered := filterInheritedChanges(changes, map[string]*inheritedTable{
+		"public.measurements_2019": inherited(),
+		"public.measurements_2020": inherited(),
+	})
+
+	if len(filtered) != 2 {
+		t.Fatalf("expected the child table drop to be skipped, got %d changes", len(filtered))
+	}
+	child := filtered[0].(*schema.ModifyTable)
+	if len(child.Changes) != 1 || child.Changes[0].(*schema.AddColumn).C != note {
+		t.Errorf("expected only the child's own column to change, got %#v", child.Changes)
+	}
+	if filtered[1].(*schema.ModifyTable).T != parent || len(filtered[1].(*schema.ModifyTable).Changes) != 1 {
+		t.Error("expected the parent's changes to be kept")
+	}
+}
@@ -0,0 +1,93 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// lockNotAvailable is the SQLSTATE of a statement that gave up waiting for a
+// lock, e.g. after lock_timeout
+const lockNotAvailable = "55P03"
+
+// LockRetry retries work that failed on lock_timeout, waiting longer after each
+// attempt, so a migration that cannot get its locks while traffic holds them
+// steps aside and tries again instead of failing outright
+type LockRetry struct {
+	Period     time.Duration // Stop retrying once this long has passed since the first attempt; zero never retries
+	Backoff    time.Duration // Wait before the second attempt, doubled before each further one; defaults to 1s
+	MaxBackoff time.Duration // Longest wait between attempts; defaults to 30s
+
+	// OnRetry is called before each wait, if set
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// IsLockTimeout reports whether err is a statement giving up on a lock
+func IsLockTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == lockNotAvailable
+}
+
+// Do runs fn until it succeeds, fails on something other than a lock timeout,
+// or the retry period is over
+func (r LockRetry) Do(ctx context.Context, fn func() error) error {
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || r.Period <= 0 || !IsLockTimeout(err) {
+			return err
+		}
+
+		wait := backoff
+		if remaining := r.Period - time.Since(start); wait > remaining {
+			if remaining <= 0 {
+				return fmt.Errorf("gave up after %d attempts in %s: %w", attempt, r.Period, err)
+			}
+			wait = remaining
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(attempt, wait, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+var concurrentCreateIndexRe = regexp.MustCompile(`(?i)\bCREATE\s+(?:UNIQUE\s+)?INDEX\s+CONCURRENTLY\b`)
+
+// DoStatement runs fn, which executes stmt, like Do. CREATE INDEX CONCURRENTLY
+// is not retried: it records the index before it waits for the transactions
+// using the table, so a lock timeout leaves an INVALID index behind that a
+// second attempt fails on with "already exists".
+func (r LockRetry) DoStatement(ctx context.Context, stmt string, fn func() error) error {
+	if !concurrentCreateIndexRe.MatchString(stmt) {
+		return r.Do(ctx, fn)
+	}
+
+	err := fn()
+	if IsLockTimeout(err) {
+		return fmt.Errorf("%w: the INVALID index it left behind must be dropped with DROP INDEX CONCURRENTLY before it can run again", err)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestIsLockTimeout(t *testing.T) {
+	lockErr := &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}
+
+	if !IsLockTimeout(lockErr) {
+		t.Error("Expected a 55P03 error to be a lock timeout")
+	}
+	if !IsLockTimeout(fmt.Errorf("statement 2: %w", lockErr)) {
+		t.Error("Expected a wrapped 55P03 error to be a lock timeout")
+	}
+	if IsLockTimeout(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}) {
+		t.Error("Expected a statement timeout not to be a lock timeout")
+	}
+	if IsLockTimeout(errors.New("connection refused")) {
+		t.Error("Expected a plain error not to be a lock timeout")
+	}
+}
+
+func TestLockRetry_Do(t *testing.T) {
+	lockErr := &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}
+
+	t.Run("retries lock timeouts until success", func(t *testing.T) {
+		var waits []time.Duration
+		retry := LockRetry{
+			Period:     time.Second,
+			Backoff:    time.Millisecond,
+			MaxBackoff: 2 * time.Millisecond,
+			OnRetry: func(attempt int, wait time.Duration, err error) {
+				waits = append(waits, wait)
+			},
+		}
+
+		calls := 0
+		err := retry.Do(context.Background(), func() error {
+			calls++
+			if calls < 4 {
+				return lockErr
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected success, got %v", err)
+		}
+		if calls != 4 {
+			t.Errorf("Expected 4 attempts, got %d", calls)
+		}
+		want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 2 * time.Millisecond}
+		if fmt.Sprint(waits) != fmt.Sprint(want) {
+			t.Errorf("Expected waits %v, got %v", want, waits)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		calls := 0
+		other := errors.New("syntax error")
+		err := LockRetry{Period: time.Second}.Do(context.Background(), func() error {
+			calls++
+			return other
+		})
+		if !errors.Is(err, other) || calls != 1 {
+			t.Errorf("Expected one attempt returning the error, got %d attempts and %v", calls, err)
+		}
+	})
+
+	t.Run("zero period does not retry", func(t *testing.T) {
+		calls := 0
+		err := LockRetry{}.Do(context.Background(), func() error {
+			calls++
+			return lockErr
+		})
+		if !IsLockTimeout(err) || calls != 1 {
+			t.Errorf("Expected one attempt returning the lock timeout, got %d attempts and %v", calls, err)
+		}
+	})
+
+	t.Run("gives up after the period", func(t *testing.T) {
+		calls := 0
+		err := LockRetry{Period: 20 * time.Millisecond, Backoff: 5 * time.Millisecond}.Do(context.Background(), func() error {
+			calls++
+			return lockErr
+		})
+		if !IsLockTimeout(err) {
+			t.Errorf("Expected the lock timeout to be returned, got %v", err)
+		}
+		if calls < 2 {
+			t.Errorf("Expected several attempts, got %d", calls)
+		}
+	})
+}
+
+func TestLockRetry_DoStatement(t *testing.T) {
+	lockErr := &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}
+	retry := LockRetry{Period: time.Second, Backoff: time.Millisecond}
+
+	calls := 0
+	err := retry.DoStatement(context.Background(), `ALTER TABLE "users" ADD COLUMN "bio" text`, func() error {
+		calls++
+		if calls < 2 {
+			return lockErr
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Expected a retried statement to succeed on its second attempt, got %d attempts and %v", calls, err)
+	}
+
+	calls = 0
+	err = retry.DoStatement(context.Background(), `CREATE UNIQUE INDEX CONCURRENTLY "idx_users_email" ON "users" ("email")`, func() error {
+		calls++
+		return lockErr
+	})
+	if calls != 1 {
+		t.Errorf("Expected CREATE INDEX CONCURRENTLY to run once, got %d attempts", calls)
+	}
+	if !IsLockTimeout(err) || !strings.Contains(err.Error(), "INVALID index") {
+		t.Errorf("Expected the lock timeout with a note on the INVALID index, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/lib/pq"
+)
+
+// MaskOptions controls how CopyMasked copies data between databases
+type MaskOptions struct {
+	PackagePath string                          // Models whose tables are copied
+	Tables      []string                        // Tables to copy; empty copies every model table
+	Truncate    bool                            // Empty the target tables before copying
+	Salt        string                          // Mixed into masked values
+	ForeignKeys generator.ForeignKeyConventions // Default actions and naming of foreign keys
+}
+
+// MaskedTable reports the copy of one table
+type MaskedTable struct {
+	Name   string
+	Rows   int64
+	Masked []string // Columns that were anonymized
+}
+
+// maskPlan is a table to copy with the mask of each of its columns
+type maskPlan struct {
+	table   generator.SchemaTable
+	masks   map[string]orm.MaskStrategy
+	columns []string
+}
+
+// CopyMasked copies the rows of the model tables from source into target,
+// anonymizing the columns tagged with mask. Tables are copied in foreign key
+// order in one transaction, so target ends up with all of the data or none of
+// it. Target must already have the schema.
+func CopyMasked(ctx context.Context, source, target *sql.DB, opts MaskOptions) ([]MaskedTable, error) {
+	plans, err := planMaskedCopy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := target.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if opts.Truncate {
+		names := make([]string, len(plans))
+		for i, plan := range plans {
+			names[i] = quoteIdentifier(plan.table.Name)
+		}
+		if _, err := tx.ExecContext(ctx, "TRUNCATE "+strings.Join(names, ", ")); err != nil {
+			return nil, fmt.Errorf("failed to truncate target tables: %w", err)
+		}
+	}
+
+	results := make([]MaskedTable, 0, len(plans))
+	for _, plan := range plans {
+		rows, err := copyMaskedTable(ctx, source, tx, plan, opts.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", plan.table.Name, err)
+		}
+
+		result := MaskedTable{Name: plan.table.Name, Rows: rows}
+		for _, column := range plan.columns {
+			if _, ok := plan.masks[column]; ok {
+				result.Masked = append(result.Masked, column)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit copy: %w", err)
+	}
+	return results, nil
+}
+
+// planMaskedCopy returns the tables to copy, referenced tables first
+func planMaskedCopy(opts MaskOptions) ([]maskPlan, error) {
+	models, err := parser.NewStructParser().ParseDirectory(opts.PackagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse structs: %w", err)
+	}
+
+	masks := make(map[string]map[string]orm.MaskStrategy)
+	for _, model := range models {
+		for _, field := range model.Fields {
+			if strategy := field.DBDef["mask"]; strategy != "" && field.IsColumn() {
+				if masks[model.TableName] == nil {
+					masks[model.TableName] = make(map[string]orm.MaskStrategy)
+				}
+				masks[model.TableName][field.DBName] = orm.MaskStrategy(strategy)
+			}
+		}
+	}
+
+	schemaGenerator := generator.NewSchemaGenerator()
+	schemaGenerator.SetForeignKeyConventions(opts.ForeignKeys)
+	schema, err := schemaGenerator.GenerateSchema(models)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	selected := make(map[string]bool, len(opts.Tables))
+	for _, name := range opts.Tables {
+		if !schema.HasTable(name) {
+			return nil, fmt.Errorf("table %s is not one of the models in %s", name, opts.PackagePath)
+		}
+		selected[name] = true
+	}
+
+	var plans []maskPlan
+	for _, name := range schema.GetTableNames() {
+		if len(selected) > 0 && !selected[name] {
+			continue
+		}
+
+		table := schema.Tables[name]
+		plan := maskPlan{table: table, masks: masks[name]}
+		for _, column := range table.Columns {
+			plan.columns = append(plan.columns, column.Name)
+		}
+		plans = append(plans, plan)
+	}
+
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("no tables to copy in %s", opts.PackagePath)
+	}
+	return plans, nil
+}
+
+// copyMaskedTable streams the rows of a table from source into tx with COPY
+// and moves serial sequences past the copied keys
+func copyMaskedTable(ctx context.Context, source *sql.DB, tx *sql.Tx, plan maskPlan, salt string) (int64, error) {
+	quoted := make([]string, len(plan.columns))
+	for i, column := range plan.columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+
+	rows, err := source.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), quoteIdentifier(plan.table.Name)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source rows: %w", err)
+	}
+	defer rows.Close()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(plan.table.Name, plan.columns...))
+	if err != nil {
+		return 0, fmt.Errorf("failed to start copy: %w", err)
+	}
+	defer stmt.Close()
+
+	values := make([]interface{}, len(plan.columns))
+	dest := make([]interface{}, len(plan.columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return 0, fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, column := range plan.table.Columns {
+			// The driver hands back text it does not decode as bytes, which
+			// COPY would otherwise read as bytea
+			if data, ok := values[i].([]byte); ok && !strings.EqualFold(column.Type, "bytea") {
+				values[i] = string(data)
+			}
+			if strategy, ok := plan.masks[column.Name]; ok {
+				if values[i], err = orm.MaskValue(strategy, values[i], salt); err != nil {
+					return 0, fmt.Errorf("failed to mask %s: %w", column.Name, err)
+				}
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, fmt.Errorf("failed to copy row: %w", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read source rows: %w", err)
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, fmt.Errorf("failed to finish copy: %w", err)
+	}
+
+	for _, column := range plan.table.Columns {
+		if !column.IsAutoIncrement {
+			continue
+		}
+		setval := fmt.Sprintf("SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			quoteIdentifier(column.Name), quoteIdentifier(plan.table.Name))
+		if _, err := tx.ExecContext(ctx, setval, quoteIdentifier(plan.table.Name), column.Name); err != nil {
+			return 0, fmt.Errorf("failed to reset sequence of %s: %w", column.Name, err)
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,75 @@
+package migrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyMasked(t *testing.T) {
+	dir := t.TempDir()
+	models := `package models
+
+type Post struct {
+	_      struct{} ` + "`" + `storm:"table:posts"` + "`" + `
+	ID     int    ` + "`" + `db:"id" storm:"type:serial;primary_key"` + "`" + `
+	UserID int    ` + "`" + `db:"user_id" storm:"type:integer;not_null;foreign_key:users.id"` + "`" + `
+	Body   string ` + "`" + `db:"body" storm:"type:text;not_null;mask:redact"` + "`" + `
+}
+
+type User struct {
+	_     struct{} ` + "`" + `storm:"table:users"` + "`" + `
+	ID    int    ` + "`" + `db:"id" storm:"type:serial;primary_key"` + "`" + `
+	Email string ` + "`" + `db:"email" storm:"type:text;not_null;mask:email"` + "`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(models), 0644))
+
+	source, sourceMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer source.Close()
+	target, targetMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer target.Close()
+
+	email, err := orm.MaskValue(orm.MaskEmail, "ada@example.com", "salt")
+	require.NoError(t, err)
+
+	// Users are referenced by posts, so they are copied first
+	targetMock.ExpectBegin()
+	targetMock.ExpectExec(`TRUNCATE "users", "posts"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	sourceMock.ExpectQuery(`SELECT "id", "email" FROM "users"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(int64(1), []byte("ada@example.com")))
+	users := targetMock.ExpectPrepare(`COPY "users" \("id", "email"\) FROM STDIN`)
+	users.ExpectExec().WithArgs(int64(1), email).WillReturnResult(sqlmock.NewResult(0, 0))
+	users.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(`SELECT setval\(pg_get_serial_sequence\(\$1, \$2\), COALESCE\(MAX\("id"\), 0\) \+ 1, false\) FROM "users"`).
+		WithArgs(`"users"`, "id").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	sourceMock.ExpectQuery(`SELECT "id", "user_id", "body" FROM "posts"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "body"}).AddRow(int64(7), int64(1), "Dear diary"))
+	posts := targetMock.ExpectPrepare(`COPY "posts" \("id", "user_id", "body"\) FROM STDIN`)
+	posts.ExpectExec().WithArgs(int64(7), int64(1), orm.RedactedValue).WillReturnResult(sqlmock.NewResult(0, 0))
+	posts.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	targetMock.ExpectExec(`SELECT setval`).WithArgs(`"posts"`, "id").WillReturnResult(sqlmock.NewResult(0, 0))
+	targetMock.ExpectCommit()
+
+	tables, err := CopyMasked(context.Background(), source, target, MaskOptions{PackagePath: dir, Truncate: true, Salt: "salt"})
+	require.NoError(t, err)
+	assert.Equal(t, []MaskedTable{
+		{Name: "users", Rows: 1, Masked: []string{"email"}},
+		{Name: "posts", Rows: 1, Masked: []string{"body"}},
+	}, tables)
+	require.NoError(t, sourceMock.ExpectationsWereMet())
+	require.NoError(t, targetMock.ExpectationsWereMet())
+
+	_, err = CopyMasked(context.Background(), source, target, MaskOptions{PackagePath: dir, Tables: []string{"comments"}})
+	assert.ErrorContains(t, err, "table comments is not one of the models")
+}
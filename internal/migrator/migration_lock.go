@@ -0,0 +1,53 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// migrationLockKey is the pg_advisory_lock key held while storm changes the
+// schema of a database
+const migrationLockKey int64 = 495874699885
+
+// LockMigrations waits for the migration lock of the database and returns the
+// function that releases it. Pushes, plan applies and storm migrate up and down
+// hold it, so only one of them changes the schema at a time. The lock is held by
+// a connection of its own and waits without the lock_timeout of the session.
+func LockMigrations(ctx context.Context, db *sql.DB) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+
+	// The lock belongs to the session, so it outlives the transaction that only
+	// lifts lock_timeout while waiting for it
+	err = func() error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, "SET LOCAL lock_timeout = 0"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			// Discard the connection rather than return it to the pool holding the lock
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
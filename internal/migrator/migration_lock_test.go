@@ -0,0 +1,41 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLockMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL lock_timeout = 0`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(migrationLockKey).WillReturnResult(sqlmock.NewResult(0, 1))
+	unlock, err := LockMigrations(context.Background(), db)
+	if err != nil {
+		t.Fatalf("LockMigrations() error = %v", err)
+	}
+	unlock()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL lock_timeout = 0`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(migrationLockKey).WillReturnError(errors.New("canceling statement due to user request"))
+	mock.ExpectRollback()
+	if _, err := LockMigrations(context.Background(), db); err == nil || !strings.Contains(err.Error(), "failed to take the migration lock") {
+		t.Errorf("LockMigrations() error = %v, want a lock failure", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -7,14 +7,30 @@ import (
 )
 
 // MigrationReverser handles the reversal of migration statements
-type MigrationReverser struct{}
+type MigrationReverser struct {
+	known map[string]string
+}
 
 func NewMigrationReverser() *MigrationReverser {
-	return &MigrationReverser{}
+	return &MigrationReverser{known: make(map[string]string)}
+}
+
+// RegisterReversal records the down statement for an up statement whose reversal cannot be
+// derived from its SQL alone, such as a drop that preserved its data
+func (mr *MigrationReverser) RegisterReversal(upSQL, downSQL string) {
+	if mr.known == nil {
+		mr.known = make(map[string]string)
+	}
+	mr.known[strings.TrimSpace(upSQL)] = downSQL
 }
 
 func (mr *MigrationReverser) ReverseSQL(sql string) (string, error) {
+	if reversed, exists := mr.known[strings.TrimSpace(sql)]; exists {
+		return reversed, nil
+	}
 
+	// Atlas prefixes statements with a comment describing them
+	sql = stripLeadingComments(sql)
 	normalizedSQL := strings.TrimSpace(strings.ToUpper(sql))
 
 	switch {
@@ -214,6 +214,11 @@ func TestMigrationReverser_DropOperations(t *testing.T) {
 			sql:      "DROP TRIGGER update_timestamp ON users",
 			expected: "-- WARNING: Cannot reverse DROP TRIGGER without original trigger definition",
 		},
+		{
+			name:     "Statement with Atlas comment",
+			sql:      "-- create index \"idx_users_email\" to table: \"users\"\nCREATE INDEX \"idx_users_email\" ON \"users\" (\"email\")",
+			expected: "DROP INDEX IF EXISTS \"idx_users_email\"",
+		},
 	}
 
 	reverser := NewMigrationReverser()
@@ -0,0 +1,287 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/introspect"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+// PlanFormat is the version of the plan file layout
+const PlanFormat = 1
+
+// Plan holds the statements a push would run, together with the state of the
+// live schema they were computed against. It is written by storm migrate plan
+// and applied only while the database still matches its preconditions.
+//
+// Hash is a SHA-256 without a key: it catches a plan that was corrupted or
+// edited by hand, not one altered on purpose, since whoever can edit the file
+// can recompute it. Keep plans where only trusted people can write them.
+type Plan struct {
+	Format        int             `json:"format"`
+	CreatedAt     time.Time       `json:"created_at"`
+	StormVersion  string          `json:"storm_version"`
+	ModelsHash    string          `json:"models_hash"` // Recorded in storm_schema_info once applied
+	Preconditions Preconditions   `json:"preconditions"`
+	Statements    []PlanStatement `json:"statements"`
+	Destructive   []string        `json:"destructive,omitempty"`
+	Warnings      []string        `json:"warnings,omitempty"`
+	Hash          string          `json:"hash"` // SHA-256 of the plan without this field
+}
+
+// PlanStatement is one statement of a plan
+type PlanStatement struct {
+	Description string `json:"description"`
+	SQL         string `json:"sql"`
+}
+
+// Preconditions describe the live schema a plan was computed against
+type Preconditions struct {
+	Database   string            `json:"database"`
+	SchemaHash string            `json:"schema_hash"` // Hash of all of Objects
+	Objects    map[string]string `json:"objects"`     // Hash of each table, view and enum, e.g. "table public.users"
+}
+
+// NewPlan returns the sealed plan of result, computed against preconditions
+func NewPlan(result *MigrationResult, preconditions Preconditions, stormVersion string) (*Plan, error) {
+	plan := &Plan{
+		Format:        PlanFormat,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+		StormVersion:  stormVersion,
+		ModelsHash:    result.ModelsHash,
+		Preconditions: preconditions,
+		Statements:    result.Statements,
+		Destructive:   result.DestructiveOps,
+		Warnings:      result.Warnings,
+	}
+	if plan.Statements == nil {
+		plan.Statements = []PlanStatement{}
+	}
+
+	hash, err := plan.computeHash()
+	if err != nil {
+		return nil, err
+	}
+	plan.Hash = hash
+	return plan, nil
+}
+
+// computeHash hashes the plan with its Hash left out
+func (p *Plan) computeHash() (string, error) {
+	unsealed := *p
+	unsealed.Hash = ""
+	data, err := json.Marshal(unsealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plan: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// WritePlan writes plan to path as indented JSON
+func WritePlan(path string, plan *Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// ReadPlan reads the plan at path and checks that it was not edited since it
+// was written
+func ReadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	if plan.Format != PlanFormat {
+		return nil, fmt.Errorf("plan %s has format %d, this version of storm reads format %d", path, plan.Format, PlanFormat)
+	}
+
+	hash, err := plan.computeHash()
+	if err != nil {
+		return nil, err
+	}
+	if plan.Hash == "" || hash != plan.Hash {
+		return nil, fmt.Errorf("plan %s does not match its hash: it was edited after storm migrate plan wrote it", path)
+	}
+	return &plan, nil
+}
+
+// InspectPreconditions fingerprints the tables, views and enums of the live
+// schema, leaving out the ignored objects and the tables storm keeps its own
+// records in
+func InspectPreconditions(ctx context.Context, db *sql.DB, ignore IgnoreRules, migrationsTable string) (Preconditions, error) {
+	dbSchema, err := introspect.NewInspector(db, "postgres").GetSchema(ctx)
+	if err != nil {
+		return Preconditions{}, fmt.Errorf("failed to inspect the database: %w", err)
+	}
+
+	ignore.Tables = append(append([]string{}, ignore.Tables...), migrationsTable, orm.SchemaInfoTable, orm.BackfillsTable)
+	return fingerprintSchema(dbSchema, ignore)
+}
+
+// fingerprintSchema hashes each object of dbSchema that is not ignored
+func fingerprintSchema(dbSchema *introspect.DatabaseSchema, ignore IgnoreRules) (Preconditions, error) {
+	objects := make(map[string]interface{})
+	for _, table := range dbSchema.Tables {
+		if ignore.IgnoresTable(table.Schema, table.Name) {
+			continue
+		}
+		objects["table "+table.Schema+"."+table.Name] = tableFingerprint(table, ignore)
+	}
+	for _, view := range dbSchema.Views {
+		if ignore.IgnoresSchema(view.Schema) {
+			continue
+		}
+		objects["view "+view.Schema+"."+view.Name] = struct {
+			Definition string
+			Columns    []*introspect.ColumnSchema
+		}{view.Definition, view.Columns}
+	}
+	for _, enum := range dbSchema.Enums {
+		if ignore.IgnoresSchema(enum.Schema) {
+			continue
+		}
+		objects["enum "+enum.Schema+"."+enum.Name] = enum.Values
+	}
+
+	preconditions := Preconditions{Database: dbSchema.Name, Objects: make(map[string]string, len(objects))}
+	for name, object := range objects {
+		data, err := json.Marshal(object)
+		if err != nil {
+			return Preconditions{}, fmt.Errorf("failed to fingerprint %s: %w", name, err)
+		}
+		preconditions.Objects[name] = hashDDL(string(data))
+	}
+
+	data, err := json.Marshal(preconditions.Objects)
+	if err != nil {
+		return Preconditions{}, fmt.Errorf("failed to fingerprint the schema: %w", err)
+	}
+	preconditions.SchemaHash = hashDDL(string(data))
+	return preconditions, nil
+}
+
+// tableFingerprint is the definition of table without its statistics and the
+// ignored columns and indexes
+func tableFingerprint(table *introspect.TableSchema, ignore IgnoreRules) introspect.TableSchema {
+	fingerprint := *table
+	fingerprint.RowCount = 0
+	fingerprint.SizeBytes = 0
+
+	fingerprint.Columns = nil
+	for _, column := range table.Columns {
+		if !ignore.IgnoresColumn(table.Name, column.Name) {
+			fingerprint.Columns = append(fingerprint.Columns, column)
+		}
+	}
+	fingerprint.Indexes = nil
+	for _, index := range table.Indexes {
+		if !ignore.IgnoresIndex(index.Name) {
+			fingerprint.Indexes = append(fingerprint.Indexes, index)
+		}
+	}
+
+	fingerprint.ForeignKeys = append([]*introspect.ForeignKeySchema{}, table.ForeignKeys...)
+	sort.Slice(fingerprint.ForeignKeys, func(i, j int) bool {
+		return fingerprint.ForeignKeys[i].Name < fingerprint.ForeignKeys[j].Name
+	})
+	return fingerprint
+}
+
+// Drift lists how live differs from the preconditions, empty when it matches
+func (p Preconditions) Drift(live Preconditions) []string {
+	var drift []string
+	if p.Database != live.Database {
+		drift = append(drift, fmt.Sprintf("plan was made for database %s, not %s", p.Database, live.Database))
+	}
+	if p.SchemaHash == live.SchemaHash {
+		return drift
+	}
+
+	var objects []string
+	for name, hash := range p.Objects {
+		liveHash, ok := live.Objects[name]
+		switch {
+		case !ok:
+			objects = append(objects, name+" was dropped")
+		case liveHash != hash:
+			objects = append(objects, name+" was changed")
+		}
+	}
+	for name := range live.Objects {
+		if _, ok := p.Objects[name]; !ok {
+			objects = append(objects, name+" was added")
+		}
+	}
+	sort.Strings(objects)
+	return append(drift, objects...)
+}
+
+// ApplyPlanOptions configures ApplyPlan
+type ApplyPlanOptions struct {
+	Ignore          IgnoreRules // Objects left out of the preconditions when the plan was made
+	MigrationsTable string      // Table of the applied migration files, left out of the preconditions
+	LockRetry       LockRetry   // Retry statements that fail on lock_timeout
+
+	// Confirm is shown the plan once its preconditions hold; returning false applies nothing
+	Confirm func(plan *Plan) (bool, error)
+}
+
+// ApplyPlan runs the statements of plan on db once its schema is confirmed to
+// still match the preconditions of the plan, and records the models hash. It
+// reports whether the statements were run.
+func ApplyPlan(ctx context.Context, db *sql.DB, plan *Plan, opts ApplyPlanOptions) (bool, error) {
+	// Hold the lock from the precondition check until the models hash is
+	// recorded, so that no other apply or push changes the schema in between
+	unlock, err := LockMigrations(ctx, db)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	live, err := InspectPreconditions(ctx, db, opts.Ignore, opts.MigrationsTable)
+	if err != nil {
+		return false, err
+	}
+	if drift := plan.Preconditions.Drift(live); len(drift) > 0 {
+		return false, fmt.Errorf("the database no longer matches the plan, run storm migrate plan again:\n  - %s", strings.Join(drift, "\n  - "))
+	}
+
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(plan)
+		if err != nil || !confirmed {
+			return false, err
+		}
+	}
+
+	statements := make([]string, len(plan.Statements))
+	for i, stmt := range plan.Statements {
+		statements[i] = stmt.SQL
+	}
+	if err := executeStatements(ctx, db, statements, opts.LockRetry); err != nil {
+		return false, err
+	}
+	if err := stampSchema(ctx, db, plan.ModelsHash, plan.StormVersion); err != nil {
+		return false, err
+	}
+	return true, nil
+}
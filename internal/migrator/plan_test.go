@@ -0,0 +1,125 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/introspect"
+)
+
+func TestPlanRoundTrip(t *testing.T) {
+	result := &MigrationResult{
+		ModelsHash: "abc",
+		Statements: []PlanStatement{
+			{Description: "Add column users.nickname", SQL: "ALTER TABLE users ADD COLUMN nickname text"},
+		},
+		DestructiveOps: []string{"Drop column users.bio"},
+	}
+	preconditions := Preconditions{Database: "app", SchemaHash: "def", Objects: map[string]string{"table public.users": "123"}}
+
+	plan, err := NewPlan(result, preconditions, "1.2.3")
+	if err != nil {
+		t.Fatalf("NewPlan() error = %v", err)
+	}
+	if plan.Hash == "" {
+		t.Fatal("NewPlan() left the plan unsealed")
+	}
+
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := WritePlan(path, plan); err != nil {
+		t.Fatalf("WritePlan() error = %v", err)
+	}
+	read, err := ReadPlan(path)
+	if err != nil {
+		t.Fatalf("ReadPlan() error = %v", err)
+	}
+	if !reflect.DeepEqual(read, plan) {
+		t.Errorf("ReadPlan() = %+v, want %+v", read, plan)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(data), "ADD COLUMN nickname text", "ADD COLUMN nickname varchar", 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPlan(path); err == nil || !strings.Contains(err.Error(), "does not match its hash") {
+		t.Errorf("ReadPlan() of an edited plan error = %v, want a hash mismatch", err)
+	}
+}
+
+func TestFingerprintSchema(t *testing.T) {
+	newSchema := func() *introspect.DatabaseSchema {
+		return &introspect.DatabaseSchema{
+			Name: "app",
+			Tables: map[string]*introspect.TableSchema{
+				"users": {
+					Name:   "users",
+					Schema: "public",
+					Columns: []*introspect.ColumnSchema{
+						{Name: "id", DataType: "uuid"},
+						{Name: "email", DataType: "text"},
+					},
+					ForeignKeys: []*introspect.ForeignKeySchema{
+						{Name: "fk_users_org", Columns: []string{"org_id"}},
+						{Name: "fk_users_team", Columns: []string{"team_id"}},
+					},
+					RowCount: 10,
+				},
+				"audit_log": {Name: "audit_log", Schema: "public"},
+			},
+			Enums: map[string]*introspect.EnumSchema{
+				"role": {Name: "role", Schema: "public", Values: []string{"admin", "member"}},
+			},
+		}
+	}
+	ignore := IgnoreRules{Tables: []string{"audit_log"}}
+
+	base, err := fingerprintSchema(newSchema(), ignore)
+	if err != nil {
+		t.Fatalf("fingerprintSchema() error = %v", err)
+	}
+	if _, ok := base.Objects["table public.audit_log"]; ok {
+		t.Error("fingerprintSchema() kept the ignored table")
+	}
+
+	same := newSchema()
+	same.Tables["users"].RowCount = 5000
+	same.Tables["users"].ForeignKeys[0], same.Tables["users"].ForeignKeys[1] = same.Tables["users"].ForeignKeys[1], same.Tables["users"].ForeignKeys[0]
+	same.Tables["audit_log"].Columns = []*introspect.ColumnSchema{{Name: "entry", DataType: "jsonb"}}
+	got, err := fingerprintSchema(same, ignore)
+	if err != nil {
+		t.Fatalf("fingerprintSchema() error = %v", err)
+	}
+	if drift := base.Drift(got); len(drift) != 0 {
+		t.Errorf("Drift() of the same schema = %v, want none", drift)
+	}
+
+	changed := newSchema()
+	changed.Tables["users"].Columns[1].DataType = "varchar"
+	changed.Enums["role"].Values = append(changed.Enums["role"].Values, "owner")
+	changed.Tables["teams"] = &introspect.TableSchema{Name: "teams", Schema: "public"}
+	got, err = fingerprintSchema(changed, ignore)
+	if err != nil {
+		t.Fatalf("fingerprintSchema() error = %v", err)
+	}
+	want := []string{"enum public.role was changed", "table public.teams was added", "table public.users was changed"}
+	if drift := base.Drift(got); !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() = %v, want %v", drift, want)
+	}
+}
+
+func TestPreconditionsDrift(t *testing.T) {
+	planned := Preconditions{Database: "app", SchemaHash: "1", Objects: map[string]string{"table public.users": "a", "table public.posts": "b"}}
+	live := Preconditions{Database: "app_staging", SchemaHash: "2", Objects: map[string]string{"table public.users": "a"}}
+
+	want := []string{"plan was made for database app, not app_staging", "table public.posts was dropped"}
+	if drift := planned.Drift(live); !reflect.DeepEqual(drift, want) {
+		t.Errorf("Drift() = %v, want %v", drift, want)
+	}
+}
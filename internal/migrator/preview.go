@@ -0,0 +1,184 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+// PreviewPrefix starts the name of every preview database, so that they can be
+// told apart from the databases they sit next to
+const PreviewPrefix = "storm_preview_"
+
+var previewNameRe = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// PreviewOptions controls how a preview database is provisioned
+type PreviewOptions struct {
+	Name              string                          // Suffix of the database name, e.g. a PR number; empty uses a timestamp
+	PackagePath       string                          // Models to create the schema from
+	Seeds             []string                        // SQL files, or directories of them, run in order after the schema
+	Strict            bool                            // Fail on unknown tag attributes and Go types instead of warning
+	ForeignKeys       generator.ForeignKeyConventions // Default actions and naming of foreign keys
+	UpdatedAtTriggers bool                            // Set updated_at columns with BEFORE UPDATE triggers
+	StormVersion      string                          // Recorded in storm_schema_info
+}
+
+// Preview is a provisioned preview database
+type Preview struct {
+	Name string // Database name
+	URL  string // Connection URL of the database
+}
+
+// PreviewName returns the database name of a preview
+func PreviewName(name string) (string, error) {
+	if name == "" {
+		name = time.Now().UTC().Format("20060102150405")
+	}
+	name = strings.ToLower(strings.NewReplacer("-", "_", ".", "_", "/", "_").Replace(name))
+	if !previewNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid preview name %q: use letters, digits, '-' and '_'", name)
+	}
+
+	name = strings.TrimPrefix(name, PreviewPrefix)
+	if len(PreviewPrefix)+len(name) > 63 {
+		return "", fmt.Errorf("preview name %q is too long", name)
+	}
+	return PreviewPrefix + name, nil
+}
+
+// CreatePreview creates a database on the server of config, applies the full
+// schema of the models to it and runs the seeds. A database that fails to
+// provision is dropped again.
+func CreatePreview(ctx context.Context, config *DBConfig, opts PreviewOptions) (*Preview, error) {
+	name, err := PreviewName(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := seedFiles(opts.Seeds)
+	if err != nil {
+		return nil, err
+	}
+
+	manager := NewTempDBManager(config)
+	adminDB, err := NewDBConfig(manager.buildAdminDBURL()).Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	defer adminDB.Close()
+
+	if _, err := adminDB.ExecContext(ctx, "CREATE DATABASE "+quoteIdentifier(name)); err != nil {
+		return nil, fmt.Errorf("failed to create preview database %s: %w", name, err)
+	}
+
+	preview := &Preview{Name: name, URL: manager.buildTempDBURL(name)}
+	if err := provisionPreview(ctx, preview, opts, seeds); err != nil {
+		_, _ = adminDB.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+quoteIdentifier(name))
+		return nil, err
+	}
+
+	return preview, nil
+}
+
+func provisionPreview(ctx context.Context, preview *Preview, opts PreviewOptions, seeds []string) error {
+	previewConfig := NewDBConfig(preview.URL)
+	db, err := previewConfig.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to preview database: %w", err)
+	}
+	defer db.Close()
+
+	_, err = NewAtlasMigrator(previewConfig).GenerateMigration(ctx, db, MigrationOptions{
+		PackagePath:       opts.PackagePath,
+		AllowDestructive:  true,
+		PushToDB:          true,
+		Strict:            opts.Strict,
+		ForeignKeys:       opts.ForeignKeys,
+		UpdatedAtTriggers: opts.UpdatedAtTriggers,
+		StormVersion:      opts.StormVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply schema: %w", err)
+	}
+
+	return runSeeds(ctx, db, seeds)
+}
+
+// runSeeds runs each seed file in its own transaction
+func runSeeds(ctx context.Context, db *sql.DB, seeds []string) error {
+	for _, seed := range seeds {
+		content, err := os.ReadFile(seed)
+		if err != nil {
+			return fmt.Errorf("failed to read seed %s: %w", seed, err)
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin seed transaction: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to run seed %s: %w", seed, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit seed %s: %w", seed, err)
+		}
+	}
+	return nil
+}
+
+// seedFiles expands directories to the .sql files they contain, sorted by name
+func seedFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list seeds in %s: %w", path, err)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// DropPreview drops a preview database on the server of config. Only databases
+// named like previews are dropped.
+func DropPreview(ctx context.Context, config *DBConfig, name string) error {
+	if !strings.HasPrefix(name, PreviewPrefix) {
+		var err error
+		if name, err = PreviewName(name); err != nil {
+			return err
+		}
+	}
+
+	adminDB, err := NewDBConfig(NewTempDBManager(config).buildAdminDBURL()).Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	defer adminDB.Close()
+
+	// Preview apps may still be connected
+	if _, err := adminDB.ExecContext(ctx, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()", name); err != nil {
+		return fmt.Errorf("failed to disconnect from preview database %s: %w", name, err)
+	}
+	if _, err := adminDB.ExecContext(ctx, "DROP DATABASE IF EXISTS "+quoteIdentifier(name)); err != nil {
+		return fmt.Errorf("failed to drop preview database %s: %w", name, err)
+	}
+	return nil
+}
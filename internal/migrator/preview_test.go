@@ -0,0 +1,63 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "pr-42", want: "storm_preview_pr_42"},
+		{name: "Feature/Login.Page", want: "storm_preview_feature_login_page"},
+		{name: "storm_preview_pr_7", want: "storm_preview_pr_7"},
+		{name: "pr 42", wantErr: true},
+		{name: "x'; DROP DATABASE main; --", wantErr: true},
+		{name: "a_very_long_branch_name_that_does_not_fit_in_an_identifier_at_all", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PreviewName(tt.name)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	generated, err := PreviewName("")
+	require.NoError(t, err)
+	assert.Regexp(t, `^storm_preview_\d{14}$`, generated)
+}
+
+func TestSeedFiles(t *testing.T) {
+	dir := t.TempDir()
+	seedDir := filepath.Join(dir, "seeds")
+	require.NoError(t, os.Mkdir(seedDir, 0755))
+	for _, name := range []string{"02_posts.sql", "01_users.sql", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(seedDir, name), []byte("SELECT 1;"), 0644))
+	}
+	extra := filepath.Join(dir, "admin.sql")
+	require.NoError(t, os.WriteFile(extra, []byte("SELECT 1;"), 0644))
+
+	files, err := seedFiles([]string{extra, seedDir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		extra,
+		filepath.Join(seedDir, "01_users.sql"),
+		filepath.Join(seedDir, "02_posts.sql"),
+	}, files)
+
+	_, err = seedFiles([]string{filepath.Join(dir, "missing.sql")})
+	assert.ErrorContains(t, err, "failed to read seed")
+}
@@ -0,0 +1,259 @@
+package migrator
+
+import (
+	"fmt"
+	"strings"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+// planPrimaryKeyChanges makes primary key changes applicable to tables other tables point
+// at. PostgreSQL refuses to drop a primary key, or change the type of one of its columns,
+// while foreign keys depend on it, and Atlas leaves unchanged foreign keys alone. Every
+// foreign key referencing an affected key column is therefore dropped before the table
+// is altered and, if it is still wanted, added back afterwards. The drops and adds are
+// returned apart from the remaining changes and must be planned on their own, since Atlas
+// orders the changes of a plan by table. The warnings describe the locking and
+// validation cost of each change.
+func planPrimaryKeyChanges(current, target *schema.Realm, changes []schema.Change) (drops, remaining, adds []schema.Change, warnings []string) {
+	handled := make(map[*schema.ForeignKey]bool)
+
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			continue
+		}
+
+		table := findTable(current, schemaName(modify.T), modify.T.Name)
+		if table == nil {
+			continue
+		}
+
+		columns, warning := primaryKeyChange(modify, table)
+		if len(columns) == 0 {
+			continue
+		}
+
+		dependents := referencingForeignKeys(current, table, columns)
+		for _, fk := range dependents {
+			if handled[fk] {
+				continue
+			}
+			handled[fk] = true
+
+			drops = append(drops, &schema.ModifyTable{T: fk.Table, Changes: []schema.Change{&schema.DropForeignKey{F: fk}}})
+			changes = removeForeignKeyChange(changes, fk)
+
+			targetFK := findForeignKey(target, fk)
+			if targetFK == nil {
+				continue
+			}
+			adds = append(adds, &schema.ModifyTable{T: targetFK.Table, Changes: []schema.Change{&schema.AddForeignKey{F: targetFK}}})
+			if !referencesUniqueColumns(targetFK) {
+				warnings = append(warnings, fmt.Sprintf("Foreign key %s on %s references %s(%s), which is no longer unique; recreating it will fail",
+					targetFK.Symbol, targetFK.Table.Name, targetFK.RefTable.Name, strings.Join(columnNames(targetFK.RefColumns), ", ")))
+			}
+		}
+
+		if len(dependents) > 0 {
+			warning += fmt.Sprintf("; %d dependent foreign key(s) are dropped and recreated, which revalidates every referencing row", len(dependents))
+		}
+		warnings = append(warnings, warning)
+	}
+
+	return drops, changes, adds, warnings
+}
+
+// primaryKeyChange returns the primary key columns of the current table a modification
+// affects and a description of what the change costs, or nil if the key is left as is
+func primaryKeyChange(modify *schema.ModifyTable, current *schema.Table) ([]string, string) {
+	for _, sub := range modify.Changes {
+		switch c := sub.(type) {
+		case *schema.ModifyPrimaryKey:
+			from, to := columnNames(partColumns(c.From)), columnNames(partColumns(c.To))
+			return from, fmt.Sprintf("Primary key of %s changes from (%s) to (%s): the table is locked while the key index is rebuilt",
+				modify.T.Name, strings.Join(from, ", "), strings.Join(to, ", "))
+		case *schema.DropPrimaryKey:
+			from := columnNames(partColumns(c.P))
+			return from, fmt.Sprintf("Primary key (%s) of %s is dropped: rows are no longer guaranteed to be unique",
+				strings.Join(from, ", "), modify.T.Name)
+		}
+	}
+
+	keys := columnNames(partColumns(current.PrimaryKey))
+	var columns []string
+	for _, sub := range modify.Changes {
+		c, ok := sub.(*schema.ModifyColumn)
+		if !ok || !c.Change.Is(schema.ChangeType) || !containsName(keys, c.From.Name) {
+			continue
+		}
+		columns = append(columns, c.From.Name)
+	}
+	if len(columns) == 0 {
+		return nil, ""
+	}
+	return columns, fmt.Sprintf("Type of primary key column(s) %s.(%s) changes: the table and its key index are rewritten under an exclusive lock",
+		modify.T.Name, strings.Join(columns, ", "))
+}
+
+// referencingForeignKeys returns the foreign keys in realm that reference any of columns of table
+func referencingForeignKeys(realm *schema.Realm, table *schema.Table, columns []string) []*schema.ForeignKey {
+	var fks []*schema.ForeignKey
+	for _, s := range realm.Schemas {
+		for _, t := range s.Tables {
+			for _, fk := range t.ForeignKeys {
+				if fk.RefTable == nil || fk.RefTable.Name != table.Name || schemaName(fk.RefTable) != schemaName(table) {
+					continue
+				}
+				for _, name := range columnNames(fk.RefColumns) {
+					if containsName(columns, name) {
+						fks = append(fks, fk)
+						break
+					}
+				}
+			}
+		}
+	}
+	return fks
+}
+
+// removeForeignKeyChange drops the changes Atlas planned for fk, which is recreated as a
+// whole around the primary key change instead
+func removeForeignKeyChange(changes []schema.Change, fk *schema.ForeignKey) []schema.Change {
+	remaining := make([]schema.Change, 0, len(changes))
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok || modify.T.Name != fk.Table.Name || schemaName(modify.T) != schemaName(fk.Table) {
+			remaining = append(remaining, change)
+			continue
+		}
+
+		kept := make([]schema.Change, 0, len(modify.Changes))
+		for _, sub := range modify.Changes {
+			switch c := sub.(type) {
+			case *schema.DropForeignKey:
+				if c.F.Symbol == fk.Symbol {
+					continue
+				}
+			case *schema.ModifyForeignKey:
+				if c.From.Symbol == fk.Symbol {
+					continue
+				}
+			}
+			kept = append(kept, sub)
+		}
+		modify.Changes = kept
+		if len(kept) > 0 {
+			remaining = append(remaining, modify)
+		}
+	}
+	return remaining
+}
+
+// findForeignKey returns the foreign key of the same name on the same table in realm
+func findForeignKey(realm *schema.Realm, fk *schema.ForeignKey) *schema.ForeignKey {
+	table := findTable(realm, schemaName(fk.Table), fk.Table.Name)
+	if table == nil {
+		return nil
+	}
+	for _, candidate := range table.ForeignKeys {
+		if candidate.Symbol == fk.Symbol {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// referencesUniqueColumns reports whether the columns fk references are covered by the
+// primary key or a unique index of the referenced table, as PostgreSQL requires
+func referencesUniqueColumns(fk *schema.ForeignKey) bool {
+	refs := columnNames(fk.RefColumns)
+	covers := func(idx *schema.Index) bool {
+		parts := columnNames(partColumns(idx))
+		if len(parts) != len(refs) {
+			return false
+		}
+		for _, name := range refs {
+			if !containsName(parts, name) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if fk.RefTable.PrimaryKey != nil && covers(fk.RefTable.PrimaryKey) {
+		return true
+	}
+	for _, idx := range fk.RefTable.Indexes {
+		if idx.Unique && covers(idx) {
+			return true
+		}
+	}
+	return false
+}
+
+func findTable(realm *schema.Realm, inSchema, name string) *schema.Table {
+	if realm == nil {
+		return nil
+	}
+	for _, s := range realm.Schemas {
+		if inSchema != "" && s.Name != inSchema {
+			continue
+		}
+		if table, ok := s.Table(name); ok {
+			return table
+		}
+	}
+	return nil
+}
+
+func schemaName(table *schema.Table) string {
+	if table.Schema == nil {
+		return ""
+	}
+	return table.Schema.Name
+}
+
+func partColumns(idx *schema.Index) []*schema.Column {
+	if idx == nil {
+		return nil
+	}
+	columns := make([]*schema.Column, 0, len(idx.Parts))
+	for _, part := range idx.Parts {
+		if part.C != nil {
+			columns = append(columns, part.C)
+		}
+	}
+	return columns
+}
+
+func columnNames(columns []*schema.Column) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// constraintReversals maps the ALTER TABLE statements that add or drop keys and
+// constraints to the statements Atlas uses to undo them. Only Atlas still knows the
+// definition of a dropped primary or foreign key when the down migration is built.
+func constraintReversals(statements, reverses []string) map[string]string {
+	reversals := make(map[string]string)
+
+	for i, stmt := range statements {
+		if i >= len(reverses) || reverses[i] == "" {
+			continue
+		}
+
+		cmd := strings.ToUpper(stripLeadingComments(stmt))
+		if !strings.HasPrefix(cmd, "ALTER TABLE") {
+			continue
+		}
+		if strings.Contains(cmd, "PRIMARY KEY") || strings.Contains(cmd, "FOREIGN KEY") || strings.Contains(cmd, "DROP CONSTRAINT") {
+			reversals[stmt] = reverses[i]
+		}
+	}
+
+	return reversals
+}
@@ -0,0 +1,163 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+// primaryKeyFixture builds users(id) referenced by orders(user_id), with users keyed by the
+// given columns
+func primaryKeyFixture(idType string, keyedByTenant bool) *schema.Realm {
+	public := schema.New("public")
+
+	id := schema.NewIntColumn("id", idType)
+	tenant := schema.NewIntColumn("tenant_id", "integer")
+	users := schema.NewTable("users").SetSchema(public).AddColumns(id, tenant)
+	if keyedByTenant {
+		users.SetPrimaryKey(schema.NewPrimaryKey(id, tenant))
+	} else {
+		users.SetPrimaryKey(schema.NewPrimaryKey(id))
+	}
+
+	orderID := schema.NewIntColumn("id", "integer")
+	userID := schema.NewIntColumn("user_id", idType)
+	orders := schema.NewTable("orders").SetSchema(public).AddColumns(orderID, userID)
+	orders.SetPrimaryKey(schema.NewPrimaryKey(orderID))
+	orders.AddForeignKeys(schema.NewForeignKey("orders_user_id_fkey").
+		AddColumns(userID).SetRefTable(users).AddRefColumns(id).SetOnDelete(schema.Cascade))
+
+	public.AddTables(users, orders)
+	return schema.NewRealm(public)
+}
+
+func realmTable(realm *schema.Realm, name string) *schema.Table {
+	table, _ := realm.Schemas[0].Table(name)
+	return table
+}
+
+func TestPlanPrimaryKeyChanges_CompositeKey(t *testing.T) {
+	current := primaryKeyFixture("integer", false)
+	target := primaryKeyFixture("integer", true)
+	users := realmTable(target, "users")
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyPrimaryKey{
+				From:   realmTable(current, "users").PrimaryKey,
+				To:     users.PrimaryKey,
+				Change: schema.ChangeParts,
+			},
+		}},
+	}
+
+	drops, remaining, adds, warnings := planPrimaryKeyChanges(current, target, changes)
+	if len(drops) != 1 || len(remaining) != 1 || len(adds) != 1 {
+		t.Fatalf("expected the foreign key to be dropped and recreated around the key change, got %d, %d and %d changes", len(drops), len(remaining), len(adds))
+	}
+	if drop, ok := drops[0].(*schema.ModifyTable).Changes[0].(*schema.DropForeignKey); !ok || drop.F.Symbol != "orders_user_id_fkey" {
+		t.Errorf("expected the dependent foreign key to be dropped, got %#v", drops[0])
+	}
+	if remaining[0] != changes[0] {
+		t.Error("expected the primary key change to be kept")
+	}
+	if add, ok := adds[0].(*schema.ModifyTable).Changes[0].(*schema.AddForeignKey); !ok || add.F.Table != realmTable(target, "orders") {
+		t.Errorf("expected the target foreign key to be added, got %#v", adds[0])
+	}
+
+	joined := strings.Join(warnings, "\n")
+	if !strings.Contains(joined, "Primary key of users changes from (id) to (id, tenant_id)") {
+		t.Errorf("expected a warning about the key change, got %q", warnings)
+	}
+	if !strings.Contains(joined, "1 dependent foreign key(s) are dropped and recreated") {
+		t.Errorf("expected a warning about the dependent foreign key, got %q", warnings)
+	}
+	if !strings.Contains(joined, "references users(id), which is no longer unique") {
+		t.Errorf("expected a warning that users(id) is no longer unique, got %q", warnings)
+	}
+}
+
+func TestPlanPrimaryKeyChanges_TypeChange(t *testing.T) {
+	current := primaryKeyFixture("integer", false)
+	target := primaryKeyFixture("bigint", false)
+	users, orders := realmTable(target, "users"), realmTable(target, "orders")
+	currentOrders := realmTable(current, "orders")
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.ModifyColumn{From: realmTable(current, "users").Columns[0], To: users.Columns[0], Change: schema.ChangeType},
+		}},
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{
+			&schema.ModifyColumn{From: currentOrders.Columns[1], To: orders.Columns[1], Change: schema.ChangeType},
+			&schema.ModifyForeignKey{From: currentOrders.ForeignKeys[0], To: orders.ForeignKeys[0], Change: schema.ChangeColumn},
+		}},
+	}
+
+	drops, remaining, adds, warnings := planPrimaryKeyChanges(current, target, changes)
+	if len(drops) != 1 || len(remaining) != 2 || len(adds) != 1 {
+		t.Fatalf("expected 1, 2 and 1 changes, got %d, %d and %d", len(drops), len(remaining), len(adds))
+	}
+	if modify := remaining[1].(*schema.ModifyTable); len(modify.Changes) != 1 {
+		t.Errorf("expected the foreign key change Atlas planned to be replaced, got %d changes", len(modify.Changes))
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Type of primary key column(s) users.(id) changes") {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+
+	driver := planningDriver(t)
+	statements, reverses, err := generateAtlasStatements(context.Background(), driver, drops)
+	if err != nil {
+		t.Fatalf("generateAtlasStatements() error = %v", err)
+	}
+	if len(statements) != 1 || !strings.Contains(statements[0], `DROP CONSTRAINT "orders_user_id_fkey"`) {
+		t.Fatalf("expected the foreign key to be dropped, got %q", statements)
+	}
+	added, _, err := generateAtlasStatements(context.Background(), driver, adds)
+	if err != nil {
+		t.Fatalf("generateAtlasStatements() error = %v", err)
+	}
+	if len(added) != 1 || !strings.Contains(added[0], `ADD CONSTRAINT "orders_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id")`) {
+		t.Errorf("expected the foreign key to be added back, got %q", added)
+	}
+
+	reverser := NewMigrationReverser()
+	for up, down := range constraintReversals(statements, reverses) {
+		reverser.RegisterReversal(up, down)
+	}
+	down, err := reverser.ReverseSQL(statements[0])
+	if err != nil {
+		t.Fatalf("ReverseSQL() error = %v", err)
+	}
+	if !strings.Contains(down, `ADD CONSTRAINT "orders_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON DELETE CASCADE`) {
+		t.Errorf("expected the dropped foreign key to be restored on rollback, got %s", down)
+	}
+}
+
+func TestPlanPrimaryKeyChanges_Unaffected(t *testing.T) {
+	current := primaryKeyFixture("integer", false)
+	target := primaryKeyFixture("integer", false)
+	users := realmTable(target, "users")
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: users, Changes: []schema.Change{
+			&schema.AddColumn{C: schema.NewStringColumn("email", "text")},
+		}},
+	}
+
+	drops, remaining, adds, warnings := planPrimaryKeyChanges(current, target, changes)
+	if len(drops) != 0 || len(remaining) != 1 || len(adds) != 0 || len(warnings) != 0 {
+		t.Errorf("expected changes that leave the key alone to pass through, got %d changes and %q", len(remaining), warnings)
+	}
+}
+
+func TestIsDestructiveChange_PrimaryKey(t *testing.T) {
+	pk := schema.NewPrimaryKey(schema.NewIntColumn("id", "integer"))
+	if !IsDestructiveChange(&schema.DropPrimaryKey{P: pk}) {
+		t.Error("expected dropping a primary key to be destructive")
+	}
+	if !IsDestructiveChange(&schema.ModifyPrimaryKey{From: pk, To: pk}) {
+		t.Error("expected changing a primary key to be destructive")
+	}
+}
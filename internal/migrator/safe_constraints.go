@@ -0,0 +1,124 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+// notNullViaCheckVersion is the first server_version_num whose SET NOT NULL skips the
+// table scan when a validated check constraint already proves the column has no NULLs
+const notNullViaCheckVersion = 120000
+
+// planSafeConstraints makes the constraints added to existing tables skip the scan that
+// holds an ACCESS EXCLUSIVE lock on the table while it runs. Checks and foreign keys are
+// added NOT VALID, which only affects new rows, and validated by a later statement that
+// lets reads and writes through. From PostgreSQL 12 on, a column made NOT NULL gets the
+// same treatment through a temporary check constraint, which SET NOT NULL then trusts
+// instead of scanning the table; older servers keep the plain SET NOT NULL. Constraints
+// of new tables are left alone, as there are no rows to scan.
+func planSafeConstraints(changes []schema.Change, serverVersion int) ([]schema.Change, []migrationStep) {
+	var steps []migrationStep
+	remaining := make([]schema.Change, 0, len(changes))
+
+	for _, change := range changes {
+		modify, ok := change.(*schema.ModifyTable)
+		if !ok {
+			remaining = append(remaining, change)
+			continue
+		}
+
+		kept := make([]schema.Change, 0, len(modify.Changes))
+		for _, sub := range modify.Changes {
+			switch c := sub.(type) {
+			case *schema.AddCheck:
+				c.Extra = append(c.Extra, &postgres.NotValid{})
+				steps = append(steps, validateConstraint(modify.T, c.C.Name))
+			case *schema.AddForeignKey:
+				c.Extra = append(c.Extra, &postgres.NotValid{})
+				steps = append(steps, validateConstraint(modify.T, c.F.Symbol))
+			case *schema.ModifyColumn:
+				if serverVersion >= notNullViaCheckVersion && c.Change.Is(schema.ChangeNull) && !c.To.Type.Null {
+					steps = append(steps, setNotNullViaCheck(modify.T, c.To.Name)...)
+					if c.Change &^= schema.ChangeNull; c.Change == schema.NoChange {
+						continue
+					}
+				}
+			}
+			kept = append(kept, sub)
+		}
+
+		modify.Changes = kept
+		if len(kept) > 0 {
+			remaining = append(remaining, modify)
+		}
+	}
+
+	return remaining, steps
+}
+
+func validateConstraint(table *schema.Table, name string) migrationStep {
+	return migrationStep{
+		Description: fmt.Sprintf("Validate constraint %s on %s", name, qualifiedName(table)),
+		Up: []string{
+			fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", quotedTable(table), quoteIdentifier(name)),
+		},
+	}
+}
+
+// setNotNullViaCheck returns the steps that make column NOT NULL behind a check
+// constraint: added NOT VALID, validated on its own, then replaced by the NOT NULL
+func setNotNullViaCheck(t *schema.Table, column string) []migrationStep {
+	// Truncated as PostgreSQL stores it, so that the later statements find it
+	check := quoteIdentifier(generator.TruncateIdentifier(fmt.Sprintf("%s_%s_not_null", t.Name, column)))
+	table, quotedTable, quotedColumn := qualifiedName(t), quotedTable(t), quoteIdentifier(column)
+
+	return []migrationStep{
+		{
+			Description: fmt.Sprintf("Check %s.%s has no NULLs for new rows", table, column),
+			Up: []string{
+				fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", quotedTable, check, quotedColumn),
+			},
+			Down: []string{
+				fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", quotedTable, check),
+			},
+		},
+		{
+			Description: fmt.Sprintf("Check the existing rows of %s.%s have no NULLs", table, column),
+			Up: []string{
+				fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", quotedTable, check),
+			},
+		},
+		{
+			Description: fmt.Sprintf("Make %s.%s NOT NULL", table, column),
+			Up: []string{
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", quotedTable, quotedColumn),
+				fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", quotedTable, check),
+			},
+			Down: []string{
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", quotedTable, quotedColumn),
+			},
+		},
+	}
+}
+
+// serverVersionNum returns the server_version_num of the server db connects to
+func serverVersionNum(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read the server version: %w", err)
+	}
+	return version, nil
+}
+
+var validateConstraintRe = regexp.MustCompile(`(?i)\bVALIDATE\s+CONSTRAINT\b`)
+
+// validatesConstraint reports whether sql validates a constraint added NOT VALID
+func validatesConstraint(sql string) bool {
+	return validateConstraintRe.MatchString(sql)
+}
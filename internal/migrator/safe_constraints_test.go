@@ -0,0 +1,124 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+	"github.com/eleven-am/storm/internal/generator"
+)
+
+func TestPlanSafeConstraints(t *testing.T) {
+	orders, fk := renameFixture("orders_user_id_fkey", schema.Cascade)
+	check := schema.NewCheck().SetName("orders_total_check").SetExpr("total >= 0")
+	total := schema.NewIntColumn("total", "integer")
+	nullable := schema.NewNullIntColumn("total", "integer")
+
+	changes := []schema.Change{
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{
+			&schema.AddCheck{C: check},
+			&schema.AddForeignKey{F: fk},
+			&schema.ModifyColumn{From: nullable, To: total, Change: schema.ChangeNull},
+		}},
+		&schema.AddTable{T: schema.NewTable("users")},
+	}
+
+	remaining, steps := planSafeConstraints(changes, 160000)
+
+	if len(remaining) != 2 {
+		t.Fatalf("expected both changes to be kept, got %d", len(remaining))
+	}
+	modify := remaining[0].(*schema.ModifyTable)
+	if len(modify.Changes) != 2 {
+		t.Fatalf("expected the NOT NULL change to be replaced by steps, got %#v", modify.Changes)
+	}
+	for _, sub := range modify.Changes {
+		var extra []schema.Clause
+		switch c := sub.(type) {
+		case *schema.AddCheck:
+			extra = c.Extra
+		case *schema.AddForeignKey:
+			extra = c.Extra
+		}
+		if len(extra) != 1 {
+			t.Errorf("expected %T to be added NOT VALID", sub)
+			continue
+		}
+		if _, ok := extra[0].(*postgres.NotValid); !ok {
+			t.Errorf("expected %T to be added NOT VALID, got %#v", sub, extra[0])
+		}
+	}
+
+	expected := []string{
+		`ALTER TABLE "public"."orders" VALIDATE CONSTRAINT "orders_total_check"`,
+		`ALTER TABLE "public"."orders" VALIDATE CONSTRAINT "orders_user_id_fkey"`,
+		`ALTER TABLE "public"."orders" ADD CONSTRAINT "orders_total_not_null" CHECK ("total" IS NOT NULL) NOT VALID`,
+		`ALTER TABLE "public"."orders" VALIDATE CONSTRAINT "orders_total_not_null"`,
+		"ALTER TABLE \"public\".\"orders\" ALTER COLUMN \"total\" SET NOT NULL;\nALTER TABLE \"public\".\"orders\" DROP CONSTRAINT \"orders_total_not_null\"",
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d", len(expected), len(steps))
+	}
+	for i, want := range expected {
+		if got := steps[i].UpSQL(); got != want {
+			t.Errorf("step %d = %s, want %s", i, got, want)
+		}
+	}
+	if got := steps[4].DownSQL(); got != `ALTER TABLE "public"."orders" ALTER COLUMN "total" DROP NOT NULL` {
+		t.Errorf("unexpected down SQL: %s", got)
+	}
+	if got := steps[0].DownSQL(); got != "" {
+		t.Errorf("expected a validation to need no reversal, got %s", got)
+	}
+}
+
+func TestPlanSafeConstraints_OldServerKeepsSetNotNull(t *testing.T) {
+	orders := schema.NewTable("orders")
+	change := &schema.ModifyColumn{
+		From:   schema.NewNullIntColumn("total", "integer"),
+		To:     schema.NewIntColumn("total", "integer"),
+		Change: schema.ChangeNull | schema.ChangeDefault,
+	}
+
+	remaining, steps := planSafeConstraints([]schema.Change{
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{change}},
+	}, 110000)
+
+	if len(steps) != 0 {
+		t.Errorf("expected no steps before PostgreSQL 12, got %d", len(steps))
+	}
+	if len(remaining) != 1 || change.Change != schema.ChangeNull|schema.ChangeDefault {
+		t.Errorf("expected the column change to be left alone, got %#v", remaining)
+	}
+
+	// From PostgreSQL 12 on, the rest of the column change is kept
+	_, steps = planSafeConstraints([]schema.Change{
+		&schema.ModifyTable{T: orders, Changes: []schema.Change{change}},
+	}, 120000)
+	if len(steps) != 3 || change.Change != schema.ChangeDefault {
+		t.Errorf("expected the NOT NULL to move to steps and the default change to stay, got %d steps and %v", len(steps), change.Change)
+	}
+}
+
+func TestSetNotNullViaCheck_LongNames(t *testing.T) {
+	invoices := schema.NewTable("customer_subscription_invoices").SetSchema(schema.New("billing"))
+	column := "reminder_" + strings.Repeat("x", 40)
+	check := generator.TruncateIdentifier("customer_subscription_invoices_" + column + "_not_null")
+	if len(check) != generator.MaxIdentifierLength {
+		t.Fatalf("expected the check name to be truncated, got %s", check)
+	}
+
+	steps := setNotNullViaCheck(invoices, column)
+	expected := []string{
+		`ALTER TABLE "billing"."customer_subscription_invoices" ADD CONSTRAINT "` + check + `" CHECK ("` + column + `" IS NOT NULL) NOT VALID`,
+		`ALTER TABLE "billing"."customer_subscription_invoices" VALIDATE CONSTRAINT "` + check + `"`,
+		`ALTER TABLE "billing"."customer_subscription_invoices" ALTER COLUMN "` + column + `" SET NOT NULL;` + "\n" +
+			`ALTER TABLE "billing"."customer_subscription_invoices" DROP CONSTRAINT "` + check + `"`,
+	}
+	for i, want := range expected {
+		if got := steps[i].UpSQL(); got != want {
+			t.Errorf("step %d = %s, want %s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+
+// ModelsHash returns the hash storm_schema_info records for the models in
+// packagePath, so a database can be checked against a checkout of the models
+func ModelsHash(packagePath string, foreignKeys generator.ForeignKeyConventions) (string, error) {
+	models, err := parser.NewStructParser().ParseDirectory(packagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse structs: %w", err)
+	}
+
+	schemaGenerator := generator.NewSchemaGenerator()
+	schemaGenerator.SetForeignKeyConventions(foreignKeys)
+	schema, err := schemaGenerator.GenerateSchema(models)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	return hashDDL(generator.NewSQLGenerator().GenerateSchema(schema)), nil
+}
+
+func hashDDL(ddl string) string {
+	sum := sha256.Sum256([]byte(ddl))
+	return hex.EncodeToString(sum[:])
+}
+
+// stampSchema records the models hash and storm version in storm_schema_info,
+// unless the latest row already holds them
+func stampSchema(ctx context.Context, db *sql.DB, modelsHash, stormVersion string) error {
+	create := `CREATE TABLE IF NOT EXISTS ` + orm.SchemaInfoTable + ` (
+    id BIGSERIAL PRIMARY KEY,
+    models_hash TEXT NOT NULL,
+    storm_version TEXT NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("failed to create %s: %w", orm.SchemaInfoTable, err)
+	}
+
+	var latestHash, latestVersion string
+	err := db.QueryRowContext(ctx, "SELECT models_hash, storm_version FROM "+orm.SchemaInfoTable+" ORDER BY id DESC LIMIT 1").Scan(&latestHash, &latestVersion)
+	switch {
+	case err == nil && latestHash == modelsHash && latestVersion == stormVersion:
+		return nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to read %s: %w", orm.SchemaInfoTable, err)
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO "+orm.SchemaInfoTable+" (models_hash, storm_version) VALUES ($1, $2)", modelsHash, stormVersion); err != nil {
+		return fmt.Errorf("failed to stamp %s: %w", orm.SchemaInfoTable, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package migrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelsHash(t *testing.T) {
+	dir := t.TempDir()
+	model := `package models
+
+type User struct {
+	_  struct{} ` + "`" + `storm:"table:users"` + "`" + `
+	ID string ` + "`" + `db:"id" storm:"type:uuid;primary_key"` + "`" + `
+}
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.go"), []byte(model), 0644))
+
+	first, err := ModelsHash(dir, generator.ForeignKeyConventions{})
+	require.NoError(t, err)
+	second, err := ModelsHash(dir, generator.ForeignKeyConventions{})
+	require.NoError(t, err)
+	assert.Len(t, first, 64)
+	assert.Equal(t, first, second)
+
+	changed := model[:len(model)-2] + "\tEmail string `db:\"email\" storm:\"type:text\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user.go"), []byte(changed), 0644))
+	third, err := ModelsHash(dir, generator.ForeignKeyConventions{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestStampSchema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// First stamp of the database
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS storm_schema_info`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT models_hash, storm_version FROM storm_schema_info`).
+		WillReturnRows(sqlmock.NewRows([]string{"models_hash", "storm_version"}))
+	mock.ExpectExec(`INSERT INTO storm_schema_info \(models_hash, storm_version\) VALUES \(\$1, \$2\)`).
+		WithArgs("abc", "1.0.0").WillReturnResult(sqlmock.NewResult(1, 1))
+	require.NoError(t, stampSchema(context.Background(), db, "abc", "1.0.0"))
+
+	// Unchanged models are not stamped again
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS storm_schema_info`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT models_hash, storm_version FROM storm_schema_info`).
+		WillReturnRows(sqlmock.NewRows([]string{"models_hash", "storm_version"}).AddRow("abc", "1.0.0"))
+	require.NoError(t, stampSchema(context.Background(), db, "abc", "1.0.0"))
+
+	// Changed models are
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS storm_schema_info`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT models_hash, storm_version FROM storm_schema_info`).
+		WillReturnRows(sqlmock.NewRows([]string{"models_hash", "storm_version"}).AddRow("abc", "1.0.0"))
+	mock.ExpectExec(`INSERT INTO storm_schema_info`).
+		WithArgs("def", "1.0.0").WillReturnResult(sqlmock.NewResult(2, 1))
+	require.NoError(t, stampSchema(context.Background(), db, "def", "1.0.0"))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,91 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+// serverFeature is SQL syntax that older PostgreSQL releases reject
+type serverFeature struct {
+	name    string
+	version int // First server_version_num accepting it
+	pattern *regexp.Regexp
+}
+
+// serverFeatures lists the syntax migrations may use that needs a recent server.
+// CREATE INDEX CONCURRENTLY on partitioned tables is not listed, as no release
+// supports it: useConcurrentIndexes builds those indexes without it.
+var serverFeatures = []serverFeature{
+	{
+		name:    "generated columns",
+		version: 120000,
+		pattern: regexp.MustCompile(`(?i)\bGENERATED\s+ALWAYS\s+AS\s*\(`),
+	},
+	{
+		name:    "column compression settings",
+		version: 140000,
+		pattern: regexp.MustCompile(`(?i)\bSET\s+COMPRESSION\b`),
+	},
+	{
+		name:    "nulls_not_distinct unique constraints",
+		version: 150000,
+		pattern: regexp.MustCompile(`(?i)\bNULLS\s+NOT\s+DISTINCT\b`),
+	},
+	{
+		name:    "MERGE statements",
+		version: 150000,
+		pattern: regexp.MustCompile(`(?im)^\s*MERGE\s+INTO\b`),
+	},
+}
+
+// unsupportedFeature returns the first feature sql uses that a server of version
+// rejects, or nil
+func unsupportedFeature(sql string, version int) *serverFeature {
+	for i, feature := range serverFeatures {
+		if version < feature.version && feature.pattern.MatchString(sql) {
+			return &serverFeatures[i]
+		}
+	}
+	return nil
+}
+
+// CheckServerFeatures refuses sql when it uses syntax the server db connects to is
+// too old for, such as NULLS NOT DISTINCT before PostgreSQL 15, which would otherwise
+// fail on the syntax alone, possibly halfway through a migration. The server version
+// is only read when sql uses such syntax.
+func CheckServerFeatures(ctx context.Context, db *sql.DB, sql string) error {
+	used := false
+	for _, feature := range serverFeatures {
+		if feature.pattern.MatchString(sql) {
+			used = true
+			break
+		}
+	}
+	if !used {
+		return nil
+	}
+
+	version, err := serverVersionNum(ctx, db)
+	if err != nil {
+		return err
+	}
+	if feature := unsupportedFeature(sql, version); feature != nil {
+		return fmt.Errorf("%s require PostgreSQL %s or later, the server runs %s",
+			feature.name, formatServerVersion(feature.version), formatServerVersion(version))
+	}
+	return nil
+}
+
+// formatServerVersion returns the release a server_version_num stands for, such as
+// 15 for 150000 or 14.10 for 140010. Releases before 10 have three parts.
+func formatServerVersion(version int) string {
+	if version >= 100000 {
+		if minor := version % 10000; minor != 0 {
+			return fmt.Sprintf("%d.%d", version/10000, minor)
+		}
+		return fmt.Sprintf("%d", version/10000)
+	}
+	return fmt.Sprintf("%d.%d.%d", version/10000, version/100%100, version%100)
+}
@@ -0,0 +1,57 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCheckServerFeatures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	plain := `CREATE UNIQUE INDEX idx_users_phone ON users (phone);`
+	if err := CheckServerFeatures(context.Background(), db, plain); err != nil {
+		t.Errorf("expected DDL without recent syntax to pass unchecked, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		sql     string
+		version int
+		wantErr string
+	}{
+		{"nulls not distinct on 14", `CREATE UNIQUE INDEX idx_users_phone ON users (phone) NULLS NOT DISTINCT;`, 140010, "nulls_not_distinct unique constraints require PostgreSQL 15 or later, the server runs 14.10"},
+		{"nulls not distinct on 15", `CREATE UNIQUE INDEX idx_users_phone ON users (phone) NULLS NOT DISTINCT;`, 150004, ""},
+		{"generated column on 11", `CREATE TABLE t (a int, b int GENERATED ALWAYS AS (a * 2) STORED);`, 110022, "generated columns require PostgreSQL 12 or later"},
+		{"identity column on 11", `CREATE TABLE t (id int GENERATED ALWAYS AS IDENTITY);`, 110022, ""},
+		{"merge on 14", "-- Backfill\nMERGE INTO totals t USING daily d ON t.id = d.id\nWHEN MATCHED THEN UPDATE SET n = d.n;", 140000, "MERGE statements require PostgreSQL 15 or later, the server runs 14"},
+		{"merge on 9.6", "MERGE INTO totals t USING daily d ON t.id = d.id WHEN MATCHED THEN DELETE;", 90624, "the server runs 9.6.24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(tt.sql, "IDENTITY") {
+				mock.ExpectQuery("server_version_num").WillReturnRows(sqlmock.NewRows([]string{"current_setting"}).AddRow(tt.version))
+			}
+			err := CheckServerFeatures(context.Background(), db, tt.sql)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected %d to be accepted, got %v", tt.version, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,332 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"ariga.io/atlas/sql/postgres"
+	"ariga.io/atlas/sql/schema"
+)
+
+// SquashOptions selects the migration files Squash collapses
+type SquashOptions struct {
+	Dir    string      // Directory holding the .up.sql and .down.sql files
+	To     string      // Version of the last migration squashed, empty for the newest
+	DryRun bool        // Return the baseline without rewriting the directory
+	Ignore IgnoreRules // Objects managed by extensions and other tools, left out of the comparison
+}
+
+// SquashResult is the baseline migration replacing the squashed ones
+type SquashResult struct {
+	Version  string   // Version of the baseline: that of the last squashed migration
+	Squashed []string // Versions collapsed into the baseline, oldest first
+	UpSQL    string
+	DownSQL  string
+	Carried  int // Statements copied as written, as the schema comparison does not see their objects
+}
+
+// squashedStatement is a statement of a squashed migration
+type squashedStatement struct {
+	Version string
+	SQL     string
+}
+
+// schemaStatement matches the statements whose result Atlas inspects, which the
+// baseline replaces by the statements creating the cumulative schema at once
+var schemaStatement = regexp.MustCompile(`(?is)^((CREATE|ALTER|DROP)\s+(UNIQUE\s+)?(TABLE|INDEX|SCHEMA|TYPE)\b|COMMENT\s+ON\s+(TABLE|COLUMN)\b|ANALYZE\b|VACUUM\b)`)
+
+// carriedTableChange matches the ALTER TABLE statements changing what Atlas does not inspect
+var carriedTableChange = regexp.MustCompile(`(?i)\b(ROW\s+LEVEL\s+SECURITY|TRIGGER|OWNER\s+TO|REPLICA\s+IDENTITY|CLUSTER\s+ON)\b`)
+
+// definitionStatement matches the statements on objects Atlas does not inspect that
+// tables can depend on, such as the functions column defaults call. The baseline
+// runs them before the tables.
+var definitionStatement = regexp.MustCompile(`(?is)^((CREATE|ALTER|DROP)\s+(OR\s+REPLACE\s+)?(EXTENSION|FUNCTION|PROCEDURE|SEQUENCE|DOMAIN|COLLATION)\b|CREATE\s+TYPE\s+\S+\s+AS\s*(\(|RANGE\b))`)
+
+// Squash collapses the migrations of opts.Dir up to opts.To into a single baseline
+// migration. The squashed migrations are applied to a temporary database, and the
+// baseline creates the schema they add up to, as Atlas inspects it, together with
+// the statements on objects Atlas does not inspect, such as functions, triggers
+// and inserted rows, copied in their original order. The baseline is applied to a
+// second temporary database and refused unless both schemas match.
+//
+// The baseline takes the version of the last squashed migration, so databases
+// that applied it skip the baseline, and its down file runs the down files of the
+// squashed migrations, newest first. Unless opts.DryRun is set, the files of the
+// squashed migrations are replaced by the baseline.
+func Squash(ctx context.Context, config *DBConfig, opts SquashOptions) (*SquashResult, error) {
+	if err := opts.Ignore.Validate(); err != nil {
+		return nil, err
+	}
+
+	versions, err := squashVersions(opts.Dir, opts.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []squashedStatement
+	for _, version := range versions {
+		content, err := os.ReadFile(filepath.Join(opts.Dir, version+".up.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+		for _, stmt := range SplitStatements(string(content)) {
+			if strings.Contains(strings.ToUpper(stmt), "CREATE DATABASE") {
+				continue
+			}
+			statements = append(statements, squashedStatement{Version: version, SQL: stmt})
+		}
+	}
+
+	tempDBManager := NewTempDBManager(config)
+	historyDB, cleanup, err := tempDBManager.CreateTempDB(ctx, fmt.Sprintf("temp_squash_%d", time.Now().Unix()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp database: %w", err)
+	}
+	defer cleanup()
+
+	for _, stmt := range statements {
+		if _, err := historyDB.ExecContext(ctx, stmt.SQL); err != nil {
+			return nil, fmt.Errorf("failed to apply migration %s to the temp database: %s: %w", stmt.Version, stmt.SQL, err)
+		}
+	}
+
+	driver, err := postgres.Open(historyDB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create driver: %w", err)
+	}
+	realm, err := driver.InspectRealm(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect squashed schema: %w", err)
+	}
+	storage, err := inspectColumnStorage(ctx, historyDB)
+	if err != nil {
+		return nil, err
+	}
+	options, err := indexStorageOptions(ctx, historyDB)
+	if err != nil {
+		return nil, err
+	}
+
+	empty := &schema.Realm{
+		Schemas: []*schema.Schema{
+			{
+				Name:   "public",
+				Tables: []*schema.Table{},
+			},
+		},
+	}
+	changes, err := driver.RealmDiff(empty, realm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate diff: %w", err)
+	}
+	changes = filterIgnoredChanges(changes, opts.Ignore)
+
+	schemaStatements, _, err := generateAtlasStatements(ctx, driver, changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SQL: %w", err)
+	}
+	schemaStatements = withIndexStorageOptions(schemaStatements, options)
+
+	descriptions := make([]string, len(schemaStatements))
+	for i := range schemaStatements {
+		if i < len(changes) {
+			descriptions[i] = DescribeChange(changes[i])
+		} else {
+			descriptions[i] = "Generated statement"
+		}
+	}
+	order := orderChangesForUpMigration(schemaStatements)
+	orderedStatements := make([]string, len(order))
+	orderedDescriptions := make([]string, len(order))
+	for i, idx := range order {
+		orderedStatements[i] = schemaStatements[idx]
+		orderedDescriptions[i] = descriptions[idx]
+	}
+	for _, step := range planColumnStorage(realm, storage, nil) {
+		for _, stmt := range step.Up {
+			orderedStatements = append(orderedStatements, stmt)
+			orderedDescriptions = append(orderedDescriptions, step.Description)
+		}
+	}
+
+	definitions, carried := carriedStatements(statements)
+
+	first, last := versions[0], versions[len(versions)-1]
+	var upBuilder strings.Builder
+	upBuilder.WriteString(fmt.Sprintf("-- Baseline squashing migrations %s to %s, generated by storm migrate squash\n", first, last))
+	upBuilder.WriteString("-- Generated at: " + time.Now().UTC().Format(time.RFC3339) + "\n")
+	upBuilder.WriteString(fmt.Sprintf("-- Databases that applied %s skip this migration.\n\n", last))
+	writeCarried := func(carried []squashedStatement) {
+		for _, stmt := range carried {
+			upBuilder.WriteString(fmt.Sprintf("-- Carried over from %s\n", stmt.Version))
+			upBuilder.WriteString(stmt.SQL)
+			if !strings.HasSuffix(stmt.SQL, ";") {
+				upBuilder.WriteString(";")
+			}
+			upBuilder.WriteString("\n\n")
+		}
+	}
+	writeCarried(definitions)
+	for i, stmt := range orderedStatements {
+		upBuilder.WriteString(fmt.Sprintf("-- Statement %d: %s\n", i+1, orderedDescriptions[i]))
+		upBuilder.WriteString(stmt)
+		if !strings.HasSuffix(stmt, ";") {
+			upBuilder.WriteString(";")
+		}
+		upBuilder.WriteString("\n\n")
+	}
+	writeCarried(carried)
+
+	var downBuilder strings.Builder
+	downBuilder.WriteString(fmt.Sprintf("-- Baseline squashing migrations %s to %s, generated by storm migrate squash\n", first, last))
+	downBuilder.WriteString("-- Runs the down migrations of the squashed migrations, newest first.\n\n")
+	for i := len(versions) - 1; i >= 0; i-- {
+		content, err := os.ReadFile(filepath.Join(opts.Dir, versions[i]+".down.sql"))
+		if os.IsNotExist(err) {
+			downBuilder.WriteString(fmt.Sprintf("-- %s has no down migration\n\n", versions[i]))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read down migration %s: %w", versions[i], err)
+		}
+		downBuilder.WriteString(fmt.Sprintf("-- Down migration of %s\n", versions[i]))
+		downBuilder.WriteString(strings.TrimSpace(string(content)) + "\n\n")
+	}
+
+	result := &SquashResult{
+		Version:  last,
+		Squashed: versions,
+		UpSQL:    upBuilder.String(),
+		DownSQL:  downBuilder.String(),
+		Carried:  len(definitions) + len(carried),
+	}
+
+	if err := verifySquash(ctx, tempDBManager, result.UpSQL, realm, storage, opts.Ignore); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	if err := os.WriteFile(filepath.Join(opts.Dir, last+".up.sql"), []byte(result.UpSQL), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write up migration: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(opts.Dir, last+".down.sql"), []byte(result.DownSQL), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write down migration: %w", err)
+	}
+	meta := MigrationMeta{Changes: []string{fmt.Sprintf("Baseline squashing migrations %s to %s", first, last)}}
+	if err := WriteMigrationMeta(opts.Dir, last, meta); err != nil {
+		return nil, err
+	}
+	for _, version := range versions[:len(versions)-1] {
+		for _, suffix := range []string{".up.sql", ".down.sql", ".meta.json"} {
+			if err := os.Remove(filepath.Join(opts.Dir, version+suffix)); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to remove squashed migration %s: %w", version, err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// verifySquash applies upSQL to a temporary database and refuses it unless the
+// schema it creates matches realm and storage, which the squashed migrations created
+func verifySquash(ctx context.Context, tempDBManager *TempDBManager, upSQL string, realm *schema.Realm, storage map[string]columnStorage, ignore IgnoreRules) error {
+	baselineDB, cleanup, err := tempDBManager.CreateTempDB(ctx, fmt.Sprintf("temp_squash_verify_%d", time.Now().Unix()))
+	if err != nil {
+		return fmt.Errorf("failed to create temp database: %w", err)
+	}
+	defer cleanup()
+
+	for _, stmt := range SplitStatements(upSQL) {
+		if _, err := baselineDB.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply the baseline to the temp database: %s: %w", stmt, err)
+		}
+	}
+
+	driver, err := postgres.Open(baselineDB)
+	if err != nil {
+		return fmt.Errorf("failed to create driver: %w", err)
+	}
+	baselineRealm, err := driver.InspectRealm(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to inspect baseline schema: %w", err)
+	}
+	baselineStorage, err := inspectColumnStorage(ctx, baselineDB)
+	if err != nil {
+		return err
+	}
+
+	changes, err := driver.RealmDiff(baselineRealm, realm)
+	if err != nil {
+		return fmt.Errorf("failed to calculate diff: %w", err)
+	}
+	changes = filterIgnoredChanges(filterEquivalentCheckChanges(changes), ignore)
+
+	var differences []string
+	for _, change := range changes {
+		differences = append(differences, DescribeChange(change))
+	}
+	for _, step := range planColumnStorage(realm, storage, baselineStorage) {
+		differences = append(differences, step.Description)
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("the baseline does not reproduce the squashed schema, it would need:\n  - %s", strings.Join(differences, "\n  - "))
+	}
+	return nil
+}
+
+// squashVersions returns the versions of the migrations in dir up to and including
+// to, or all of them when to is empty, oldest first
+func squashVersions(dir, to string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob migration files: %w", err)
+	}
+
+	var versions []string
+	found := to == ""
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".up.sql")
+		versions = append(versions, version)
+		if version == to {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("migration %s not found in %s", to, dir)
+	}
+	if len(versions) < 2 {
+		return nil, fmt.Errorf("nothing to squash: %s holds %d migration files to squash, at least 2 are needed", dir, len(versions))
+	}
+	return versions, nil
+}
+
+// carriedStatements returns the statements of the squashed migrations the baseline
+// copies as written, as the schema comparison does not see their objects: the
+// definitions the tables can depend on, and the others, such as triggers and
+// inserted rows, which run after the tables
+func carriedStatements(statements []squashedStatement) (definitions, carried []squashedStatement) {
+	for _, stmt := range statements {
+		sql := stripLeadingComments(stmt.SQL)
+		switch {
+		case definitionStatement.MatchString(sql):
+			definitions = append(definitions, stmt)
+		case schemaStatement.MatchString(sql):
+			if strings.HasPrefix(strings.ToUpper(sql), "ALTER TABLE") && carriedTableChange.MatchString(sql) {
+				carried = append(carried, stmt)
+			}
+		default:
+			carried = append(carried, stmt)
+		}
+	}
+	return definitions, carried
+}
@@ -0,0 +1,120 @@
+package migrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSquashVersions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"20240101000000_create_users.up.sql",
+		"20240101000000_create_users.down.sql",
+		"20240201000000_add_posts.up.sql",
+		"20240301000000_add_tags.up.sql",
+		"notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	versions, err := squashVersions(dir, "20240201000000_add_posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"20240101000000_create_users", "20240201000000_add_posts"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("squashVersions up to a version = %v, want %v", versions, want)
+	}
+
+	versions, err = squashVersions(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[2] != "20240301000000_add_tags" {
+		t.Errorf("expected every migration without a version, got %v", versions)
+	}
+
+	if _, err := squashVersions(dir, "20240401000000_missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected an unknown version to be refused, got %v", err)
+	}
+	if _, err := squashVersions(dir, "20240101000000_create_users"); err == nil || !strings.Contains(err.Error(), "nothing to squash") {
+		t.Errorf("expected a single migration to be refused, got %v", err)
+	}
+}
+
+func TestCarriedStatements(t *testing.T) {
+	var statements []squashedStatement
+	for _, sql := range []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		"-- ID generation functions\nCREATE OR REPLACE FUNCTION gen_cuid() RETURNS text AS $$ SELECT 'c' $$ LANGUAGE sql",
+		"CREATE TABLE users (id text PRIMARY KEY DEFAULT gen_cuid())",
+		"CREATE UNIQUE INDEX idx_users_email ON users (email)",
+		"ALTER TABLE users ADD COLUMN email text",
+		"ALTER TABLE users ENABLE ROW LEVEL SECURITY",
+		"CREATE TYPE status AS ENUM ('active', 'archived')",
+		"CREATE TYPE money_range AS RANGE (subtype = numeric)",
+		"CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION storm_touch()",
+		"COMMENT ON COLUMN users.email IS 'Login'",
+		"INSERT INTO users (id) VALUES ('admin')",
+		"ANALYZE users",
+		"DROP FUNCTION gen_cuid()",
+	} {
+		statements = append(statements, squashedStatement{Version: "v1", SQL: sql})
+	}
+
+	definitions, carried := carriedStatements(statements)
+
+	var got []string
+	for _, stmt := range definitions {
+		got = append(got, stripLeadingComments(stmt.SQL))
+	}
+	want := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		"CREATE OR REPLACE FUNCTION gen_cuid() RETURNS text AS $$ SELECT 'c' $$ LANGUAGE sql",
+		"CREATE TYPE money_range AS RANGE (subtype = numeric)",
+		"DROP FUNCTION gen_cuid()",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("definitions = %q, want %q", got, want)
+	}
+
+	got = nil
+	for _, stmt := range carried {
+		got = append(got, stmt.SQL)
+	}
+	want = []string{
+		"ALTER TABLE users ENABLE ROW LEVEL SECURITY",
+		"CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION storm_touch()",
+		"INSERT INTO users (id) VALUES ('admin')",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("carried = %q, want %q", got, want)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := `-- Migration UP
+CREATE TABLE users (id int);
+
+-- Statement 2: touch
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+	NEW.updated_at = now();
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- trailing comment`
+
+	statements := SplitStatements(sql)
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d: %q", len(statements), statements)
+	}
+	if !strings.Contains(statements[1], "RETURN NEW;") || !strings.HasSuffix(statements[1], "LANGUAGE plpgsql;") {
+		t.Errorf("expected the dollar-quoted body to stay in one statement, got %q", statements[1])
+	}
+}
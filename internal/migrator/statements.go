@@ -0,0 +1,75 @@
+package migrator
+
+import "strings"
+
+// SplitStatements splits a migration file into its statements on the semicolons
+// outside dollar-quoted strings, leaving out the ones holding only comments
+func SplitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	inDollarQuote := false
+
+	runes := []rune(sql)
+	i := 0
+
+	for i < len(runes) {
+		char := runes[i]
+
+		// Check for dollar quotes
+		if char == '$' && i+1 < len(runes) && runes[i+1] == '$' {
+			if !inDollarQuote {
+				// Starting dollar quote
+				inDollarQuote = true
+				current.WriteRune(char)
+				current.WriteRune(runes[i+1])
+				i += 2
+				continue
+			} else {
+				// Ending dollar quote
+				inDollarQuote = false
+				current.WriteRune(char)
+				current.WriteRune(runes[i+1])
+				i += 2
+				continue
+			}
+		}
+
+		// Check for statement terminator
+		if !inDollarQuote && char == ';' {
+			current.WriteRune(char)
+			stmt := strings.TrimSpace(current.String())
+			// Only add non-empty statements that aren't just comments
+			if stmt != "" && !isOnlyComments(stmt) {
+				statements = append(statements, stmt)
+			}
+			current.Reset()
+			i++
+			continue
+		}
+
+		current.WriteRune(char)
+		i++
+	}
+
+	// Add any remaining content
+	if current.Len() > 0 {
+		stmt := strings.TrimSpace(current.String())
+		if stmt != "" && !isOnlyComments(stmt) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	return statements
+}
+
+// isOnlyComments checks if a statement contains only comments and whitespace
+func isOnlyComments(stmt string) bool {
+	lines := strings.Split(stmt, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			return false
+		}
+	}
+	return true
+}
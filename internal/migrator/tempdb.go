@@ -25,6 +25,10 @@ func (tm *TempDBManager) CreateTempDB(ctx context.Context, tempDBName string) (*
 		ConnMaxLifetime: tm.baseConfig.ConnMaxLifetime,
 		MaxOpenConns:    tm.baseConfig.MaxOpenConns,
 		MaxIdleConns:    tm.baseConfig.MaxIdleConns,
+
+		LockTimeout:      tm.baseConfig.LockTimeout,
+		StatementTimeout: tm.baseConfig.StatementTimeout,
+		ApplicationName:  tm.baseConfig.ApplicationName,
 	}
 
 	adminDB, err := adminConfig.Connect(ctx)
@@ -44,6 +48,9 @@ func (tm *TempDBManager) CreateTempDB(ctx context.Context, tempDBName string) (*
 		ConnMaxLifetime: 10 * time.Minute,
 		MaxOpenConns:    5,
 		MaxIdleConns:    2,
+
+		StatementTimeout: tm.baseConfig.StatementTimeout,
+		ApplicationName:  tm.baseConfig.ApplicationName,
 	}
 
 	tempDB, err := tempConfig.Connect(ctx)
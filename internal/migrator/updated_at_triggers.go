@@ -0,0 +1,160 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"ariga.io/atlas/sql/schema"
+)
+
+const (
+	// touchUpdatedAtName names both the shared trigger function and the trigger on each table
+	touchUpdatedAtName = "touch_updated_at"
+
+	touchUpdatedAtBody = ` RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+)
+
+// updatedAtTriggers is what the database currently has of the updated_at triggers
+type updatedAtTriggers struct {
+	Tables    map[string]bool // Schema qualified tables with a touch_updated_at trigger
+	Functions map[string]bool // Schemas with the shared touch_updated_at function
+}
+
+// inspectUpdatedAtTriggers reads which tables have a touch_updated_at trigger and which
+// schemas have the shared function. Atlas does not inspect triggers and functions.
+func inspectUpdatedAtTriggers(ctx context.Context, db *sql.DB) (updatedAtTriggers, error) {
+	current := updatedAtTriggers{Tables: map[string]bool{}, Functions: map[string]bool{}}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT n.nspname, c.relname
+		FROM pg_trigger t
+		JOIN pg_class c ON c.oid = t.tgrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE t.tgname = $1 AND NOT t.tgisinternal`, touchUpdatedAtName)
+	if err != nil {
+		return current, fmt.Errorf("failed to read updated_at triggers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schemaName, table string
+		if err := rows.Scan(&schemaName, &table); err != nil {
+			return current, fmt.Errorf("failed to read updated_at triggers: %w", err)
+		}
+		current.Tables[schemaName+"."+table] = true
+	}
+	if err := rows.Err(); err != nil {
+		return current, err
+	}
+
+	functions, err := db.QueryContext(ctx, `
+		SELECT n.nspname
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE p.proname = $1`, touchUpdatedAtName)
+	if err != nil {
+		return current, fmt.Errorf("failed to read the %s function: %w", touchUpdatedAtName, err)
+	}
+	defer functions.Close()
+
+	for functions.Next() {
+		var schemaName string
+		if err := functions.Scan(&schemaName); err != nil {
+			return current, fmt.Errorf("failed to read the %s function: %w", touchUpdatedAtName, err)
+		}
+		current.Functions[schemaName] = true
+	}
+	return current, functions.Err()
+}
+
+// planUpdatedAtTriggers returns the steps that give every target table with an updated_at
+// timestamp a BEFORE UPDATE trigger setting it, and remove the trigger from tables that
+// no longer have the column, or from every table once the option is turned off. The
+// shared function of each schema is created with its first trigger and kept afterwards,
+// since triggers written by hand may use it as well.
+func planUpdatedAtTriggers(target *schema.Realm, current updatedAtTriggers, enabled bool) []migrationStep {
+	var functions, steps []migrationStep
+	created := make(map[string]bool)
+
+	for _, s := range target.Schemas {
+		for _, table := range s.Tables {
+			name := qualifiedName(table)
+			if enabled && hasUpdatedAtColumn(table) {
+				schemaName := triggerSchema(table)
+				if !current.Functions[schemaName] && !created[schemaName] {
+					created[schemaName] = true
+					functions = append(functions, createTouchUpdatedAtFunction(schemaName))
+				}
+				if !current.Tables[name] {
+					steps = append(steps, createUpdatedAtTrigger(table))
+				}
+			} else if current.Tables[name] {
+				steps = append(steps, dropUpdatedAtTrigger(table))
+			}
+		}
+	}
+
+	return append(functions, steps...)
+}
+
+func hasUpdatedAtColumn(table *schema.Table) bool {
+	column, ok := table.Column("updated_at")
+	if !ok || column.Type == nil {
+		return false
+	}
+	_, ok = column.Type.Type.(*schema.TimeType)
+	return ok
+}
+
+// triggerSchema is the schema of table, public when it has none
+func triggerSchema(table *schema.Table) string {
+	if name := schemaName(table); name != "" {
+		return name
+	}
+	return "public"
+}
+
+// touchUpdatedAtFunctionName is the schema qualified name of the shared function
+func touchUpdatedAtFunctionName(schemaName string) string {
+	return quoteIdentifier(schemaName) + "." + touchUpdatedAtName
+}
+
+func createTouchUpdatedAtFunction(schemaName string) migrationStep {
+	function := touchUpdatedAtFunctionName(schemaName)
+	return migrationStep{
+		Description: "Create function " + function,
+		Up:          []string{"CREATE OR REPLACE FUNCTION " + function + "()" + touchUpdatedAtBody},
+		Down:        []string{"DROP FUNCTION IF EXISTS " + function + "()"},
+	}
+}
+
+func createUpdatedAtTrigger(table *schema.Table) migrationStep {
+	return migrationStep{
+		Description: fmt.Sprintf("Create trigger %s on %s", touchUpdatedAtName, qualifiedName(table)),
+		Up:          []string{updatedAtTriggerSQL(table)},
+		Down:        []string{dropUpdatedAtTriggerSQL(table)},
+	}
+}
+
+func dropUpdatedAtTrigger(table *schema.Table) migrationStep {
+	return migrationStep{
+		Description: fmt.Sprintf("Drop trigger %s on %s", touchUpdatedAtName, qualifiedName(table)),
+		Up:          []string{dropUpdatedAtTriggerSQL(table)},
+		Down:        []string{updatedAtTriggerSQL(table)},
+	}
+}
+
+func updatedAtTriggerSQL(table *schema.Table) string {
+	return fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s()",
+		touchUpdatedAtName, quotedTable(table), touchUpdatedAtFunctionName(triggerSchema(table)))
+}
+
+func dropUpdatedAtTriggerSQL(table *schema.Table) string {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", touchUpdatedAtName, quotedTable(table))
+}
@@ -0,0 +1,120 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+
+	"ariga.io/atlas/sql/schema"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func triggerRealm() *schema.Realm {
+	public := schema.New("public")
+	public.AddTables(
+		schema.NewTable("posts").AddColumns(schema.NewTimeColumn("updated_at", "timestamptz")),
+		schema.NewTable("tags").AddColumns(schema.NewStringColumn("name", "text")),
+		schema.NewTable("users").AddColumns(schema.NewTimeColumn("updated_at", "timestamptz")),
+		schema.NewTable("notes").AddColumns(schema.NewStringColumn("updated_at", "text")),
+	)
+	return schema.NewRealm(public)
+}
+
+func TestPlanUpdatedAtTriggers(t *testing.T) {
+	steps := planUpdatedAtTriggers(triggerRealm(), updatedAtTriggers{
+		Tables: map[string]bool{"public.users": true, "public.tags": true},
+	}, true)
+
+	expected := []struct{ up, down string }{
+		{`CREATE OR REPLACE FUNCTION "public".touch_updated_at()` + touchUpdatedAtBody, `DROP FUNCTION IF EXISTS "public".touch_updated_at()`},
+		{
+			`CREATE TRIGGER touch_updated_at BEFORE UPDATE ON "public"."posts" FOR EACH ROW EXECUTE FUNCTION "public".touch_updated_at()`,
+			`DROP TRIGGER IF EXISTS touch_updated_at ON "public"."posts"`,
+		},
+		{
+			`DROP TRIGGER IF EXISTS touch_updated_at ON "public"."tags"`,
+			`CREATE TRIGGER touch_updated_at BEFORE UPDATE ON "public"."tags" FOR EACH ROW EXECUTE FUNCTION "public".touch_updated_at()`,
+		},
+	}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected %d steps, got %d: %+v", len(expected), len(steps), steps)
+	}
+	for i, want := range expected {
+		if steps[i].UpSQL() != want.up || steps[i].DownSQL() != want.down {
+			t.Errorf("step %d: got up %q down %q", i, steps[i].UpSQL(), steps[i].DownSQL())
+		}
+	}
+}
+
+func TestPlanUpdatedAtTriggers_UpToDate(t *testing.T) {
+	steps := planUpdatedAtTriggers(triggerRealm(), updatedAtTriggers{
+		Tables:    map[string]bool{"public.posts": true, "public.users": true},
+		Functions: map[string]bool{"public": true},
+	}, true)
+	if len(steps) != 0 {
+		t.Errorf("expected no steps, got %+v", steps)
+	}
+}
+
+func TestPlanUpdatedAtTriggers_OtherSchema(t *testing.T) {
+	billing := schema.New("billing")
+	billing.AddTables(schema.NewTable("invoices").AddColumns(schema.NewTimeColumn("updated_at", "timestamptz")))
+
+	steps := planUpdatedAtTriggers(schema.NewRealm(billing), updatedAtTriggers{
+		Tables:    map[string]bool{},
+		Functions: map[string]bool{"public": true},
+	}, true)
+
+	want := []string{
+		`CREATE OR REPLACE FUNCTION "billing".touch_updated_at()` + touchUpdatedAtBody,
+		`CREATE TRIGGER touch_updated_at BEFORE UPDATE ON "billing"."invoices" FOR EACH ROW EXECUTE FUNCTION "billing".touch_updated_at()`,
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("expected %d steps, got %d: %+v", len(want), len(steps), steps)
+	}
+	for i := range want {
+		if steps[i].UpSQL() != want[i] {
+			t.Errorf("step %d: got up %q, want %q", i, steps[i].UpSQL(), want[i])
+		}
+	}
+}
+
+func TestPlanUpdatedAtTriggers_Disabled(t *testing.T) {
+	steps := planUpdatedAtTriggers(triggerRealm(), updatedAtTriggers{
+		Tables:    map[string]bool{"public.posts": true, "public.users": true},
+		Functions: map[string]bool{"public": true},
+	}, false)
+
+	want := []string{
+		`DROP TRIGGER IF EXISTS touch_updated_at ON "public"."posts"`,
+		`DROP TRIGGER IF EXISTS touch_updated_at ON "public"."users"`,
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("expected %d steps, got %d: %+v", len(want), len(steps), steps)
+	}
+	for i := range want {
+		if steps[i].UpSQL() != want[i] {
+			t.Errorf("step %d: got up %q, want %q", i, steps[i].UpSQL(), want[i])
+		}
+	}
+}
+
+func TestInspectUpdatedAtTriggers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("FROM pg_trigger").WithArgs("touch_updated_at").WillReturnRows(
+		sqlmock.NewRows([]string{"nspname", "relname"}).AddRow("public", "users"))
+	mock.ExpectQuery("FROM pg_proc").WithArgs("touch_updated_at").WillReturnRows(
+		sqlmock.NewRows([]string{"nspname"}).AddRow("public"))
+
+	current, err := inspectUpdatedAtTriggers(context.Background(), db)
+	if err != nil {
+		t.Fatalf("inspectUpdatedAtTriggers() error = %v", err)
+	}
+	if !current.Functions["public"] || len(current.Tables) != 1 || !current.Tables["public.users"] {
+		t.Errorf("unexpected triggers %+v", current)
+	}
+}
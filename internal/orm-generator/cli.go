@@ -2,6 +2,7 @@ package orm_generator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -66,6 +67,8 @@ func (cli *CLICommands) getValidateCommand() *cobra.Command {
 func (cli *CLICommands) getGenerateORMCommand() *cobra.Command {
 	var packagePath string
 	var packageName string
+	var models []string
+	var features []string
 
 	cmd := &cobra.Command{
 		Use:   "generate-orm",
@@ -83,6 +86,8 @@ Code is generated in the same directory as the input models.`,
 			config := GenerationConfig{
 				PackageName: packageName,
 				OutputDir:   packagePath,
+				Models:      models,
+				Features:    features,
 			}
 
 			generator := NewCodeGenerator(config)
@@ -105,6 +110,8 @@ Code is generated in the same directory as the input models.`,
 
 	cmd.Flags().StringVar(&packagePath, "package", "./internal/db", "Package path containing model definitions")
 	cmd.Flags().StringVar(&packageName, "pkg-name", "", "Package name for generated code (default: auto-detect from models)")
+	cmd.Flags().StringSliceVar(&models, "models", nil, "Only generate these models (default: all)")
+	cmd.Flags().StringSliceVar(&features, "features", nil, "Only generate these features: "+strings.Join(Features, ", ")+" (default: all)")
 
 	return cmd
 }
@@ -95,9 +95,16 @@ type TestProfile struct {
 		t.Fatalf("Failed to write test models: %v", err)
 	}
 
+	var written []string
 	config := GenerationConfig{
 		PackageName: "models",
 		OutputDir:   outputDir,
+		Progress: func(done, total int, file string) {
+			if total != 9 || done != len(written)+1 {
+				t.Errorf("unexpected progress %d/%d for %s", done, total, file)
+			}
+			written = append(written, file)
+		},
 	}
 
 	generator := NewCodeGenerator(config)
@@ -117,12 +124,17 @@ type TestProfile struct {
 		t.Fatalf("Code generation failed: %v", err)
 	}
 
+	if len(written) != 9 {
+		t.Errorf("expected progress for 9 files, got %v", written)
+	}
+
 	expectedFiles := []string{
 		"columns.go",
 		"test_user_repository.go",
 		"test_post_repository.go",
 		"test_profile_repository.go",
 		"storm.go",
+		"filters.go",
 	}
 
 	for _, filename := range expectedFiles {
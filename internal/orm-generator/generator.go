@@ -2,18 +2,27 @@ package orm_generator
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
+	"github.com/eleven-am/storm/internal/generator"
 	stormParser "github.com/eleven-am/storm/internal/parser"
+	"github.com/eleven-am/storm/pkg/storm"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
 )
 
 // CodeGenerator handles generation of type-safe ORM code
@@ -23,27 +32,87 @@ type CodeGenerator struct {
 	outputDir   string
 	templates   map[string]*template.Template
 	models      map[string]*ModelMetadata
+	allModels   map[string]*ModelMetadata // Every discovered model, including unselected ones
+	selected    []string                  // Model names to generate (empty = all)
+	features    map[string]bool           // Features to generate (empty = all)
+	progress    ProgressFunc
+	progressMu  sync.Mutex // Guards filesDone and progress calls while files are written concurrently
+	filesDone   int
+	filesTotal  int
+	force       bool
+	workers     int
+	unchanged   map[string]bool // Models whose files are already up to date
+	templateDir string
+	funcs       template.FuncMap
+	data        map[string]interface{}
+	sources     map[string]string // Template sources, when the generator has custom templates
+	declared    map[string]bool   // Types declared in the hand-written files of the models package
 }
 
+// ProgressFunc is called after each generated file is written, with the number of
+// files written so far and the total
+type ProgressFunc func(done, total int, file string)
+
 // GenerationConfig configures code generation
 type GenerationConfig struct {
-	PackageName  string   // Package name for generated code
-	OutputDir    string   // Output directory
-	Models       []string // Model names to generate (empty = all)
-	Features     []string // Features to generate (columns, repositories, etc.)
-	TemplateDir  string   // Custom template directory
-	FileHeader   string   // Custom file header
-	IncludeTests bool     // Whether to generate tests
-	IncludeDocs  bool     // Whether to generate documentation
+	PackageName  string       // Package name for generated code
+	OutputDir    string       // Output directory
+	Models       []string     // Model names to generate (empty = all)
+	Features     []string     // Features to generate, see Features (empty = all)
+	TemplateDir  string       // Directory of <template>.tmpl files replacing the built-in templates
+	FileHeader   string       // Custom file header
+	IncludeTests bool         // Whether to generate tests
+	IncludeDocs  bool         // Whether to generate documentation
+	Progress     ProgressFunc // Notified as files are written (optional)
+	Force        bool         // Regenerate every file, even for unchanged models
+	Workers      int          // Model files rendered concurrently (0 = GOMAXPROCS)
+
+	// Funcs are added to the functions of the templates, replacing built-in ones
+	// of the same name, e.g. a license header or naming helpers for custom templates
+	Funcs template.FuncMap
+
+	// Data is available to every template as .Data
+	Data map[string]interface{}
 }
 
+// Generated features, selectable with GenerationConfig.Features
+const (
+	FeatureMetadata     = "metadata"     // <model>_metadata.go
+	FeatureColumns      = "columns"      // columns.go
+	FeatureRepositories = "repositories" // <model>_repository.go
+	FeatureExtensions   = "extensions"   // <model>_extensions.go, created once
+	FeatureStorm        = "storm"        // storm.go
+	FeatureFilters      = "filters"      // filters.go
+)
+
+// Features lists the features the generator can generate
+var Features = []string{FeatureMetadata, FeatureColumns, FeatureRepositories, FeatureExtensions, FeatureStorm, FeatureFilters}
+
 func NewCodeGenerator(config GenerationConfig) *CodeGenerator {
+	var features map[string]bool
+	if len(config.Features) > 0 {
+		features = make(map[string]bool, len(config.Features))
+		for _, feature := range config.Features {
+			features[strings.ToLower(strings.TrimSpace(feature))] = true
+		}
+	}
+
 	return &CodeGenerator{
 		tagParser:   NewORMTagParser(),
 		packageName: config.PackageName,
 		outputDir:   config.OutputDir,
 		templates:   make(map[string]*template.Template),
 		models:      make(map[string]*ModelMetadata),
+		allModels:   make(map[string]*ModelMetadata),
+		selected:    config.Models,
+		features:    features,
+		progress:    config.Progress,
+		force:       config.Force,
+		workers:     config.Workers,
+		unchanged:   make(map[string]bool),
+		templateDir: config.TemplateDir,
+		funcs:       config.Funcs,
+		data:        config.Data,
 	}
 }
 
@@ -56,6 +125,8 @@ func (g *CodeGenerator) DiscoverModels(packagePath string) error {
 		g.packageName = packageName
 	}
 
+	g.declared = declaredTypes(packagePath)
+
 	structParser := stormParser.NewStructParser()
 	tables, err := structParser.ParseDirectory(packagePath)
 	if err != nil {
@@ -76,23 +147,65 @@ func (g *CodeGenerator) DiscoverModels(packagePath string) error {
 			fmt.Printf("Skipping model %s: no primary key defined\n", metadata.Name)
 			continue
 		}
-		g.models[metadata.Name] = metadata
+		g.allModels[metadata.Name] = metadata
+	}
+
+	// Unselected models stay known for validating relationships to them
+	if len(g.selected) == 0 {
+		for name, model := range g.allModels {
+			g.models[name] = model
+		}
+		return nil
+	}
+	for _, name := range g.selected {
+		name = strings.TrimSpace(name)
+		model, exists := g.allModels[name]
+		if !exists {
+			return fmt.Errorf("model %s not found in %s", name, packagePath)
+		}
+		g.models[name] = model
 	}
 
 	return nil
 }
 
+// enabled reports whether a feature is generated
+func (g *CodeGenerator) enabled(feature string) bool {
+	return g.features == nil || g.features[feature]
+}
+
+func (g *CodeGenerator) validateFeatures() error {
+	for feature := range g.features {
+		known := false
+		for _, f := range Features {
+			known = known || f == feature
+		}
+		if !known {
+			return fmt.Errorf("unknown feature %q, expected one of %s", feature, strings.Join(Features, ", "))
+		}
+	}
+	return nil
+}
+
 func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormParser.TableDefinition) *ModelMetadata {
 	metadata := &ModelMetadata{
-		Name:          tableDef.StructName,
-		TableName:     tableDef.TableName,
-		Columns:       make([]FieldMetadata, 0, len(tableDef.Fields)),
-		PrimaryKeys:   make([]string, 0),
-		Indexes:       make([]IndexMetadata, 0),
-		Relationships: make([]FieldMetadata, 0),
+		Name:             tableDef.StructName,
+		TableName:        tableDef.TableName,
+		Columns:          make([]FieldMetadata, 0, len(tableDef.Fields)),
+		PrimaryKeys:      make([]string, 0),
+		Indexes:          make([]IndexMetadata, 0),
+		Relationships:    make([]FieldMetadata, 0),
+		SchemaHash:       schemaHash(tableDef.Fields),
+		MaterializedView: tableDef.IsMaterializedView(),
+		DefaultScope:     tableDef.DefaultScope(),
+		SoftDelete:       tableDef.SoftDeleteColumn(),
 	}
 
 	for _, field := range tableDef.Fields {
+		if field.IsTransient() {
+			continue
+		}
+
 		fieldMeta := FieldMetadata{
 			Name:   field.Name,
 			DBName: field.DBName,
@@ -131,23 +244,160 @@ func (g *CodeGenerator) convertTableDefinitionToModelMetadata(tableDef stormPars
 			fieldMeta.IsUnique = true
 		}
 
-		if defaultVal, hasDefault := field.DBDef["default"]; hasDefault {
-			fieldMeta.DefaultValue = defaultVal
-			if isAutoGeneratedDefault(defaultVal) || field.DBDef["type"] == "serial" {
-				fieldMeta.IsAutoGenerated = true
-			}
+		if _, notNull := field.DBDef["not_null"]; fieldMeta.IsPrimaryKey || (notNull && !field.IsPointer) {
+			fieldMeta.IsRequired = true
 		}
 
+		applyGeneratedValues(&fieldMeta, field.DBDef)
+
 		if dbType, hasType := field.DBDef["type"]; hasType {
 			fieldMeta.DBType = dbType
 		}
 
+		fieldMeta.Mask = field.DBDef["mask"]
+
+		if enumValues, isEnum := field.DBDef["enum"]; isEnum && !field.IsArray {
+			for _, value := range strings.Split(enumValues, ",") {
+				fieldMeta.EnumValues = append(fieldMeta.EnumValues, strings.TrimSpace(value))
+			}
+			fieldMeta.EnumType = enumType(tableDef.StructName, field)
+			fieldMeta.DeclareEnum = !g.declared[fieldMeta.EnumType]
+		}
+
 		metadata.Columns = append(metadata.Columns, fieldMeta)
 	}
 
+	constraints, err := tableConstraints(tableDef)
+	if err != nil {
+		fmt.Printf("Warning: failed to resolve constraints of %s: %v\n", tableDef.StructName, err)
+	}
+	metadata.Constraints = constraints
+
 	return metadata
 }
 
+// tableConstraints names the constraints the schema generator creates for the table
+// and the columns each one covers, so that the runtime can tell which fields a
+// constraint violation is about. Checks cover the columns their expression mentions.
+func tableConstraints(tableDef stormParser.TableDefinition) ([]ConstraintMetadata, error) {
+	table, err := generator.NewSchemaGenerator().GenerateTable(tableDef)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]bool, len(table.Columns))
+	for _, column := range table.Columns {
+		columns[column.Name] = true
+	}
+
+	constraints := make([]ConstraintMetadata, 0, len(table.Constraints))
+	for _, column := range table.Columns {
+		if column.CheckConstraint != nil {
+			constraints = append(constraints, ConstraintMetadata{
+				Name:       fmt.Sprintf("%s_%s_check", table.Name, column.Name),
+				Type:       "CHECK",
+				Definition: *column.CheckConstraint,
+				Columns:    []string{column.Name},
+			})
+		}
+	}
+	for _, constraint := range table.Constraints {
+		covered := constraint.Columns
+		if len(covered) == 0 {
+			covered = referencedColumns(constraint.Definition, columns)
+		}
+		constraints = append(constraints, ConstraintMetadata{
+			Name:       constraint.Name,
+			Type:       constraint.Type,
+			Definition: constraint.Definition,
+			Columns:    covered,
+		})
+	}
+
+	return constraints, nil
+}
+
+// referencedColumns returns the columns an expression mentions, in order of appearance
+func referencedColumns(expression string, columns map[string]bool) []string {
+	words := strings.FieldsFunc(expression, func(r rune) bool {
+		return !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+
+	var referenced []string
+	seen := make(map[string]bool)
+	for _, word := range words {
+		if columns[word] && !seen[word] {
+			seen[word] = true
+			referenced = append(referenced, word)
+		}
+	}
+	return referenced
+}
+
+// schemaHash hashes the parsed fields the way the runtime hashes the compiled struct,
+// so that it can tell when the model changed after generation
+func schemaHash(fields []stormParser.FieldDefinition) string {
+	signatures := make([]orm.FieldSignature, len(fields))
+	for i, field := range fields {
+		signatures[i] = orm.FieldSignature{
+			Name:  field.Name,
+			DB:    field.DBTag,
+			DBDef: field.DBDefTag,
+			Storm: field.StormTag,
+			ORM:   field.ORMTag,
+		}
+	}
+	return orm.SchemaHash(signatures)
+}
+
+// enumType names the Go type of an enum column: the field's own type when it
+// has a named one, such as Status OrderStatus, or else the model and field
+// name, e.g. OrderStatus for Order.Status
+func enumType(structName string, field stormParser.FieldDefinition) string {
+	if token.IsIdentifier(field.Type) && !builtinTypes[field.Type] {
+		return field.Type
+	}
+	return structName + field.Name
+}
+
+var builtinTypes = map[string]bool{
+	"string": true, "bool": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// declaredTypes returns the types declared in the hand-written files of a
+// package, whose enum types the generator leaves to the user
+func declaredTypes(packagePath string) map[string]bool {
+	declared := make(map[string]bool)
+	matches, _ := filepath.Glob(filepath.Join(packagePath, "*.go"))
+
+	fileSet := token.NewFileSet()
+	for _, file := range matches {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+
+		src, err := parser.ParseFile(fileSet, file, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil || ast.IsGenerated(src) {
+			continue
+		}
+
+		for _, decl := range src.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				declared[spec.(*ast.TypeSpec).Name.Name] = true
+			}
+		}
+	}
+
+	return declared
+}
+
 func (g *CodeGenerator) detectPackageName(packagePath string) (string, error) {
 	pattern := filepath.Join(packagePath, "*.go")
 	matches, err := filepath.Glob(pattern)
@@ -210,34 +460,135 @@ func (g *CodeGenerator) mapSchemaTypeToGo(schemaType string) string {
 }
 
 func (g *CodeGenerator) GenerateAll() error {
+	if err := g.validateFeatures(); err != nil {
+		return err
+	}
+
 	if err := g.loadTemplates(); err != nil {
 		return fmt.Errorf("failed to load templates: %w", err)
 	}
 
-	if err := g.generateMetadata(); err != nil {
-		return fmt.Errorf("failed to generate metadata: %w", err)
+	// Metadata and a repository per model, plus columns.go, storm.go and filters.go
+	g.filesDone, g.filesTotal = 0, 0
+	for _, feature := range []string{FeatureMetadata, FeatureRepositories} {
+		if g.enabled(feature) {
+			g.filesTotal += len(g.models)
+		}
+	}
+	for _, feature := range []string{FeatureColumns, FeatureStorm, FeatureFilters} {
+		if g.enabled(feature) {
+			g.filesTotal++
+		}
+	}
+
+	// Files of models whose inputs did not change are kept as they are. The
+	// aggregate columns.go, storm.go and filters.go are always rewritten.
+	g.resolveRelationships()
+
+	g.unchanged = make(map[string]bool)
+	for name, model := range g.models {
+		model.ContentHash = g.contentHash(model)
+		if !g.force && g.upToDate(model) {
+			g.unchanged[name] = true
+		}
 	}
 
-	if err := g.generateColumnConstants(); err != nil {
-		return fmt.Errorf("failed to generate column constants: %w", err)
+	if g.enabled(FeatureMetadata) {
+		if err := g.generateMetadata(); err != nil {
+			return fmt.Errorf("failed to generate metadata: %w", err)
+		}
 	}
 
-	if err := g.generateRepositories(); err != nil {
-		return fmt.Errorf("failed to generate repositories: %w", err)
+	if g.enabled(FeatureColumns) {
+		if err := g.generateColumnConstants(); err != nil {
+			return fmt.Errorf("failed to generate column constants: %w", err)
+		}
+	}
+
+	if g.enabled(FeatureRepositories) {
+		if err := g.generateRepositories(); err != nil {
+			return fmt.Errorf("failed to generate repositories: %w", err)
+		}
+	}
+
+	if g.enabled(FeatureExtensions) {
+		if err := g.generateExtensions(); err != nil {
+			return fmt.Errorf("failed to generate extension files: %w", err)
+		}
 	}
 
 	// Relationships are handled by WithXXX methods in repositories
 	// No need for a separate relationships file
 
-	if err := g.generateStorm(); err != nil {
-		return fmt.Errorf("failed to generate Storm: %w", err)
+	if g.enabled(FeatureStorm) {
+		if err := g.generateStorm(); err != nil {
+			return fmt.Errorf("failed to generate Storm: %w", err)
+		}
+	}
+
+	if g.enabled(FeatureFilters) {
+		if err := g.generateFilters(); err != nil {
+			return fmt.Errorf("failed to generate filters: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// loadTemplates uses the shared built-in templates, unless the generator has
+// custom templates or functions and needs templates of its own
 func (g *CodeGenerator) loadTemplates() error {
-	funcMap := template.FuncMap{
+	if g.templateDir == "" && len(g.funcs) == 0 {
+		g.templates = parsedTemplates()
+		return nil
+	}
+
+	sources, err := readTemplateSources(g.templateDir)
+	if err != nil {
+		return err
+	}
+	parsed, err := parseTemplates(g.funcs, sources)
+	if err != nil {
+		return err
+	}
+	g.templates, g.sources = parsed, sources
+	return nil
+}
+
+// templateSources are the built-in templates, by the name a custom template
+// replacing one is given in TemplateDir, e.g. repository.tmpl
+var templateSources = map[string]string{
+	"metadata":      metadataTemplate,
+	"columns":       columnTemplate,
+	"repository":    repositoryTemplate,
+	"relationships": relationshipsTemplate,
+	"storm":         stormTemplate,
+	"extensions":    extensionsTemplate,
+	"filters":       filtersTemplate,
+}
+
+var (
+	templatesOnce sync.Once
+	templates     map[string]*template.Template
+)
+
+// parsedTemplates parses the built-in templates on first use and shares them
+// between generators. Parsed templates are safe to execute concurrently.
+func parsedTemplates() map[string]*template.Template {
+	templatesOnce.Do(func() {
+		parsed, err := parseTemplates(nil, templateSources)
+		if err != nil {
+			panic(err)
+		}
+		templates = parsed
+	})
+	return templates
+}
+
+// templateFuncs returns the functions available to every template
+func templateFuncs() template.FuncMap {
+	var g *CodeGenerator // The type mappings use no generator state
+	return template.FuncMap{
 		"lower":          strings.ToLower,
 		"upper":          strings.ToUpper,
 		"title":          strings.Title,
@@ -256,53 +607,111 @@ func (g *CodeGenerator) loadTemplates() error {
 		"now":            time.Now,
 		"sanitizeGoName": sanitizeGoName,
 	}
+}
 
-	g.templates["metadata"] = template.Must(template.New("metadata").Funcs(funcMap).Parse(metadataTemplate))
-	g.templates["columns"] = template.Must(template.New("columns").Funcs(funcMap).Parse(columnTemplate))
-	g.templates["repository"] = template.Must(template.New("repository").Funcs(funcMap).Parse(repositoryTemplate))
-	g.templates["relationships"] = template.Must(template.New("relationships").Funcs(funcMap).Parse(relationshipsTemplate))
-	g.templates["storm"] = template.Must(template.New("storm").Funcs(funcMap).Parse(stormTemplate))
+// readTemplateSources returns the built-in templates, replacing those that dir
+// has a <name>.tmpl file for
+func readTemplateSources(dir string) (map[string]string, error) {
+	sources := make(map[string]string, len(templateSources))
+	for name, source := range templateSources {
+		sources[name] = source
+	}
+	if dir == "" {
+		return sources, nil
+	}
 
-	return nil
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".tmpl")
+		if _, builtin := templateSources[name]; !builtin {
+			known := make([]string, 0, len(templateSources))
+			for builtinName := range templateSources {
+				known = append(known, builtinName+".tmpl")
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown template %s in %s, expected one of %s", entry.Name(), dir, strings.Join(known, ", "))
+		}
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", entry.Name(), err)
+		}
+		sources[name] = string(content)
+	}
+	return sources, nil
+}
+
+// parseTemplates parses sources with funcs added to the template functions
+func parseTemplates(funcs template.FuncMap, sources map[string]string) (map[string]*template.Template, error) {
+	funcMap := templateFuncs()
+	for name, fn := range funcs {
+		funcMap[name] = fn
+	}
+
+	parsed := make(map[string]*template.Template, len(sources))
+	for name, source := range sources {
+		tmpl, err := template.New(name).Funcs(funcMap).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		parsed[name] = tmpl
+	}
+	return parsed, nil
 }
 
 func (g *CodeGenerator) generateMetadata() error {
-	for _, model := range g.models {
-		hasTimeFields := false
+	var files []generatedFile
+	for _, model := range g.sortedModels() {
+		if g.unchanged[model.Name] {
+			g.fileDone(metadataFilename(model))
+			continue
+		}
+
+		hasTimeFields, hasEnums := false, false
 		for _, col := range model.Columns {
-			if col.Type == "time.Time" {
-				hasTimeFields = true
-				break
-			}
+			hasTimeFields = hasTimeFields || col.Type == "time.Time"
+			hasEnums = hasEnums || col.DeclareEnum
 		}
 
 		data := struct {
-			Package       string
-			Model         *ModelMetadata
-			HasTimeFields bool
-			Now           time.Time
+			Package        string
+			Data           map[string]interface{}
+			Model          *ModelMetadata
+			HasTimeFields  bool
+			HasEnums       bool
+			Now            time.Time
+			Version        string
+			CodegenVersion int
 		}{
-			Package:       g.packageName,
-			Model:         model,
-			HasTimeFields: hasTimeFields,
-			Now:           time.Now(),
+			Package:        g.packageName,
+			Data:           g.data,
+			Model:          model,
+			HasTimeFields:  hasTimeFields,
+			HasEnums:       hasEnums,
+			Now:            time.Now(),
+			Version:        storm.Version,
+			CodegenVersion: orm.CodegenVersion,
 		}
 
-		filename := fmt.Sprintf("%s_metadata.go", strings.ToLower(model.Name))
-		if err := g.executeTemplate("metadata", filename, data); err != nil {
-			return err
-		}
+		files = append(files, generatedFile{template: "metadata", filename: metadataFilename(model), data: data})
 	}
-	return nil
+	return g.executeTemplates(files)
 }
 
 func (g *CodeGenerator) generateColumnConstants() error {
 	data := struct {
 		Package string
+		Data    map[string]interface{}
 		Models  map[string]*ModelMetadata
 		Now     time.Time
 	}{
 		Package: g.packageName,
+		Data:    g.data,
 		Models:  g.models,
 		Now:     time.Now(),
 	}
@@ -311,19 +720,56 @@ func (g *CodeGenerator) generateColumnConstants() error {
 }
 
 func (g *CodeGenerator) generateRepositories() error {
-	for _, model := range g.models {
+	var files []generatedFile
+	for _, model := range g.sortedModels() {
+		if g.unchanged[model.Name] {
+			g.fileDone(repositoryFilename(model))
+			continue
+		}
+
 		data := struct {
 			Package string
+			Data    map[string]interface{}
 			Model   *ModelMetadata
 			Now     time.Time
 		}{
 			Package: g.packageName,
+			Data:    g.data,
 			Model:   model,
 			Now:     time.Now(),
 		}
 
-		filename := fmt.Sprintf("%s_repository.go", toSnakeCase(model.Name))
-		if err := g.executeTemplate("repository", filename, data); err != nil {
+		files = append(files, generatedFile{template: "repository", filename: repositoryFilename(model), data: data})
+	}
+	return g.executeTemplates(files)
+}
+
+// generateExtensions creates the <model>_extensions.go file of each model that does
+// not have one yet. Existing files belong to the user and are left alone.
+func (g *CodeGenerator) generateExtensions() error {
+	for _, model := range g.models {
+		path := filepath.Join(g.outputDir, extensionsFilename(model))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check %s: %w", path, err)
+		}
+
+		data := struct {
+			Package string
+			Data    map[string]interface{}
+			Model   *ModelMetadata
+		}{
+			Package: g.packageName,
+			Data:    g.data,
+			Model:   model,
+		}
+
+		var buf bytes.Buffer
+		if err := g.templates["extensions"].Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to execute template extensions: %w", err)
+		}
+		if err := writeFile(path, buf.Bytes()); err != nil {
 			return err
 		}
 	}
@@ -333,10 +779,12 @@ func (g *CodeGenerator) generateRepositories() error {
 func (g *CodeGenerator) generateRelationships() error {
 	data := struct {
 		Package string
+		Data    map[string]interface{}
 		Models  map[string]*ModelMetadata
 		Now     time.Time
 	}{
 		Package: g.packageName,
+		Data:    g.data,
 		Models:  g.models,
 		Now:     time.Now(),
 	}
@@ -347,17 +795,88 @@ func (g *CodeGenerator) generateRelationships() error {
 func (g *CodeGenerator) generateStorm() error {
 	data := struct {
 		Package string
+		Data    map[string]interface{}
 		Models  map[string]*ModelMetadata
 		Now     time.Time
+		Version string
 	}{
 		Package: g.packageName,
+		Data:    g.data,
 		Models:  g.models,
 		Now:     time.Now(),
+		Version: storm.Version,
 	}
 
 	return g.executeTemplate("storm", "storm.go", data)
 }
 
+// filterField is a column a generated filter struct has fields for
+type filterField struct {
+	Name   string // Go name of the column
+	Type   string // Go type of the values
+	DBName string // Column name, used for the JSON names of the fields
+	Ranged bool   // Whether the filter has Min and Max fields for the column
+}
+
+// filterModel is a model and the columns of its filter struct
+type filterModel struct {
+	Model  *ModelMetadata
+	Fields []filterField
+}
+
+// filterFields returns the columns of model that can be filtered on: those of
+// enum, string, bool, numeric and time types. Numeric and time columns also get a
+// range.
+func filterFields(model *ModelMetadata) []filterField {
+	var fields []filterField
+	for _, col := range model.Columns {
+		if col.IsArray {
+			continue
+		}
+		field := filterField{Name: sanitizeGoName(col.Name), Type: col.Type, DBName: col.DBName}
+		switch {
+		case col.EnumType != "":
+			field.Type = col.EnumType
+		case col.Type == "string" || col.Type == "bool":
+		case col.Type == "int" || col.Type == "int32" || col.Type == "int64" ||
+			col.Type == "float32" || col.Type == "float64" || col.Type == "time.Time":
+			field.Ranged = true
+		default:
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func (g *CodeGenerator) generateFilters() error {
+	models := make([]filterModel, 0, len(g.models))
+	hasTimeFields := false
+	for _, model := range g.sortedModels() {
+		fields := filterFields(model)
+		for _, field := range fields {
+			hasTimeFields = hasTimeFields || field.Type == "time.Time"
+		}
+		models = append(models, filterModel{Model: model, Fields: fields})
+	}
+
+	data := struct {
+		Package       string
+		Data          map[string]interface{}
+		Models        []filterModel
+		HasTimeFields bool
+		Now           time.Time
+	}{
+		Package:       g.packageName,
+		Data:          g.data,
+		Models:        models,
+		HasTimeFields: hasTimeFields,
+		Now:           time.Now(),
+	}
+
+	return g.executeTemplate("filters", "filters.go", data)
+}
+
 func (g *CodeGenerator) executeTemplate(templateName, filename string, data interface{}) error {
 	tmpl, exists := g.templates[templateName]
 	if !exists {
@@ -375,7 +894,195 @@ func (g *CodeGenerator) executeTemplate(templateName, filename string, data inte
 	}
 
 	outputPath := filepath.Join(g.outputDir, filename)
-	return writeFile(outputPath, formatted)
+	if err := writeFile(outputPath, formatted); err != nil {
+		return err
+	}
+
+	g.fileDone(filename)
+	return nil
+}
+
+// generatedFile is a file rendered from a template by executeTemplates
+type generatedFile struct {
+	template string
+	filename string
+	data     interface{}
+}
+
+// executeTemplates renders, formats and writes the files with a pool of
+// workers. When several files fail, the error of the first in order is
+// returned.
+func (g *CodeGenerator) executeTemplates(files []generatedFile) error {
+	workers := g.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	errs := make([]error, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = g.executeTemplate(files[i].template, files[i].filename, files[i].data)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRelationships looks up what relationships need from their target model: its
+// table, and whether loading keeps the inverse in sync. The inverse must be a single
+// pointer back to the loading model, e.g. Post.Author for Author.Posts, so that it
+// can point at the record it was loaded for.
+func (g *CodeGenerator) resolveRelationships() {
+	for _, model := range g.models {
+		for i := range model.Relationships {
+			rel := &model.Relationships[i]
+			if target := g.findModel(rel.Relationship.Target); target != nil {
+				rel.TargetTable = target.TableName
+			}
+			inverse := g.inverseOf(model, *rel)
+			rel.SyncInverse = inverse != nil && inverse.IsPointer && !inverse.IsArray &&
+				(rel.Relationship.Type == "has_many" && inverse.Relationship.Type == "belongs_to" ||
+					rel.Relationship.Type == "has_one" && inverse.Relationship.Type == "belongs_to" ||
+					rel.Relationship.Type == "belongs_to" && inverse.Relationship.Type == "has_one")
+		}
+	}
+}
+
+// inverseOf finds the relationship field a relationship names as its inverse
+func (g *CodeGenerator) inverseOf(model *ModelMetadata, rel FieldMetadata) *FieldMetadata {
+	if rel.Relationship == nil || rel.Relationship.Inverse == "" {
+		return nil
+	}
+	target := g.findModel(rel.Relationship.Target)
+	if target == nil {
+		return nil
+	}
+	for i := range target.Relationships {
+		if target.Relationships[i].Name == rel.Relationship.Inverse {
+			return &target.Relationships[i]
+		}
+	}
+	return nil
+}
+
+// findModel finds a model by name, including models not selected for generation
+func (g *CodeGenerator) findModel(name string) *ModelMetadata {
+	if model, exists := g.models[name]; exists {
+		return model
+	}
+	return g.allModels[name]
+}
+
+// fileDone counts a written or skipped file towards the progress
+func (g *CodeGenerator) fileDone(filename string) {
+	g.progressMu.Lock()
+	defer g.progressMu.Unlock()
+
+	g.filesDone++
+	if g.progress != nil {
+		g.progress(g.filesDone, g.filesTotal, filename)
+	}
+}
+
+// sortedModels returns the models to generate ordered by name
+func (g *CodeGenerator) sortedModels() []*ModelMetadata {
+	models := make([]*ModelMetadata, 0, len(g.models))
+	for _, name := range g.GetModelNames() {
+		models = append(models, g.models[name])
+	}
+	return models
+}
+
+// UnchangedModels returns the models whose files were left untouched by the last
+// GenerateAll because their inputs did not change
+func (g *CodeGenerator) UnchangedModels() []string {
+	names := make([]string, 0, len(g.unchanged))
+	for name := range g.unchanged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contentHash hashes everything the files of a model are generated from: the
+// resolved model, the package name, the Storm version and the templates, with
+// their custom data and the names of custom functions. What a custom function
+// returns is not known; changing it takes --force.
+func (g *CodeGenerator) contentHash(model *ModelMetadata) string {
+	h := sha256.New()
+	encoded, _ := json.Marshal(model)
+	h.Write(encoded)
+	fmt.Fprintf(h, "\x00%s\x00%s\x00%s\x00%s", g.packageName, storm.Version, metadataTemplate, repositoryTemplate)
+	if g.sources != nil {
+		fmt.Fprintf(h, "\x00%s\x00%s", g.sources["metadata"], g.sources["repository"])
+	}
+	if len(g.data) > 0 {
+		encoded, _ := json.Marshal(g.data)
+		h.Write(encoded)
+	}
+	if len(g.funcs) > 0 {
+		names := make([]string, 0, len(g.funcs))
+		for name := range g.funcs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(h, "\x00%s", strings.Join(names, ","))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// upToDate reports whether the generated files of a model exist and were generated
+// from the same content hash
+func (g *CodeGenerator) upToDate(model *ModelMetadata) bool {
+	stamp := []byte(contentHashPrefix + model.ContentHash + "\n")
+	var filenames []string
+	if g.enabled(FeatureMetadata) {
+		filenames = append(filenames, metadataFilename(model))
+	}
+	if g.enabled(FeatureRepositories) {
+		filenames = append(filenames, repositoryFilename(model))
+	}
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filepath.Join(g.outputDir, filename))
+		if err != nil || !bytes.Contains(content, stamp) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentHashPrefix starts the header line recording a model's content hash
+const contentHashPrefix = "// Content hash: "
+
+func metadataFilename(model *ModelMetadata) string {
+	return fmt.Sprintf("%s_metadata.go", strings.ToLower(model.Name))
+}
+
+func repositoryFilename(model *ModelMetadata) string {
+	return fmt.Sprintf("%s_repository.go", toSnakeCase(model.Name))
+}
+
+func extensionsFilename(model *ModelMetadata) string {
+	return fmt.Sprintf("%s_extensions.go", toSnakeCase(model.Name))
 }
 
 func (g *CodeGenerator) mapDBTypeToGo(dbType string) string {
@@ -479,9 +1186,11 @@ func (g *CodeGenerator) GetModelsByTable() map[string]*ModelMetadata {
 	return result
 }
 
+// ValidateModels validates the models in name order, so the same models always
+// report the same error first
 func (g *CodeGenerator) ValidateModels() error {
-	for name, model := range g.models {
-		if err := g.validateModel(model); err != nil {
+	for _, name := range g.GetModelNames() {
+		if err := g.validateModel(g.models[name]); err != nil {
 			return fmt.Errorf("model %s validation failed: %w", name, err)
 		}
 	}
@@ -493,7 +1202,11 @@ func (g *CodeGenerator) validateModel(model *ModelMetadata) error {
 		return fmt.Errorf("model %s has no primary key", model.Name)
 	}
 
-	for _, rel := range model.Relationships {
+	relationships := append([]FieldMetadata{}, model.Relationships...)
+	sort.Slice(relationships, func(i, j int) bool {
+		return relationships[i].Name < relationships[j].Name
+	})
+	for _, rel := range relationships {
 		if err := g.validateRelationship(model, rel); err != nil {
 			return fmt.Errorf("relationship %s validation failed: %w", rel.Name, err)
 		}
@@ -502,16 +1215,51 @@ func (g *CodeGenerator) validateModel(model *ModelMetadata) error {
 	return nil
 }
 
+// validateInverse checks that a declared inverse is a relationship of the target
+// pointing back to the model, with the opposite cardinality
+func (g *CodeGenerator) validateInverse(model, targetModel *ModelMetadata, rel FieldMetadata) error {
+	if rel.Relationship.Inverse == "" {
+		return nil
+	}
+	if rel.Relationship.Type == "has_many_through" {
+		return fmt.Errorf("inverse is not supported on has_many_through relationship %s", rel.Name)
+	}
+
+	inverse := g.inverseOf(model, rel)
+	if inverse == nil {
+		return fmt.Errorf("inverse relationship %s not found on %s", rel.Relationship.Inverse, targetModel.Name)
+	}
+	if inverse.Relationship.Target != model.Name {
+		return fmt.Errorf("inverse relationship %s.%s points to %s, not %s", targetModel.Name, inverse.Name, inverse.Relationship.Target, model.Name)
+	}
+
+	valid := map[string][]string{
+		"belongs_to": {"has_one", "has_many"},
+		"has_one":    {"belongs_to"},
+		"has_many":   {"belongs_to"},
+	}
+	for _, t := range valid[rel.Relationship.Type] {
+		if inverse.Relationship.Type == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("inverse relationship %s.%s is %s, which cannot be the inverse of %s", targetModel.Name, inverse.Name, inverse.Relationship.Type, rel.Relationship.Type)
+}
+
 func (g *CodeGenerator) validateRelationship(model *ModelMetadata, rel FieldMetadata) error {
 	if rel.Relationship == nil {
 		return fmt.Errorf("relationship %s has no metadata", rel.Name)
 	}
 
-	targetModel, exists := g.models[rel.Relationship.Target]
-	if !exists {
+	targetModel := g.findModel(rel.Relationship.Target)
+	if targetModel == nil {
 		return fmt.Errorf("target model %s not found for relationship %s", rel.Relationship.Target, rel.Name)
 	}
 
+	if err := g.validateInverse(model, targetModel, rel); err != nil {
+		return err
+	}
+
 	switch rel.Relationship.Type {
 	case "belongs_to":
 		if !g.hasColumn(model, rel.Relationship.ForeignKey) {
@@ -556,15 +1304,17 @@ func (g *CodeGenerator) GenerateForModel(modelName string) error {
 
 	data := struct {
 		Package string
+		Data    map[string]interface{}
 		Model   *ModelMetadata
 		Now     time.Time
 	}{
 		Package: g.packageName,
+		Data:    g.data,
 		Model:   model,
 		Now:     time.Now(),
 	}
 
-	filename := fmt.Sprintf("%s_repository.go", toSnakeCase(model.Name))
+	filename := repositoryFilename(model)
 	if err := g.executeTemplate("repository", filename, data); err != nil {
 		return fmt.Errorf("failed to generate repository: %w", err)
 	}
@@ -589,7 +1339,9 @@ func (g *CodeGenerator) CleanOutput() error {
 				return err
 			}
 
-			if bytes.Contains(content, []byte("// Code generated by db-migrator")) {
+			// Only generated files carry the marker; extension files never do
+			if bytes.Contains(content, []byte("// Code generated by storm orm")) ||
+				bytes.Contains(content, []byte("// Code generated by db-migrator")) {
 				return os.Remove(path)
 			}
 		}
@@ -603,6 +1355,7 @@ func isAutoGeneratedDefault(defaultValue string) bool {
 		"now()", "CURRENT_TIMESTAMP", "current_timestamp",
 		"gen_random_uuid()", "uuid_generate_v4()",
 		"gen_cuid()", "cuid()", // CUID generation functions
+		"gen_cuid2()", "gen_ulid()", "gen_nanoid(", "gen_uuidv7()", // Other ID generation functions
 		"nextval", "NEXTVAL", // for sequences
 	}
 
@@ -1,11 +1,20 @@
 package orm_generator
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"text/template"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	stormParser "github.com/eleven-am/storm/internal/parser"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Helper function to check if a file or directory exists
@@ -212,3 +221,713 @@ func TestGenerateForModel(t *testing.T) {
 	// This is expected behavior when templates are missing
 	t.Logf("GenerateForModel completed, output directory exists: %v", fileExists(outputDir))
 }
+
+// hashedAccount mirrors the Account model parsed in TestSchemaHash_MatchesRuntime
+type hashedAccount struct {
+	_      struct{} `storm:"table:accounts"`
+	ID     string   `db:"id" storm:"type:uuid;primary_key"`
+	Email  string   `db:"email" storm:"type:text;unique"`
+	secret string
+}
+
+func TestSchemaHash_MatchesRuntime(t *testing.T) {
+	source := "package models\n\ntype Account struct {\n" +
+		"\t_ struct{} `storm:\"table:accounts\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tEmail string `db:\"email\" storm:\"type:text;unique\"`\n" +
+		"\tsecret string\n}\n"
+	path := filepath.Join(t.TempDir(), "account.go")
+	require.NoError(t, os.WriteFile(path, []byte(source), 0644))
+
+	tables, err := stormParser.NewStructParser().ParseFile(path)
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+
+	metadata := &orm.ModelMetadata{
+		TableName:      "accounts",
+		StructName:     "Account",
+		PrimaryKeys:    []string{"id"},
+		CodegenVersion: orm.CodegenVersion,
+		SchemaHash:     schemaHash(tables[0].Fields),
+	}
+
+	orm.SetCompatibilityMode(orm.CompatibilityError)
+	defer orm.SetCompatibilityMode(orm.CompatibilityWarn)
+
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = orm.NewRepository[hashedAccount](sqlx.NewDb(db, "postgres"), metadata)
+	assert.NoError(t, err, "hash of the parsed source should match the compiled struct")
+}
+
+func TestGenerateAll_ConstraintColumns(t *testing.T) {
+	modelDir := t.TempDir()
+	outputDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Member struct {\n" +
+		"\t_ struct{} `storm:\"table:members;unique:uk_tenant_email,tenant_id,email;check:ck_adult,age >= 18 OR tenant_id IS NULL\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tTenantID string `db:\"tenant_id\" storm:\"type:uuid\"`\n" +
+		"\tEmail string `db:\"email\" storm:\"type:text;unique\"`\n" +
+		"\tAge int `db:\"age\" storm:\"type:integer;check:age >= 0\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: outputDir})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+
+	member, ok := generator.GetModel("Member")
+	require.True(t, ok)
+	columns := make(map[string][]string)
+	for _, constraint := range member.Constraints {
+		columns[constraint.Name] = constraint.Columns
+	}
+	assert.Equal(t, map[string][]string{
+		"members_pkey":      {"id"},
+		"members_email_key": {"email"},
+		"members_age_check": {"age"},
+		"uk_tenant_email":   {"tenant_id", "email"},
+		"ck_adult":          {"age", "tenant_id"},
+	}, columns)
+
+	require.NoError(t, generator.GenerateAll())
+	generated, err := os.ReadFile(filepath.Join(outputDir, "member_metadata.go"))
+	require.NoError(t, err)
+	assert.Regexp(t, `"uk_tenant_email":\s+\{"tenant_id", "email"\},`, string(generated))
+}
+
+func TestGenerateAll_RegistersMetadata(t *testing.T) {
+	modelDir := t.TempDir()
+	outputDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Gadget struct {\n" +
+		"\t_ struct{} `storm:\"table:gadgets\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tName string `db:\"name\" storm:\"type:varchar(80);not_null;unique\"`\n" +
+		"\tStatus string `db:\"status\" storm:\"type:text;default:'draft'\"`\n" +
+		"\tOwner string `db:\"owner\" storm:\"type:text;mask:email\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: outputDir})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	generated, err := os.ReadFile(filepath.Join(outputDir, "gadget_metadata.go"))
+	require.NoError(t, err)
+	content := string(generated)
+	assert.Contains(t, content, "storm.RegisterMetadata[Gadget](GadgetMetadata)")
+	assert.Regexp(t, `FieldOrder: \[\]string\{\s+"ID",\s+"Name",\s+"Status",\s+"Owner",\s+\}`, content)
+	assert.Regexp(t, `DBName:\s+"name",\s+DBType:\s+"varchar\(80\)",[\s\S]+?IsNullable:\s+false,\s+IsUnique:\s+true,`, content)
+	assert.Regexp(t, `DBName:\s+"status",[\s\S]+?IsNullable:\s+true,\s+IsUnique:\s+false,\s+Default:\s+"'draft'",`, content)
+	assert.Regexp(t, `DBName:\s+"owner",[\s\S]+?Mask:\s+"email",`, content)
+}
+
+func TestGenerateAll_SkipsUnchangedModels(t *testing.T) {
+	modelDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	writeModels := func(noteColumn string) {
+		source := "package models\n\n" +
+			"type Author struct {\n" +
+			"\t_ struct{} `storm:\"table:authors\"`\n" +
+			"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+			"\tName string `db:\"name\" storm:\"type:text\"`\n}\n\n" +
+			"type Note struct {\n" +
+			"\t_ struct{} `storm:\"table:notes\"`\n" +
+			"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+			"\tBody string `db:\"" + noteColumn + "\" storm:\"type:text\"`\n}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+	}
+
+	generate := func(force bool) (*CodeGenerator, []string) {
+		var files []string
+		generator := NewCodeGenerator(GenerationConfig{
+			PackageName: "models",
+			OutputDir:   outputDir,
+			Force:       force,
+			Progress: func(done, total int, file string) {
+				files = append(files, file)
+			},
+		})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.GenerateAll())
+		return generator, files
+	}
+
+	writeModels("body")
+	generator, files := generate(false)
+	assert.Empty(t, generator.UnchangedModels())
+	assert.Len(t, files, 7)
+
+	authorFile := filepath.Join(outputDir, "author_metadata.go")
+	before, err := os.ReadFile(authorFile)
+	require.NoError(t, err)
+	author, _ := generator.GetModel("Author")
+	assert.Contains(t, string(before), contentHashPrefix+author.ContentHash)
+
+	generator, files = generate(false)
+	assert.Equal(t, []string{"Author", "Note"}, generator.UnchangedModels())
+	assert.Len(t, files, 7, "skipped files still count towards the progress")
+
+	writeModels("content")
+	generator, _ = generate(false)
+	assert.Equal(t, []string{"Author"}, generator.UnchangedModels())
+	note, err := os.ReadFile(filepath.Join(outputDir, "note_metadata.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(note), `"content"`)
+
+	after, err := os.ReadFile(authorFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(before), string(after), "files of unchanged models are not rewritten")
+
+	require.NoError(t, os.Remove(filepath.Join(outputDir, "author_repository.go")))
+	generator, _ = generate(false)
+	assert.Equal(t, []string{"Note"}, generator.UnchangedModels(), "a missing file regenerates its model")
+
+	generator, _ = generate(true)
+	assert.Empty(t, generator.UnchangedModels())
+}
+
+func TestGenerateAll_ExtensionFiles(t *testing.T) {
+	modelDir := t.TempDir()
+	outputDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Author struct {\n" +
+		"\t_ struct{} `storm:\"table:authors\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tName string `db:\"name\" storm:\"type:text\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	generate := func() *CodeGenerator {
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: outputDir, Force: true})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.GenerateAll())
+		return generator
+	}
+
+	generate()
+	extensions := filepath.Join(outputDir, "author_extensions.go")
+	content, err := os.ReadFile(extensions)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "package models")
+	assert.Contains(t, string(content), "func (r *AuthorRepository)")
+	assert.NotContains(t, string(content), "Code generated")
+
+	custom := string(content) + "\nfunc (r *AuthorRepository) Custom() {}\n"
+	require.NoError(t, os.WriteFile(extensions, []byte(custom), 0644))
+
+	generator := generate()
+	content, err = os.ReadFile(extensions)
+	require.NoError(t, err)
+	assert.Equal(t, custom, string(content), "regeneration keeps the extension file")
+
+	require.NoError(t, generator.CleanOutput())
+	assert.False(t, fileExists(filepath.Join(outputDir, "author_repository.go")))
+	assert.True(t, fileExists(extensions), "CleanOutput keeps the extension file")
+}
+
+func TestGenerateAll_ConcurrentRendering(t *testing.T) {
+	modelDir := t.TempDir()
+	var source strings.Builder
+	source.WriteString("package models\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&source, "\ntype Item%d struct {\n"+
+			"\t_ struct{} `storm:\"table:items_%d\"`\n"+
+			"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n"+
+			"\tName string `db:\"name\" storm:\"type:text\"`\n}\n", i, i)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source.String()), 0644))
+
+	generate := func(workers int) string {
+		outputDir := t.TempDir()
+		var done []int
+		generator := NewCodeGenerator(GenerationConfig{
+			PackageName: "models",
+			OutputDir:   outputDir,
+			Workers:     workers,
+			Progress: func(n, total int, file string) {
+				assert.Equal(t, 83, total)
+				done = append(done, n)
+			},
+		})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.GenerateAll())
+
+		require.Len(t, done, 83)
+		for i, n := range done {
+			assert.Equal(t, i+1, n, "progress is reported once per file, in order")
+		}
+		return outputDir
+	}
+
+	sequential := generate(1)
+	concurrent := generate(8)
+	generatedOn := regexp.MustCompile(`(?m)^// Generated on: .*$`)
+	for _, name := range []string{"item0_metadata.go", "item17_repository.go", "item39_metadata.go", "columns.go"} {
+		want, err := os.ReadFile(filepath.Join(sequential, name))
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(concurrent, name))
+		require.NoError(t, err)
+		assert.Equal(t, generatedOn.ReplaceAllString(string(want), ""), generatedOn.ReplaceAllString(string(got), ""), name)
+	}
+
+	first := NewCodeGenerator(GenerationConfig{PackageName: "models"})
+	second := NewCodeGenerator(GenerationConfig{PackageName: "models"})
+	require.NoError(t, first.loadTemplates())
+	require.NoError(t, second.loadTemplates())
+	assert.Same(t, first.templates["repository"], second.templates["repository"], "templates are parsed once")
+}
+
+func TestGenerateAll_ModelsAndFeatures(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Author struct {\n" +
+		"\t_ struct{} `storm:\"table:authors\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tName string `db:\"name\" storm:\"type:text\"`\n}\n\n" +
+		"type Note struct {\n" +
+		"\t_ struct{} `storm:\"table:notes\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+		"\tAuthorID string `db:\"author_id\" storm:\"type:uuid\"`\n" +
+		"\tAuthor *Author `db:\"-\" storm:\"relation:belongs_to:Author;foreign_key:author_id\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	t.Run("subset of models and features", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var files []string
+		generator := NewCodeGenerator(GenerationConfig{
+			PackageName: "models",
+			OutputDir:   outputDir,
+			Models:      []string{"Note"},
+			Features:    []string{"metadata", "Repositories"},
+			Progress: func(done, total int, file string) {
+				assert.Equal(t, 2, total)
+				files = append(files, file)
+			},
+		})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.ValidateModels(), "relationships to unselected models are validated")
+		require.NoError(t, generator.GenerateAll())
+
+		assert.Equal(t, []string{"Note"}, generator.GetModelNames())
+		assert.Equal(t, []string{"note_metadata.go", "note_repository.go"}, files)
+		for _, name := range []string{"author_metadata.go", "columns.go", "storm.go", "note_extensions.go"} {
+			assert.False(t, fileExists(filepath.Join(outputDir, name)), name)
+		}
+	})
+
+	t.Run("unknown model", func(t *testing.T) {
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: t.TempDir(), Models: []string{"Comment"}})
+		err := generator.DiscoverModels(modelDir)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "model Comment not found")
+	})
+
+	t.Run("unknown feature", func(t *testing.T) {
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: t.TempDir(), Features: []string{"mocks"}})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		err := generator.GenerateAll()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown feature "mocks"`)
+	})
+}
+
+func TestGenerateAll_InverseRelationships(t *testing.T) {
+	writeModels := func(t *testing.T, authorNotes, noteAuthor string) string {
+		modelDir := t.TempDir()
+		source := "package models\n\n" +
+			"type Author struct {\n" +
+			"\t_ struct{} `storm:\"table:authors\"`\n" +
+			"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+			"\tName string `db:\"name\" storm:\"type:text\"`\n" +
+			"\tNotes []Note `db:\"-\" storm:\"" + authorNotes + "\"`\n}\n\n" +
+			"type Note struct {\n" +
+			"\t_ struct{} `storm:\"table:notes\"`\n" +
+			"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n" +
+			"\tAuthorID string `db:\"author_id\" storm:\"type:uuid\"`\n" +
+			"\tAuthor *Author `db:\"-\" storm:\"" + noteAuthor + "\"`\n}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+		return modelDir
+	}
+
+	t.Run("loading keeps both sides in sync", func(t *testing.T) {
+		modelDir := writeModels(t,
+			"relation:has_many:Note;foreign_key:author_id;inverse:Author",
+			"relation:belongs_to:Author;foreign_key:author_id;inverse:Notes")
+		outputDir := t.TempDir()
+
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: outputDir})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.ValidateModels())
+		require.NoError(t, generator.GenerateAll())
+
+		author, err := os.ReadFile(filepath.Join(outputDir, "author_metadata.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(author), `Inverse:     "Author"`)
+		assert.Contains(t, string(author), "notes[i].Author = model.(*Author)")
+		assert.Contains(t, string(author), "var rows []Note")
+		assert.Contains(t, string(author), "related[i].Author = model.(*Author)")
+		assert.Contains(t, string(author), "model.(*Author).Notes = related")
+
+		// A has_many inverse would only hold the one loaded record, so it is left alone
+		note, err := os.ReadFile(filepath.Join(outputDir, "note_metadata.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(note), `Inverse:     "Notes"`)
+		assert.NotContains(t, string(note), "author.Notes =")
+		assert.NotContains(t, string(note), "related.Notes =")
+		assert.Contains(t, string(note), "model.(*Note).Author = &related")
+	})
+
+	t.Run("missing inverse", func(t *testing.T) {
+		modelDir := writeModels(t,
+			"relation:has_many:Note;foreign_key:author_id;inverse:Writer",
+			"relation:belongs_to:Author;foreign_key:author_id")
+
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: t.TempDir()})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		err := generator.ValidateModels()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "inverse relationship Writer not found on Note")
+	})
+
+	t.Run("inverse with the wrong cardinality", func(t *testing.T) {
+		modelDir := writeModels(t,
+			"relation:has_many:Note;foreign_key:author_id;inverse:Author",
+			"relation:has_one:Author;foreign_key:author_id")
+
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: t.TempDir()})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		err := generator.ValidateModels()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Note.Author is has_one, which cannot be the inverse of has_many")
+	})
+}
+
+func TestGenerateAll_CustomTemplates(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Author struct {\n" +
+		"\t_ struct{} `storm:\"table:authors\"`\n" +
+		"\tID string `db:\"id\" storm:\"type:uuid;primary_key\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	t.Run("template directory, functions and data", func(t *testing.T) {
+		templateDir := t.TempDir()
+		custom := "// {{ .Data.license }}\n\npackage {{ .Package }}\n\n// {{ shout .Model.Name }}\nconst {{ .Model.Name }}Table = \"{{ .Model.TableName }}\"\n"
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "repository.tmpl"), []byte(custom), 0644))
+
+		outputDir := t.TempDir()
+		generator := NewCodeGenerator(GenerationConfig{
+			PackageName: "models",
+			OutputDir:   outputDir,
+			Features:    []string{"metadata", "repositories"},
+			TemplateDir: templateDir,
+			Funcs:       template.FuncMap{"shout": func(s string) string { return strings.ToUpper(s) + "!" }},
+			Data:        map[string]interface{}{"license": "Copyright Acme Corp"},
+		})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		require.NoError(t, generator.GenerateAll())
+
+		repository, err := os.ReadFile(filepath.Join(outputDir, "author_repository.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(repository), "// Copyright Acme Corp")
+		assert.Contains(t, string(repository), "// AUTHOR!")
+		assert.Contains(t, string(repository), `const AuthorTable = "authors"`)
+		assert.True(t, fileExists(filepath.Join(outputDir, "author_metadata.go")), "templates without a replacement are built in")
+
+		assert.NotSame(t, parsedTemplates()["metadata"], generator.templates["metadata"])
+		assert.NotContains(t, parsedTemplates()["repository"].Root.String(), "shout", "the shared templates are unchanged")
+	})
+
+	t.Run("custom data is part of the content hash", func(t *testing.T) {
+		model := &ModelMetadata{Name: "Author", TableName: "authors"}
+		builtin := NewCodeGenerator(GenerationConfig{PackageName: "models"})
+		acme := NewCodeGenerator(GenerationConfig{PackageName: "models", Data: map[string]interface{}{"license": "Acme"}})
+		globex := NewCodeGenerator(GenerationConfig{PackageName: "models", Data: map[string]interface{}{"license": "Globex"}})
+		assert.NotEqual(t, builtin.contentHash(model), acme.contentHash(model))
+		assert.NotEqual(t, acme.contentHash(model), globex.contentHash(model))
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		templateDir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(templateDir, "repositories.tmpl"), []byte(""), 0644))
+
+		generator := NewCodeGenerator(GenerationConfig{PackageName: "models", OutputDir: t.TempDir(), TemplateDir: templateDir})
+		require.NoError(t, generator.DiscoverModels(modelDir))
+		err := generator.GenerateAll()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown template repositories.tmpl")
+	})
+}
+
+func TestGenerateAll_EnumTypes(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Order struct {\n" +
+		"\t_ struct{} `dbdef:\"table:orders\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tStatus string `db:\"status\" dbdef:\"type:order_status;enum:pending,in_progress,shipped\"`\n" +
+		"\tStage Phase `db:\"stage\" dbdef:\"type:text;enum:draft,final\"`\n}\n\n" +
+		"type Phase string\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"metadata", "columns"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "order_metadata.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), "type OrderStatus string")
+	assert.Contains(t, string(metadata), `OrderStatusInProgress OrderStatus = "in_progress"`)
+	assert.Contains(t, string(metadata), "var OrderStatusValues = []OrderStatus{OrderStatusPending, OrderStatusInProgress, OrderStatusShipped}")
+	assert.Contains(t, string(metadata), "func (e OrderStatus) IsValid() bool")
+	assert.Contains(t, string(metadata), "func (e OrderStatus) Value() (driver.Value, error)")
+	assert.Contains(t, string(metadata), "func (e *OrderStatus) Scan(src interface{}) error")
+	assert.NotContains(t, string(metadata), "type Phase string", "types declared in the models package are not redeclared")
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(columns), "Status storm.Column[OrderStatus]")
+	assert.Contains(t, string(columns), "Stage storm.Column[Phase]")
+}
+
+func TestGenerateAll_ColumnLists(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\nimport \"time\"\n\n" +
+		"type User struct {\n" +
+		"\t_ struct{} `dbdef:\"table:users\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key;default:gen_random_uuid()\"`\n" +
+		"\tEmail string `db:\"email\" dbdef:\"type:text;not_null\"`\n" +
+		"\tName string `db:\"name\" dbdef:\"type:text\"`\n" +
+		"\tCreatedAt time.Time `db:\"created_at\" dbdef:\"type:timestamptz;default:now()\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"columns"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(columns), `var UserAllColumns = []string{"id", "email", "name", "created_at"}`)
+	assert.Contains(t, string(columns), `var UserInsertColumns = []string{"email", "name"}`)
+	assert.Contains(t, string(columns), `var UserUpdatableColumns = []string{"email", "name"}`)
+}
+
+func TestGenerateAll_TransientFields(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type User struct {\n" +
+		"\t_ struct{} `dbdef:\"table:users\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tEmail string `db:\"email\" dbdef:\"type:text;not_null\"`\n" +
+		"\tScore int `db:\"-\"`\n" +
+		"\tDisplayName string `dbdef:\"-\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"columns"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	model, exists := generator.GetModel("User")
+	require.True(t, exists)
+	assert.Len(t, model.Columns, 2)
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(columns), `var UserAllColumns = []string{"id", "email"}`)
+	assert.NotContains(t, string(columns), "display_name")
+}
+
+func TestGenerateAll_MaterializedViewRefresh(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type User struct {\n" +
+		"\t_ struct{} `dbdef:\"table:users\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tEmail string `db:\"email\" dbdef:\"type:text\"`\n}\n\n" +
+		"type DailySales struct {\n" +
+		"\t_ struct{} `storm:\"table:daily_sales;materialized_view\"`\n" +
+		"\tDay string `db:\"day\" dbdef:\"type:date;primary_key\"`\n" +
+		"\tTotal int64 `db:\"total\" dbdef:\"type:bigint\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"metadata", "repositories"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	repository, err := os.ReadFile(filepath.Join(outputDir, "daily_sales_repository.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(repository), "func (r *DailySalesRepository) RefreshDailySales(ctx context.Context, concurrently bool) error {")
+	assert.Contains(t, string(repository), "storm.RefreshOptions{Concurrently: concurrently}")
+
+	repository, err = os.ReadFile(filepath.Join(outputDir, "user_repository.go"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(repository), "Refresh")
+}
+
+func TestGenerateAll_DefaultScope(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"import \"time\"\n\n" +
+		"type Post struct {\n" +
+		"\t_ struct{} `storm:\"table:posts;default_scope:posts.deleted_at IS NULL\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tDeletedAt *time.Time `db:\"deleted_at\" dbdef:\"type:timestamptz\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"metadata", "repositories"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), `DefaultScope: "posts.deleted_at IS NULL",`)
+
+	repository, err := os.ReadFile(filepath.Join(outputDir, "post_repository.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(repository), "func (r *PostRepository) DefaultScope(condition storm.Condition) *PostRepository {")
+	assert.Contains(t, string(repository), "func (q *PostQuery) Unscoped() *PostQuery {")
+}
+
+func TestGenerateAll_Filters(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"import \"time\"\n\n" +
+		"type Order struct {\n" +
+		"\t_ struct{} `storm:\"table:orders\"`\n" +
+		"\tID int64 `db:\"id\" dbdef:\"type:bigserial;primary_key\"`\n" +
+		"\tCustomer string `db:\"customer\" dbdef:\"type:text;not_null\"`\n" +
+		"\tPaid bool `db:\"paid\" dbdef:\"type:boolean;not_null\"`\n" +
+		"\tTags []string `db:\"tags\" dbdef:\"type:text[]\"`\n" +
+		"\tShippedAt *time.Time `db:\"shipped_at\" dbdef:\"type:timestamptz\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"columns", "filters"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "filters.go"))
+	require.NoError(t, err)
+	filters := string(content)
+
+	assert.Contains(t, filters, "type OrderFilter struct {")
+	assert.Regexp(t, `IDMin\s+\*int64\s+`+"`"+`json:"id_min,omitempty"`+"`", filters)
+	assert.Regexp(t, `Customer\s+\*string\s+`+"`"+`json:"customer,omitempty"`+"`", filters)
+	assert.Regexp(t, `ShippedAtMax\s+\*time.Time`, filters)
+	assert.NotContains(t, filters, "CustomerMin")
+	assert.NotContains(t, filters, "PaidMin")
+	assert.NotContains(t, filters, "Tags")
+	assert.Contains(t, filters, "conditions = append(conditions, Orders.Paid.Eq(*f.Paid))")
+	assert.Contains(t, filters, "conditions = append(conditions, Orders.ShippedAt.Gte(*f.ShippedAtMin))")
+	assert.Contains(t, filters, "conditions = append(conditions, Orders.ID.Lte(*f.IDMax))")
+}
+
+func TestGenerateAll_JSONBColumns(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"type Account struct {\n" +
+		"\t_ struct{} `storm:\"table:accounts\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tSettings map[string]interface{} `db:\"settings\" dbdef:\"type:jsonb\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"columns"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	columns, err := os.ReadFile(filepath.Join(outputDir, "columns.go"))
+	require.NoError(t, err)
+	assert.Regexp(t, `Settings\s+storm.JSONBColumn`, string(columns))
+	assert.Contains(t, string(columns), `Settings: storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "settings", Table: "accounts"}},`)
+}
+
+func TestGenerateAll_SoftDelete(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"import \"time\"\n\n" +
+		"type Post struct {\n" +
+		"\t_ struct{} `storm:\"table:posts\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tDeletedAt *time.Time `db:\"deleted_at\" dbdef:\"type:timestamptz;soft_delete\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"metadata", "repositories"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), `SoftDelete: "deleted_at",`)
+
+	repository, err := os.ReadFile(filepath.Join(outputDir, "post_repository.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(repository), "Restore(ctx, id)")
+}
+
+func TestGenerateAll_AutoTimestamps(t *testing.T) {
+	modelDir := t.TempDir()
+	source := "package models\n\n" +
+		"import \"time\"\n\n" +
+		"type Post struct {\n" +
+		"\t_ struct{} `storm:\"table:posts\"`\n" +
+		"\tID string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+		"\tCreatedAt time.Time `db:\"created_at\" dbdef:\"type:timestamptz;not_null;default:now();auto_create_time\"`\n" +
+		"\tUpdatedAt time.Time `db:\"updated_at\" dbdef:\"type:timestamptz;not_null;auto_update_time\"`\n}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(modelDir, "models.go"), []byte(source), 0644))
+
+	outputDir := t.TempDir()
+	generator := NewCodeGenerator(GenerationConfig{
+		PackageName: "models",
+		OutputDir:   outputDir,
+		Features:    []string{"metadata"},
+	})
+	require.NoError(t, generator.DiscoverModels(modelDir))
+	require.NoError(t, generator.GenerateAll())
+
+	metadata, err := os.ReadFile(filepath.Join(outputDir, "post_metadata.go"))
+	require.NoError(t, err)
+	assert.Regexp(t, `AutoCreateTime:\s+true,`, string(metadata))
+	assert.Regexp(t, `AutoUpdateTime:\s+true,`, string(metadata))
+	assert.NotContains(t, string(metadata), "IsAutoGenerated: true", "the repository writes auto timestamps, even over default:now()")
+}
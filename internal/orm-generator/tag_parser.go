@@ -3,6 +3,7 @@ package orm_generator
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/eleven-am/storm/internal/parser"
@@ -242,22 +243,35 @@ type FieldMetadata struct {
 	IsRequired      bool              // Whether it's required (not null)
 	IsAutoGenerated bool              // Whether it's auto-generated (serial, default:now(), etc)
 	DefaultValue    string            // Default value
+	Mask            string            // How the column is anonymized, from the mask attribute
+	AutoCreateTime  bool              // Create sets the column to the current time, from the auto_create_time attribute
+	AutoUpdateTime  bool              // Create and Update set the column to the current time, from the auto_update_time attribute
 	Tags            map[string]string // All struct tags
 	DBDef           map[string]string // Parsed dbdef tags
 	Relationship    *ParsedORMTag     // Parsed ORM relationship tag
+	SyncInverse     bool              // Whether loading sets the inverse relationship of the loaded records
+	TargetTable     string            // Table of the relationship's target model
+	EnumValues      []string          // Values of an enum column, from the enum attribute
+	EnumType        string            // Go type of an enum column's values and conditions
+	DeclareEnum     bool              // Whether EnumType is generated rather than declared in the models package
 }
 
 // ModelMetadata represents metadata about a model for code generation
 type ModelMetadata struct {
-	Name          string               // Struct name
-	Package       string               // Package name
-	TableName     string               // Database table name
-	Fields        []FieldMetadata      // All fields
-	Relationships []FieldMetadata      // Only relationship fields
-	Columns       []FieldMetadata      // Only database columns
-	PrimaryKeys   []string             // Primary key column names
-	Indexes       []IndexMetadata      // Index definitions
-	Constraints   []ConstraintMetadata // Constraint definitions
+	Name             string               // Struct name
+	Package          string               // Package name
+	TableName        string               // Database table name
+	Fields           []FieldMetadata      // All fields
+	Relationships    []FieldMetadata      // Only relationship fields
+	Columns          []FieldMetadata      // Only database columns
+	PrimaryKeys      []string             // Primary key column names
+	Indexes          []IndexMetadata      // Index definitions
+	Constraints      []ConstraintMetadata // Constraint definitions
+	SchemaHash       string               // Hash of the struct fields, checked by the runtime
+	MaterializedView bool                 // The model reads a materialized view
+	DefaultScope     string               // Condition every query of the model is restricted to
+	SoftDelete       string               // Column deleting a record sets, from the soft_delete attribute
+	ContentHash      string               `json:"-"` // Hash of everything the model's files are generated from
 }
 
 // IndexMetadata represents index metadata
@@ -270,28 +284,36 @@ type IndexMetadata struct {
 
 // ConstraintMetadata represents constraint metadata
 type ConstraintMetadata struct {
-	Name       string // Constraint name
-	Type       string // Constraint type (CHECK, FOREIGN KEY, etc.)
-	Definition string // Constraint definition
+	Name       string   // Constraint name
+	Type       string   // Constraint type (CHECK, FOREIGN KEY, etc.)
+	Definition string   // Constraint definition
+	Columns    []string // Columns the constraint covers
 }
 
 func (p *ORMTagParser) ParseModelFromTable(table parser.TableDefinition) (*ModelMetadata, error) {
 	metadata := &ModelMetadata{
-		Name:          table.StructName,
-		Package:       "",
-		TableName:     table.TableName,
-		Fields:        make([]FieldMetadata, 0),
-		Relationships: make([]FieldMetadata, 0),
-		Columns:       make([]FieldMetadata, 0),
-		PrimaryKeys:   make([]string, 0),
-		Indexes:       make([]IndexMetadata, 0),
-		Constraints:   make([]ConstraintMetadata, 0),
+		Name:             table.StructName,
+		Package:          "",
+		TableName:        table.TableName,
+		Fields:           make([]FieldMetadata, 0),
+		Relationships:    make([]FieldMetadata, 0),
+		Columns:          make([]FieldMetadata, 0),
+		PrimaryKeys:      make([]string, 0),
+		Indexes:          make([]IndexMetadata, 0),
+		Constraints:      make([]ConstraintMetadata, 0),
+		MaterializedView: table.IsMaterializedView(),
+		DefaultScope:     table.DefaultScope(),
+		SoftDelete:       table.SoftDeleteColumn(),
 	}
 
 	for _, field := range table.Fields {
+		if field.IsTransient() {
+			continue
+		}
+
 		fieldMeta, err := p.parseFieldFromAST(field)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse field %s: %w", field.Name, err)
+			return nil, parser.WithPosition(field.Pos, fmt.Errorf("failed to parse field %s: %w", field.Name, err))
 		}
 
 		metadata.Fields = append(metadata.Fields, fieldMeta)
@@ -314,6 +336,24 @@ func (p *ORMTagParser) ParseFieldFromAST(field parser.FieldDefinition) (FieldMet
 	return p.parseFieldFromAST(field)
 }
 
+// applyGeneratedValues records the default of the column and whether its value
+// is generated by the database or written by the repository as a timestamp
+func applyGeneratedValues(fieldMeta *FieldMetadata, dbDef map[string]string) {
+	if defaultVal, hasDefault := dbDef["default"]; hasDefault {
+		fieldMeta.DefaultValue = defaultVal
+		if isAutoGeneratedDefault(defaultVal) || dbDef["type"] == "serial" {
+			fieldMeta.IsAutoGenerated = true
+		}
+	}
+
+	// The repository writes auto timestamps itself, even over a default:now()
+	_, fieldMeta.AutoCreateTime = dbDef["auto_create_time"]
+	_, fieldMeta.AutoUpdateTime = dbDef["auto_update_time"]
+	if fieldMeta.AutoCreateTime || fieldMeta.AutoUpdateTime {
+		fieldMeta.IsAutoGenerated = false
+	}
+}
+
 func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMetadata, error) {
 	fieldMeta := FieldMetadata{
 		Name:      field.Name,
@@ -323,6 +363,7 @@ func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMet
 		IsArray:   field.IsArray,
 		Tags:      make(map[string]string),
 		DBDef:     field.DBDef,
+		Mask:      field.DBDef["mask"],
 	}
 
 	fieldMeta.Tags["db"] = field.DBTag
@@ -338,12 +379,7 @@ func (p *ORMTagParser) parseFieldFromAST(field parser.FieldDefinition) (FieldMet
 		fieldMeta.IsUnique = true
 	}
 
-	if defaultVal, hasDefault := field.DBDef["default"]; hasDefault {
-		fieldMeta.DefaultValue = defaultVal
-		if isAutoGeneratedDefault(defaultVal) || field.DBDef["type"] == "serial" {
-			fieldMeta.IsAutoGenerated = true
-		}
-	}
+	applyGeneratedValues(&fieldMeta, field.DBDef)
 
 	if field.StormTag != "" {
 		isRelationshipField := field.IsArray || field.IsPointer
@@ -423,7 +459,11 @@ func (p *ORMTagParser) parseField(field reflect.StructField) (FieldMetadata, err
 	}
 
 	if dbdefTag := field.Tag.Get("dbdef"); dbdefTag != "" {
-		fieldMeta.DBDef = parseDBDefTag(dbdefTag)
+		dbdef, err := parseDBDefTag(dbdefTag)
+		if err != nil {
+			return fieldMeta, err
+		}
+		fieldMeta.DBDef = dbdef
 
 		if _, exists := fieldMeta.DBDef["primary_key"]; exists {
 			fieldMeta.IsPrimaryKey = true
@@ -453,29 +493,35 @@ func (p *ORMTagParser) parseField(field reflect.StructField) (FieldMetadata, err
 	return fieldMeta, nil
 }
 
-func parseDBDefTag(tag string) map[string]string {
+// dbdefKeyPattern matches the attribute names of a dbdef tag
+var dbdefKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func parseDBDefTag(tag string) (map[string]string, error) {
 	result := make(map[string]string)
+	if strings.TrimSpace(tag) == "" {
+		return result, nil
+	}
 
-	parts := strings.Split(tag, ";")
+	parts := parser.SplitTopLevel(tag, ';')
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
-			continue
+			return nil, fmt.Errorf("invalid dbdef tag %q: empty attribute", tag)
 		}
 
+		key, value := part, "true"
 		if strings.Contains(part, ":") {
 			kv := strings.SplitN(part, ":", 2)
-			if len(kv) == 2 {
-				key := strings.TrimSpace(kv[0])
-				value := strings.TrimSpace(kv[1])
-				result[key] = value
-			}
-		} else {
-			result[part] = "true"
+			key = strings.TrimSpace(kv[0])
+			value = strings.TrimSpace(kv[1])
+		}
+		if !dbdefKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid dbdef tag %q: attribute name %q", tag, key)
 		}
+		result[key] = value
 	}
 
-	return result
+	return result, nil
 }
 
 func toSnakeCase(s string) string {
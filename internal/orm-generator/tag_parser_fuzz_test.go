@@ -0,0 +1,147 @@
+package orm_generator
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func FuzzParseDBDefTag(f *testing.F) {
+	seeds := []string{
+		"",
+		"type:uuid;primary_key;default:gen_random_uuid()",
+		"type:varchar(255);not_null;unique",
+		";;type:text;;",
+		"check:(price > 0 AND price < 100)",
+		"default:'a:b;c'",
+		":orphan_value",
+		"=value",
+		"type:",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, tag string) {
+		attrs, err := parseDBDefTag(tag)
+		if err != nil {
+			return
+		}
+
+		for key, value := range attrs {
+			if key == "" {
+				t.Fatalf("parseDBDefTag(%q) produced an empty key", tag)
+			}
+			if key != strings.TrimSpace(key) || value != strings.TrimSpace(value) {
+				t.Fatalf("parseDBDefTag(%q) produced untrimmed pair %q=%q", tag, key, value)
+			}
+			if strings.Contains(key, ";") || strings.Contains(key, ":") {
+				t.Fatalf("parseDBDefTag(%q) produced key %q containing a separator", tag, key)
+			}
+		}
+	})
+}
+
+// TestParseDBDefTag_RoundTrip checks that serializing well-formed attributes
+// and parsing them again yields the same attributes
+func TestParseDBDefTag_RoundTrip(t *testing.T) {
+	cases := []map[string]string{
+		{"type": "uuid", "primary_key": "true"},
+		{"type": "varchar(255)", "not_null": "true", "unique": "true", "default": "'pending'"},
+		{"type": "integer", "fk": "users.id", "on_delete": "CASCADE"},
+		{"type": "timestamptz", "default": "now()"},
+		{"type": "numeric(10,2)", "check": "amount >= 0"},
+	}
+
+	for _, attrs := range cases {
+		keys := make([]string, 0, len(attrs))
+		for key := range attrs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if attrs[key] == "true" {
+				parts = append(parts, key)
+			} else {
+				parts = append(parts, key+":"+attrs[key])
+			}
+		}
+		tag := strings.Join(parts, ";")
+
+		got, err := parseDBDefTag(tag)
+		if err != nil {
+			t.Fatalf("parseDBDefTag(%q) error = %v", tag, err)
+		}
+		if len(got) != len(attrs) {
+			t.Fatalf("parseDBDefTag(%q) = %v, want %v", tag, got, attrs)
+		}
+		for key, value := range attrs {
+			if got[key] != value {
+				t.Errorf("parseDBDefTag(%q)[%q] = %q, want %q", tag, key, got[key], value)
+			}
+		}
+	}
+}
+
+func TestParseDBDefTag_Malformed(t *testing.T) {
+	for _, tag := range []string{";type:text", "type:text;;unique", "type:text;", ":orphan_value", "=value", "not null"} {
+		if attrs, err := parseDBDefTag(tag); err == nil {
+			t.Errorf("parseDBDefTag(%q) = %v, want an error", tag, attrs)
+		}
+	}
+}
+
+func FuzzParseORMTag(f *testing.F) {
+	seeds := []string{
+		"belongs_to:User",
+		"belongs_to:User,foreign_key:author_id",
+		"has_many:Post,foreign_key:user_id,order_by:created_at DESC",
+		"has_many_through:Tag,join_table:post_tags,source_fk:post_id,target_fk:tag_id",
+		"has_one:Profile,foreign_key:user_id,dependent:destroy",
+		"has_many:Post,,foreign_key:user_id",
+		"has_many:Post,foreign_key:",
+		"belongs_to:",
+		":User",
+		"belongs_to:User:Extra",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, tag string) {
+		parsed, err := NewORMTagParser().ParseORMTag(tag)
+		if err != nil {
+			if err.Error() == "" {
+				t.Fatalf("ParseORMTag(%q) returned an empty error message", tag)
+			}
+			return
+		}
+
+		switch parsed.Type {
+		case "belongs_to", "has_one", "has_many", "has_many_through":
+		default:
+			t.Fatalf("ParseORMTag(%q) accepted invalid type %q", tag, parsed.Type)
+		}
+
+		if parsed.Target == "" {
+			t.Fatalf("ParseORMTag(%q) accepted an empty target", tag)
+		}
+
+		switch parsed.Type {
+		case "belongs_to":
+			if parsed.ForeignKey == "" || parsed.TargetKey == "" {
+				t.Fatalf("ParseORMTag(%q) left belongs_to keys empty: %+v", tag, parsed)
+			}
+		case "has_one", "has_many":
+			if parsed.ForeignKey == "" || parsed.SourceKey == "" {
+				t.Fatalf("ParseORMTag(%q) left %s keys empty: %+v", tag, parsed.Type, parsed)
+			}
+		case "has_many_through":
+			if parsed.JoinTable == "" || parsed.SourceFK == "" || parsed.TargetFK == "" {
+				t.Fatalf("ParseORMTag(%q) left join keys empty: %+v", tag, parsed)
+			}
+		}
+	})
+}
@@ -12,6 +12,9 @@ const metadataTemplate = `//go:build !exclude_generated
 // Source package: {{ .Package }}
 // Model: {{ .Model.Name }}
 // Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
+// Generated by: storm {{ .Version }}
+// Schema hash: {{ .Model.SchemaHash }}
+// Content hash: {{ .Model.ContentHash }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -25,23 +28,115 @@ import (
 	{{- if .Model.Relationships }}
 	"context"
 	{{- end }}
+	{{- if .HasEnums }}
+	"database/sql/driver"
+	"fmt"
+	{{- end }}
 	storm "github.com/eleven-am/storm/pkg/storm-orm"
 )
+{{- range .Model.Columns }}
+{{- if .DeclareEnum }}
+{{- $enum := .EnumType }}
+
+// {{ $enum }} is a value of {{ $.Model.TableName }}.{{ .DBName }}
+type {{ $enum }} string
+
+// Values of {{ $enum }}
+const (
+	{{- range .EnumValues }}
+	{{ $enum }}{{ pascal . }} {{ $enum }} = {{ printf "%q" . }}
+	{{- end }}
+)
+
+// {{ $enum }}Values lists the values of {{ $enum }} in declaration order
+var {{ $enum }}Values = []{{ $enum }}{ {{- range $i, $v := .EnumValues }}{{ if $i }}, {{ end }}{{ $enum }}{{ pascal $v }}{{ end -}} }
+
+// IsValid reports whether e is one of the {{ $enum }}Values
+func (e {{ $enum }}) IsValid() bool {
+	for _, value := range {{ $enum }}Values {
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the database value of e
+func (e {{ $enum }}) String() string {
+	return string(e)
+}
+
+// Value implements driver.Valuer, refusing values that are not valid
+func (e {{ $enum }}) Value() (driver.Value, error) {
+	if !e.IsValid() {
+		return nil, fmt.Errorf("invalid {{ $enum }} %q", string(e))
+	}
+	return string(e), nil
+}
+
+// Scan implements sql.Scanner
+func (e *{{ $enum }}) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		*e = {{ $enum }}(v)
+	case []byte:
+		*e = {{ $enum }}(v)
+	case nil:
+		*e = ""
+	default:
+		return fmt.Errorf("cannot scan %T into {{ $enum }}", src)
+	}
+	return nil
+}
+{{- end }}
+{{- end }}
 
 // {{ .Model.Name }}Metadata provides compile-time metadata for {{ .Model.Name }}
 var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 	TableName:  "{{ .Model.TableName }}",
 	StructName: "{{ .Model.Name }}",
+
+	// Checked against {{ .Model.Name }} when its repository is created
+	GeneratorVersion: "{{ .Version }}",
+	CodegenVersion:   {{ .CodegenVersion }},
+	SchemaHash:       "{{ .Model.SchemaHash }}",
+	{{- if .Model.DefaultScope }}
+
+	// Every query of {{ .Model.Name }} is restricted to this, unless Unscoped
+	DefaultScope: {{ printf "%q" .Model.DefaultScope }},
+	{{- end }}
+	{{- if .Model.SoftDelete }}
+
+	// Delete sets this column instead of removing the row, and queries skip those set
+	SoftDelete: {{ printf "%q" .Model.SoftDelete }},
+	{{- end }}
 	
 	Columns: map[string]*storm.ColumnMetadata{
 		{{- range .Model.Columns }}
 		"{{ .Name }}": {
 			FieldName:       "{{ .Name }}",
 			DBName:          "{{ .DBName }}",
+			{{- if .DBType }}
+			DBType:          {{ printf "%q" .DBType }},
+			{{- end }}
 			GoType:          "{{ .Type }}",
 			IsPointer:       {{ .IsPointer }},
 			IsPrimaryKey:    {{ .IsPrimaryKey }},
 			IsAutoGenerated: {{ .IsAutoGenerated }},
+			IsNullable:      {{ not .IsRequired }},
+			IsUnique:        {{ .IsUnique }},
+			{{- if .DefaultValue }}
+			Default:         {{ printf "%q" .DefaultValue }},
+			{{- end }}
+			{{- if .Mask }}
+			Mask:            {{ printf "%q" .Mask }},
+			{{- end }}
+			{{- if .AutoCreateTime }}
+			AutoCreateTime:  true,
+			{{- end }}
+			{{- if .AutoUpdateTime }}
+			AutoUpdateTime:  true,
+			{{- end }}
 			
 			// Generated accessor functions for zero-reflection field access
 			GetValue: func(model interface{}) interface{} {
@@ -76,11 +171,28 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 		{{- end }}
 	},
 	
+	FieldOrder: []string{
+		{{- range .Model.Columns }}
+		"{{ .Name }}",
+		{{- end }}
+	},
+	
 	PrimaryKeys: []string{
 		{{- range .Model.PrimaryKeys }}
 		"{{ . }}",
 		{{- end }}
 	},
+	{{- if .Model.Constraints }}
+	
+	// Constraint name -> columns, used to report the fields a violation is about
+	Constraints: map[string][]string{
+		{{- range .Model.Constraints }}
+		{{- if .Columns }}
+		"{{ .Name }}": { {{- range $i, $c := .Columns }}{{ if $i }}, {{ end }}"{{ $c }}"{{ end -}} },
+		{{- end }}
+		{{- end }}
+	},
+	{{- end }}
 	
 	Relationships: map[string]*storm.RelationshipMetadata{
 		{{- range .Model.Relationships }}
@@ -88,6 +200,9 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 			Name:   "{{ .Name }}",
 			Type:   "{{ .Relationship.Type }}",
 			Target: "{{ .Relationship.Target }}",
+			{{- if .TargetTable }}
+			TargetTable: "{{ .TargetTable }}",
+			{{- end }}
 			{{- if .Relationship.ForeignKey }}
 			ForeignKey: "{{ .Relationship.ForeignKey }}",
 			{{- end }}
@@ -106,6 +221,9 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 			{{- if .Relationship.TargetFK }}
 			ThroughTK: "{{ .Relationship.TargetFK }}",
 			{{- end }}
+			{{- if .Relationship.Inverse }}
+			Inverse: "{{ .Relationship.Inverse }}",
+			{{- end }}
 			
 			// Zero-reflection relationship scanning - directly scan and set on model
 			ScanToModel: func(ctx context.Context, exec storm.DBExecutor, query string, args []interface{}, model interface{}) error {
@@ -115,6 +233,11 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 				if err != nil {
 					return err
 				}
+				{{- if .SyncInverse }}
+				for i := range {{ lower .Name }} {
+					{{ lower .Name }}[i].{{ .Relationship.Inverse }} = model.(*{{ $.Model.Name }})
+				}
+				{{- end }}
 				model.(*{{ $.Model.Name }}).{{ .Name }} = {{ lower .Name }}
 				{{- else if or (eq .Relationship.Type "has_one") (eq .Relationship.Type "belongs_to") }}
 				var {{ lower .Name }} {{ .Relationship.Target }}
@@ -122,6 +245,9 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 				if err != nil {
 					return err
 				}
+				{{- if .SyncInverse }}
+				{{ lower .Name }}.{{ .Relationship.Inverse }} = model.(*{{ $.Model.Name }})
+				{{- end }}
 				{{- if .IsPointer }}
 				model.(*{{ $.Model.Name }}).{{ .Name }} = &{{ lower .Name }}
 				{{- else }}
@@ -130,10 +256,52 @@ var {{ .Model.Name }}Metadata = &storm.ModelMetadata{
 				{{- end }}
 				return nil
 			},
+			
+			// Batched loading - scans the related records of many models in one query
+			ScanTargets: func(ctx context.Context, exec storm.DBExecutor, query string, args []interface{}) ([]interface{}, error) {
+				var rows []{{ .Relationship.Target }}
+				if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+					return nil, err
+				}
+				targets := make([]interface{}, len(rows))
+				for i := range rows {
+					targets[i] = rows[i]
+				}
+				return targets, nil
+			},
+			SetTargets: func(model interface{}, targets []interface{}) {
+				{{- if or (eq .Relationship.Type "has_many") (eq .Relationship.Type "has_many_through") }}
+				related := make([]{{ .Relationship.Target }}, len(targets))
+				for i, target := range targets {
+					related[i] = target.({{ .Relationship.Target }})
+					{{- if .SyncInverse }}
+					related[i].{{ .Relationship.Inverse }} = model.(*{{ $.Model.Name }})
+					{{- end }}
+				}
+				model.(*{{ $.Model.Name }}).{{ .Name }} = related
+				{{- else }}
+				if len(targets) == 0 {
+					return
+				}
+				related := targets[0].({{ .Relationship.Target }})
+				{{- if .SyncInverse }}
+				related.{{ .Relationship.Inverse }} = model.(*{{ $.Model.Name }})
+				{{- end }}
+				{{- if .IsPointer }}
+				model.(*{{ $.Model.Name }}).{{ .Name }} = &related
+				{{- else }}
+				model.(*{{ $.Model.Name }}).{{ .Name }} = related
+				{{- end }}
+				{{- end }}
+			},
 		},
 		{{- end }}
 	},
 }
+
+func init() {
+	storm.RegisterMetadata[{{ .Model.Name }}]({{ .Model.Name }}Metadata)
+}
 `
 
 // columnTemplate generates type-safe column constants
@@ -166,11 +334,11 @@ import (
 // {{ $model.Name }}s provides type-safe column references for {{ $model.Name }}
 var {{ $model.Name }}s = struct {
 	{{range $model.Columns}}
-	{{ sanitizeGoName .Name }} {{ if eq .Type "string" }}storm.StringColumn{{ else if eq .Type "int" }}storm.NumericColumn[int]{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{{ else if eq .Type "bool" }}storm.BoolColumn{{ else if eq .Type "time.Time" }}storm.TimeColumn{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{{ else if eq .Type "" }}storm.StringColumn{{ else }}storm.Column[interface{}]{{ end }} ` + "`json:\"{{ .DBName }}\"`" + `
+	{{ sanitizeGoName .Name }} {{ if .EnumType }}storm.Column[{{ .EnumType }}]{{ else if eq .Type "string" }}storm.StringColumn{{ else if eq .Type "int" }}storm.NumericColumn[int]{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{{ else if eq .Type "bool" }}storm.BoolColumn{{ else if eq .Type "time.Time" }}storm.TimeColumn{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{{ else if eq .Type "storm.Vector" }}storm.VectorColumn{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{{ else if eq (lower .DBType) "jsonb" }}storm.JSONBColumn{{ else if eq .Type "" }}storm.StringColumn{{ else }}storm.Column[interface{}]{{ end }} ` + "`json:\"{{ .DBName }}\"`" + `
 	{{end}}
 }{
 	{{range $model.Columns}}
-	{{ sanitizeGoName .Name }}: {{ if eq .Type "string" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "int" }}storm.NumericColumn[int]{ComparableColumn: storm.ComparableColumn[int]{Column: storm.Column[int]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{ComparableColumn: storm.ComparableColumn[int32]{Column: storm.Column[int32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{ComparableColumn: storm.ComparableColumn[int64]{Column: storm.Column[int64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{ComparableColumn: storm.ComparableColumn[float32]{Column: storm.Column[float32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{ComparableColumn: storm.ComparableColumn[float64]{Column: storm.Column[float64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "bool" }}storm.BoolColumn{Column: storm.Column[bool]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "time.Time" }}storm.TimeColumn{ComparableColumn: storm.ComparableColumn[time.Time]{Column: storm.Column[time.Time]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{Column: storm.Column[[]string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{Column: storm.Column[{{ .Type }}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else }}storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}{{ end }},
+	{{ sanitizeGoName .Name }}: {{ if .EnumType }}storm.Column[{{ .EnumType }}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}{{ else if eq .Type "string" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "int" }}storm.NumericColumn[int]{ComparableColumn: storm.ComparableColumn[int]{Column: storm.Column[int]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int32" }}storm.NumericColumn[int32]{ComparableColumn: storm.ComparableColumn[int32]{Column: storm.Column[int32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "int64" }}storm.NumericColumn[int64]{ComparableColumn: storm.ComparableColumn[int64]{Column: storm.Column[int64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float32" }}storm.NumericColumn[float32]{ComparableColumn: storm.ComparableColumn[float32]{Column: storm.Column[float32]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "float64" }}storm.NumericColumn[float64]{ComparableColumn: storm.ComparableColumn[float64]{Column: storm.Column[float64]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "bool" }}storm.BoolColumn{Column: storm.Column[bool]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "time.Time" }}storm.TimeColumn{ComparableColumn: storm.ComparableColumn[time.Time]{Column: storm.Column[time.Time]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}}{{ else if eq .Type "storm.StringArray" }}storm.ArrayColumn[string]{Column: storm.Column[[]string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "storm.Vector" }}storm.VectorColumn{Column: storm.Column[storm.Vector]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "[]" }}storm.ArrayColumn[{{ .Type }}]{Column: storm.Column[{{ .Type }}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "json.RawMessage" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "storm.JSONData" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if hasPrefix .Type "JSONField[" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq (lower .DBType) "jsonb" }}storm.JSONBColumn{Column: storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else if eq .Type "" }}storm.StringColumn{Column: storm.Column[string]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}}{{ else }}storm.Column[interface{}]{Name: "{{ .DBName }}", Table: "{{ $model.TableName }}"}{{ end }},
 	{{end}}
 }
 
@@ -180,6 +348,15 @@ var {{ $model.Name }}Table = storm.Table{
 	PrimaryKeys: []string{ {{ range $model.PrimaryKeys }}"{{ . }}", {{ end }} },
 }
 
+// {{ $model.Name }}AllColumns lists every column of {{ $model.TableName }} in field order, for SELECT lists
+var {{ $model.Name }}AllColumns = []string{ {{- range $i, $c := $model.Columns }}{{ if $i }}, {{ end }}"{{ $c.DBName }}"{{ end -}} }
+
+// {{ $model.Name }}InsertColumns lists the columns an INSERT into {{ $model.TableName }} sets: all but those the database generates
+var {{ $model.Name }}InsertColumns = []string{ {{- $n := 0 }}{{ range $model.Columns }}{{ if not .IsAutoGenerated }}{{ if $n }}, {{ end }}{{ $n = 1 }}"{{ .DBName }}"{{ end }}{{ end -}} }
+
+// {{ $model.Name }}UpdatableColumns lists the columns an UPDATE of {{ $model.TableName }} may set: all but the primary key and generated columns
+var {{ $model.Name }}UpdatableColumns = []string{ {{- $n = 0 }}{{ range $model.Columns }}{{ if not (or .IsPrimaryKey .IsAutoGenerated) }}{{ if $n }}, {{ end }}{{ $n = 1 }}"{{ .DBName }}"{{ end }}{{ end -}} }
+
 {{end}}
 `
 
@@ -195,6 +372,7 @@ const repositoryTemplate = `//go:build !exclude_generated
 // Source package: {{ .Package }}
 // Model: {{ .Model.Name }}
 // Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
+// Content hash: {{ .Model.ContentHash }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -207,6 +385,7 @@ package {{ .Package }}
 import (
 	"context"
 	"fmt"
+	"net/url"
 	storm "github.com/eleven-am/storm/pkg/storm-orm"
 	"github.com/jmoiron/sqlx"
 )
@@ -221,9 +400,16 @@ import (
 //   - Update(ctx, record) - Update single record by primary key, returns updated record
 //   - Delete(ctx, id) - Delete record by primary key ID, returns deleted record
 //   - DeleteRecord(ctx, record) - Delete record using the record instance, returns deleted record
+{{- if .Model.SoftDelete }}
+//   - Restore(ctx, id) - Undo the soft delete of a record, returns restored record
+{{- end }}
 //
 // Batch Operations:
 //   - CreateMany(ctx, records) - Insert multiple records in transaction
+//   - CreateManyWithOptions(ctx, records, opts) - Insert in chunks, optionally skipping failed chunks
+//   - FindByIDs(ctx, ids...) - Find records by primary keys, in chunks for large key sets
+//   - DeleteByIDs(ctx, ids...) - Delete records by primary keys, in chunks for large key sets
+//   - DeleteWhere(ctx, conditions...) - Delete records matching all conditions
 //   - BulkUpdate(ctx, records, opts) - Update multiple records with bulk operation
 //   - Upsert(ctx, record, opts) - Insert or update single record on conflict
 //   - UpsertMany(ctx, records, opts) - Insert or update multiple records on conflict
@@ -336,6 +522,27 @@ func (r *{{ .Model.Name }}Repository) Authorize(fn func(ctx context.Context, que
 	}
 }
 
+// DefaultScope returns a new Repository whose queries are restricted to condition,
+// which a query drops with Unscoped
+//
+// Example:
+//   visible := repo.DefaultScope({{ .Model.Name }}s.DeletedAt.IsNull())
+//   {{ lower .Model.Name }}s, err := visible.Query(ctx).Find()
+func (r *{{ .Model.Name }}Repository) DefaultScope(condition storm.Condition) *{{ .Model.Name }}Repository {
+	return &{{ .Model.Name }}Repository{
+		Repository: r.Repository.DefaultScope(condition),
+	}
+}
+{{- if .Model.MaterializedView }}
+
+// Refresh{{ .Model.Name }} refreshes the {{ .Model.TableName }} materialized view. Refreshing
+// concurrently keeps it readable meanwhile, which needs a unique index on the view.
+// Use Refresh with storm.RefreshOptions{Lock: true} to skip overlapping refreshes.
+func (r *{{ .Model.Name }}Repository) Refresh{{ .Model.Name }}(ctx context.Context, concurrently bool) error {
+	return r.Refresh(ctx, storm.RefreshOptions{Concurrently: concurrently})
+}
+{{- end }}
+
 // {{ .Model.Name }}Query provides type-safe query building for {{ .Model.Name }}
 //
 // Query Methods (returned by Query(ctx)):
@@ -357,8 +564,9 @@ func (r *{{ .Model.Name }}Repository) Authorize(fn func(ctx context.Context, que
 //   - First() - Execute query and return first record
 //   - Count() - Execute count query
 //   - Exists() - Check if any records exist
-//   - Delete() - Execute DELETE query
+//   - Delete() - Execute DELETE query, refused without conditions unless AllowFullTable() is called
 //   - ExecuteRaw(query, args...) - Execute raw SQL
+//   - ToSQL() - Return the SELECT statement without running it
 //
 // Example usage:
 //   // Simple query
@@ -444,6 +652,17 @@ func (q *{{ .Model.Name }}Query) OrderBy(expressions ...string) *{{ .Model.Name
 	return q
 }
 
+// OrderByRandom shuffles the results in an order fixed by seed, so pages
+// of the same seed neither repeat nor skip records.
+//
+// Examples:
+//   // A stable shuffle per visitor
+//   query.OrderByRandom(sessionSeed).Limit(20).Offset(40)
+func (q *{{ .Model.Name }}Query) OrderByRandom(seed int64) *{{ .Model.Name }}Query {
+	q.Query = q.Query.OrderByRandom(seed)
+	return q
+}
+
 // Limit restricts the number of results returned.
 // Useful for pagination and preventing large result sets.
 //
@@ -470,6 +689,17 @@ func (q *{{ .Model.Name }}Query) Offset(offset uint64) *{{ .Model.Name }}Query {
 	return q
 }
 
+// Filter applies the filter and sort parameters of a request, as whitelisted
+// by spec. Invalid parameters fail the query with storm.ErrInvalidFilter.
+//
+// Examples:
+//   // GET /{{ lower .Model.Name }}s?{{ (index .Model.Columns 0).DBName }}[in]=1,2&sort=-{{ (index .Model.Columns 0).DBName }}
+//   query.Filter(spec, r.URL.Query())
+func (q *{{ .Model.Name }}Query) Filter(spec storm.FilterSpec, params url.Values) *{{ .Model.Name }}Query {
+	q.Query = q.Query.Filter(spec, params)
+	return q
+}
+
 // Find executes the query and returns all matching {{ .Model.Name }} records.
 // Returns an empty slice if no records are found.
 //
@@ -543,13 +773,72 @@ func (q *{{ .Model.Name }}Query) Exists() (bool, error) {
 	return q.Query.Exists()
 }
 
+// AllowFullTable lets Delete run without conditions.
+// Without it, a Delete with no Where returns storm.ErrNoConditions.
+func (q *{{ .Model.Name }}Query) AllowFullTable() *{{ .Model.Name }}Query {
+	q.Query = q.Query.AllowFullTable()
+	return q
+}
+
+// AllowFullTableUpdate lets Update run without conditions.
+// Without it, an Update with no Where returns storm.ErrNoConditions,
+// unless the Storm allows full table updates.
+func (q *{{ .Model.Name }}Query) AllowFullTableUpdate() *{{ .Model.Name }}Query {
+	q.Query = q.Query.AllowFullTableUpdate()
+	return q
+}
+
+// Unscoped drops the default scopes from the query, e.g. to find soft-deleted
+// {{ lower .Model.Name }}s. Authorization conditions are kept.
+func (q *{{ .Model.Name }}Query) Unscoped() *{{ .Model.Name }}Query {
+	q.Query = q.Query.Unscoped()
+	return q
+}
+
+// Sample reads about percent of the {{ lower .Model.Name }}s at random, through
+// TABLESAMPLE, before the conditions apply.
+//
+// Examples:
+//   // Rough share of matching rows from a 1% sample
+//   query.Sample(storm.System, 1).Count()
+func (q *{{ .Model.Name }}Query) Sample(method storm.SampleMethod, percent float64) *{{ .Model.Name }}Query {
+	q.Query = q.Query.Sample(method, percent)
+	return q
+}
+
+// ForUpdate locks the {{ lower .Model.Name }}s the query returns until the end of the
+// transaction. Combine with SkipLocked to let concurrent workers claim different rows.
+func (q *{{ .Model.Name }}Query) ForUpdate() *{{ .Model.Name }}Query {
+	q.Query = q.Query.ForUpdate()
+	return q
+}
+
+// ForShare locks the {{ lower .Model.Name }}s the query returns against changes until the
+// end of the transaction, sharing the lock with other readers.
+func (q *{{ .Model.Name }}Query) ForShare() *{{ .Model.Name }}Query {
+	q.Query = q.Query.ForShare()
+	return q
+}
+
+// SkipLocked leaves out the rows other transactions have locked instead of waiting.
+func (q *{{ .Model.Name }}Query) SkipLocked() *{{ .Model.Name }}Query {
+	q.Query = q.Query.SkipLocked()
+	return q
+}
+
+// NoWait fails the query instead of waiting when a row is locked.
+func (q *{{ .Model.Name }}Query) NoWait() *{{ .Model.Name }}Query {
+	q.Query = q.Query.NoWait()
+	return q
+}
+
 // Delete removes all {{ .Model.Name }} records matching the query conditions.
 // Returns the number of records deleted.
 // WARNING: This is a bulk operation that cannot be undone.
 //
 // Examples:
 //   // Delete all {{ lower .Model.Name }}s (use with caution!)
-//   deleted, err := repo.Query(ctx).Delete()
+//   deleted, err := repo.Query(ctx).AllowFullTable().Delete()
 {{- if $firstBoolField }}
 //   // Delete inactive {{ lower .Model.Name }}s
 //   deleted, err := repo.Query(ctx).Where({{ .Model.Name }}s.{{ sanitizeGoName $firstBoolField }}.Eq(false)).Delete()
@@ -612,6 +901,88 @@ package {{ .Package }}
 // No additional relationship helpers needed - see repository files for With{{ .Model.Name }} methods
 `
 
+// extensionsTemplate generates the companion file for hand-written repository
+// methods. It is written once and never overwritten or cleaned.
+const extensionsTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Extensions for {{ .Model.Name }}Repository.
+//
+// This file was created by storm orm generate-orm for your own code and is yours
+// to edit: regenerating never overwrites or removes it. Methods declared here are
+// available on the generated Storm, e.g. storm.{{ plural .Model.Name }}.FindByEmail(ctx, email):
+//
+//   func (r *{{ .Model.Name }}Repository) FindByEmail(ctx context.Context, email string) (*{{ .Model.Name }}, error) {
+//       return r.Query(ctx).Where({{ .Model.Name }}s.Email.Eq(email)).First()
+//   }
+//
+// It carries the same build tag as the generated code, so that it is excluded
+// along with it.
+
+package {{ .Package }}
+`
+
+// filtersTemplate generates a filter struct per model, for transport layers to bind
+// user input into conditions
+const filtersTemplate = `//go:build !exclude_generated
+// +build !exclude_generated
+
+// Code generated by storm orm generate-orm; DO NOT EDIT.
+//
+// This file was automatically generated from Go struct definitions.
+// Any changes made to this file will be lost when regenerating.
+//
+// Source package: {{ .Package }}
+// Models found: {{ len .Models }}
+// Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
+//
+// To regenerate this file, run:
+//   storm orm generate-orm --package={{ .Package }}
+
+package {{ .Package }}
+
+import (
+	{{- if .HasTimeFields }}
+	"time"
+	{{- end }}
+	storm "github.com/eleven-am/storm/pkg/storm-orm"
+)
+{{ range .Models }}
+{{- $model := .Model }}
+// {{ $model.Name }}Filter holds optional conditions on the columns of {{ $model.Name }}, for
+// HTTP, GraphQL or RPC handlers to decode user input into. Nil fields are ignored;
+// Min and Max fields bound a range, inclusively.
+type {{ $model.Name }}Filter struct {
+	{{- range .Fields }}
+	{{ .Name }} *{{ .Type }} ` + "`json:\"{{ .DBName }},omitempty\"`" + `
+	{{- if .Ranged }}
+	{{ .Name }}Min *{{ .Type }} ` + "`json:\"{{ .DBName }}_min,omitempty\"`" + `
+	{{ .Name }}Max *{{ .Type }} ` + "`json:\"{{ .DBName }}_max,omitempty\"`" + `
+	{{- end }}
+	{{- end }}
+}
+
+// ToConditions returns a condition per field of f that is set, each to pass to Where.
+// An empty filter returns no conditions.
+func (f {{ $model.Name }}Filter) ToConditions() []storm.Condition {
+	var conditions []storm.Condition
+	{{- range .Fields }}
+	if f.{{ .Name }} != nil {
+		conditions = append(conditions, {{ $model.Name }}s.{{ .Name }}.Eq(*f.{{ .Name }}))
+	}
+	{{- if .Ranged }}
+	if f.{{ .Name }}Min != nil {
+		conditions = append(conditions, {{ $model.Name }}s.{{ .Name }}.Gte(*f.{{ .Name }}Min))
+	}
+	if f.{{ .Name }}Max != nil {
+		conditions = append(conditions, {{ $model.Name }}s.{{ .Name }}.Lte(*f.{{ .Name }}Max))
+	}
+	{{- end }}
+	{{- end }}
+	return conditions
+}
+{{ end }}`
+
 // stormTemplate generates the Storm struct with all repositories
 const stormTemplate = `//go:build !exclude_generated
 // +build !exclude_generated
@@ -624,6 +995,7 @@ const stormTemplate = `//go:build !exclude_generated
 // Source package: {{ .Package }}
 // Models found: {{ len .Models }}
 // Generated on: {{ .Now.Format "2006-01-02 15:04:05 MST" }}
+// Generated by: storm {{ .Version }}
 //
 // To regenerate this file, run:
 //   storm orm generate-orm --package={{ .Package }}
@@ -658,6 +1030,10 @@ import (
 //
 // Batch Operations:
 //   - CreateMany(ctx, records) - Insert multiple records in transaction
+//   - CreateManyWithOptions(ctx, records, opts) - Insert in chunks, optionally skipping failed chunks
+//   - FindByIDs(ctx, ids...) - Find records by primary keys, in chunks for large key sets
+//   - DeleteByIDs(ctx, ids...) - Delete records by primary keys, in chunks for large key sets
+//   - DeleteWhere(ctx, conditions...) - Delete records matching all conditions
 //   - BulkUpdate(ctx, records, opts) - Update multiple records with bulk operation
 //   - Upsert(ctx, record, opts) - Insert or update single record on conflict
 //   - UpsertMany(ctx, records, opts) - Insert or update multiple records on conflict
@@ -688,6 +1064,18 @@ func NewStorm(db *sqlx.DB, logger ...storm.QueryLogger) *Storm {
 	return storm
 }
 
+// NewStormWithTx returns a Storm bound to tx, whose WithTransaction calls reuse tx.
+// Tests pass stormtest.BeginTx(t, db) to discard every change when they finish.
+func NewStormWithTx(db *sqlx.DB, tx *sqlx.Tx, logger ...storm.QueryLogger) *Storm {
+	storm := &Storm{
+		Storm: storm.NewStormWithTx(db, tx, logger...),
+	}
+	
+	storm.initializeRepositories()
+	
+	return storm
+}
+
 func (s *Storm) WithTransaction(ctx context.Context, fn func(*Storm) error) error {
 	return s.Storm.WithTransaction(ctx, func(baseStorm *storm.Storm) error {
 		txStorm := &Storm{
@@ -713,6 +1101,7 @@ func (s *Storm) initializeRepositories() {
 	
 	{{range $modelName, $model := .Models}}
 	if baseRepo, err := storm.NewRepositoryWithExecutor[{{ $model.Name }}](executor, {{ $model.Name }}Metadata); err == nil {
+		baseRepo.SetStorm(s.Storm)
 		s.{{ plural $model.Name }} = &{{ $model.Name }}Repository{
 			Repository: baseRepo,
 		}
@@ -121,7 +121,7 @@ func (v *ModelValidator) validateField(typeName string, field reflect.StructFiel
 	}
 
 	dbdefTag := field.Tag.Get("dbdef")
-	if dbdefTag != "" {
+	if dbdefTag != "" && dbdefTag != "-" {
 		if err := v.validateDbdefTag(dbdefTag); err != nil {
 			errors = append(errors, ModelValidationError{
 				Type:    typeName,
@@ -398,7 +398,7 @@ func (v *ModelValidator) validateTableDefinition(table parser.TableDefinition) [
 func (v *ModelValidator) validateTableField(typeName string, field parser.FieldDefinition) []ModelValidationError {
 	var errors []ModelValidationError
 
-	if field.DBDefTag != "" {
+	if field.DBDefTag != "" && !field.IsTransient() {
 		if err := v.validateDbdefTag(field.DBDefTag); err != nil {
 			errors = append(errors, ModelValidationError{
 				Type:    typeName,
@@ -0,0 +1,16 @@
+package parser
+
+import (
+	"fmt"
+	"go/token"
+)
+
+// WithPosition prefixes err with the source position of the struct or field
+// it refers to, so editors can jump straight to the offending definition.
+// Errors without a known position are returned unchanged.
+func WithPosition(pos token.Position, err error) error {
+	if err == nil || !pos.IsValid() {
+		return err
+	}
+	return fmt.Errorf("%s: %w", pos, err)
+}
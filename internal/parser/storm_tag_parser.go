@@ -48,15 +48,28 @@ type ParsedStormTag struct {
 	Counter            string   // Counter cache column
 
 	// Special attributes
-	Column    string // Database column name (replaces db tag for relationships)
-	Ignore    bool   // Exclude from database operations
-	Computed  string // Computed/derived field
-	Immutable bool   // Immutable field (create-only)
+	Column     string // Database column name (replaces db tag for relationships)
+	Ignore     bool   // Exclude from database operations
+	Computed   string // Computed/derived field
+	Immutable  bool   // Immutable field (create-only)
+	Mask       string // How the column is anonymized when data is copied out of production
+	SoftDelete bool   // Deleting a record sets this timestamp instead of removing the row
+
+	// Timestamps the ORM keeps, instead of relying on database defaults
+	AutoCreateTime bool // Create sets this timestamp when the record leaves it zero
+	AutoUpdateTime bool // Create and Update set this timestamp
+
+	// Column storage tuning for large values
+	Storage     string // PostgreSQL storage strategy: plain, main, external or extended
+	Compression string // Compression method of large values: pglz or lz4
 
 	// Table-level attributes (for _ struct{} fields)
-	Table         string   // Table name
-	Indexes       []string // Index definitions
-	UniqueIndexes []string // Unique constraints
+	Table            string   // Table name
+	Indexes          []string // Index definitions
+	UniqueIndexes    []string // Unique constraints
+	Checks           []string // Check constraints, in declaration order
+	MaterializedView bool     // The model reads a materialized view rather than a table
+	DefaultScope     string   // Condition every query of the model is restricted to
 
 	// Raw tag value
 	Raw string
@@ -87,7 +100,7 @@ func (p *StormTagParser) ParseStormTag(tag string, isRelationshipField bool) (*P
 		Validate:       true, // Default for relationships
 	}
 
-	attributes := strings.Split(tag, ";")
+	attributes := SplitTopLevel(tag, ';')
 	for _, attr := range attributes {
 		attr = strings.TrimSpace(attr)
 		if attr == "" {
@@ -135,6 +148,12 @@ func (p *StormTagParser) parseFlagAttribute(flag string, parsed *ParsedStormTag)
 		parsed.Ignore = true
 	case "immutable":
 		parsed.Immutable = true
+	case "soft_delete":
+		parsed.SoftDelete = true
+	case "auto_create_time":
+		parsed.AutoCreateTime = true
+	case "auto_update_time":
+		parsed.AutoUpdateTime = true
 	case "validate":
 		parsed.Validate = true
 	case "no_validate":
@@ -143,6 +162,8 @@ func (p *StormTagParser) parseFlagAttribute(flag string, parsed *ParsedStormTag)
 		parsed.Autosave = true
 	case "no_autosave":
 		parsed.Autosave = false
+	case "materialized_view":
+		parsed.MaterializedView = true
 	default:
 		return fmt.Errorf("unknown flag attribute: %s", flag)
 	}
@@ -163,6 +184,7 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 		parsed.Default = value
 	case "check":
 		parsed.Check = value
+		parsed.Checks = append(parsed.Checks, value)
 	case "foreign_key":
 		parsed.ForeignKey = value
 		parsed.RelationForeignKey = value
@@ -183,6 +205,21 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 		parsed.ArrayType = value
 	case "computed":
 		parsed.Computed = value
+	case "mask":
+		if err := NewTagParser().validateMask(value); err != nil {
+			return fmt.Errorf("invalid mask '%s': %w", value, err)
+		}
+		parsed.Mask = value
+	case "storage":
+		if err := ValidateStorage(value); err != nil {
+			return fmt.Errorf("invalid storage '%s': %w", value, err)
+		}
+		parsed.Storage = value
+	case "compression":
+		if err := ValidateCompression(value); err != nil {
+			return fmt.Errorf("invalid compression '%s': %w", value, err)
+		}
+		parsed.Compression = value
 
 	case "table":
 		parsed.Table = value
@@ -190,6 +227,8 @@ func (p *StormTagParser) parseKeyValueAttribute(key, value string, parsed *Parse
 		parsed.Indexes = append(parsed.Indexes, value)
 	case "unique":
 		parsed.UniqueIndexes = append(parsed.UniqueIndexes, value)
+	case "default_scope":
+		parsed.DefaultScope = value
 
 	case "relation":
 		return p.parseRelationAttribute(value, parsed)
@@ -440,6 +479,24 @@ func (p *ParsedStormTag) ToDBDefAttributes() map[string]string {
 	if p.ArrayType != "" {
 		attrs["array_type"] = p.ArrayType
 	}
+	if p.Mask != "" {
+		attrs["mask"] = p.Mask
+	}
+	if p.SoftDelete {
+		attrs["soft_delete"] = ""
+	}
+	if p.AutoCreateTime {
+		attrs["auto_create_time"] = ""
+	}
+	if p.AutoUpdateTime {
+		attrs["auto_update_time"] = ""
+	}
+	if p.Storage != "" {
+		attrs["storage"] = p.Storage
+	}
+	if p.Compression != "" {
+		attrs["compression"] = p.Compression
+	}
 
 	return attrs
 }
@@ -464,6 +521,15 @@ func (p *ParsedStormTag) ToTableLevelAttributes() map[string]string {
 			attrs["unique"] = unique
 		}
 	}
+	if len(p.Checks) > 0 {
+		attrs["check"] = strings.Join(p.Checks, ";")
+	}
+	if p.MaterializedView {
+		attrs["materialized_view"] = ""
+	}
+	if p.DefaultScope != "" {
+		attrs["default_scope"] = p.DefaultScope
+	}
 
 	return attrs
 }
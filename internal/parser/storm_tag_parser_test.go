@@ -139,6 +139,60 @@ func TestStormTagParser_ToDBDefAttributes(t *testing.T) {
 	}
 }
 
+func TestStormTagParser_Mask(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("type:text;not_null;mask:email", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.Mask != "email" {
+		t.Errorf("expected mask email, got %q", parsed.Mask)
+	}
+	if attrs := parsed.ToDBDefAttributes(); attrs["mask"] != "email" {
+		t.Errorf("expected mask attribute email, got %q", attrs["mask"])
+	}
+}
+
+func TestStormTagParser_Storage(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("type:text;storage:external;compression:lz4", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	attrs := parsed.ToDBDefAttributes()
+	if attrs["storage"] != "external" || attrs["compression"] != "lz4" {
+		t.Errorf("expected storage external and compression lz4, got %q and %q", attrs["storage"], attrs["compression"])
+	}
+}
+
+func TestStormTagParser_SoftDelete(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("type:timestamptz;soft_delete", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := parsed.ToDBDefAttributes()["soft_delete"]; !ok {
+		t.Errorf("expected the soft_delete flag in %v", parsed.ToDBDefAttributes())
+	}
+}
+
+func TestStormTagParser_AutoTimestamps(t *testing.T) {
+	parser := NewStormTagParser()
+
+	for _, flag := range []string{"auto_create_time", "auto_update_time"} {
+		parsed, err := parser.ParseStormTag("type:timestamptz;not_null;"+flag, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := parsed.ToDBDefAttributes()[flag]; !ok {
+			t.Errorf("expected the %s flag in %v", flag, parsed.ToDBDefAttributes())
+		}
+	}
+}
+
 func TestStormTagParser_ValidationErrors(t *testing.T) {
 	parser := NewStormTagParser()
 
@@ -166,6 +220,24 @@ func TestStormTagParser_ValidationErrors(t *testing.T) {
 			isRelationship: true,
 			expectError:    "invalid relationship type",
 		},
+		{
+			name:           "unknown mask strategy",
+			tag:            "type:text;mask:scramble",
+			isRelationship: false,
+			expectError:    "invalid mask 'scramble'",
+		},
+		{
+			name:           "unknown storage strategy",
+			tag:            "type:text;storage:toasted",
+			isRelationship: false,
+			expectError:    "invalid storage 'toasted'",
+		},
+		{
+			name:           "unknown compression method",
+			tag:            "type:bytea;compression:zstd",
+			isRelationship: false,
+			expectError:    "invalid compression 'zstd'",
+		},
 		{
 			name:           "missing join_table for has_many_through",
 			tag:            "relation:has_many_through:Tag;source_fk:user_id;target_fk:tag_id",
@@ -363,3 +435,38 @@ func TestStormTagParser_ToTableLevelAttributes(t *testing.T) {
 		t.Errorf("expected index attribute 'idx_user_id', got '%s'", attrs["index"])
 	}
 }
+
+func TestStormTagParser_TableLevelChecks(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("table:orders;check:ck_dates,start_date < end_date;check:(price > 0 AND discount <= price)", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	attrs := parsed.ToTableLevelAttributes()
+	expected := "ck_dates,start_date < end_date;(price > 0 AND discount <= price)"
+	if attrs["check"] != expected {
+		t.Errorf("expected check attribute %q, got %q", expected, attrs["check"])
+	}
+}
+
+func TestStormTagParser_CheckWithSeparators(t *testing.T) {
+	parser := NewStormTagParser()
+
+	parsed, err := parser.ParseStormTag("type:varchar(20);check:(status IN ('a;b', 'c') OR status IS NULL);not_null", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if parsed.Check != "(status IN ('a;b', 'c') OR status IS NULL)" {
+		t.Errorf("unexpected check expression: %q", parsed.Check)
+	}
+	if !parsed.NotNull {
+		t.Error("expected not_null after check expression")
+	}
+
+	if _, err := parser.ParseStormTag("type:integer;check:(price > 0", false); err == nil {
+		t.Error("expected error for unbalanced check expression")
+	}
+}
@@ -23,6 +23,24 @@ type FieldDefinition struct {
 	JSONTag   string
 	ORMTag    string // Deprecated: use StormTag instead
 	StormTag  string // New unified tag
+	Pos       token.Position
+}
+
+// IsRelationship reports whether the field declares a relationship rather than a column
+func (f FieldDefinition) IsRelationship() bool {
+	return f.ORMTag != "" || strings.Contains(f.StormTag, "relation:")
+}
+
+// IsColumn reports whether the field maps to a database column
+func (f FieldDefinition) IsColumn() bool {
+	return !f.IsTransient() && !f.IsRelationship()
+}
+
+// IsTransient reports whether the field is excluded from persistence with db:"-",
+// dbdef:"-", storm:"-" or storm:"ignore". Relationship fields, which are usually
+// tagged db:"-" too, are not transient.
+func (f FieldDefinition) IsTransient() bool {
+	return f.DBName == "-" && !f.IsRelationship()
 }
 
 // TableDefinition represents a complete table structure
@@ -31,6 +49,32 @@ type TableDefinition struct {
 	TableName  string
 	Fields     []FieldDefinition
 	TableLevel map[string]string
+	TagErrors  []error // Table-level tags that could not be parsed
+	Pos        token.Position
+}
+
+// IsMaterializedView reports whether the model reads a materialized view, declared
+// with the materialized_view table-level attribute, rather than a table of its own
+func (t TableDefinition) IsMaterializedView() bool {
+	_, exists := t.TableLevel["materialized_view"]
+	return exists
+}
+
+// DefaultScope returns the condition every query of the model is restricted to,
+// declared with the default_scope table-level attribute, or "" without one
+func (t TableDefinition) DefaultScope() string {
+	return t.TableLevel["default_scope"]
+}
+
+// SoftDeleteColumn returns the column declared with the soft_delete attribute, which
+// deleting a record sets instead of removing the row, or "" without one
+func (t TableDefinition) SoftDeleteColumn() string {
+	for _, field := range t.Fields {
+		if _, exists := field.DBDef["soft_delete"]; exists && field.IsColumn() {
+			return field.DBName
+		}
+	}
+	return ""
 }
 
 // StructParser handles parsing Go struct definitions
@@ -87,9 +131,10 @@ func (p *StructParser) ParseFile(filename string) ([]TableDefinition, error) {
 			if structType, ok := node.Type.(*ast.StructType); ok {
 				table, err := p.parseStruct(node.Name.Name, structType)
 				if err != nil {
-					fmt.Printf("Warning: failed to parse struct %s: %v\n", node.Name.Name, err)
+					fmt.Printf("Warning: %s: failed to parse struct %s: %v\n", p.fileSet.Position(node.Pos()), node.Name.Name, err)
 					return true
 				}
+				table.Pos = p.fileSet.Position(node.Pos())
 
 				if p.isDatabaseStruct(table) {
 					tables = append(tables, table)
@@ -111,12 +156,13 @@ func (p *StructParser) parseStruct(structName string, structType *ast.StructType
 	}
 
 	for _, field := range structType.Fields.List {
-		fieldDefs, tableLevelAttrs, err := p.parseField(field)
+		fieldDefs, tableLevelAttrs, tagErrors, err := p.parseField(field)
 		if err != nil {
 			return table, fmt.Errorf("failed to parse field: %w", err)
 		}
 
 		table.Fields = append(table.Fields, fieldDefs...)
+		table.TagErrors = append(table.TagErrors, tagErrors...)
 
 		for k, v := range tableLevelAttrs {
 			table.TableLevel[k] = v
@@ -130,33 +176,22 @@ func (p *StructParser) parseStruct(structName string, structType *ast.StructType
 	return table, nil
 }
 
-func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[string]string, error) {
+func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[string]string, []error, error) {
 	var fields []FieldDefinition
+	var tagErrors []error
 	tableLevelAttrs := make(map[string]string)
 
 	if len(field.Names) == 0 {
 		if field.Tag != nil {
-			tagValue := strings.Trim(field.Tag.Value, "`")
-			stormTag := p.extractTag(tagValue, "storm")
-			if stormTag != "" {
-				parsed, err := p.stormTagParser.ParseStormTag(stormTag, false)
-				if err == nil {
-					attrs := parsed.ToTableLevelAttributes()
-					for k, v := range attrs {
-						tableLevelAttrs[k] = v
-					}
-				}
-			} else {
-				dbdefTag := p.extractTag(tagValue, "dbdef")
-				if dbdefTag != "" {
-					attrs := p.tagParser.ParseDBDefTag(dbdefTag)
-					for k, v := range attrs {
-						tableLevelAttrs[k] = v
-					}
-				}
+			attrs, err := p.parseTableLevelTag(strings.Trim(field.Tag.Value, "`"))
+			if err != nil {
+				tagErrors = append(tagErrors, err)
+			}
+			for k, v := range attrs {
+				tableLevelAttrs[k] = v
 			}
 		}
-		return fields, tableLevelAttrs, nil
+		return fields, tableLevelAttrs, tagErrors, nil
 	}
 
 	for _, name := range field.Names {
@@ -165,30 +200,19 @@ func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[stri
 		}
 
 		if name.Name == "_" && field.Tag != nil {
-			tagValue := strings.Trim(field.Tag.Value, "`")
-			stormTag := p.extractTag(tagValue, "storm")
-			if stormTag != "" {
-				parsed, err := p.stormTagParser.ParseStormTag(stormTag, false)
-				if err == nil {
-					attrs := parsed.ToTableLevelAttributes()
-					for k, v := range attrs {
-						tableLevelAttrs[k] = v
-					}
-				}
-			} else {
-				dbdefTag := p.extractTag(tagValue, "dbdef")
-				if dbdefTag != "" {
-					attrs := p.tagParser.ParseDBDefTag(dbdefTag)
-					for k, v := range attrs {
-						tableLevelAttrs[k] = v
-					}
-				}
+			attrs, err := p.parseTableLevelTag(strings.Trim(field.Tag.Value, "`"))
+			if err != nil {
+				tagErrors = append(tagErrors, err)
+			}
+			for k, v := range attrs {
+				tableLevelAttrs[k] = v
 			}
 			continue
 		}
 
 		fieldDef := FieldDefinition{
 			Name: name.Name,
+			Pos:  p.fileSet.Position(name.Pos()),
 		}
 
 		fieldType, isPointer, isArray := p.parseFieldType(field.Type)
@@ -237,10 +261,44 @@ func (p *StructParser) parseField(field *ast.Field) ([]FieldDefinition, map[stri
 			fieldDef.DBDef = make(map[string]string)
 		}
 
+		if p.excludesField(fieldDef) {
+			fieldDef.DBName = "-"
+			fieldDef.DBDef = make(map[string]string)
+		}
+
 		fields = append(fields, fieldDef)
 	}
 
-	return fields, tableLevelAttrs, nil
+	return fields, tableLevelAttrs, tagErrors, nil
+}
+
+// excludesField reports whether the tags of field keep it out of the database
+func (p *StructParser) excludesField(field FieldDefinition) bool {
+	switch {
+	case field.DBTag == "-", field.DBDefTag == "-", field.StormTag == "-":
+		return true
+	case field.StormTag != "":
+		parsed, err := p.stormTagParser.ParseStormTag(field.StormTag, field.IsArray || field.IsPointer)
+		return err == nil && parsed.Ignore
+	}
+	return false
+}
+
+// parseTableLevelTag parses the storm or dbdef tag of an embedded or blank field into table-level attributes
+func (p *StructParser) parseTableLevelTag(tagValue string) (map[string]string, error) {
+	if stormTag := p.extractTag(tagValue, "storm"); stormTag != "" {
+		parsed, err := p.stormTagParser.ParseStormTag(stormTag, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid table-level storm tag: %w", err)
+		}
+		return parsed.ToTableLevelAttributes(), nil
+	}
+
+	if dbdefTag := p.extractTag(tagValue, "dbdef"); dbdefTag != "" {
+		return p.tagParser.ParseDBDefTag(dbdefTag), nil
+	}
+
+	return nil, nil
 }
 
 func (p *StructParser) parseFieldType(expr ast.Expr) (string, bool, bool) {
@@ -229,6 +229,105 @@ type Team struct {
 	}
 }
 
+func TestStructParser_Positions(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "positions.go")
+
+	testCode := `package models
+
+type User struct {
+	ID    string ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+	Email string ` + "`" + `db:"email" dbdef:"type:text"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	if len(tables) != 1 {
+		t.Fatalf("Expected 1 table, got %d", len(tables))
+	}
+
+	table := tables[0]
+	if table.Pos.Filename != testFile || table.Pos.Line != 3 {
+		t.Errorf("Expected table position %s:3, got %s", testFile, table.Pos)
+	}
+
+	emailField := findField(table.Fields, "Email")
+	if emailField == nil {
+		t.Fatal("Email field not found")
+	}
+
+	if emailField.Pos.Line != 5 || emailField.Pos.Column != 2 {
+		t.Errorf("Expected Email field at line 5, column 2, got %s", emailField.Pos)
+	}
+}
+
+func TestStructParser_TransientFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "transient.go")
+
+	testCode := `package models
+
+type User struct {
+	ID       string ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+	Score    int    ` + "`" + `db:"-"` + "`" + `
+	Cached   string ` + "`" + `dbdef:"-"` + "`" + `
+	Display  string ` + "`" + `storm:"-"` + "`" + `
+	Computed bool   ` + "`" + `storm:"ignore"` + "`" + `
+	Posts    []Post ` + "`" + `db:"-" storm:"relation:has_many:Post;foreign_key:user_id"` + "`" + `
+}
+
+type Post struct {
+	ID     string ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+	UserID string ` + "`" + `db:"user_id" dbdef:"type:uuid"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	var user *TableDefinition
+	for i := range tables {
+		if tables[i].StructName == "User" {
+			user = &tables[i]
+		}
+	}
+	if user == nil {
+		t.Fatal("User table not found")
+	}
+
+	for _, name := range []string{"Score", "Cached", "Display", "Computed"} {
+		field := findField(user.Fields, name)
+		if field == nil {
+			t.Fatalf("%s field not found", name)
+		}
+		if !field.IsTransient() || field.IsColumn() {
+			t.Errorf("Expected %s to be transient, got DBName %q", name, field.DBName)
+		}
+	}
+
+	posts := findField(user.Fields, "Posts")
+	if posts == nil {
+		t.Fatal("Posts field not found")
+	}
+	if posts.IsTransient() {
+		t.Error("Expected the Posts relationship not to be transient")
+	}
+}
+
 func findField(fields []FieldDefinition, name string) *FieldDefinition {
 	for _, f := range fields {
 		if f.Name == name {
@@ -237,3 +336,82 @@ func findField(fields []FieldDefinition, name string) *FieldDefinition {
 	}
 	return nil
 }
+
+func TestStructParser_MaterializedView(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "views.go")
+
+	testCode := `package models
+
+type DailySales struct {
+	_   struct{} ` + "`" + `storm:"table:daily_sales;materialized_view"` + "`" + `
+	Day string   ` + "`" + `db:"day" dbdef:"type:date;primary_key"` + "`" + `
+}
+
+type TopUsers struct {
+	_  struct{} ` + "`" + `dbdef:"table:top_users;materialized_view"` + "`" + `
+	ID string   ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+}
+
+type User struct {
+	_  struct{} ` + "`" + `dbdef:"table:users"` + "`" + `
+	ID string   ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string]bool{"DailySales": true, "TopUsers": true, "User": false}
+	for _, table := range tables {
+		if len(table.TagErrors) > 0 {
+			t.Errorf("%s: unexpected tag errors %v", table.StructName, table.TagErrors)
+		}
+		if table.IsMaterializedView() != expected[table.StructName] {
+			t.Errorf("%s: expected IsMaterializedView() to be %v", table.StructName, expected[table.StructName])
+		}
+	}
+}
+
+func TestStructParser_DefaultScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "posts.go")
+
+	testCode := `package models
+
+type Post struct {
+	_  struct{} ` + "`" + `storm:"table:posts;default_scope:deleted_at IS NULL"` + "`" + `
+	ID string   ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+}
+
+type Comment struct {
+	_  struct{} ` + "`" + `dbdef:"table:comments;default_scope:hidden = false"` + "`" + `
+	ID string   ` + "`" + `db:"id" dbdef:"type:uuid;primary_key"` + "`" + `
+}
+`
+
+	if err := os.WriteFile(testFile, []byte(testCode), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	tables, err := NewStructParser().ParseFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to parse file: %v", err)
+	}
+
+	expected := map[string]string{"Post": "deleted_at IS NULL", "Comment": "hidden = false"}
+	for _, table := range tables {
+		if len(table.TagErrors) > 0 {
+			t.Errorf("%s: unexpected tag errors %v", table.StructName, table.TagErrors)
+		}
+		if table.DefaultScope() != expected[table.StructName] {
+			t.Errorf("%s: expected DefaultScope() %q, got %q", table.StructName, expected[table.StructName], table.DefaultScope())
+		}
+	}
+}
@@ -5,6 +5,32 @@ import (
 	"strings"
 )
 
+// knownFieldAttributes lists the field-level dbdef attributes understood by the schema generator
+var knownFieldAttributes = map[string]bool{
+	"type": true, "default": true, "check": true, "prev": true, "enum": true,
+	"fk": true, "foreign_key": true, "on_delete": true, "on_update": true, "constraint": true,
+	"primary_key": true, "not_null": true, "unique": true, "auto_increment": true,
+	"array": true, "array_type": true, "dimensions": true, "mask": true,
+	"storage": true, "compression": true, "soft_delete": true,
+	"auto_create_time": true, "auto_update_time": true,
+}
+
+// knownTableLevelAttributes lists the table-level dbdef attributes understood by the schema generator
+var knownTableLevelAttributes = map[string]bool{
+	"table": true, "index": true, "unique": true, "check": true, "on_delete": true, "on_update": true,
+	"materialized_view": true, "default_scope": true,
+}
+
+// IsKnownFieldAttribute reports whether key is a recognised field-level dbdef attribute
+func IsKnownFieldAttribute(key string) bool {
+	return knownFieldAttributes[key]
+}
+
+// IsKnownTableLevelAttribute reports whether key is a recognised table-level dbdef attribute
+func IsKnownTableLevelAttribute(key string) bool {
+	return knownTableLevelAttributes[key]
+}
+
 // TagParser handles parsing of dbdef struct tags
 type TagParser struct{}
 
@@ -19,7 +45,7 @@ func (p *TagParser) ParseDBDefTag(tagValue string) map[string]string {
 		return attributes
 	}
 
-	parts := strings.Split(tagValue, ";")
+	parts := SplitTopLevel(tagValue, ';')
 
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
@@ -76,7 +102,7 @@ func (p *TagParser) ValidateDBDefTag(tagValue string) error {
 			if err := p.validatePrev(value); err != nil {
 				return fmt.Errorf("invalid prev hint '%s': %w", value, err)
 			}
-		case "primary_key", "not_null", "unique", "auto_increment":
+		case "primary_key", "not_null", "unique", "auto_increment", "soft_delete", "auto_create_time", "auto_update_time":
 			if value != "" {
 				return fmt.Errorf("flag attribute '%s' should not have a value", key)
 			}
@@ -92,6 +118,18 @@ func (p *TagParser) ValidateDBDefTag(tagValue string) error {
 			if err := p.validateArrayType(value); err != nil {
 				return fmt.Errorf("invalid array type '%s': %w", value, err)
 			}
+		case "mask":
+			if err := p.validateMask(value); err != nil {
+				return fmt.Errorf("invalid mask '%s': %w", value, err)
+			}
+		case "storage":
+			if err := ValidateStorage(value); err != nil {
+				return fmt.Errorf("invalid storage '%s': %w", value, err)
+			}
+		case "compression":
+			if err := ValidateCompression(value); err != nil {
+				return fmt.Errorf("invalid compression '%s': %w", value, err)
+			}
 		default:
 			fmt.Printf("Warning: unknown dbdef attribute '%s'\n", key)
 		}
@@ -122,9 +160,11 @@ func (p *TagParser) validateType(typeValue string) error {
 
 		"json": true, "jsonb": true,
 
-		"uuid":  true,
-		"cuid":  true,
-		"cuid2": true,
+		"uuid":   true,
+		"cuid":   true,
+		"cuid2":  true,
+		"ulid":   true,
+		"nanoid": true,
 
 		"text[]": true, "integer[]": true, "uuid[]": true,
 
@@ -206,6 +246,10 @@ func (p *TagParser) validateCheck(checkValue string) error {
 		return fmt.Errorf("check constraint cannot be empty")
 	}
 
+	if err := checkBalanced(checkValue); err != nil {
+		return err
+	}
+
 	checkLower := strings.ToLower(checkValue)
 
 	if strings.Contains(checkLower, "jsonb_typeof") {
@@ -282,6 +326,46 @@ func (p *TagParser) validateOnDeleteUpdate(action string) error {
 	return fmt.Errorf("must be one of: CASCADE, SET NULL, SET DEFAULT, RESTRICT, NO ACTION")
 }
 
+// validMaskStrategies lists the values of the mask attribute
+var validMaskStrategies = []string{"email", "name", "redact", "hash"}
+
+func (p *TagParser) validateMask(strategy string) error {
+	for _, valid := range validMaskStrategies {
+		if strategy == valid {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be one of: %s", strings.Join(validMaskStrategies, ", "))
+}
+
+// validStorageModes lists the values of the storage attribute, PostgreSQL's column
+// storage strategies
+var validStorageModes = []string{"plain", "main", "external", "extended"}
+
+// validCompressionMethods lists the values of the compression attribute
+var validCompressionMethods = []string{"pglz", "lz4"}
+
+// ValidateStorage checks the value of the storage attribute
+func ValidateStorage(mode string) error {
+	for _, valid := range validStorageModes {
+		if strings.EqualFold(mode, valid) {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(validStorageModes, ", "))
+}
+
+// ValidateCompression checks the value of the compression attribute
+func ValidateCompression(method string) error {
+	for _, valid := range validCompressionMethods {
+		if strings.EqualFold(method, valid) {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of: %s", strings.Join(validCompressionMethods, ", "))
+}
+
 func (p *TagParser) validateEnum(enumValue string) error {
 	if enumValue == "" {
 		return fmt.Errorf("enum values cannot be empty")
@@ -439,3 +523,93 @@ func (p *TagParser) GetPrevName(attributes map[string]string) string {
 	}
 	return ""
 }
+
+// SplitTopLevel splits value on sep, ignoring separators that appear inside
+// parentheses or single-quoted SQL literals. This lets expressions such as
+// check:(price > 0 AND status IN ('a', 'b')) carry commas and semicolons.
+// A doubled single quote inside a literal is treated as an escaped quote.
+func SplitTopLevel(value string, sep rune) []string {
+	var parts []string
+	depth := 0
+	inQuote := false
+	start := 0
+
+	for i, r := range value {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case r == sep && depth == 0:
+			parts = append(parts, value[start:i])
+			start = i + len(string(r))
+		}
+	}
+
+	return append(parts, value[start:])
+}
+
+// TrimEnclosingParens removes a single pair of parentheses wrapping the whole expression,
+// so "(a > 0 AND b > 0)" becomes "a > 0 AND b > 0" while "(a) OR (b)" is left untouched
+func TrimEnclosingParens(expr string) string {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return expr
+	}
+
+	depth := 0
+	inQuote := false
+	for i, r := range expr {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 && i != len(expr)-1 {
+				return expr
+			}
+		}
+	}
+
+	if depth != 0 || inQuote {
+		return expr
+	}
+
+	return strings.TrimSpace(expr[1 : len(expr)-1])
+}
+
+// checkBalanced reports unbalanced parentheses or unterminated string literals in a SQL expression
+func checkBalanced(expr string) error {
+	depth := 0
+	inQuote := false
+	for _, r := range expr {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+		}
+	}
+
+	if inQuote {
+		return fmt.Errorf("unterminated string literal")
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
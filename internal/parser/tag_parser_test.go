@@ -111,6 +111,24 @@ func TestTagParser_ParseDBDefTag(t *testing.T) {
 				"default": "CURRENT_TIMESTAMP",
 			},
 		},
+		{
+			name: "parenthesised check keeps separators",
+			tag:  "type:numeric(10,2);check:(price > 0 AND (discount <= price; discount >= 0));not_null",
+			expected: map[string]string{
+				"type":     "numeric(10,2)",
+				"check":    "(price > 0 AND (discount <= price; discount >= 0))",
+				"not_null": "",
+			},
+		},
+		{
+			name: "quoted literal keeps separators",
+			tag:  "type:text;default:'a;b';check:status <> 'it''s;done'",
+			expected: map[string]string{
+				"type":    "text",
+				"default": "'a;b'",
+				"check":   "status <> 'it''s;done'",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -386,3 +404,52 @@ func TestTagParser_GetPrevName(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitTopLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		sep      rune
+		expected []string
+	}{
+		{"plain", "a;b;c", ';', []string{"a", "b", "c"}},
+		{"parentheses", "ck,(a, b);x", ';', []string{"ck,(a, b)", "x"}},
+		{"nested parentheses", "f(g(a, b), c),d", ',', []string{"f(g(a, b), c)", "d"}},
+		{"quoted", "'a,b',c", ',', []string{"'a,b'", "c"}},
+		{"escaped quote", "'it''s,ok',c", ',', []string{"'it''s,ok'", "c"}},
+		{"stray closing paren", "a),b", ',', []string{"a)", "b"}},
+		{"empty", "", ';', []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := SplitTopLevel(tt.value, tt.sep)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitTopLevel(%q) = %q, want %q", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTrimEnclosingParens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"(a > 0 AND b > 0)", "a > 0 AND b > 0"},
+		{" ( a > 0 ) ", "a > 0"},
+		{"(a > 0) OR (b > 0)", "(a > 0) OR (b > 0)"},
+		{"((a > 0))", "(a > 0)"},
+		{"(a = ')')", "a = ')'"},
+		{"a > 0", "a > 0"},
+		{"(a > 0", "(a > 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := TrimEnclosingParens(tt.input); result != tt.expected {
+				t.Errorf("TrimEnclosingParens(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@ package storm
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -51,12 +55,12 @@ func (m *MigratorImpl) Generate(ctx context.Context, opts storm.MigrateOptions)
 		return nil, fmt.Errorf("failed to get current schema: %w", err)
 	}
 
-	desiredSchema, err := m.getDesiredSchema(opts.PackagePath)
+	desiredSchema, err := m.getDesiredSchema(opts.PackagePath, opts.Strict)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get desired schema: %w", err)
 	}
 
-	migration, err := m.generateMigration(currentSchema, desiredSchema, opts.CreateDBIfNotExists)
+	migration, err := m.generateMigration(currentSchema, desiredSchema, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate migration: %w", err)
 	}
@@ -73,7 +77,7 @@ func (m *MigratorImpl) Apply(ctx context.Context, migration *storm.Migration) er
 		return fmt.Errorf("failed to create migrations table: %w", err)
 	}
 
-	applied, err := m.isMigrationApplied(ctx, migration.Name)
+	applied, err := m.isMigrationApplied(ctx, migrationVersion(migration))
 	if err != nil {
 		return fmt.Errorf("failed to check migration status: %w", err)
 	}
@@ -83,29 +87,42 @@ func (m *MigratorImpl) Apply(ctx context.Context, migration *storm.Migration) er
 		return nil
 	}
 
-	tx, err := m.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	var rollback = func() { tx.Rollback() }
-	defer func() {
-		if rollback != nil {
-			rollback()
-		}
-	}()
-
-	if err := m.executeMigration(ctx, tx, migration); err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	if err := migrator.CheckServerFeatures(ctx, m.db.DB, migration.UpSQL); err != nil {
+		return fmt.Errorf("cannot apply migration %s: %w", migration.Name, err)
 	}
 
-	if err := m.recordMigration(ctx, tx, migration); err != nil {
-		return fmt.Errorf("failed to record migration: %w", err)
+	if migrator.RunsInPhases(migration.UpSQL) {
+		m.logger.Warn("Migration builds indexes concurrently or validates constraints, running it in several transactions", "name", migration.Name)
+		err := m.applyInPhases(ctx, migration.Name, "migration", "statement", m.upStatements(migration),
+			func(exec sqlx.ExecerContext) error {
+				if err := m.recordMigration(ctx, exec, migration); err != nil {
+					return fmt.Errorf("failed to record migration: %w", err)
+				}
+				return nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to execute migration: %w", err)
+		}
+		m.logger.Info("Migration applied successfully", "name", migration.Name)
+		return nil
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit migration: %w", err)
+	// A retry runs the whole transaction again, so that the locks it already
+	// holds are released while it waits
+	err = m.lockRetry(migration.Name).Do(ctx, func() error {
+		return m.inTransaction(ctx, "migration", func(tx *sqlx.Tx) error {
+			if err := m.executeMigration(ctx, tx, migration); err != nil {
+				return fmt.Errorf("failed to execute migration: %w", err)
+			}
+			if err := m.recordMigration(ctx, tx, migration); err != nil {
+				return fmt.Errorf("failed to record migration: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
 	}
-	rollback = nil
 
 	m.logger.Info("Migration applied successfully", "name", migration.Name)
 	return nil
@@ -114,7 +131,7 @@ func (m *MigratorImpl) Apply(ctx context.Context, migration *storm.Migration) er
 func (m *MigratorImpl) Rollback(ctx context.Context, migration *storm.Migration) error {
 	m.logger.Info("Rolling back migration...", "name", migration.Name)
 
-	applied, err := m.isMigrationApplied(ctx, migration.Name)
+	applied, err := m.isMigrationApplied(ctx, migrationVersion(migration))
 	if err != nil {
 		return fmt.Errorf("failed to check migration status: %w", err)
 	}
@@ -124,29 +141,40 @@ func (m *MigratorImpl) Rollback(ctx context.Context, migration *storm.Migration)
 		return nil
 	}
 
-	tx, err := m.db.BeginTxx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	var rollback = func() { tx.Rollback() }
-	defer func() {
-		if rollback != nil {
-			rollback()
-		}
-	}()
-
-	if err := m.executeRollback(ctx, tx, migration); err != nil {
-		return fmt.Errorf("failed to execute rollback: %w", err)
+	if err := migrator.CheckServerFeatures(ctx, m.db.DB, migration.DownSQL); err != nil {
+		return fmt.Errorf("cannot roll back migration %s: %w", migration.Name, err)
 	}
 
-	if err := m.removeMigrationRecord(ctx, tx, migration); err != nil {
-		return fmt.Errorf("failed to remove migration record: %w", err)
+	if migrator.RunsInPhases(migration.DownSQL) {
+		m.logger.Warn("Rollback builds indexes concurrently or validates constraints, running it in several transactions", "name", migration.Name)
+		err := m.applyInPhases(ctx, migration.Name, "rollback", "rollback statement", migrator.SplitStatements(migration.DownSQL),
+			func(exec sqlx.ExecerContext) error {
+				if err := m.removeMigrationRecord(ctx, exec, migration); err != nil {
+					return fmt.Errorf("failed to remove migration record: %w", err)
+				}
+				return nil
+			})
+		if err != nil {
+			return fmt.Errorf("failed to execute rollback: %w", err)
+		}
+		m.logger.Info("Migration rolled back successfully", "name", migration.Name)
+		return nil
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit rollback: %w", err)
+	err = m.lockRetry(migration.Name).Do(ctx, func() error {
+		return m.inTransaction(ctx, "rollback", func(tx *sqlx.Tx) error {
+			if err := m.executeRollback(ctx, tx, migration); err != nil {
+				return fmt.Errorf("failed to execute rollback: %w", err)
+			}
+			if err := m.removeMigrationRecord(ctx, tx, migration); err != nil {
+				return fmt.Errorf("failed to remove migration record: %w", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
 	}
-	rollback = nil
 
 	m.logger.Info("Migration rolled back successfully", "name", migration.Name)
 	return nil
@@ -167,12 +195,15 @@ func (m *MigratorImpl) Status(ctx context.Context) (*storm.MigrationStatus, erro
 		return nil, fmt.Errorf("failed to get pending migrations: %w", err)
 	}
 
-	return &storm.MigrationStatus{
+	status := &storm.MigrationStatus{
 		Applied:   len(applied),
 		Pending:   len(pending),
 		Available: len(applied) + len(pending),
-		Current:   "",
-	}, nil
+	}
+	if len(applied) > 0 {
+		status.Current = applied[len(applied)-1]
+	}
+	return status, nil
 }
 
 func (m *MigratorImpl) History(ctx context.Context) ([]*storm.MigrationRecord, error) {
@@ -181,9 +212,9 @@ func (m *MigratorImpl) History(ctx context.Context) ([]*storm.MigrationRecord, e
 	}
 
 	query := fmt.Sprintf(`
-		SELECT name, applied_at, checksum
+		SELECT version, applied_at, checksum
 		FROM %s
-		ORDER BY applied_at DESC
+		ORDER BY version DESC
 	`, m.config.MigrationsTable)
 
 	rows, err := m.db.QueryContext(ctx, query)
@@ -212,22 +243,131 @@ func (m *MigratorImpl) Pending(ctx context.Context) ([]*storm.Migration, error)
 	return m.getPendingMigrations(ctx)
 }
 
+func (m *MigratorImpl) Up(ctx context.Context) ([]*storm.Migration, error) {
+	unlock, err := m.lockMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	pending, err := m.getPendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]*storm.Migration, 0, len(pending))
+	for _, migration := range pending {
+		if err := m.Apply(ctx, migration); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", migration.Version, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+func (m *MigratorImpl) Down(ctx context.Context, steps int) ([]*storm.Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+	unlock, err := m.lockMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if err := m.createMigrationsTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied, err := m.getAppliedMigrations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	if steps > len(applied) {
+		steps = len(applied)
+	}
+
+	// Load every migration first, so a missing down file stops the rollback
+	// before it changes anything
+	var migrations []*storm.Migration
+	for i := len(applied) - 1; i >= len(applied)-steps; i-- {
+		upFile := filepath.Join(m.config.MigrationsDir, applied[i]+".up.sql")
+		migration, err := m.loadMigration(upFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load migration %s: %w", applied[i], err)
+		}
+		if migration.DownSQL == "" {
+			return nil, fmt.Errorf("migration %s has no %s.down.sql in %s", applied[i], applied[i], m.config.MigrationsDir)
+		}
+		migrations = append(migrations, migration)
+	}
+
+	rolledBack := make([]*storm.Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if err := m.Rollback(ctx, migration); err != nil {
+			return rolledBack, fmt.Errorf("migration %s: %w", migration.Version, err)
+		}
+		rolledBack = append(rolledBack, migration)
+	}
+	return rolledBack, nil
+}
+
 func (m *MigratorImpl) createMigrationsTable(ctx context.Context) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
-			name VARCHAR(255) PRIMARY KEY,
-			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			version VARCHAR(255) PRIMARY KEY,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			checksum VARCHAR(64) NOT NULL
 		)
 	`, m.config.MigrationsTable)
 
-	_, err := m.db.ExecContext(ctx, query)
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	// Tables created before migrations were keyed by version called the column name
+	if strings.HasPrefix(m.db.DriverName(), "sqlite") {
+		return m.upgradeSQLiteMigrationsTable(ctx)
+	}
+	upgrade := fmt.Sprintf(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = '%[1]s' AND column_name = 'name')
+			   AND NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = '%[1]s' AND column_name = 'version') THEN
+				ALTER TABLE %[1]s RENAME COLUMN name TO version;
+			END IF;
+		END $$
+	`, m.config.MigrationsTable)
+
+	_, err := m.db.ExecContext(ctx, upgrade)
+	return err
+}
+
+// upgradeSQLiteMigrationsTable renames the name column of a migrations table
+// created by an earlier release, reading the columns from PRAGMA table_info since
+// SQLite has no DO blocks
+func (m *MigratorImpl) upgradeSQLiteMigrationsTable(ctx context.Context) error {
+	var columns []string
+	if err := m.db.SelectContext(ctx, &columns, "SELECT name FROM pragma_table_info(?)", m.config.MigrationsTable); err != nil {
+		return err
+	}
+
+	hasName, hasVersion := false, false
+	for _, column := range columns {
+		hasName = hasName || column == "name"
+		hasVersion = hasVersion || column == "version"
+	}
+	if !hasName || hasVersion {
+		return nil
+	}
+
+	_, err := m.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN name TO version", m.config.MigrationsTable))
 	return err
 }
 
 func (m *MigratorImpl) isMigrationApplied(ctx context.Context, name string) (bool, error) {
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) FROM %s WHERE name = $1
+		SELECT COUNT(*) FROM %s WHERE version = $1
 	`, m.config.MigrationsTable)
 
 	var count int
@@ -237,7 +377,7 @@ func (m *MigratorImpl) isMigrationApplied(ctx context.Context, name string) (boo
 
 func (m *MigratorImpl) getAppliedMigrations(ctx context.Context) ([]string, error) {
 	query := fmt.Sprintf(`
-		SELECT name FROM %s ORDER BY applied_at
+		SELECT version FROM %s ORDER BY version
 	`, m.config.MigrationsTable)
 
 	var names []string
@@ -304,6 +444,7 @@ func (m *MigratorImpl) loadMigration(upFile string) (*storm.Migration, error) {
 
 	return &storm.Migration{
 		Name:      name,
+		Version:   name,
 		UpSQL:     string(upContent),
 		DownSQL:   downContent,
 		Checksum:  m.calculateChecksum(string(upContent)),
@@ -311,140 +452,240 @@ func (m *MigratorImpl) loadMigration(upFile string) (*storm.Migration, error) {
 	}, nil
 }
 
-func (m *MigratorImpl) executeMigration(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
-	if migration.UpSQL == "" {
+// sessionSettings returns the lock_timeout, statement_timeout and
+// application_name migrations run with
+func (m *MigratorImpl) sessionSettings() []migrator.SessionSetting {
+	if m.config == nil {
 		return nil
 	}
+	settings := migrator.DBConfig{
+		LockTimeout:      m.config.MigrationLockTimeout,
+		StatementTimeout: m.config.MigrationStatementTimeout,
+		ApplicationName:  m.config.MigrationApplicationName,
+	}
+	return settings.SessionSettings()
+}
 
-	statements := m.splitSQLStatements(migration.UpSQL)
-	for _, stmt := range statements {
-		stmt = strings.TrimSpace(stmt)
-		if stmt == "" {
-			continue
-		}
-
-		// Skip CREATE DATABASE statements when applying migrations
-		// These are only for push mode or manual execution
-		if strings.Contains(strings.ToUpper(stmt), "CREATE DATABASE") {
-			m.logger.Info("Skipping CREATE DATABASE statement in migration apply")
-			continue
+// setSessionSettings applies the migration session settings; local limits them
+// to the current transaction
+func (m *MigratorImpl) setSessionSettings(ctx context.Context, exec sqlx.ExecerContext, local bool) error {
+	for _, setting := range m.sessionSettings() {
+		if _, err := exec.ExecContext(ctx, "SELECT set_config($1, $2, $3)", setting.Name, setting.Value, local); err != nil {
+			return fmt.Errorf("failed to set %s: %w", setting.Name, err)
 		}
+	}
+	return nil
+}
 
-		if _, err := tx.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("failed to execute statement: %s: %w", stmt, err)
-		}
+// inTransaction runs fn in a transaction with the session settings and commits it
+func (m *MigratorImpl) inTransaction(ctx context.Context, what string, fn func(tx *sqlx.Tx) error) error {
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
+	if err := m.setSessionSettings(ctx, tx, true); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", what, err)
+	}
 	return nil
 }
 
-// splitSQLStatements properly splits PostgreSQL statements, handling dollar-quoted strings
-func (m *MigratorImpl) splitSQLStatements(sql string) []string {
-	var statements []string
-	var current strings.Builder
-	inDollarQuote := false
-
-	runes := []rune(sql)
-	i := 0
-
-	for i < len(runes) {
-		char := runes[i]
-
-		// Check for dollar quotes
-		if char == '$' && i+1 < len(runes) && runes[i+1] == '$' {
-			if !inDollarQuote {
-				// Starting dollar quote
-				inDollarQuote = true
-				current.WriteRune(char)
-				current.WriteRune(runes[i+1])
-				i += 2
-				continue
-			} else {
-				// Ending dollar quote
-				inDollarQuote = false
-				current.WriteRune(char)
-				current.WriteRune(runes[i+1])
-				i += 2
-				continue
-			}
-		}
+// lockRetry retries a migration that hit lock_timeout for MigrationLockRetry
+func (m *MigratorImpl) lockRetry(name string) migrator.LockRetry {
+	if m.config == nil {
+		return migrator.LockRetry{}
+	}
+	return migrator.LockRetry{
+		Period: m.config.MigrationLockRetry,
+		OnRetry: func(attempt int, wait time.Duration, err error) {
+			m.logger.Warn("Migration could not get its locks, retrying", "name", name, "attempt", attempt, "wait", wait, "error", err)
+		},
+	}
+}
 
-		// Check for statement terminator
-		if !inDollarQuote && char == ';' {
-			current.WriteRune(char)
-			stmt := strings.TrimSpace(current.String())
-			// Only add non-empty statements that aren't just comments
-			if stmt != "" && !isOnlyComments(stmt) {
-				statements = append(statements, stmt)
-			}
-			current.Reset()
-			i++
-			continue
-		}
+// lockMigrations takes the migration lock of a PostgreSQL database, so that no
+// push or plan apply changes the schema while migrations run, see
+// migrator.LockMigrations. SQLite allows a single writer anyway.
+func (m *MigratorImpl) lockMigrations(ctx context.Context) (func(), error) {
+	if strings.HasPrefix(m.db.DriverName(), "sqlite") {
+		return func() {}, nil
+	}
+	return migrator.LockMigrations(ctx, m.db.DB)
+}
+
+// retrying retries each statement run through exec that hits lock_timeout, for
+// migrations outside a transaction, where a statement can be retried on its own,
+// except CREATE INDEX CONCURRENTLY, see migrator.LockRetry.DoStatement
+func (m *MigratorImpl) retrying(exec sqlx.ExecerContext, name string) sqlx.ExecerContext {
+	return retryingExecer{exec: exec, retry: m.lockRetry(name)}
+}
+
+type retryingExecer struct {
+	exec  sqlx.ExecerContext
+	retry migrator.LockRetry
+}
 
-		current.WriteRune(char)
-		i++
+func (e retryingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := e.retry.DoStatement(ctx, query, func() error {
+		var err error
+		result, err = e.exec.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// sessionConn takes a connection out of the pool for a migration that cannot
+// run in a transaction and applies the session settings to it. release resets
+// them before the connection goes back, or discards it if that fails.
+func (m *MigratorImpl) sessionConn(ctx context.Context) (*sqlx.Conn, func(), error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get connection: %w", err)
 	}
 
-	// Add any remaining content
-	if current.Len() > 0 {
-		stmt := strings.TrimSpace(current.String())
-		if stmt != "" && !isOnlyComments(stmt) {
-			statements = append(statements, stmt)
+	release := func() {
+		for _, setting := range m.sessionSettings() {
+			if _, err := conn.ExecContext(context.Background(), "RESET "+setting.Name); err != nil {
+				_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+				break
+			}
 		}
+		conn.Close()
 	}
 
-	return statements
+	if err := m.setSessionSettings(ctx, conn, false); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return conn, release, nil
 }
 
-// isOnlyComments checks if a statement contains only comments and whitespace
-func isOnlyComments(stmt string) bool {
-	lines := strings.Split(stmt, "\n")
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
-			return false
+// applyInPhases runs the statements of a migration that builds indexes concurrently or
+// validates constraints, which get transactions of their own. The statements around each CREATE or DROP INDEX CONCURRENTLY keep running in
+// transactions of their own, see migrator.TransactionPhases, while the concurrent ones
+// run on a session connection where each is retried on its own. finish records the
+// outcome in the last transaction, which is added after a concurrent statement ending
+// the migration. A failed phase leaves the phases before it applied.
+func (m *MigratorImpl) applyInPhases(ctx context.Context, name, what, kind string, statements []string, finish func(exec sqlx.ExecerContext) error) error {
+	phases := migrator.TransactionPhases(statements)
+	if len(phases) == 0 || !phases[len(phases)-1].Transactional {
+		phases = append(phases, migrator.MigrationPhase{Transactional: true})
+	}
+
+	var conn *sqlx.Conn
+	for i, phase := range phases {
+		last := i == len(phases)-1
+
+		var err error
+		if phase.Transactional {
+			// A retry runs the whole phase again, releasing the locks it holds while it waits
+			err = m.lockRetry(name).Do(ctx, func() error {
+				return m.inTransaction(ctx, what, func(tx *sqlx.Tx) error {
+					if err := m.executeStatements(ctx, tx, phase.Statements, kind); err != nil {
+						return err
+					}
+					if last {
+						return finish(tx)
+					}
+					return nil
+				})
+			})
+		} else {
+			if conn == nil {
+				var release func()
+				if conn, release, err = m.sessionConn(ctx); err != nil {
+					return err
+				}
+				defer release()
+			}
+			err = m.executeStatements(ctx, m.retrying(conn, name), phase.Statements, kind)
+		}
+		if err != nil {
+			return fmt.Errorf("phase %d of %d: %w", i+1, len(phases), err)
 		}
 	}
-	return true
+	return nil
 }
 
-func (m *MigratorImpl) executeRollback(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
-	if migration.DownSQL == "" {
-		return fmt.Errorf("no rollback script available for migration %s", migration.Name)
+func (m *MigratorImpl) executeMigration(ctx context.Context, tx sqlx.ExecerContext, migration *storm.Migration) error {
+	return m.executeStatements(ctx, tx, m.upStatements(migration), "statement")
+}
+
+// upStatements returns the statements migration applies. CREATE DATABASE statements
+// are left out, as they are only for push mode or manual execution.
+func (m *MigratorImpl) upStatements(migration *storm.Migration) []string {
+	if migration.UpSQL == "" {
+		return nil
+	}
+
+	var statements []string
+	for _, stmt := range migrator.SplitStatements(migration.UpSQL) {
+		if strings.Contains(strings.ToUpper(stmt), "CREATE DATABASE") {
+			m.logger.Info("Skipping CREATE DATABASE statement in migration apply")
+			continue
+		}
+		statements = append(statements, stmt)
 	}
+	return statements
+}
 
-	statements := strings.Split(migration.DownSQL, ";")
+// executeStatements runs statements through exec, naming the failing one by kind
+func (m *MigratorImpl) executeStatements(ctx context.Context, exec sqlx.ExecerContext, statements []string, kind string) error {
 	for _, stmt := range statements {
 		stmt = strings.TrimSpace(stmt)
 		if stmt == "" {
 			continue
 		}
 
-		if _, err := tx.ExecContext(ctx, stmt); err != nil {
-			return fmt.Errorf("failed to execute rollback statement: %s: %w", stmt, err)
+		if _, err := exec.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to execute %s: %s: %w", kind, stmt, err)
 		}
 	}
 
 	return nil
 }
 
-func (m *MigratorImpl) recordMigration(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
+func (m *MigratorImpl) executeRollback(ctx context.Context, tx sqlx.ExecerContext, migration *storm.Migration) error {
+	if migration.DownSQL == "" {
+		return fmt.Errorf("no rollback script available for migration %s", migration.Name)
+	}
+
+	return m.executeStatements(ctx, tx, migrator.SplitStatements(migration.DownSQL), "rollback statement")
+}
+
+// migrationVersion returns the version a migration is recorded under: the name
+// of its files without .up.sql
+func migrationVersion(migration *storm.Migration) string {
+	if migration.Version != "" {
+		return migration.Version
+	}
+	return migration.Name
+}
+
+func (m *MigratorImpl) recordMigration(ctx context.Context, tx sqlx.ExecerContext, migration *storm.Migration) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (name, applied_at, checksum)
+		INSERT INTO %s (version, applied_at, checksum)
 		VALUES ($1, $2, $3)
 	`, m.config.MigrationsTable)
 
-	_, err := tx.ExecContext(ctx, query, migration.Name, time.Now(), migration.Checksum)
+	_, err := tx.ExecContext(ctx, query, migrationVersion(migration), time.Now(), migration.Checksum)
 	return err
 }
 
-func (m *MigratorImpl) removeMigrationRecord(ctx context.Context, tx *sqlx.Tx, migration *storm.Migration) error {
+func (m *MigratorImpl) removeMigrationRecord(ctx context.Context, tx sqlx.ExecerContext, migration *storm.Migration) error {
 	query := fmt.Sprintf(`
-		DELETE FROM %s WHERE name = $1
+		DELETE FROM %s WHERE version = $1
 	`, m.config.MigrationsTable)
 
-	_, err := tx.ExecContext(ctx, query, migration.Name)
+	_, err := tx.ExecContext(ctx, query, migrationVersion(migration))
 	return err
 }
 
@@ -469,7 +710,7 @@ func (m *MigratorImpl) getCurrentSchemaOrEmpty(ctx context.Context) (*storm.Sche
 	return currentSchema, nil
 }
 
-func (m *MigratorImpl) getDesiredSchema(packagePath string) (*storm.Schema, error) {
+func (m *MigratorImpl) getDesiredSchema(packagePath string, strict bool) (*storm.Schema, error) {
 	structParser := NewStructParser()
 	models, err := structParser.ParseDirectory(packagePath)
 	if err != nil {
@@ -477,6 +718,7 @@ func (m *MigratorImpl) getDesiredSchema(packagePath string) (*storm.Schema, erro
 	}
 
 	schemaGenerator := NewSchemaGenerator()
+	schemaGenerator.SetStrictMode(strict)
 	schema, err := schemaGenerator.GenerateSchema(models)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate schema: %w", err)
@@ -485,16 +727,38 @@ func (m *MigratorImpl) getDesiredSchema(packagePath string) (*storm.Schema, erro
 	return m.convertGeneratorSchemaToStorm(schema), nil
 }
 
-func (m *MigratorImpl) generateMigration(current, desired *storm.Schema, createDBIfNotExists bool) (*storm.Migration, error) {
+func (m *MigratorImpl) generateMigration(current, desired *storm.Schema, migrateOpts storm.MigrateOptions) (*storm.Migration, error) {
 	atlasMigrator := NewAtlasMigrator(m.config.DatabaseURL)
 
+	preservation, err := migrator.ParseDataPreservation(migrateOpts.DataPreservation)
+	if err != nil {
+		return nil, err
+	}
+
+	// The history of applied migrations is not one of the models
+	ignore := migrator.IgnoreRules(migrateOpts.Ignore)
+	ignore.Tables = append(append([]string{}, ignore.Tables...), m.config.MigrationsTable)
+
 	opts := MigrationOptions{
 		PackagePath:         m.config.ModelsPackage,
 		OutputDir:           m.config.MigrationsDir,
 		DryRun:              false,
 		AllowDestructive:    false,
 		PushToDB:            false,
-		CreateDBIfNotExists: createDBIfNotExists,
+		CreateDBIfNotExists: migrateOpts.CreateDBIfNotExists,
+		Strict:              migrateOpts.Strict,
+		DataPreservation:    preservation,
+		RetentionPeriod:     migrateOpts.RetentionPeriod,
+		ConcurrentIndexes:   migrateOpts.ConcurrentIndexes,
+		SafeConstraints:     migrateOpts.SafeConstraints,
+		Analyze:             migrateOpts.Analyze,
+		ForeignKeys: generator.ForeignKeyConventions{
+			OnDelete: migrateOpts.ForeignKeyOnDelete,
+			OnUpdate: migrateOpts.ForeignKeyOnUpdate,
+			Naming:   migrateOpts.ForeignKeyNaming,
+		},
+		Ignore:            ignore,
+		UpdatedAtTriggers: migrateOpts.UpdatedAtTriggers,
 	}
 
 	ctx := context.Background()
@@ -518,7 +782,8 @@ func (m *MigratorImpl) generateMigration(current, desired *storm.Schema, createD
 // saveMigration removed - migration files are saved by AtlasMigrator
 
 func (m *MigratorImpl) calculateChecksum(content string) string {
-	return fmt.Sprintf("%x", len(content))
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
 }
 
 func NewStructParser() *parser.StructParser {
@@ -0,0 +1,275 @@
+package storm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+func newTestRunner(t *testing.T, files map[string]string) (*MigratorImpl, sqlmock.Sqlmock) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	config := &storm.Config{MigrationsDir: dir, MigrationsTable: "schema_migrations"}
+	return NewMigrator(sqlx.NewDb(db, "postgres"), config, &TestLogger{}), mock
+}
+
+func expectMigrationLock(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec(`SET LOCAL lock_timeout = 0`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectMigrationUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectMigrationsTable(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`RENAME COLUMN name TO version`).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigratorUp(t *testing.T) {
+	runner, mock := newTestRunner(t, map[string]string{
+		"20240101000000_create_users.up.sql": "CREATE TABLE users (id BIGINT);",
+		"20240102000000_add_email.up.sql":    "ALTER TABLE users ADD COLUMN email TEXT;",
+	})
+
+	expectMigrationLock(mock)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("20240101000000_create_users"))
+
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+		WithArgs("20240102000000_add_email").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE users ADD COLUMN email TEXT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, applied_at, checksum\)`).
+		WithArgs("20240102000000_add_email", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != "20240102000000_add_email" {
+		t.Fatalf("expected only the pending migration to be applied, got %v", applied)
+	}
+	if len(applied[0].Checksum) != 64 {
+		t.Errorf("expected a SHA-256 checksum, got %q", applied[0].Checksum)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigratorDown(t *testing.T) {
+	t.Run("rolls back the newest migrations", func(t *testing.T) {
+		runner, mock := newTestRunner(t, map[string]string{
+			"20240101000000_create_users.up.sql":   "CREATE TABLE users (id BIGINT);",
+			"20240101000000_create_users.down.sql": "DROP TABLE users;",
+			"20240102000000_add_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT;",
+			"20240102000000_add_email.down.sql":    "ALTER TABLE users DROP COLUMN email;",
+		})
+
+		expectMigrationLock(mock)
+		expectMigrationsTable(mock)
+		mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).
+				AddRow("20240101000000_create_users").
+				AddRow("20240102000000_add_email"))
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+			WithArgs("20240102000000_add_email").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec(`ALTER TABLE users DROP COLUMN email`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations WHERE version = \$1`).
+			WithArgs("20240102000000_add_email").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		expectMigrationUnlock(mock)
+
+		rolledBack, err := runner.Down(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Down failed: %v", err)
+		}
+		if len(rolledBack) != 1 || rolledBack[0].Version != "20240102000000_add_email" {
+			t.Fatalf("expected the newest migration to be rolled back, got %v", rolledBack)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("missing down file rolls back nothing", func(t *testing.T) {
+		runner, mock := newTestRunner(t, map[string]string{
+			"20240101000000_create_users.up.sql":   "CREATE TABLE users (id BIGINT);",
+			"20240101000000_create_users.down.sql": "DROP TABLE users;",
+			"20240102000000_add_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT;",
+		})
+
+		expectMigrationLock(mock)
+		expectMigrationsTable(mock)
+		mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).
+				AddRow("20240101000000_create_users").
+				AddRow("20240102000000_add_email"))
+		expectMigrationUnlock(mock)
+
+		_, err := runner.Down(context.Background(), 2)
+		if err == nil || !strings.Contains(err.Error(), "has no 20240102000000_add_email.down.sql") {
+			t.Errorf("expected a missing down file error, got %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestMigratorUp_ConcurrentIndexPhases(t *testing.T) {
+	runner, mock := newTestRunner(t, map[string]string{
+		"20240101000000_orders.up.sql": `ALTER TABLE orders ADD COLUMN total INTEGER;
+CREATE INDEX CONCURRENTLY idx_orders_total ON orders (total);`,
+	})
+
+	expectMigrationLock(mock)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+		WithArgs("20240101000000_orders").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE orders ADD COLUMN total INTEGER`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec(`CREATE INDEX CONCURRENTLY idx_orders_total ON orders \(total\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO schema_migrations \(version, applied_at, checksum\)`).
+		WithArgs("20240101000000_orders", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectMigrationUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("expected the migration to be applied, got %v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRetryingExecer_DoesNotRetryConcurrentIndexBuilds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	exec := retryingExecer{exec: sqlx.NewDb(db, "postgres"), retry: migrator.LockRetry{Period: time.Second, Backoff: time.Millisecond}}
+	lockErr := &pq.Error{Code: "55P03", Message: "canceling statement due to lock timeout"}
+
+	mock.ExpectExec(`ALTER TABLE orders ADD COLUMN total INTEGER`).WillReturnError(lockErr)
+	mock.ExpectExec(`ALTER TABLE orders ADD COLUMN total INTEGER`).WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := exec.ExecContext(context.Background(), "ALTER TABLE orders ADD COLUMN total INTEGER"); err != nil {
+		t.Fatalf("expected the ALTER TABLE to succeed on its retry, got %v", err)
+	}
+
+	mock.ExpectExec(`CREATE INDEX CONCURRENTLY idx_orders_total ON orders \(total\)`).WillReturnError(lockErr)
+	_, err = exec.ExecContext(context.Background(), "CREATE INDEX CONCURRENTLY idx_orders_total ON orders (total)")
+	if !migrator.IsLockTimeout(err) {
+		t.Errorf("expected the lock timeout of the index build, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMigratorUp_RefusesSyntaxTheServerLacks(t *testing.T) {
+	runner, mock := newTestRunner(t, map[string]string{
+		"20240101000000_totals.up.sql": `MERGE INTO totals t USING daily d ON t.day = d.day
+WHEN MATCHED THEN UPDATE SET count = d.count;`,
+	})
+
+	expectMigrationLock(mock)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT version FROM schema_migrations ORDER BY version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	expectMigrationsTable(mock)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+		WithArgs("20240101000000_totals").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`server_version_num`).
+		WillReturnRows(sqlmock.NewRows([]string{"current_setting"}).AddRow(140005))
+	expectMigrationUnlock(mock)
+
+	_, err := runner.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "MERGE statements require PostgreSQL 15 or later, the server runs 14.5") {
+		t.Fatalf("expected the migration to be refused before running, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateMigrationsTable_UpgradesSQLite(t *testing.T) {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, `CREATE TABLE schema_migrations (name VARCHAR(255) PRIMARY KEY, applied_at TIMESTAMP NOT NULL, checksum VARCHAR(64) NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewMigrator(db, &storm.Config{MigrationsTable: "schema_migrations"}, &TestLogger{})
+	for i := 0; i < 2; i++ {
+		if err := runner.createMigrationsTable(ctx); err != nil {
+			t.Fatalf("createMigrationsTable() error = %v", err)
+		}
+	}
+
+	var columns []string
+	if err := db.SelectContext(ctx, &columns, "SELECT name FROM pragma_table_info('schema_migrations')"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(columns, ",") != "version,applied_at,checksum" {
+		t.Errorf("columns = %v, want the name column renamed to version", columns)
+	}
+}
@@ -30,6 +30,13 @@ func (o *ORMImpl) Generate(ctx context.Context, opts storm.GenerateOptions) erro
 		OutputDir:    opts.OutputDir,
 		IncludeTests: opts.IncludeTests,
 		IncludeDocs:  true,
+		Progress:     opts.Progress,
+		Models:       opts.Models,
+		Features:     opts.Features,
+		Force:        opts.Force,
+		TemplateDir:  opts.TemplateDir,
+		Funcs:        opts.TemplateFuncs,
+		Data:         opts.TemplateData,
 	}
 
 	generator := orm_generator.NewCodeGenerator(config)
@@ -47,6 +54,6 @@ func (o *ORMImpl) Generate(ctx context.Context, opts storm.GenerateOptions) erro
 	}
 
 	models := generator.GetModelNames()
-	o.logger.Info("ORM code generated successfully", "models", len(models))
+	o.logger.Info("ORM code generated successfully", "models", len(models), "unchanged", len(generator.UnchangedModels()))
 	return nil
 }
@@ -0,0 +1,332 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// aggregateColumn is an expression selected by Aggregate under alias
+type aggregateColumn struct {
+	alias string
+	expr  squirrel.Sqlizer
+}
+
+// Count returns COUNT(*), the number of rows of each group, for Having:
+//
+//	Teams.Query(ctx).
+//		InnerJoin("team_members", "team_members.team_id = teams.id").
+//		GroupBy(Teams.ID.String()).
+//		Having(orm.CountGt(5))
+func Count() Expression[int64] {
+	return Expression[int64]{sql: "COUNT(*)"}
+}
+
+// Count returns the number of rows of each group where the column is not NULL
+func (c Column[T]) Count() Expression[int64] {
+	return Expression[int64]{sql: "COUNT(" + c.String() + ")"}
+}
+
+// CountDistinct returns the number of distinct values the column takes in each group
+func (c Column[T]) CountDistinct() Expression[int64] {
+	return Expression[int64]{sql: "COUNT(DISTINCT " + c.String() + ")"}
+}
+
+// Sum returns the sum of the column over each group
+func (c NumericColumn[T]) Sum() Expression[T] {
+	return Expression[T]{sql: "SUM(" + c.String() + ")"}
+}
+
+// Avg returns the average of the column over each group
+func (c NumericColumn[T]) Avg() Expression[float64] {
+	return Expression[float64]{sql: "AVG(" + c.String() + ")"}
+}
+
+// Min returns the smallest value of the column in each group
+func (c ComparableColumn[T]) Min() Expression[T] {
+	return Expression[T]{sql: "MIN(" + c.String() + ")"}
+}
+
+// Max returns the largest value of the column in each group
+func (c ComparableColumn[T]) Max() Expression[T] {
+	return Expression[T]{sql: "MAX(" + c.String() + ")"}
+}
+
+// CountEq matches the groups of exactly n rows
+func CountEq(n int64) Condition {
+	return Count().Eq(n)
+}
+
+// CountGt matches the groups of more than n rows
+func CountGt(n int64) Condition {
+	return Count().Gt(n)
+}
+
+// CountGte matches the groups of at least n rows
+func CountGte(n int64) Condition {
+	return Count().Gte(n)
+}
+
+// CountLt matches the groups of fewer than n rows
+func CountLt(n int64) Condition {
+	return Count().Lt(n)
+}
+
+// CountLte matches the groups of at most n rows
+func CountLte(n int64) Condition {
+	return Count().Lte(n)
+}
+
+// SumGt matches the groups whose sum of column is greater than value
+func SumGt[T Numeric](column NumericColumn[T], value T) Condition {
+	return column.Sum().Gt(value)
+}
+
+// SumGte matches the groups whose sum of column is at least value
+func SumGte[T Numeric](column NumericColumn[T], value T) Condition {
+	return column.Sum().Gte(value)
+}
+
+// SumLt matches the groups whose sum of column is less than value
+func SumLt[T Numeric](column NumericColumn[T], value T) Condition {
+	return column.Sum().Lt(value)
+}
+
+// SumLte matches the groups whose sum of column is at most value
+func SumLte[T Numeric](column NumericColumn[T], value T) Condition {
+	return column.Sum().Lte(value)
+}
+
+// GroupBy returns one record per group of rows sharing the values of columns, for
+// Having to filter on aggregates of the group. Group by the primary key to return
+// whole records, which PostgreSQL allows as the other columns depend on it. The
+// columns of the model are then selected qualified by its table, as grouped queries
+// usually join the rows they aggregate.
+func (q *Query[T]) GroupBy(columns ...string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.groupBy = append(q.groupBy, columns...)
+	q.resetColumns()
+	return q
+}
+
+// Having keeps the groups matching condition, which is usually built from aggregates
+// such as CountGt or Column.Sum, see GroupBy
+func (q *Query[T]) Having(condition Condition) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.having = append(q.having, condition.ToSqlizer())
+	return q
+}
+
+// applyGrouping adds the GROUP BY and HAVING clauses of the query to builder
+func (q *Query[T]) applyGrouping(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if len(q.groupBy) > 0 {
+		builder = builder.GroupBy(q.groupBy...)
+	}
+	if len(q.having) > 0 {
+		builder = builder.Having(q.having)
+	}
+	return builder
+}
+
+// SumInt64 returns the sum of column over the records matching the query, or 0 when
+// none match
+func (q *Query[T]) SumInt64(column string) (int64, error) {
+	var sum int64
+	err := q.aggregate("sum", "COALESCE(SUM("+column+"), 0)", &sum)
+	return sum, err
+}
+
+// SumFloat64 returns the sum of column over the records matching the query, or 0 when
+// none match
+func (q *Query[T]) SumFloat64(column string) (float64, error) {
+	var sum float64
+	err := q.aggregate("sum", "COALESCE(SUM("+column+"), 0)", &sum)
+	return sum, err
+}
+
+// AvgFloat64 returns the average of column over the records matching the query, or 0
+// when none match
+func (q *Query[T]) AvgFloat64(column string) (float64, error) {
+	var avg sql.NullFloat64
+	err := q.aggregate("avg", "AVG("+column+")", &avg)
+	return avg.Float64, err
+}
+
+// MinInt64 returns the smallest value of column among the records matching the query,
+// or 0 when none match
+func (q *Query[T]) MinInt64(column string) (int64, error) {
+	var value sql.NullInt64
+	err := q.aggregate("min", "MIN("+column+")", &value)
+	return value.Int64, err
+}
+
+// MaxInt64 returns the largest value of column among the records matching the query,
+// or 0 when none match
+func (q *Query[T]) MaxInt64(column string) (int64, error) {
+	var value sql.NullInt64
+	err := q.aggregate("max", "MAX("+column+")", &value)
+	return value.Int64, err
+}
+
+// MinFloat64 returns the smallest value of column among the records matching the
+// query, or 0 when none match
+func (q *Query[T]) MinFloat64(column string) (float64, error) {
+	var value sql.NullFloat64
+	err := q.aggregate("min", "MIN("+column+")", &value)
+	return value.Float64, err
+}
+
+// MaxFloat64 returns the largest value of column among the records matching the
+// query, or 0 when none match
+func (q *Query[T]) MaxFloat64(column string) (float64, error) {
+	var value sql.NullFloat64
+	err := q.aggregate("max", "MAX("+column+")", &value)
+	return value.Float64, err
+}
+
+// MinTime returns the earliest value of column among the records matching the query,
+// or the zero time when none match
+func (q *Query[T]) MinTime(column string) (time.Time, error) {
+	var value sql.NullTime
+	err := q.aggregate("min", "MIN("+column+")", &value)
+	return value.Time, err
+}
+
+// MaxTime returns the latest value of column among the records matching the query, or
+// the zero time when none match
+func (q *Query[T]) MaxTime(column string) (time.Time, error) {
+	var value sql.NullTime
+	err := q.aggregate("max", "MAX("+column+")", &value)
+	return value.Time, err
+}
+
+// CountDistinct returns the number of distinct values column takes among the records
+// matching the query, NULL aside
+func (q *Query[T]) CountDistinct(column string) (int64, error) {
+	var count int64
+	err := q.aggregate("count_distinct", "COUNT(DISTINCT "+column+")", &count)
+	return count, err
+}
+
+// aggregate selects expression over the records matching the query into dest. The
+// aggregates of a grouped query have a value per group, which Aggregate returns.
+func (q *Query[T]) aggregate(op, expression string, dest interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	if len(q.groupBy) > 0 || len(q.having) > 0 {
+		return &Error{
+			Op:    op,
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("cannot aggregate a grouped query into a single value, use Aggregate"),
+		}
+	}
+
+	builder := q.applyJoins(q.builder.RemoveColumns().Column(expression))
+	if len(q.whereClause) > 0 {
+		builder = builder.Where(q.whereClause)
+	}
+
+	return q.repo.executeQueryMiddleware(OpQuery, op, q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    op,
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build %s query: %w", op, err),
+			}
+		}
+
+		if execErr := q.reader().GetContext(q.ctx, dest, sqlQuery, args...); execErr != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to execute %s query: %w", op, execErr), op, q.repo.metadata.TableName)
+		}
+		return nil
+	})
+}
+
+// SelectAggregate adds expr, usually an aggregate such as Column.Sum, to the columns
+// Aggregate selects, under alias
+func (q *Query[T]) SelectAggregate(alias string, expr squirrel.Sqlizer) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.aggregates = append(q.aggregates, aggregateColumn{alias: alias, expr: expr})
+	return q
+}
+
+// Aggregate scans the GroupBy columns and the SelectAggregate expressions of the query
+// into dest, a pointer to a slice of structs for a row per group, or a pointer to a
+// struct for a query without GroupBy. Fields are matched by their db tag to the column
+// names, which drop the table of qualified GroupBy columns:
+//
+//	var stats []struct {
+//		AuthorID int64   `db:"author_id"`
+//		Posts    int64   `db:"posts"`
+//		Rating   float64 `db:"rating"`
+//	}
+//	err := Posts.Query(ctx).
+//		GroupBy(Posts.AuthorID.String()).
+//		SelectAggregate("posts", orm.Count()).
+//		SelectAggregate("rating", Posts.Rating.Avg()).
+//		Having(orm.CountGte(10)).
+//		Aggregate(&stats)
+func (q *Query[T]) Aggregate(dest interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	target := reflect.TypeOf(dest)
+	if target == nil || target.Kind() != reflect.Ptr {
+		return &Error{
+			Op:    "aggregate",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("aggregate destination must be a pointer, got %T", dest),
+		}
+	}
+	if len(q.aggregates) == 0 {
+		return &Error{
+			Op:    "aggregate",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("no aggregates selected, add them with SelectAggregate"),
+		}
+	}
+
+	builder := q.builder.RemoveColumns().Columns(q.groupBy...)
+	for _, aggregate := range q.aggregates {
+		builder = builder.Column(squirrel.Alias(aggregate.expr, aggregate.alias))
+	}
+	builder = q.applyClauses(builder)
+
+	return q.repo.executeQueryMiddleware(OpQuery, "aggregate", q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "aggregate",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build aggregate query: %w", err),
+			}
+		}
+
+		var execErr error
+		if target.Elem().Kind() == reflect.Slice {
+			execErr = q.reader().SelectContext(q.ctx, dest, sqlQuery, args...)
+		} else {
+			execErr = q.reader().GetContext(q.ctx, dest, sqlQuery, args...)
+		}
+		if execErr != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to execute aggregate query: %w", execErr), "aggregate", q.repo.metadata.TableName)
+		}
+		return nil
+	})
+}
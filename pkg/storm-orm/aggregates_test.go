@@ -0,0 +1,194 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateConditions(t *testing.T) {
+	amount := NumericColumn[int64]{ComparableColumn: ComparableColumn[int64]{Column: Column[int64]{Table: "orders", Name: "amount"}}}
+	email := Column[string]{Table: "members", Name: "email"}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		sql       string
+		args      []interface{}
+	}{
+		{"count", CountGt(5), "(COUNT(*)) > ?", []interface{}{int64(5)}},
+		{"count at most", CountLte(2), "(COUNT(*)) <= ?", []interface{}{int64(2)}},
+		{"sum", SumGte(amount, 100), "(SUM(orders.amount)) >= ?", []interface{}{int64(100)}},
+		{"average", amount.Avg().Lt(9.5), "(AVG(orders.amount)) < ?", []interface{}{9.5}},
+		{"distinct values", email.CountDistinct().Eq(1), "(COUNT(DISTINCT members.email)) = ?", []interface{}{int64(1)}},
+		{"max", amount.Max().Gt(10).And(amount.Min().Gt(0)), "((MAX(orders.amount)) > ? AND (MIN(orders.amount)) > ?)", []interface{}{int64(10), int64(0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := tt.condition.ToSqlizer().ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, tt.sql, sql)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestQueryGroupBy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	grouped := func() *Query[TestUser] {
+		return repo.Query(context.Background()).
+			InnerJoin("posts", "posts.author_id = users.id").
+			Where(Column[bool]{Table: "posts", Name: "published"}.Eq(true)).
+			GroupBy("users.id").
+			Having(CountGt(5))
+	}
+
+	t.Run("filters groups with having", func(t *testing.T) {
+		sql, args, err := grouped().OrderBy("users.name").ToSQL()
+		require.NoError(t, err)
+		assert.Regexp(t, `^SELECT (users\.\w+, )+users\.\w+ FROM`, sql)
+		assert.Contains(t, sql, "FROM users INNER JOIN posts ON posts.author_id = users.id WHERE (posts.published = $1) GROUP BY users.id HAVING ((COUNT(*)) > $2) ORDER BY users.name")
+		assert.Equal(t, []interface{}{true, int64(5)}, args)
+	})
+
+	t.Run("counts the groups", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM \(SELECT 1 FROM users INNER JOIN posts ON posts.author_id = users.id WHERE \(posts.published = \$1\) GROUP BY users.id HAVING \(\(COUNT\(\*\)\) > \$2\)\) AS groups`).
+			WithArgs(true, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		count, err := grouped().Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to delete or update groups", func(t *testing.T) {
+		_, err := grouped().Delete()
+		assert.ErrorContains(t, err, "grouped query")
+
+		_, err = grouped().Update(Column[string]{Name: "name"}.Set("x"))
+		assert.ErrorContains(t, err, "grouped query")
+	})
+}
+
+func TestQueryAggregates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+
+	t.Run("sums the matching records", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(users.id\), 0\) FROM users WHERE \(users.is_active = \$1\)$`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(42))
+
+		sum, err := repo.Query(context.Background()).Where(active.IsTrue()).SumInt64("users.id")
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), sum)
+	})
+
+	t.Run("returns zero values over no records", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT AVG\(id\) FROM users$`).
+			WillReturnRows(sqlmock.NewRows([]string{"avg"}).AddRow(nil))
+		mock.ExpectQuery(`SELECT MAX\(created_at\) FROM users$`).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+		avg, err := repo.Query(context.Background()).AvgFloat64("id")
+		require.NoError(t, err)
+		assert.Zero(t, avg)
+
+		latest, err := repo.Query(context.Background()).MaxTime("created_at")
+		require.NoError(t, err)
+		assert.True(t, latest.IsZero())
+	})
+
+	t.Run("reads times", func(t *testing.T) {
+		day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(`SELECT MIN\(created_at\) FROM users INNER JOIN posts ON posts.author_id = users.id$`).
+			WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(day))
+
+		earliest, err := repo.Query(context.Background()).
+			InnerJoin("posts", "posts.author_id = users.id").
+			MinTime("created_at")
+		require.NoError(t, err)
+		assert.Equal(t, day, earliest)
+	})
+
+	t.Run("counts distinct values", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(DISTINCT email\) FROM users$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+		count, err := repo.Query(context.Background()).CountDistinct("email")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+	})
+
+	t.Run("refuses a grouped query", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).GroupBy("name").SumInt64("id")
+		assert.ErrorContains(t, err, "use Aggregate")
+	})
+
+	t.Run("aggregates each group into structs", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT users.name, \(COUNT\(\*\)\) AS users, \(MAX\(users.id\)\) AS last_id FROM users WHERE \(users.is_active = \$1\) GROUP BY users.name HAVING \(\(COUNT\(\*\)\) > \$2\) ORDER BY users.name$`).
+			WithArgs(true, int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"name", "users", "last_id"}).
+				AddRow("alice", 2, 5).
+				AddRow("bob", 3, 9))
+
+		var stats []struct {
+			Name   string `db:"name"`
+			Users  int64  `db:"users"`
+			LastID int64  `db:"last_id"`
+		}
+		id := ComparableColumn[int]{Column: Column[int]{Table: "users", Name: "id"}}
+		err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			GroupBy("users.name").
+			SelectAggregate("users", Count()).
+			SelectAggregate("last_id", id.Max()).
+			Having(CountGt(1)).
+			OrderBy("users.name").
+			Aggregate(&stats)
+		require.NoError(t, err)
+		require.Len(t, stats, 2)
+		assert.Equal(t, "bob", stats[1].Name)
+		assert.Equal(t, int64(3), stats[1].Users)
+		assert.Equal(t, int64(9), stats[1].LastID)
+	})
+
+	t.Run("aggregates the whole query into a struct", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT \(COUNT\(\*\)\) AS total FROM users$`).
+			WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(4))
+
+		var totals struct {
+			Total int64 `db:"total"`
+		}
+		err := repo.Query(context.Background()).SelectAggregate("total", Count()).Aggregate(&totals)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), totals.Total)
+	})
+
+	t.Run("refuses an aggregate without expressions", func(t *testing.T) {
+		var totals struct{}
+		err := repo.Query(context.Background()).Aggregate(&totals)
+		assert.ErrorContains(t, err, "SelectAggregate")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
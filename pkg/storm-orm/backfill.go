@@ -0,0 +1,278 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BackfillsTable holds the checkpoints of backfills
+const BackfillsTable = "storm_backfills"
+
+// Backfill states
+const (
+	BackfillRunning = "running"
+	BackfillDone    = "done"
+	BackfillFailed  = "failed"
+)
+
+// Backfill walks a table in batches of keys, in key order, and checkpoints the
+// last key of each batch in storm_backfills, so an interrupted backfill picks up
+// where it stopped
+type Backfill struct {
+	Name      string        // Identifies the checkpoint; reuse it to resume
+	Table     string        // Table to walk
+	Key       string        // Unique column walked in order, defaults to id
+	Where     string        // Optional SQL condition limiting the rows walked
+	BatchSize int           // Keys per batch, defaults to 1000
+	Throttle  time.Duration // Pause between batches, to leave room for other traffic
+
+	// Batch does the work for the keys between batch.From and batch.To. It runs
+	// in the transaction that advances the checkpoint, so a batch is either
+	// done and recorded or neither.
+	Batch func(ctx context.Context, tx *sqlx.Tx, batch BackfillBatch) error
+
+	// Progress is called after each batch, if set
+	Progress func(status BackfillStatus)
+}
+
+// BackfillBatch is the range of keys of one batch, inclusive, in the text form
+// of the key column. Bind them as parameters, e.g. WHERE id BETWEEN $1 AND $2.
+type BackfillBatch struct {
+	From string
+	To   string
+	Size int
+}
+
+// BackfillStatus is the checkpoint of a backfill
+type BackfillStatus struct {
+	Name       string         `db:"name"`
+	Table      string         `db:"table_name"`
+	LastKey    sql.NullString `db:"last_key"`
+	RowsDone   int64          `db:"rows_done"`
+	Status     string         `db:"status"`
+	Error      sql.NullString `db:"error"`
+	StartedAt  time.Time      `db:"started_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+	FinishedAt sql.NullTime   `db:"finished_at"`
+}
+
+const backfillColumns = "name, table_name, last_key, rows_done, status, error, started_at, updated_at, finished_at"
+
+// RunBackfill runs b to the end, one transaction per batch. A backfill that was
+// interrupted or failed resumes after its last checkpoint; one that is done
+// returns straight away. Runs of the same backfill in several processes take
+// turns batch by batch.
+func (s *Storm) RunBackfill(ctx context.Context, b Backfill) (*BackfillStatus, error) {
+	db := s.GetDB()
+	if db == nil || s.isInTransaction() {
+		return nil, fmt.Errorf("backfill %s: backfills commit each batch and cannot run inside a transaction", b.Name)
+	}
+	if b.Name == "" || b.Table == "" || b.Batch == nil {
+		return nil, fmt.Errorf("backfill needs a name, a table and a batch function")
+	}
+	if b.Key == "" {
+		b.Key = "id"
+	}
+	if b.BatchSize <= 0 {
+		b.BatchSize = 1000
+	}
+
+	if err := s.startBackfill(ctx, b); err != nil {
+		return nil, err
+	}
+
+	for {
+		status, more, err := s.runBackfillBatch(ctx, db, b)
+		if err != nil {
+			// A cancelled run is interrupted rather than failed
+			if ctx.Err() == nil {
+				s.failBackfill(b.Name, err)
+			}
+			return nil, fmt.Errorf("backfill %s: %w", b.Name, err)
+		}
+		if b.Progress != nil {
+			b.Progress(*status)
+		}
+		if !more {
+			return status, nil
+		}
+
+		if b.Throttle > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(b.Throttle):
+			}
+		}
+	}
+}
+
+// startBackfill creates the checkpoint of b, or marks a failed one as running again
+func (s *Storm) startBackfill(ctx context.Context, b Backfill) error {
+	if err := s.ensureBackfillsTable(ctx); err != nil {
+		return err
+	}
+
+	insert := "INSERT INTO " + BackfillsTable + " (name, table_name) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING"
+	if _, err := s.executor.ExecContext(ctx, insert, b.Name, b.Table); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to create checkpoint: %w", err), "backfill", BackfillsTable)
+	}
+
+	restart := "UPDATE " + BackfillsTable + " SET status = $2, error = NULL, updated_at = now() WHERE name = $1 AND status = $3"
+	if _, err := s.executor.ExecContext(ctx, restart, b.Name, BackfillRunning, BackfillFailed); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to restart checkpoint: %w", err), "backfill", BackfillsTable)
+	}
+	return nil
+}
+
+func (s *Storm) ensureBackfillsTable(ctx context.Context) error {
+	create := `CREATE TABLE IF NOT EXISTS ` + BackfillsTable + ` (
+    name TEXT PRIMARY KEY,
+    table_name TEXT NOT NULL,
+    last_key TEXT,
+    rows_done BIGINT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'running',
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ
+)`
+	if _, err := s.executor.ExecContext(ctx, create); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to create %s: %w", BackfillsTable, err), "backfill", BackfillsTable)
+	}
+	return nil
+}
+
+// runBackfillBatch processes the batch after the checkpoint and advances it,
+// reporting whether there may be more batches
+func (s *Storm) runBackfillBatch(ctx context.Context, db *sqlx.DB, b Backfill) (*BackfillStatus, bool, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the checkpoint makes concurrent runs take turns
+	var status BackfillStatus
+	lock := "SELECT " + backfillColumns + " FROM " + BackfillsTable + " WHERE name = $1 FOR UPDATE"
+	if err := tx.GetContext(ctx, &status, lock, b.Name); err != nil {
+		return nil, false, parsePostgreSQLError(fmt.Errorf("failed to lock checkpoint: %w", err), "backfill", BackfillsTable)
+	}
+	if status.Status == BackfillDone {
+		return &status, false, nil
+	}
+
+	var batch struct {
+		From sql.NullString `db:"first_key"`
+		To   sql.NullString `db:"last_key"`
+		Size int            `db:"size"`
+	}
+	query, args := backfillBatchQuery(b, status.LastKey)
+	if err := tx.GetContext(ctx, &batch, query, args...); err != nil {
+		return nil, false, parsePostgreSQLError(fmt.Errorf("failed to find next batch: %w", err), "backfill", b.Table)
+	}
+
+	if batch.Size == 0 {
+		finish := "UPDATE " + BackfillsTable + " SET status = $2, updated_at = now(), finished_at = now() WHERE name = $1 RETURNING " + backfillColumns
+		if err := tx.GetContext(ctx, &status, finish, b.Name, BackfillDone); err != nil {
+			return nil, false, parsePostgreSQLError(fmt.Errorf("failed to finish checkpoint: %w", err), "backfill", BackfillsTable)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, false, fmt.Errorf("failed to commit: %w", err)
+		}
+		return &status, false, nil
+	}
+
+	if err := b.Batch(ctx, tx, BackfillBatch{From: batch.From.String, To: batch.To.String, Size: batch.Size}); err != nil {
+		return nil, false, fmt.Errorf("batch %s..%s: %w", batch.From.String, batch.To.String, err)
+	}
+
+	advance := "UPDATE " + BackfillsTable + " SET last_key = $2, rows_done = rows_done + $3, updated_at = now() WHERE name = $1 RETURNING " + backfillColumns
+	if err := tx.GetContext(ctx, &status, advance, b.Name, batch.To.String, batch.Size); err != nil {
+		return nil, false, parsePostgreSQLError(fmt.Errorf("failed to advance checkpoint: %w", err), "backfill", BackfillsTable)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return &status, true, nil
+}
+
+// backfillBatchQuery selects the first and last key and the size of the batch
+// after lastKey
+func backfillBatchQuery(b Backfill, lastKey sql.NullString) (string, []interface{}) {
+	key := quoteQualifiedIdentifier(b.Key)
+
+	var conditions []string
+	var args []interface{}
+	if lastKey.Valid {
+		args = append(args, lastKey.String)
+		conditions = append(conditions, fmt.Sprintf("%s > $1", key))
+	}
+	if b.Where != "" {
+		conditions = append(conditions, "("+b.Where+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// array_agg rather than MIN and MAX, which not every key type has
+	return fmt.Sprintf("SELECT keys[1] AS first_key, keys[cardinality(keys)] AS last_key, COALESCE(cardinality(keys), 0) AS size "+
+		"FROM (SELECT array_agg(k::text ORDER BY k) AS keys FROM (SELECT %s AS k FROM %s%s ORDER BY %s LIMIT %d) batch) batches",
+		key, quoteQualifiedIdentifier(b.Table), where, key, b.BatchSize), args
+}
+
+// quoteQualifiedIdentifier quotes each part of a possibly schema-qualified name
+func quoteQualifiedIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// failBackfill records err on the checkpoint
+func (s *Storm) failBackfill(name string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fail := "UPDATE " + BackfillsTable + " SET status = $2, error = $3, updated_at = now() WHERE name = $1"
+	_, _ = s.executor.ExecContext(ctx, fail, name, BackfillFailed, err.Error())
+}
+
+// Backfills returns the checkpoints of all backfills, newest first
+func (s *Storm) Backfills(ctx context.Context) ([]BackfillStatus, error) {
+	var exists bool
+	if err := s.executor.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", BackfillsTable); err != nil {
+		return nil, parsePostgreSQLError(fmt.Errorf("failed to look up %s: %w", BackfillsTable, err), "backfill", BackfillsTable)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	var statuses []BackfillStatus
+	query := "SELECT " + backfillColumns + " FROM " + BackfillsTable + " ORDER BY started_at DESC, name"
+	if err := s.executor.SelectContext(ctx, &statuses, query); err != nil {
+		return nil, parsePostgreSQLError(fmt.Errorf("failed to read %s: %w", BackfillsTable, err), "backfill", BackfillsTable)
+	}
+	return statuses, nil
+}
+
+// ResetBackfill deletes the checkpoint of a backfill, so that its next run
+// starts from the beginning
+func (s *Storm) ResetBackfill(ctx context.Context, name string) error {
+	result, err := s.executor.ExecContext(ctx, "DELETE FROM "+BackfillsTable+" WHERE name = $1", name)
+	if err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to reset backfill %s: %w", name, err), "backfill", BackfillsTable)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return &Error{Op: "backfill", Table: BackfillsTable, Err: fmt.Errorf("backfill %s: %w", name, ErrNotFound)}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillBatchQuery(t *testing.T) {
+	b := Backfill{Table: "users", Key: "id", BatchSize: 500}
+
+	query, args := backfillBatchQuery(b, sql.NullString{})
+	assert.Contains(t, query, `(SELECT "id" AS k FROM "users" ORDER BY "id" LIMIT 500) batch`)
+	assert.Empty(t, args)
+
+	b.Where = "email <> lower(email)"
+	query, args = backfillBatchQuery(b, sql.NullString{String: "41", Valid: true})
+	assert.Contains(t, query, `FROM "users" WHERE "id" > $1 AND (email <> lower(email)) ORDER BY "id" LIMIT 500`)
+	assert.Equal(t, []interface{}{"41"}, args)
+}
+
+func TestRunBackfill(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	now := time.Now()
+	columns := []string{"name", "table_name", "last_key", "rows_done", "status", "error", "started_at", "updated_at", "finished_at"}
+	checkpoint := func(lastKey interface{}, rows int64, status string) *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow("lower-emails", "users", lastKey, rows, status, nil, now, now, nil)
+	}
+	batchRows := []string{"first_key", "last_key", "size"}
+
+	start := func() {
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS storm_backfills`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO storm_backfills \(name, table_name\) VALUES \(\$1, \$2\) ON CONFLICT \(name\) DO NOTHING`).
+			WithArgs("lower-emails", "users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE storm_backfills SET status = \$2, error = NULL`).
+			WithArgs("lower-emails", BackfillRunning, BackfillFailed).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	lowerEmails := func(ctx context.Context, tx *sqlx.Tx, batch BackfillBatch) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET email = lower(email) WHERE id BETWEEN $1 AND $2", batch.From, batch.To)
+		return err
+	}
+
+	t.Run("batches advance the checkpoint until no keys are left", func(t *testing.T) {
+		start()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM storm_backfills WHERE name = \$1 FOR UPDATE`).WillReturnRows(checkpoint(nil, 0, BackfillRunning))
+		mock.ExpectQuery(`SELECT keys\[1\] AS first_key`).WillReturnRows(sqlmock.NewRows(batchRows).AddRow("1", "2", 2))
+		mock.ExpectExec(`UPDATE users SET email = lower\(email\)`).WithArgs("1", "2").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(`UPDATE storm_backfills SET last_key = \$2, rows_done = rows_done \+ \$3`).
+			WithArgs("lower-emails", "2", 2).WillReturnRows(checkpoint("2", 2, BackfillRunning))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(checkpoint("2", 2, BackfillRunning))
+		mock.ExpectQuery(`"id" > \$1`).WithArgs("2").WillReturnRows(sqlmock.NewRows(batchRows).AddRow(nil, nil, 0))
+		mock.ExpectQuery(`UPDATE storm_backfills SET status = \$2, updated_at = now\(\), finished_at = now\(\)`).
+			WithArgs("lower-emails", BackfillDone).WillReturnRows(checkpoint("2", 2, BackfillDone))
+		mock.ExpectCommit()
+
+		var progress []int64
+		status, err := storm.RunBackfill(context.Background(), Backfill{
+			Name:     "lower-emails",
+			Table:    "users",
+			Batch:    lowerEmails,
+			Progress: func(status BackfillStatus) { progress = append(progress, status.RowsDone) },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, BackfillDone, status.Status)
+		assert.Equal(t, int64(2), status.RowsDone)
+		assert.Equal(t, []int64{2, 2}, progress)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed batch is rolled back and recorded", func(t *testing.T) {
+		start()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FOR UPDATE`).WillReturnRows(checkpoint("2", 2, BackfillRunning))
+		mock.ExpectQuery(`SELECT keys\[1\] AS first_key`).WillReturnRows(sqlmock.NewRows(batchRows).AddRow("3", "4", 2))
+		mock.ExpectExec(`UPDATE users`).WillReturnError(errors.New("deadlock detected"))
+		mock.ExpectRollback()
+		mock.ExpectExec(`UPDATE storm_backfills SET status = \$2, error = \$3`).
+			WithArgs("lower-emails", BackfillFailed, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := storm.RunBackfill(context.Background(), Backfill{Name: "lower-emails", Table: "users", Batch: lowerEmails})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "batch 3..4: deadlock detected")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("backfills cannot run inside a transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err := storm.WithTransaction(context.Background(), func(tx *Storm) error {
+			_, err := tx.RunBackfill(context.Background(), Backfill{Name: "lower-emails", Table: "users", Batch: lowerEmails})
+			return err
+		})
+		assert.ErrorContains(t, err, "cannot run inside a transaction")
+	})
+}
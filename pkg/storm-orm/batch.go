@@ -0,0 +1,189 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+// batchSavepoint is the savepoint each chunk of a partial batch runs under
+const batchSavepoint = "storm_batch"
+
+// CreateManyOptions configures CreateManyWithOptions
+type CreateManyOptions struct {
+	// ContinueOnError inserts every chunk under its own savepoint, so a chunk that
+	// fails, e.g. on a duplicate key, is skipped instead of failing the batch
+	ContinueOnError bool
+	// ChunkSize is the number of records per INSERT. With ContinueOnError it
+	// defaults to 1, so errors are reported per record; otherwise all records
+	// go into one INSERT. Chunks are kept under PostgreSQL's parameter limit.
+	ChunkSize int
+}
+
+// BatchError is the error of a record that was not written
+type BatchError struct {
+	Index int   // Index of the record in the batch
+	Err   error // Error of the chunk the record was in
+}
+
+// BatchResult reports which records of a batch were written
+type BatchResult struct {
+	Succeeded []int        // Indexes of the records that were written
+	Failed    []BatchError // Records that were not written, in batch order
+}
+
+// HasErrors reports whether any record of the batch failed
+func (b *BatchResult) HasErrors() bool {
+	return len(b.Failed) > 0
+}
+
+// CreateManyWithOptions inserts records like CreateMany. With ContinueOnError,
+// failed chunks are rolled back to their savepoint and reported in the result
+// while the rest of the batch is committed. The returned error is only set when
+// the batch as a whole could not run.
+func (r *Repository[T]) CreateManyWithOptions(ctx context.Context, records []T, opts CreateManyOptions) (*BatchResult, error) {
+	result := &BatchResult{}
+	if len(records) == 0 {
+		return result, nil
+	}
+
+	if !opts.ContinueOnError && opts.ChunkSize <= 0 {
+		if err := r.CreateMany(ctx, records); err != nil {
+			return nil, err
+		}
+		for i := range records {
+			result.Succeeded = append(result.Succeeded, i)
+		}
+		return result, nil
+	}
+
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+	if columns, _ := r.getInsertFields(records[0]); len(columns) > 0 && chunkSize*len(columns) > maxParameters {
+		chunkSize = maxParameters / len(columns)
+	}
+
+	insertChunks := func(tx DBExecutor) error {
+		for start := 0; start < len(records); start += chunkSize {
+			end := start + chunkSize
+			if end > len(records) {
+				end = len(records)
+			}
+
+			err := r.insertChunk(ctx, tx, records[start:end], opts.ContinueOnError)
+			if err != nil && !opts.ContinueOnError {
+				return err
+			}
+
+			for i := start; i < end; i++ {
+				if err != nil {
+					result.Failed = append(result.Failed, BatchError{Index: i, Err: err})
+				} else {
+					result.Succeeded = append(result.Succeeded, i)
+				}
+			}
+
+			// A savepoint that cannot be rolled back leaves the transaction aborted
+			if savepointErr, ok := err.(*savepointError); ok {
+				return savepointErr.err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	switch db := r.db.(type) {
+	case *sqlx.Tx:
+		err = insertChunks(db)
+	case *sqlx.DB:
+		err = r.WithinTransaction(ctx, func(tx *sqlx.Tx) error {
+			return insertChunks(tx)
+		})
+	default:
+		err = fmt.Errorf("batch inserts need a database connection or transaction, got %T", r.db)
+	}
+	if err != nil {
+		if _, ok := err.(*Error); !ok {
+			err = &Error{
+				Op:    "createMany",
+				Table: r.metadata.TableName,
+				Err:   err,
+			}
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// savepointError marks a failure to roll back to the batch savepoint
+type savepointError struct {
+	err error
+}
+
+func (e *savepointError) Error() string {
+	return e.err.Error()
+}
+
+// insertChunk inserts records with one INSERT through the middleware. With
+// savepoint set the INSERT runs under batchSavepoint, which is rolled back if
+// it fails so that the transaction can go on.
+func (r *Repository[T]) insertChunk(ctx context.Context, executor DBExecutor, records []T, savepoint bool) error {
+	columns, _ := r.getInsertFields(records[0])
+	if len(columns) == 0 {
+		return nil
+	}
+
+	query := squirrel.Insert(r.metadata.TableName).
+		PlaceholderFormat(squirrel.Dollar).
+		Columns(columns...)
+	for _, record := range records {
+		_, values := r.getInsertFields(record)
+		query = query.Values(values...)
+	}
+
+	if savepoint {
+		if _, err := executor.ExecContext(ctx, "SAVEPOINT "+batchSavepoint); err != nil {
+			return &savepointError{err: fmt.Errorf("failed to create savepoint: %w", err)}
+		}
+	}
+
+	err := r.executeQueryMiddleware(OpCreateMany, "create_many", ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "createMany",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build batch insert query: %w", err),
+			}
+		}
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		if _, err := executor.ExecContext(ctx, sqlQuery, args...); err != nil {
+			return parsePostgreSQLError(err, "createMany", r.metadata.TableName)
+		}
+		return nil
+	})
+
+	if !savepoint {
+		return err
+	}
+	if err != nil {
+		if _, rbErr := executor.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+batchSavepoint); rbErr != nil {
+			return &savepointError{err: fmt.Errorf("failed to roll back to savepoint: %w", rbErr)}
+		}
+		return err
+	}
+	if _, err := executor.ExecContext(ctx, "RELEASE SAVEPOINT "+batchSavepoint); err != nil {
+		return &savepointError{err: fmt.Errorf("failed to release savepoint: %w", err)}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateManyWithOptions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	users := []TestUser{
+		{Name: "User1", Email: "user1@example.com"},
+		{Name: "User2", Email: "taken@example.com"},
+		{Name: "User3", Email: "user3@example.com"},
+	}
+	duplicate := errors.New(`pq: duplicate key value violates unique constraint "users_email_key"`)
+
+	t.Run("failed rows are rolled back to their savepoint", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnError(duplicate)
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := repo.CreateManyWithOptions(context.Background(), users, CreateManyOptions{ContinueOnError: true})
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 2}, result.Succeeded)
+		require.True(t, result.HasErrors())
+		require.Len(t, result.Failed, 1)
+		assert.Equal(t, 1, result.Failed[0].Index)
+		assert.ErrorIs(t, result.Failed[0].Err, ErrDuplicateKey)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("chunks fail together", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnError(duplicate)
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		result, err := repo.CreateManyWithOptions(context.Background(), users, CreateManyOptions{ContinueOnError: true, ChunkSize: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, result.Succeeded)
+		require.Len(t, result.Failed, 2)
+		assert.Equal(t, 0, result.Failed[0].Index)
+		assert.Equal(t, 1, result.Failed[1].Index)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed savepoint rollback aborts the batch", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT storm_batch`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnError(duplicate)
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_batch`).WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		result, err := repo.CreateManyWithOptions(context.Background(), users, CreateManyOptions{ContinueOnError: true})
+		require.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "failed to roll back to savepoint")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without ContinueOnError the first error fails the batch", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`INSERT INTO users`).WillReturnError(duplicate)
+		mock.ExpectRollback()
+
+		_, err := repo.CreateManyWithOptions(context.Background(), users, CreateManyOptions{ChunkSize: 2})
+		assert.ErrorIs(t, err, ErrDuplicateKey)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("defaults to CreateMany", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users`).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		result, err := repo.CreateManyWithOptions(context.Background(), users, CreateManyOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, []int{0, 1, 2}, result.Succeeded)
+		assert.False(t, result.HasErrors())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
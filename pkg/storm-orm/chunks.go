@@ -0,0 +1,81 @@
+package orm
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// DefaultInChunkSize is the number of values bound into one IN list before a key
+// set is split across several queries
+const DefaultInChunkSize = 1000
+
+var inChunkSize atomic.Int64
+
+// maxParameters is the number of bind parameters PostgreSQL accepts in one statement;
+// multi-row statements that would bind more are split
+var maxParameters = 65535
+
+func init() {
+	inChunkSize.Store(DefaultInChunkSize)
+}
+
+// SetInChunkSize sets how many keys a single IN list may hold. Larger key sets are
+// queried in chunks of this size and the results merged, which keeps queries under
+// PostgreSQL's 65535 parameter limit, and Column.In binds larger lists as a single
+// array. Values below 1 restore the default.
+func SetInChunkSize(size int) {
+	if size < 1 {
+		size = DefaultInChunkSize
+	}
+	inChunkSize.Store(int64(size))
+}
+
+// chunkValues splits values into slices of at most the configured chunk size,
+// dropping repeated keys so that merged results hold each row once
+func chunkValues(values []interface{}) [][]interface{} {
+	size := int(inChunkSize.Load())
+
+	seen := make(map[interface{}]struct{}, len(values))
+	unique := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		if value != nil && reflect.TypeOf(value).Comparable() {
+			if _, ok := seen[value]; ok {
+				continue
+			}
+			seen[value] = struct{}{}
+		}
+		unique = append(unique, value)
+	}
+
+	chunks := make([][]interface{}, 0, (len(unique)+size-1)/size)
+	for start := 0; start < len(unique); start += size {
+		end := start + size
+		if end > len(unique) {
+			end = len(unique)
+		}
+		chunks = append(chunks, unique[start:end])
+	}
+	return chunks
+}
+
+// chunkRecords splits records into slices that bind at most maxParameters values
+// when each record binds one value per column
+func chunkRecords[T any](records []T, columns int) [][]T {
+	size := len(records)
+	if columns > 0 && size*columns > maxParameters {
+		size = maxParameters / columns
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	chunks := make([][]T, 0, (len(records)+size-1)/size)
+	for start := 0; start < len(records); start += size {
+		end := start + size
+		if end > len(records) {
+			end = len(records)
+		}
+		chunks = append(chunks, records[start:end])
+	}
+	return chunks
+}
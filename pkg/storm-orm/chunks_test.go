@@ -0,0 +1,51 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkValues(t *testing.T) {
+	defer SetInChunkSize(DefaultInChunkSize)
+
+	SetInChunkSize(3)
+	assert.Equal(t, [][]interface{}{{1, 2, 3}, {4, 5}}, chunkValues([]interface{}{1, 2, 3, 4, 5}))
+	assert.Equal(t, [][]interface{}{{"a", "b"}}, chunkValues([]interface{}{"a", "b", "a"}), "repeated keys are dropped")
+	assert.Empty(t, chunkValues(nil))
+
+	SetInChunkSize(0)
+	assert.Len(t, chunkValues(make([]interface{}, DefaultInChunkSize)), 1, "nil keys are kept and sizes below 1 restore the default")
+}
+
+func TestChunkRecords(t *testing.T) {
+	defer func(limit int) { maxParameters = limit }(maxParameters)
+	maxParameters = 7
+
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}, chunkRecords([]int{1, 2, 3, 4, 5, 6, 7}, 2))
+	assert.Equal(t, [][]int{{1, 2, 3}}, chunkRecords([]int{1, 2, 3}, 1), "records under the limit stay together")
+	assert.Equal(t, [][]int{{1}, {2}}, chunkRecords([]int{1, 2}, 10), "a record over the limit goes alone")
+}
+
+func TestInBindsLongListsAsArray(t *testing.T) {
+	defer SetInChunkSize(DefaultInChunkSize)
+	SetInChunkSize(2)
+
+	id := Column[int]{Table: "users", Name: "id"}
+
+	sql, args, err := id.In(1, 2).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "users.id IN (?,?)", sql)
+	assert.Equal(t, []interface{}{1, 2}, args)
+
+	sql, args, err = id.In(1, 2, 3).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "users.id = ANY(?)", sql)
+	assert.Equal(t, []interface{}{pq.Array([]int{1, 2, 3})}, args)
+
+	sql, _, err = id.NotIn(1, 2, 3).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "users.id <> ALL(?)", sql)
+}
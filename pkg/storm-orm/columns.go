@@ -1,10 +1,13 @@
 package orm
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 )
 
 // Column represents a type-safe database column reference
@@ -28,7 +31,13 @@ func (c Column[T]) NotEq(value T) Condition {
 	return Condition{squirrel.NotEq{c.String(): value}}
 }
 
+// In matches the values of the column in values. Lists longer than the IN chunk
+// size (see SetInChunkSize) are bound as a single array, = ANY($1), so they do not
+// run into PostgreSQL's parameter limit.
 func (c Column[T]) In(values ...T) Condition {
+	if len(values) > int(inChunkSize.Load()) {
+		return Condition{squirrel.Expr(c.String()+" = ANY(?)", pq.Array(values))}
+	}
 	interfaces := make([]interface{}, len(values))
 	for i, v := range values {
 		interfaces[i] = v
@@ -36,7 +45,12 @@ func (c Column[T]) In(values ...T) Condition {
 	return Condition{squirrel.Eq{c.String(): interfaces}}
 }
 
+// NotIn matches the values of the column not in values, binding long lists as a
+// single array like In
 func (c Column[T]) NotIn(values ...T) Condition {
+	if len(values) > int(inChunkSize.Load()) {
+		return Condition{squirrel.Expr(c.String()+" <> ALL(?)", pq.Array(values))}
+	}
 	interfaces := make([]interface{}, len(values))
 	for i, v := range values {
 		interfaces[i] = v
@@ -253,42 +267,106 @@ type JSONBColumn struct {
 	Column[interface{}]
 }
 
-func (c JSONBColumn) Path(path string) JSONBColumn {
+// Path returns the JSON value at path, a key per level, as a JSONB column (#>)
+func (c JSONBColumn) Path(path ...string) JSONBColumn {
 	return JSONBColumn{
 		Column: Column[interface{}]{
-			Name:  fmt.Sprintf("(%s->'%s')", c.String(), path),
+			Name:  fmt.Sprintf("(%s #> %s)", c.String(), jsonbPathLiteral(path)),
 			Table: "",
 		},
 	}
 }
 
-func (c JSONBColumn) PathText(path string) StringColumn {
+// PathText returns the value at path, a key per level, as text (#>>), to compare
+// like any string column:
+//
+//	Users.Settings.PathText("notifications", "email").Eq("weekly")
+func (c JSONBColumn) PathText(path ...string) StringColumn {
 	return StringColumn{
 		Column: Column[string]{
-			Name:  fmt.Sprintf("(%s->>'%s')", c.String(), path),
+			Name:  fmt.Sprintf("(%s #>> %s)", c.String(), jsonbPathLiteral(path)),
 			Table: "",
 		},
 	}
 }
 
+// jsonbPathLiteral renders path as a quoted text[] literal, so that keys taken from
+// requests cannot end the literal
+func jsonbPathLiteral(path []string) string {
+	elements := make([]string, len(path))
+	for i, key := range path {
+		key = strings.ReplaceAll(key, `\`, `\\`)
+		key = strings.ReplaceAll(key, `"`, `\"`)
+		elements[i] = `"` + key + `"`
+	}
+	return "'{" + strings.ReplaceAll(strings.Join(elements, ","), "'", "''") + "}'"
+}
+
+// Contains matches the rows whose value contains value (@>), which is encoded as
+// JSON unless it already is JSON text or bytes
 func (c JSONBColumn) Contains(value interface{}) Condition {
-	return Condition{squirrel.Expr(c.String()+" @> ?", value)}
+	return c.jsonCondition("@>", value)
 }
 
+// ContainedBy matches the rows whose value is contained in value (<@)
 func (c JSONBColumn) ContainedBy(value interface{}) Condition {
-	return Condition{squirrel.Expr(c.String()+" <@ ?", value)}
+	return c.jsonCondition("<@", value)
 }
 
+func (c JSONBColumn) jsonCondition(operator string, value interface{}) Condition {
+	document, err := jsonbValue(value)
+	if err != nil {
+		return Condition{Expression[bool]{err: fmt.Errorf("invalid JSON for %s: %w", c.String(), err)}}
+	}
+	return Condition{squirrel.Expr(c.String()+" "+operator+" ?::jsonb", document)}
+}
+
+// jsonbValue returns value as JSON text to bind
+func jsonbValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case json.RawMessage:
+		return string(v), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// The ? of the key operators is doubled, as squirrel would take it for a placeholder
+
+// HasKey matches the rows whose value has key at its top level (?)
 func (c JSONBColumn) HasKey(key string) Condition {
-	return Condition{squirrel.Expr(c.String()+" ? ?", key)}
+	return Condition{squirrel.Expr(c.String()+" ?? ?", key)}
 }
 
+// HasAnyKey matches the rows whose value has any of keys at its top level (?|)
 func (c JSONBColumn) HasAnyKey(keys []string) Condition {
-	return Condition{squirrel.Expr(c.String()+" ?| ?", keys)}
+	return Condition{squirrel.Expr(c.String()+" ??| ?", pq.Array(keys))}
 }
 
+// HasAllKeys matches the rows whose value has all of keys at its top level (?&)
 func (c JSONBColumn) HasAllKeys(keys []string) Condition {
-	return Condition{squirrel.Expr(c.String()+" ?& ?", keys)}
+	return Condition{squirrel.Expr(c.String()+" ??& ?", pq.Array(keys))}
+}
+
+// PathExists matches the rows for which the SQL/JSON path query returns an item (@?):
+//
+//	Orders.Items.PathExists(`$[*] ? (@.quantity > 10)`)
+func (c JSONBColumn) PathExists(jsonPath string) Condition {
+	return Condition{squirrel.Expr(c.String()+" @?? ?::jsonpath", jsonPath)}
+}
+
+// PathMatch matches the rows for which the SQL/JSON path predicate is true (@@):
+//
+//	Users.Settings.PathMatch(`$.notifications.email == "weekly"`)
+func (c JSONBColumn) PathMatch(jsonPath string) Condition {
+	return Condition{squirrel.Expr(c.String()+" @@ ?::jsonpath", jsonPath)}
 }
 
 // Condition wraps squirrel conditions for type safety
@@ -317,6 +395,7 @@ type Action struct {
 	column     string
 	expression string
 	value      interface{}
+	err        error // Set when the expression failed to build
 }
 
 func (a Action) Column() string {
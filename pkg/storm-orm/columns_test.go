@@ -1,10 +1,13 @@
 package orm
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 )
 
 func TestStringColumn(t *testing.T) {
@@ -303,27 +306,52 @@ func TestJSONBColumn(t *testing.T) {
 		{
 			name:     "Contains",
 			method:   func() Condition { return col.Contains(map[string]interface{}{"active": true}) },
-			expected: "users.metadata @> ?",
+			expected: "users.metadata @> ?::jsonb",
 		},
 		{
 			name:     "ContainedBy",
 			method:   func() Condition { return col.ContainedBy(map[string]interface{}{"active": true, "role": "admin"}) },
-			expected: "users.metadata <@ ?",
+			expected: "users.metadata <@ ?::jsonb",
 		},
 		{
 			name:     "HasKey",
 			method:   func() Condition { return col.HasKey("active") },
-			expected: "users.metadata ? ?",
+			expected: "users.metadata ?? ?",
 		},
 		{
 			name:     "HasAnyKey",
 			method:   func() Condition { return col.HasAnyKey([]string{"active", "role"}) },
-			expected: "users.metadata ?| ?",
+			expected: "users.metadata ??| ?",
 		},
 		{
 			name:     "HasAllKeys",
 			method:   func() Condition { return col.HasAllKeys([]string{"active", "role"}) },
-			expected: "users.metadata ?& ?",
+			expected: "users.metadata ??& ?",
+		},
+		{
+			name:     "PathText",
+			method:   func() Condition { return col.PathText("profile", "name").Eq("ada") },
+			expected: `(users.metadata #>> '{"profile","name"}') = ?`,
+		},
+		{
+			name:     "PathText escapes keys",
+			method:   func() Condition { return col.PathText(`it's "quoted"`).IsNull() },
+			expected: `(users.metadata #>> '{"it''s \"quoted\""}') IS NULL`,
+		},
+		{
+			name:     "Path",
+			method:   func() Condition { return col.Path("tags").HasKey("go") },
+			expected: `(users.metadata #> '{"tags"}') ?? ?`,
+		},
+		{
+			name:     "PathExists",
+			method:   func() Condition { return col.PathExists("$.tags[*] ? (@ == \"go\")") },
+			expected: "users.metadata @?? ?::jsonpath",
+		},
+		{
+			name:     "PathMatch",
+			method:   func() Condition { return col.PathMatch("$.age > 18") },
+			expected: "users.metadata @@ ?::jsonpath",
 		},
 	}
 
@@ -341,6 +369,58 @@ func TestJSONBColumn(t *testing.T) {
 	}
 }
 
+func TestJSONBColumnQueries(t *testing.T) {
+	col := JSONBColumn{Column: Column[interface{}]{Name: "metadata", Table: "users"}}
+
+	tests := []struct {
+		name         string
+		condition    Condition
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			name:         "key operators keep their question mark",
+			condition:    col.HasKey("active").And(col.HasAnyKey([]string{"admin", "owner"})),
+			expectedSQL:  "SELECT id FROM users WHERE (users.metadata ? $1 AND users.metadata ?| $2)",
+			expectedArgs: []interface{}{"active", pq.Array([]string{"admin", "owner"})},
+		},
+		{
+			name:         "containment is bound as JSON",
+			condition:    col.Contains(map[string]interface{}{"role": "admin"}),
+			expectedSQL:  "SELECT id FROM users WHERE users.metadata @> $1::jsonb",
+			expectedArgs: []interface{}{`{"role":"admin"}`},
+		},
+		{
+			name:         "JSON path",
+			condition:    col.PathExists("$.tags[*]"),
+			expectedSQL:  "SELECT id FROM users WHERE users.metadata @? $1::jsonpath",
+			expectedArgs: []interface{}{"$.tags[*]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, err := squirrel.Select("id").From("users").Where(tt.condition.ToSqlizer()).PlaceholderFormat(squirrel.Dollar).ToSql()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tt.expectedSQL {
+				t.Errorf("expected SQL %q, got %q", tt.expectedSQL, sql)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("expected args %v, got %v", tt.expectedArgs, args)
+			}
+		})
+	}
+
+	t.Run("values that are not JSON fail the query", func(t *testing.T) {
+		_, _, err := col.Contains(make(chan int)).ToSqlizer().ToSql()
+		if err == nil || !strings.Contains(err.Error(), "invalid JSON for users.metadata") {
+			t.Errorf("expected an invalid JSON error, got %v", err)
+		}
+	})
+}
+
 func TestConditionOperations(t *testing.T) {
 	col1 := StringColumn{Column: Column[string]{Name: "name", Table: "users"}}
 	col2 := NumericColumn[int]{
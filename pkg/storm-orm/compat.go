@@ -0,0 +1,148 @@
+package orm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// CodegenVersion is the revision of the generated code format this runtime expects.
+// It is raised when generated code has to be regenerated to keep working.
+const CodegenVersion = 1
+
+// CompatibilityMode sets what happens when generated code does not match its model
+type CompatibilityMode int
+
+const (
+	CompatibilityWarn   CompatibilityMode = iota // Record a warning, see CompatibilityWarnings, and carry on (default)
+	CompatibilityError                           // Fail repository creation
+	CompatibilityIgnore                          // Skip the check
+)
+
+var (
+	compatibilityMu   sync.RWMutex
+	compatibilityMode = CompatibilityWarn
+	checkedModels     sync.Map // *ModelMetadata -> error, checked once per model
+)
+
+// SetCompatibilityMode sets how repositories react to generated code that is older
+// than the model it was generated from. With CompatibilityError the generated
+// NewStorm panics at startup instead of running with stale metadata.
+func SetCompatibilityMode(mode CompatibilityMode) {
+	compatibilityMu.Lock()
+	defer compatibilityMu.Unlock()
+	compatibilityMode = mode
+}
+
+func currentCompatibilityMode() CompatibilityMode {
+	compatibilityMu.RLock()
+	defer compatibilityMu.RUnlock()
+	return compatibilityMode
+}
+
+// CompatibilityWarnings returns the stale generated code found so far, one error
+// per model, sorted by message. In CompatibilityWarn mode repositories are created
+// regardless, so callers check it once their repositories exist.
+func CompatibilityWarnings() []error {
+	var warnings []error
+	checkedModels.Range(func(_, result interface{}) bool {
+		if result != nil {
+			warnings = append(warnings, result.(error))
+		}
+		return true
+	})
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Error() < warnings[j].Error()
+	})
+	return warnings
+}
+
+// FieldSignature is the part of a model field the generated code depends on: its
+// name and the tags that define its column or relationship
+type FieldSignature struct {
+	Name  string
+	DB    string
+	DBDef string
+	Storm string
+	ORM   string
+}
+
+// SchemaHash hashes the fields of a model in declaration order. The generator
+// stamps it into the metadata and the runtime recomputes it from the struct.
+func SchemaHash(fields []FieldSignature) string {
+	h := sha256.New()
+	for _, f := range fields {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\n", f.Name, f.DB, f.DBDef, f.Storm, f.ORM)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// structSchemaHash computes SchemaHash from the exported, non-embedded fields of a
+// struct, the fields the generator reads from the source
+func structSchemaHash(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+
+	fields := make([]FieldSignature, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+		fields = append(fields, FieldSignature{
+			Name:  field.Name,
+			DB:    field.Tag.Get("db"),
+			DBDef: field.Tag.Get("dbdef"),
+			Storm: field.Tag.Get("storm"),
+			ORM:   field.Tag.Get("orm"),
+		})
+	}
+	return SchemaHash(fields)
+}
+
+// checkCompatibility compares the generation stamp of the metadata with the model
+// struct T. Metadata without a stamp, e.g. written by hand, is not checked.
+func checkCompatibility[T any](metadata *ModelMetadata) error {
+	mode := currentCompatibilityMode()
+	if mode == CompatibilityIgnore || metadata.SchemaHash == "" {
+		return nil
+	}
+
+	if result, ok := checkedModels.Load(metadata); ok {
+		if mode == CompatibilityError && result != nil {
+			return result.(error)
+		}
+		return nil
+	}
+
+	err := compareGeneratedCode(metadata, reflect.TypeOf((*T)(nil)).Elem())
+	checkedModels.Store(metadata, err)
+
+	if mode == CompatibilityError {
+		return err
+	}
+	return nil
+}
+
+func compareGeneratedCode(metadata *ModelMetadata, model reflect.Type) error {
+	if metadata.CodegenVersion < CodegenVersion {
+		return fmt.Errorf("%w: %s was generated by storm %s, which this version of the ORM no longer supports; regenerate it with storm orm",
+			ErrStaleGeneratedCode, metadata.StructName, metadata.GeneratorVersion)
+	}
+	if metadata.CodegenVersion > CodegenVersion {
+		return fmt.Errorf("%w: %s was generated by storm %s, which is newer than this version of the ORM; upgrade github.com/eleven-am/storm",
+			ErrStaleGeneratedCode, metadata.StructName, metadata.GeneratorVersion)
+	}
+	if hash := structSchemaHash(model); hash != metadata.SchemaHash {
+		return fmt.Errorf("%w: the fields of %s changed since its code was generated (schema hash %s, generated from %s); regenerate it with storm orm",
+			ErrStaleGeneratedCode, metadata.StructName, hash, metadata.SchemaHash)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package orm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type compatModel struct {
+	ID      int64  `db:"id" dbdef:"type:bigserial;primary_key"`
+	Name    string `db:"name"`
+	Orders  []int  `db:"-" orm:"has_many:Order;foreign_key:model_id"`
+	private string
+}
+
+func compatMetadata(hash string) *ModelMetadata {
+	return &ModelMetadata{
+		TableName:        "compat_models",
+		StructName:       "compatModel",
+		PrimaryKeys:      []string{"id"},
+		GeneratorVersion: "1.0.0-alpha",
+		CodegenVersion:   CodegenVersion,
+		SchemaHash:       hash,
+	}
+}
+
+func TestStructSchemaHash(t *testing.T) {
+	hash := structSchemaHash(reflect.TypeOf(compatModel{}))
+	assert.Len(t, hash, 16)
+	assert.Equal(t, hash, structSchemaHash(reflect.TypeOf(&compatModel{})))
+
+	assert.Equal(t, hash, SchemaHash([]FieldSignature{
+		{Name: "ID", DB: "id", DBDef: "type:bigserial;primary_key"},
+		{Name: "Name", DB: "name"},
+		{Name: "Orders", DB: "-", ORM: "has_many:Order;foreign_key:model_id"},
+	}), "unexported fields are not part of the hash")
+
+	assert.NotEqual(t, hash, SchemaHash([]FieldSignature{
+		{Name: "ID", DB: "id", DBDef: "type:bigserial;primary_key"},
+		{Name: "Name", DB: "full_name"},
+		{Name: "Orders", DB: "-", ORM: "has_many:Order;foreign_key:model_id"},
+	}), "a renamed column changes the hash")
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	current := structSchemaHash(reflect.TypeOf(compatModel{}))
+	defer SetCompatibilityMode(CompatibilityWarn)
+
+	t.Run("matching code", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityError)
+		_, err := NewRepository[compatModel](sqlxDB, compatMetadata(current))
+		assert.NoError(t, err)
+	})
+
+	t.Run("unstamped metadata is not checked", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityError)
+		_, err := NewRepository[compatModel](sqlxDB, compatMetadata(""))
+		assert.NoError(t, err)
+	})
+
+	t.Run("changed model fails in error mode", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityError)
+		_, err := NewRepository[compatModel](sqlxDB, compatMetadata("0123456789abcdef"))
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrStaleGeneratedCode)
+		assert.Contains(t, err.Error(), "fields of compatModel changed")
+	})
+
+	t.Run("old code format fails in error mode", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityError)
+		metadata := compatMetadata(current)
+		metadata.CodegenVersion = CodegenVersion - 1
+		_, err := NewRepository[compatModel](sqlxDB, metadata)
+		assert.ErrorIs(t, err, ErrStaleGeneratedCode)
+	})
+
+	t.Run("changed model warns once in warn mode", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityWarn)
+		metadata := compatMetadata("fedcba9876543210")
+		for i := 0; i < 2; i++ {
+			_, err := NewRepository[compatModel](sqlxDB, metadata)
+			assert.NoError(t, err)
+		}
+
+		count := 0
+		for _, warning := range CompatibilityWarnings() {
+			if strings.Contains(warning.Error(), "generated from fedcba9876543210") {
+				assert.ErrorIs(t, warning, ErrStaleGeneratedCode)
+				count++
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("ignore mode skips the check", func(t *testing.T) {
+		SetCompatibilityMode(CompatibilityIgnore)
+		_, err := NewRepository[compatModel](sqlxDB, compatMetadata("ffffffffffffffff"))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,172 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// RecordWithCounts is a record returned by FindWithCounts with the number of its
+// related records, keyed by relationship name
+type RecordWithCounts[T any] struct {
+	Record T
+	Counts map[string]int64
+}
+
+// WithCount counts the related records of each relationship for every record
+// returned by FindWithCounts:
+//
+//	Users.Query(ctx).WithCount("Posts").FindWithCounts()
+func (q *Query[T]) WithCount(relationships ...string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	for _, rel := range relationships {
+		q.counts = append(q.counts, include{
+			name:       rel,
+			conditions: make([]Condition, 0),
+		})
+	}
+	return q
+}
+
+// WithCountWhere counts only the related records matching all the conditions
+func (q *Query[T]) WithCountWhere(relationship string, conditions ...Condition) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.counts = append(q.counts, include{
+		name:       relationship,
+		conditions: conditions,
+	})
+	return q
+}
+
+// FindWithCounts runs the query like Find and returns each record with the counts
+// requested by WithCount. The counts are correlated subqueries of the same SELECT,
+// so no query is run per record.
+func (q *Query[T]) FindWithCounts() ([]RecordWithCounts[T], error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	builder := q.applyProjections(q.builder)
+	aliases := make(map[string]string, len(q.counts))
+	for i, count := range q.counts {
+		rel := q.repo.getRelationship(count.name)
+		if rel == nil {
+			return nil, fmt.Errorf("relationship %s not found", count.name)
+		}
+
+		subquery, err := q.relatedSubquery(rel, "COUNT(*)")
+		if err != nil {
+			return nil, err
+		}
+		for _, condition := range count.conditions {
+			subquery = subquery.Where(condition.ToSqlizer())
+		}
+
+		alias := fmt.Sprintf("storm_count_%d", i)
+		aliases[alias] = count.name
+		builder = builder.Column(squirrel.Alias(subquery, alias))
+	}
+	builder = q.applyClauses(builder)
+
+	var results []RecordWithCounts[T]
+	err := q.repo.executeQueryMiddleware(OpQuery, "find_with_counts", q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		rows, err := q.reader().QueryxContext(q.ctx, sqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to execute query: %w", err), "find", q.repo.metadata.TableName)
+		}
+		defer rows.Close()
+
+		results, err = scanWithCounts[T](rows, aliases)
+		if err != nil {
+			return &Error{
+				Op:    "find",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to scan results: %w", err),
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(q.includes) > 0 && len(results) > 0 {
+		records := make([]T, len(results))
+		for i := range results {
+			records[i] = results[i].Record
+		}
+		for _, include := range q.includes {
+			if err := q.loadRelationship(records, include); err != nil {
+				return nil, fmt.Errorf("failed to load relationship %s: %w", include.name, err)
+			}
+		}
+		for i := range results {
+			results[i].Record = records[i]
+		}
+	}
+
+	return results, nil
+}
+
+// scanWithCounts scans the model columns of each row into a record and the count
+// columns, named by aliases, into its counts
+func scanWithCounts[T any](rows *sqlx.Rows, aliases map[string]string) ([]RecordWithCounts[T], error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var zero T
+	traversals := rows.Mapper.TraversalsByName(reflect.TypeOf(zero), columns)
+	for i, column := range columns {
+		if _, ok := aliases[column]; !ok && len(traversals[i]) == 0 {
+			return nil, fmt.Errorf("missing destination name %s in %T", column, zero)
+		}
+	}
+
+	results := make([]RecordWithCounts[T], 0)
+	for rows.Next() {
+		result := RecordWithCounts[T]{Counts: make(map[string]int64, len(aliases))}
+		record := reflect.ValueOf(&result.Record).Elem()
+
+		counts := make([]int64, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if _, ok := aliases[column]; ok {
+				dest[i] = &counts[i]
+				continue
+			}
+			dest[i] = reflectx.FieldByIndexes(record, traversals[i]).Addr().Interface()
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, column := range columns {
+			if name, ok := aliases[column]; ok {
+				result.Counts[name] = counts[i]
+			}
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
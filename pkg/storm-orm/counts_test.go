@@ -0,0 +1,83 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFindWithCounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name:        "Posts",
+			Type:        "has_many",
+			Target:      "Post",
+			TargetTable: "posts",
+			ForeignKey:  "author_id",
+			SourceKey:   "id",
+		},
+		"Tags": {
+			Name:        "Tags",
+			Type:        "has_many_through",
+			Target:      "Tag",
+			TargetTable: "tags",
+			Through:     "user_tags",
+			ThroughFK:   "user_id",
+			ThroughTK:   "tag_id",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+	ctx := context.Background()
+	published := BoolColumn{Column: Column[bool]{Name: "published", Table: "posts"}}
+
+	t.Run("counts are selected as subqueries", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .*, \(SELECT COUNT\(\*\) FROM posts WHERE posts.author_id = users.id AND posts.published = \$1\) AS storm_count_0, `+
+			`\(SELECT COUNT\(\*\) FROM user_tags JOIN tags ON tags.id = user_tags.tag_id WHERE user_tags.user_id = users.id\) AS storm_count_1 `+
+			`FROM users WHERE \(users.is_active = \$2\)`).
+			WithArgs(true, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "storm_count_0", "storm_count_1"}).
+				AddRow(1, "alice", 3, 1).
+				AddRow(2, "bob", 0, 2))
+
+		results, err := repo.Query(ctx).
+			WithCountWhere("Posts", published.Eq(true)).
+			WithCount("Tags").
+			Where(Condition{squirrel.Eq{"users.is_active": true}}).
+			FindWithCounts()
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		assert.Equal(t, "alice", results[0].Record.Name)
+		assert.Equal(t, map[string]int64{"Posts": 3, "Tags": 1}, results[0].Counts)
+		assert.Equal(t, 2, results[1].Record.ID)
+		assert.Equal(t, map[string]int64{"Posts": 0, "Tags": 2}, results[1].Counts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "nickname"}).AddRow(1, "al"))
+
+		_, err := repo.Query(ctx).WithCount("Posts").FindWithCounts()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing destination name nickname")
+	})
+
+	t.Run("unknown relationship", func(t *testing.T) {
+		_, err := repo.Query(ctx).WithCount("Comments").FindWithCounts()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "relationship Comments not found")
+	})
+}
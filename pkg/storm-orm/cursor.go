@@ -0,0 +1,277 @@
+package orm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// CursorPage is a page of records returned by CursorPaginate
+type CursorPage[T any] struct {
+	Records    []T
+	NextCursor string // Cursor of the page after this one, empty on the last page
+	PrevCursor string // Cursor of the page before this one, empty on the first page
+}
+
+// cursorKey is a column the records of a cursor page are ordered by
+type cursorKey struct {
+	column *ColumnMetadata
+	desc   bool
+}
+
+// cursorToken is what an opaque cursor holds: the values of the ordering columns of
+// the record to continue from, and the direction to continue in
+type cursorToken struct {
+	Columns  []string `json:"c"`
+	Values   []string `json:"v"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// CursorPaginate returns pageSize records following cursor, or the first page for an
+// empty cursor. Instead of skipping rows with OFFSET, which PostgreSQL has to read and
+// discard, the page starts with a condition on the ORDER BY columns, so a deep page
+// costs as much as the first when an index covers them:
+//
+//	page, err := Users.Query(ctx).Where(Users.IsActive.IsTrue()).OrderBy("created_at DESC").CursorPaginate(r.URL.Query().Get("cursor"), 50)
+//	// respond with page.Records, page.NextCursor and page.PrevCursor
+//
+// The query must be ordered by columns of the model, each optionally followed by ASC
+// or DESC, and the primary key is added to the ordering to tell records apart; without
+// OrderBy, records are ordered by their primary key. The ordering columns must not be
+// NULL. A cursor only fits a query with the same ordering, otherwise the call fails
+// with an error wrapping ErrInvalidCursor. Limit and Offset of the query are replaced.
+func (q *Query[T]) CursorPaginate(cursor string, pageSize int) (*CursorPage[T], error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if pageSize < 1 {
+		return nil, q.cursorError(fmt.Errorf("page size must be at least 1, got %d", pageSize))
+	}
+
+	keys, err := q.cursorKeys()
+	if err != nil {
+		return nil, q.cursorError(err)
+	}
+
+	var token cursorToken
+	if cursor != "" {
+		if token, err = decodeCursor(cursor, keys); err != nil {
+			return nil, q.cursorError(err)
+		}
+		values, err := cursorValues(keys, token.Values)
+		if err != nil {
+			return nil, q.cursorError(fmt.Errorf("%w: %v", ErrInvalidCursor, err))
+		}
+		q.Where(Condition{q.keysetCondition(keys, values, token.Backward)})
+	}
+
+	// A backward page is read in reverse order and flipped afterwards
+	q.orderBy = make([]orderBy, len(keys))
+	for i, key := range keys {
+		direction := "ASC"
+		if key.desc != token.Backward {
+			direction = "DESC"
+		}
+		q.orderBy[i] = orderBy{sql: q.repo.metadata.TableName + "." + key.column.DBName + " " + direction}
+	}
+	// One record more tells whether there is a page after this one
+	q.Limit(uint64(pageSize) + 1)
+	q.offset = nil
+
+	records, err := q.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(records) > pageSize
+	if more {
+		records = records[:pageSize]
+	}
+	if token.Backward {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+	}
+
+	page := &CursorPage[T]{Records: records}
+	if len(records) == 0 {
+		return page, nil
+	}
+
+	// Coming from a cursor, there are records on the side the cursor came from
+	hasNext, hasPrev := more, cursor != ""
+	if token.Backward {
+		hasNext, hasPrev = cursor != "", more
+	}
+	if hasNext {
+		if page.NextCursor, err = encodeCursor(keys, records[len(records)-1], false); err != nil {
+			return nil, q.cursorError(err)
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = encodeCursor(keys, records[0], true); err != nil {
+			return nil, q.cursorError(err)
+		}
+	}
+	return page, nil
+}
+
+// cursorKeys returns the columns the query is ordered by, followed by the primary key
+// columns it does not order by already
+func (q *Query[T]) cursorKeys() ([]cursorKey, error) {
+	metadata := q.repo.metadata
+	var keys []cursorKey
+	seen := make(map[string]bool)
+
+	for _, order := range q.orderBy {
+		fields := strings.Fields(order.sql)
+		if len(order.args) > 0 || len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("cannot paginate by %q, order by columns of the model", order.sql)
+		}
+
+		desc := false
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				desc = true
+			default:
+				return nil, fmt.Errorf("cannot paginate by %q, order by columns of the model", order.sql)
+			}
+		}
+
+		column := columnByName(metadata, strings.TrimPrefix(fields[0], metadata.TableName+"."))
+		if column == nil {
+			return nil, fmt.Errorf("cannot paginate by %q, order by columns of the model", order.sql)
+		}
+		if !seen[column.DBName] {
+			seen[column.DBName] = true
+			keys = append(keys, cursorKey{column: column, desc: desc})
+		}
+	}
+
+	if len(metadata.PrimaryKeys) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	// The primary key follows the direction of the last ordering, keeping a single
+	// direction that a row comparison can use
+	desc := len(keys) > 0 && keys[len(keys)-1].desc
+	for _, pk := range metadata.PrimaryKeys {
+		if seen[pk] {
+			continue
+		}
+		column := columnByName(metadata, pk)
+		if column == nil {
+			return nil, fmt.Errorf("primary key column %s not found", pk)
+		}
+		keys = append(keys, cursorKey{column: column, desc: desc})
+	}
+	return keys, nil
+}
+
+// keysetCondition matches the records that come after values in the ordering of keys,
+// or before them when backward is set. When the keys share a direction, a row
+// comparison is used, which PostgreSQL can answer from an index on the keys.
+func (q *Query[T]) keysetCondition(keys []cursorKey, values []interface{}, backward bool) squirrel.Sqlizer {
+	operator := func(desc bool) string {
+		if desc != backward {
+			return "<"
+		}
+		return ">"
+	}
+
+	columns := make([]string, len(keys))
+	sameDirection := true
+	for i, key := range keys {
+		columns[i] = q.repo.metadata.TableName + "." + key.column.DBName
+		sameDirection = sameDirection && key.desc == keys[0].desc
+	}
+
+	if sameDirection {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+		return squirrel.Expr(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator(keys[0].desc), placeholders), values...)
+	}
+
+	// (a > ?) OR (a = ? AND b < ?) OR ...
+	or := make(squirrel.Or, len(keys))
+	for i, key := range keys {
+		and := make(squirrel.And, 0, i+1)
+		for j := 0; j < i; j++ {
+			and = append(and, squirrel.Expr(columns[j]+" = ?", values[j]))
+		}
+		or[i] = append(and, squirrel.Expr(columns[i]+" "+operator(key.desc)+" ?", values[i]))
+	}
+	return or
+}
+
+// encodeCursor returns the cursor continuing from record in the given direction
+func encodeCursor[T any](keys []cursorKey, record T, backward bool) (string, error) {
+	token := cursorToken{Backward: backward}
+	for _, key := range keys {
+		value := key.column.GetValue(record)
+		if value == nil {
+			return "", fmt.Errorf("cannot paginate by %s, which is NULL", key.column.DBName)
+		}
+		token.Columns = append(token.Columns, key.column.DBName)
+		if t, ok := value.(time.Time); ok {
+			token.Values = append(token.Values, t.Format(time.RFC3339Nano))
+		} else {
+			token.Values = append(token.Values, fmt.Sprint(value))
+		}
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor reads cursor, checking it was made for the ordering of keys
+func decodeCursor(cursor string, keys []cursorKey) (cursorToken, error) {
+	var token cursorToken
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return token, fmt.Errorf("%w: not a cursor", ErrInvalidCursor)
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return token, fmt.Errorf("%w: not a cursor", ErrInvalidCursor)
+	}
+
+	if len(token.Columns) != len(keys) || len(token.Values) != len(keys) {
+		return token, fmt.Errorf("%w: the cursor was made for another ordering", ErrInvalidCursor)
+	}
+	for i, key := range keys {
+		if token.Columns[i] != key.column.DBName {
+			return token, fmt.Errorf("%w: the cursor was made for another ordering", ErrInvalidCursor)
+		}
+	}
+	return token, nil
+}
+
+// cursorValues converts the values of a cursor to the Go types of their columns
+func cursorValues(keys []cursorKey, raw []string) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		column := *key.column
+		column.GoType = strings.TrimPrefix(column.GoType, "*")
+		value, err := filterValue(&column, raw[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (q *Query[T]) cursorError(err error) error {
+	return &Error{
+		Op:    "cursor_paginate",
+		Table: q.repo.metadata.TableName,
+		Err:   err,
+	}
+}
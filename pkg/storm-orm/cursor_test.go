@@ -0,0 +1,106 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorPaginate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "name", "created_at"}
+	userRows := func(ids ...int) *sqlmock.Rows {
+		rows := sqlmock.NewRows(columns)
+		for _, id := range ids {
+			rows.AddRow(id, "user", day.Add(-time.Duration(id)*time.Hour))
+		}
+		return rows
+	}
+	query := func() *Query[TestUser] {
+		return repo.Query(context.Background()).OrderBy("created_at DESC")
+	}
+
+	var next string
+	t.Run("first page", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users ORDER BY users.created_at DESC, users.id DESC LIMIT 3$`).
+			WillReturnRows(userRows(1, 2, 3))
+
+		page, err := query().CursorPaginate("", 2)
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+		assert.Equal(t, 1, page.Records[0].ID)
+		assert.NotEmpty(t, page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
+		next = page.NextCursor
+	})
+
+	var prev string
+	t.Run("next page continues after the last record", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(\(users.created_at, users.id\) < \(\$1, \$2\)\) ORDER BY users.created_at DESC, users.id DESC LIMIT 3$`).
+			WithArgs(day.Add(-2*time.Hour), int64(2)).
+			WillReturnRows(userRows(3))
+
+		page, err := query().CursorPaginate(next, 2)
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		assert.Empty(t, page.NextCursor)
+		assert.NotEmpty(t, page.PrevCursor)
+		prev = page.PrevCursor
+	})
+
+	t.Run("previous page is read backwards", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(\(users.created_at, users.id\) > \(\$1, \$2\)\) ORDER BY users.created_at ASC, users.id ASC LIMIT 3$`).
+			WithArgs(day.Add(-3*time.Hour), int64(3)).
+			WillReturnRows(userRows(2, 1))
+
+		page, err := query().CursorPaginate(prev, 2)
+		require.NoError(t, err)
+		require.Len(t, page.Records, 2)
+		assert.Equal(t, []int{1, 2}, []int{page.Records[0].ID, page.Records[1].ID})
+		assert.NotEmpty(t, page.NextCursor)
+		assert.Empty(t, page.PrevCursor)
+	})
+
+	t.Run("mixed directions expand the comparison", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE \(\(\(users.name > \$1\) OR \(users.name = \$2 AND users.id < \$3\)\)\) ORDER BY users.name ASC, users.id DESC LIMIT 11$`).
+			WithArgs("user", "user", int64(5)).
+			WillReturnRows(userRows())
+
+		cursor, err := encodeCursor([]cursorKey{
+			{column: columnByName(repo.metadata, "name")},
+			{column: columnByName(repo.metadata, "id"), desc: true},
+		}, TestUser{ID: 5, Name: "user"}, false)
+		require.NoError(t, err)
+
+		page, err := repo.Query(context.Background()).OrderBy("name", "users.id DESC").CursorPaginate(cursor, 10)
+		require.NoError(t, err)
+		assert.Empty(t, page.Records)
+	})
+
+	t.Run("refuses a cursor of another ordering", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).OrderBy("name").CursorPaginate(next, 2)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+
+		_, err = query().CursorPaginate("not a cursor", 2)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+
+	t.Run("refuses to order by expressions", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).OrderBy("lower(name)").CursorPaginate("", 2)
+		assert.ErrorContains(t, err, "order by columns of the model")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
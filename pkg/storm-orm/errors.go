@@ -5,20 +5,46 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 // Common errors
 var (
-	ErrNotFound         = errors.New("record not found")
-	ErrInvalidStruct    = errors.New("invalid struct type")
-	ErrNoPrimaryKey     = errors.New("no primary key defined")
-	ErrDuplicateKey     = errors.New("duplicate key violation")
-	ErrForeignKey       = errors.New("foreign key violation")
-	ErrCheckConstraint  = errors.New("check constraint violation")
-	ErrNotNull          = errors.New("not null constraint violation")
-	ErrConnectionFailed = errors.New("database connection failed")
-	ErrTimeout          = errors.New("operation timeout")
-	ErrCanceled         = errors.New("operation canceled")
+	ErrNotFound           = errors.New("record not found")
+	ErrInvalidStruct      = errors.New("invalid struct type")
+	ErrNoPrimaryKey       = errors.New("no primary key defined")
+	ErrDuplicateKey       = errors.New("duplicate key violation")
+	ErrForeignKey         = errors.New("foreign key violation")
+	ErrCheckConstraint    = errors.New("check constraint violation")
+	ErrNotNull            = errors.New("not null constraint violation")
+	ErrConnectionFailed   = errors.New("database connection failed")
+	ErrTimeout            = errors.New("operation timeout")
+	ErrCanceled           = errors.New("operation canceled")
+	ErrStaleGeneratedCode = errors.New("generated code is out of date")
+	ErrNoConditions       = errors.New("query has no conditions")
+	ErrInvalidFilter      = errors.New("invalid filter")
+	ErrInvalidCursor      = errors.New("invalid cursor")
+
+	ErrSerializationFailure = errors.New("could not serialize access due to concurrent update")
+	ErrDeadlock             = errors.New("deadlock detected")
+
+	// Names matching the PostgreSQL condition names, for the errors above
+	ErrUniqueViolation     = ErrDuplicateKey
+	ErrForeignKeyViolation = ErrForeignKey
+	ErrCheckViolation      = ErrCheckConstraint
+	ErrNotNullViolation    = ErrNotNull
+)
+
+// PostgreSQL error codes mapped to the errors above
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgCheckViolation       = "23514"
+	pgNotNullViolation     = "23502"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgQueryCanceled        = "57014"
 )
 
 // Error provides detailed error information
@@ -30,6 +56,8 @@ type Error struct {
 	Args       []interface{} // Query arguments (if applicable)
 	Constraint string        // Constraint name (if applicable)
 	Column     string        // Column name (if applicable)
+	Columns    []string      // Columns of the violated key (if applicable)
+	Fields     []string      // Go fields of the violated columns (if applicable)
 	Retryable  bool          // Whether the operation can be retried
 }
 
@@ -87,6 +115,13 @@ func parsePostgreSQLError(err error, op, table string) error {
 		}
 	}
 
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if mapped := parsePQError(pqErr, op, table); mapped != nil {
+			return mapped
+		}
+	}
+
 	errStr := err.Error()
 
 	if strings.Contains(errStr, "duplicate key value violates unique constraint") {
@@ -170,6 +205,77 @@ func parsePostgreSQLError(err error, op, table string) error {
 	}
 }
 
+// parsePQError maps a PostgreSQL error by its SQLSTATE code, which unlike the
+// message does not depend on the server locale. It returns nil for codes
+// without a typed error.
+func parsePQError(pqErr *pq.Error, op, table string) error {
+	mapped := &Error{
+		Op:         op,
+		Table:      table,
+		Constraint: pqErr.Constraint,
+		Column:     pqErr.Column,
+	}
+	if mapped.Table == "" {
+		mapped.Table = pqErr.Table
+	}
+	if mapped.Column == "" {
+		mapped.Column = extractColumnName(pqErr.Message)
+	}
+
+	switch string(pqErr.Code) {
+	case pgUniqueViolation, pgForeignKeyViolation, pgCheckViolation:
+		if mapped.Constraint == "" {
+			mapped.Constraint = extractConstraintName(pqErr.Message)
+		}
+	}
+
+	switch string(pqErr.Code) {
+	case pgUniqueViolation:
+		mapped.Err = ErrUniqueViolation
+		mapped.Columns = extractKeyColumns(pqErr.Detail)
+	case pgForeignKeyViolation:
+		mapped.Err = ErrForeignKeyViolation
+		mapped.Columns = extractKeyColumns(pqErr.Detail)
+	case pgCheckViolation:
+		mapped.Err = ErrCheckViolation
+	case pgNotNullViolation:
+		mapped.Err = ErrNotNullViolation
+	case pgSerializationFailure:
+		mapped.Err = ErrSerializationFailure
+		mapped.Retryable = true
+	case pgDeadlockDetected:
+		mapped.Err = ErrDeadlock
+		mapped.Retryable = true
+	case pgQueryCanceled:
+		mapped.Err = ErrTimeout
+		mapped.Retryable = true
+	default:
+		return nil
+	}
+
+	return mapped
+}
+
+// extractKeyColumns reads the columns from a key detail such as
+// "Key (tenant_id, email)=(1, a@b.c) already exists."
+func extractKeyColumns(detail string) []string {
+	start := strings.Index(detail, "Key (")
+	if start == -1 {
+		return nil
+	}
+	start += len("Key (")
+	end := strings.Index(detail[start:], ")=")
+	if end == -1 {
+		return nil
+	}
+
+	columns := strings.Split(detail[start:start+end], ",")
+	for i := range columns {
+		columns[i] = strings.Trim(strings.TrimSpace(columns[i]), `"`)
+	}
+	return columns
+}
+
 func extractConstraintName(errStr string) string {
 
 	start := strings.Index(errStr, "\"")
@@ -230,6 +336,12 @@ func IsRetryable(err error) bool {
 	return false
 }
 
+// IsSerializationError reports whether err is a serialization failure or a
+// deadlock, after which the whole transaction can be retried
+func IsSerializationError(err error) bool {
+	return errors.Is(err, ErrSerializationFailure) || errors.Is(err, ErrDeadlock)
+}
+
 func IsConstraintError(err error) bool {
 	return errors.Is(err, ErrDuplicateKey) ||
 		errors.Is(err, ErrForeignKey) ||
@@ -252,3 +364,65 @@ func GetColumnName(err error) string {
 	}
 	return ""
 }
+
+// GetFieldNames returns the Go fields of the model involved in a constraint
+// violation, e.g. []string{"Email"} for a duplicate email, so that callers can
+// report the failure against the fields a user submitted
+func GetFieldNames(err error) []string {
+	var ormErr *Error
+	if errors.As(err, &ormErr) {
+		return ormErr.Fields
+	}
+	return nil
+}
+
+// resolveFields sets the Go fields of a constraint violation on the table of the
+// metadata. The columns come from the error when PostgreSQL reports them and from
+// the constraint otherwise: first its generated entry in the metadata, then
+// PostgreSQL's default <table>_<column>_key, _fkey and _check names.
+func (m *ModelMetadata) resolveFields(err error) error {
+	var ormErr *Error
+	if !errors.As(err, &ormErr) || len(ormErr.Fields) > 0 {
+		return err
+	}
+	if ormErr.Table != "" && ormErr.Table != m.TableName {
+		return err
+	}
+
+	columns := ormErr.Columns
+	if len(columns) == 0 && ormErr.Column != "" {
+		columns = []string{ormErr.Column}
+	}
+	if len(columns) == 0 && ormErr.Constraint != "" {
+		columns = m.constraintColumns(ormErr.Constraint)
+	}
+
+	for _, column := range columns {
+		if field, ok := m.ReverseMap[column]; ok {
+			ormErr.Fields = append(ormErr.Fields, field)
+		}
+	}
+	return err
+}
+
+func (m *ModelMetadata) constraintColumns(constraint string) []string {
+	if columns, ok := m.Constraints[constraint]; ok {
+		return columns
+	}
+	if constraint == m.TableName+"_pkey" {
+		return m.PrimaryKeys
+	}
+
+	name, ok := strings.CutPrefix(constraint, m.TableName+"_")
+	if !ok {
+		return nil
+	}
+	for _, suffix := range []string{"_key", "_fkey", "_check"} {
+		if column, ok := strings.CutSuffix(name, suffix); ok {
+			if _, known := m.ReverseMap[column]; known {
+				return []string{column}
+			}
+		}
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package orm
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/lib/pq"
@@ -156,6 +159,70 @@ func TestParsePostgreSQLError(t *testing.T) {
 	}
 }
 
+func TestParsePostgreSQLError_Codes(t *testing.T) {
+	t.Run("unique violation with key columns", func(t *testing.T) {
+		err := parsePostgreSQLError(&pq.Error{
+			Code:       "23505",
+			Message:    "duplicate key value violates unique constraint \"users_tenant_email_key\"",
+			Detail:     "Key (tenant_id, email)=(1, test@example.com) already exists.",
+			Constraint: "users_tenant_email_key",
+		}, "create", "users")
+
+		var ormErr *Error
+		if !errors.As(err, &ormErr) {
+			t.Fatalf("expected *Error, got %T", err)
+		}
+		if !errors.Is(err, ErrUniqueViolation) || !errors.Is(err, ErrDuplicateKey) {
+			t.Errorf("expected a unique violation, got %v", err)
+		}
+		if ormErr.Constraint != "users_tenant_email_key" {
+			t.Errorf("expected constraint users_tenant_email_key, got %q", ormErr.Constraint)
+		}
+		if len(ormErr.Columns) != 2 || ormErr.Columns[0] != "tenant_id" || ormErr.Columns[1] != "email" {
+			t.Errorf("expected columns [tenant_id email], got %v", ormErr.Columns)
+		}
+	})
+
+	t.Run("localized message", func(t *testing.T) {
+		err := parsePostgreSQLError(&pq.Error{
+			Code:       "23503",
+			Message:    "une instruction insert ou update sur la table « posts » viole la contrainte de clé étrangère",
+			Constraint: "posts_user_id_fkey",
+			Table:      "posts",
+		}, "commit", "")
+		if !errors.Is(err, ErrForeignKeyViolation) {
+			t.Errorf("expected a foreign key violation, got %v", err)
+		}
+		if GetConstraintName(err) != "posts_user_id_fkey" {
+			t.Errorf("expected constraint posts_user_id_fkey, got %q", GetConstraintName(err))
+		}
+		if !strings.Contains(err.Error(), "table=posts") {
+			t.Errorf("expected the table of the driver error, got %q", err.Error())
+		}
+	})
+
+	t.Run("wrapped driver errors", func(t *testing.T) {
+		err := parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", &pq.Error{Code: "40001"}), "commit", "")
+		if !errors.Is(err, ErrSerializationFailure) || !IsSerializationError(err) || !IsRetryable(err) {
+			t.Errorf("expected a retryable serialization failure, got %v", err)
+		}
+	})
+
+	t.Run("deadlock", func(t *testing.T) {
+		err := parsePostgreSQLError(&pq.Error{Code: "40P01"}, "update", "users")
+		if !errors.Is(err, ErrDeadlock) || !IsSerializationError(err) || !IsRetryable(err) {
+			t.Errorf("expected a retryable deadlock, got %v", err)
+		}
+	})
+
+	t.Run("check violation", func(t *testing.T) {
+		err := parsePostgreSQLError(&pq.Error{Code: "23514", Constraint: "products_price_check"}, "create", "products")
+		if !errors.Is(err, ErrCheckViolation) || IsRetryable(err) {
+			t.Errorf("expected a check violation, got %v", err)
+		}
+	})
+}
+
 func TestValidationError(t *testing.T) {
 	err := ValidationError{
 		Field:   "email",
@@ -346,3 +413,65 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestResolveFields(t *testing.T) {
+	metadata := &ModelMetadata{
+		TableName:   "users",
+		ReverseMap:  map[string]string{"id": "ID", "tenant_id": "TenantID", "email": "Email", "age": "Age"},
+		PrimaryKeys: []string{"id"},
+		Constraints: map[string][]string{"uq_tenant_email": {"tenant_id", "email"}},
+	}
+
+	tests := []struct {
+		name string
+		err  *Error
+		want []string
+	}{
+		{
+			name: "columns reported by the database",
+			err:  &Error{Table: "users", Err: ErrUniqueViolation, Columns: []string{"tenant_id", "email"}},
+			want: []string{"TenantID", "Email"},
+		},
+		{
+			name: "not-null column",
+			err:  &Error{Table: "users", Err: ErrNotNullViolation, Column: "email"},
+			want: []string{"Email"},
+		},
+		{
+			name: "generated constraint",
+			err:  &Error{Table: "users", Err: ErrUniqueViolation, Constraint: "uq_tenant_email"},
+			want: []string{"TenantID", "Email"},
+		},
+		{
+			name: "default constraint name",
+			err:  &Error{Table: "users", Err: ErrCheckViolation, Constraint: "users_age_check"},
+			want: []string{"Age"},
+		},
+		{
+			name: "primary key",
+			err:  &Error{Table: "users", Err: ErrUniqueViolation, Constraint: "users_pkey"},
+			want: []string{"ID"},
+		},
+		{
+			name: "unknown constraint",
+			err:  &Error{Table: "users", Err: ErrCheckViolation, Constraint: "users_check"},
+		},
+		{
+			name: "other table",
+			err:  &Error{Table: "orders", Err: ErrNotNullViolation, Column: "email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := metadata.resolveFields(fmt.Errorf("wrapped: %w", tt.err))
+			if got := GetFieldNames(err); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetFieldNames() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := metadata.resolveFields(nil); err != nil {
+		t.Errorf("resolveFields(nil) = %v, want nil", err)
+	}
+}
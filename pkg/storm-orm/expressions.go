@@ -0,0 +1,306 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Expression is a typed SQL expression with its parameters, such as a CASE or a
+// COALESCE, usable in conditions, ordering, updates and the selected columns:
+//
+//	name := orm.Coalesce(Users.Nickname.Expression(), Users.Name.Expression())
+//	Users.Query(ctx).OrderByExpr(name.Asc()).Find()
+type Expression[T any] struct {
+	sql  string
+	args []interface{}
+	err  error
+}
+
+// Expression returns the column as an expression, to combine with others
+func (c Column[T]) Expression() Expression[T] {
+	return Expression[T]{sql: c.String()}
+}
+
+// Value returns value as an expression, bound as a parameter
+func Value[T any](value T) Expression[T] {
+	return Expression[T]{sql: "?", args: []interface{}{value}}
+}
+
+// Coalesce returns the first of the expressions that is not NULL
+func Coalesce[T any](first Expression[T], rest ...Expression[T]) Expression[T] {
+	return callExpression("COALESCE", append([]Expression[T]{first}, rest...))
+}
+
+// NullIf returns NULL when value equals other, and value otherwise
+func NullIf[T any](value, other Expression[T]) Expression[T] {
+	return callExpression("NULLIF", []Expression[T]{value, other})
+}
+
+// Greatest returns the largest of the expressions, ignoring NULLs
+func Greatest[T any](first Expression[T], rest ...Expression[T]) Expression[T] {
+	return callExpression("GREATEST", append([]Expression[T]{first}, rest...))
+}
+
+// Least returns the smallest of the expressions, ignoring NULLs
+func Least[T any](first Expression[T], rest ...Expression[T]) Expression[T] {
+	return callExpression("LEAST", append([]Expression[T]{first}, rest...))
+}
+
+// Lower returns value in lower case, to order or compare text regardless of case:
+//
+//	Users.Query(ctx).OrderByExpr(orm.Lower(Users.Name.Expression()).Asc()).Find()
+func Lower(value Expression[string]) Expression[string] {
+	return callExpression("LOWER", []Expression[string]{value})
+}
+
+// Upper returns value in upper case
+func Upper(value Expression[string]) Expression[string] {
+	return callExpression("UPPER", []Expression[string]{value})
+}
+
+// Text returns the value under key of the JSONB column as text, with the key bound as
+// a parameter, so keys coming from requests are safe to use
+func (c JSONBColumn) Text(key string) Expression[string] {
+	return Expression[string]{sql: "(" + c.String() + " ->> ?)", args: []interface{}{key}}
+}
+
+func callExpression[T any](function string, expressions []Expression[T]) Expression[T] {
+	parts := make([]string, len(expressions))
+	var args []interface{}
+	for i, expr := range expressions {
+		if expr.err != nil {
+			return Expression[T]{err: expr.err}
+		}
+		parts[i] = expr.sql
+		args = append(args, expr.args...)
+	}
+	return Expression[T]{sql: function + "(" + strings.Join(parts, ", ") + ")", args: args}
+}
+
+// CaseBuilder builds a CASE expression one WHEN at a time; see Case
+type CaseBuilder[T any] struct {
+	whens []string
+	args  []interface{}
+	err   error
+}
+
+// Case starts a CASE expression returning T:
+//
+//	tier := orm.Case[string]().
+//		When(Users.Points.Gte(1000), orm.Value("gold")).
+//		When(Users.Points.Gte(100), orm.Value("silver")).
+//		Else(orm.Value("bronze"))
+func Case[T any]() CaseBuilder[T] {
+	return CaseBuilder[T]{}
+}
+
+// When returns result for the rows matching condition that no earlier When matched
+func (b CaseBuilder[T]) When(condition Condition, result Expression[T]) CaseBuilder[T] {
+	if b.err != nil {
+		return b
+	}
+	if result.err != nil {
+		b.err = result.err
+		return b
+	}
+	sql, args, err := condition.ToSqlizer().ToSql()
+	if err != nil {
+		b.err = fmt.Errorf("invalid CASE condition: %w", err)
+		return b
+	}
+
+	// The builder is a value, so copy what an earlier copy may share
+	b.whens = append(append([]string{}, b.whens...), "WHEN "+sql+" THEN "+result.sql)
+	b.args = append(append(append([]interface{}{}, b.args...), args...), result.args...)
+	return b
+}
+
+// Else ends the expression, returning result for the rows no When matched
+func (b CaseBuilder[T]) Else(result Expression[T]) Expression[T] {
+	return b.build(" ELSE "+result.sql, result.args)
+}
+
+// End ends the expression, returning NULL for the rows no When matched
+func (b CaseBuilder[T]) End() Expression[T] {
+	return b.build("", nil)
+}
+
+func (b CaseBuilder[T]) build(elseSQL string, elseArgs []interface{}) Expression[T] {
+	if b.err != nil {
+		return Expression[T]{err: b.err}
+	}
+	if len(b.whens) == 0 {
+		return Expression[T]{err: fmt.Errorf("CASE expression needs at least one When")}
+	}
+	return Expression[T]{
+		sql:  "CASE " + strings.Join(b.whens, " ") + elseSQL + " END",
+		args: append(append([]interface{}{}, b.args...), elseArgs...),
+	}
+}
+
+// ToSql renders the expression, so it can be used with squirrel directly
+func (e Expression[T]) ToSql() (string, []interface{}, error) {
+	if e.err != nil {
+		return "", nil, e.err
+	}
+	return e.sql, e.args, nil
+}
+
+func (e Expression[T]) compare(operator string, value T) Condition {
+	if e.err != nil {
+		return Condition{e}
+	}
+	return Condition{squirrel.Expr("("+e.sql+") "+operator+" ?", append(append([]interface{}{}, e.args...), value)...)}
+}
+
+func (e Expression[T]) Eq(value T) Condition {
+	return e.compare("=", value)
+}
+
+func (e Expression[T]) NotEq(value T) Condition {
+	return e.compare("<>", value)
+}
+
+func (e Expression[T]) Gt(value T) Condition {
+	return e.compare(">", value)
+}
+
+func (e Expression[T]) Gte(value T) Condition {
+	return e.compare(">=", value)
+}
+
+func (e Expression[T]) Lt(value T) Condition {
+	return e.compare("<", value)
+}
+
+func (e Expression[T]) Lte(value T) Condition {
+	return e.compare("<=", value)
+}
+
+func (e Expression[T]) IsNull() Condition {
+	if e.err != nil {
+		return Condition{e}
+	}
+	return Condition{squirrel.Expr("("+e.sql+") IS NULL", e.args...)}
+}
+
+func (e Expression[T]) IsNotNull() Condition {
+	if e.err != nil {
+		return Condition{e}
+	}
+	return Condition{squirrel.Expr("("+e.sql+") IS NOT NULL", e.args...)}
+}
+
+// Asc orders by the expression, smallest first; see Query.OrderByExpr
+func (e Expression[T]) Asc() squirrel.Sqlizer {
+	return e.ordering("ASC")
+}
+
+// Desc orders by the expression, largest first; see Query.OrderByExpr
+func (e Expression[T]) Desc() squirrel.Sqlizer {
+	return e.ordering("DESC")
+}
+
+func (e Expression[T]) ordering(direction string) squirrel.Sqlizer {
+	if e.err != nil {
+		return e
+	}
+	return squirrel.Expr(e.sql+" "+direction, e.args...)
+}
+
+// SetExpr sets the column to the value of expr for each updated row
+func (c Column[T]) SetExpr(expr Expression[T]) Action {
+	return Action{
+		column:     c.String(),
+		expression: c.Name + " = " + expr.sql,
+		value:      expr.args,
+		err:        expr.err,
+	}
+}
+
+// OrderByExpr orders by expressions with parameters, such as Expression.Asc, after
+// the orderings already given
+func (q *Query[T]) OrderByExpr(expressions ...squirrel.Sqlizer) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	for _, expr := range expressions {
+		sql, args, err := expr.ToSql()
+		if err != nil {
+			q.err = fmt.Errorf("invalid order by expression: %w", err)
+			return q
+		}
+		q.orderBy = append(q.orderBy, orderBy{sql: sql, args: args})
+	}
+	return q
+}
+
+// OrderByRandom shuffles the records, after the orderings already given. The order is
+// derived from seed and the primary key, so the same seed returns the same order, and
+// pages fetched with Limit and Offset neither repeat nor skip records. Pass a new seed,
+// such as one kept in the session of the user, for another order.
+func (q *Query[T]) OrderByRandom(seed int64) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	keys := q.repo.metadata.PrimaryKeys
+	if len(keys) == 0 {
+		q.err = ErrNoPrimaryKey
+		return q
+	}
+
+	columns := make([]string, len(keys))
+	for i, key := range keys {
+		columns[i] = q.repo.metadata.TableName + "." + key + "::text"
+	}
+	q.orderBy = append(q.orderBy, orderBy{
+		sql:  "md5(concat_ws(',', " + strings.Join(columns, ", ") + ", ?::text))",
+		args: []interface{}{seed},
+	})
+	return q
+}
+
+// SelectAs selects expr in place of column, so records hold its value in the field
+// of that column:
+//
+//	Users.Query(ctx).SelectAs("name", orm.Coalesce(Users.Nickname.Expression(), Users.Name.Expression()))
+func (q *Query[T]) SelectAs(column string, expr squirrel.Sqlizer) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if columnByName(q.repo.metadata, column) == nil {
+		q.err = fmt.Errorf("unknown column %s", column)
+		return q
+	}
+	if _, _, err := expr.ToSql(); err != nil {
+		q.err = fmt.Errorf("invalid expression for %s: %w", column, err)
+		return q
+	}
+	if q.projections == nil {
+		q.projections = make(map[string]squirrel.Sqlizer)
+	}
+	q.projections[column] = expr
+	return q
+}
+
+// applyProjections selects the expressions given to SelectAs in place of their columns
+func (q *Query[T]) applyProjections(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if len(q.projections) == 0 {
+		return builder
+	}
+	builder = builder.RemoveColumns()
+	for _, column := range q.columns() {
+		if expr, ok := q.projections[column]; ok {
+			builder = builder.Column(squirrel.Alias(expr, column))
+			continue
+		}
+		if len(q.groupBy) > 0 {
+			// Qualified like GroupBy selects them
+			column = q.repo.metadata.TableName + "." + column
+		}
+		builder = builder.Column(column)
+	}
+	return builder
+}
@@ -0,0 +1,160 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressions(t *testing.T) {
+	points := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Table: "users", Name: "points"}}}
+	name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+	nickname := StringColumn{Column: Column[string]{Table: "users", Name: "nickname"}}
+
+	t.Run("case with else", func(t *testing.T) {
+		sql, args, err := Case[string]().
+			When(points.Gte(1000), Value("gold")).
+			When(points.Gte(100), Value("silver")).
+			Else(Value("bronze")).
+			ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "CASE WHEN users.points >= ? THEN ? WHEN users.points >= ? THEN ? ELSE ? END", sql)
+		assert.Equal(t, []interface{}{1000, "gold", 100, "silver", "bronze"}, args)
+	})
+
+	t.Run("case branches do not share state", func(t *testing.T) {
+		base := Case[int]().When(points.Gt(0), Value(1))
+		negative, _, _ := base.When(points.Lt(0), Value(-1)).End().ToSql()
+		zero, _, _ := base.When(points.Eq(0), Value(0)).End().ToSql()
+		assert.Equal(t, "CASE WHEN users.points > ? THEN ? WHEN users.points < ? THEN ? END", negative)
+		assert.Equal(t, "CASE WHEN users.points > ? THEN ? WHEN users.points = ? THEN ? END", zero)
+	})
+
+	t.Run("case without when fails", func(t *testing.T) {
+		_, _, err := Case[string]().Else(Value("x")).ToSql()
+		assert.ErrorContains(t, err, "at least one When")
+	})
+
+	t.Run("functions", func(t *testing.T) {
+		sql, args, err := Coalesce(NullIf(nickname.Expression(), Value("")), name.Expression()).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "COALESCE(NULLIF(users.nickname, ?), users.name)", sql)
+		assert.Equal(t, []interface{}{""}, args)
+
+		sql, args, err = Least(Greatest(points.Expression(), Value(0)), Value(100)).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "LEAST(GREATEST(users.points, ?), ?)", sql)
+		assert.Equal(t, []interface{}{0, 100}, args)
+	})
+
+	t.Run("text functions", func(t *testing.T) {
+		sql, args, err := Lower(Coalesce(nickname.Expression(), Value(""))).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "LOWER(COALESCE(users.nickname, ?))", sql)
+		assert.Equal(t, []interface{}{""}, args)
+
+		settings := JSONBColumn{Column: Column[interface{}]{Table: "users", Name: "settings"}}
+		sql, args, err = Upper(settings.Text("theme")).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "UPPER((users.settings ->> ?))", sql)
+		assert.Equal(t, []interface{}{"theme"}, args)
+	})
+
+	t.Run("conditions", func(t *testing.T) {
+		sql, args, err := Coalesce(points.Expression(), Value(0)).Gt(10).ToSqlizer().ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "(COALESCE(users.points, ?)) > ?", sql)
+		assert.Equal(t, []interface{}{0, 10}, args)
+	})
+}
+
+func TestQueryExpressions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+	email := StringColumn{Column: Column[string]{Table: "users", Name: "email"}}
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+
+	t.Run("selects and orders by expressions", func(t *testing.T) {
+		display := Coalesce(NullIf(name.Expression(), Value("")), email.Expression())
+		sql, args, err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			SelectAs("name", display).
+			OrderByExpr(Case[int]().When(active.IsTrue(), Value(0)).Else(Value(1)).Asc()).
+			OrderBy("id").
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "(COALESCE(NULLIF(users.name, $1), users.email)) AS name")
+		assert.Contains(t, sql, "WHERE (users.is_active = $2) ORDER BY CASE WHEN users.is_active = $3 THEN $4 ELSE $5 END ASC, id")
+		assert.Equal(t, []interface{}{"", true, true, 0, 1}, args)
+	})
+
+	t.Run("orders by bound expressions", func(t *testing.T) {
+		settings := JSONBColumn{Column: Column[interface{}]{Table: "users", Name: "settings"}}
+		sql, args, err := repo.Query(context.Background()).
+			OrderByExpr(Lower(name.Expression()).Asc(), settings.Text("rank").Desc()).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "ORDER BY LOWER(users.name) ASC, (users.settings ->> $1) DESC")
+		assert.Equal(t, []interface{}{"rank"}, args)
+	})
+
+	t.Run("orders randomly by seed", func(t *testing.T) {
+		sql, args, err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			OrderByRandom(42).
+			Limit(10).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.is_active = $1) ORDER BY md5(concat_ws(',', users.id::text, $2::text)) LIMIT 10")
+		assert.Equal(t, []interface{}{true, int64(42)}, args)
+	})
+
+	t.Run("finds records with the selected expressions", func(t *testing.T) {
+		mock.ExpectQuery(`^SELECT id, created_at, email, is_active, \(COALESCE\(NULLIF\(users.name, \$1\), users.email\)\) AS name, updated_at FROM users$`).
+			WithArgs("").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "alice@example.com", "alice@example.com"))
+
+		users, err := repo.Query(context.Background()).
+			SelectAs("name", Coalesce(NullIf(name.Expression(), Value("")), email.Expression())).
+			Find()
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "alice@example.com", users[0].Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unknown columns", func(t *testing.T) {
+		_, _, err := repo.Query(context.Background()).SelectAs("missing", Value("x")).ToSQL()
+		assert.ErrorContains(t, err, "unknown column missing")
+	})
+
+	t.Run("updates with an expression", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET name = COALESCE\(NULLIF\(users.name, \$1\), users.email\) WHERE \(users.is_active = \$2\)`).
+			WithArgs("", true).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		updated, err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			Update(name.SetExpr(Coalesce(NullIf(name.Expression(), Value("")), email.Expression())))
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses an update with an invalid expression", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			Update(name.SetExpr(Case[string]().End()))
+		assert.ErrorContains(t, err, "at least one When")
+	})
+}
@@ -0,0 +1,276 @@
+package orm
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// FilterOp is an operator clients can use in a filter parameter
+type FilterOp string
+
+const (
+	FilterEq         FilterOp = "eq"       // status=active or status[eq]=active
+	FilterNotEq      FilterOp = "ne"       // status[ne]=archived
+	FilterGt         FilterOp = "gt"       // age[gt]=18
+	FilterGte        FilterOp = "gte"      // age[gte]=18
+	FilterLt         FilterOp = "lt"       // age[lt]=65
+	FilterLte        FilterOp = "lte"      // age[lte]=65
+	FilterIn         FilterOp = "in"       // status[in]=active,pending
+	FilterNotIn      FilterOp = "nin"      // status[nin]=archived,deleted
+	FilterContains   FilterOp = "contains" // name[contains]=smith, case-insensitive
+	FilterStartsWith FilterOp = "prefix"   // name[prefix]=smi, case-insensitive
+	FilterIsNull     FilterOp = "null"     // deleted_at[null]=true
+)
+
+// Operator sets for FilterSpec.Fields
+var (
+	FilterOpsEquality   = []FilterOp{FilterEq, FilterNotEq, FilterIn, FilterNotIn}
+	FilterOpsComparison = []FilterOp{FilterEq, FilterNotEq, FilterIn, FilterNotIn, FilterGt, FilterGte, FilterLt, FilterLte}
+	FilterOpsText       = []FilterOp{FilterEq, FilterNotEq, FilterIn, FilterNotIn, FilterContains, FilterStartsWith}
+)
+
+// SortParam is the query parameter listing the sort columns, e.g.
+// sort=-created_at,name for created_at descending, then name ascending
+const SortParam = "sort"
+
+// FilterSpec whitelists how clients may filter and sort a list endpoint.
+// Fields and Sorts are keyed by column name; parameters for other columns of
+// the model are refused and parameters that name no column are ignored, so
+// that pagination and other parameters can share the query string.
+type FilterSpec struct {
+	Fields map[string][]FilterOp // Column -> operators allowed on it
+	Sorts  []string              // Columns clients may sort on
+}
+
+// Conditions translates the filter and sort parameters of params into
+// conditions and ORDER BY expressions for the model of metadata. Values are
+// converted to the Go type of their column, so they are always bound as
+// arguments rather than written into the SQL.
+func (s FilterSpec) Conditions(metadata *ModelMetadata, params url.Values) ([]Condition, []string, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	for _, key := range keys {
+		if key == SortParam {
+			continue
+		}
+
+		column, op := key, FilterEq
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			column, op = key[:open], FilterOp(key[open+1:len(key)-1])
+		}
+
+		meta := columnByName(metadata, column)
+		if meta == nil {
+			continue
+		}
+		if !s.allows(column, op) {
+			return nil, nil, filterError(metadata, column, "filtering %s with %q is not allowed", column, op)
+		}
+
+		for _, raw := range params[key] {
+			condition, err := filterCondition(metadata, meta, op, raw)
+			if err != nil {
+				return nil, nil, err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+
+	orderBy, err := s.orderBy(metadata, params[SortParam])
+	if err != nil {
+		return nil, nil, err
+	}
+	return conditions, orderBy, nil
+}
+
+func (s FilterSpec) allows(column string, op FilterOp) bool {
+	for _, allowed := range s.Fields[column] {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (s FilterSpec) orderBy(metadata *ModelMetadata, values []string) ([]string, error) {
+	var orderBy []string
+	for _, value := range values {
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			if column == "" {
+				continue
+			}
+
+			direction := "ASC"
+			if strings.HasPrefix(column, "-") {
+				column, direction = column[1:], "DESC"
+			}
+
+			sortable := false
+			for _, allowed := range s.Sorts {
+				sortable = sortable || allowed == column
+			}
+			if !sortable || columnByName(metadata, column) == nil {
+				return nil, filterError(metadata, column, "sorting by %s is not allowed", column)
+			}
+			orderBy = append(orderBy, metadata.TableName+"."+column+" "+direction)
+		}
+	}
+	return orderBy, nil
+}
+
+// Filter adds the filter and sort parameters of params to the query, as
+// allowed by spec. Parameters the spec does not allow and values that do not
+// fit their column fail the query with an error wrapping ErrInvalidFilter, for
+// handlers to answer with 400 Bad Request.
+//
+//	spec := storm.FilterSpec{
+//		Fields: map[string][]storm.FilterOp{"status": storm.FilterOpsEquality, "created_at": storm.FilterOpsComparison},
+//		Sorts:  []string{"created_at", "name"},
+//	}
+//	users, err := db.Users.Query(ctx).Filter(spec, r.URL.Query()).Limit(50).Find()
+func (q *Query[T]) Filter(spec FilterSpec, params url.Values) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	conditions, orderBy, err := spec.Conditions(q.repo.metadata, params)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	for _, condition := range conditions {
+		q.Where(condition)
+	}
+	return q.OrderBy(orderBy...)
+}
+
+func columnByName(metadata *ModelMetadata, column string) *ColumnMetadata {
+	if field, ok := metadata.ReverseMap[column]; ok {
+		return metadata.Columns[field]
+	}
+	return nil
+}
+
+func filterCondition(metadata *ModelMetadata, column *ColumnMetadata, op FilterOp, raw string) (Condition, error) {
+	name := metadata.TableName + "." + column.DBName
+
+	switch op {
+	case FilterIsNull:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Condition{}, filterError(metadata, column.DBName, "%s[null] must be true or false", column.DBName)
+		}
+		if isNull {
+			return Condition{squirrel.Eq{name: nil}}, nil
+		}
+		return Condition{squirrel.NotEq{name: nil}}, nil
+	case FilterContains, FilterStartsWith:
+		pattern := EscapeLike(raw) + "%"
+		if op == FilterContains {
+			pattern = "%" + pattern
+		}
+		return Condition{squirrel.ILike{name: pattern}}, nil
+	case FilterIn, FilterNotIn:
+		parts := strings.Split(raw, ",")
+		values := make([]interface{}, len(parts))
+		for i, part := range parts {
+			value, err := filterValue(column, part)
+			if err != nil {
+				return Condition{}, filterError(metadata, column.DBName, "%s", err)
+			}
+			values[i] = value
+		}
+		if op == FilterIn {
+			return Condition{squirrel.Eq{name: values}}, nil
+		}
+		return Condition{squirrel.NotEq{name: values}}, nil
+	}
+
+	value, err := filterValue(column, raw)
+	if err != nil {
+		return Condition{}, filterError(metadata, column.DBName, "%s", err)
+	}
+
+	switch op {
+	case FilterEq:
+		return Condition{squirrel.Eq{name: value}}, nil
+	case FilterNotEq:
+		return Condition{squirrel.NotEq{name: value}}, nil
+	case FilterGt:
+		return Condition{squirrel.Gt{name: value}}, nil
+	case FilterGte:
+		return Condition{squirrel.GtOrEq{name: value}}, nil
+	case FilterLt:
+		return Condition{squirrel.Lt{name: value}}, nil
+	case FilterLte:
+		return Condition{squirrel.LtOrEq{name: value}}, nil
+	}
+	return Condition{}, filterError(metadata, column.DBName, "unknown operator %q", op)
+}
+
+// filterValue converts a parameter to the Go type of its column. Types the
+// filter does not know are passed on as text for PostgreSQL to cast.
+func filterValue(column *ColumnMetadata, raw string) (interface{}, error) {
+	switch column.GoType {
+	case "int", "int8", "int16", "int32", "int64":
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an integer", column.DBName)
+		}
+		return value, nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a non-negative integer", column.DBName)
+		}
+		return value, nil
+	case "float32", "float64":
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", column.DBName)
+		}
+		return value, nil
+	case "bool":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", column.DBName)
+		}
+		return value, nil
+	case "time.Time":
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, nil
+		}
+		value, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 time or a date", column.DBName)
+		}
+		return value, nil
+	}
+	return raw, nil
+}
+
+// EscapeLike escapes the wildcards of a LIKE pattern, so that value matches itself
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func filterError(metadata *ModelMetadata, column, format string, args ...interface{}) error {
+	return &Error{
+		Op:     "filter",
+		Table:  metadata.TableName,
+		Column: column,
+		Err:    fmt.Errorf("%w: %s", ErrInvalidFilter, fmt.Sprintf(format, args...)),
+	}
+}
@@ -0,0 +1,84 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFilter(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	spec := FilterSpec{
+		Fields: map[string][]FilterOp{
+			"id":         FilterOpsComparison,
+			"name":       FilterOpsText,
+			"is_active":  {FilterEq},
+			"created_at": append(FilterOpsComparison, FilterIsNull),
+		},
+		Sorts: []string{"created_at", "name"},
+	}
+
+	build := func(query string) (string, []interface{}, error) {
+		params, err := url.ParseQuery(query)
+		require.NoError(t, err)
+		return repo.Query(context.Background()).Filter(spec, params).buildQuery()
+	}
+
+	t.Run("typed conditions and sorts", func(t *testing.T) {
+		sql, args, err := build("is_active=true&id[gte]=10&name[contains]=50%25_off&page=2&sort=-created_at,name")
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.id >= $1 AND users.is_active = $2 AND users.name ILIKE $3)")
+		assert.Contains(t, sql, "ORDER BY users.created_at DESC, users.name ASC")
+		assert.Equal(t, []interface{}{int64(10), true, `%50\%\_off%`}, args)
+	})
+
+	t.Run("lists and null checks", func(t *testing.T) {
+		sql, args, err := build("id[in]=1,2,3&created_at[null]=false")
+		require.NoError(t, err)
+		assert.Contains(t, sql, "users.created_at IS NOT NULL")
+		assert.Contains(t, sql, "users.id IN ($1,$2,$3)")
+		assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, args)
+	})
+
+	t.Run("times and dates", func(t *testing.T) {
+		_, args, err := build("created_at[gte]=2026-01-01&created_at[lt]=2026-02-01T00:00:00Z")
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{
+			time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		}, args)
+	})
+
+	invalid := map[string]string{
+		"column not whitelisted":   "email=a@b.c",
+		"operator not whitelisted": "is_active[ne]=true",
+		"unknown operator":         "name[regex]=.*",
+		"value of the wrong type":  "id=abc",
+		"bad null flag":            "created_at[null]=maybe",
+		"sort not whitelisted":     "sort=email",
+		"sort on unknown column":   "sort=-password",
+	}
+	for name, query := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := build(query)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidFilter), err.Error())
+			var ormErr *Error
+			require.True(t, errors.As(err, &ormErr))
+			assert.Equal(t, "filter", ormErr.Op)
+		})
+	}
+}
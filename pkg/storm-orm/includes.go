@@ -0,0 +1,377 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// throughPair links a record to one of its targets through the join table of a
+// has_many_through relationship
+type throughPair struct {
+	Source interface{} `db:"source"`
+	Target interface{} `db:"target"`
+}
+
+// loadRelationshipBatch loads relationship onto all records with one query per chunk
+// of keys, instead of one per record, and sets the related records of each record
+// from the results. has_many_through relationships read the join table first.
+func (q *Query[T]) loadRelationshipBatch(records []T, relationship *RelationshipMetadata, target *ModelMetadata, include include) error {
+	models := make([]interface{}, len(records))
+	for i := range records {
+		models[i] = &records[i]
+	}
+	path := []string{includeStep(q.repo.metadata, include.name)}
+	return q.loadBatch(models, q.repo.metadata, relationship, target, include, path)
+}
+
+// loadBatch loads relationship of source onto models, pointers to source records,
+// then the includes nested in include onto the related records. path holds the
+// relationships loaded to reach them, ending with this one.
+func (q *Query[T]) loadBatch(models []interface{}, source *ModelMetadata, relationship *RelationshipMetadata, target *ModelMetadata, include include, path []string) error {
+	sourceKey, targetKey := relationship.SourceKey, relationship.ForeignKey
+	switch relationship.Type {
+	case "belongs_to":
+		sourceKey, targetKey = relationship.ForeignKey, relationship.TargetKey
+	case "has_one", "has_many":
+	case "has_many_through":
+		targetKey = relationship.TargetKey
+	default:
+		return fmt.Errorf("unsupported relationship type: %s", relationship.Type)
+	}
+
+	sourceColumn := keyColumn(source, sourceKey)
+	if sourceColumn == nil {
+		return fmt.Errorf("key %s of relationship %s not found on %s", defaultKey(sourceKey), relationship.Name, source.TableName)
+	}
+	targetColumn := keyColumn(target, targetKey)
+	if targetColumn == nil {
+		return fmt.Errorf("key %s of relationship %s not found on %s", defaultKey(targetKey), relationship.Name, target.TableName)
+	}
+
+	keys := make([]string, len(models))
+	values := make([]interface{}, 0, len(models))
+	for i, model := range models {
+		value := sourceColumn.GetValue(reflect.ValueOf(model).Elem().Interface())
+		if value == nil || isZeroValue(value) {
+			continue
+		}
+		keys[i] = relationKey(value)
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	var related map[string][]interface{}
+	if relationship.Type == "has_many_through" {
+		var err error
+		if related, err = q.loadThroughTargets(relationship, target, targetColumn, values, include, path); err != nil {
+			return err
+		}
+	} else {
+		targets, err := q.loadTargets(relationship, target, targetColumn, values, include, path)
+		if err != nil {
+			return err
+		}
+		related = make(map[string][]interface{})
+		for _, target := range targets {
+			key := relationKey(targetColumn.GetValue(target))
+			related[key] = append(related[key], target)
+		}
+	}
+
+	for i, model := range models {
+		if keys[i] == "" {
+			continue
+		}
+		if targets, ok := related[keys[i]]; ok {
+			relationship.SetTargets(model, targets)
+		}
+	}
+	return nil
+}
+
+// loadNested loads includes onto targets, records of metadata, in place, one query
+// per relationship and chunk of keys whatever the number of targets
+func (q *Query[T]) loadNested(targets []interface{}, metadata *ModelMetadata, includes []include, path []string) error {
+	if len(targets) == 0 || len(includes) == 0 {
+		return nil
+	}
+
+	models := make([]interface{}, len(targets))
+	for i, target := range targets {
+		model := reflect.New(reflect.TypeOf(target))
+		model.Elem().Set(reflect.ValueOf(target))
+		models[i] = model.Interface()
+	}
+
+	for _, nested := range includes {
+		step := includeStep(metadata, nested.name)
+		for _, loaded := range path {
+			if loaded == step {
+				return fmt.Errorf("include %s loads %s twice", strings.Join(append(path, step), " -> "), step)
+			}
+		}
+
+		relationship := metadata.Relationships[nested.name]
+		if relationship == nil {
+			return fmt.Errorf("relationship %s not found on %s", nested.name, metadata.TableName)
+		}
+		target := relationship.targetMetadata()
+		if relationship.ScanTargets == nil || relationship.SetTargets == nil || target == nil {
+			return fmt.Errorf("relationship %s of %s cannot be loaded nested, regenerate its metadata", nested.name, metadata.TableName)
+		}
+
+		nestedPath := append(path[:len(path):len(path)], step)
+		if err := q.loadBatch(models, metadata, relationship, target, nested, nestedPath); err != nil {
+			return fmt.Errorf("failed to load relationship %s: %w", nested.name, err)
+		}
+	}
+
+	for i, model := range models {
+		targets[i] = reflect.ValueOf(model).Elem().Interface()
+	}
+	return nil
+}
+
+// loadTargets selects the targets, soft-deleted ones aside, whose column holds one of
+// values, in the order of include and at most its limit per value, with the includes
+// nested in include loaded
+func (q *Query[T]) loadTargets(relationship *RelationshipMetadata, target *ModelMetadata, column *ColumnMetadata, values []interface{}, include include, path []string) ([]interface{}, error) {
+	var loaded []interface{}
+	for _, chunk := range chunkValues(values) {
+		builder := squirrel.Select("*").
+			From(relationship.targetTable()).
+			Where(squirrel.Eq{column.DBName: chunk})
+		if scope := softDeleteScope(target); scope != nil {
+			builder = builder.Where(scope)
+		}
+		builder = include.filter(builder)
+		if include.limit > 0 {
+			builder = rankTargets(builder, relationship.targetTable(), column.DBName, target, include)
+		} else if len(include.orderBy) > 0 {
+			builder = builder.OrderBy(include.orderBy...)
+		}
+		builder = builder.PlaceholderFormat(squirrel.Dollar)
+
+		err := q.repo.executeQueryMiddleware(OpQuery, "load_relationship", q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+			sqlQuery, args, err := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder).ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build query for relationship %s: %w", relationship.Name, err)
+			}
+
+			targets, err := relationship.ScanTargets(q.ctx, q.reader(), sqlQuery, args)
+			if err != nil {
+				return &Error{
+					Op:    "load_relationship",
+					Table: relationship.targetTable(),
+					Err:   fmt.Errorf("failed to load relationship %s: %w", relationship.Name, err),
+				}
+			}
+			loaded = append(loaded, targets...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := q.loadNested(loaded, target, include.nested, path); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// rankTargets limits the targets filtered selects to the first include.limit of each
+// value of partition, numbering them with ROW_NUMBER in the order of include
+func rankTargets(filtered squirrel.SelectBuilder, table, partition string, target *ModelMetadata, include include) squirrel.SelectBuilder {
+	window := Window{partition: []string{partition}, order: include.orderBy}
+	ranked := filtered.Column("ROW_NUMBER() OVER " + window.String() + " AS storm_rank")
+
+	fields := target.FieldNames()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = target.Columns[field].DBName
+	}
+	return squirrel.Select(columns...).
+		FromSelect(ranked, table).
+		Where(squirrel.LtOrEq{"storm_rank": include.limit}).
+		OrderBy(include.orderBy...)
+}
+
+// loadThroughTargets reads the pairs of the join table for values, then the targets
+// they link to, grouped by the key of the record they belong to
+func (q *Query[T]) loadThroughTargets(relationship *RelationshipMetadata, target *ModelMetadata, column *ColumnMetadata, values []interface{}, include include, path []string) (map[string][]interface{}, error) {
+	var pairs []throughPair
+	for _, chunk := range chunkValues(values) {
+		builder := squirrel.Select(relationship.ThroughFK+" AS source", relationship.ThroughTK+" AS target").
+			From(relationship.Through).
+			Where(squirrel.Eq{relationship.ThroughFK: chunk}).
+			PlaceholderFormat(squirrel.Dollar)
+
+		err := q.repo.executeQueryMiddleware(OpQuery, "load_relationship", q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+			sqlQuery, args, err := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder).ToSql()
+			if err != nil {
+				return fmt.Errorf("failed to build query for relationship %s: %w", relationship.Name, err)
+			}
+
+			var chunkPairs []throughPair
+			if err := q.reader().SelectContext(q.ctx, &chunkPairs, sqlQuery, args...); err != nil {
+				return parsePostgreSQLError(fmt.Errorf("failed to load relationship %s: %w", relationship.Name, err), "load_relationship", relationship.Through)
+			}
+			pairs = append(pairs, chunkPairs...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	targetValues := make([]interface{}, 0, len(pairs))
+	for _, pair := range pairs {
+		if pair.Target != nil {
+			targetValues = append(targetValues, pair.Target)
+		}
+	}
+	if len(targetValues) == 0 {
+		return nil, nil
+	}
+	// A target may belong to many records, so the limit applies per record here
+	unlimited := include
+	unlimited.limit = 0
+	targets, err := q.loadTargets(relationship, target, column, targetValues, unlimited, path)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make(map[string][]string)
+	for _, pair := range pairs {
+		if pair.Target != nil {
+			key := relationKey(pair.Target)
+			sources[key] = append(sources[key], relationKey(pair.Source))
+		}
+	}
+	related := make(map[string][]interface{})
+	for _, target := range targets {
+		for _, source := range sources[relationKey(column.GetValue(target))] {
+			if include.limit == 0 || uint64(len(related[source])) < include.limit {
+				related[source] = append(related[source], target)
+			}
+		}
+	}
+	return related, nil
+}
+
+// IncludeOptions constrains the related records IncludeWith loads for each record:
+// Where keeps those matching all its conditions, OrderBy orders them, usually by
+// Column.Asc or Column.Desc, and Limit keeps at most that many of them, 0 for all.
+// Batched loads rank the records with ROW_NUMBER, so the limit holds per record
+// rather than for the whole query; has_many_through relationships apply it once
+// the targets are read.
+type IncludeOptions struct {
+	Where   []Condition
+	OrderBy []string
+	Limit   uint64
+}
+
+// filter adds the conditions of the include to query
+func (i include) filter(query squirrel.SelectBuilder) squirrel.SelectBuilder {
+	for _, condition := range i.conditions {
+		query = query.Where(condition.ToSqlizer())
+	}
+	return query
+}
+
+// apply adds the conditions, order and limit of the include to query, which loads
+// the related records of a single record
+func (i include) apply(query squirrel.SelectBuilder) squirrel.SelectBuilder {
+	query = i.filter(query)
+	if len(i.orderBy) > 0 {
+		query = query.OrderBy(i.orderBy...)
+	}
+	if i.limit > 0 {
+		query = query.Limit(i.limit)
+	}
+	return query
+}
+
+// addInclude adds the relationship path, such as "Posts.Comments", to includes,
+// sharing the levels it has in common with those already there, and applies opts to
+// its last level
+func addInclude(includes []include, path string, opts IncludeOptions) ([]include, error) {
+	name, rest, nested := strings.Cut(path, ".")
+	if name == "" || (nested && rest == "") {
+		return includes, fmt.Errorf("invalid include path %q", path)
+	}
+
+	index := -1
+	for i := range includes {
+		if includes[i].name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		includes = append(includes, include{name: name, conditions: make([]Condition, 0)})
+		index = len(includes) - 1
+	}
+
+	if !nested {
+		includes[index].conditions = append(includes[index].conditions, opts.Where...)
+		includes[index].orderBy = append(includes[index].orderBy, opts.OrderBy...)
+		if opts.Limit > 0 {
+			includes[index].limit = opts.Limit
+		}
+		return includes, nil
+	}
+	children, err := addInclude(includes[index].nested, rest, opts)
+	if err != nil {
+		return includes, fmt.Errorf("invalid include path %q", path)
+	}
+	includes[index].nested = children
+	return includes, nil
+}
+
+// includeStep names relationship of metadata in an include path, to tell when a path
+// loads the same relationship twice
+func includeStep(metadata *ModelMetadata, relationship string) string {
+	return metadata.TableName + "." + relationship
+}
+
+// keyColumn returns the column of metadata a relationship key names, by column or
+// field name, defaulting to id
+func keyColumn(metadata *ModelMetadata, key string) *ColumnMetadata {
+	key = defaultKey(key)
+	if column := columnByName(metadata, key); column != nil {
+		return column
+	}
+	return metadata.Columns[key]
+}
+
+func defaultKey(key string) string {
+	if key == "" {
+		return "id"
+	}
+	return key
+}
+
+// relationKey returns a comparable form of a key value, so that the key of a record
+// matches that of its targets whatever Go type each side scans it into
+func relationKey(value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			value = v
+		}
+	}
+	if bytes, ok := value.([]byte); ok {
+		return string(bytes)
+	}
+	return fmt.Sprint(value)
+}
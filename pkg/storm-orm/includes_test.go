@@ -0,0 +1,407 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type includeAuthor struct {
+	ID    int64         `db:"id"`
+	Name  string        `db:"name"`
+	Books []includeBook `db:"-"`
+	Tags  []includeTag  `db:"-"`
+}
+
+type includeBook struct {
+	ID       int64          `db:"id"`
+	AuthorID int64          `db:"author_id"`
+	Title    string         `db:"title"`
+	Author   *includeAuthor `db:"-"`
+}
+
+type includeTag struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func scanTargets[T any](ctx context.Context, exec DBExecutor, query string, args []interface{}) ([]interface{}, error) {
+	var rows []T
+	if err := exec.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	targets := make([]interface{}, len(rows))
+	for i := range rows {
+		targets[i] = rows[i]
+	}
+	return targets, nil
+}
+
+var (
+	includeAuthorMetadata = &ModelMetadata{
+		TableName:  "include_authors",
+		StructName: "includeAuthor",
+		Columns: map[string]*ColumnMetadata{
+			"ID":   {FieldName: "ID", DBName: "id", IsPrimaryKey: true, GetValue: func(model interface{}) interface{} { return model.(includeAuthor).ID }},
+			"Name": {FieldName: "Name", DBName: "name", GetValue: func(model interface{}) interface{} { return model.(includeAuthor).Name }},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "Name": "name"},
+		ReverseMap:  map[string]string{"id": "ID", "name": "Name"},
+		PrimaryKeys: []string{"id"},
+		Relationships: map[string]*RelationshipMetadata{
+			"Books": {
+				Name:        "Books",
+				Type:        "has_many",
+				Target:      "includeBook",
+				TargetTable: "include_books",
+				ForeignKey:  "author_id",
+				SourceKey:   "id",
+				ScanTargets: scanTargets[includeBook],
+				SetTargets: func(model interface{}, targets []interface{}) {
+					books := make([]includeBook, len(targets))
+					for i, target := range targets {
+						books[i] = target.(includeBook)
+					}
+					model.(*includeAuthor).Books = books
+				},
+			},
+			"Tags": {
+				Name:        "Tags",
+				Type:        "has_many_through",
+				Target:      "includeTag",
+				TargetTable: "include_tags",
+				SourceKey:   "id",
+				TargetKey:   "id",
+				Through:     "include_author_tags",
+				ThroughFK:   "author_id",
+				ThroughTK:   "tag_id",
+				ScanTargets: scanTargets[includeTag],
+				SetTargets: func(model interface{}, targets []interface{}) {
+					tags := make([]includeTag, len(targets))
+					for i, target := range targets {
+						tags[i] = target.(includeTag)
+					}
+					model.(*includeAuthor).Tags = tags
+				},
+			},
+		},
+	}
+
+	includeBookMetadata = &ModelMetadata{
+		TableName:  "include_books",
+		StructName: "includeBook",
+		Columns: map[string]*ColumnMetadata{
+			"ID":       {FieldName: "ID", DBName: "id", IsPrimaryKey: true, GetValue: func(model interface{}) interface{} { return model.(includeBook).ID }},
+			"AuthorID": {FieldName: "AuthorID", DBName: "author_id", GetValue: func(model interface{}) interface{} { return model.(includeBook).AuthorID }},
+			"Title":    {FieldName: "Title", DBName: "title", GetValue: func(model interface{}) interface{} { return model.(includeBook).Title }},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "AuthorID": "author_id", "Title": "title"},
+		ReverseMap:  map[string]string{"id": "ID", "author_id": "AuthorID", "title": "Title"},
+		PrimaryKeys: []string{"id"},
+		Relationships: map[string]*RelationshipMetadata{
+			"Author": {
+				Name:        "Author",
+				Type:        "belongs_to",
+				Target:      "includeAuthor",
+				TargetTable: "include_authors",
+				ForeignKey:  "author_id",
+				TargetKey:   "id",
+				ScanTargets: scanTargets[includeAuthor],
+				SetTargets: func(model interface{}, targets []interface{}) {
+					if len(targets) == 0 {
+						return
+					}
+					author := targets[0].(includeAuthor)
+					model.(*includeBook).Author = &author
+				},
+			},
+		},
+	}
+
+	includeTagMetadata = &ModelMetadata{
+		TableName:  "include_tags",
+		StructName: "includeTag",
+		Columns: map[string]*ColumnMetadata{
+			"ID":   {FieldName: "ID", DBName: "id", IsPrimaryKey: true, GetValue: func(model interface{}) interface{} { return model.(includeTag).ID }},
+			"Name": {FieldName: "Name", DBName: "name", GetValue: func(model interface{}) interface{} { return model.(includeTag).Name }},
+		},
+		ColumnMap:   map[string]string{"ID": "id", "Name": "name"},
+		ReverseMap:  map[string]string{"id": "ID", "name": "Name"},
+		PrimaryKeys: []string{"id"},
+	}
+)
+
+func init() {
+	RegisterMetadata[includeAuthor](includeAuthorMetadata)
+	RegisterMetadata[includeBook](includeBookMetadata)
+	RegisterMetadata[includeTag](includeTagMetadata)
+}
+
+func TestIncludeBatched(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	authors, err := NewRepository[includeAuthor](sqlxDB, includeAuthorMetadata)
+	require.NoError(t, err)
+	books, err := NewRepository[includeBook](sqlxDB, includeBookMetadata)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	authorRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).
+			AddRow(1, "Ann").
+			AddRow(2, "Bob").
+			AddRow(3, "Cy")
+	}
+
+	t.Run("has_many loads all records in one query", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1,\$2,\$3\)$`).
+			WithArgs(int64(1), int64(2), int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).
+				AddRow(10, 1, "First").
+				AddRow(11, 2, "Second").
+				AddRow(12, 1, "Third"))
+
+		records, err := authors.Query(ctx).Include("Books").Find()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []int64{10, 12}, []int64{records[0].Books[0].ID, records[0].Books[1].ID})
+		require.Len(t, records[1].Books, 1)
+		assert.Equal(t, "Second", records[1].Books[0].Title)
+		assert.Nil(t, records[2].Books)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("belongs_to queries each key once", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM include_books`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).
+				AddRow(10, 1, "First").
+				AddRow(11, 2, "Second").
+				AddRow(12, 1, "Third"))
+		mock.ExpectQuery(`SELECT \* FROM include_authors WHERE id IN \(\$1,\$2\)$`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann").AddRow(2, "Bob"))
+
+		records, err := books.Query(ctx).Include("Author").Find()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, "Ann", records[0].Author.Name)
+		assert.Equal(t, "Bob", records[1].Author.Name)
+		assert.Equal(t, "Ann", records[2].Author.Name)
+		assert.NotSame(t, records[0].Author, records[2].Author)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("conditions apply to the batched query", func(t *testing.T) {
+		title := StringColumn{Column: Column[string]{Table: "include_books", Name: "title"}}
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1,\$2,\$3\) AND include_books.title = \$4$`).
+			WithArgs(int64(1), int64(2), int64(3), "First").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(10, 1, "First"))
+
+		records, err := authors.Query(ctx).IncludeWhere("Books", title.Eq("First")).Find()
+		require.NoError(t, err)
+		require.Len(t, records[0].Books, 1)
+		assert.Nil(t, records[1].Books)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("has_many_through reads the join table then the targets", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT author_id AS source, tag_id AS target FROM include_author_tags WHERE author_id IN \(\$1,\$2,\$3\)$`).
+			WithArgs(int64(1), int64(2), int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"source", "target"}).
+				AddRow(1, 7).
+				AddRow(2, 7).
+				AddRow(2, 8))
+		mock.ExpectQuery(`SELECT \* FROM include_tags WHERE id IN \(\$1,\$2\)$`).
+			WithArgs(int64(7), int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "go").AddRow(8, "sql"))
+
+		records, err := authors.Query(ctx).Include("Tags").Find()
+		require.NoError(t, err)
+		assert.Equal(t, []includeTag{{ID: 7, Name: "go"}}, records[0].Tags)
+		assert.Equal(t, []includeTag{{ID: 7, Name: "go"}, {ID: 8, Name: "sql"}}, records[1].Tags)
+		assert.Nil(t, records[2].Tags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order and limit apply per record", func(t *testing.T) {
+		title := Column[string]{Table: "include_books", Name: "title"}
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT id, author_id, title FROM \(SELECT \*, ROW_NUMBER\(\) OVER \(PARTITION BY author_id ORDER BY include_books.title DESC\) AS storm_rank FROM include_books WHERE author_id IN \(\$1,\$2,\$3\)\) AS include_books WHERE storm_rank <= \$4 ORDER BY include_books.title DESC$`).
+			WithArgs(int64(1), int64(2), int64(3), uint64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).
+				AddRow(12, 1, "Third").
+				AddRow(11, 2, "Second"))
+
+		records, err := authors.Query(ctx).IncludeWith("Books", IncludeOptions{OrderBy: []string{title.Desc()}, Limit: 1}).Find()
+		require.NoError(t, err)
+		assert.Equal(t, []includeBook{{ID: 12, AuthorID: 1, Title: "Third"}}, records[0].Books)
+		assert.Equal(t, []includeBook{{ID: 11, AuthorID: 2, Title: "Second"}}, records[1].Books)
+		assert.Nil(t, records[2].Books)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order without a limit needs no ranking", func(t *testing.T) {
+		title := Column[string]{Table: "include_books", Name: "title"}
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1,\$2,\$3\) ORDER BY include_books.title ASC$`).
+			WithArgs(int64(1), int64(2), int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).
+				AddRow(10, 1, "First").
+				AddRow(12, 1, "Third"))
+
+		records, err := authors.Query(ctx).IncludeWith("Books", IncludeOptions{OrderBy: []string{title.Asc()}}).Find()
+		require.NoError(t, err)
+		assert.Equal(t, []int64{10, 12}, []int64{records[0].Books[0].ID, records[0].Books[1].ID})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("has_many_through limits each record after reading the targets", func(t *testing.T) {
+		name := Column[string]{Table: "include_tags", Name: "name"}
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT author_id AS source, tag_id AS target FROM include_author_tags WHERE author_id IN \(\$1,\$2,\$3\)$`).
+			WithArgs(int64(1), int64(2), int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"source", "target"}).
+				AddRow(1, 7).
+				AddRow(2, 7).
+				AddRow(2, 8))
+		mock.ExpectQuery(`SELECT \* FROM include_tags WHERE id IN \(\$1,\$2\) ORDER BY include_tags.name DESC$`).
+			WithArgs(int64(7), int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(8, "sql").AddRow(7, "go"))
+
+		records, err := authors.Query(ctx).IncludeWith("Tags", IncludeOptions{OrderBy: []string{name.Desc()}, Limit: 1}).Find()
+		require.NoError(t, err)
+		assert.Equal(t, []includeTag{{ID: 7, Name: "go"}}, records[0].Tags)
+		assert.Equal(t, []includeTag{{ID: 8, Name: "sql"}}, records[1].Tags)
+		assert.Nil(t, records[2].Tags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("large key sets are queried in chunks", func(t *testing.T) {
+		SetInChunkSize(2)
+		defer SetInChunkSize(DefaultInChunkSize)
+
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).WillReturnRows(authorRows())
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1,\$2\)$`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(10, 1, "First"))
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1\)$`).
+			WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(13, 3, "Fourth"))
+
+		records, err := authors.Query(ctx).Include("Books").Find()
+		require.NoError(t, err)
+		require.Len(t, records[0].Books, 1)
+		require.Len(t, records[2].Books, 1)
+		assert.Equal(t, "Fourth", records[2].Books[0].Title)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestIncludeNested(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	authors, err := NewRepository[includeAuthor](sqlxDB, includeAuthorMetadata)
+	require.NoError(t, err)
+	books, err := NewRepository[includeBook](sqlxDB, includeBookMetadata)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	bookRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "author_id", "title"}).
+			AddRow(10, 1, "First").
+			AddRow(11, 2, "Second").
+			AddRow(12, 1, "Third")
+	}
+
+	t.Run("each level is loaded in one query", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM include_books`).WillReturnRows(bookRows())
+		mock.ExpectQuery(`SELECT \* FROM include_authors WHERE id IN \(\$1,\$2\)$`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann").AddRow(2, "Bob"))
+		mock.ExpectQuery(`SELECT author_id AS source, tag_id AS target FROM include_author_tags WHERE author_id IN \(\$1,\$2\)$`).
+			WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"source", "target"}).AddRow(1, 7).AddRow(2, 8))
+		mock.ExpectQuery(`SELECT \* FROM include_tags WHERE id IN \(\$1,\$2\)$`).
+			WithArgs(int64(7), int64(8)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "go").AddRow(8, "sql"))
+
+		records, err := books.Query(ctx).Include("Author.Tags").Find()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []includeTag{{ID: 7, Name: "go"}}, records[0].Author.Tags)
+		assert.Equal(t, []includeTag{{ID: 8, Name: "sql"}}, records[1].Author.Tags)
+		assert.Equal(t, []includeTag{{ID: 7, Name: "go"}}, records[2].Author.Tags)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("paths share their common levels", func(t *testing.T) {
+		title := StringColumn{Column: Column[string]{Table: "include_books", Name: "title"}}
+		mock.ExpectQuery(`SELECT .* FROM include_books`).WillReturnRows(bookRows())
+		mock.ExpectQuery(`SELECT \* FROM include_authors WHERE id IN \(\$1,\$2\)$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann").AddRow(2, "Bob"))
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1,\$2\) AND include_books.title = \$3$`).
+			WithArgs(int64(1), int64(2), "Third").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(12, 1, "Third"))
+		mock.ExpectQuery(`SELECT author_id AS source, tag_id AS target FROM include_author_tags`).
+			WillReturnRows(sqlmock.NewRows([]string{"source", "target"}))
+
+		records, err := books.Query(ctx).
+			IncludeWhere("Author.Books", title.Eq("Third")).
+			Include("Author.Tags").
+			Find()
+		require.NoError(t, err)
+		require.Len(t, records[0].Author.Books, 1)
+		assert.Equal(t, "Third", records[0].Author.Books[0].Title)
+		assert.Nil(t, records[1].Author.Books)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("paths loading a relationship twice are refused", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann"))
+		mock.ExpectQuery(`SELECT \* FROM include_books WHERE author_id IN \(\$1\)$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(10, 1, "First"))
+		mock.ExpectQuery(`SELECT \* FROM include_authors WHERE id IN \(\$1\)$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann"))
+
+		_, err := authors.Query(ctx).Include("Books.Author.Books").Find()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "loads include_authors.Books twice")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid paths are refused", func(t *testing.T) {
+		_, err := authors.Query(ctx).Include("Books.").Find()
+		assert.EqualError(t, err, `invalid include path "Books."`)
+
+		mock.ExpectQuery(`SELECT .* FROM include_authors`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Ann"))
+		mock.ExpectQuery(`SELECT \* FROM include_books`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "author_id", "title"}).AddRow(10, 1, "First"))
+
+		_, err = authors.Query(ctx).Include("Books.Publisher").Find()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "relationship Publisher not found on include_books")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRelationKey(t *testing.T) {
+	assert.Equal(t, relationKey(int64(5)), relationKey(int32(5)))
+	assert.Equal(t, relationKey("a1"), relationKey([]byte("a1")))
+	assert.Equal(t, relationKey(int64(5)), relationKey(sql.NullInt64{Int64: 5, Valid: true}))
+}
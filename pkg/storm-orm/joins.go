@@ -2,6 +2,8 @@ package orm
 
 import (
 	"fmt"
+
+	"github.com/Masterminds/squirrel"
 )
 
 // JoinType represents different types of SQL joins
@@ -12,6 +14,7 @@ const (
 	LeftJoin  JoinType = "LEFT JOIN"
 	RightJoin JoinType = "RIGHT JOIN"
 	FullJoin  JoinType = "FULL OUTER JOIN"
+	CrossJoin JoinType = "CROSS JOIN"
 )
 
 // join represents a SQL join clause (internal use only)
@@ -94,18 +97,27 @@ func (q *Query[T]) JoinRelationship(relationshipName string, joinType JoinType)
 		return q
 	}
 
+	target := rel.targetTable()
+
+	// Soft-deleted targets are left out of the join itself, so that a left join
+	// still returns the rows without any
+	scope := ""
+	if condition := softDeleteCondition(rel.targetMetadata(), target); condition != "" {
+		scope = " AND " + condition
+	}
+
 	switch rel.Type {
 	case "belongs_to":
 		condition := fmt.Sprintf("%s.%s = %s.%s",
 			repo.metadata.TableName, rel.ForeignKey,
-			rel.Target, rel.TargetKey)
-		q.Join(InnerJoin, rel.Target, condition)
+			target, rel.TargetKey)
+		q.Join(InnerJoin, target, condition+scope)
 
 	case "has_one", "has_many":
 		condition := fmt.Sprintf("%s.%s = %s.%s",
 			repo.metadata.TableName, rel.SourceKey,
-			rel.Target, rel.ForeignKey)
-		q.Join(InnerJoin, rel.Target, condition)
+			target, rel.ForeignKey)
+		q.Join(InnerJoin, target, condition+scope)
 
 	case "has_many_through":
 		condition1 := fmt.Sprintf("%s.%s = %s.%s",
@@ -115,8 +127,8 @@ func (q *Query[T]) JoinRelationship(relationshipName string, joinType JoinType)
 
 		condition2 := fmt.Sprintf("%s.%s = %s.%s",
 			rel.Through, rel.ThroughTK,
-			rel.Target, rel.TargetKey)
-		q.Join(InnerJoin, rel.Target, condition2)
+			target, rel.TargetKey)
+		q.Join(InnerJoin, target, condition2+scope)
 
 	default:
 		q.err = fmt.Errorf("unsupported relationship type for join: %s", rel.Type)
@@ -125,6 +137,102 @@ func (q *Query[T]) JoinRelationship(relationshipName string, joinType JoinType)
 	return q
 }
 
+// WhereHas keeps the records that have at least one related record matching all
+// the conditions, using an EXISTS subquery:
+//
+//	Users.Query(ctx).WhereHas("Posts", Posts.Published.Eq(true))
+func (q *Query[T]) WhereHas(relationship string, conditions ...Condition) *Query[T] {
+	return q.whereExists(relationship, false, conditions)
+}
+
+// WhereDoesntHave keeps the records that have no related record matching all the
+// conditions, using a NOT EXISTS subquery
+func (q *Query[T]) WhereDoesntHave(relationship string, conditions ...Condition) *Query[T] {
+	return q.whereExists(relationship, true, conditions)
+}
+
+func (q *Query[T]) whereExists(relationshipName string, negate bool, conditions []Condition) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	rel := q.repo.getRelationship(relationshipName)
+	if rel == nil {
+		q.err = fmt.Errorf("relationship %s not found", relationshipName)
+		return q
+	}
+
+	subquery, err := q.relatedSubquery(rel, "1")
+	if err != nil {
+		q.err = err
+		return q
+	}
+	for _, condition := range conditions {
+		subquery = subquery.Where(condition.ToSqlizer())
+	}
+
+	// The subquery keeps ? placeholders so that the outer query numbers them
+	sql, args, err := subquery.ToSql()
+	if err != nil {
+		q.err = fmt.Errorf("failed to build subquery for %s: %w", relationshipName, err)
+		return q
+	}
+
+	operator := "EXISTS"
+	if negate {
+		operator = "NOT EXISTS"
+	}
+	q.whereClause = append(q.whereClause, squirrel.Expr(operator+" ("+sql+")", args...))
+	return q
+}
+
+// RelatedAlias names the related table inside the subqueries of WhereHas and
+// WithCount when a relationship points back to the table of the query, such as
+// the manager of a user. Conditions on the related records then qualify their
+// columns with it.
+const RelatedAlias = "r1"
+
+// relatedSubquery selects column from the records related to the current row of the query
+func (q *Query[T]) relatedSubquery(rel *RelationshipMetadata, column string) (squirrel.SelectBuilder, error) {
+	source := q.repo.metadata.TableName
+	target := rel.targetTable()
+	sourceKey := q.repo.columnName(rel.SourceKey)
+	targetKey := rel.TargetKey
+	if targetKey == "" {
+		targetKey = "id"
+	}
+
+	from, related := target, target
+	if target == source {
+		from, related = target+" AS "+RelatedAlias, RelatedAlias
+	}
+
+	var builder squirrel.SelectBuilder
+	switch rel.Type {
+	case "belongs_to":
+		builder = squirrel.Select(column).From(from).
+			Where(fmt.Sprintf("%s.%s = %s.%s", related, targetKey, source, q.repo.columnName(rel.ForeignKey)))
+
+	case "has_one", "has_many":
+		builder = squirrel.Select(column).From(from).
+			Where(fmt.Sprintf("%s.%s = %s.%s", related, rel.ForeignKey, source, sourceKey))
+
+	case "has_many_through":
+		builder = squirrel.Select(column).From(rel.Through).
+			Join(fmt.Sprintf("%s ON %s.%s = %s.%s", from, related, targetKey, rel.Through, rel.ThroughTK)).
+			Where(fmt.Sprintf("%s.%s = %s.%s", rel.Through, rel.ThroughFK, source, sourceKey))
+
+	default:
+		return squirrel.SelectBuilder{}, fmt.Errorf("unsupported relationship type for subquery: %s", rel.Type)
+	}
+
+	// Soft-deleted records are not related records
+	if condition := softDeleteCondition(rel.targetMetadata(), related); condition != "" {
+		builder = builder.Where(condition)
+	}
+	return builder, nil
+}
+
 func (q *Query[T]) RawJoin(joinClause string, args ...interface{}) *Query[T] {
 	join := join{
 		Type:      "",
@@ -0,0 +1,69 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// ForUpdate locks the rows the query returns until the end of the transaction, so
+// other transactions can neither update, delete nor lock them meanwhile. Run it in
+// a transaction: on its own, a query releases its locks as soon as it returns. With
+// joins, only the rows of the model are locked.
+//
+//	Jobs.Query(ctx).WithTx(tx).Where(Jobs.Status.Eq("pending")).OrderBy("id").ForUpdate().SkipLocked().Limit(10).Find()
+func (q *Query[T]) ForUpdate() *Query[T] {
+	q.lock = "FOR UPDATE"
+	return q
+}
+
+// ForShare locks the rows the query returns against updates and deletes until the
+// end of the transaction, while letting other transactions share the lock
+func (q *Query[T]) ForShare() *Query[T] {
+	q.lock = "FOR SHARE"
+	return q
+}
+
+// SkipLocked leaves out the rows another transaction has locked instead of waiting
+// for them, which lets concurrent workers each claim different jobs of a queue. It
+// needs ForUpdate or ForShare.
+func (q *Query[T]) SkipLocked() *Query[T] {
+	q.lockWait = "SKIP LOCKED"
+	return q
+}
+
+// NoWait fails the query with a lock_not_available error, instead of waiting, when
+// a row is locked by another transaction. It needs ForUpdate or ForShare.
+func (q *Query[T]) NoWait() *Query[T] {
+	q.lockWait = "NOWAIT"
+	return q
+}
+
+// applyLock adds the locking clause of the query to builder
+func (q *Query[T]) applyLock(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	if q.lock == "" {
+		return builder
+	}
+
+	clause := q.lock
+	if len(q.joins) > 0 {
+		// Locking the joined rows too would fail on the nullable side of outer joins
+		clause += " OF " + q.repo.metadata.TableName
+	}
+	if q.lockWait != "" {
+		clause += " " + q.lockWait
+	}
+	return builder.Suffix(clause)
+}
+
+// lockError is the error of a query that waits for locks without locking rows
+func (q *Query[T]) lockError() error {
+	if q.lockWait == "" || q.lock != "" {
+		return nil
+	}
+	return &Error{
+		Op:    "lock",
+		Table: q.repo.metadata.TableName,
+		Err:   fmt.Errorf("%s needs ForUpdate or ForShare", q.lockWait),
+	}
+}
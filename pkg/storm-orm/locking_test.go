@@ -0,0 +1,60 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLocking(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+
+	tests := []struct {
+		name   string
+		query  func(*Query[TestUser]) *Query[TestUser]
+		suffix string
+	}{
+		{"for update", func(q *Query[TestUser]) *Query[TestUser] { return q.ForUpdate() }, " FOR UPDATE"},
+		{"for share", func(q *Query[TestUser]) *Query[TestUser] { return q.ForShare() }, " FOR SHARE"},
+		{"skip locked", func(q *Query[TestUser]) *Query[TestUser] { return q.ForUpdate().SkipLocked() }, " FOR UPDATE SKIP LOCKED"},
+		{"no wait", func(q *Query[TestUser]) *Query[TestUser] { return q.NoWait().ForShare() }, " FOR SHARE NOWAIT"},
+		{"joins lock the model rows", func(q *Query[TestUser]) *Query[TestUser] {
+			return q.LeftJoin("posts", "posts.author_id = users.id").ForUpdate()
+		}, " FOR UPDATE OF users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, _, err := tt.query(repo.Query(context.Background()).Where(active.IsTrue()).Limit(10)).ToSQL()
+			require.NoError(t, err)
+			assert.Regexp(t, `LIMIT 10`+tt.suffix+`$`, sql)
+		})
+	}
+
+	t.Run("claims jobs with find", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(users.is_active = \$1\) ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED$`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		user, err := repo.Query(context.Background()).Where(active.IsTrue()).OrderBy("id").ForUpdate().SkipLocked().First()
+		require.NoError(t, err)
+		assert.Equal(t, 7, user.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to wait for locks it does not take", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).SkipLocked().Find()
+		assert.ErrorContains(t, err, "SKIP LOCKED needs ForUpdate or ForShare")
+	})
+}
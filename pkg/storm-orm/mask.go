@@ -0,0 +1,69 @@
+package orm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// MaskStrategy names how a column tagged with mask is anonymized
+type MaskStrategy string
+
+const (
+	MaskEmail  MaskStrategy = "email"  // user_<hash>@example.com
+	MaskName   MaskStrategy = "name"   // A made-up first and last name
+	MaskRedact MaskStrategy = "redact" // The text REDACTED
+	MaskHash   MaskStrategy = "hash"   // The first 32 hex digits of a SHA-256 hash
+)
+
+// RedactedValue replaces the values of columns masked with MaskRedact
+const RedactedValue = "REDACTED"
+
+var (
+	maskFirstNames = []string{
+		"Alex", "Blair", "Casey", "Dana", "Eden", "Finley", "Gray", "Harper",
+		"Indigo", "Jordan", "Kai", "Logan", "Morgan", "Noel", "Oakley", "Parker",
+		"Quinn", "Riley", "Sage", "Taylor", "Umi", "Val", "Wren", "Xen",
+		"Yael", "Zion", "Avery", "Brook", "Cameron", "Drew", "Emery", "Frankie",
+	}
+	maskLastNames = []string{
+		"Abbott", "Barker", "Carver", "Dalton", "Ellis", "Fischer", "Garner", "Hale",
+		"Irving", "Jensen", "Keller", "Lawson", "Mercer", "Nolan", "Osborne", "Porter",
+		"Quincy", "Rowe", "Sutton", "Thorne", "Underwood", "Vance", "Whitaker", "Xavier",
+		"York", "Zimmer", "Archer", "Bishop", "Conway", "Doyle", "Everett", "Foster",
+	}
+)
+
+// MaskValue anonymizes value with strategy. The result depends only on the
+// value and salt, so a value masks the same way in every table it appears in
+// and references between tables survive. NULLs stay NULL.
+func MaskValue(strategy MaskStrategy, value interface{}, salt string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	text := formatCSVValue(value)
+	switch strategy {
+	case MaskEmail:
+		sum := maskSum(strings.ToLower(strings.TrimSpace(text)), salt)
+		return "user_" + hex.EncodeToString(sum[:6]) + "@example.com", nil
+	case MaskName:
+		sum := maskSum(text, salt)
+		first := binary.BigEndian.Uint32(sum[0:4]) % uint32(len(maskFirstNames))
+		last := binary.BigEndian.Uint32(sum[4:8]) % uint32(len(maskLastNames))
+		return maskFirstNames[first] + " " + maskLastNames[last], nil
+	case MaskRedact:
+		return RedactedValue, nil
+	case MaskHash:
+		sum := maskSum(text, salt)
+		return hex.EncodeToString(sum[:16]), nil
+	default:
+		return nil, fmt.Errorf("unknown mask strategy %q", strategy)
+	}
+}
+
+func maskSum(text, salt string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(salt + "\x00" + text))
+}
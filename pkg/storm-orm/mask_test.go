@@ -0,0 +1,43 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskValue(t *testing.T) {
+	email, err := MaskValue(MaskEmail, "Ada@Example.com", "salt")
+	require.NoError(t, err)
+	assert.Regexp(t, `^user_[0-9a-f]{12}@example\.com$`, email)
+
+	// Case variants of an address mask the same, so references line up
+	again, err := MaskValue(MaskEmail, []byte(" ada@example.com"), "salt")
+	require.NoError(t, err)
+	assert.Equal(t, email, again)
+
+	other, err := MaskValue(MaskEmail, "ada@example.com", "pepper")
+	require.NoError(t, err)
+	assert.NotEqual(t, email, other)
+
+	name, err := MaskValue(MaskName, "Ada Lovelace", "salt")
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, name)
+	assert.NotContains(t, name, "Lovelace")
+
+	redacted, err := MaskValue(MaskRedact, "secret", "salt")
+	require.NoError(t, err)
+	assert.Equal(t, RedactedValue, redacted)
+
+	hash, err := MaskValue(MaskHash, 42, "salt")
+	require.NoError(t, err)
+	assert.Len(t, hash, 32)
+
+	null, err := MaskValue(MaskEmail, nil, "salt")
+	require.NoError(t, err)
+	assert.Nil(t, null)
+
+	_, err = MaskValue("scramble", "x", "salt")
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MergeOptions makes Upsert and UpsertMany run a MERGE statement, which needs
+// PostgreSQL 15 or later, instead of INSERT ... ON CONFLICT. ConflictColumns then
+// only match the records to existing rows and need no unique index, and matched
+// rows can be deleted or left alone depending on their values. Conditions are SQL
+// referring to the existing row by the table name and to the record as excluded,
+// as in UpdateExpr:
+//
+//	err := storm.Stock.UpsertMany(ctx, levels, orm.UpsertOptions{
+//		ConflictColumns: []string{"warehouse_id", "sku"},
+//		UpdateColumns:   []string{"quantity"},
+//		Merge: &orm.MergeOptions{
+//			DeleteWhen: "excluded.quantity = 0",
+//			UpdateWhen: "stock.quantity <> excluded.quantity",
+//		},
+//	})
+type MergeOptions struct {
+	DeleteWhen string // Matched rows meeting this condition are deleted
+	UpdateWhen string // Other matched rows are only updated when this condition holds
+	SkipInsert bool   // Records matching no row are left out instead of inserted
+}
+
+// mergeVersion is the first server_version_num supporting MERGE
+const mergeVersion = 150000
+
+// mergeRecords upserts records with MERGE from a temporary table holding columns,
+// which is dropped when done, or at the latest with the transaction
+func (r *Repository[T]) mergeRecords(ctx context.Context, tx *sqlx.Tx, op OperationType, record interface{}, records []T, columns []string, opts UpsertOptions) error {
+	errOp, name := "upsert", "upsert"
+	if op == OpUpsertMany {
+		errOp, name = "upsertMany", "upsert_many"
+	}
+
+	temp := fmt.Sprintf("storm_merge_%d", upsertTables.Add(1))
+	statement := mergeStatement(r.metadata.TableName, temp, columns, opts)
+
+	return r.executeQueryMiddleware(op, name, ctx, record, statement, func(middlewareCtx *MiddlewareContext) error {
+		middlewareCtx.Query = statement
+
+		var version int
+		if err := tx.GetContext(ctx, &version, "SELECT current_setting('server_version_num')::int"); err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to read the server version: %w", err), errOp, r.metadata.TableName)
+		}
+		if version < mergeVersion {
+			return &Error{
+				Op:    errOp,
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("MERGE requires PostgreSQL 15 or later, the server runs %d; leave UpsertOptions.Merge unset to upsert with ON CONFLICT", version),
+			}
+		}
+
+		copied := opts.Strategy.copies(len(records) * len(columns))
+		return r.stageRecords(ctx, tx, errOp, temp, records, columns, copied, statement)
+	})
+}
+
+// mergeStatement returns the MERGE of the rows of source, holding columns, into
+// table. The source is named excluded so that UpdateExpr reads the same as with
+// ON CONFLICT.
+func mergeStatement(table, source string, columns []string, opts UpsertOptions) string {
+	merge := opts.Merge
+	if merge == nil {
+		merge = &MergeOptions{}
+	}
+
+	on := make([]string, len(opts.ConflictColumns))
+	for i, column := range opts.ConflictColumns {
+		on[i] = fmt.Sprintf("%s.%s = excluded.%s", table, column, column)
+	}
+
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "MERGE INTO %s USING %s AS excluded ON %s", table, source, strings.Join(on, " AND "))
+
+	actions := 0
+	if merge.DeleteWhen != "" {
+		fmt.Fprintf(&sql, " WHEN MATCHED AND (%s) THEN DELETE", merge.DeleteWhen)
+		actions++
+	}
+	if setParts := updateAssignments(columns, opts); len(setParts) > 0 {
+		sql.WriteString(" WHEN MATCHED")
+		if merge.UpdateWhen != "" {
+			fmt.Fprintf(&sql, " AND (%s)", merge.UpdateWhen)
+		}
+		sql.WriteString(" THEN UPDATE SET " + strings.Join(setParts, ", "))
+		actions++
+	}
+	if !merge.SkipInsert {
+		values := make([]string, len(columns))
+		for i, column := range columns {
+			values[i] = "excluded." + column
+		}
+		fmt.Fprintf(&sql, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(values, ", "))
+		actions++
+	}
+	if actions == 0 {
+		sql.WriteString(" WHEN MATCHED THEN DO NOTHING")
+	}
+	return sql.String()
+}
+
+// inTransaction runs fn in the transaction of the repository, or in one of its own
+// that is committed when fn succeeds
+func (r *Repository[T]) inTransaction(ctx context.Context, op string, fn func(tx *sqlx.Tx) error) error {
+	if tx, ok := r.db.(*sqlx.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := r.db.(*sqlx.DB).BeginTxx(ctx, nil)
+	if err != nil {
+		return &Error{
+			Op:    op,
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("failed to begin transaction: %w", err),
+		}
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", err), op, r.metadata.TableName)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeStatement(t *testing.T) {
+	columns := []string{"email", "name", "is_active"}
+
+	t.Run("updates and inserts like ON CONFLICT", func(t *testing.T) {
+		sql := mergeStatement("users", "staged", columns, UpsertOptions{
+			ConflictColumns: []string{"email"},
+			Merge:           &MergeOptions{},
+		})
+		assert.Equal(t, "MERGE INTO users USING staged AS excluded ON users.email = excluded.email"+
+			" WHEN MATCHED THEN UPDATE SET name = EXCLUDED.name, is_active = EXCLUDED.is_active"+
+			" WHEN NOT MATCHED THEN INSERT (email, name, is_active) VALUES (excluded.email, excluded.name, excluded.is_active)", sql)
+	})
+
+	t.Run("deletes and updates conditionally", func(t *testing.T) {
+		sql := mergeStatement("users", "staged", columns, UpsertOptions{
+			ConflictColumns: []string{"email"},
+			UpdateColumns:   []string{"name"},
+			UpdateExpr:      map[string]string{"name": "COALESCE(EXCLUDED.name, users.name)"},
+			Merge: &MergeOptions{
+				DeleteWhen: "NOT excluded.is_active",
+				UpdateWhen: "users.name IS DISTINCT FROM excluded.name",
+				SkipInsert: true,
+			},
+		})
+		assert.Equal(t, "MERGE INTO users USING staged AS excluded ON users.email = excluded.email"+
+			" WHEN MATCHED AND (NOT excluded.is_active) THEN DELETE"+
+			" WHEN MATCHED AND (users.name IS DISTINCT FROM excluded.name) THEN UPDATE SET name = COALESCE(EXCLUDED.name, users.name)", sql)
+	})
+
+	t.Run("does nothing when there is nothing to do", func(t *testing.T) {
+		sql := mergeStatement("users", "staged", []string{"email"}, UpsertOptions{
+			ConflictColumns: []string{"email"},
+			Merge:           &MergeOptions{SkipInsert: true},
+		})
+		assert.Equal(t, "MERGE INTO users USING staged AS excluded ON users.email = excluded.email WHEN MATCHED THEN DO NOTHING", sql)
+	})
+}
+
+func TestUpsertMerge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	opts := UpsertOptions{
+		ConflictColumns: []string{"email"},
+		UpdateColumns:   []string{"name"},
+		Merge:           &MergeOptions{DeleteWhen: "NOT excluded.is_active"},
+	}
+	version := func(num int) {
+		mock.ExpectQuery(`SELECT current_setting\('server_version_num'\)::int`).
+			WillReturnRows(sqlmock.NewRows([]string{"current_setting"}).AddRow(num))
+	}
+
+	t.Run("upsert merges one record", func(t *testing.T) {
+		mock.ExpectBegin()
+		version(150002)
+		mock.ExpectExec(`CREATE TEMP TABLE storm_merge_\d+ ON COMMIT DROP AS SELECT .*email.* FROM users WITH NO DATA`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO storm_merge_\d+ \(.*\) VALUES \(.*\)`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`MERGE INTO users USING storm_merge_\d+ AS excluded ON users.email = excluded.email WHEN MATCHED AND \(NOT excluded.is_active\) THEN DELETE WHEN MATCHED THEN UPDATE SET name = EXCLUDED.name WHEN NOT MATCHED THEN INSERT`).
+			WithoutArgs().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`DROP TABLE storm_merge_\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		user := TestUser{Name: "User1", Email: "user1@example.com", IsActive: true}
+		require.NoError(t, repo.Upsert(context.Background(), &user, opts))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upsert many copies large batches", func(t *testing.T) {
+		copied := opts
+		copied.Strategy = UpsertCopy
+
+		mock.ExpectBegin()
+		version(160000)
+		mock.ExpectExec(`CREATE TEMP TABLE storm_merge_\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn := mock.ExpectPrepare(`COPY "storm_merge_\d+" \(.*\) FROM STDIN`)
+		copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`MERGE INTO users USING storm_merge_\d+ AS excluded`).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`DROP TABLE storm_merge_\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		users := []TestUser{
+			{Name: "User1", Email: "user1@example.com", IsActive: true},
+			{Name: "User2", Email: "user2@example.com"},
+		}
+		require.NoError(t, repo.UpsertMany(context.Background(), users, copied))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses servers without MERGE", func(t *testing.T) {
+		mock.ExpectBegin()
+		version(140009)
+		mock.ExpectRollback()
+
+		users := []TestUser{{Name: "User1", Email: "user1@example.com"}}
+		err := repo.UpsertMany(context.Background(), users, opts)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "MERGE requires PostgreSQL 15 or later")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
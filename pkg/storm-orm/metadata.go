@@ -15,12 +15,29 @@ type ModelMetadata struct {
 	Columns    map[string]*ColumnMetadata // key is Go field name
 	ColumnMap  map[string]string          // Go field -> DB column
 	ReverseMap map[string]string          // DB column -> Go field
+	FieldOrder []string                   // Go fields of the columns in declaration order
 
 	// Primary keys only - other column lists are determined dynamically
 	PrimaryKeys []string // DB column names
 
+	// Constraint name -> DB columns it covers
+	Constraints map[string][]string
+
 	// Relationships
 	Relationships map[string]*RelationshipMetadata
+
+	// Condition every query of the model is restricted to, such as "deleted_at IS NULL",
+	// declared with the default_scope table-level attribute; see Repository.DefaultScope
+	DefaultScope string
+
+	// Column Delete sets to the time of deletion instead of removing the row, declared
+	// with the soft_delete attribute; queries only see the rows where it is NULL
+	SoftDelete string
+
+	// Code generation stamp, checked against the model struct at startup
+	GeneratorVersion string // Storm version that generated the code
+	CodegenVersion   int    // Revision of the generated code format
+	SchemaHash       string // Hash of the model fields the code was generated from
 }
 
 // ColumnMetadata contains metadata for a single column
@@ -35,6 +52,9 @@ type ColumnMetadata struct {
 	IsUnique        bool                // Has unique constraint?
 	IsPointer       bool                // Is this a pointer field in Go struct?
 	Default         string              // Default value
+	Mask            MaskStrategy        // How the column is anonymized on masked imports
+	AutoCreateTime  bool                // Create sets the column to the current time when the record leaves it zero
+	AutoUpdateTime  bool                // Create and Update set the column to the current time
 	Tags            map[string]string   // All dbdef tags
 	Constraints     []string            // Check constraints
 	ForeignKey      *ForeignKeyMetadata // Foreign key info if applicable
@@ -54,17 +74,47 @@ type ForeignKeyMetadata struct {
 
 // RelationshipMetadata contains relationship information
 type RelationshipMetadata struct {
-	Name       string
-	Type       string // belongs_to, has_one, has_many, has_many_through
-	Target     string // Target model name
-	ForeignKey string // Foreign key field
-	TargetKey  string // Target key field (for belongs_to)
-	SourceKey  string // Source key field (for has_one/has_many)
-	Through    string // Through model (for has_many_through)
-	ThroughFK  string // Through foreign key
-	ThroughTK  string // Through target key
+	Name        string
+	Type        string // belongs_to, has_one, has_many, has_many_through
+	Target      string // Target model name
+	TargetTable string // Table of the target model
+	ForeignKey  string // Foreign key field
+	TargetKey   string // Target key field (for belongs_to)
+	SourceKey   string // Source key field (for has_one/has_many)
+	Through     string // Through model (for has_many_through)
+	ThroughFK   string // Through foreign key
+	ThroughTK   string // Through target key
+	Inverse     string // Relationship on the target pointing back, set when this one is loaded
 
 	// Generated function - zero reflection, atomic operation
 	// Scans database results directly into the model's relationship field
 	ScanToModel func(ctx context.Context, exec DBExecutor, query string, args []interface{}, model interface{}) error
+
+	// Generated functions for batched loading: ScanTargets scans the related records
+	// of many models at once, SetTargets sets those of one model on its field.
+	// Metadata generated before they existed is loaded one record at a time.
+	ScanTargets func(ctx context.Context, exec DBExecutor, query string, args []interface{}) ([]interface{}, error)
+	SetTargets  func(model interface{}, targets []interface{})
+}
+
+// targetTable returns the table of the target model. Metadata generated before
+// TargetTable existed names the target table in Target.
+func (r *RelationshipMetadata) targetTable() string {
+	if r.TargetTable != "" {
+		return r.TargetTable
+	}
+	return r.Target
+}
+
+// targetMetadata returns the registered metadata of the target model, or nil
+func (r *RelationshipMetadata) targetMetadata() *ModelMetadata {
+	if metadata := MetadataForTable(r.targetTable()); metadata != nil {
+		return metadata
+	}
+	for _, metadata := range RegisteredModels() {
+		if metadata.StructName == r.Target {
+			return metadata
+		}
+	}
+	return nil
 }
@@ -2,7 +2,10 @@ package orm
 
 import (
 	"context"
+	"fmt"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 // OperationType represents different types of database operations
@@ -19,22 +22,105 @@ const (
 	OpBulkUpdate OperationType = "bulk_update"
 	OpFind       OperationType = "find"
 	OpQuery      OperationType = "query"
+	OpImport     OperationType = "import"
 )
 
 // MiddlewareContext contains information passed to middleware
 type MiddlewareContext struct {
 	Operation    OperationType
+	Name         string // Method that runs the statement, such as "count" or "find_into"
 	TableName    string
+	Model        *ModelMetadata // Metadata of the model of the repository
 	Record       interface{}
 	Records      interface{}
 	QueryBuilder interface{} // squirrel.SelectBuilder, squirrel.InsertBuilder, etc.
 	Query        string
 	Args         []interface{}
 	Error        error
-	StartTime    time.Time
-	Duration     time.Duration
+	StartTime    time.Time     // When the middleware chain started
+	Duration     time.Duration // Time the statement took, set once it returns
 	Context      context.Context
-	Metadata     map[string]interface{}
+	Metadata     map[string]interface{} // Values middlewares pass each other, see MiddlewareKey
+
+	// executor is the connection or transaction of the repository, see BestEffort
+	executor DBExecutor
+}
+
+// MiddlewareKey is a typed key of MiddlewareContext.Metadata, so that middlewares
+// can pass values to each other without type assertions:
+//
+//	var tenantKey = orm.NewMiddlewareKey[string]("tenant")
+//
+//	tenantKey.Set(ctx, tenantFrom(ctx.Context)) // in one middleware
+//	tenant, ok := tenantKey.Get(ctx)          // in a later one
+type MiddlewareKey[V any] struct {
+	name string
+}
+
+// NewMiddlewareKey returns the key of the values stored under name
+func NewMiddlewareKey[V any](name string) MiddlewareKey[V] {
+	return MiddlewareKey[V]{name: name}
+}
+
+// Set stores value in the metadata of ctx
+func (k MiddlewareKey[V]) Set(ctx *MiddlewareContext, value V) {
+	if ctx.Metadata == nil {
+		ctx.Metadata = make(map[string]interface{})
+	}
+	ctx.Metadata[k.name] = value
+}
+
+// Get returns the value stored in the metadata of ctx, and whether there is one of
+// the type of the key
+func (k MiddlewareKey[V]) Get(ctx *MiddlewareContext) (V, bool) {
+	value, ok := ctx.Metadata[k.name].(V)
+	return value, ok
+}
+
+// bestEffortSavepoint is the savepoint the work of BestEffort runs under
+const bestEffortSavepoint = "storm_best_effort"
+
+// BestEffort runs fn, side work of a middleware such as writing an audit log, so that
+// its failure cannot break the operation. Inside a transaction, fn runs under a
+// savepoint that is rolled back when fn fails, which leaves the transaction usable
+// where a failed statement would otherwise abort it; outside one, fn runs on the
+// connection of the repository. The error of fn is returned for the middleware to
+// log, and only fails the operation if the middleware returns it:
+//
+//	repo.AddMiddleware(func(next orm.QueryMiddlewareFunc) orm.QueryMiddlewareFunc {
+//		return func(ctx *orm.MiddlewareContext) error {
+//			if err := next(ctx); err != nil {
+//				return err
+//			}
+//			if err := ctx.BestEffort(writeAuditLog(ctx)); err != nil {
+//				log.Printf("audit log: %v", err)
+//			}
+//			return nil
+//		}
+//	})
+func (ctx *MiddlewareContext) BestEffort(fn func(executor DBExecutor) error) error {
+	if ctx.executor == nil {
+		return fmt.Errorf("best-effort work needs the repository connection, which this context does not have")
+	}
+
+	tx, ok := ctx.executor.(*sqlx.Tx)
+	if !ok {
+		return fn(ctx.executor)
+	}
+
+	if _, err := tx.ExecContext(ctx.Context, "SAVEPOINT "+bestEffortSavepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx.Context, "ROLLBACK TO SAVEPOINT "+bestEffortSavepoint); rbErr != nil {
+			return fmt.Errorf("%w (and failed to roll back to savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx.Context, "RELEASE SAVEPOINT "+bestEffortSavepoint); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
 }
 
 // QueryMiddlewareFunc represents middleware that can modify queries
@@ -70,29 +156,47 @@ func (mm *middlewareManager) ExecuteMiddleware(ctx *MiddlewareContext, finalFunc
 
 // Repository middleware integration
 
-func (r *Repository[T]) executeQueryMiddleware(op OperationType, ctx context.Context, record interface{}, queryBuilder interface{}, finalFunc QueryMiddlewareFunc) error {
-	if r.middlewareManager == nil {
-		return finalFunc(&MiddlewareContext{
-			Operation:    op,
-			TableName:    r.metadata.TableName,
-			Record:       record,
-			QueryBuilder: queryBuilder,
-			Context:      ctx,
-			StartTime:    time.Now(),
-			Metadata:     make(map[string]interface{}),
-		})
+// executeQueryMiddleware runs finalFunc, which executes the statement of the operation,
+// through the middlewares of the repository. name is the method running it, see
+// MiddlewareContext.Name.
+func (r *Repository[T]) executeQueryMiddleware(op OperationType, name string, ctx context.Context, record interface{}, queryBuilder interface{}, finalFunc QueryMiddlewareFunc) error {
+	// Writes start the window in which the replica may be stale
+	if op != OpQuery && op != OpFind && r.storm != nil && r.storm.replica != nil {
+		execute := finalFunc
+		finalFunc = func(ctx *MiddlewareContext) error {
+			err := execute(ctx)
+			if err == nil {
+				r.storm.replica.markWrite()
+			}
+			return err
+		}
+	}
+
+	// Constraint violations name the fields they are about
+	resolve := finalFunc
+	finalFunc = func(ctx *MiddlewareContext) error {
+		start := time.Now()
+		err := resolve(ctx)
+		ctx.Duration = time.Since(start)
+		return r.metadata.resolveFields(err)
 	}
 
 	middlewareCtx := &MiddlewareContext{
 		Operation:    op,
+		Name:         name,
 		TableName:    r.metadata.TableName,
+		Model:        r.metadata,
 		Record:       record,
 		QueryBuilder: queryBuilder,
 		Context:      ctx,
 		StartTime:    time.Now(),
 		Metadata:     make(map[string]interface{}),
+		executor:     r.db,
 	}
 
+	if r.middlewareManager == nil {
+		return finalFunc(middlewareCtx)
+	}
 	return r.middlewareManager.ExecuteMiddleware(middlewareCtx, finalFunc)
 }
 
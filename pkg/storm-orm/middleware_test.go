@@ -225,7 +225,107 @@ func TestMiddlewareErrorHandling(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestMiddlewareBestEffort tests that best-effort middleware work cannot abort the transaction
+func TestMiddlewareBestEffort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := sqlx.NewDb(db, "postgres").Beginx()
+	require.NoError(t, err)
+
+	repo, err := NewRepositoryWithTx[TestUser](tx, createTestUserMetadata())
+	require.NoError(t, err)
+
+	var auditErr error
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			if err := next(ctx); err != nil {
+				return err
+			}
+			auditErr = ctx.BestEffort(func(executor DBExecutor) error {
+				_, err := executor.ExecContext(ctx.Context, "INSERT INTO audit_log (operation) VALUES ($1)", string(ctx.Operation))
+				return err
+			})
+			return nil
+		}
+	})
+
+	t.Run("failed work rolls back its savepoint", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SAVEPOINT storm_best_effort`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO audit_log`).WithArgs("delete").WillReturnError(fmt.Errorf("relation audit_log does not exist"))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT storm_best_effort`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		deleted, err := repo.DeleteByIDs(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		assert.ErrorContains(t, auditErr, "audit_log does not exist")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("successful work releases its savepoint", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM users`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`SAVEPOINT storm_best_effort`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO audit_log`).WithArgs("delete").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT storm_best_effort`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := repo.DeleteByIDs(context.Background(), 2)
+		require.NoError(t, err)
+		assert.NoError(t, auditErr)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 // TestMiddlewareCount tests middleware for Count operations with flexible SQL matching
+func TestMiddlewareContextFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	tenantKey := NewMiddlewareKey[int]("tenant")
+	var seen MiddlewareContext
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			tenantKey.Set(ctx, 42)
+			return next(ctx)
+		}
+	})
+	repo.AddMiddleware(func(next QueryMiddlewareFunc) QueryMiddlewareFunc {
+		return func(ctx *MiddlewareContext) error {
+			err := next(ctx)
+			seen = *ctx
+			return err
+		}
+	})
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+		WillDelayFor(5 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	_, err = repo.Query(context.Background()).Count()
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, OpQuery, seen.Operation)
+	assert.Equal(t, "count", seen.Name)
+	assert.Same(t, metadata, seen.Model)
+	assert.False(t, seen.StartTime.IsZero())
+	assert.GreaterOrEqual(t, seen.Duration, 5*time.Millisecond)
+
+	tenant, ok := tenantKey.Get(&seen)
+	assert.True(t, ok)
+	assert.Equal(t, 42, tenant)
+
+	_, ok = NewMiddlewareKey[string]("tenant").Get(&seen)
+	assert.False(t, ok, "a key of another type does not match the value")
+}
+
 func TestMiddlewareCount(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
 		// Just verify that both conditions are present
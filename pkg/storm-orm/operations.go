@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
@@ -14,6 +15,8 @@ type UpsertOptions struct {
 	ConflictColumns []string          // Columns that define conflicts (ON CONFLICT)
 	UpdateColumns   []string          // Columns to update on conflict (if empty, updates all non-conflict columns)
 	UpdateExpr      map[string]string // Custom update expressions (column -> expression)
+	Strategy        UpsertStrategy    // How UpsertMany sends its records, see UpsertStrategy
+	Merge           *MergeOptions     // Upsert with MERGE instead of ON CONFLICT, see MergeOptions
 }
 
 func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
@@ -39,10 +42,11 @@ func (r *Repository[T]) Create(ctx context.Context, record *T) (*T, error) {
 		Columns(columns...).
 		Values(values...)
 
-	err := r.executeQueryMiddleware(OpCreate, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+	err := r.executeQueryMiddleware(OpCreate, "create", ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
 
-		returningCols := r.getAutoGeneratedColumns()
+		// Auto timestamps are returned as well, so the record holds the time it was written with
+		returningCols := append(r.getAutoGeneratedColumns(), r.getAutoTimeColumns(true)...)
 
 		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
@@ -104,6 +108,9 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id}).
 		PlaceholderFormat(squirrel.Dollar).
 		Limit(1)
+	for _, scope := range r.scopeConditions() {
+		query = query.Where(scope)
+	}
 
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
@@ -123,6 +130,32 @@ func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error
 	return &record, nil
 }
 
+// FindByIDs returns the records with the given primary keys, in no particular
+// order. Keys beyond the IN chunk size (see SetInChunkSize) are queried in
+// several chunks and the results merged.
+func (r *Repository[T]) FindByIDs(ctx context.Context, ids ...interface{}) ([]T, error) {
+	if len(r.metadata.PrimaryKeys) != 1 {
+		return nil, &Error{
+			Op:    "findByIDs",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("composite primary keys not supported"),
+		}
+	}
+
+	records := make([]T, 0, len(ids))
+	for _, chunk := range chunkValues(ids) {
+		found, err := r.Query(ctx).
+			Where(Condition{squirrel.Eq{r.metadata.PrimaryKeys[0]: chunk}}).
+			Find()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+
+	return records, nil
+}
+
 func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 	if record == nil {
 		return nil, &Error{
@@ -145,7 +178,7 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 		query = query.Where(squirrel.Eq{pkCol: value})
 	}
 
-	err := r.executeQueryMiddleware(OpUpdate, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+	err := r.executeQueryMiddleware(OpUpdate, "update", ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.UpdateBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -157,6 +190,18 @@ func (r *Repository[T]) Update(ctx context.Context, record *T) (*T, error) {
 			}
 		}
 
+		// The record takes the auto_update_time it was written with
+		if autoTimeCols := r.getAutoTimeColumns(false); len(autoTimeCols) > 0 {
+			sqlQuery += " RETURNING " + strings.Join(autoTimeCols, ", ")
+			middlewareCtx.Query = sqlQuery
+			middlewareCtx.Args = args
+
+			if err := r.db.GetContext(ctx, record, sqlQuery, args...); err != nil {
+				return parsePostgreSQLError(err, "update", r.metadata.TableName)
+			}
+			return nil
+		}
+
 		middlewareCtx.Query = sqlQuery
 		middlewareCtx.Args = args
 
@@ -213,10 +258,16 @@ func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, update
 	for column, value := range updates {
 		query = query.Set(column, value)
 	}
+	now := time.Now()
+	for _, column := range r.getAutoTimeColumns(false) {
+		if _, set := updates[column]; !set {
+			query = query.Set(column, now)
+		}
+	}
 
 	var record *T
 
-	err := r.executeQueryMiddleware(OpUpdate, ctx, updates, query, func(middlewareCtx *MiddlewareContext) error {
+	err := r.executeQueryMiddleware(OpUpdate, "update_fields", ctx, updates, query, func(middlewareCtx *MiddlewareContext) error {
 		// First, fetch the record that will be updated (within middleware execution)
 		var err error
 		record, err = r.FindByID(ctx, id)
@@ -275,6 +326,9 @@ func (r *Repository[T]) UpdateFields(ctx context.Context, id interface{}, update
 	return record, nil
 }
 
+// Delete removes the record with the given primary key and returns it. Models with a
+// soft_delete column keep the row and set the column to now() instead, see Restore;
+// Query(ctx).Unscoped().Delete() removes such rows for good.
 func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error) {
 	if len(r.metadata.PrimaryKeys) != 1 {
 		return nil, &Error{
@@ -284,13 +338,11 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error)
 		}
 	}
 
-	query := squirrel.Delete(r.metadata.TableName).
-		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id}).
-		PlaceholderFormat(squirrel.Dollar)
+	query := r.deleteStatement(false, squirrel.Eq{r.metadata.PrimaryKeys[0]: id}, softDeleteScope(r.metadata))
 
 	var record *T
 
-	err := r.executeQueryMiddleware(OpDelete, ctx, id, query, func(middlewareCtx *MiddlewareContext) error {
+	err := r.executeQueryMiddleware(OpDelete, "delete", ctx, id, query, func(middlewareCtx *MiddlewareContext) error {
 		// First, fetch the record that will be deleted (within middleware execution)
 		var err error
 		record, err = r.FindByID(ctx, id)
@@ -298,7 +350,7 @@ func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (*T, error)
 			return err
 		}
 
-		finalQuery := middlewareCtx.QueryBuilder.(squirrel.DeleteBuilder)
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.Sqlizer)
 
 		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
@@ -349,16 +401,10 @@ func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error)
 		}
 	}
 
-	query := squirrel.Delete(r.metadata.TableName).
-		PlaceholderFormat(squirrel.Dollar)
+	query := r.deleteStatement(false, squirrel.Eq(r.getPrimaryKeyValues(*record)), softDeleteScope(r.metadata))
 
-	pkValues := r.getPrimaryKeyValues(*record)
-	for pkCol, value := range pkValues {
-		query = query.Where(squirrel.Eq{pkCol: value})
-	}
-
-	err := r.executeQueryMiddleware(OpDelete, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
-		finalQuery := middlewareCtx.QueryBuilder.(squirrel.DeleteBuilder)
+	err := r.executeQueryMiddleware(OpDelete, "delete_record", ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.Sqlizer)
 
 		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
@@ -400,6 +446,137 @@ func (r *Repository[T]) DeleteRecord(ctx context.Context, record *T) (*T, error)
 	return record, nil
 }
 
+// DeleteByIDs deletes the records with the given primary keys and returns the
+// number deleted. Large key sets are deleted in chunks (see SetInChunkSize),
+// inside one transaction unless the repository already runs in one.
+func (r *Repository[T]) DeleteByIDs(ctx context.Context, ids ...interface{}) (int64, error) {
+	if len(r.metadata.PrimaryKeys) != 1 {
+		return 0, &Error{
+			Op:    "deleteByIDs",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("composite primary keys not supported"),
+		}
+	}
+
+	chunks := chunkValues(ids)
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	deleteChunks := func(tx *sqlx.Tx) error {
+		for _, chunk := range chunks {
+			query := r.Query(ctx).Where(Condition{squirrel.Eq{r.metadata.PrimaryKeys[0]: chunk}})
+			if tx != nil {
+				query = query.WithTx(tx)
+			}
+			count, err := query.Delete()
+			if err != nil {
+				return err
+			}
+			deleted += count
+		}
+		return nil
+	}
+
+	var err error
+	if _, isDB := r.db.(*sqlx.DB); isDB && len(chunks) > 1 {
+		err = r.WithinTransaction(ctx, deleteChunks)
+	} else {
+		err = deleteChunks(nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// DeleteWhere deletes the records matching all the conditions and returns the
+// number deleted. It refuses to run without conditions; empty a table with
+// Query(ctx).AllowFullTable().Delete() instead.
+func (r *Repository[T]) DeleteWhere(ctx context.Context, conditions ...Condition) (int64, error) {
+	if len(conditions) == 0 {
+		return 0, &Error{
+			Op:    "deleteWhere",
+			Table: r.metadata.TableName,
+			Err:   ErrNoConditions,
+		}
+	}
+
+	query := r.Query(ctx)
+	for _, condition := range conditions {
+		query = query.Where(condition)
+	}
+	return query.Delete()
+}
+
+// Restore undoes the soft delete of the record with the given primary key, setting
+// its soft_delete column back to NULL, and returns it. It fails with ErrNotFound when
+// no deleted record has the key, and for models without a soft_delete column.
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) (*T, error) {
+	if len(r.metadata.PrimaryKeys) != 1 {
+		return nil, &Error{
+			Op:    "restore",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("composite primary keys not supported"),
+		}
+	}
+
+	column := r.metadata.SoftDelete
+	if column == "" {
+		return nil, &Error{
+			Op:    "restore",
+			Table: r.metadata.TableName,
+			Err:   fmt.Errorf("%s has no soft_delete column", r.metadata.StructName),
+		}
+	}
+
+	fields := r.metadata.FieldNames()
+	returning := make([]string, len(fields))
+	for i, field := range fields {
+		returning[i] = r.metadata.Columns[field].DBName
+	}
+
+	query := squirrel.Update(r.metadata.TableName).
+		Set(column, nil).
+		Where(squirrel.Eq{r.metadata.PrimaryKeys[0]: id}).
+		Where(squirrel.NotEq{column: nil}).
+		Suffix("RETURNING " + strings.Join(returning, ", ")).
+		PlaceholderFormat(squirrel.Dollar)
+	for _, scope := range r.declaredScopes() {
+		query = query.Where(scope)
+	}
+
+	var record T
+	err := r.executeQueryMiddleware(OpUpdate, "restore", ctx, id, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.UpdateBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "restore",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		if err := r.db.GetContext(ctx, &record, sqlQuery, args...); err != nil {
+			return parsePostgreSQLError(err, "restore", r.metadata.TableName)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
 func (r *Repository[T]) CreateMany(ctx context.Context, records []T) error {
 	if len(records) == 0 {
 		return nil
@@ -445,49 +622,23 @@ func (r *Repository[T]) CreateMany(ctx context.Context, records []T) error {
 		return nil
 	}
 
-	query := squirrel.Insert(r.metadata.TableName).
-		PlaceholderFormat(squirrel.Dollar).
-		Columns(columns...)
-
-	for _, record := range records {
-		_, values := r.getInsertFields(record)
-		query = query.Values(values...)
-	}
-
-	return r.executeQueryMiddleware(OpCreateMany, ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
-		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
-
-		sqlQuery, args, err := finalQuery.ToSql()
-		if err != nil {
-			return &Error{
-				Op:    "createMany",
-				Table: r.metadata.TableName,
-				Err:   fmt.Errorf("failed to build batch insert query: %w", err),
-			}
-		}
-
-		middlewareCtx.Query = sqlQuery
-		middlewareCtx.Args = args
-
-		_, err = executor.ExecContext(ctx, sqlQuery, args...)
-		if err != nil {
-			return parsePostgreSQLError(err, "createMany", r.metadata.TableName)
+	// Records beyond PostgreSQL's parameter limit go into further INSERTs of the
+	// same transaction
+	for _, chunk := range chunkRecords(records, len(columns)) {
+		if err := r.insertChunk(ctx, executor, chunk, false); err != nil {
+			return err
 		}
+	}
 
-		if needsCommit {
-			tx := executor.(*sqlx.Tx)
-			if err := tx.Commit(); err != nil {
-				return &Error{
-					Op:    "createMany",
-					Table: r.metadata.TableName,
-					Err:   fmt.Errorf("failed to commit transaction: %w", err),
-				}
-			}
-			rollback = nil
+	if needsCommit {
+		tx := executor.(*sqlx.Tx)
+		if err := tx.Commit(); err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", err), "createMany", r.metadata.TableName)
 		}
+		rollback = nil
+	}
 
-		return nil
-	})
+	return nil
 }
 
 func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOptions) error {
@@ -515,13 +666,20 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 			Err:   fmt.Errorf("no fields to insert"),
 		}
 	}
+	opts = r.touchUpdateTimes(columns, r.keepCreateTimes(columns, opts))
+
+	if opts.Merge != nil {
+		return r.inTransaction(ctx, "upsert", func(tx *sqlx.Tx) error {
+			return r.mergeRecords(ctx, tx, OpUpsert, record, []T{*record}, columns, opts)
+		})
+	}
 
 	query := squirrel.Insert(r.metadata.TableName).
 		PlaceholderFormat(squirrel.Dollar).
 		Columns(columns...).
 		Values(values...)
 
-	return r.executeQueryMiddleware(OpUpsert, ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+	return r.executeQueryMiddleware(OpUpsert, "upsert", ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -533,39 +691,7 @@ func (r *Repository[T]) Upsert(ctx context.Context, record *T, opts UpsertOption
 			}
 		}
 
-		onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(opts.ConflictColumns, ", "))
-
-		var updateColumns []string
-		if len(opts.UpdateColumns) > 0 {
-			updateColumns = opts.UpdateColumns
-		} else {
-			conflictSet := make(map[string]bool)
-			for _, col := range opts.ConflictColumns {
-				conflictSet[col] = true
-			}
-
-			for _, col := range columns {
-				if !conflictSet[col] {
-					updateColumns = append(updateColumns, col)
-				}
-			}
-		}
-
-		if len(updateColumns) > 0 {
-			var setParts []string
-			for _, col := range updateColumns {
-				if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
-					setParts = append(setParts, fmt.Sprintf("%s = %s", col, expr))
-				} else {
-					setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-				}
-			}
-			onConflict += " DO UPDATE SET " + strings.Join(setParts, ", ")
-		} else {
-			onConflict += " DO NOTHING"
-		}
-
-		finalSqlQuery := sqlQuery + onConflict
+		finalSqlQuery := sqlQuery + onConflictClause(columns, opts)
 
 		middlewareCtx.Query = finalSqlQuery
 		middlewareCtx.Args = args
@@ -631,7 +757,37 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 	if len(columns) == 0 {
 		return nil
 	}
+	opts = r.touchUpdateTimes(columns, r.keepCreateTimes(columns, opts))
+
+	if opts.Merge != nil {
+		if err := r.mergeRecords(ctx, executor.(*sqlx.Tx), OpUpsertMany, records, records, columns, opts); err != nil {
+			return err
+		}
+	} else if opts.Strategy.copies(len(records) * len(columns)) {
+		if err := r.upsertManyCopy(ctx, executor.(*sqlx.Tx), records, columns, opts); err != nil {
+			return err
+		}
+	} else {
+		// Records beyond PostgreSQL's parameter limit go into further statements of
+		// the same transaction
+		for _, chunk := range chunkRecords(records, len(columns)) {
+			if err := r.upsertChunk(ctx, executor, chunk, columns, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	if needsCommit {
+		if err := executor.(*sqlx.Tx).Commit(); err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", err), "upsertMany", r.metadata.TableName)
+		}
+		rollback = nil
+	}
+	return nil
+}
 
+// upsertChunk upserts records with one INSERT ... ON CONFLICT through the middleware
+func (r *Repository[T]) upsertChunk(ctx context.Context, executor DBExecutor, records []T, columns []string, opts UpsertOptions) error {
 	query := squirrel.Insert(r.metadata.TableName).
 		PlaceholderFormat(squirrel.Dollar).
 		Columns(columns...)
@@ -641,7 +797,7 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 		query = query.Values(values...)
 	}
 
-	return r.executeQueryMiddleware(OpUpsertMany, ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
+	return r.executeQueryMiddleware(OpUpsertMany, "upsert_many", ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -653,59 +809,132 @@ func (r *Repository[T]) UpsertMany(ctx context.Context, records []T, opts Upsert
 			}
 		}
 
-		onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(opts.ConflictColumns, ", "))
-		var updateColumns []string
-		if len(opts.UpdateColumns) > 0 {
-			updateColumns = opts.UpdateColumns
-		} else {
-			conflictSet := make(map[string]bool)
-			for _, col := range opts.ConflictColumns {
-				conflictSet[col] = true
-			}
+		finalSqlQuery := sqlQuery + onConflictClause(columns, opts)
 
-			for _, col := range columns {
-				if !conflictSet[col] {
-					updateColumns = append(updateColumns, col)
-				}
-			}
+		middlewareCtx.Query = finalSqlQuery
+		middlewareCtx.Args = args
+
+		_, err = executor.ExecContext(ctx, finalSqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(err, "upsertMany", r.metadata.TableName)
+		}
+
+		return nil
+	})
+}
+
+// keepCreateTimes leaves the auto_create_time columns out of the columns an upsert
+// updates by default, so that existing rows keep their creation time
+func (r *Repository[T]) keepCreateTimes(columns []string, opts UpsertOptions) UpsertOptions {
+	if len(opts.UpdateColumns) > 0 {
+		return opts
+	}
+
+	skip := make(map[string]bool)
+	for _, col := range r.metadata.Columns {
+		if col.AutoCreateTime {
+			skip[col.DBName] = true
 		}
+	}
+	if len(skip) == 0 {
+		return opts
+	}
+	for _, col := range opts.ConflictColumns {
+		skip[col] = true
+	}
+
+	for _, col := range columns {
+		if !skip[col] {
+			opts.UpdateColumns = append(opts.UpdateColumns, col)
+		}
+	}
+	return opts
+}
+
+// touchUpdateTimes sets the auto_update_time columns to now() whenever an upsert
+// updates an existing row, whether or not opts lists them, unless opts.UpdateExpr
+// gives them an expression
+func (r *Repository[T]) touchUpdateTimes(columns []string, opts UpsertOptions) UpsertOptions {
+	touched := r.getAutoTimeColumns(false)
+	if len(touched) == 0 {
+		return opts
+	}
 
-		if len(updateColumns) > 0 {
-			var setParts []string
-			for _, col := range updateColumns {
-				if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
-					setParts = append(setParts, fmt.Sprintf("%s = %s", col, expr))
-				} else {
-					setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
-				}
+	updateColumns := opts.UpdateColumns
+	if len(updateColumns) == 0 {
+		conflictSet := make(map[string]bool)
+		for _, col := range opts.ConflictColumns {
+			conflictSet[col] = true
+		}
+		for _, col := range columns {
+			if !conflictSet[col] {
+				updateColumns = append(updateColumns, col)
 			}
-			onConflict += " DO UPDATE SET " + strings.Join(setParts, ", ")
-		} else {
-			onConflict += " DO NOTHING"
 		}
+	}
 
-		finalSqlQuery := sqlQuery + onConflict
+	listed := make(map[string]bool)
+	for _, col := range updateColumns {
+		listed[col] = true
+	}
+	updateExpr := make(map[string]string, len(opts.UpdateExpr)+len(touched))
+	for col, expr := range opts.UpdateExpr {
+		updateExpr[col] = expr
+	}
 
-		middlewareCtx.Query = finalSqlQuery
-		middlewareCtx.Args = args
+	opts.UpdateColumns = append([]string{}, updateColumns...)
+	for _, col := range touched {
+		if !listed[col] {
+			opts.UpdateColumns = append(opts.UpdateColumns, col)
+		}
+		if _, hasCustom := updateExpr[col]; !hasCustom {
+			updateExpr[col] = "now()"
+		}
+	}
+	opts.UpdateExpr = updateExpr
+	return opts
+}
 
-		_, err = executor.ExecContext(ctx, finalSqlQuery, args...)
-		if err != nil {
-			return parsePostgreSQLError(err, "upsertMany", r.metadata.TableName)
+// onConflictClause returns the ON CONFLICT clause of an upsert inserting columns,
+// which updates the row as updateAssignments says, or does nothing when there is
+// no column to update
+func onConflictClause(columns []string, opts UpsertOptions) string {
+	onConflict := fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(opts.ConflictColumns, ", "))
+
+	setParts := updateAssignments(columns, opts)
+	if len(setParts) == 0 {
+		return onConflict + " DO NOTHING"
+	}
+	return onConflict + " DO UPDATE SET " + strings.Join(setParts, ", ")
+}
+
+// updateAssignments returns the SET assignments of an upsert inserting columns: the
+// non-conflict columns, or opts.UpdateColumns, are updated from the excluded row
+// unless opts.UpdateExpr gives them an expression
+func updateAssignments(columns []string, opts UpsertOptions) []string {
+	var updateColumns []string
+	if len(opts.UpdateColumns) > 0 {
+		updateColumns = opts.UpdateColumns
+	} else {
+		conflictSet := make(map[string]bool)
+		for _, col := range opts.ConflictColumns {
+			conflictSet[col] = true
 		}
 
-		if needsCommit {
-			tx := executor.(*sqlx.Tx)
-			if err := tx.Commit(); err != nil {
-				return &Error{
-					Op:    "upsertMany",
-					Table: r.metadata.TableName,
-					Err:   fmt.Errorf("failed to commit transaction: %w", err),
-				}
+		for _, col := range columns {
+			if !conflictSet[col] {
+				updateColumns = append(updateColumns, col)
 			}
-			rollback = nil
 		}
+	}
 
-		return nil
-	})
+	var setParts []string
+	for _, col := range updateColumns {
+		if expr, hasCustom := opts.UpdateExpr[col]; hasCustom {
+			setParts = append(setParts, fmt.Sprintf("%s = %s", col, expr))
+		} else {
+			setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+		}
+	}
+	return setParts
 }
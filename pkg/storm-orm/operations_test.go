@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -64,6 +65,48 @@ func TestFindByID(t *testing.T) {
 }
 
 // TestDeleteRecord tests the DeleteRecord operation
+func TestFindByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	SetInChunkSize(2)
+	defer SetInChunkSize(DefaultInChunkSize)
+
+	t.Run("large key sets are chunked", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(id IN \(\$1,\$2\)\)`).
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John").AddRow(2, "Jane"))
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(id IN \(\$1\)\)`).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Jim"))
+
+		users, err := repo.FindByIDs(context.Background(), 1, 2, 2, 3)
+		require.NoError(t, err)
+		require.Len(t, users, 3)
+		assert.Equal(t, "Jim", users[2].Name)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		users, err := repo.FindByIDs(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, users)
+	})
+
+	t.Run("chunk error stops the lookup", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .* FROM users WHERE \(id IN`).
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.FindByIDs(context.Background(), 1, 2, 3)
+		require.Error(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestDeleteRecord(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -118,6 +161,95 @@ func TestDeleteRecord(t *testing.T) {
 }
 
 // TestCreateMany tests the CreateMany operation
+func TestDeleteByIDs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	SetInChunkSize(2)
+	defer SetInChunkSize(DefaultInChunkSize)
+
+	t.Run("single chunk", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM users WHERE \(id IN \(\$1,\$2\)\)`).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		deleted, err := repo.DeleteByIDs(context.Background(), 1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("chunks share a transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM users WHERE \(id IN \(\$1,\$2\)\)`).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`DELETE FROM users WHERE \(id IN \(\$1\)\)`).
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		deleted, err := repo.DeleteByIDs(context.Background(), 1, 2, 3)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed chunk rolls back", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM users`).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`DELETE FROM users`).
+			WithArgs(3).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		deleted, err := repo.DeleteByIDs(context.Background(), 1, 2, 3)
+		require.Error(t, err)
+		assert.Zero(t, deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		deleted, err := repo.DeleteByIDs(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+}
+
+func TestDeleteWhere(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("with conditions", func(t *testing.T) {
+		mock.ExpectExec(`DELETE FROM users WHERE \(users.is_active = \$1 AND users.name = \$2\)`).
+			WithArgs(false, "temp").
+			WillReturnResult(sqlmock.NewResult(0, 4))
+
+		activeCol := Column[bool]{Name: "is_active", Table: "users"}
+		nameCol := Column[string]{Name: "name", Table: "users"}
+		deleted, err := repo.DeleteWhere(context.Background(), activeCol.Eq(false), nameCol.Eq("temp"))
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), deleted)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("without conditions", func(t *testing.T) {
+		_, err := repo.DeleteWhere(context.Background())
+		assert.ErrorIs(t, err, ErrNoConditions)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestCreateMany(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -149,6 +281,29 @@ func TestCreateMany(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("CreateMany splits records beyond the parameter limit", func(t *testing.T) {
+		defer func(limit int) { maxParameters = limit }(maxParameters)
+		maxParameters = 6
+
+		users := []TestUser{
+			{Name: "User1", Email: "user1@example.com"},
+			{Name: "User2", Email: "user2@example.com"},
+			{Name: "User3", Email: "user3@example.com"},
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO users .* VALUES \(\$1,\$2,\$3\),\(\$4,\$5,\$6\)$`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`INSERT INTO users .* VALUES \(\$1,\$2,\$3\)$`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateMany(context.Background(), users)
+		require.NoError(t, err)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("CreateMany with empty slice", func(t *testing.T) {
 		users := []TestUser{}
 
@@ -177,6 +332,29 @@ func TestCreateMany(t *testing.T) {
 	})
 }
 
+// TestCreateConstraintFields tests that constraint violations name the fields involved
+func TestCreateConstraintFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`INSERT INTO users`).
+		WillReturnError(&pq.Error{
+			Code:       "23505",
+			Message:    `duplicate key value violates unique constraint "users_email_key"`,
+			Detail:     "Key (email)=(user1@example.com) already exists.",
+			Constraint: "users_email_key",
+		})
+
+	_, err = repo.Create(context.Background(), &TestUser{Name: "User1", Email: "user1@example.com"})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.Equal(t, []string{"Email"}, GetFieldNames(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestUpsert tests the Upsert operation
 func TestUpsert(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -446,14 +624,46 @@ func TestQueryUpdate(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("Query Update without WHERE clause is refused", func(t *testing.T) {
+		isActiveCol := Column[bool]{Name: "is_active", Table: "users"}
+		rowsAffected, err := repo.Query(context.Background()).Update(
+			isActiveCol.Set(false),
+		)
+		assert.ErrorIs(t, err, ErrNoConditions)
+		assert.Equal(t, int64(0), rowsAffected)
+
+		// No SQL should be executed
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("Query Update without WHERE clause", func(t *testing.T) {
 		// Set up mock expectations - update all records
-		mock.ExpectExec(`UPDATE users SET is_active = \$1`).
+		mock.ExpectExec(`UPDATE users SET is_active = \$1$`).
 			WithArgs(false).
 			WillReturnResult(sqlmock.NewResult(0, 10))
 
 		// Execute Query Update without WHERE clause using Actions
 		isActiveCol := Column[bool]{Name: "is_active", Table: "users"}
+		rowsAffected, err := repo.Query(context.Background()).AllowFullTableUpdate().Update(
+			isActiveCol.Set(false),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), rowsAffected)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Query Update without WHERE clause allowed by Storm", func(t *testing.T) {
+		mock.ExpectExec(`UPDATE users SET is_active = \$1$`).
+			WithArgs(false).
+			WillReturnResult(sqlmock.NewResult(0, 10))
+
+		storm := NewStorm(sqlxDB)
+		repo.SetStorm(storm)
+		defer repo.SetStorm(nil)
+
+		storm.AllowFullTableUpdates(true)
+		isActiveCol := Column[bool]{Name: "is_active", Table: "users"}
 		rowsAffected, err := repo.Query(context.Background()).Update(
 			isActiveCol.Set(false),
 		)
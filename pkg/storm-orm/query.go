@@ -7,8 +7,15 @@ import (
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"strings"
+	"time"
 )
 
+// orderBy is an ORDER BY item with the parameters of its expression
+type orderBy struct {
+	sql  string
+	args []interface{}
+}
+
 // Query provides a fluent interface for building database queries with all features integrated
 type Query[T any] struct {
 	repo    *Repository[T]
@@ -19,8 +26,26 @@ type Query[T any] struct {
 	// Query options
 	limit       *uint64
 	offset      *uint64
-	orderBy     []string
+	orderBy     []orderBy
 	whereClause squirrel.And
+	scoped      int  // Leading conditions of whereClause that come from default scopes
+	unscoped    bool // Unscoped was called, so Delete removes soft-deleted rows for good
+	groupBy     []string
+	having      squirrel.And
+	projections map[string]squirrel.Sqlizer // Expressions selected in place of columns
+	aggregates  []aggregateColumn           // Expressions selected by Aggregate
+	windows     []aggregateColumn           // Expressions selected by FindInto
+	omitted     map[string]bool             // Columns left out by Select and Omit
+	lock        string                      // Row locking clause, see ForUpdate
+	lockWait    string                      // SKIP LOCKED or NOWAIT
+	sample      string                      // TABLESAMPLE clause, see Sample
+
+	// Allow Delete and Update without conditions
+	allowFullTable       bool
+	allowFullTableUpdate bool
+
+	// Read from the primary when the replica may be stale
+	readYourWrites bool
 
 	// Transaction support
 	tx *sqlx.Tx
@@ -28,6 +53,9 @@ type Query[T any] struct {
 	// Join support
 	joins    []join
 	includes []include
+
+	// Relationship counts selected by FindWithCounts
+	counts []include
 }
 
 func (r *Repository[T]) Query(ctx context.Context) *Query[T] {
@@ -36,12 +64,16 @@ func (r *Repository[T]) Query(ctx context.Context) *Query[T] {
 		builder: squirrel.Select(r.Columns()...).
 			From(r.metadata.TableName).
 			PlaceholderFormat(squirrel.Dollar),
-		ctx:         ctx,
-		whereClause: squirrel.And{},
-		joins:       make([]join, 0),
-		includes:    make([]include, 0),
+		ctx:                  ctx,
+		whereClause:          squirrel.And{},
+		joins:                make([]join, 0),
+		includes:             make([]include, 0),
+		allowFullTableUpdate: r.storm != nil && r.storm.allowFullTableUpdates,
 	}
 
+	query.whereClause = append(query.whereClause, r.scopeConditions()...)
+	query.scoped = len(query.whereClause)
+
 	for _, authFunc := range r.authorizeFuncs {
 		query = authFunc(ctx, query)
 	}
@@ -54,6 +86,16 @@ func (q *Query[T]) WithTx(tx *sqlx.Tx) *Query[T] {
 	return q
 }
 
+// Unscoped drops the default scopes of the repository and its model from the query,
+// e.g. to find soft-deleted rows, and makes Delete remove rows rather than soft
+// delete them. Conditions added by Authorize are kept.
+func (q *Query[T]) Unscoped() *Query[T] {
+	q.whereClause = append(squirrel.And{}, q.whereClause[q.scoped:]...)
+	q.scoped = 0
+	q.unscoped = true
+	return q
+}
+
 func (q *Query[T]) Where(condition Condition) *Query[T] {
 	if q.err != nil {
 		return q
@@ -66,7 +108,9 @@ func (q *Query[T]) OrderBy(expressions ...string) *Query[T] {
 	if q.err != nil {
 		return q
 	}
-	q.orderBy = append(q.orderBy, expressions...)
+	for _, expr := range expressions {
+		q.orderBy = append(q.orderBy, orderBy{sql: expr})
+	}
 	return q
 }
 
@@ -114,27 +158,48 @@ func (q *Query[T]) FullJoin(table, condition string) *Query[T] {
 	return q.Join(FullJoin, table, condition)
 }
 
+// Include loads relationships onto the records Find returns. A dot path, such as
+// "Posts.Comments.Author", also loads the relationships of the related records,
+// one level at a time.
 func (q *Query[T]) Include(relationships ...string) *Query[T] {
 	if q.err != nil {
 		return q
 	}
 	for _, rel := range relationships {
-		q.includes = append(q.includes, include{
-			name:       rel,
-			conditions: make([]Condition, 0),
-		})
+		q.includes, q.err = addInclude(q.includes, rel, IncludeOptions{})
+		if q.err != nil {
+			return q
+		}
 	}
 	return q
 }
 
+// IncludeWhere loads a relationship like Include, keeping only the related records
+// matching all the conditions. For a dot path the conditions apply to its last
+// relationship.
 func (q *Query[T]) IncludeWhere(relationship string, conditions ...Condition) *Query[T] {
 	if q.err != nil {
 		return q
 	}
-	q.includes = append(q.includes, include{
-		name:       relationship,
-		conditions: conditions,
-	})
+	q.includes, q.err = addInclude(q.includes, relationship, IncludeOptions{Where: conditions})
+	return q
+}
+
+// IncludeWith loads a relationship like IncludeWhere, ordered and limited per record
+// as opts says:
+//
+//	// The 5 latest comments of each post
+//	posts, err := storm.Posts.Query(ctx).
+//		IncludeWith("Comments", orm.IncludeOptions{
+//			OrderBy: []string{Comments.CreatedAt.Desc()},
+//			Limit:   5,
+//		}).
+//		Find()
+func (q *Query[T]) IncludeWith(relationship string, opts IncludeOptions) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	q.includes, q.err = addInclude(q.includes, relationship, opts)
 	return q
 }
 
@@ -142,28 +207,38 @@ func (q *Query[T]) buildQuery() (string, []interface{}, error) {
 	if q.err != nil {
 		return "", nil, q.err
 	}
+	if err := q.lockError(); err != nil {
+		return "", nil, err
+	}
 
-	builder := q.builder
+	builder := q.applyClauses(q.applyProjections(q.builder))
 
-	for _, join := range q.joins {
-		switch join.Type {
-		case InnerJoin:
-			builder = builder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case LeftJoin:
-			builder = builder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case RightJoin:
-			builder = builder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case FullJoin:
-			builder = builder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
-		}
+	baseSQL, baseArgs, err := builder.ToSql()
+	if err != nil {
+		return "", nil, err
 	}
 
+	return baseSQL, baseArgs, nil
+}
+
+// ToSQL returns the SELECT statement Find runs for the query, without the
+// queries that load included relationships
+func (q *Query[T]) ToSQL() (string, []interface{}, error) {
+	return q.buildQuery()
+}
+
+// applyClauses adds the joins, conditions, grouping, ordering and paging of the query to builder
+func (q *Query[T]) applyClauses(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
+	builder = q.applyJoins(builder)
+
 	if len(q.whereClause) > 0 {
 		builder = builder.Where(q.whereClause)
 	}
 
+	builder = q.applyGrouping(builder)
+
 	for _, orderBy := range q.orderBy {
-		builder = builder.OrderBy(orderBy)
+		builder = builder.OrderByClause(orderBy.sql, orderBy.args...)
 	}
 
 	if q.limit != nil {
@@ -174,52 +249,47 @@ func (q *Query[T]) buildQuery() (string, []interface{}, error) {
 		builder = builder.Offset(*q.offset)
 	}
 
-	baseSQL, baseArgs, err := builder.ToSql()
-	if err != nil {
-		return "", nil, err
-	}
-
-	return baseSQL, baseArgs, nil
+	return q.applyLock(builder)
 }
 
-func (q *Query[T]) Find() ([]T, error) {
-	if len(q.includes) > 0 {
-		return q.findWithRelationships()
-	}
-
-	finalBuilder := q.builder
-
+// applyJoins adds the joins of the query to builder
+func (q *Query[T]) applyJoins(builder squirrel.SelectBuilder) squirrel.SelectBuilder {
 	for _, join := range q.joins {
 		switch join.Type {
 		case InnerJoin:
-			finalBuilder = finalBuilder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition), join.Args...)
 		case LeftJoin:
-			finalBuilder = finalBuilder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition), join.Args...)
 		case RightJoin:
-			finalBuilder = finalBuilder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
+			builder = builder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition), join.Args...)
 		case FullJoin:
-			finalBuilder = finalBuilder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
+			builder = builder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition), join.Args...)
+		case CrossJoin:
+			builder = builder.CrossJoin(join.Table, join.Args...)
+		case "":
+			// Added by RawJoin, which holds the whole clause
+			builder = builder.JoinClause(join.Condition, join.Args...)
 		}
 	}
+	return builder
+}
 
-	if len(q.whereClause) > 0 {
-		finalBuilder = finalBuilder.Where(q.whereClause)
+func (q *Query[T]) Find() ([]T, error) {
+	if q.err != nil {
+		return nil, q.err
 	}
-
-	for _, orderBy := range q.orderBy {
-		finalBuilder = finalBuilder.OrderBy(orderBy)
+	if err := q.lockError(); err != nil {
+		return nil, err
 	}
 
-	if q.limit != nil {
-		finalBuilder = finalBuilder.Limit(*q.limit)
+	if len(q.includes) > 0 {
+		return q.findWithRelationships()
 	}
 
-	if q.offset != nil {
-		finalBuilder = finalBuilder.Offset(*q.offset)
-	}
+	finalBuilder := q.applyClauses(q.applyProjections(q.builder))
 
 	var records []T
-	err := q.repo.executeQueryMiddleware(OpQuery, q.ctx, nil, finalBuilder, func(middlewareCtx *MiddlewareContext) error {
+	err := q.repo.executeQueryMiddleware(OpQuery, "find", q.ctx, nil, finalBuilder, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -231,19 +301,14 @@ func (q *Query[T]) Find() ([]T, error) {
 			}
 		}
 
-		var execErr error
-		if q.tx != nil {
-			execErr = q.tx.SelectContext(q.ctx, &records, sqlQuery, args...)
-		} else {
+		reader := q.reader()
+		execErr := reader.SelectContext(q.ctx, &records, sqlQuery, args...)
+		if execErr == nil && len(records) == 0 && q.retryOnPrimary(reader) {
 			execErr = q.repo.db.SelectContext(q.ctx, &records, sqlQuery, args...)
 		}
 
 		if execErr != nil {
-			return &Error{
-				Op:    "find",
-				Table: q.repo.metadata.TableName,
-				Err:   fmt.Errorf("failed to execute query: %w", execErr),
-			}
+			return parsePostgreSQLError(fmt.Errorf("failed to execute query: %w", execErr), "find", q.repo.metadata.TableName)
 		}
 
 		return nil
@@ -271,29 +336,30 @@ func (q *Query[T]) First() (*T, error) {
 }
 
 func (q *Query[T]) Count() (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
 	countBuilder := squirrel.Select("COUNT(*)").
-		From(q.repo.metadata.TableName).
+		From(q.from()).
 		PlaceholderFormat(squirrel.Dollar)
 
-	for _, join := range q.joins {
-		switch join.Type {
-		case InnerJoin:
-			countBuilder = countBuilder.InnerJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case LeftJoin:
-			countBuilder = countBuilder.LeftJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case RightJoin:
-			countBuilder = countBuilder.RightJoin(fmt.Sprintf("%s ON %s", join.Table, join.Condition))
-		case FullJoin:
-			countBuilder = countBuilder.Join(fmt.Sprintf("FULL OUTER JOIN %s ON %s", join.Table, join.Condition))
-		}
-	}
+	countBuilder = q.applyJoins(countBuilder)
 
 	if len(q.whereClause) > 0 {
 		countBuilder = countBuilder.Where(q.whereClause)
 	}
 
+	// A grouped query returns a record per group, so the groups are counted
+	if len(q.groupBy) > 0 {
+		groups := q.applyGrouping(countBuilder.RemoveColumns().Column("1").PlaceholderFormat(squirrel.Question))
+		countBuilder = squirrel.Select("COUNT(*)").
+			FromSelect(groups, "groups").
+			PlaceholderFormat(squirrel.Dollar)
+	}
+
 	var count int64
-	err := q.repo.executeQueryMiddleware(OpQuery, q.ctx, nil, countBuilder, func(middlewareCtx *MiddlewareContext) error {
+	err := q.repo.executeQueryMiddleware(OpQuery, "count", q.ctx, nil, countBuilder, func(middlewareCtx *MiddlewareContext) error {
 		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
 
 		sqlQuery, args, err := finalQuery.ToSql()
@@ -305,19 +371,10 @@ func (q *Query[T]) Count() (int64, error) {
 			}
 		}
 
-		var execErr error
-		if q.tx != nil {
-			execErr = q.tx.GetContext(q.ctx, &count, sqlQuery, args...)
-		} else {
-			execErr = q.repo.db.GetContext(q.ctx, &count, sqlQuery, args...)
-		}
+		execErr := q.reader().GetContext(q.ctx, &count, sqlQuery, args...)
 
 		if execErr != nil {
-			return &Error{
-				Op:    "count",
-				Table: q.repo.metadata.TableName,
-				Err:   fmt.Errorf("failed to execute count query: %w", execErr),
-			}
+			return parsePostgreSQLError(fmt.Errorf("failed to execute count query: %w", execErr), "count", q.repo.metadata.TableName)
 		}
 
 		return nil
@@ -334,17 +391,53 @@ func (q *Query[T]) Exists() (bool, error) {
 	return count > 0, nil
 }
 
+// AllowFullTable lets Delete run without conditions and remove every row
+func (q *Query[T]) AllowFullTable() *Query[T] {
+	q.allowFullTable = true
+	return q
+}
+
+// Delete removes the records matching the query, or sets the soft_delete column of
+// those not deleted yet when the model has one and the query is not Unscoped. A
+// query without conditions is refused with ErrNoConditions unless AllowFullTable was
+// called.
 func (q *Query[T]) Delete() (int64, error) {
-	deleteBuilder := squirrel.Delete(q.repo.metadata.TableName).
-		PlaceholderFormat(squirrel.Dollar)
+	// A condition that failed to build must not widen the delete to every row
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	if len(q.groupBy) > 0 || len(q.having) > 0 {
+		return 0, &Error{
+			Op:    "delete",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("cannot delete the records of a grouped query"),
+		}
+	}
+
+	// Only a SELECT can sample, so the sample would be ignored
+	if q.sample != "" {
+		return 0, q.sampleError(fmt.Errorf("cannot delete the records of a sampled query"))
+	}
+
+	if len(q.whereClause) == q.scoped && !q.allowFullTable {
+		return 0, &Error{
+			Op:    "delete",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("%w: call AllowFullTable to delete every row", ErrNoConditions),
+		}
+	}
 
+	// Unless unscoped, the conditions hide the rows soft deleted already
+	var where squirrel.Sqlizer
 	if len(q.whereClause) > 0 {
-		deleteBuilder = deleteBuilder.Where(q.whereClause)
+		where = q.whereClause
 	}
+	deleteBuilder := q.repo.deleteStatement(q.unscoped, where)
 
 	var rowsAffected int64
-	err := q.repo.executeQueryMiddleware(OpDelete, q.ctx, nil, deleteBuilder, func(middlewareCtx *MiddlewareContext) error {
-		finalQuery := middlewareCtx.QueryBuilder.(squirrel.DeleteBuilder)
+	err := q.repo.executeQueryMiddleware(OpDelete, "delete", q.ctx, nil, deleteBuilder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.Sqlizer)
 
 		sqlQuery, args, err := finalQuery.ToSql()
 		if err != nil {
@@ -381,8 +474,20 @@ func (q *Query[T]) Delete() (int64, error) {
 	return rowsAffected, err
 }
 
-// Update updates records using type-safe Action operations
+// AllowFullTableUpdate lets Update run without conditions and change every row
+func (q *Query[T]) AllowFullTableUpdate() *Query[T] {
+	q.allowFullTableUpdate = true
+	return q
+}
+
+// Update updates records using type-safe Action operations. A query without
+// conditions is refused with ErrNoConditions unless AllowFullTableUpdate was
+// called or the Storm allows full table updates.
 func (q *Query[T]) Update(actions ...Action) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
 	if len(actions) == 0 {
 		return 0, &Error{
 			Op:    "update",
@@ -391,12 +496,41 @@ func (q *Query[T]) Update(actions ...Action) (int64, error) {
 		}
 	}
 
+	if len(q.groupBy) > 0 || len(q.having) > 0 {
+		return 0, &Error{
+			Op:    "update",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("cannot update the records of a grouped query"),
+		}
+	}
+
+	// Only a SELECT can sample, so the sample would be ignored
+	if q.sample != "" {
+		return 0, q.sampleError(fmt.Errorf("cannot update the records of a sampled query"))
+	}
+
+	if len(q.whereClause) == q.scoped && !q.allowFullTableUpdate {
+		return 0, &Error{
+			Op:    "update",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("%w: call AllowFullTableUpdate to update every row", ErrNoConditions),
+		}
+	}
+
 	// Build the update query with custom expressions
 	var setParts []string
 	var args []interface{}
 	argIndex := 1
 
 	for _, action := range actions {
+		if action.err != nil {
+			return 0, &Error{
+				Op:    "update",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("invalid action for %s: %w", action.column, action.err),
+			}
+		}
+
 		expression := action.Expression()
 		value := action.Value()
 
@@ -420,6 +554,20 @@ func (q *Query[T]) Update(actions ...Action) (int64, error) {
 		setParts = append(setParts, expression)
 	}
 
+	// Touch the auto_update_time columns the actions leave alone
+	now := time.Now()
+	for _, column := range q.repo.getAutoTimeColumns(false) {
+		touched := false
+		for _, action := range actions {
+			touched = touched || action.column == column || action.column == q.repo.metadata.TableName+"."+column
+		}
+		if !touched {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d", column, argIndex))
+			args = append(args, now)
+			argIndex++
+		}
+	}
+
 	// Build raw SQL since squirrel doesn't handle custom expressions well
 	baseSQL := fmt.Sprintf("UPDATE %s SET %s", q.repo.metadata.TableName, strings.Join(setParts, ", "))
 
@@ -452,7 +600,7 @@ func (q *Query[T]) Update(actions ...Action) (int64, error) {
 	}
 
 	var rowsAffected int64
-	err := q.repo.executeQueryMiddleware(OpUpdateMany, q.ctx, actions, baseSQL, func(middlewareCtx *MiddlewareContext) error {
+	err := q.repo.executeQueryMiddleware(OpUpdateMany, "update", q.ctx, actions, baseSQL, func(middlewareCtx *MiddlewareContext) error {
 		middlewareCtx.Query = baseSQL
 		middlewareCtx.Args = args
 
@@ -516,6 +664,15 @@ func (q *Query[T]) loadRelationship(records []T, include include) error {
 		return fmt.Errorf("relationship %s not found", include.name)
 	}
 
+	if relationship.ScanTargets != nil && relationship.SetTargets != nil {
+		if target := relationship.targetMetadata(); target != nil {
+			return q.loadRelationshipBatch(records, relationship, target, include)
+		}
+	}
+
+	if len(include.nested) > 0 {
+		return fmt.Errorf("relationship %s cannot load nested includes, regenerate its metadata", include.name)
+	}
 	if relationship.ScanToModel == nil {
 		return fmt.Errorf("relationship %s does not have ScanToModel function", include.name)
 	}
@@ -540,14 +697,9 @@ func (q *Query[T]) loadRelationship(records []T, include include) error {
 
 func (q *Query[T]) executeSingleRelationshipQuery(relationship *RelationshipMetadata, query string, args []interface{}, record *T) error {
 	// Use middleware system with proper transaction support
-	return q.repo.executeQueryMiddleware(OpQuery, q.ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
-		// Get the appropriate database executor (transaction-aware)
-		var executor DBExecutor
-		if q.tx != nil {
-			executor = q.tx
-		} else {
-			executor = q.repo.db
-		}
+	return q.repo.executeQueryMiddleware(OpQuery, "load_relationship", q.ctx, record, query, func(middlewareCtx *MiddlewareContext) error {
+		// Get the appropriate database executor (transaction and replica aware)
+		executor := q.reader()
 
 		// Execute the ScanToModel function with proper context
 		if err := relationship.ScanToModel(q.ctx, executor, query, args, record); err != nil {
@@ -603,12 +755,7 @@ func (q *Query[T]) buildBelongsToSingleQuery(relationship *RelationshipMetadata,
 		Where(squirrel.Eq{relationship.TargetKey: fkValue}).
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Apply conditions from IncludeWhere
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
-	}
-
-	return query.ToSql()
+	return include.apply(query).ToSql()
 }
 
 func (q *Query[T]) buildHasOneSingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
@@ -643,12 +790,7 @@ func (q *Query[T]) buildHasOneSingleQuery(relationship *RelationshipMetadata, re
 		Where(squirrel.Eq{relationship.ForeignKey: sourceValue}).
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Apply conditions from IncludeWhere
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
-	}
-
-	return query.ToSql()
+	return include.apply(query).ToSql()
 }
 
 func (q *Query[T]) buildHasManySingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
@@ -683,12 +825,7 @@ func (q *Query[T]) buildHasManySingleQuery(relationship *RelationshipMetadata, r
 		Where(squirrel.Eq{relationship.ForeignKey: sourceValue}).
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Apply conditions from IncludeWhere
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
-	}
-
-	return query.ToSql()
+	return include.apply(query).ToSql()
 }
 
 func (q *Query[T]) buildHasManyThroughSingleQuery(relationship *RelationshipMetadata, record T, include include) (string, []interface{}, error) {
@@ -727,12 +864,7 @@ func (q *Query[T]) buildHasManyThroughSingleQuery(relationship *RelationshipMeta
 		Where(squirrel.Eq{"jt." + relationship.ThroughFK: sourceValue}).
 		PlaceholderFormat(squirrel.Dollar)
 
-	// Apply conditions from IncludeWhere
-	for _, condition := range include.conditions {
-		query = query.Where(condition.ToSqlizer())
-	}
-
-	return query.ToSql()
+	return include.apply(query).ToSql()
 }
 
 // isZeroValue checks if a value is the zero value for its type
@@ -774,11 +906,7 @@ func (q *Query[T]) ExecuteRaw(query string, args ...interface{}) ([]T, error) {
 	}
 
 	if err != nil {
-		return nil, &Error{
-			Op:    "executeRaw",
-			Table: q.repo.metadata.TableName,
-			Err:   fmt.Errorf("failed to execute raw query: %w", err),
-		}
+		return nil, parsePostgreSQLError(fmt.Errorf("failed to execute raw query: %w", err), "executeRaw", q.repo.metadata.TableName)
 	}
 
 	return records, nil
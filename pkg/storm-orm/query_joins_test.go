@@ -3,8 +3,10 @@ package orm
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -35,3 +37,189 @@ func TestQueryJoinRelationship(t *testing.T) {
 		assert.Equal(t, query, result) // Should return self for chaining
 	})
 }
+
+func TestQueryWhereHas(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Posts": {
+			Name:        "Posts",
+			Type:        "has_many",
+			Target:      "Post",
+			TargetTable: "posts",
+			ForeignKey:  "author_id",
+			SourceKey:   "id",
+		},
+		"Team": {
+			Name:        "Team",
+			Type:        "belongs_to",
+			Target:      "Team",
+			TargetTable: "teams",
+			ForeignKey:  "Name",
+			TargetKey:   "name",
+		},
+		"Tags": {
+			Name:        "Tags",
+			Type:        "has_many_through",
+			Target:      "Tag",
+			TargetTable: "tags",
+			Through:     "user_tags",
+			ThroughFK:   "user_id",
+			ThroughTK:   "tag_id",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+	ctx := context.Background()
+	published := BoolColumn{Column: Column[bool]{Name: "published", Table: "posts"}}
+	tagName := StringColumn{Column: Column[string]{Name: "name", Table: "tags"}}
+
+	t.Run("has_many with conditions", func(t *testing.T) {
+		query, args, err := repo.Query(ctx).
+			Where(Condition{squirrel.Eq{"users.is_active": true}}).
+			WhereHas("Posts", published.Eq(true)).
+			buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "WHERE (users.is_active = $1 AND EXISTS (SELECT 1 FROM posts WHERE posts.author_id = users.id AND posts.published = $2))")
+		assert.Equal(t, []interface{}{true, true}, args)
+	})
+
+	t.Run("doesnt have", func(t *testing.T) {
+		query, args, err := repo.Query(ctx).WhereDoesntHave("Posts").buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "WHERE (NOT EXISTS (SELECT 1 FROM posts WHERE posts.author_id = users.id))")
+		assert.Empty(t, args)
+	})
+
+	t.Run("belongs_to resolves field names", func(t *testing.T) {
+		query, _, err := repo.Query(ctx).WhereHas("Team").buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "EXISTS (SELECT 1 FROM teams WHERE teams.name = users.name)")
+	})
+
+	t.Run("has_many_through", func(t *testing.T) {
+		query, args, err := repo.Query(ctx).WhereHas("Tags", tagName.Eq("go")).buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "EXISTS (SELECT 1 FROM user_tags JOIN tags ON tags.id = user_tags.tag_id WHERE user_tags.user_id = users.id AND tags.name = $1)")
+		assert.Equal(t, []interface{}{"go"}, args)
+	})
+
+	t.Run("count uses the subquery", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE \(EXISTS \(SELECT 1 FROM posts WHERE posts.author_id = users.id AND posts.published = \$1\)\)`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		count, err := repo.Query(ctx).WhereHas("Posts", published.Eq(true)).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("self-referential relationships alias the related table", func(t *testing.T) {
+		metadata.Relationships["Inviter"] = &RelationshipMetadata{
+			Name:        "Inviter",
+			Type:        "belongs_to",
+			Target:      "TestUser",
+			TargetTable: "users",
+			ForeignKey:  "invited_by",
+			TargetKey:   "id",
+		}
+		metadata.Relationships["Invitees"] = &RelationshipMetadata{
+			Name:        "Invitees",
+			Type:        "has_many",
+			Target:      "TestUser",
+			TargetTable: "users",
+			ForeignKey:  "invited_by",
+			SourceKey:   "id",
+		}
+		inviterActive := BoolColumn{Column: Column[bool]{Name: "is_active", Table: RelatedAlias}}
+
+		query, args, err := repo.Query(ctx).WhereHas("Inviter", inviterActive.Eq(true)).buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "EXISTS (SELECT 1 FROM users AS r1 WHERE r1.id = users.invited_by AND r1.is_active = $1)")
+		assert.Equal(t, []interface{}{true}, args)
+
+		query, _, err = repo.Query(ctx).WhereDoesntHave("Invitees").buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "NOT EXISTS (SELECT 1 FROM users AS r1 WHERE r1.invited_by = users.id)")
+	})
+
+	t.Run("unknown relationship", func(t *testing.T) {
+		_, err := repo.Query(ctx).WhereHas("Comments").Find()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "relationship Comments not found")
+	})
+}
+
+type joinArticle struct {
+	ID        int64      `db:"id"`
+	AuthorID  int64      `db:"author_id"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+func init() {
+	RegisterMetadata[joinArticle](&ModelMetadata{
+		TableName:  "join_articles",
+		StructName: "joinArticle",
+		Columns: map[string]*ColumnMetadata{
+			"ID":        {FieldName: "ID", DBName: "id", IsPrimaryKey: true},
+			"AuthorID":  {FieldName: "AuthorID", DBName: "author_id"},
+			"DeletedAt": {FieldName: "DeletedAt", DBName: "deleted_at", IsPointer: true},
+		},
+		PrimaryKeys: []string{"id"},
+		SoftDelete:  "deleted_at",
+	})
+}
+
+func TestRelationshipsSkipSoftDeletedRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Relationships = map[string]*RelationshipMetadata{
+		"Articles": {
+			Name:        "Articles",
+			Type:        "has_many",
+			Target:      "joinArticle",
+			TargetTable: "join_articles",
+			ForeignKey:  "author_id",
+			SourceKey:   "id",
+		},
+	}
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("WhereHas", func(t *testing.T) {
+		query, _, err := repo.Query(ctx).WhereHas("Articles").buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "EXISTS (SELECT 1 FROM join_articles WHERE join_articles.author_id = users.id AND join_articles.deleted_at IS NULL)")
+
+		query, _, err = repo.Query(ctx).WhereDoesntHave("Articles").buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "NOT EXISTS (SELECT 1 FROM join_articles WHERE join_articles.author_id = users.id AND join_articles.deleted_at IS NULL)")
+	})
+
+	t.Run("WithCount", func(t *testing.T) {
+		mock.ExpectQuery(`\(SELECT COUNT\(\*\) FROM join_articles WHERE join_articles.author_id = users.id AND join_articles.deleted_at IS NULL\) AS storm_count_0 FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "storm_count_0"}).AddRow(1, "alice", 2))
+
+		results, err := repo.Query(ctx).WithCount("Articles").FindWithCounts()
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, map[string]int64{"Articles": 2}, results[0].Counts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("JoinRelationship", func(t *testing.T) {
+		query, _, err := repo.Query(ctx).JoinRelationship("Articles", InnerJoin).buildQuery()
+		require.NoError(t, err)
+		assert.Contains(t, query, "JOIN join_articles ON users.id = join_articles.author_id AND join_articles.deleted_at IS NULL")
+	})
+}
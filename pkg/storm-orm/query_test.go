@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -278,6 +279,24 @@ func TestQueryDelete(t *testing.T) {
 
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("Delete without conditions is refused", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).Delete()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrNoConditions)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Delete without conditions when allowed", func(t *testing.T) {
+		mock.ExpectExec(`^DELETE FROM users$`).
+			WillReturnResult(sqlmock.NewResult(0, 12))
+
+		rowsAffected, err := repo.Query(context.Background()).AllowFullTable().Delete()
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), rowsAffected)
+
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 // TestQueryJoins tests join methods
@@ -415,3 +434,26 @@ func TestQueryExecuteRaw(t *testing.T) {
 		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+// TestQueryErrorMapping tests that query errors carry the typed database errors
+func TestQueryErrorMapping(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	mock.ExpectQuery(`SELECT .* FROM users`).
+		WillReturnError(&pq.Error{Code: "40001", Message: "could not serialize access due to read/write dependencies among transactions"})
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+		WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+
+	_, err = repo.Query(context.Background()).Find()
+	assert.ErrorIs(t, err, ErrSerializationFailure)
+	assert.True(t, IsRetryable(err))
+
+	_, err = repo.Query(context.Background()).Count()
+	assert.ErrorIs(t, err, ErrTimeout)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrRefreshInProgress is returned by a locked refresh of a materialized view that
+// another session is already refreshing
+var ErrRefreshInProgress = errors.New("materialized view refresh already in progress")
+
+// refreshLockPrefix namespaces the advisory locks guarding refreshes
+const refreshLockPrefix = "storm_refresh:"
+
+// RefreshOptions controls how a materialized view is refreshed
+type RefreshOptions struct {
+	// Concurrently keeps the view readable while it is refreshed. PostgreSQL needs a
+	// unique index on the view for it, and refuses it for a view that was never populated.
+	Concurrently bool
+
+	// Lock guards the refresh with a transaction-level advisory lock on the view name.
+	// A refresh started while another one runs returns ErrRefreshInProgress straight
+	// away instead of queuing behind it.
+	Lock bool
+}
+
+// RefreshMaterializedView refreshes the materialized view named view. Inside a
+// transaction the refresh, and its lock, are part of it; otherwise the refresh runs
+// in a transaction of its own.
+func (s *Storm) RefreshMaterializedView(ctx context.Context, view string, opts RefreshOptions) error {
+	executor := s.executor
+	if db := s.GetDB(); db != nil && !s.isInTransaction() {
+		executor = db
+	}
+	return refreshMaterializedView(ctx, executor, view, opts)
+}
+
+// Refresh refreshes the materialized view the repository reads
+func (r *Repository[T]) Refresh(ctx context.Context, opts RefreshOptions) error {
+	return refreshMaterializedView(ctx, r.db, r.metadata.TableName, opts)
+}
+
+// refreshMaterializedView refreshes view through executor, in a transaction of its
+// own when executor is a connection pool, since the advisory lock lasts as long as
+// the transaction holding it
+func refreshMaterializedView(ctx context.Context, executor DBExecutor, view string, opts RefreshOptions) error {
+	db, ok := executor.(*sqlx.DB)
+	if !ok {
+		return refreshInTransaction(ctx, executor, view, opts)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("refresh %s: failed to begin transaction: %w", view, err)
+	}
+	defer tx.Rollback()
+
+	if err := refreshInTransaction(ctx, tx, view, opts); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("refresh %s: failed to commit: %w", view, err)
+	}
+	return nil
+}
+
+func refreshInTransaction(ctx context.Context, executor DBExecutor, view string, opts RefreshOptions) error {
+	if opts.Lock {
+		var locked bool
+		if err := executor.GetContext(ctx, &locked, "SELECT pg_try_advisory_xact_lock(hashtext($1))", refreshLockPrefix+view); err != nil {
+			return fmt.Errorf("refresh %s: failed to take the refresh lock: %w", view, err)
+		}
+		if !locked {
+			return fmt.Errorf("refresh %s: %w", view, ErrRefreshInProgress)
+		}
+	}
+
+	stmt := "REFRESH MATERIALIZED VIEW "
+	if opts.Concurrently {
+		stmt += "CONCURRENTLY "
+	}
+	if _, err := executor.ExecContext(ctx, stmt+quoteQualifiedIdentifier(view)); err != nil {
+		return parsePostgreSQLError(err, "refresh", view)
+	}
+	return nil
+}
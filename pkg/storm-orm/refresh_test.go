@@ -0,0 +1,65 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshMaterializedView(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+	ctx := context.Background()
+
+	t.Run("plain", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^REFRESH MATERIALIZED VIEW "reporting"\."daily_sales"$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		require.NoError(t, storm.RefreshMaterializedView(ctx, "reporting.daily_sales", RefreshOptions{}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("concurrently with lock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock\(hashtext\(\$1\)\)`).
+			WithArgs("storm_refresh:daily_sales").
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+		mock.ExpectExec(`^REFRESH MATERIALIZED VIEW CONCURRENTLY "daily_sales"$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		require.NoError(t, storm.RefreshMaterializedView(ctx, "daily_sales", RefreshOptions{Concurrently: true, Lock: true}))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skipped while another refresh holds the lock", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs("storm_refresh:daily_sales").
+			WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+		mock.ExpectRollback()
+
+		err := storm.RefreshMaterializedView(ctx, "daily_sales", RefreshOptions{Lock: true})
+		assert.ErrorIs(t, err, ErrRefreshInProgress)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("inside a transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`^REFRESH MATERIALIZED VIEW "daily_sales"$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := storm.WithTransaction(ctx, func(tx *Storm) error {
+			return tx.RefreshMaterializedView(ctx, "daily_sales", RefreshOptions{})
+		})
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,87 @@
+package orm
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu     sync.RWMutex
+	registryByType = make(map[reflect.Type]*ModelMetadata)
+)
+
+// RegisterMetadata makes the metadata of T available through Metadata and
+// RegisteredModels. Generated metadata files call it from init.
+func RegisterMetadata[T any](metadata *ModelMetadata) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registryByType[reflect.TypeOf((*T)(nil)).Elem()] = metadata
+}
+
+// Metadata returns the generated metadata of T, or nil when T is not a
+// generated model. Applications can build admin screens, exporters and filters
+// on the same table, column and relationship information the ORM uses:
+//
+//	meta := storm.Metadata[models.User]()
+//	for _, field := range meta.FieldNames() {
+//		column := meta.Columns[field]
+//		fmt.Println(column.DBName, column.DBType, column.IsNullable)
+//	}
+//
+// The returned metadata is shared and must not be modified.
+func Metadata[T any]() *ModelMetadata {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registryByType[reflect.TypeOf((*T)(nil)).Elem()]
+}
+
+// MetadataForTable returns the metadata of the model stored in table, or nil
+func MetadataForTable(table string) *ModelMetadata {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for _, metadata := range registryByType {
+		if metadata.TableName == table {
+			return metadata
+		}
+	}
+	return nil
+}
+
+// RegisteredModels returns the metadata of every registered model, ordered by
+// struct name
+func RegisteredModels() []*ModelMetadata {
+	registryMu.RLock()
+	models := make([]*ModelMetadata, 0, len(registryByType))
+	for _, metadata := range registryByType {
+		models = append(models, metadata)
+	}
+	registryMu.RUnlock()
+
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].StructName < models[j].StructName
+	})
+	return models
+}
+
+// FieldNames returns the Go field names of the columns in declaration order.
+// Metadata generated before the order was recorded lists primary keys first
+// and the rest by name.
+func (m *ModelMetadata) FieldNames() []string {
+	if len(m.FieldOrder) > 0 {
+		return append([]string(nil), m.FieldOrder...)
+	}
+
+	names := make([]string, 0, len(m.Columns))
+	for name := range m.Columns {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := m.Columns[names[i]].IsPrimaryKey, m.Columns[names[j]].IsPrimaryKey
+		if pi != pj {
+			return pi
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,56 @@
+package orm
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type registryGadget struct {
+	ID    string
+	Name  string
+	Price int
+}
+
+type registryWidget struct {
+	ID string
+}
+
+func TestMetadataRegistry(t *testing.T) {
+	gadgets := &ModelMetadata{
+		TableName:  "registry_gadgets",
+		StructName: "registryGadget",
+		Columns: map[string]*ColumnMetadata{
+			"ID":    {FieldName: "ID", DBName: "id", IsPrimaryKey: true},
+			"Name":  {FieldName: "Name", DBName: "name"},
+			"Price": {FieldName: "Price", DBName: "price"},
+		},
+		FieldOrder: []string{"ID", "Price", "Name"},
+	}
+	widgets := &ModelMetadata{
+		TableName:  "registry_widgets",
+		StructName: "registryWidget",
+		Columns:    map[string]*ColumnMetadata{"ID": {FieldName: "ID", DBName: "id", IsPrimaryKey: true}},
+	}
+	RegisterMetadata[registryGadget](gadgets)
+	RegisterMetadata[registryWidget](widgets)
+
+	assert.Same(t, gadgets, Metadata[registryGadget]())
+	assert.Nil(t, Metadata[*registryGadget](), "pointers are a different type")
+	assert.Nil(t, Metadata[struct{ Unregistered bool }]())
+
+	assert.Same(t, widgets, MetadataForTable("registry_widgets"))
+	assert.Nil(t, MetadataForTable("missing"))
+
+	var names []string
+	for _, metadata := range RegisteredModels() {
+		names = append(names, metadata.StructName)
+	}
+	assert.Subset(t, names, []string{"registryGadget", "registryWidget"})
+	assert.Less(t, slices.Index(names, "registryGadget"), slices.Index(names, "registryWidget"))
+
+	assert.Equal(t, []string{"ID", "Price", "Name"}, gadgets.FieldNames())
+	gadgets.FieldOrder = nil
+	assert.Equal(t, []string{"ID", "Name", "Price"}, gadgets.FieldNames(), "without a recorded order, primary keys come first")
+}
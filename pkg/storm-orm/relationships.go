@@ -56,6 +56,8 @@ type relationshipDef struct {
 type include struct {
 	name       string
 	conditions []Condition // Additional conditions for the relationship
+	orderBy    []string    // Order of the related records of each record
+	limit      uint64      // Related records kept per record, 0 for all
 	nested     []include   // Nested includes (e.g., "Author.Team")
 }
 
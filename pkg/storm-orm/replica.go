@@ -0,0 +1,92 @@
+package orm
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultReplicaMaxLag is how long after a write ReadYourWrites queries read from
+// the primary when ReplicaOptions.MaxLag is not set
+const DefaultReplicaMaxLag = time.Second
+
+// ReplicaOptions configures the read replica of a Storm
+type ReplicaOptions struct {
+	// MaxLag is the longest the replica is expected to trail the primary. Within
+	// MaxLag of a write through the Storm, ReadYourWrites queries use the primary.
+	MaxLag time.Duration
+}
+
+// replica is the read replica of a Storm, shared with its transactions
+type replica struct {
+	executor  DBExecutor
+	maxLag    time.Duration
+	lastWrite atomic.Int64 // Unix nanoseconds of the last successful write
+}
+
+func (r *replica) markWrite() {
+	r.lastWrite.Store(time.Now().UnixNano())
+}
+
+// mayBeStale reports whether the last write may not have reached the replica yet
+func (r *replica) mayBeStale() bool {
+	last := r.lastWrite.Load()
+	return last != 0 && time.Since(time.Unix(0, last)) < r.maxLag
+}
+
+// UseReplica sends the reads of queries that run outside a transaction to db, a
+// streaming replica of the primary. Writes, FindByID, ExecuteRaw and everything
+// inside a transaction keep using the primary. Queries that must see the
+// caller's own writes opt in with Query.ReadYourWrites. Call it before the
+// Storm is used.
+func (s *Storm) UseReplica(db *sqlx.DB, opts ...ReplicaOptions) {
+	options := ReplicaOptions{MaxLag: DefaultReplicaMaxLag}
+	if len(opts) > 0 && opts[0].MaxLag > 0 {
+		options.MaxLag = opts[0].MaxLag
+	}
+
+	var executor DBExecutor = db
+	if s.logger != nil {
+		executor = &loggingExecutor{executor: db, logger: s.logger}
+	}
+
+	s.replica = &replica{executor: executor, maxLag: options.MaxLag}
+}
+
+// ReadYourWrites protects the query against replica lag. It reads from the
+// primary while the last write through the Storm may not have reached the
+// replica, and Find retries an empty replica result on the primary.
+func (q *Query[T]) ReadYourWrites() *Query[T] {
+	q.readYourWrites = true
+	return q
+}
+
+// readReplica returns the replica reads of the repository may use, or nil when
+// there is none or the repository runs in a transaction
+func (r *Repository[T]) readReplica() *replica {
+	if r.storm == nil || r.storm.replica == nil || r.storm.isInTransaction() {
+		return nil
+	}
+	return r.storm.replica
+}
+
+// reader returns the executor for the reads of the query
+func (q *Query[T]) reader() DBExecutor {
+	if q.tx != nil {
+		return q.tx
+	}
+
+	// Rows can only be locked on the primary
+	replica := q.repo.readReplica()
+	if replica == nil || q.lock != "" || (q.readYourWrites && replica.mayBeStale()) {
+		return q.repo.db
+	}
+	return replica.executor
+}
+
+// retryOnPrimary reports whether an empty result read through reader should be
+// read again from the primary
+func (q *Query[T]) retryOnPrimary(reader DBExecutor) bool {
+	return q.readYourWrites && q.tx == nil && reader != q.repo.db
+}
@@ -0,0 +1,100 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicaReads(t *testing.T) {
+	primaryDB, primary, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primaryDB.Close()
+	replicaDB, replica, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replicaDB.Close()
+
+	sqlxPrimary := sqlx.NewDb(primaryDB, "postgres")
+	storm := NewStorm(sqlxPrimary)
+	storm.UseReplica(sqlx.NewDb(replicaDB, "postgres"), ReplicaOptions{MaxLag: time.Hour})
+
+	repo, err := NewRepository[TestUser](sqlxPrimary, createTestUserMetadata())
+	require.NoError(t, err)
+	repo.SetStorm(storm)
+
+	ctx := context.Background()
+	nameCol := Column[string]{Name: "name", Table: "users"}
+	userRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "John")
+	}
+	expectationsMet := func(t *testing.T) {
+		require.NoError(t, primary.ExpectationsWereMet())
+		require.NoError(t, replica.ExpectationsWereMet())
+	}
+
+	t.Run("queries read from the replica", func(t *testing.T) {
+		replica.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(userRows())
+		replica.ExpectQuery(`SELECT COUNT\(\*\) FROM users`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		users, err := repo.Query(ctx).Find()
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		count, err := repo.Query(ctx).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		expectationsMet(t)
+	})
+
+	t.Run("locking reads use the primary", func(t *testing.T) {
+		primary.ExpectQuery(`SELECT .* FROM users FOR UPDATE$`).WillReturnRows(userRows())
+
+		users, err := repo.Query(ctx).ForUpdate().Find()
+		require.NoError(t, err)
+		assert.Len(t, users, 1)
+		expectationsMet(t)
+	})
+
+	t.Run("empty replica result is retried on the primary", func(t *testing.T) {
+		replica.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		primary.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(userRows())
+
+		user, err := repo.Query(ctx).ReadYourWrites().Where(nameCol.Eq("John")).First()
+		require.NoError(t, err)
+		assert.Equal(t, "John", user.Name)
+		expectationsMet(t)
+	})
+
+	t.Run("empty replica result without ReadYourWrites", func(t *testing.T) {
+		replica.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		users, err := repo.Query(ctx).Find()
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		expectationsMet(t)
+	})
+
+	t.Run("reads after a write use the primary", func(t *testing.T) {
+		primary.ExpectExec(`UPDATE users SET name = \$1 WHERE`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		primary.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(userRows())
+		replica.ExpectQuery(`SELECT .* FROM users`).WillReturnRows(userRows())
+
+		_, err := repo.Query(ctx).Where(nameCol.Eq("Jon")).Update(nameCol.Set("John"))
+		require.NoError(t, err)
+
+		_, err = repo.Query(ctx).ReadYourWrites().Find()
+		require.NoError(t, err)
+
+		// Queries that did not opt in keep reading from the replica
+		_, err = repo.Query(ctx).Find()
+		require.NoError(t, err)
+		expectationsMet(t)
+	})
+}
@@ -3,7 +3,9 @@ package orm
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -21,6 +23,12 @@ type Repository[T any] struct {
 
 	// Authorization functions
 	authorizeFuncs []AuthorizeFunc[T]
+
+	// Conditions every query is restricted to, see DefaultScope
+	scopes []Condition
+
+	// Storm whose settings apply to the queries of this repository
+	storm *Storm
 }
 
 func NewRepository[T any](db *sqlx.DB, metadata *ModelMetadata) (*Repository[T], error) {
@@ -81,6 +89,10 @@ func (r *Repository[T]) initialize() error {
 		return ErrNoPrimaryKey
 	}
 
+	if err := checkCompatibility[T](r.metadata); err != nil {
+		return err
+	}
+
 	r.middlewareManager = newMiddlewareManager()
 
 	return nil
@@ -94,14 +106,35 @@ func (r *Repository[T]) PrimaryKeys() []string {
 	return r.metadata.PrimaryKeys
 }
 
+// Columns returns the database columns of the model in declaration order, see
+// ModelMetadata.FieldNames
 func (r *Repository[T]) Columns() []string {
-	columns := make([]string, 0, len(r.metadata.Columns))
-	for _, col := range r.metadata.Columns {
-		columns = append(columns, col.DBName)
+	fields := r.metadata.FieldNames()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = r.metadata.Columns[field].DBName
 	}
 	return columns
 }
 
+// columnName resolves a key given as a Go field or database column name to the
+// column name, defaulting to id
+func (r *Repository[T]) columnName(key string) string {
+	if key == "" {
+		return "id"
+	}
+	if column, ok := r.metadata.ColumnMap[key]; ok {
+		return column
+	}
+	return key
+}
+
+// SetStorm links the repository to the Storm that owns it, so that the settings
+// of the Storm apply to its queries. Generated code calls it for every repository.
+func (r *Repository[T]) SetStorm(storm *Storm) {
+	r.storm = storm
+}
+
 // getRelationship returns the relationship metadata for the given relationship name
 func (r *Repository[T]) getRelationship(name string) *RelationshipMetadata {
 	if r.metadata.Relationships == nil {
@@ -126,10 +159,107 @@ func (r *Repository[T]) Authorize(fn AuthorizeFunc[T]) *Repository[T] {
 		metadata:          r.metadata,
 		middlewareManager: r.middlewareManager,
 		authorizeFuncs:    newFuncs,
+		scopes:            r.scopes,
+		storm:             r.storm,
+	}
+}
+
+// DefaultScope returns a new Repository whose queries and FindByID are restricted to
+// condition, e.g. to hide soft-deleted rows or the rows of other tenants. Scopes add
+// up, following the default_scope of the model if it declares one, and a query drops
+// them all with Unscoped. Conditions of a scope do not count as the conditions Delete
+// and Update require.
+func (r *Repository[T]) DefaultScope(condition Condition) *Repository[T] {
+	scopes := make([]Condition, len(r.scopes)+1)
+	copy(scopes, r.scopes)
+	scopes[len(r.scopes)] = condition
+
+	return &Repository[T]{
+		db:                r.db,
+		metadata:          r.metadata,
+		middlewareManager: r.middlewareManager,
+		authorizeFuncs:    r.authorizeFuncs,
+		scopes:            scopes,
+		storm:             r.storm,
+	}
+}
+
+// scopeConditions returns the default scope of the model, hiding soft-deleted rows,
+// followed by the scopes of the repository
+func (r *Repository[T]) scopeConditions() []squirrel.Sqlizer {
+	var conditions []squirrel.Sqlizer
+	if scope := softDeleteScope(r.metadata); scope != nil {
+		conditions = append(conditions, scope)
+	}
+	return append(conditions, r.declaredScopes()...)
+}
+
+// declaredScopes returns the default_scope of the model followed by the scopes of
+// the repository, leaving out soft deletes
+func (r *Repository[T]) declaredScopes() []squirrel.Sqlizer {
+	var conditions []squirrel.Sqlizer
+	if r.metadata.DefaultScope != "" {
+		conditions = append(conditions, squirrel.Expr(r.metadata.DefaultScope))
+	}
+	for _, scope := range r.scopes {
+		conditions = append(conditions, scope.ToSqlizer())
+	}
+	return conditions
+}
+
+// softDeleteScope returns the condition hiding the soft-deleted rows of the model,
+// or nil when it has no soft_delete column
+func softDeleteScope(metadata *ModelMetadata) squirrel.Sqlizer {
+	if metadata.SoftDelete == "" {
+		return nil
 	}
+	return squirrel.Expr(softDeleteCondition(metadata, metadata.TableName))
+}
+
+// softDeleteCondition returns the SQL hiding the soft-deleted rows of the model
+// when its table is referred to as table, or "" when the metadata is nil or has
+// no soft_delete column
+func softDeleteCondition(metadata *ModelMetadata, table string) string {
+	if metadata == nil || metadata.SoftDelete == "" {
+		return ""
+	}
+	return table + "." + metadata.SoftDelete + " IS NULL"
+}
+
+// deleteStatement returns the statement deleting the rows matching the conditions,
+// nil ones aside: an UPDATE setting the soft_delete and auto_update_time columns to
+// now() when the model has a soft_delete column, unless hard is set, and a DELETE
+// otherwise
+func (r *Repository[T]) deleteStatement(hard bool, conditions ...squirrel.Sqlizer) squirrel.Sqlizer {
+	if column := r.metadata.SoftDelete; column != "" && !hard {
+		update := squirrel.Update(r.metadata.TableName).
+			Set(column, squirrel.Expr("now()")).
+			PlaceholderFormat(squirrel.Dollar)
+		for _, updated := range r.getAutoTimeColumns(false) {
+			if updated != column {
+				update = update.Set(updated, squirrel.Expr("now()"))
+			}
+		}
+		for _, condition := range conditions {
+			if condition != nil {
+				update = update.Where(condition)
+			}
+		}
+		return update
+	}
+
+	statement := squirrel.Delete(r.metadata.TableName).
+		PlaceholderFormat(squirrel.Dollar)
+	for _, condition := range conditions {
+		if condition != nil {
+			statement = statement.Where(condition)
+		}
+	}
+	return statement
 }
 
 func (r *Repository[T]) getInsertFields(model T) (columns []string, values []interface{}) {
+	now := time.Now()
 	for _, colMeta := range r.metadata.Columns {
 		if colMeta.IsAutoGenerated {
 			continue
@@ -139,6 +269,12 @@ func (r *Repository[T]) getInsertFields(model T) (columns []string, values []int
 			continue
 		}
 
+		if (colMeta.AutoCreateTime || colMeta.AutoUpdateTime) && isZeroTime(colMeta, model) {
+			columns = append(columns, colMeta.DBName)
+			values = append(values, now)
+			continue
+		}
+
 		if colMeta.IsPointer && colMeta.IsNil != nil {
 			if colMeta.IsNil(model) {
 				continue // Skip nil pointers (let DB use default)
@@ -164,6 +300,28 @@ func (r *Repository[T]) getAutoGeneratedColumns() []string {
 	return cols
 }
 
+// getAutoTimeColumns returns the auto_update_time columns, and with create the
+// auto_create_time columns as well
+func (r *Repository[T]) getAutoTimeColumns(create bool) []string {
+	var cols []string
+	for _, field := range r.metadata.FieldNames() {
+		col := r.metadata.Columns[field]
+		if col.AutoUpdateTime || (create && col.AutoCreateTime) {
+			cols = append(cols, col.DBName)
+		}
+	}
+	return cols
+}
+
+// isZeroTime reports whether the record leaves the timestamp of colMeta unset
+func isZeroTime(colMeta *ColumnMetadata, model interface{}) bool {
+	if colMeta.IsPointer && colMeta.IsNil != nil && colMeta.IsNil(model) {
+		return true
+	}
+	t, ok := colMeta.GetValue(model).(time.Time)
+	return ok && t.IsZero()
+}
+
 func (r *Repository[T]) getPrimaryKeyValues(record T) map[string]interface{} {
 	pkValues := make(map[string]interface{})
 	for _, pkCol := range r.metadata.PrimaryKeys {
@@ -177,13 +335,19 @@ func (r *Repository[T]) getPrimaryKeyValues(record T) map[string]interface{} {
 
 func (r *Repository[T]) getUpdateFields(model T) map[string]interface{} {
 	fields := make(map[string]interface{})
+	now := time.Now()
 
 	for _, colMeta := range r.metadata.Columns {
 		if colMeta.IsPrimaryKey {
 			continue
 		}
 
-		if colMeta.IsAutoGenerated {
+		if colMeta.IsAutoGenerated || colMeta.AutoCreateTime {
+			continue
+		}
+
+		if colMeta.AutoUpdateTime {
+			fields[colMeta.DBName] = now
 			continue
 		}
 
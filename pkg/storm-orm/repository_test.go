@@ -1,7 +1,9 @@
 package orm
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
@@ -145,3 +147,256 @@ func TestNewRepository(t *testing.T) {
 		assert.Contains(t, err.Error(), "database cannot be nil")
 	})
 }
+
+func TestDefaultScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.DefaultScope = "users.is_active = true"
+	base, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+	repo := base.DefaultScope(name.NotEq("deleted")).Authorize(func(ctx context.Context, query *Query[TestUser]) *Query[TestUser] {
+		return query.Where(ComparableColumn[int]{Column: Column[int]{Table: "users", Name: "id"}}.Gt(0))
+	})
+
+	t.Run("restricts every query", func(t *testing.T) {
+		sql, args, err := repo.Query(context.Background()).Where(name.Eq("alice")).ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.is_active = true AND users.name <> $1 AND users.id > $2 AND users.name = $3)")
+		assert.Equal(t, []interface{}{"deleted", 0, "alice"}, args)
+	})
+
+	t.Run("unscoped keeps the authorization", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).Unscoped().ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.id > $1)")
+	})
+
+	t.Run("restricts FindByID", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE id = \$1 AND users.is_active = true AND users.name <> \$2 LIMIT 1`).
+			WithArgs(1, "deleted").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		_, err := repo.FindByID(context.Background(), 1)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("scopes are not conditions of a delete", func(t *testing.T) {
+		_, err := base.DefaultScope(name.NotEq("deleted")).Query(context.Background()).Delete()
+		assert.ErrorIs(t, err, ErrNoConditions)
+	})
+}
+
+func TestSoftDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.SoftDelete = "deleted_at"
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+
+	t.Run("queries hide deleted rows", func(t *testing.T) {
+		sql, _, err := repo.Query(ctx).Where(name.Eq("alice")).ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.deleted_at IS NULL AND users.name = $1)")
+
+		sql, _, err = repo.Query(ctx).Unscoped().Where(name.Eq("alice")).ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.name = $1)")
+	})
+
+	t.Run("Delete sets the column", func(t *testing.T) {
+		mock.ExpectQuery(`WHERE id = \$1 AND users.deleted_at IS NULL LIMIT 1`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alice"))
+		mock.ExpectExec(`^UPDATE users SET deleted_at = now\(\) WHERE id = \$1 AND users.deleted_at IS NULL$`).
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		record, err := repo.Delete(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", record.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query Delete sets the column of the matching rows", func(t *testing.T) {
+		mock.ExpectExec(`^UPDATE users SET deleted_at = now\(\) WHERE \(users.deleted_at IS NULL AND users.name = \$1\)$`).
+			WithArgs("alice").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		deleted, err := repo.Query(ctx).Where(name.Eq("alice")).Delete()
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unscoped Delete removes the rows", func(t *testing.T) {
+		mock.ExpectExec(`^DELETE FROM users WHERE \(users.name = \$1\)$`).
+			WithArgs("alice").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := repo.Query(ctx).Unscoped().Where(name.Eq("alice")).Delete()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Restore clears the column", func(t *testing.T) {
+		mock.ExpectQuery(`^UPDATE users SET deleted_at = \$1 WHERE id = \$2 AND deleted_at IS NOT NULL RETURNING `).
+			WithArgs(nil, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alice"))
+
+		record, err := repo.Restore(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", record.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Restore of a record that is not deleted", func(t *testing.T) {
+		mock.ExpectQuery(`^UPDATE users SET deleted_at`).
+			WithArgs(nil, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		_, err := repo.Restore(ctx, 2)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Restore needs a soft_delete column", func(t *testing.T) {
+		plain, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+		require.NoError(t, err)
+
+		_, err = plain.Restore(ctx, 1)
+		assert.ErrorContains(t, err, "has no soft_delete column")
+	})
+}
+
+func TestAutoTimestamps(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	metadata := createTestUserMetadata()
+	metadata.Columns["CreatedAt"].IsAutoGenerated = false
+	metadata.Columns["CreatedAt"].AutoCreateTime = true
+	metadata.Columns["UpdatedAt"].IsAutoGenerated = false
+	metadata.Columns["UpdatedAt"].AutoUpdateTime = true
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	written := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("insert fills zero timestamps", func(t *testing.T) {
+		columns, values := repo.getInsertFields(TestUser{Name: "alice"})
+		fields := make(map[string]interface{})
+		for i, column := range columns {
+			fields[column] = values[i]
+		}
+		for _, column := range []string{"created_at", "updated_at"} {
+			value, ok := fields[column].(time.Time)
+			require.True(t, ok, "expected %s to be written", column)
+			assert.False(t, value.IsZero())
+		}
+
+		columns, values = repo.getInsertFields(TestUser{Name: "alice", CreatedAt: written})
+		for i, column := range columns {
+			if column == "created_at" {
+				assert.Equal(t, written, values[i])
+			}
+		}
+	})
+
+	t.Run("Create returns the timestamps into the record", func(t *testing.T) {
+		mock.ExpectQuery(`^INSERT INTO users .* RETURNING id, created_at, updated_at$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, written, written))
+
+		record, err := repo.Create(ctx, &TestUser{Name: "alice"})
+		require.NoError(t, err)
+		assert.Equal(t, written, record.CreatedAt)
+		assert.Equal(t, written, record.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update touches updated_at and keeps created_at", func(t *testing.T) {
+		fields := repo.getUpdateFields(TestUser{ID: 1, Name: "bob"})
+		assert.NotContains(t, fields, "created_at")
+		assert.IsType(t, time.Time{}, fields["updated_at"])
+
+		mock.ExpectQuery(`^UPDATE users SET .* WHERE id = \$\d RETURNING updated_at$`).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(written))
+
+		record, err := repo.Update(ctx, &TestUser{ID: 1, Name: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, written, record.UpdatedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Update of a missing record", func(t *testing.T) {
+		mock.ExpectQuery(`^UPDATE users SET`).
+			WillReturnRows(sqlmock.NewRows([]string{"updated_at"}))
+
+		_, err := repo.Update(ctx, &TestUser{ID: 2, Name: "bob"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query Update touches updated_at", func(t *testing.T) {
+		name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+		mock.ExpectExec(`^UPDATE users SET name = \$1, updated_at = \$2 WHERE`).
+			WithArgs("bob", sqlmock.AnyArg(), "alice").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := repo.Query(ctx).Where(name.Eq("alice")).Update(name.Set("bob"))
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upserts keep created_at of existing rows", func(t *testing.T) {
+		opts := repo.keepCreateTimes([]string{"email", "name", "created_at", "updated_at"}, UpsertOptions{ConflictColumns: []string{"email"}})
+		assert.Equal(t, []string{"name", "updated_at"}, opts.UpdateColumns)
+
+		opts = repo.keepCreateTimes([]string{"email", "created_at"}, UpsertOptions{ConflictColumns: []string{"email"}, UpdateColumns: []string{"created_at"}})
+		assert.Equal(t, []string{"created_at"}, opts.UpdateColumns)
+	})
+
+	t.Run("upserts touch updated_at", func(t *testing.T) {
+		columns := []string{"email", "name", "updated_at"}
+		opts := repo.touchUpdateTimes(columns, UpsertOptions{ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}})
+		assert.Equal(t, " ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, updated_at = now()", onConflictClause(columns, opts))
+
+		updateExpr := map[string]string{"name": "users.name || EXCLUDED.name"}
+		opts = repo.touchUpdateTimes(columns, UpsertOptions{ConflictColumns: []string{"email"}, UpdateExpr: updateExpr})
+		assert.Equal(t, " ON CONFLICT (email) DO UPDATE SET name = users.name || EXCLUDED.name, updated_at = now()", onConflictClause(columns, opts))
+		assert.Len(t, updateExpr, 1, "the options of the caller are left alone")
+
+		updateExpr = map[string]string{"updated_at": "GREATEST(users.updated_at, EXCLUDED.updated_at)"}
+		opts = repo.touchUpdateTimes(columns, UpsertOptions{ConflictColumns: []string{"email"}, UpdateColumns: []string{"name"}, UpdateExpr: updateExpr})
+		assert.Equal(t, " ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name, updated_at = GREATEST(users.updated_at, EXCLUDED.updated_at)", onConflictClause(columns, opts))
+	})
+
+	t.Run("soft delete touches updated_at", func(t *testing.T) {
+		softMetadata := *metadata
+		softMetadata.SoftDelete = "deleted_at"
+		softRepo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), &softMetadata)
+		require.NoError(t, err)
+
+		name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+		mock.ExpectExec(`^UPDATE users SET deleted_at = now\(\), updated_at = now\(\) WHERE \(users.deleted_at IS NULL AND users.name = \$1\)$`).
+			WithArgs("alice").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err = softRepo.Query(ctx).Where(name.Eq("alice")).Delete()
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
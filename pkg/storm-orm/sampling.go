@@ -0,0 +1,58 @@
+package orm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SampleMethod is the way TABLESAMPLE picks the rows of a sample
+type SampleMethod string
+
+const (
+	// Bernoulli picks each row with the given probability, reading the whole table
+	Bernoulli SampleMethod = "BERNOULLI"
+	// System picks whole pages of the table, which is much faster on large tables
+	// but returns rows that are stored together
+	System SampleMethod = "SYSTEM"
+)
+
+// Sample reads a random sample of about percent of the rows of the table, between 0
+// and 100, before the conditions of the query apply. It suits previews and data
+// quality checks of large tables, where reading every row is too slow; the sample
+// differs on each call:
+//
+//	Events.Query(ctx).Sample(orm.System, 1).Where(Events.Payload.IsNull()).Count()
+func (q *Query[T]) Sample(method SampleMethod, percent float64) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if method != Bernoulli && method != System {
+		q.err = q.sampleError(fmt.Errorf("unknown sample method %q", method))
+		return q
+	}
+	if percent < 0 || percent > 100 {
+		q.err = q.sampleError(fmt.Errorf("sample percent must be between 0 and 100, got %v", percent))
+		return q
+	}
+
+	q.sample = fmt.Sprintf("TABLESAMPLE %s (%s)", method, strconv.FormatFloat(percent, 'f', -1, 64))
+	q.builder = q.builder.From(q.from())
+	return q
+}
+
+// from is the FROM clause of the query, with its TABLESAMPLE if any
+func (q *Query[T]) from() string {
+	if q.sample == "" {
+		return q.repo.metadata.TableName
+	}
+	return q.repo.metadata.TableName + " " + q.sample
+}
+
+// sampleError is the error of a query that cannot use a sample, or of an invalid one
+func (q *Query[T]) sampleError(err error) error {
+	return &Error{
+		Op:    "sample",
+		Table: q.repo.metadata.TableName,
+		Err:   err,
+	}
+}
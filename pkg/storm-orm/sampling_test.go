@@ -0,0 +1,60 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySample(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+
+	t.Run("samples before the conditions", func(t *testing.T) {
+		sql, args, err := repo.Query(context.Background()).
+			Sample(Bernoulli, 2.5).
+			Where(active.IsTrue()).
+			Limit(10).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "FROM users TABLESAMPLE BERNOULLI (2.5) WHERE (users.is_active = $1) LIMIT 10")
+		assert.Equal(t, []interface{}{true}, args)
+	})
+
+	t.Run("counts the sample", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users TABLESAMPLE SYSTEM \(1\) WHERE \(users.is_active = \$1\)`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+		count, err := repo.Query(context.Background()).Sample(System, 1).Where(active.IsTrue()).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects invalid samples", func(t *testing.T) {
+		_, _, err := repo.Query(context.Background()).Sample(Bernoulli, 150).ToSQL()
+		assert.ErrorContains(t, err, "between 0 and 100")
+
+		_, _, err = repo.Query(context.Background()).Sample("RANDOM", 10).ToSQL()
+		assert.ErrorContains(t, err, "unknown sample method")
+	})
+
+	t.Run("refuses to write through a sample", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).Sample(System, 10).Where(active.IsTrue()).Delete()
+		assert.ErrorContains(t, err, "cannot delete the records of a sampled query")
+
+		_, err = repo.Query(context.Background()).Sample(System, 10).Where(active.IsTrue()).Update(active.Set(false))
+		assert.ErrorContains(t, err, "cannot update the records of a sampled query")
+	})
+}
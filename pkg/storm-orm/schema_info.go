@@ -0,0 +1,42 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaInfoTable is the table storm migrate --push and storm schema apply
+// stamp after each successful migration
+const SchemaInfoTable = "storm_schema_info"
+
+// SchemaInfo describes the model revision a database was last migrated to
+type SchemaInfo struct {
+	ModelsHash   string    `db:"models_hash"`   // SHA-256 of the schema generated from the models
+	StormVersion string    `db:"storm_version"` // Version of storm that applied the migration
+	GeneratedAt  time.Time `db:"generated_at"`  // When the migration was applied
+}
+
+// SchemaVersion returns the latest stamp in storm_schema_info, or nil when the
+// database has never been migrated by storm
+func (s *Storm) SchemaVersion(ctx context.Context) (*SchemaInfo, error) {
+	var exists bool
+	if err := s.executor.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", SchemaInfoTable); err != nil {
+		return nil, parsePostgreSQLError(fmt.Errorf("failed to look up %s: %w", SchemaInfoTable, err), "schema_version", SchemaInfoTable)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	var info SchemaInfo
+	query := "SELECT models_hash, storm_version, generated_at FROM " + SchemaInfoTable + " ORDER BY id DESC LIMIT 1"
+	if err := s.executor.GetContext(ctx, &info, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, parsePostgreSQLError(fmt.Errorf("failed to read %s: %w", SchemaInfoTable, err), "schema_version", SchemaInfoTable)
+	}
+	return &info, nil
+}
@@ -0,0 +1,53 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	storm := NewStorm(sqlx.NewDb(db, "postgres"))
+
+	t.Run("not stamped", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT to_regclass\(\$1\) IS NOT NULL`).WithArgs(SchemaInfoTable).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		info, err := storm.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	t.Run("latest stamp", func(t *testing.T) {
+		generatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(`SELECT to_regclass\(\$1\) IS NOT NULL`).WithArgs(SchemaInfoTable).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT models_hash, storm_version, generated_at FROM storm_schema_info ORDER BY id DESC LIMIT 1`).
+			WillReturnRows(sqlmock.NewRows([]string{"models_hash", "storm_version", "generated_at"}).AddRow("abc123", "1.0.0-alpha", generatedAt))
+
+		info, err := storm.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, &SchemaInfo{ModelsHash: "abc123", StormVersion: "1.0.0-alpha", GeneratedAt: generatedAt}, info)
+	})
+
+	t.Run("empty table", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT to_regclass\(\$1\) IS NOT NULL`).WithArgs(SchemaInfoTable).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT models_hash`).
+			WillReturnRows(sqlmock.NewRows([]string{"models_hash", "storm_version", "generated_at"}))
+
+		info, err := storm.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, info)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
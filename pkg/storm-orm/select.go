@@ -0,0 +1,110 @@
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Select fetches only the given columns of the model, leaving the other fields of
+// the records at their zero value. The primary key is always selected, so the
+// records can still be updated or have relationships loaded; keep the columns
+// relationships are joined on when using Include.
+//
+//	Documents.Query(ctx).Select(Documents.Title, Documents.UpdatedAt).Find()
+func (q *Query[T]) Select(columns ...ColumnRef) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	selected := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		name, err := q.modelColumn(column)
+		if err != nil {
+			q.err = err
+			return q
+		}
+		selected[name] = true
+	}
+	for _, pk := range q.repo.metadata.PrimaryKeys {
+		selected[pk] = true
+	}
+
+	q.omitted = make(map[string]bool)
+	for _, column := range q.repo.Columns() {
+		if !selected[column] {
+			q.omitted[column] = true
+		}
+	}
+	q.resetColumns()
+	return q
+}
+
+// Omit leaves columns out of the fetched records, which keep their zero value for
+// them, e.g. to skip large bytea or jsonb fields in a listing. Primary key columns
+// cannot be omitted.
+//
+//	Documents.Query(ctx).Omit(Documents.Body, Documents.Attachments).Find()
+func (q *Query[T]) Omit(columns ...ColumnRef) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+
+	if q.omitted == nil {
+		q.omitted = make(map[string]bool)
+	}
+	for _, column := range columns {
+		name, err := q.modelColumn(column)
+		if err != nil {
+			q.err = err
+			return q
+		}
+		for _, pk := range q.repo.metadata.PrimaryKeys {
+			if name == pk {
+				q.err = fmt.Errorf("cannot omit primary key column %s", name)
+				return q
+			}
+		}
+		q.omitted[name] = true
+	}
+	q.resetColumns()
+	return q
+}
+
+// modelColumn returns the name of column, which must be a column of the model
+func (q *Query[T]) modelColumn(column ColumnRef) (string, error) {
+	name := column.columnName()
+	if table, bare, qualified := strings.Cut(name, "."); qualified {
+		if table != q.repo.metadata.TableName {
+			return "", fmt.Errorf("unknown column %s", name)
+		}
+		name = bare
+	}
+	if columnByName(q.repo.metadata, name) == nil {
+		return "", fmt.Errorf("unknown column %s", name)
+	}
+	return name, nil
+}
+
+// columns returns the columns of the model the query selects
+func (q *Query[T]) columns() []string {
+	columns := make([]string, 0, len(q.repo.metadata.Columns))
+	for _, column := range q.repo.Columns() {
+		if !q.omitted[column] {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// resetColumns selects the columns of the model again after Select, Omit or GroupBy
+// changed them. A grouped query qualifies them by the table of the model, as grouped
+// queries usually join the rows they aggregate.
+func (q *Query[T]) resetColumns() {
+	columns := q.columns()
+	if len(q.groupBy) > 0 {
+		for i, column := range columns {
+			columns[i] = q.repo.metadata.TableName + "." + column
+		}
+	}
+	q.builder = q.builder.RemoveColumns().Columns(columns...)
+}
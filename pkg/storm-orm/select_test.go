@@ -0,0 +1,70 @@
+package orm
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySelectColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	id := Column[int]{Table: "users", Name: "id"}
+	name := StringColumn{Column: Column[string]{Table: "users", Name: "name"}}
+	email := StringColumn{Column: Column[string]{Name: "email"}}
+
+	selected := func(sql string) []string {
+		list := strings.TrimSuffix(strings.TrimPrefix(sql, "SELECT "), " FROM users")
+		columns := strings.Split(list, ", ")
+		sort.Strings(columns)
+		return columns
+	}
+
+	t.Run("selects the given columns and the primary key", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).Select(name).ToSQL()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "name"}, selected(sql))
+	})
+
+	t.Run("omits columns", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).Omit(email, name).ToSQL()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"created_at", "id", "is_active", "updated_at"}, selected(sql))
+	})
+
+	t.Run("scans the selected columns into records", func(t *testing.T) {
+		mock.ExpectQuery(`^SELECT \w+, \w+ FROM users$`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "alice@example.com"))
+
+		users, err := repo.Query(context.Background()).Select(email).Find()
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, TestUser{ID: 1, Email: "alice@example.com"}, users[0])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the selection when grouped", func(t *testing.T) {
+		sql, _, err := repo.Query(context.Background()).Select(name).GroupBy("users.id").ToSQL()
+		require.NoError(t, err)
+		assert.Regexp(t, `^SELECT users\.(id|name), users\.(id|name) FROM users GROUP BY users.id$`, sql)
+	})
+
+	t.Run("refuses unknown columns and the primary key", func(t *testing.T) {
+		_, _, err := repo.Query(context.Background()).Select(Column[string]{Table: "posts", Name: "name"}).ToSQL()
+		assert.ErrorContains(t, err, "unknown column posts.name")
+
+		_, _, err = repo.Query(context.Background()).Omit(id).ToSQL()
+		assert.ErrorContains(t, err, "cannot omit primary key column id")
+	})
+}
@@ -33,6 +33,12 @@ type Storm struct {
 	executor DBExecutor  // Current executor (DB or TX)
 	logger   QueryLogger // Optional query logger
 
+	// Lets Query.Update run without conditions in every repository
+	allowFullTableUpdates bool
+
+	// Optional read replica for queries outside transactions
+	replica *replica
+
 	// Repository registry - will be populated by code generation
 	repositories map[string]interface{}
 }
@@ -55,6 +61,18 @@ func NewStorm(db *sqlx.DB, logger ...QueryLogger) *Storm {
 	return storm
 }
 
+// NewStormWithTx returns a Storm bound to tx. Calls to WithTransaction on it
+// reuse tx instead of beginning transactions of their own, so nothing is
+// committed until the caller commits tx.
+func NewStormWithTx(db *sqlx.DB, tx *sqlx.Tx, logger ...QueryLogger) *Storm {
+	var queryLogger QueryLogger
+	if len(logger) > 0 {
+		queryLogger = logger[0]
+	}
+
+	return newStormWithExecutor(db, tx, queryLogger)
+}
+
 func newStormWithExecutor(db *sqlx.DB, executor DBExecutor, logger QueryLogger) *Storm {
 	storm := &Storm{
 		db:           db,
@@ -203,12 +221,14 @@ func (s *Storm) WithTransaction(ctx context.Context, fn func(*Storm) error) erro
 	}()
 
 	txStorm := newStormWithExecutor(db, tx, s.logger)
+	txStorm.allowFullTableUpdates = s.allowFullTableUpdates
+	txStorm.replica = s.replica
 	if err := fn(txStorm); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", err), "commit", "")
 	}
 	committed = true
 
@@ -242,12 +262,14 @@ func (s *Storm) WithTransactionOptions(ctx context.Context, opts *TransactionOpt
 	}()
 
 	txStorm := newStormWithExecutor(db, tx, s.logger)
+	txStorm.allowFullTableUpdates = s.allowFullTableUpdates
+	txStorm.replica = s.replica
 	if err := fn(txStorm); err != nil {
 		return err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return parsePostgreSQLError(fmt.Errorf("failed to commit transaction: %w", err), "commit", "")
 	}
 	committed = true
 
@@ -285,6 +307,14 @@ func (s *Storm) GetDB() *sqlx.DB {
 	return nil
 }
 
+// AllowFullTableUpdates sets whether Query.Update may run without conditions in
+// the repositories of this Storm. It is off by default, so an update that would
+// touch every row returns ErrNoConditions unless the query calls
+// AllowFullTableUpdate. Set it before the Storm is used.
+func (s *Storm) AllowFullTableUpdates(allow bool) {
+	s.allowFullTableUpdates = allow
+}
+
 // GetLogger returns the query logger if set
 func (s *Storm) GetLogger() QueryLogger {
 	return s.logger
@@ -241,3 +241,29 @@ func TestStormGetters(t *testing.T) {
 		}
 	})
 }
+
+func TestNewStormWithTx(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock db: %v", err)
+	}
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "postgres")
+	mock.ExpectBegin()
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+
+	storm := NewStormWithTx(db, tx)
+	if storm.db != db {
+		t.Error("storm db does not match input db")
+	}
+	if !storm.isInTransaction() {
+		t.Error("storm should be bound to the transaction")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
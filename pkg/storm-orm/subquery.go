@@ -0,0 +1,97 @@
+package orm
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Subquery is a query nested in the conditions of another, built by Query.Subquery
+// or Query.AsExists, so that both run in a single round trip
+type Subquery struct {
+	sql  string
+	args []interface{}
+	err  error
+}
+
+// ToSql renders the subquery without parentheses, so it can be used with squirrel
+// directly
+func (s Subquery) ToSql() (string, []interface{}, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	return s.sql, s.args, nil
+}
+
+// Subquery selects column from the records matching the query, for Column.InSubquery
+// in the conditions of another query:
+//
+//	authors := Posts.Query(ctx).Where(Posts.Published.IsTrue()).Subquery(Posts.AuthorID)
+//	Users.Query(ctx).Where(Users.ID.InSubquery(authors)).Find()
+//
+// Conditions comparing with the columns of the outer query, such as
+// Posts.AuthorID.EqColumn(Users.ID.Column), make it a correlated subquery. Ordering,
+// limit, offset and joins apply; Include does not.
+func (q *Query[T]) Subquery(column ColumnRef) Subquery {
+	return q.subquery(column.columnName())
+}
+
+// AsExists selects nothing from the records matching the query, for Exists and
+// NotExists in the conditions of another query
+func (q *Query[T]) AsExists() Subquery {
+	return q.subquery("1")
+}
+
+func (q *Query[T]) subquery(column string) Subquery {
+	if q.err != nil {
+		return Subquery{err: q.err}
+	}
+	if err := q.lockError(); err != nil {
+		return Subquery{err: err}
+	}
+
+	// The subquery keeps ? placeholders so that the outer query numbers them
+	builder := q.applyClauses(q.builder.RemoveColumns().Column(column)).PlaceholderFormat(squirrel.Question)
+	sql, args, err := builder.ToSql()
+	if err != nil {
+		return Subquery{err: fmt.Errorf("failed to build subquery of %s: %w", q.repo.metadata.TableName, err)}
+	}
+	return Subquery{sql: sql, args: args}
+}
+
+// InSubquery matches the rows whose column is among the values the subquery selects
+func (c Column[T]) InSubquery(sub Subquery) Condition {
+	return sub.condition(c.String() + " IN")
+}
+
+// NotInSubquery matches the rows whose column is not among the values the subquery
+// selects. Like NOT IN, it matches nothing when the subquery selects a NULL.
+func (c Column[T]) NotInSubquery(sub Subquery) Condition {
+	return sub.condition(c.String() + " NOT IN")
+}
+
+// EqColumn matches the rows where the column equals other, usually a column of the
+// outer query in the conditions of a correlated subquery
+func (c Column[T]) EqColumn(other Column[T]) Condition {
+	return Condition{squirrel.Expr(c.String() + " = " + other.String())}
+}
+
+// Exists matches the rows for which the subquery, usually correlated, returns a record
+//
+//	commented := Comments.Query(ctx).Where(Comments.PostID.EqColumn(Posts.ID.Column)).AsExists()
+//	Posts.Query(ctx).Where(orm.Exists(commented)).Find()
+func Exists(sub Subquery) Condition {
+	return sub.condition("EXISTS")
+}
+
+// NotExists matches the rows for which the subquery returns no record
+func NotExists(sub Subquery) Condition {
+	return sub.condition("NOT EXISTS")
+}
+
+func (s Subquery) condition(operator string) Condition {
+	if s.err != nil {
+		return Condition{s}
+	}
+	return Condition{squirrel.Expr(operator+" ("+s.sql+")", s.args...)}
+}
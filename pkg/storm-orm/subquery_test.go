@@ -0,0 +1,78 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubqueries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+	users, err := NewRepository[TestUser](sqlxDB, createTestUserMetadata())
+	require.NoError(t, err)
+	posts, err := NewRepository[TestPost](sqlxDB, &ModelMetadata{
+		TableName:   "posts",
+		StructName:  "TestPost",
+		PrimaryKeys: []string{"id"},
+		Columns: map[string]*ColumnMetadata{
+			"ID":    {FieldName: "ID", DBName: "id", GoType: "int64", IsPrimaryKey: true},
+			"Title": {FieldName: "Title", DBName: "title", GoType: "string"},
+		},
+	})
+	require.NoError(t, err)
+
+	userID := Column[int]{Table: "users", Name: "id"}
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+	authorID := Column[int]{Table: "posts", Name: "author_id"}
+	title := StringColumn{Column: Column[string]{Table: "posts", Name: "title"}}
+
+	t.Run("in subquery", func(t *testing.T) {
+		authors := posts.Query(context.Background()).Where(title.Like("Go%")).Limit(100).Subquery(authorID)
+
+		sql, args, err := users.Query(context.Background()).
+			Where(active.IsTrue()).
+			Where(userID.InSubquery(authors)).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.is_active = $1 AND users.id IN (SELECT posts.author_id FROM posts WHERE (posts.title LIKE $2) LIMIT 100))")
+		assert.Equal(t, []interface{}{true, "Go%"}, args)
+	})
+
+	t.Run("correlated exists", func(t *testing.T) {
+		written := posts.Query(context.Background()).
+			Where(authorID.EqColumn(userID)).
+			Where(title.Eq("draft")).
+			AsExists()
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE \(NOT EXISTS \(SELECT 1 FROM posts WHERE \(posts.author_id = users.id AND posts.title = \$1\)\)\)`).
+			WithArgs("draft").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		count, err := users.Query(context.Background()).Where(NotExists(written)).Count()
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not in subquery", func(t *testing.T) {
+		sql, _, err := users.Query(context.Background()).
+			Where(userID.NotInSubquery(posts.Query(context.Background()).Subquery(authorID))).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "WHERE (users.id NOT IN (SELECT posts.author_id FROM posts))")
+	})
+
+	t.Run("errors of the subquery fail the outer query", func(t *testing.T) {
+		invalid := posts.Query(context.Background()).SkipLocked().AsExists()
+		_, _, err := users.Query(context.Background()).Where(Exists(invalid)).ToSQL()
+		assert.ErrorContains(t, err, "SKIP LOCKED needs ForUpdate or ForShare")
+	})
+}
@@ -0,0 +1,240 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+// TableFunction is a set-returning function, such as unnest, generate_series or
+// jsonb_to_recordset, used as a FROM item of a query:
+//
+//	tags := orm.Unnest(orm.Column[[]string]{Table: "posts", Name: "tags"}).As("t", "tag")
+//	Posts.Query(ctx).FromFunction(tags).Where(orm.FunctionColumn[string](tags, "tag").Eq("go"))
+type TableFunction struct {
+	name       string
+	args       []functionArgument
+	ordinality bool
+	alias      string
+	columns    []string
+}
+
+// functionArgument is an argument of a TableFunction, bound as a parameter cast to
+// cast when it is set
+type functionArgument struct {
+	value interface{}
+	cast  string
+}
+
+// ColumnRef is implemented by the typed columns, such as Users.Email. Function
+// arguments reference them by name rather than bind them as a value, and Select and
+// Omit pick the columns of a query with them.
+type ColumnRef interface {
+	columnName() string
+}
+
+func (c Column[T]) columnName() string {
+	return c.String()
+}
+
+// Function calls the set-returning function name. Arguments are bound as parameters,
+// except typed columns, which are referenced by name, and squirrel expressions, which
+// are written in place, so squirrel.Expr("?::int[]", values) casts a parameter.
+func Function(name string, args ...interface{}) TableFunction {
+	fn := TableFunction{name: name}
+	for _, arg := range args {
+		fn.args = append(fn.args, functionArgument{value: arg})
+	}
+	return fn
+}
+
+// Unnest expands an array to a row per element. The array is an array column, or a
+// Go slice of strings, integers, floats or booleans, which is bound as a typed array.
+func Unnest(array interface{}) TableFunction {
+	arg := functionArgument{value: array}
+	if elem, ok := sliceElementType(array); ok {
+		arg = functionArgument{value: pq.Array(array), cast: elem + "[]"}
+	}
+	return TableFunction{name: "unnest", args: []functionArgument{arg}}
+}
+
+// GenerateSeries returns the values from start to stop, counting by step when it is
+// given. Integers are bound as bigint, floats as numeric and times as timestamptz; a
+// series of times needs a step, given as a time.Duration or an interval such as "1 day".
+func GenerateSeries(start, stop interface{}, step ...interface{}) TableFunction {
+	fn := TableFunction{name: "generate_series", args: []functionArgument{
+		{value: start, cast: seriesType(start)},
+		{value: stop, cast: seriesType(stop)},
+	}}
+	if len(step) > 0 {
+		fn.args = append(fn.args, seriesStep(step[0]))
+	}
+	return fn
+}
+
+// JSONBToRecordset expands a JSON array of objects, given as a JSONB column or a JSON
+// document, to a row per object. Declare the fields to read with their types through
+// As, e.g. As("item", "sku text", "quantity int").
+func JSONBToRecordset(json interface{}) TableFunction {
+	return TableFunction{name: "jsonb_to_recordset", args: []functionArgument{{value: json, cast: "jsonb"}}}
+}
+
+// As names the rows of the function alias, and their columns columns. Functions
+// returning records need each column written with its type, as in "sku text".
+func (f TableFunction) As(alias string, columns ...string) TableFunction {
+	f.alias = alias
+	f.columns = columns
+	return f
+}
+
+// WithOrdinality numbers the rows of the function from 1 in an extra bigint column,
+// named by the last of the columns given to As
+func (f TableFunction) WithOrdinality() TableFunction {
+	f.ordinality = true
+	return f
+}
+
+// Alias returns the name the rows of the function are referenced by
+func (f TableFunction) Alias() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// FunctionColumn returns a column of the rows of fn for conditions and ordering
+func FunctionColumn[V any](fn TableFunction, name string) Column[V] {
+	return Column[V]{Name: name, Table: fn.Alias()}
+}
+
+// ToSql renders the function as a FROM item, so it can be used with squirrel directly
+func (f TableFunction) ToSql() (string, []interface{}, error) {
+	if f.name == "" {
+		return "", nil, fmt.Errorf("table function has no name")
+	}
+
+	var args []interface{}
+	rendered := make([]string, len(f.args))
+	for i, arg := range f.args {
+		switch v := arg.value.(type) {
+		case ColumnRef:
+			rendered[i] = v.columnName()
+		case squirrel.Sqlizer:
+			sql, sqlArgs, err := v.ToSql()
+			if err != nil {
+				return "", nil, fmt.Errorf("argument %d of %s: %w", i+1, f.name, err)
+			}
+			rendered[i] = sql
+			args = append(args, sqlArgs...)
+		default:
+			rendered[i] = "?"
+			if arg.cast != "" {
+				rendered[i] += "::" + arg.cast
+			}
+			args = append(args, arg.value)
+		}
+	}
+
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "%s(%s)", f.name, strings.Join(rendered, ", "))
+	if f.ordinality {
+		sql.WriteString(" WITH ORDINALITY")
+	}
+	if f.alias != "" {
+		sql.WriteString(" AS " + f.alias)
+		if len(f.columns) > 0 {
+			sql.WriteString("(" + strings.Join(f.columns, ", ") + ")")
+		}
+	}
+	return sql.String(), args, nil
+}
+
+// FromFunction adds the rows of fn to the FROM clause, so each record is returned
+// once per row of the function. The function can take columns of the model as
+// arguments, as PostgreSQL joins function calls laterally, and conditions on its
+// columns filter the records:
+//
+//	tags := orm.Unnest(orm.Column[[]string]{Table: "posts", Name: "tags"}).As("t", "tag")
+//	Posts.Query(ctx).FromFunction(tags).Where(orm.FunctionColumn[string](tags, "tag").In("go", "sql"))
+//
+// Name the columns of the function apart from those of the model, as the model
+// columns are selected unqualified.
+func (q *Query[T]) FromFunction(fn TableFunction) *Query[T] {
+	return q.addFunctionJoin(CrossJoin, fn, "")
+}
+
+// JoinFunction joins the rows of fn on condition, e.g. to keep the days of a
+// generate_series without records with a LeftJoin
+func (q *Query[T]) JoinFunction(joinType JoinType, fn TableFunction, condition string) *Query[T] {
+	return q.addFunctionJoin(joinType, fn, condition)
+}
+
+func (q *Query[T]) addFunctionJoin(joinType JoinType, fn TableFunction, condition string) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	sql, args, err := fn.ToSql()
+	if err != nil {
+		q.err = fmt.Errorf("invalid table function: %w", err)
+		return q
+	}
+	q.joins = append(q.joins, join{
+		Type:      joinType,
+		Table:     sql,
+		Condition: condition,
+		Args:      args,
+	})
+	return q
+}
+
+// sliceElementType returns the PostgreSQL type of the elements of a Go slice Unnest
+// binds as an array
+func sliceElementType(value interface{}) (string, bool) {
+	t := reflect.TypeOf(value)
+	// A []byte is a bytea value rather than an array
+	if t == nil || t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return "", false
+	}
+	switch t.Elem().Kind() {
+	case reflect.String:
+		return "text", true
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "bigint", true
+	case reflect.Int32, reflect.Int16, reflect.Int8, reflect.Uint16:
+		return "integer", true
+	case reflect.Float32, reflect.Float64:
+		return "double precision", true
+	case reflect.Bool:
+		return "boolean", true
+	}
+	return "", false
+}
+
+// seriesType returns the type generate_series bounds are bound as
+func seriesType(value interface{}) string {
+	switch value.(type) {
+	case time.Time, *time.Time:
+		return "timestamptz"
+	case float32, float64:
+		return "numeric"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "bigint"
+	}
+	return ""
+}
+
+// seriesStep returns the step argument of generate_series, binding durations and
+// strings as intervals
+func seriesStep(step interface{}) functionArgument {
+	switch v := step.(type) {
+	case time.Duration:
+		return functionArgument{value: fmt.Sprintf("%d microseconds", v.Microseconds()), cast: "interval"}
+	case string:
+		return functionArgument{value: v, cast: "interval"}
+	}
+	return functionArgument{value: step, cast: seriesType(step)}
+}
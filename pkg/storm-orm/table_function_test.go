@@ -0,0 +1,90 @@
+package orm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableFunction_ToSql(t *testing.T) {
+	t.Run("unnest of a column", func(t *testing.T) {
+		sql, args, err := Unnest(Column[[]string]{Table: "posts", Name: "tags"}).As("t", "tag").ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "unnest(posts.tags) AS t(tag)", sql)
+		assert.Empty(t, args)
+	})
+
+	t.Run("unnest of a Go slice", func(t *testing.T) {
+		sql, args, err := Unnest([]int64{1, 2}).WithOrdinality().As("ids", "id", "position").ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "unnest(?::bigint[]) WITH ORDINALITY AS ids(id, position)", sql)
+		require.Len(t, args, 1)
+		assert.Equal(t, pq.Array([]int64{1, 2}), args[0])
+	})
+
+	t.Run("generate_series of days", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		sql, args, err := GenerateSeries(from, from.AddDate(0, 0, 6), 24*time.Hour).As("d", "day").ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "generate_series(?::timestamptz, ?::timestamptz, ?::interval) AS d(day)", sql)
+		assert.Equal(t, []interface{}{from, from.AddDate(0, 0, 6), "86400000000 microseconds"}, args)
+	})
+
+	t.Run("jsonb_to_recordset with column definitions", func(t *testing.T) {
+		items := JSONBToRecordset(JSONBColumn{Column[interface{}]{Table: "orders", Name: "items"}}).
+			As("item", "sku text", "quantity int")
+		sql, _, err := items.ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "jsonb_to_recordset(orders.items) AS item(sku text, quantity int)", sql)
+	})
+
+	t.Run("expressions are written in place", func(t *testing.T) {
+		sql, args, err := Function("regexp_split_to_table", squirrel.Expr("lower(?)", "A B"), `\s+`).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "regexp_split_to_table(lower(?), ?)", sql)
+		assert.Equal(t, []interface{}{"A B", `\s+`}, args)
+	})
+}
+
+func TestQueryFromFunction(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("cross joins the function and numbers its parameters", func(t *testing.T) {
+		ids := Unnest([]int64{1, 2}).As("wanted", "wanted_id")
+		sql, args, err := repo.Query(context.Background()).
+			Where(Column[string]{Name: "name"}.Eq("alice")).
+			FromFunction(ids).
+			Where(FunctionColumn[int64](ids, "wanted_id").Eq(2)).
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "FROM users CROSS JOIN unnest($1::bigint[]) AS wanted(wanted_id) WHERE (name = $2 AND wanted.wanted_id = $3)")
+		assert.Equal(t, []interface{}{pq.Array([]int64{1, 2}), "alice", int64(2)}, args)
+	})
+
+	t.Run("joins the function on a condition", func(t *testing.T) {
+		days := GenerateSeries(1, 7).As("d", "day")
+		sql, args, err := repo.Query(context.Background()).
+			JoinFunction(LeftJoin, days, "d.day = users.id").
+			ToSQL()
+		require.NoError(t, err)
+		assert.Contains(t, sql, "FROM users LEFT JOIN generate_series($1::bigint, $2::bigint) AS d(day) ON d.day = users.id")
+		assert.Equal(t, []interface{}{1, 7}, args)
+	})
+
+	t.Run("a function without a name fails the query", func(t *testing.T) {
+		_, err := repo.Query(context.Background()).FromFunction(TableFunction{}).Find()
+		assert.ErrorContains(t, err, "invalid table function")
+	})
+}
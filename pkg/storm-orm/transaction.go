@@ -80,7 +80,7 @@ func (tm *TransactionManager) WithTransactionOptions(ctx context.Context, opts *
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+		return parsePostgreSQLError(fmt.Errorf("failed to commit: %w", err), "commit", "")
 	}
 	committed = true
 
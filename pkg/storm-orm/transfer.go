@@ -0,0 +1,421 @@
+package orm
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ImportOptions controls how ImportCSV and ImportJSONL map their input to columns
+type ImportOptions struct {
+	Columns     map[string]string // Header or key -> Go field or column, for input named differently from the model
+	SkipUnknown bool              // Ignore headers and keys that match no column instead of failing
+	Mask        bool              // Anonymize columns tagged with mask before copying them
+	MaskSalt    string            // Mixed into masked values, so they cannot be matched against known inputs
+}
+
+// ExportCSV writes the rows of query to w as CSV, with a header of the column
+// names in declaration order. A nil query exports the whole table. NULLs are
+// written as empty fields, which ImportCSV reads back as NULL for nullable
+// columns. The driver has no COPY TO, so rows are streamed from a SELECT.
+func (r *Repository[T]) ExportCSV(ctx context.Context, w io.Writer, query *Query[T]) (int64, error) {
+	columns := r.Columns()
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return 0, &Error{Op: "export", Table: r.metadata.TableName, Err: fmt.Errorf("failed to write header: %w", err)}
+	}
+
+	record := make([]string, len(columns))
+	count, err := r.exportRows(ctx, query, columns, func(values []interface{}) error {
+		for i, value := range values {
+			record[i] = formatCSVValue(value)
+		}
+		return writer.Write(record)
+	})
+	writer.Flush()
+	if err == nil {
+		if err = writer.Error(); err != nil {
+			err = &Error{Op: "export", Table: r.metadata.TableName, Err: fmt.Errorf("failed to write rows: %w", err)}
+		}
+	}
+	return count, err
+}
+
+// ExportJSONL writes the rows of query to w as one JSON object per line, keyed
+// by column name. A nil query exports the whole table.
+func (r *Repository[T]) ExportJSONL(ctx context.Context, w io.Writer, query *Query[T]) (int64, error) {
+	columns := r.Columns()
+	encoder := json.NewEncoder(w)
+
+	object := make(map[string]interface{}, len(columns))
+	return r.exportRows(ctx, query, columns, func(values []interface{}) error {
+		for i, value := range values {
+			object[columns[i]] = r.jsonValue(columns[i], value)
+		}
+		return encoder.Encode(object)
+	})
+}
+
+// exportRows runs the SELECT of query for columns and passes each row to write
+func (r *Repository[T]) exportRows(ctx context.Context, query *Query[T], columns []string, write func([]interface{}) error) (int64, error) {
+	if query == nil {
+		query = r.Query(ctx)
+	}
+	if query.err != nil {
+		return 0, query.err
+	}
+
+	selected := make([]string, len(columns))
+	for i, column := range columns {
+		selected[i] = r.metadata.TableName + "." + column
+	}
+	builder := query.applyClauses(query.builder.RemoveColumns().Columns(selected...))
+
+	var count int64
+	err := r.executeQueryMiddleware(OpQuery, "export", ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "export",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		rows, err := query.reader().QueryxContext(ctx, sqlQuery, args...)
+		if err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to execute query: %w", err), "export", r.metadata.TableName)
+		}
+		defer rows.Close()
+
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return &Error{Op: "export", Table: r.metadata.TableName, Err: fmt.Errorf("failed to scan row: %w", err)}
+			}
+			if err := write(values); err != nil {
+				return &Error{Op: "export", Table: r.metadata.TableName, Err: fmt.Errorf("failed to write row: %w", err)}
+			}
+			count++
+		}
+		if err := rows.Err(); err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to read rows: %w", err), "export", r.metadata.TableName)
+		}
+		return nil
+	})
+	return count, err
+}
+
+// formatCSVValue renders a scanned value in the text form PostgreSQL accepts back
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonValue keeps json and jsonb columns as JSON and turns other text into strings
+func (r *Repository[T]) jsonValue(column string, value interface{}) interface{} {
+	data, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if col := r.metadata.Columns[r.metadata.ReverseMap[column]]; col != nil && isJSONType(col.DBType) && json.Valid(data) {
+		return json.RawMessage(data)
+	}
+	return string(data)
+}
+
+func isJSONType(dbType string) bool {
+	dbType = strings.ToLower(dbType)
+	return dbType == "json" || dbType == "jsonb"
+}
+
+// ImportCSV copies the rows of a CSV file into the table with COPY. The header
+// names the columns, by database column or Go field name; empty fields of
+// nullable columns become NULL. All rows are copied in one transaction, the
+// repository's own when it has one.
+func (r *Repository[T]) ImportCSV(ctx context.Context, rd io.Reader, opts ImportOptions) (int64, error) {
+	reader := csv.NewReader(rd)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, &Error{Op: "import", Table: r.metadata.TableName, Err: fmt.Errorf("failed to read header: %w", err)}
+	}
+
+	columns, positions, err := r.importColumns(header, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	nullable := make([]bool, len(columns))
+	for i, column := range columns {
+		if col := r.metadata.Columns[r.metadata.ReverseMap[column]]; col != nil {
+			nullable[i] = col.IsNullable || col.IsPointer
+		}
+	}
+
+	return r.copyIn(ctx, columns, opts, func() ([]interface{}, error) {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, position := range positions {
+			field := record[position]
+			if field == "" && nullable[i] {
+				continue
+			}
+			values[i] = field
+		}
+		return values, nil
+	})
+}
+
+// ImportJSONL copies the rows of a file with one JSON object per line into the
+// table with COPY. The keys of the first object name the columns, by database
+// column or Go field name; keys missing from later objects are copied as NULL.
+func (r *Repository[T]) ImportJSONL(ctx context.Context, rd io.Reader, opts ImportOptions) (int64, error) {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	next := func() (map[string]interface{}, error) {
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(text))
+			decoder.UseNumber()
+			var object map[string]interface{}
+			if err := decoder.Decode(&object); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			return object, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	first, err := next()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, &Error{Op: "import", Table: r.metadata.TableName, Err: fmt.Errorf("failed to read input: %w", err)}
+	}
+
+	keys := make([]string, 0, len(first))
+	for key := range first {
+		keys = append(keys, key)
+	}
+	columns, positions, err := r.importColumns(keys, opts)
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	object := first
+	return r.copyIn(ctx, columns, opts, func() ([]interface{}, error) {
+		if object == nil {
+			var err error
+			if object, err = next(); err != nil {
+				return nil, err
+			}
+		}
+		current := object
+		object = nil
+
+		for key := range current {
+			if known[key] {
+				continue
+			}
+			if _, isColumn := r.importColumn(key, opts); isColumn || !opts.SkipUnknown {
+				return nil, fmt.Errorf("line %d: key %q is not in the first line", line, key)
+			}
+		}
+
+		values := make([]interface{}, len(columns))
+		for i, position := range positions {
+			value, err := copyValue(current[keys[position]])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", line, columns[i], err)
+			}
+			values[i] = value
+		}
+		return values, nil
+	})
+}
+
+// copyValue turns a decoded JSON value into the text COPY expects
+func copyValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+}
+
+// importColumns resolves input names to columns, returning the columns to copy
+// and the position of each in names
+func (r *Repository[T]) importColumns(names []string, opts ImportOptions) ([]string, []int, error) {
+	var columns []string
+	var positions []int
+	seen := make(map[string]string, len(names))
+
+	for i, name := range names {
+		column, ok := r.importColumn(name, opts)
+		if !ok {
+			if opts.SkipUnknown {
+				continue
+			}
+			return nil, nil, &Error{Op: "import", Table: r.metadata.TableName, Column: name, Err: fmt.Errorf("%q matches no column", name)}
+		}
+		if previous, dup := seen[column]; dup {
+			return nil, nil, &Error{Op: "import", Table: r.metadata.TableName, Column: column, Err: fmt.Errorf("%q and %q both map to column %s", previous, name, column)}
+		}
+		seen[column] = name
+
+		columns = append(columns, column)
+		positions = append(positions, i)
+	}
+
+	if len(columns) == 0 {
+		return nil, nil, &Error{Op: "import", Table: r.metadata.TableName, Err: fmt.Errorf("input names no columns")}
+	}
+	return columns, positions, nil
+}
+
+// importColumn resolves an input name to a column, through opts.Columns first
+func (r *Repository[T]) importColumn(name string, opts ImportOptions) (string, bool) {
+	target := strings.TrimSpace(name)
+	if mapped, ok := opts.Columns[target]; ok {
+		target = mapped
+	}
+
+	if column, ok := r.metadata.ColumnMap[target]; ok {
+		return column, true
+	}
+	if _, ok := r.metadata.ReverseMap[target]; ok {
+		return target, true
+	}
+	return "", false
+}
+
+// copyIn copies the rows returned by next, until it returns io.EOF, into
+// columns with COPY FROM STDIN
+func (r *Repository[T]) copyIn(ctx context.Context, columns []string, opts ImportOptions, next func() ([]interface{}, error)) (int64, error) {
+	statement := pq.CopyIn(r.metadata.TableName, columns...)
+
+	masks := make(map[int]MaskStrategy)
+	if opts.Mask {
+		for i, column := range columns {
+			if col := r.metadata.Columns[r.metadata.ReverseMap[column]]; col != nil && col.Mask != "" {
+				masks[i] = col.Mask
+			}
+		}
+	}
+
+	var count int64
+	copyRows := func(tx *sqlx.Tx) error {
+		return r.executeQueryMiddleware(OpImport, "import", ctx, nil, nil, func(middlewareCtx *MiddlewareContext) error {
+			middlewareCtx.Query = statement
+
+			stmt, err := tx.PrepareContext(ctx, statement)
+			if err != nil {
+				return parsePostgreSQLError(fmt.Errorf("failed to start copy: %w", err), "import", r.metadata.TableName)
+			}
+			defer stmt.Close()
+
+			for {
+				values, err := next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return &Error{Op: "import", Table: r.metadata.TableName, Err: fmt.Errorf("failed to read input: %w", err)}
+				}
+				for i, strategy := range masks {
+					if values[i], err = MaskValue(strategy, values[i], opts.MaskSalt); err != nil {
+						return &Error{Op: "import", Table: r.metadata.TableName, Column: columns[i], Err: err}
+					}
+				}
+				if _, err := stmt.ExecContext(ctx, values...); err != nil {
+					return parsePostgreSQLError(fmt.Errorf("failed to copy row: %w", err), "import", r.metadata.TableName)
+				}
+				count++
+			}
+
+			if _, err := stmt.ExecContext(ctx); err != nil {
+				return parsePostgreSQLError(fmt.Errorf("failed to finish copy: %w", err), "import", r.metadata.TableName)
+			}
+			return nil
+		})
+	}
+
+	var err error
+	switch db := r.db.(type) {
+	case *sqlx.Tx:
+		err = copyRows(db)
+	case *sqlx.DB:
+		err = r.WithinTransaction(ctx, copyRows)
+	default:
+		err = &Error{Op: "import", Table: r.metadata.TableName, Err: fmt.Errorf("imports need a database connection or transaction, got %T", r.db)}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
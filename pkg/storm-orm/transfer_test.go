@@ -0,0 +1,152 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "created_at", "email", "is_active", "name", "updated_at"}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow(int64(1), created, []byte("ada@example.com"), true, "Ada, Countess", created).
+			AddRow(int64(2), created, []byte("bob@example.com"), false, nil, created)
+	}
+
+	t.Run("CSV has a header and one line per row", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT users\.id, users\.created_at, users\.email, users\.is_active, users\.name, users\.updated_at FROM users WHERE \(users\.is_active = \$1\)`).
+			WithArgs(true).
+			WillReturnRows(rows())
+
+		var buf bytes.Buffer
+		count, err := repo.ExportCSV(context.Background(), &buf, repo.Query(context.Background()).Where(Column[bool]{Name: "is_active", Table: "users"}.Eq(true)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		assert.Equal(t, "id,created_at,email,is_active,name,updated_at\n"+
+			"1,2024-05-01T12:00:00Z,ada@example.com,true,\"Ada, Countess\",2024-05-01T12:00:00Z\n"+
+			"2,2024-05-01T12:00:00Z,bob@example.com,false,,2024-05-01T12:00:00Z\n", buf.String())
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("JSONL has one object per row", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT users\.id, .* FROM users$`).WillReturnRows(rows())
+
+		var buf bytes.Buffer
+		count, err := repo.ExportJSONL(context.Background(), &buf, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"id":1,"created_at":"2024-05-01T12:00:00Z","email":"ada@example.com","is_active":true,"name":"Ada, Countess","updated_at":"2024-05-01T12:00:00Z"}`, lines[0])
+		assert.JSONEq(t, `{"id":2,"created_at":"2024-05-01T12:00:00Z","email":"bob@example.com","is_active":false,"name":null,"updated_at":"2024-05-01T12:00:00Z"}`, lines[1])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestImport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	t.Run("CSV headers map by column, field or option", func(t *testing.T) {
+		mock.ExpectBegin()
+		copyIn := mock.ExpectPrepare(`COPY "users" \("name", "email", "is_active"\) FROM STDIN`)
+		copyIn.ExpectExec().WithArgs("Ada", "ada@example.com", "true").WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WithArgs("", "bob@example.com", "false").WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		input := "name,Email,active,notes\nAda,ada@example.com,true,first\n,bob@example.com,false,\n"
+		count, err := repo.ImportCSV(context.Background(), strings.NewReader(input), ImportOptions{
+			Columns:     map[string]string{"active": "IsActive"},
+			SkipUnknown: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("masked imports rewrite tagged columns", func(t *testing.T) {
+		metadata := createTestUserMetadata()
+		metadata.Columns["Email"].Mask = MaskEmail
+		masked, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), metadata)
+		require.NoError(t, err)
+
+		email, err := MaskValue(MaskEmail, "ada@example.com", "salt")
+		require.NoError(t, err)
+
+		mock.ExpectBegin()
+		copyIn := mock.ExpectPrepare(`COPY "users" \("name", "email"\) FROM STDIN`)
+		copyIn.ExpectExec().WithArgs("Ada", email).WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		count, err := masked.ImportCSV(context.Background(), strings.NewReader("name,email\nAda,ada@example.com\n"), ImportOptions{Mask: true, MaskSalt: "salt"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown headers fail before copying", func(t *testing.T) {
+		_, err := repo.ImportCSV(context.Background(), strings.NewReader("name,notes\nAda,first\n"), ImportOptions{})
+		var ormErr *Error
+		require.True(t, errors.As(err, &ormErr))
+		assert.Equal(t, "notes", ormErr.Column)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("columns mapped twice fail", func(t *testing.T) {
+		_, err := repo.ImportCSV(context.Background(), strings.NewReader("name,Name\nAda,Ada\n"), ImportOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "both map to column name")
+	})
+
+	t.Run("JSONL keys of the first line name the columns", func(t *testing.T) {
+		mock.ExpectBegin()
+		copyIn := mock.ExpectPrepare(`COPY "users" \("email"\) FROM STDIN`)
+		copyIn.ExpectExec().WithArgs("ada@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WithArgs(nil).WillReturnResult(sqlmock.NewResult(0, 0))
+		copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		input := "{\"email\":\"ada@example.com\"}\n\n{\"email\":null}\n"
+		count, err := repo.ImportJSONL(context.Background(), strings.NewReader(input), ImportOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("JSONL keys added after the first line roll back", func(t *testing.T) {
+		mock.ExpectBegin()
+		copyIn := mock.ExpectPrepare(`COPY "users" \("email"\) FROM STDIN`)
+		copyIn.ExpectExec().WithArgs("ada@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		input := "{\"email\":\"ada@example.com\"}\n{\"email\":\"bob@example.com\",\"name\":\"Bob\"}\n"
+		_, err := repo.ImportJSONL(context.Background(), strings.NewReader(input), ImportOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `line 2: key "name" is not in the first line`)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,150 @@
+package orm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// UpsertStrategy selects how UpsertMany sends its records to PostgreSQL
+type UpsertStrategy string
+
+const (
+	// UpsertValues sends every record in one INSERT ... VALUES statement, which
+	// binds a parameter per column of each record
+	UpsertValues UpsertStrategy = ""
+	// UpsertCopy copies the records into a temporary table with COPY and upserts
+	// them with one INSERT ... SELECT, which binds no parameters and keeps large
+	// batches clear of PostgreSQL's limit of 65535
+	UpsertCopy UpsertStrategy = "copy"
+	// UpsertAuto copies batches that would bind more than CopyUpsertThreshold
+	// parameters and sends smaller ones as VALUES
+	UpsertAuto UpsertStrategy = "auto"
+)
+
+// CopyUpsertThreshold is the number of bound parameters above which UpsertAuto
+// copies a batch instead of sending it as VALUES
+const CopyUpsertThreshold = 10000
+
+// copies reports whether a batch binding params parameters is copied
+func (s UpsertStrategy) copies(params int) bool {
+	return s == UpsertCopy || (s == UpsertAuto && params > CopyUpsertThreshold)
+}
+
+// upsertTables numbers the temporary tables of copied upserts, so that several
+// upserts in one transaction do not collide
+var upsertTables atomic.Uint64
+
+// upsertManyCopy upserts records through a temporary table holding columns, which
+// is dropped when done, or at the latest with the transaction
+func (r *Repository[T]) upsertManyCopy(ctx context.Context, tx *sqlx.Tx, records []T, columns []string, opts UpsertOptions) error {
+	temp := fmt.Sprintf("storm_upsert_%d", upsertTables.Add(1))
+
+	query := squirrel.Insert(r.metadata.TableName).
+		PlaceholderFormat(squirrel.Dollar).
+		Columns(columns...).
+		Select(squirrel.Select(columns...).From(temp))
+
+	return r.executeQueryMiddleware(OpUpsertMany, "upsert_many", ctx, records, query, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.InsertBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "upsertMany",
+				Table: r.metadata.TableName,
+				Err:   fmt.Errorf("failed to build batch upsert query: %w", err),
+			}
+		}
+		sqlQuery += onConflictClause(columns, opts)
+
+		middlewareCtx.Query = sqlQuery
+		middlewareCtx.Args = args
+
+		return r.stageRecords(ctx, tx, "upsertMany", temp, records, columns, true, sqlQuery, args...)
+	})
+}
+
+// stageRecords creates the temporary table temp holding columns of the table of
+// the model, fills it with records, with COPY when copied is set and with INSERTs
+// otherwise, runs statement, which reads it, and drops it again. ON COMMIT DROP
+// removes it with the transaction when a step fails.
+func (r *Repository[T]) stageRecords(ctx context.Context, tx *sqlx.Tx, errOp, temp string, records []T, columns []string, copied bool, statement string, args ...interface{}) error {
+	// CREATE TABLE AS leaves out the NOT NULL constraints, which the columns
+	// that are not set would violate
+	create := fmt.Sprintf("CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		temp, strings.Join(columns, ", "), r.metadata.TableName)
+	if _, err := tx.ExecContext(ctx, create); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to create staging table: %w", err), errOp, r.metadata.TableName)
+	}
+
+	if copied {
+		if err := r.copyRecords(ctx, tx, errOp, temp, records, columns); err != nil {
+			return err
+		}
+	} else {
+		for _, chunk := range chunkRecords(records, len(columns)) {
+			insert := squirrel.Insert(temp).PlaceholderFormat(squirrel.Dollar).Columns(columns...)
+			for _, row := range chunk {
+				_, values := r.getInsertFields(row)
+				insert = insert.Values(values...)
+			}
+			sqlQuery, insertArgs, err := insert.ToSql()
+			if err != nil {
+				return &Error{
+					Op:    errOp,
+					Table: r.metadata.TableName,
+					Err:   fmt.Errorf("failed to build staging rows: %w", err),
+				}
+			}
+			if _, err := tx.ExecContext(ctx, sqlQuery, insertArgs...); err != nil {
+				return parsePostgreSQLError(fmt.Errorf("failed to fill staging table: %w", err), errOp, r.metadata.TableName)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+		return parsePostgreSQLError(err, errOp, r.metadata.TableName)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DROP TABLE "+temp); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to drop staging table: %w", err), errOp, r.metadata.TableName)
+	}
+	return nil
+}
+
+// copyRecords copies the values of columns of records into table with COPY FROM STDIN
+func (r *Repository[T]) copyRecords(ctx context.Context, tx *sqlx.Tx, errOp, table string, records []T, columns []string) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to start copy: %w", err), errOp, r.metadata.TableName)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		// Fields are matched by name, as a record may leave out nil pointers
+		names, values := r.getInsertFields(record)
+		byName := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			byName[name] = values[i]
+		}
+
+		row := make([]interface{}, len(columns))
+		for i, column := range columns {
+			row[i] = byName[column]
+		}
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to copy row: %w", err), errOp, r.metadata.TableName)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return parsePostgreSQLError(fmt.Errorf("failed to finish copy: %w", err), errOp, r.metadata.TableName)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertManyCopy(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	users := []TestUser{
+		{Name: "User1", Email: "user1@example.com", IsActive: true},
+		{Name: "User2", Email: "user2@example.com", IsActive: false},
+	}
+	opts := UpsertOptions{
+		ConflictColumns: []string{"email"},
+		UpdateColumns:   []string{"name"},
+		Strategy:        UpsertCopy,
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE storm_upsert_\d+ ON COMMIT DROP AS SELECT .* FROM users WITH NO DATA`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn := mock.ExpectPrepare(`COPY "storm_upsert_\d+" \(.*"email".*\) FROM STDIN`)
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO users \(.*\) SELECT .* FROM storm_upsert_\d+ ON CONFLICT \(email\) DO UPDATE SET name = EXCLUDED.name`).
+		WithoutArgs().
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DROP TABLE storm_upsert_\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.UpsertMany(context.Background(), users, opts))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertStrategy(t *testing.T) {
+	assert.False(t, UpsertValues.copies(CopyUpsertThreshold*2))
+	assert.True(t, UpsertCopy.copies(1))
+	assert.False(t, UpsertAuto.copies(CopyUpsertThreshold))
+	assert.True(t, UpsertAuto.copies(CopyUpsertThreshold+1))
+}
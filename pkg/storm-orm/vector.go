@@ -0,0 +1,115 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vector handles pgvector vector(N) columns
+type Vector []float32
+
+// Scan implements the sql.Scanner interface for Vector
+func (v *Vector) Scan(value interface{}) error {
+	if value == nil {
+		*v = nil
+		return nil
+	}
+
+	switch val := value.(type) {
+	case []byte:
+		return v.parse(string(val))
+	case string:
+		return v.parse(val)
+	default:
+		return fmt.Errorf("cannot scan %T into Vector", value)
+	}
+}
+
+// Value implements the driver.Valuer interface for Vector
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return v.String(), nil
+}
+
+// String formats the vector as a pgvector literal, e.g. [1,2.5,3]
+func (v Vector) String() string {
+	elements := make([]string, len(v))
+	for i, f := range v {
+		elements[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+// parse parses a pgvector literal into a Go slice
+func (v *Vector) parse(s string) error {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return fmt.Errorf("invalid vector format: %s", s)
+	}
+
+	content := strings.TrimSpace(s[1 : len(s)-1])
+	if content == "" {
+		*v = Vector{}
+		return nil
+	}
+
+	parts := strings.Split(content, ",")
+	result := make(Vector, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return fmt.Errorf("invalid vector element %q: %w", part, err)
+		}
+		result[i] = float32(f)
+	}
+
+	*v = result
+	return nil
+}
+
+// VectorColumn provides pgvector similarity search operations. Each distance is a
+// numeric expression, so it can be compared against a threshold or used for ordering.
+// The vector is written into the SQL as a literal so that ORDER BY can use it.
+type VectorColumn struct {
+	Column[Vector]
+}
+
+// L2Distance is the Euclidean distance to the vector (<->)
+func (c VectorColumn) L2Distance(vector Vector) NumericColumn[float64] {
+	return c.distance("<->", vector)
+}
+
+// CosineDistance is the cosine distance to the vector (<=>)
+func (c VectorColumn) CosineDistance(vector Vector) NumericColumn[float64] {
+	return c.distance("<=>", vector)
+}
+
+// InnerProduct is the negative inner product with the vector (<#>), so smaller is closer
+func (c VectorColumn) InnerProduct(vector Vector) NumericColumn[float64] {
+	return c.distance("<#>", vector)
+}
+
+// NearestTo orders rows by Euclidean distance to the vector, closest first
+func (c VectorColumn) NearestTo(vector Vector) string {
+	return c.L2Distance(vector).Asc()
+}
+
+// Within matches rows closer than maxDistance to the vector by Euclidean distance
+func (c VectorColumn) Within(vector Vector, maxDistance float64) Condition {
+	return c.L2Distance(vector).Lt(maxDistance)
+}
+
+func (c VectorColumn) distance(operator string, vector Vector) NumericColumn[float64] {
+	return NumericColumn[float64]{
+		ComparableColumn: ComparableColumn[float64]{
+			Column: Column[float64]{
+				Name:  fmt.Sprintf("(%s %s '%s')", c.String(), operator, vector.String()),
+				Table: "",
+			},
+		},
+	}
+}
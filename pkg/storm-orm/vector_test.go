@@ -0,0 +1,62 @@
+package orm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVector_Scan(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected Vector
+		wantErr  bool
+	}{
+		{name: "nil value", input: nil, expected: nil},
+		{name: "string", input: "[1,2.5,-3]", expected: Vector{1, 2.5, -3}},
+		{name: "bytes with spaces", input: []byte("[ 0.25, 4 ]"), expected: Vector{0.25, 4}},
+		{name: "empty", input: "[]", expected: Vector{}},
+		{name: "not a vector", input: "{1,2}", wantErr: true},
+		{name: "bad element", input: "[1,x]", wantErr: true},
+		{name: "unsupported type", input: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Vector
+			err := v.Scan(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestVector_Value(t *testing.T) {
+	value, err := Vector{1, 2.5, -0.125}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "[1,2.5,-0.125]", value)
+
+	value, err = Vector(nil).Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestVectorColumn(t *testing.T) {
+	col := VectorColumn{Column: Column[Vector]{Name: "embedding", Table: "items"}}
+	query := Vector{1, 2, 3}
+
+	assert.Equal(t, "(items.embedding <-> '[1,2,3]') ASC", col.NearestTo(query))
+	assert.Equal(t, "(items.embedding <=> '[1,2,3]') ASC", col.CosineDistance(query).Asc())
+	assert.Equal(t, "(items.embedding <#> '[1,2,3]') ASC", col.InnerProduct(query).Asc())
+
+	sql, args, err := col.Within(query, 0.5).ToSqlizer().ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "(items.embedding <-> '[1,2,3]') < ?", sql)
+	assert.Equal(t, []interface{}{0.5}, args)
+}
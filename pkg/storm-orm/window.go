@@ -0,0 +1,165 @@
+package orm
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// Window is the OVER clause of a window function: the rows it partitions the
+// results into and their order in each partition. The zero Window spans all rows.
+//
+//	orm.PartitionBy(Users.TeamID).OrderBy(Users.CreatedAt.Desc())
+type Window struct {
+	partition []string
+	order     []string
+}
+
+// PartitionBy returns a window computing the function separately over the rows
+// sharing the values of columns
+func PartitionBy(columns ...ColumnRef) Window {
+	partition := make([]string, len(columns))
+	for i, column := range columns {
+		partition[i] = column.columnName()
+	}
+	return Window{partition: partition}
+}
+
+// OrderBy orders the rows of each partition, usually by Column.Asc or Column.Desc
+func (w Window) OrderBy(orderings ...string) Window {
+	w.order = append(append([]string{}, w.order...), orderings...)
+	return w
+}
+
+func (w Window) String() string {
+	var parts []string
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+strings.Join(w.partition, ", "))
+	}
+	if len(w.order) > 0 {
+		parts = append(parts, "ORDER BY "+strings.Join(w.order, ", "))
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// RowNumber numbers the rows of each partition from 1, see Expression.Over
+func RowNumber() Expression[int64] {
+	return Expression[int64]{sql: "ROW_NUMBER()"}
+}
+
+// Rank ranks the rows of each partition from 1, with gaps after ties
+func Rank() Expression[int64] {
+	return Expression[int64]{sql: "RANK()"}
+}
+
+// DenseRank ranks the rows of each partition from 1, without gaps after ties
+func DenseRank() Expression[int64] {
+	return Expression[int64]{sql: "DENSE_RANK()"}
+}
+
+// Lag returns value for the row offset rows before the current one in its
+// partition, or NULL when there is none
+func Lag[T any](value Expression[T], offset int) Expression[T] {
+	return shiftExpression("LAG", value, offset)
+}
+
+// Lead returns value for the row offset rows after the current one in its
+// partition, or NULL when there is none
+func Lead[T any](value Expression[T], offset int) Expression[T] {
+	return shiftExpression("LEAD", value, offset)
+}
+
+func shiftExpression[T any](function string, value Expression[T], offset int) Expression[T] {
+	if value.err != nil {
+		return value
+	}
+	if offset < 0 {
+		return Expression[T]{err: fmt.Errorf("%s offset must not be negative, got %d", function, offset)}
+	}
+	return Expression[T]{
+		sql:  function + "(" + value.sql + ", " + strconv.Itoa(offset) + ")",
+		args: value.args,
+	}
+}
+
+// Over computes the expression, a window function such as RowNumber or an aggregate
+// such as NumericColumn.Sum, over window instead of collapsing the rows into groups:
+//
+//	orm.RowNumber().Over(orm.PartitionBy(Users.TeamID).OrderBy(Users.CreatedAt.Desc()))
+//	Orders.Total.Sum().Over(orm.Window{}.OrderBy(Orders.CreatedAt.Asc())) // running total
+func (e Expression[T]) Over(window Window) Expression[T] {
+	if e.err != nil {
+		return e
+	}
+	return Expression[T]{sql: e.sql + " OVER " + window.String(), args: e.args}
+}
+
+// SelectWindow adds expr, usually a window function, to the columns FindInto selects
+// along with the model, under alias
+func (q *Query[T]) SelectWindow(alias string, expr squirrel.Sqlizer) *Query[T] {
+	if q.err != nil {
+		return q
+	}
+	if _, _, err := expr.ToSql(); err != nil {
+		q.err = fmt.Errorf("invalid expression for %s: %w", alias, err)
+		return q
+	}
+	q.windows = append(q.windows, aggregateColumn{alias: alias, expr: expr})
+	return q
+}
+
+// FindInto scans the records matching the query, with the SelectWindow expressions,
+// into dest, a pointer to a slice of structs embedding the model and holding a field
+// per expression, matched by its db tag:
+//
+//	var ranked []struct {
+//		models.User
+//		Position int64 `db:"position"`
+//	}
+//	err := Users.Query(ctx).
+//		SelectWindow("position", orm.RowNumber().Over(orm.PartitionBy(Users.TeamID).OrderBy(Users.Points.Desc()))).
+//		FindInto(&ranked)
+func (q *Query[T]) FindInto(dest interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	if err := q.lockError(); err != nil {
+		return err
+	}
+
+	target := reflect.TypeOf(dest)
+	if target == nil || target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Slice {
+		return &Error{
+			Op:    "find_into",
+			Table: q.repo.metadata.TableName,
+			Err:   fmt.Errorf("destination must be a pointer to a slice, got %T", dest),
+		}
+	}
+
+	builder := q.applyProjections(q.builder)
+	for _, window := range q.windows {
+		builder = builder.Column(squirrel.Alias(window.expr, window.alias))
+	}
+	builder = q.applyClauses(builder)
+
+	return q.repo.executeQueryMiddleware(OpQuery, "find_into", q.ctx, nil, builder, func(middlewareCtx *MiddlewareContext) error {
+		finalQuery := middlewareCtx.QueryBuilder.(squirrel.SelectBuilder)
+
+		sqlQuery, args, err := finalQuery.ToSql()
+		if err != nil {
+			return &Error{
+				Op:    "find_into",
+				Table: q.repo.metadata.TableName,
+				Err:   fmt.Errorf("failed to build query: %w", err),
+			}
+		}
+
+		if execErr := q.reader().SelectContext(q.ctx, dest, sqlQuery, args...); execErr != nil {
+			return parsePostgreSQLError(fmt.Errorf("failed to execute query: %w", execErr), "find_into", q.repo.metadata.TableName)
+		}
+		return nil
+	})
+}
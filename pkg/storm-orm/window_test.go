@@ -0,0 +1,97 @@
+package orm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowFunctions(t *testing.T) {
+	team := Column[int]{Table: "users", Name: "team_id"}
+	created := Column[int]{Table: "users", Name: "created_at"}
+	points := NumericColumn[int]{ComparableColumn: ComparableColumn[int]{Column: Column[int]{Table: "users", Name: "points"}}}
+
+	t.Run("row number over a partition", func(t *testing.T) {
+		sql, args, err := RowNumber().Over(PartitionBy(team).OrderBy(created.Desc())).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "ROW_NUMBER() OVER (PARTITION BY users.team_id ORDER BY users.created_at DESC)", sql)
+		assert.Empty(t, args)
+	})
+
+	t.Run("ranks and aggregates", func(t *testing.T) {
+		sql, _, _ := DenseRank().Over(Window{}.OrderBy(points.Desc())).ToSql()
+		assert.Equal(t, "DENSE_RANK() OVER (ORDER BY users.points DESC)", sql)
+
+		sql, _, _ = points.Sum().Over(PartitionBy(team)).ToSql()
+		assert.Equal(t, "SUM(users.points) OVER (PARTITION BY users.team_id)", sql)
+	})
+
+	t.Run("lag and lead", func(t *testing.T) {
+		sql, args, err := Lag(Coalesce(points.Expression(), Value(0)), 1).Over(Window{}.OrderBy(created.Asc())).ToSql()
+		require.NoError(t, err)
+		assert.Equal(t, "LAG(COALESCE(users.points, ?), 1) OVER (ORDER BY users.created_at ASC)", sql)
+		assert.Equal(t, []interface{}{0}, args)
+
+		_, _, err = Lead(points.Expression(), -1).Over(Window{}).ToSql()
+		assert.ErrorContains(t, err, "must not be negative")
+	})
+
+	t.Run("windows do not share orderings", func(t *testing.T) {
+		base := PartitionBy(team).OrderBy(created.Asc())
+		first := base.OrderBy(points.Asc())
+		second := base.OrderBy(points.Desc())
+		assert.Equal(t, "(PARTITION BY users.team_id ORDER BY users.created_at ASC, users.points ASC)", first.String())
+		assert.Equal(t, "(PARTITION BY users.team_id ORDER BY users.created_at ASC, users.points DESC)", second.String())
+	})
+}
+
+func TestQueryFindInto(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewRepository[TestUser](sqlx.NewDb(db, "postgres"), createTestUserMetadata())
+	require.NoError(t, err)
+
+	active := BoolColumn{Column: Column[bool]{Table: "users", Name: "is_active"}}
+	created := Column[int]{Table: "users", Name: "created_at"}
+
+	t.Run("scans the model with window columns", func(t *testing.T) {
+		mock.ExpectQuery(`SELECT .*, \(ROW_NUMBER\(\) OVER \(ORDER BY users.created_at DESC\)\) AS position FROM users WHERE \(users.is_active = \$1\) LIMIT 2$`).
+			WithArgs(true).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "position"}).
+				AddRow(7, "alice", 1).
+				AddRow(3, "bob", 2))
+
+		var ranked []struct {
+			TestUser
+			Position int64 `db:"position"`
+		}
+		err := repo.Query(context.Background()).
+			Where(active.IsTrue()).
+			SelectWindow("position", RowNumber().Over(Window{}.OrderBy(created.Desc()))).
+			Limit(2).
+			FindInto(&ranked)
+		require.NoError(t, err)
+		require.Len(t, ranked, 2)
+		assert.Equal(t, "bob", ranked[1].Name)
+		assert.Equal(t, int64(2), ranked[1].Position)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses a destination that is not a slice", func(t *testing.T) {
+		var single TestUser
+		err := repo.Query(context.Background()).FindInto(&single)
+		assert.ErrorContains(t, err, "pointer to a slice")
+	})
+
+	t.Run("refuses an invalid window expression", func(t *testing.T) {
+		var ranked []TestUser
+		err := repo.Query(context.Background()).SelectWindow("previous", Lag(Value(1), -2)).FindInto(&ranked)
+		assert.ErrorContains(t, err, "invalid expression for previous")
+	})
+}
@@ -27,6 +27,15 @@ type Config struct {
 	MigrationsTable string `yaml:"migrations_table" env:"STORM_MIGRATIONS_TABLE"`
 	AutoMigrate     bool   `yaml:"auto_migrate" env:"STORM_AUTO_MIGRATE"`
 
+	// Session settings of Migrator().Apply and Rollback, so a migration stuck
+	// behind traffic fails instead of blocking the table. Zero turns a timeout off.
+	MigrationLockTimeout      time.Duration `yaml:"migration_lock_timeout" env:"STORM_MIGRATION_LOCK_TIMEOUT"`
+	MigrationStatementTimeout time.Duration `yaml:"migration_statement_timeout" env:"STORM_MIGRATION_STATEMENT_TIMEOUT"`
+	MigrationApplicationName  string        `yaml:"migration_application_name" env:"STORM_MIGRATION_APPLICATION_NAME"`
+	// MigrationLockRetry retries a migration that hit the lock timeout, backing
+	// off between attempts, for up to this long. Zero fails straight away.
+	MigrationLockRetry time.Duration `yaml:"migration_lock_retry" env:"STORM_MIGRATION_LOCK_RETRY"`
+
 	// ORM settings
 	GenerateHooks bool `yaml:"generate_hooks" env:"STORM_GENERATE_HOOKS"`
 	GenerateTests bool `yaml:"generate_tests" env:"STORM_GENERATE_TESTS"`
@@ -44,21 +53,24 @@ type Config struct {
 // NewConfig creates a config with sensible defaults
 func NewConfig() *Config {
 	return &Config{
-		Driver:           "postgres",
-		MaxOpenConns:     25,
-		MaxIdleConns:     5,
-		ConnMaxLifetime:  time.Hour,
-		ModelsPackage:    "./models",
-		MigrationsDir:    "./migrations",
-		MigrationsTable:  "schema_migrations",
-		AutoMigrate:      false,
-		GenerateHooks:    true,
-		GenerateTests:    false,
-		GenerateMocks:    false,
-		StrictMode:       true,
-		NamingConvention: "snake_case",
-		Logger:           NewDefaultLogger(),
-		Debug:            false,
+		Driver:                    "postgres",
+		MaxOpenConns:              25,
+		MaxIdleConns:              5,
+		ConnMaxLifetime:           time.Hour,
+		ModelsPackage:             "./models",
+		MigrationsDir:             "./migrations",
+		MigrationsTable:           "schema_migrations",
+		AutoMigrate:               false,
+		MigrationLockTimeout:      10 * time.Second,
+		MigrationStatementTimeout: 5 * time.Minute,
+		MigrationApplicationName:  "storm",
+		GenerateHooks:             true,
+		GenerateTests:             false,
+		GenerateMocks:             false,
+		StrictMode:                true,
+		NamingConvention:          "snake_case",
+		Logger:                    NewDefaultLogger(),
+		Debug:                     false,
 	}
 }
 
@@ -136,6 +148,24 @@ func (c *Config) LoadEnv() {
 	if auto := os.Getenv("STORM_AUTO_MIGRATE"); auto != "" {
 		c.AutoMigrate = auto == "true"
 	}
+	if timeout := os.Getenv("STORM_MIGRATION_LOCK_TIMEOUT"); timeout != "" {
+		if val, err := time.ParseDuration(timeout); err == nil {
+			c.MigrationLockTimeout = val
+		}
+	}
+	if timeout := os.Getenv("STORM_MIGRATION_STATEMENT_TIMEOUT"); timeout != "" {
+		if val, err := time.ParseDuration(timeout); err == nil {
+			c.MigrationStatementTimeout = val
+		}
+	}
+	if name := os.Getenv("STORM_MIGRATION_APPLICATION_NAME"); name != "" {
+		c.MigrationApplicationName = name
+	}
+	if retry := os.Getenv("STORM_MIGRATION_LOCK_RETRY"); retry != "" {
+		if val, err := time.ParseDuration(retry); err == nil {
+			c.MigrationLockRetry = val
+		}
+	}
 	if hooks := os.Getenv("STORM_GENERATE_HOOKS"); hooks != "" {
 		c.GenerateHooks = hooks == "true"
 	}
@@ -190,6 +220,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("migrations table is required")
 	}
 
+	if c.MigrationLockTimeout < 0 || c.MigrationStatementTimeout < 0 || c.MigrationLockRetry < 0 {
+		return fmt.Errorf("migration timeouts cannot be negative")
+	}
+
 	if c.NamingConvention != "snake_case" && c.NamingConvention != "camelCase" {
 		return fmt.Errorf("naming convention must be 'snake_case' or 'camelCase'")
 	}
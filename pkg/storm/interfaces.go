@@ -2,6 +2,7 @@ package storm
 
 import (
 	"context"
+	"text/template"
 	"time"
 )
 
@@ -24,6 +25,14 @@ type Migrator interface {
 
 	// Pending returns all pending migrations
 	Pending(ctx context.Context) ([]*Migration, error)
+
+	// Up applies the pending migration files in version order, recording each
+	// in the migrations table, and returns the ones it applied
+	Up(ctx context.Context) ([]*Migration, error)
+
+	// Down rolls back the last steps applied migrations, newest first, and
+	// returns the ones it rolled back
+	Down(ctx context.Context, steps int) ([]*Migration, error)
 }
 
 // SchemaInspector analyzes database schema
@@ -210,6 +219,27 @@ type MigrateOptions struct {
 	AllowDestructive    bool
 	SkipPrompt          bool
 	CreateDBIfNotExists bool
+	Strict              bool
+	DataPreservation    string        // "rename" or "archive" keeps the data of dropped tables and columns
+	RetentionPeriod     time.Duration // Drop preserved tables and columns once they are this old, zero keeps them
+	ConcurrentIndexes   bool          // Build and drop indexes of existing tables with CONCURRENTLY
+	SafeConstraints     bool          // Add constraints to existing tables NOT VALID and validate them separately
+	Analyze             bool          // ANALYZE existing tables that get indexes, filled or retyped columns, or updated rows
+	ForeignKeyOnDelete  string        // ON DELETE action of foreign keys whose field and table set none
+	ForeignKeyOnUpdate  string        // ON UPDATE action of foreign keys whose field and table set none
+	ForeignKeyNaming    string        // Foreign key name pattern, e.g. "fk_{table}_{column}"
+	Ignore              IgnoreRules   // Objects managed by extensions and other tools, left out of migrations
+	UpdatedAtTriggers   bool          // Set updated_at columns with BEFORE UPDATE triggers instead of from the ORM
+}
+
+// IgnoreRules lists database objects migrations leave alone. Patterns use path.Match
+// syntax, e.g. "_timescaledb_*".
+type IgnoreRules struct {
+	Extensions []string `yaml:"extensions"` // Ignore the objects these extensions create: postgis, timescaledb
+	Schemas    []string `yaml:"schemas"`    // Schema names
+	Tables     []string `yaml:"tables"`     // Table names, optionally schema qualified
+	Columns    []string `yaml:"columns"`    // table.column
+	Indexes    []string `yaml:"indexes"`    // Index names
 }
 
 // GenerateOptions configures ORM code generation
@@ -219,4 +249,28 @@ type GenerateOptions struct {
 	IncludeHooks bool
 	IncludeTests bool
 	IncludeMocks bool
+
+	// Models limits generation to the named models (empty = all)
+	Models []string
+
+	// Features limits generation to metadata, columns, repositories, extensions,
+	// storm and filters (empty = all)
+	Features []string
+
+	// Force regenerates the files of every model, not only of the changed ones
+	Force bool
+
+	// Progress is called after each generated file is written (optional)
+	Progress func(done, total int, file string)
+
+	// TemplateDir holds <template>.tmpl files replacing the built-in templates:
+	// metadata, columns, repository, extensions and storm
+	TemplateDir string
+
+	// TemplateFuncs are added to the template functions, e.g. organization-specific
+	// naming helpers; a function replaces a built-in one of the same name
+	TemplateFuncs template.FuncMap
+
+	// TemplateData is available to every template as .Data, e.g. a license header
+	TemplateData map[string]interface{}
 }
@@ -261,6 +261,14 @@ func (m *migrator) Pending(ctx context.Context) ([]*Migration, error) {
 	return nil, ErrNotImplemented
 }
 
+func (m *migrator) Up(ctx context.Context) ([]*Migration, error) {
+	return nil, ErrNotImplemented
+}
+
+func (m *migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	return nil, ErrNotImplemented
+}
+
 type ORM struct {
 	storm *Storm
 	impl  ORMGenerator
@@ -33,6 +33,15 @@ func TestNewConfig(t *testing.T) {
 	if config.AutoMigrate != false {
 		t.Errorf("Expected AutoMigrate to be false, got %v", config.AutoMigrate)
 	}
+	if config.MigrationLockTimeout != 10*time.Second {
+		t.Errorf("Expected MigrationLockTimeout to be 10s, got %v", config.MigrationLockTimeout)
+	}
+	if config.MigrationStatementTimeout != 5*time.Minute {
+		t.Errorf("Expected MigrationStatementTimeout to be 5m, got %v", config.MigrationStatementTimeout)
+	}
+	if config.MigrationApplicationName != "storm" {
+		t.Errorf("Expected MigrationApplicationName to be 'storm', got %s", config.MigrationApplicationName)
+	}
 	if config.GenerateHooks != true {
 		t.Errorf("Expected GenerateHooks to be true, got %v", config.GenerateHooks)
 	}
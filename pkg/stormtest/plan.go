@@ -0,0 +1,175 @@
+package stormtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DefaultSeqScanMinRows is the table size from which a sequential scan counts
+// as a missing index. Smaller tables are cheaper to scan than to look up.
+const DefaultSeqScanMinRows = 1000
+
+// PlanQuery is a statement whose plan is checked for sequential scans
+type PlanQuery struct {
+	Name string // Reported with the scans found, e.g. "users by email"
+	SQL  string
+	Args []interface{}
+
+	err error // Building the statement failed
+}
+
+// PlanOptions tunes which sequential scans fail a plan check
+type PlanOptions struct {
+	// MinRows is the number of rows from which a scanned table fails the check;
+	// zero uses DefaultSeqScanMinRows
+	MinRows int64
+	// AllowTables are tables that may be scanned whatever their size
+	AllowTables []string
+}
+
+// SeqScan is a sequential scan found in a query plan
+type SeqScan struct {
+	Table  string // Schema-qualified table name
+	Rows   int64  // Rows in the table when it was explained
+	Filter string // Condition applied while scanning, if any
+}
+
+func (s SeqScan) String() string {
+	if s.Filter == "" {
+		return fmt.Sprintf("Seq Scan on %s (%d rows)", s.Table, s.Rows)
+	}
+	return fmt.Sprintf("Seq Scan on %s (%d rows) filtering %s", s.Table, s.Rows, s.Filter)
+}
+
+// FromQuery builds a PlanQuery from the SELECT a storm query runs, such as a
+// generated UserQuery
+func FromQuery(name string, query interface {
+	ToSQL() (string, []interface{}, error)
+}) PlanQuery {
+	sql, args, err := query.ToSQL()
+	return PlanQuery{Name: name, SQL: sql, Args: args, err: err}
+}
+
+// AssertNoSeqScans explains every query on db and fails the test for each
+// sequential scan of a table with at least MinRows rows, which usually means a
+// filter or sort lost its index when a model changed.
+//
+// The planner only prefers an index when the table is large enough and its
+// statistics are current, so seed representative data and run ANALYZE before
+// the check. db may be a test transaction from BeginTx.
+func AssertNoSeqScans(tb testing.TB, db sqlx.QueryerContext, queries []PlanQuery, opts ...PlanOptions) {
+	tb.Helper()
+
+	var options PlanOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	for _, query := range queries {
+		scans, err := FindSeqScans(context.Background(), db, query, options)
+		if err != nil {
+			tb.Errorf("stormtest: %s: %v", query.Name, err)
+			continue
+		}
+		for _, scan := range scans {
+			tb.Errorf("stormtest: %s: %s\n%s", query.Name, scan, query.SQL)
+		}
+	}
+}
+
+// FindSeqScans explains query on db and returns the sequential scans of tables
+// that fail the options
+func FindSeqScans(ctx context.Context, db sqlx.QueryerContext, query PlanQuery, opts PlanOptions) ([]SeqScan, error) {
+	if query.err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", query.err)
+	}
+
+	minRows := opts.MinRows
+	if minRows == 0 {
+		minRows = DefaultSeqScanMinRows
+	}
+	allowed := make(map[string]bool, len(opts.AllowTables))
+	for _, table := range opts.AllowTables {
+		allowed[table] = true
+	}
+
+	var output []byte
+	if err := db.QueryRowxContext(ctx, "EXPLAIN (VERBOSE, FORMAT JSON) "+query.SQL, query.Args...).Scan(&output); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	scans, err := parseSeqScans(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []SeqScan
+	for _, scan := range scans {
+		name := scan.Table[strings.LastIndex(scan.Table, ".")+1:]
+		if allowed[name] || allowed[scan.Table] {
+			continue
+		}
+
+		schema, table, _ := strings.Cut(scan.Table, ".")
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table))
+		if err := sqlx.GetContext(ctx, db, &scan.Rows, countQuery); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", scan.Table, err)
+		}
+		if scan.Rows >= minRows {
+			failed = append(failed, scan)
+		}
+	}
+
+	return failed, nil
+}
+
+// planNode is the part of an EXPLAIN (FORMAT JSON) node the check reads
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Schema       string     `json:"Schema"`
+	Filter       string     `json:"Filter"`
+	Plans        []planNode `json:"Plans"`
+}
+
+// parseSeqScans returns the sequential scans of an EXPLAIN (FORMAT JSON) plan,
+// one per scanned table
+func parseSeqScans(output []byte) ([]SeqScan, error) {
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(output, &plans); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+
+	var scans []SeqScan
+	seen := make(map[string]bool)
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		if node.NodeType == "Seq Scan" {
+			schema := node.Schema
+			if schema == "" {
+				schema = "public"
+			}
+			table := schema + "." + node.RelationName
+			if !seen[table] {
+				seen[table] = true
+				scans = append(scans, SeqScan{Table: table, Filter: node.Filter})
+			}
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, plan := range plans {
+		walk(plan.Plan)
+	}
+
+	return scans, nil
+}
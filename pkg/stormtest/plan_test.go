@@ -0,0 +1,99 @@
+package stormtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const teamsByOwnerPlan = `[{"Plan": {
+	"Node Type": "Hash Join",
+	"Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "teams", "Schema": "public", "Filter": "(teams.name = 'core'::text)"},
+		{"Node Type": "Hash", "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "users", "Schema": "public"}
+		]},
+		{"Node Type": "Index Scan", "Relation Name": "members", "Schema": "public", "Index Name": "members_pkey"}
+	]
+}}]`
+
+type statement struct {
+	sql string
+	err error
+}
+
+func (s statement) ToSQL() (string, []interface{}, error) {
+	return s.sql, []interface{}{"core"}, s.err
+}
+
+func TestParseSeqScans(t *testing.T) {
+	scans, err := parseSeqScans([]byte(teamsByOwnerPlan))
+	require.NoError(t, err)
+	assert.Equal(t, []SeqScan{
+		{Table: "public.teams", Filter: "(teams.name = 'core'::text)"},
+		{Table: "public.users"},
+	}, scans)
+
+	_, err = parseSeqScans([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestAssertNoSeqScans(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	query := FromQuery("teams by name", statement{sql: "SELECT * FROM teams JOIN users ON users.id = teams.owner_id WHERE teams.name = $1"})
+
+	t.Run("large tables fail", func(t *testing.T) {
+		mock.ExpectQuery(`EXPLAIN \(VERBOSE, FORMAT JSON\) SELECT \* FROM teams`).
+			WithArgs("core").
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(teamsByOwnerPlan))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM "public"\."teams"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5000))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM "public"\."users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(20))
+
+		rec := &recordingTB{TB: t}
+		AssertNoSeqScans(rec, sqlxDB, []PlanQuery{query})
+		require.Len(t, rec.errors, 1)
+		assert.Contains(t, rec.errors[0], "teams by name: Seq Scan on public.teams (5000 rows) filtering (teams.name = 'core'::text)")
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("allowed tables and threshold", func(t *testing.T) {
+		mock.ExpectQuery(`EXPLAIN`).
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow(teamsByOwnerPlan))
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM "public"\."users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(20))
+
+		rec := &recordingTB{TB: t}
+		AssertNoSeqScans(rec, sqlxDB, []PlanQuery{query}, PlanOptions{MinRows: 50, AllowTables: []string{"teams"}})
+		assert.Empty(t, rec.errors)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query that cannot be built", func(t *testing.T) {
+		rec := &recordingTB{TB: t}
+		AssertNoSeqScans(rec, sqlxDB, []PlanQuery{FromQuery("broken", statement{err: errors.New("unknown column")})})
+		require.Len(t, rec.errors, 1)
+		assert.Contains(t, rec.errors[0], "broken: failed to build query: unknown column")
+	})
+}
+
+func TestFindSeqScans_ExplainError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`EXPLAIN`).WillReturnError(errors.New("relation \"teams\" does not exist"))
+
+	_, err = FindSeqScans(context.Background(), sqlx.NewDb(db, "postgres"), PlanQuery{Name: "teams", SQL: "SELECT * FROM teams"}, PlanOptions{})
+	assert.ErrorContains(t, err, "failed to explain query")
+}
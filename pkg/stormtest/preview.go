@@ -0,0 +1,39 @@
+package stormtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/migrator"
+	"github.com/eleven-am/storm/pkg/storm"
+)
+
+// PreviewDatabase creates a database next to the one at databaseURL with the
+// full schema of the models in packagePath, runs the seed files, and returns
+// its connection URL. The database is dropped when the test finishes.
+//
+//	func TestCheckout(t *testing.T) {
+//		url := stormtest.PreviewDatabase(t, os.Getenv("DATABASE_URL"), "./models", "./testdata/seed.sql")
+//		db := sqlx.MustConnect("postgres", url)
+//		...
+//	}
+func PreviewDatabase(tb testing.TB, databaseURL, packagePath string, seeds ...string) string {
+	tb.Helper()
+
+	config := migrator.NewDBConfig(databaseURL)
+	preview, err := migrator.CreatePreview(context.Background(), config, migrator.PreviewOptions{
+		PackagePath:  packagePath,
+		Seeds:        seeds,
+		StormVersion: storm.Version,
+	})
+	if err != nil {
+		tb.Fatalf("stormtest: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		if err := migrator.DropPreview(context.Background(), config, preview.Name); err != nil {
+			tb.Errorf("stormtest: %v", err)
+		}
+	})
+	return preview.URL
+}
@@ -0,0 +1,144 @@
+// Package stormtest provides helpers for testing code built on Storm.
+package stormtest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eleven-am/storm/internal/generator"
+	"github.com/eleven-am/storm/internal/parser"
+)
+
+// UpdateSnapshotsEnv is the environment variable that, when set to a
+// non-empty value, rewrites golden files instead of comparing against them.
+const UpdateSnapshotsEnv = "STORM_UPDATE_SNAPSHOTS"
+
+// RenderSchema parses the models in packagePath and renders the schema they
+// produce as canonical SQL. Tables are emitted in dependency order with ties
+// broken alphabetically, so the output is stable across runs.
+func RenderSchema(packagePath string) (string, error) {
+	tables, err := parser.NewStructParser().ParseDirectory(packagePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse structs: %w", err)
+	}
+
+	schema, err := generator.NewSchemaGenerator().GenerateSchema(tables)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	return generator.NewSQLGenerator().GenerateSchema(schema), nil
+}
+
+// AssertSchemaSnapshot renders the schema for the models in packagePath and
+// compares it with the golden file at goldenPath. The test fails with a line
+// diff when the schema has drifted from the snapshot.
+//
+// Run the test with STORM_UPDATE_SNAPSHOTS=1 to accept the new schema.
+func AssertSchemaSnapshot(tb testing.TB, packagePath, goldenPath string) {
+	tb.Helper()
+
+	actual, err := RenderSchema(packagePath)
+	if err != nil {
+		tb.Fatalf("stormtest: failed to render schema for %s: %v", packagePath, err)
+	}
+
+	AssertSnapshot(tb, goldenPath, actual)
+}
+
+// AssertSnapshot compares actual with the contents of the golden file at
+// goldenPath. It is useful for snapshotting generated migration SQL as well as
+// schemas. Line endings and trailing whitespace are ignored.
+func AssertSnapshot(tb testing.TB, goldenPath, actual string) {
+	tb.Helper()
+
+	actual = normalizeSnapshot(actual)
+
+	if os.Getenv(UpdateSnapshotsEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			tb.Fatalf("stormtest: failed to create snapshot directory: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(actual), 0644); err != nil {
+			tb.Fatalf("stormtest: failed to write snapshot %s: %v", goldenPath, err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(goldenPath)
+	if errors.Is(err, os.ErrNotExist) {
+		tb.Fatalf("stormtest: snapshot %s does not exist; rerun with %s=1 to create it", goldenPath, UpdateSnapshotsEnv)
+	}
+	if err != nil {
+		tb.Fatalf("stormtest: failed to read snapshot %s: %v", goldenPath, err)
+	}
+
+	expected := normalizeSnapshot(string(data))
+	if expected == actual {
+		return
+	}
+
+	tb.Errorf("stormtest: snapshot %s does not match (rerun with %s=1 to update)\n%s",
+		goldenPath, UpdateSnapshotsEnv, Diff(expected, actual))
+}
+
+// Diff returns a line diff between expected and actual. Removed lines are
+// prefixed with "-", added lines with "+" and unchanged lines are indented.
+func Diff(expected, actual string) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(actual, "\n")
+
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out.WriteString("- " + a[i] + "\n")
+	}
+	for ; j < len(b); j++ {
+		out.WriteString("+ " + b[j] + "\n")
+	}
+
+	return out.String()
+}
+
+func normalizeSnapshot(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
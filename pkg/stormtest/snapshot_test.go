@@ -0,0 +1,104 @@
+package stormtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testModels = "package models\n\n" +
+	"type User struct {\n" +
+	"\tID    string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+	"\tEmail string `db:\"email\" dbdef:\"type:varchar(255);not_null;unique\"`\n" +
+	"}\n\n" +
+	"type Team struct {\n" +
+	"\tID      string `db:\"id\" dbdef:\"type:uuid;primary_key\"`\n" +
+	"\tOwnerID string `db:\"owner_id\" dbdef:\"type:uuid;not_null;fk:users.id\"`\n" +
+	"}\n"
+
+// recordingTB captures failures so mismatches can be asserted on
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func writeModels(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(testModels), 0644))
+	return dir
+}
+
+func TestRenderSchema(t *testing.T) {
+	dir := writeModels(t)
+
+	first, err := RenderSchema(dir)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		again, err := RenderSchema(dir)
+		require.NoError(t, err)
+		assert.Equal(t, first, again, "rendered schema should be stable")
+	}
+
+	assert.Contains(t, first, "CREATE TABLE users")
+	assert.Contains(t, first, "CREATE TABLE teams")
+	assert.Less(t, strings.Index(first, "CREATE TABLE users"), strings.Index(first, "CREATE TABLE teams"),
+		"referenced tables should be rendered first")
+}
+
+func TestAssertSchemaSnapshot(t *testing.T) {
+	dir := writeModels(t)
+	golden := filepath.Join(t.TempDir(), "testdata", "schema.golden.sql")
+
+	t.Run("update writes the snapshot", func(t *testing.T) {
+		t.Setenv(UpdateSnapshotsEnv, "1")
+		AssertSchemaSnapshot(t, dir, golden)
+
+		data, err := os.ReadFile(golden)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "CREATE TABLE users")
+	})
+
+	t.Run("matching snapshot passes", func(t *testing.T) {
+		AssertSchemaSnapshot(t, dir, golden)
+	})
+
+	t.Run("drift reports a diff", func(t *testing.T) {
+		drifted := strings.Replace(testModels, "varchar(255)", "text", 1)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(drifted), 0644))
+
+		rec := &recordingTB{TB: t}
+		AssertSchemaSnapshot(rec, dir, golden)
+
+		require.Len(t, rec.errors, 1)
+		assert.Contains(t, rec.errors[0], "- ")
+		assert.Contains(t, rec.errors[0], "email varchar(255)")
+		assert.Contains(t, rec.errors[0], "+ ")
+		assert.Contains(t, rec.errors[0], "email text")
+	})
+}
+
+func TestAssertSnapshotIgnoresWhitespace(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "up.sql")
+	require.NoError(t, os.WriteFile(golden, []byte("CREATE TABLE a ();  \r\n\r\n"), 0644))
+
+	rec := &recordingTB{TB: t}
+	AssertSnapshot(rec, golden, "CREATE TABLE a ();\n")
+	assert.Empty(t, rec.errors)
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff("a\nb\nc", "a\nx\nc")
+	assert.Equal(t, "  a\n- b\n+ x\n  c\n", diff)
+}
@@ -0,0 +1,56 @@
+package stormtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/jmoiron/sqlx"
+)
+
+// BeginTx begins a transaction that is rolled back at test cleanup.
+// The test is failed immediately if the transaction cannot be started.
+func BeginTx(tb testing.TB, db *sqlx.DB) *sqlx.Tx {
+	tb.Helper()
+
+	tx, err := db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		tb.Fatalf("stormtest: failed to begin test transaction: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			tb.Errorf("stormtest: failed to roll back test transaction: %v", err)
+		}
+	})
+
+	return tx
+}
+
+// NewStorm begins a transaction on db and returns a Storm bound to it.
+// The transaction is rolled back when the test finishes, so every change made
+// through the returned Storm is discarded without truncating tables.
+//
+// Calls to WithTransaction on the returned Storm reuse the test transaction,
+// which means code under test that commits its own transactions stays isolated.
+// Generated code is bound the same way with models.NewStormWithTx(db, BeginTx(t, db)).
+func NewStorm(tb testing.TB, db *sqlx.DB, logger ...orm.QueryLogger) *orm.Storm {
+	tb.Helper()
+
+	return orm.NewStormWithTx(db, BeginTx(tb, db), logger...)
+}
+
+// NewRepository creates a repository bound to a transaction that is rolled
+// back at test cleanup.
+func NewRepository[T any](tb testing.TB, db *sqlx.DB, metadata *orm.ModelMetadata) *orm.Repository[T] {
+	tb.Helper()
+
+	repo, err := orm.NewRepositoryWithExecutor[T](BeginTx(tb, db), metadata)
+	if err != nil {
+		tb.Fatalf("stormtest: failed to create test repository: %v", err)
+	}
+
+	return repo
+}
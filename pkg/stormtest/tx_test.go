@@ -0,0 +1,78 @@
+package stormtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	orm "github.com/eleven-am/storm/pkg/storm-orm"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type txUser struct {
+	ID int `db:"id"`
+}
+
+func TestNewStorm(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	t.Run("rolls back at cleanup", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		t.Run("inner", func(t *testing.T) {
+			s := NewStorm(t, sqlxDB)
+			assert.Equal(t, sqlxDB, s.GetDB())
+		})
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nested transactions reuse the test transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		t.Run("inner", func(t *testing.T) {
+			s := NewStorm(t, sqlxDB)
+			err := s.WithTransaction(context.Background(), func(txStorm *orm.Storm) error {
+				assert.Same(t, s, txStorm)
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestNewRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	t.Run("inner", func(t *testing.T) {
+		repo := NewRepository[txUser](t, sqlxDB, &orm.ModelMetadata{
+			TableName:  "users",
+			StructName: "txUser",
+			Columns: map[string]*orm.ColumnMetadata{
+				"ID": {FieldName: "ID", DBName: "id", GoType: "int", IsPrimaryKey: true},
+			},
+			ColumnMap:   map[string]string{"ID": "id"},
+			PrimaryKeys: []string{"id"},
+		})
+		assert.True(t, repo.IsTransaction())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}